@@ -800,6 +800,9 @@ func (m callMsg) Data() []byte                  { return m.CallMsg.Data }
 func (m callMsg) AccessList() types2.AccessList { return m.CallMsg.AccessList }
 func (m callMsg) IsFree() bool                  { return false }
 
+func (m callMsg) BlobGas() uint64                { return 0 }
+func (m callMsg) MaxFeePerBlobGas() *uint256.Int { return new(uint256.Int) }
+
 // filterBackend implements filters.Backend to support filtering for logs without
 // taking bloom-bits acceleration structures into account.
 type filterBackend struct {
@@ -492,6 +492,8 @@ func init() {
 	cmdSetPrune.Flags().Uint64Var(&pruneRBefore, "prune.r.before", 0, "")
 	cmdSetPrune.Flags().Uint64Var(&pruneTBefore, "prune.t.before", 0, "")
 	cmdSetPrune.Flags().Uint64Var(&pruneCBefore, "prune.c.before", 0, "")
+	cmdSetPrune.Flags().StringSliceVar(&keepLogAddrs, "prune.r.keep.addresses", nil, "")
+	cmdSetPrune.Flags().StringSliceVar(&keepLogTopics, "prune.r.keep.topics", nil, "")
 	cmdSetPrune.Flags().StringSliceVar(&experiments, "experiments", nil, "Storage mode to override database")
 	rootCmd.AddCommand(cmdSetPrune)
 }
@@ -1326,7 +1328,7 @@ func stage(st *stagedsync.Sync, tx kv.Tx, db kv.RoDB, stage stages.SyncStage) *s
 func overrideStorageMode(db kv.RwDB) error {
 	chainConfig := fromdb.ChainConfig(db)
 	pm, err := prune.FromCli(chainConfig.ChainID.Uint64(), pruneFlag, pruneH, pruneR, pruneT, pruneC,
-		pruneHBefore, pruneRBefore, pruneTBefore, pruneCBefore, experiments)
+		pruneHBefore, pruneRBefore, pruneTBefore, pruneCBefore, keepLogAddrs, keepLogTopics, experiments)
 	if err != nil {
 		return err
 	}
@@ -33,6 +33,7 @@ type HttpCfg struct {
 	WebsocketEnabled         bool
 	WebsocketCompression     bool
 	RpcAllowListFilePath     string
+	RpcRateLimitFilePath     string
 	RpcBatchConcurrency      uint
 	RpcStreamingDisable      bool
 	DBReadConcurrency        int
@@ -64,4 +65,6 @@ type HttpCfg struct {
 
 	BatchLimit      int // Maximum number of requests in a batch
 	ReturnDataLimit int // Maximum number of bytes returned from calls (like eth_call)
+
+	MaxGetProofRewindBlockCount int // Maximum number of blocks eth_getProof and eth_simulateV1 rewind the state trie by
 }
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// parseRateLimitForRPC reads the rate limits of the API calls, nil if no file is provided. For example:
+//
+//	{
+//	  "rate": 100, "burst": 500, "apiKeyHeader": "X-Api-Key",
+//	  "costs": {"debug_traceBlockByNumber": 200, "eth_chainId": 0},
+//	  "methods": {"debug_traceBlockByNumber": {"rate": 0.5, "burst": 2}}
+//	}
+func parseRateLimitForRPC(path string) (*rpc.RateLimiter, error) {
+	path = strings.TrimSpace(path)
+	if path == "" { // no file is provided
+		return nil, nil
+	}
+
+	fileContents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg rpc.RateLimitConfig
+	if err := json.Unmarshal(fileContents, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return rpc.NewRateLimiter(cfg)
+}
@@ -17,6 +17,11 @@ type AdminAPI interface {
 	// Peers returns information about the connected remote nodes.
 	// https://geth.ethereum.org/docs/rpc/ns-admin#admin_peers
 	Peers(ctx context.Context) ([]*p2p.PeerInfo, error)
+
+	// Peer reputation related (see ./admin_peer_reputations.go)
+	PeerReputations(ctx context.Context) ([]PeerReputation, error)
+	BanPeer(ctx context.Context, node string, seconds *uint64) (bool, error)
+	UnbanPeer(ctx context.Context, node string) (bool, error)
 }
 
 // AdminAPIImpl data structure to store things needed for admin_* commands.
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+)
+
+// PeerReputation is the reputation of a peer known to a sentry
+type PeerReputation struct {
+	ID          string            `json:"id"`
+	Protocol    string            `json:"protocol"`
+	Connected   bool              `json:"connected"`
+	Score       float64           `json:"score"`
+	Penalties   map[string]uint64 `json:"penalties,omitempty"`
+	BannedUntil *time.Time        `json:"bannedUntil,omitempty"`
+	BanReason   string            `json:"banReason,omitempty"`
+}
+
+// PeerReputations implements admin_peerReputations. Returns the peers which have been penalised or banned,
+// with their score and penalties, for every sentry of the node.
+func (api *AdminAPIImpl) PeerReputations(ctx context.Context) ([]PeerReputation, error) {
+	peers, err := api.ethBackend.PeerReputations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]PeerReputation, 0, len(peers))
+	for _, rep := range peers {
+		var id enode.ID
+		copy(id[:], rep.ID)
+		peer := PeerReputation{
+			ID:        id.String(),
+			Protocol:  eth.ProtocolToString[uint(rep.Protocol)],
+			Connected: rep.Connected,
+			Score:     rep.Score,
+			BanReason: rep.BanReason,
+		}
+		if len(rep.Penalties) > 0 {
+			peer.Penalties = make(map[string]uint64, len(rep.Penalties))
+			for _, penalty := range rep.Penalties {
+				peer.Penalties[penalty.Reason] = penalty.Count
+			}
+		}
+		if rep.BannedUntil != 0 {
+			until := time.Unix(0, rep.BannedUntil)
+			peer.BannedUntil = &until
+		}
+		res = append(res, peer)
+	}
+	return res, nil
+}
+
+// BanPeer implements admin_banPeer. Disconnects the peer, given by its enode URL or node ID, and prevents it
+// from connecting to any sentry of the node for the given number of seconds, one day by default.
+// The ban survives restarts.
+func (api *AdminAPIImpl) BanPeer(ctx context.Context, node string, seconds *uint64) (bool, error) {
+	id, err := parseNodeID(node)
+	if err != nil {
+		return false, err
+	}
+	var duration uint64 // the default duration of the sentries
+	if seconds != nil {
+		duration = *seconds
+	}
+	if err := api.ethBackend.BanPeer(ctx, id, duration, "banned with admin_banPeer"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnbanPeer implements admin_unbanPeer. Lifts the ban of the peer, given by its enode URL or node ID,
+// and forgets its penalties. Returns whether the peer was banned.
+func (api *AdminAPIImpl) UnbanPeer(ctx context.Context, node string) (bool, error) {
+	id, err := parseNodeID(node)
+	if err != nil {
+		return false, err
+	}
+	return api.ethBackend.UnbanPeer(ctx, id)
+}
+
+// parseNodeID accepts an enode URL or a node ID, as returned by admin_peers
+func parseNodeID(node string) (enode.ID, error) {
+	if strings.HasPrefix(node, "enode://") {
+		n, err := enode.ParseV4(node)
+		if err != nil {
+			return enode.ID{}, fmt.Errorf("invalid enode: %w", err)
+		}
+		return n.ID(), nil
+	}
+	id, err := enode.ParseID(node)
+	if err != nil {
+		return enode.ID{}, fmt.Errorf("invalid node id: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcservices"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentry/reputation"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+)
+
+type testPeerReputations struct {
+	peers []*reputation.Peer
+	bans  []*reputation.BanRequest
+}
+
+func (s *testPeerReputations) Peers(context.Context, *reputation.PeersRequest) (*reputation.PeersReply, error) {
+	return &reputation.PeersReply{Peers: s.peers}, nil
+}
+
+func (s *testPeerReputations) Ban(_ context.Context, req *reputation.BanRequest) (*reputation.BanReply, error) {
+	s.bans = append(s.bans, req)
+	return &reputation.BanReply{}, nil
+}
+
+func (s *testPeerReputations) Unban(_ context.Context, req *reputation.UnbanRequest) (*reputation.UnbanReply, error) {
+	for i, ban := range s.bans {
+		if string(ban.ID) == string(req.ID) {
+			s.bans = append(s.bans[:i], s.bans[i+1:]...)
+			return &reputation.UnbanReply{Banned: true}, nil
+		}
+	}
+	return &reputation.UnbanReply{}, nil
+}
+
+func TestAdminPeerReputations(t *testing.T) {
+	ctx := context.Background()
+	until := time.Unix(1700000000, 0)
+	server := &testPeerReputations{peers: []*reputation.Peer{{
+		ID:          enode.ID{1}.Bytes(),
+		Protocol:    eth.ETH66,
+		Score:       35,
+		Penalties:   []*reputation.Penalty{{Reason: "empty response", Count: 6}},
+		BannedUntil: until.UnixNano(),
+		BanReason:   "score reached 100",
+	}}}
+	api := NewAdminAPI(rpcservices.NewRemoteBackend(nil, reputation.NewPeerReputationClientDirect(server), nil, nil))
+
+	peers, err := api.PeerReputations(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(peers))
+	require.Equal(t, enode.ID{1}.String(), peers[0].ID)
+	require.Equal(t, "eth66", peers[0].Protocol)
+	require.Equal(t, map[string]uint64{"empty response": 6}, peers[0].Penalties)
+	require.NotNil(t, peers[0].BannedUntil)
+	require.True(t, until.Equal(*peers[0].BannedUntil))
+
+	id := enode.ID{2}
+	seconds := uint64(60)
+	banned, err := api.BanPeer(ctx, id.String(), &seconds)
+	require.NoError(t, err)
+	require.True(t, banned)
+	_, err = api.BanPeer(ctx, "enode://invalid", nil)
+	require.ErrorContains(t, err, "invalid enode")
+	require.Equal(t, []*reputation.BanRequest{{ID: id.Bytes(), Seconds: 60, Reason: "banned with admin_banPeer"}}, server.bans)
+
+	unbanned, err := api.UnbanPeer(ctx, id.String())
+	require.NoError(t, err)
+	require.True(t, unbanned)
+	unbanned, err = api.UnbanPeer(ctx, id.String())
+	require.NoError(t, err)
+	require.False(t, unbanned)
+
+	// Without the service, e.g. with a private API of an older node
+	api = NewAdminAPI(rpcservices.NewRemoteBackend(nil, nil, nil, nil))
+	_, err = api.PeerReputations(ctx)
+	require.ErrorContains(t, err, "peer reputations are not served")
+}
@@ -2,6 +2,9 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
 	"sync"
 	"testing"
 
@@ -17,8 +20,11 @@ import (
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
 )
@@ -271,3 +277,104 @@ func TestFilterAddressIntersection(t *testing.T) {
 		require.Empty(t, blockNumbersFromTraces(t, stream.Buffer()))
 	})
 }
+
+func TestFilterCursor(t *testing.T) {
+	for _, historyV3 := range []bool{false, true} {
+		historyV3 := historyV3
+		t.Run(fmt.Sprintf("historyV3=%t", historyV3), func(t *testing.T) {
+			testFilterCursor(t, historyV3)
+		})
+	}
+}
+
+// testFilterCursor pages through trace_filter results, cursors being positions in the call trace indexes
+// or in the temporal ones
+func testFilterCursor(t *testing.T, historyV3 bool) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(params.Ether)}},
+	}
+	m := stages.MockWithHistoryV3(t, gspec, ethash.NewFaker(), historyV3)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	api := NewTraceAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, &httpcfg.HttpCfg{})
+
+	toAddress1 := common.Address{1}
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 10, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(toAddress1)
+		signer := types.LatestSigner(m.ChainConfig)
+		for j := 0; j < 2; j++ {
+			txn, err := types.SignTx(types.NewTransaction(block.TxNonce(m.Address), toAddress1, new(uint256.Int), 21000, new(uint256.Int), nil), *signer, m.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block.AddTx(txn)
+		}
+	}, false /* intermediateHashes */)
+	require.NoError(t, err, "generate chain")
+	require.NoError(t, m.InsertChain(chain), "inserting chain")
+
+	fromBlock, toBlock := uint64(1), uint64(10)
+	stream := jsoniter.ConfigDefault.BorrowStream(nil)
+	defer jsoniter.ConfigDefault.ReturnStream(stream)
+	req := TraceFilterRequest{
+		FromBlock: (*hexutil.Uint64)(&fromBlock),
+		ToBlock:   (*hexutil.Uint64)(&toBlock),
+		ToAddress: []*common.Address{&toAddress1},
+	}
+	require.NoError(t, api.Filter(context.Background(), req, stream))
+	var all []map[string]interface{}
+	require.NoError(t, json.Unmarshal(stream.Buffer(), &all))
+	require.Equal(t, 30, len(all)) // 2 transactions and the block reward in each block
+
+	// Pages of 7 traces end in the middle of blocks, the next ones resume right after them
+	count := uint64(7)
+	req.Count = &count
+	req.Cursor = &hexutil.Bytes{}
+	var paged []map[string]interface{}
+	var cursors []*hexutil.Bytes
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5)
+		stream.Reset(nil)
+		require.NoError(t, api.Filter(context.Background(), req, stream))
+		var page struct {
+			Traces     []map[string]interface{} `json:"traces"`
+			NextCursor *hexutil.Bytes           `json:"nextCursor"`
+		}
+		require.NoError(t, json.Unmarshal(stream.Buffer(), &page))
+		paged = append(paged, page.Traces...)
+		if page.NextCursor == nil {
+			require.Equal(t, 30%7, len(page.Traces))
+			break
+		}
+		require.Equal(t, 7, len(page.Traces))
+		req.Cursor = page.NextCursor
+		cursors = append(cursors, page.NextCursor)
+	}
+	require.Equal(t, all, paged)
+
+	// A cursor tells where it resumes in the indexes of its node
+	decoded, err := decodeSearchCursor(*cursors[0], traceFilterCursor, historyV3)
+	require.NoError(t, err)
+	require.Equal(t, historyV3, decoded.temporal)
+	_, err = decodeSearchCursor(*cursors[0], traceFilterCursor, !historyV3)
+	require.ErrorContains(t, err, "another history layout")
+
+	// An empty page would never move the cursor
+	req.Cursor = cursors[0]
+	zero := uint64(0)
+	req.Count = &zero
+	stream.Reset(nil)
+	require.ErrorContains(t, api.Filter(context.Background(), req, stream), "count must be positive")
+	req.Count = &count
+
+	after := uint64(1)
+	req.After = &after
+	stream.Reset(nil)
+	require.Error(t, api.Filter(context.Background(), req, stream))
+	req.After = nil
+	req.Cursor = &hexutil.Bytes{0x01, 0x02}
+	stream.Reset(nil)
+	require.Error(t, api.Filter(context.Background(), req, stream))
+}
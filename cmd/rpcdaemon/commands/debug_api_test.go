@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"encoding/json"
+	"math/big"
 	"reflect"
 	"testing"
 
@@ -15,10 +16,13 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv/order"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	common2 "github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state/temporal"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
@@ -56,8 +60,8 @@ func TestTraceBlockByNumber(t *testing.T) {
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000)
-	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
+	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0, m.Dirs.Tmp)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -105,8 +109,8 @@ func TestTraceBlockByHash(t *testing.T) {
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000)
-	api := NewPrivateDebugAPI(baseApi, m.DB, 0)
+	ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0, m.Dirs.Tmp)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -135,13 +139,96 @@ func TestTraceBlockByHash(t *testing.T) {
 	}
 }
 
+// TestTraceCombined tests that the traces of blocks and bundles combined by the tracer are the combination
+// of the traces of their transactions.
+func TestTraceCombined(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0, m.Dirs.Tmp)
+	gasProfileTracer, combine := "gasProfileTracer", true
+	trace := func(call func(*tracers.TraceConfig, *jsoniter.Stream) error, config *tracers.TraceConfig) []byte {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		require.NoError(t, call(config, stream))
+		require.NoError(t, stream.Flush())
+		return buf.Bytes()
+	}
+	var profile struct {
+		Transactions uint64 `json:"transactions"`
+		GasUsed      uint64 `json:"gasUsed"`
+	}
+
+	for _, number := range []rpc.BlockNumber{6, 7, 8} { // Blocks of several transactions
+		traceBlock := func(config *tracers.TraceConfig, stream *jsoniter.Stream) error {
+			return api.TraceBlockByNumber(m.Ctx, number, config, stream)
+		}
+		var traces []struct {
+			Result json.RawMessage `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(trace(traceBlock, &tracers.TraceConfig{Tracer: &gasProfileTracer}), &traces))
+		results := make([]json.RawMessage, len(traces))
+		for i, trace := range traces {
+			results[i] = trace.Result
+		}
+		expected, err := tracers.Combiner(gasProfileTracer)(results)
+		require.NoError(t, err)
+		combined := trace(traceBlock, &tracers.TraceConfig{Tracer: &gasProfileTracer, Combine: &combine})
+		require.JSONEq(t, string(expected), string(combined))
+
+		tx, err := m.DB.BeginRo(m.Ctx)
+		require.NoError(t, err)
+		block, err := api.blockByRPCNumber(number, tx)
+		tx.Rollback()
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(combined, &profile))
+		require.Equal(t, uint64(len(block.Transactions())), profile.Transactions)
+		require.Equal(t, block.GasUsed(), profile.GasUsed)
+	}
+
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		from, to = crypto.PubkeyToAddress(key.PublicKey), common.HexToAddress("0x000000000000000000000000000000000000dead")
+		gas      = hexutil.Uint64(params.TxGas)
+		value    = (*hexutil.Big)(big.NewInt(1))
+		latest   = StateContext{BlockNumber: rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)}
+		bundles  = []Bundle{
+			{Transactions: []ethapi.CallArgs{{From: &from, To: &to, Gas: &gas, Value: value}, {From: &from, To: &to, Gas: &gas, Value: value}}},
+			{Transactions: []ethapi.CallArgs{{From: &from, To: &to, Gas: &gas, Value: value}}},
+		}
+	)
+	traceCallMany := func(config *tracers.TraceConfig, stream *jsoniter.Stream) error {
+		return api.TraceCallMany(m.Ctx, bundles, latest, config, stream)
+	}
+	var traces [][]json.RawMessage
+	require.NoError(t, json.Unmarshal(trace(traceCallMany, &tracers.TraceConfig{Tracer: &gasProfileTracer}), &traces))
+	var combined []json.RawMessage
+	require.NoError(t, json.Unmarshal(trace(traceCallMany, &tracers.TraceConfig{Tracer: &gasProfileTracer, Combine: &combine}), &combined))
+	require.Len(t, combined, len(bundles))
+	for i, bundle := range bundles {
+		expected, err := tracers.Combiner(gasProfileTracer)(traces[i])
+		require.NoError(t, err)
+		require.JSONEq(t, string(expected), string(combined[i]))
+		require.NoError(t, json.Unmarshal(combined[i], &profile))
+		require.Equal(t, uint64(len(bundle.Transactions)), profile.Transactions)
+		require.Equal(t, uint64(len(bundle.Transactions))*params.TxGas, profile.GasUsed)
+	}
+
+	callTracer := "callTracer"
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.ErrorContains(t, api.TraceBlockByNumber(m.Ctx, 1, &tracers.TraceConfig{Tracer: &callTracer, Combine: &combine}, stream), "can't be combined")
+}
+
 func TestTraceTransaction(t *testing.T) {
 	m, _, _ := rpcdaemontest.CreateTestSentry(t)
 	agg := m.HistoryV3Components()
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	api := NewPrivateDebugAPI(base, m.DB, 0)
+	api := NewPrivateDebugAPI(base, m.DB, 0, m.Dirs.Tmp)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -174,7 +261,7 @@ func TestTraceTransactionNoRefund(t *testing.T) {
 	agg := m.HistoryV3Components()
 	api := NewPrivateDebugAPI(
 		NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine),
-		m.DB, 0)
+		m.DB, 0, m.Dirs.Tmp)
 	for _, tt := range debugTraceTransactionNoRefundTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -208,7 +295,7 @@ func TestStorageRangeAt(t *testing.T) {
 	agg := m.HistoryV3Components()
 	api := NewPrivateDebugAPI(
 		NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine),
-		m.DB, 0)
+		m.DB, 0, m.Dirs.Tmp)
 	t.Run("invalid addr", func(t *testing.T) {
 		var block4 *types.Block
 		err := m.DB.View(m.Ctx, func(tx kv.Tx) error {
@@ -305,7 +392,7 @@ func TestAccountRange(t *testing.T) {
 	agg := m.HistoryV3Components()
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	api := NewPrivateDebugAPI(base, m.DB, 0)
+	api := NewPrivateDebugAPI(base, m.DB, 0, m.Dirs.Tmp)
 
 	t.Run("valid account", func(t *testing.T) {
 		addr := common.HexToAddress("0x537e697c7ab75a26f9ecf0ce810e3154dfcaaf55")
@@ -368,7 +455,7 @@ func TestGetModifiedAccountsByNumber(t *testing.T) {
 	agg := m.HistoryV3Components()
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	api := NewPrivateDebugAPI(base, m.DB, 0)
+	api := NewPrivateDebugAPI(base, m.DB, 0, m.Dirs.Tmp)
 
 	t.Run("correct input", func(t *testing.T) {
 		n, n2 := rpc.BlockNumber(1), rpc.BlockNumber(2)
@@ -471,7 +558,7 @@ func TestAccountAt(t *testing.T) {
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
-	api := NewPrivateDebugAPI(base, m.DB, 0)
+	api := NewPrivateDebugAPI(base, m.DB, 0, m.Dirs.Tmp)
 
 	var blockHash0, blockHash1, blockHash3, blockHash10, blockHash12 common.Hash
 	_ = m.DB.View(m.Ctx, func(tx kv.Tx) error {
@@ -0,0 +1,468 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// maxWitnessRewindBlockCount is how far behind the head of the state trie a block can be for its witness to
+// be built or verified, the trie being rewound in memory to the state the block is executed on
+const maxWitnessRewindBlockCount = 1_000
+
+// ExecutionWitness is the result of debug_executionWitness: the part of the state a block accesses, along with
+// the trie nodes proving it against the state root of its parent, which is enough to execute it statelessly.
+type ExecutionWitness struct {
+	StateRoot common.Hash                      `json:"stateRoot"` // State root of the parent block
+	Witness   hexutil.Bytes                    `json:"witness"`   // Trie nodes, accounts, storage and code in the binary witness format of turbo/trie
+	Accounts  []common.Address                 `json:"accounts"`
+	Storage   map[common.Address][]common.Hash `json:"storage"`
+	Codes     []hexutil.Bytes                  `json:"codes"`
+}
+
+// WitnessVerification is the result of debug_verifyWitness.
+type WitnessVerification struct {
+	Valid     bool        `json:"valid"`
+	StateRoot common.Hash `json:"stateRoot"` // State root computed by executing the block on the witness
+	Error     string      `json:"error,omitempty"`
+}
+
+// ExecutionWitness implements debug_executionWitness. Re-executes the given block and returns the witness of the
+// state it accesses.
+func (api *PrivateDebugAPIImpl) ExecutionWitness(ctx context.Context, blockNr rpc.BlockNumber) (*ExecutionWitness, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	block, parent, err := api.witnessBlock(ctx, tx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := rpchelper.CreateHistoryStateReader(tx, block.NumberU64(), 0, api.historyV3(tx), chainConfig.ChainName)
+	if err != nil {
+		return nil, err
+	}
+	recorder := newWitnessRecorder(reader)
+	if err = api.executeBlock(ctx, tx, chainConfig, block, recorder, state.NewNoopWriter()); err != nil {
+		return nil, err
+	}
+
+	stateTx, release, err := api.stateTrieAt(ctx, tx, parent.Number.Uint64(), maxWitnessRewindBlockCount, api.tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	loadList, witnessList := recorder.retainLists()
+	loader := trie.NewFlatDBTrieLoader("debug_executionWitness")
+	if err = loader.Reset(loadList, nil, nil, false); err != nil {
+		return nil, err
+	}
+	t, err := loader.CalcTrie(stateTx, ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if root := t.Hash(); root != parent.Root {
+		return nil, fmt.Errorf("state root %x doesn't match the one of block %d: %x", root, parent.Number.Uint64(), parent.Root)
+	}
+	for address, code := range recorder.codes {
+		if err = t.UpdateAccountCode(crypto.Keccak256(address[:]), code); err != nil {
+			return nil, err
+		}
+	}
+
+	witness, err := t.ExtractWitness(false, witnessList)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err = witness.WriteInto(&buf); err != nil {
+		return nil, err
+	}
+
+	result := &ExecutionWitness{
+		StateRoot: parent.Root,
+		Witness:   buf.Bytes(),
+		Accounts:  make([]common.Address, 0, len(recorder.accounts)),
+		Storage:   make(map[common.Address][]common.Hash, len(recorder.storage)),
+		Codes:     make([]hexutil.Bytes, 0, len(recorder.codes)),
+	}
+	for address := range recorder.accounts {
+		result.Accounts = append(result.Accounts, address)
+	}
+	sort.Slice(result.Accounts, func(i, j int) bool {
+		return bytes.Compare(result.Accounts[i][:], result.Accounts[j][:]) < 0
+	})
+	for _, address := range result.Accounts {
+		if keys, ok := recorder.storage[address]; ok {
+			storage := make([]common.Hash, 0, len(keys))
+			for key := range keys {
+				storage = append(storage, key)
+			}
+			sort.Slice(storage, func(i, j int) bool { return bytes.Compare(storage[i][:], storage[j][:]) < 0 })
+			result.Storage[address] = storage
+		}
+		if code, ok := recorder.codes[address]; ok {
+			result.Codes = append(result.Codes, code)
+		}
+	}
+	return result, nil
+}
+
+// VerifyWitness implements debug_verifyWitness. Re-executes the given block using only the state of the given
+// witness, as returned by debug_executionWitness, and checks the resulting state root against the block's.
+func (api *PrivateDebugAPIImpl) VerifyWitness(ctx context.Context, blockNr rpc.BlockNumber, witness hexutil.Bytes) (*WitnessVerification, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	block, parent, err := api.witnessBlock(ctx, tx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := trie.NewWitnessFromReader(bytes.NewReader(witness), false)
+	if err != nil {
+		return nil, fmt.Errorf("malformed witness: %w", err)
+	}
+	t, err := trie.BuildTrieFromWitness(w, false)
+	if err != nil {
+		return nil, fmt.Errorf("malformed witness: %w", err)
+	}
+	if root := t.Hash(); root != parent.Root {
+		return &WitnessVerification{
+			StateRoot: root,
+			Error:     fmt.Sprintf("witness root %x doesn't match the state root of block %d: %x", root, parent.Number.Uint64(), parent.Root),
+		}, nil
+	}
+
+	trieState := &witnessTrieState{t: t}
+	if err = api.executeBlock(ctx, tx, chainConfig, block, trieState, trieState); err != nil {
+		return &WitnessVerification{StateRoot: parent.Root, Error: err.Error()}, nil
+	}
+	root := t.Hash()
+	if root != block.Root() {
+		return &WitnessVerification{
+			StateRoot: root,
+			Error:     fmt.Sprintf("state root %x doesn't match the one of block %d: %x", root, block.NumberU64(), block.Root()),
+		}, nil
+	}
+	return &WitnessVerification{Valid: true, StateRoot: root}, nil
+}
+
+// witnessBlock returns the block of the given number, along with the header of its parent.
+func (api *PrivateDebugAPIImpl) witnessBlock(ctx context.Context, tx kv.Tx, blockNr rpc.BlockNumber) (*types.Block, *types.Header, error) {
+	block, err := api.blockByRPCNumber(blockNr, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if block == nil {
+		return nil, nil, fmt.Errorf("invalid arguments; block with number %d not found", blockNr)
+	}
+	if block.NumberU64() == 0 {
+		return nil, nil, fmt.Errorf("genesis block has no witness")
+	}
+	parent, err := api._blockReader.Header(ctx, tx, block.ParentHash(), block.NumberU64()-1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parent == nil {
+		return nil, nil, fmt.Errorf("parent of block %d not found", block.NumberU64())
+	}
+	return block, parent, nil
+}
+
+// executeBlock executes the block on the state read from stateReader, checks the results against its header
+// and commits the resulting changes to stateWriter.
+func (api *PrivateDebugAPIImpl) executeBlock(ctx context.Context, tx kv.Tx, chainConfig *chain.Config, block *types.Block, stateReader state.StateReader, stateWriter state.WriterWithChangeSets) error {
+	engine, ok := api.engine().(consensus.Engine)
+	if !ok {
+		return fmt.Errorf("consensus engine %s can't execute blocks", api.engine().Type())
+	}
+	header := block.Header()
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		h, _ := api._blockReader.Header(ctx, tx, hash, number)
+		return h
+	}
+	chainReader := stagedsync.NewChainReaderImpl(chainConfig, tx, api._blockReader)
+
+	ibs := state.New(stateReader)
+	if err := core.InitializeBlockExecution(engine, chainReader, nil, header, block.Transactions(), block.Uncles(), chainConfig, ibs, firehose.NoOpContext); err != nil {
+		return err
+	}
+	if chainConfig.DAOForkSupport && chainConfig.DAOForkBlock != nil && chainConfig.DAOForkBlock.Cmp(block.Number()) == 0 {
+		misc.ApplyDAOHardFork(ibs, firehose.NoOpContext)
+	}
+
+	gp := new(core.GasPool).AddGas(block.GasLimit())
+	usedGas := new(uint64)
+	noop := state.NewNoopWriter()
+	receipts := make(types.Receipts, 0, len(block.Transactions()))
+	for i, txn := range block.Transactions() {
+		select {
+		default:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		ibs.Prepare(txn.Hash(), block.Hash(), i)
+		receipt, _, err := core.ApplyTransaction(chainConfig, core.GetHashFn(header, getHeader), engine, nil, gp, ibs, noop, header, txn, usedGas, vm.Config{}, firehose.NoOpContext)
+		if err != nil {
+			return fmt.Errorf("could not apply tx %d from block %d [%x]: %w", i, block.NumberU64(), txn.Hash(), err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	if *usedGas != header.GasUsed {
+		return fmt.Errorf("gas used by execution: %d, in header: %d", *usedGas, header.GasUsed)
+	}
+	if chainConfig.IsByzantium(header.Number.Uint64()) {
+		if receiptSha := types.DeriveSha(receipts); receiptSha != header.ReceiptHash {
+			return fmt.Errorf("mismatched receipt headers for block %d (%x != %x)", block.NumberU64(), receiptSha, header.ReceiptHash)
+		}
+	}
+
+	_, _, _, err := core.FinalizeBlockExecution(engine, stateReader, header, block.Transactions(), block.Uncles(), stateWriter, chainConfig, ibs, receipts, block.Withdrawals(), nil, chainReader, false, firehose.NoOpContext)
+	return err
+}
+
+// witnessRecorder is a StateReader recording the state read by the execution of a block.
+type witnessRecorder struct {
+	reader   state.StateReader
+	accounts map[common.Address]*accounts.Account // Accounts read, nil for the ones which don't exist
+	storage  map[common.Address]map[common.Hash]struct{}
+	codes    map[common.Address][]byte
+}
+
+func newWitnessRecorder(reader state.StateReader) *witnessRecorder {
+	return &witnessRecorder{
+		reader:   reader,
+		accounts: map[common.Address]*accounts.Account{},
+		storage:  map[common.Address]map[common.Hash]struct{}{},
+		codes:    map[common.Address][]byte{},
+	}
+}
+
+func (r *witnessRecorder) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	account, err := r.reader.ReadAccountData(address)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := r.accounts[address]; !ok {
+		var recorded *accounts.Account
+		if account != nil {
+			recorded = new(accounts.Account)
+			recorded.Copy(account)
+		}
+		r.accounts[address] = recorded
+	}
+	return account, nil
+}
+
+func (r *witnessRecorder) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	if _, err := r.ReadAccountData(address); err != nil {
+		return nil, err
+	}
+	if _, ok := r.storage[address]; !ok {
+		r.storage[address] = map[common.Hash]struct{}{}
+	}
+	r.storage[address][*key] = struct{}{}
+	return r.reader.ReadAccountStorage(address, incarnation, key)
+}
+
+func (r *witnessRecorder) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	code, err := r.reader.ReadAccountCode(address, incarnation, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) > 0 {
+		r.codes[address] = code
+	}
+	return code, nil
+}
+
+// ReadAccountCodeSize records the whole code, as the witness format doesn't carry code sizes on their own
+func (r *witnessRecorder) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	code, err := r.ReadAccountCode(address, incarnation, codeHash)
+	return len(code), err
+}
+
+func (r *witnessRecorder) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	return r.reader.ReadAccountIncarnation(address)
+}
+
+// retainLists returns the keys to load from the hashed state, the ones of the storage including the incarnation
+// of their account, and the ones to keep in the witness along with the code read.
+func (r *witnessRecorder) retainLists() (*trie.RetainList, *trie.RetainList) {
+	loadList := trie.NewRetainList(0)
+	witnessList := trie.NewRetainListBuilder()
+	for address, account := range r.accounts {
+		addrHash := crypto.Keccak256Hash(address[:])
+		loadList.AddKey(addrHash[:])
+		witnessList.AddTouch(addrHash[:])
+		// Accounts created by the block have neither storage nor code to prove
+		if account == nil {
+			continue
+		}
+		for key := range r.storage[address] {
+			keyHash := crypto.Keccak256Hash(key[:])
+			loadList.AddKey(dbutils.GenerateCompositeStorageKey(addrHash, account.Incarnation, keyHash))
+			witnessList.AddStorageTouch(dbutils.GenerateCompositeTrieKey(addrHash, keyHash))
+		}
+		if _, ok := r.codes[address]; ok {
+			witnessList.ReadCode(account.CodeHash)
+		}
+	}
+	return loadList, witnessList.Build()
+}
+
+// witnessTrieState reads and writes the state of a block on a trie built from its witness. Reaching a part of
+// the state that the witness doesn't hold is an error.
+type witnessTrieState struct {
+	t *trie.Trie
+}
+
+func (s *witnessTrieState) account(address common.Address) (common.Hash, *accounts.Account, error) {
+	addrHash := crypto.Keccak256Hash(address[:])
+	account, ok := s.t.GetAccount(addrHash[:])
+	if !ok {
+		return addrHash, nil, fmt.Errorf("account %x is missing from the witness", address)
+	}
+	return addrHash, account, nil
+}
+
+func (s *witnessTrieState) storageKey(address common.Address, key *common.Hash) ([]byte, error) {
+	addrHash := crypto.Keccak256Hash(address[:])
+	storageKey := dbutils.GenerateCompositeTrieKey(addrHash, crypto.Keccak256Hash(key[:]))
+	if _, ok := s.t.Get(storageKey); !ok {
+		return nil, fmt.Errorf("storage %x of account %x is missing from the witness", *key, address)
+	}
+	return storageKey, nil
+}
+
+func (s *witnessTrieState) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	_, account, err := s.account(address)
+	return account, err
+}
+
+func (s *witnessTrieState) ReadAccountStorage(address common.Address, _ uint64, key *common.Hash) ([]byte, error) {
+	storageKey, err := s.storageKey(address, key)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := s.t.Get(storageKey)
+	return value, nil
+}
+
+func (s *witnessTrieState) ReadAccountCode(address common.Address, _ uint64, codeHash common.Hash) ([]byte, error) {
+	if codeHash == trie.EmptyCodeHash {
+		return nil, nil
+	}
+	code, ok := s.t.GetAccountCode(crypto.Keccak256(address[:]))
+	if !ok {
+		return nil, fmt.Errorf("code of account %x is missing from the witness", address)
+	}
+	return code, nil
+}
+
+func (s *witnessTrieState) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	code, err := s.ReadAccountCode(address, incarnation, codeHash)
+	return len(code), err
+}
+
+func (s *witnessTrieState) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	_, account, err := s.account(address)
+	if err != nil || account == nil {
+		return 0, err
+	}
+	return account.Incarnation, nil
+}
+
+func (s *witnessTrieState) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	addrHash, _, err := s.account(address)
+	if err != nil {
+		return err
+	}
+	s.t.UpdateAccount(addrHash[:], account)
+	return nil
+}
+
+// UpdateAccountCode does nothing, the code hash being part of the account
+func (s *witnessTrieState) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+func (s *witnessTrieState) DeleteAccount(address common.Address, original *accounts.Account) error {
+	addrHash, _, err := s.account(address)
+	if err != nil {
+		return err
+	}
+	s.t.Delete(addrHash[:])
+	return nil
+}
+
+func (s *witnessTrieState) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	storageKey, err := s.storageKey(address, key)
+	if err != nil {
+		return err
+	}
+	if value.IsZero() {
+		s.t.Delete(storageKey)
+	} else {
+		s.t.Update(storageKey, value.Bytes())
+	}
+	return nil
+}
+
+// CreateContract clears the storage of the account, adding it first if it doesn't exist so that the storage
+// of the new contract is written under it
+func (s *witnessTrieState) CreateContract(address common.Address) error {
+	addrHash, account, err := s.account(address)
+	if err != nil {
+		return err
+	}
+	if account != nil {
+		s.t.DeleteSubtree(addrHash[:])
+		return nil
+	}
+	created := accounts.NewAccount()
+	s.t.UpdateAccount(addrHash[:], &created)
+	return nil
+}
+
+func (s *witnessTrieState) WriteChangeSets() error { return nil }
+
+func (s *witnessTrieState) WriteHistory() error { return nil }
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionWitness(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	baseApi := NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
+	api := NewPrivateDebugAPI(baseApi, m.DB, 0, m.Dirs.Tmp)
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	head := rawdb.ReadCurrentHeader(tx).Number.Uint64()
+	tx.Rollback()
+
+	for blockNum := uint64(1); blockNum <= head; blockNum++ {
+		witness, err := api.ExecutionWitness(ctx, rpc.BlockNumber(blockNum))
+		require.NoError(t, err, "block %d", blockNum)
+		require.NotEmpty(t, witness.Accounts, "block %d", blockNum)
+
+		result, err := api.VerifyWitness(ctx, rpc.BlockNumber(blockNum), witness.Witness)
+		require.NoError(t, err, "block %d", blockNum)
+		require.True(t, result.Valid, "block %d: %s", blockNum, result.Error)
+	}
+
+	// A witness is only valid for the block it was built for
+	witness, err := api.ExecutionWitness(ctx, rpc.BlockNumber(head))
+	require.NoError(t, err)
+	result, err := api.VerifyWitness(ctx, rpc.BlockNumber(head-1), witness.Witness)
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.NotEmpty(t, result.Error)
+
+	_, err = api.VerifyWitness(ctx, rpc.BlockNumber(head), hexutil.Bytes(witness.Witness[:len(witness.Witness)/2]))
+	require.Error(t, err)
+
+	_, err = api.ExecutionWitness(ctx, 0)
+	require.Error(t, err)
+	_, err = api.ExecutionWitness(ctx, rpc.BlockNumber(head+1))
+	require.Error(t, err)
+}
@@ -3,6 +3,8 @@ package commands
 import (
 	"context"
 
+	jsoniter "github.com/json-iterator/go"
+
 	"github.com/ledgerwatch/erigon-lib/common"
 
 	"github.com/ledgerwatch/erigon/eth/filters"
@@ -29,6 +31,9 @@ type ErigonAPI interface {
 	GetBlockByTimestamp(ctx context.Context, timeStamp rpc.Timestamp, fullTx bool) (map[string]interface{}, error)
 	GetBalanceChangesInBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*hexutil.Big, error)
 
+	// State related (see ./erigon_state_diffs.go)
+	GetStateDiffsInBlockRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber, stream *jsoniter.Stream) error
+
 	// Receipt related (see ./erigon_receipts.go)
 	GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error)
 	//GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error)
@@ -42,6 +47,9 @@ type ErigonAPI interface {
 
 	// NodeInfo returns a collection of metadata known about the host.
 	NodeInfo(ctx context.Context) ([]p2p.NodeInfo, error)
+
+	// Sync related (see ./erigon_sync_stages.go)
+	SyncStages(ctx context.Context) ([]SyncStage, error)
 }
 
 // ErigonImpl is implementation of the ErigonAPI interface
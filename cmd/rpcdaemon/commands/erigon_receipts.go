@@ -72,7 +72,7 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 		end = *number
 	} else {
 		// Convert the RPC block numbers into internal representations
-		latest, err := rpchelper.GetLatestBlockNumber(tx)
+		latest, err := rpchelper.GetLatestBlockNumberWithReceipts(tx)
 		if err != nil {
 			return nil, err
 		}
@@ -227,7 +227,7 @@ func (api *ErigonImpl) GetLatestLogs(ctx context.Context, crit filters.FilterCri
 			return nil, fmt.Errorf("negative value for ToBlock: %v", crit.ToBlock)
 		}
 	} else {
-		latest, err = rpchelper.GetLatestBlockNumber(tx)
+		latest, err = rpchelper.GetLatestBlockNumberWithReceipts(tx)
 		//to fetch latest
 		latest += 1
 		if err != nil {
@@ -394,7 +394,11 @@ func (api *ErigonImpl) GetBlockReceiptsByBlockHash(ctx context.Context, cannonic
 	result := make([]map[string]interface{}, 0, len(receipts))
 	for _, receipt := range receipts {
 		txn := block.Transactions()[receipt.TransactionIndex]
-		result = append(result, marshalReceipt(receipt, txn, chainConfig, block.HeaderNoCopy(), txn.Hash(), true))
+		fields, err := marshalReceipt(receipt, txn, chainConfig, block.HeaderNoCopy(), txn.Hash(), true)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fields)
 	}
 
 	if chainConfig.Bor != nil {
@@ -405,7 +409,11 @@ func (api *ErigonImpl) GetBlockReceiptsByBlockHash(ctx context.Context, cannonic
 				return nil, err
 			}
 			if borReceipt != nil {
-				result = append(result, marshalReceipt(borReceipt, borTx, chainConfig, block.HeaderNoCopy(), borReceipt.TxHash, false))
+				fields, err := marshalReceipt(borReceipt, borTx, chainConfig, block.HeaderNoCopy(), borReceipt.TxHash, false)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, fields)
 			}
 		}
 	}
@@ -34,7 +34,7 @@ func TestGetLogs(t *testing.T) {
 	agg := m.HistoryV3Components()
 	baseApi := NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
 	{
-		ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000)
+		ethApi := NewEthAPI(baseApi, m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
 
 		logs, err := ethApi.GetLogs(context.Background(), filters.FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(10)})
 		assert.NoError(err)
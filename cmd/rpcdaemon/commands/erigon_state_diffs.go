@@ -0,0 +1,294 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/order"
+	"github.com/ledgerwatch/erigon-lib/kv/rawdbv3"
+
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state/historyv2read"
+	"github.com/ledgerwatch/erigon/core/state/temporal"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// BlockStateDiffs is an element of the `erigon_getStateDiffsInBlockRange` response: the accounts changed by a block
+type BlockStateDiffs struct {
+	BlockNumber hexutil.Uint64                        `json:"blockNumber"`
+	BlockHash   common.Hash                           `json:"blockHash"`
+	Accounts    map[common.Address]*AccountStateDiffs `json:"accounts"`
+}
+
+// AccountStateDiffs holds the values of an account before and after a block. The balances of an account which
+// doesn't exist are null. The code is only present when it was changed by the block.
+type AccountStateDiffs struct {
+	Balance StateDiffBalance                  `json:"balance"`
+	Nonce   StateDiffNonce                    `json:"nonce"`
+	Code    *StateDiffCode                    `json:"code,omitempty"`
+	Storage map[common.Hash]*StateDiffStorage `json:"storage"`
+}
+
+// accountChanges are the values of an account changed by a block, as they were before it
+type accountChanges struct {
+	changed bool              // false when only the storage of the account was changed
+	account *accounts.Account // nil if the account didn't exist
+	storage map[common.Hash]common.Hash
+}
+
+// stateChanges are the accounts changed by a block
+type stateChanges map[common.Address]*accountChanges
+
+func (s stateChanges) get(addr common.Address) *accountChanges {
+	c, ok := s[addr]
+	if !ok {
+		c = &accountChanges{storage: make(map[common.Hash]common.Hash)}
+		s[addr] = c
+	}
+	return c
+}
+
+// GetStateDiffsInBlockRange implements erigon_getStateDiffsInBlockRange. Streams, for every block of [fromBlock, toBlock],
+// the balance, nonce, code and storage slots of the accounts changed by the block, with their values before and after it.
+// The values before the block are read from the account and storage changesets, so no block is re-executed.
+func (api *ErigonImpl) GetStateDiffsInBlockRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return err
+	}
+	from, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(fromBlock), tx, api.filters)
+	if err != nil {
+		return err
+	}
+	to, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(toBlock), tx, api.filters)
+	if err != nil {
+		return err
+	}
+	if from > to {
+		return fmt.Errorf("invalid parameters: fromBlock (%d) cannot be greater than toBlock (%d)", from, to)
+	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if to > executed {
+		return fmt.Errorf("toBlock (%d) is later than the latest executed block (%d)", to, executed)
+	}
+	if err = api.checkPruneHistory(tx, from); err != nil {
+		return err
+	}
+	historyV3 := api.historyV3(tx)
+
+	stream.WriteArrayStart()
+	for blockNum := from; blockNum <= to; blockNum++ {
+		if blockNum > from {
+			stream.WriteMore()
+		}
+		diffs, err := api.blockStateDiffs(ctx, tx, blockNum, blockNum == executed, historyV3, chainConfig.ChainName)
+		if err == nil {
+			var b []byte
+			if b, err = json.Marshal(diffs); err == nil {
+				stream.Write(b)
+			}
+		}
+		if err != nil {
+			stream.WriteObjectStart()
+			rpc.HandleError(err, stream)
+			stream.WriteObjectEnd()
+		}
+		if err = stream.Flush(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+	stream.WriteArrayEnd()
+	return stream.Flush()
+}
+
+func (api *ErigonImpl) blockStateDiffs(ctx context.Context, tx kv.Tx, blockNum uint64, latest, historyV3 bool, chainName string) (*BlockStateDiffs, error) {
+	hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	var changes stateChanges
+	if historyV3 {
+		changes, err = stateChangesV3(tx.(kv.TemporalTx), blockNum)
+	} else {
+		changes, err = stateChangesV2(tx, blockNum)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	beforeReader, err := rpchelper.CreateHistoryStateReader(tx, blockNum, 0, historyV3, chainName)
+	if err != nil {
+		return nil, err
+	}
+	afterReader, err := rpchelper.CreateStateReaderFromBlockNumber(ctx, tx, blockNum, latest, 0, api.stateCache, historyV3, chainName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BlockStateDiffs{
+		BlockNumber: hexutil.Uint64(blockNum),
+		BlockHash:   hash,
+		Accounts:    make(map[common.Address]*AccountStateDiffs, len(changes)),
+	}
+	for addr, c := range changes {
+		after, err := afterReader.ReadAccountData(addr)
+		if err != nil {
+			return nil, err
+		}
+		before := c.account
+		if !c.changed {
+			before = after
+		}
+		if before == nil && after == nil && len(c.storage) == 0 {
+			// touched empty accounts are recorded in the history of erigon3, not in the changesets
+			continue
+		}
+
+		diff := &AccountStateDiffs{Storage: make(map[common.Hash]*StateDiffStorage, len(c.storage))}
+		if before != nil {
+			diff.Balance.From = (*hexutil.Big)(before.Balance.ToBig())
+			diff.Nonce.From = hexutil.Uint64(before.Nonce)
+		}
+		if after != nil {
+			diff.Balance.To = (*hexutil.Big)(after.Balance.ToBig())
+			diff.Nonce.To = hexutil.Uint64(after.Nonce)
+		}
+		if beforeCodeHash, afterCodeHash := codeHashOf(before), codeHashOf(after); beforeCodeHash != afterCodeHash {
+			diff.Code = &StateDiffCode{}
+			if before != nil && beforeCodeHash != (common.Hash{}) {
+				if diff.Code.From, err = beforeReader.ReadAccountCode(addr, before.Incarnation, beforeCodeHash); err != nil {
+					return nil, err
+				}
+			}
+			if after != nil && afterCodeHash != (common.Hash{}) {
+				if diff.Code.To, err = afterReader.ReadAccountCode(addr, after.Incarnation, afterCodeHash); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for loc, value := range c.storage {
+			slot := &StateDiffStorage{From: value}
+			if after != nil {
+				loc := loc
+				enc, err := afterReader.ReadAccountStorage(addr, after.Incarnation, &loc)
+				if err != nil {
+					return nil, err
+				}
+				slot.To = common.BytesToHash(enc)
+			}
+			diff.Storage[loc] = slot
+		}
+		result.Accounts[addr] = diff
+	}
+	return result, nil
+}
+
+// codeHashOf returns the code hash of the account, or the zero hash if it has no code
+func codeHashOf(acc *accounts.Account) common.Hash {
+	if acc == nil || acc.IsEmptyCodeHash() {
+		return common.Hash{}
+	}
+	return acc.CodeHash
+}
+
+// stateChangesV2 returns the accounts changed by the block, as they were before it, from the changesets
+func stateChangesV2(tx kv.Tx, blockNum uint64) (stateChanges, error) {
+	changes := make(stateChanges)
+	if err := changeset.ForRange(tx, kv.AccountChangeSet, blockNum, blockNum+1, func(_ uint64, k, v []byte) error {
+		c := changes.get(common.BytesToAddress(k))
+		c.changed = true
+		if len(v) == 0 {
+			return nil
+		}
+		v, err := historyv2read.RestoreCodeHash(tx, k, v, nil)
+		if err != nil {
+			return err
+		}
+		c.account = new(accounts.Account)
+		return c.account.DecodeForStorage(v)
+	}); err != nil {
+		return nil, err
+	}
+	// The keys are address, incarnation and location: the slots of the incarnation which existed before the block come first
+	if err := changeset.ForRange(tx, kv.StorageChangeSet, blockNum, blockNum+1, func(_ uint64, k, v []byte) error {
+		c := changes.get(common.BytesToAddress(k[:length.Addr]))
+		loc := common.BytesToHash(k[length.Addr+length.Incarnation:])
+		if _, ok := c.storage[loc]; !ok {
+			c.storage[loc] = common.BytesToHash(v)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// stateChangesV3 returns the accounts changed by the block, as they were before it, from the history of the temporal DB
+func stateChangesV3(tx kv.TemporalTx, blockNum uint64) (stateChanges, error) {
+	fromTxNum, err := rawdbv3.TxNums.Min(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	toTxNum, err := rawdbv3.TxNums.Max(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(stateChanges)
+	it, err := tx.HistoryRange(temporal.AccountsHistory, int(fromTxNum), int(toTxNum+1), order.Asc, -1)
+	if err != nil {
+		return nil, err
+	}
+	for it.HasNext() {
+		k, v, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		c := changes.get(common.BytesToAddress(k))
+		c.changed = true
+		if len(v) == 0 {
+			continue
+		}
+		c.account = new(accounts.Account)
+		if err = accounts.DeserialiseV3(c.account, v); err != nil {
+			return nil, err
+		}
+	}
+	it, err = tx.HistoryRange(temporal.StorageHistory, int(fromTxNum), int(toTxNum+1), order.Asc, -1)
+	if err != nil {
+		return nil, err
+	}
+	for it.HasNext() {
+		k, v, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		c := changes.get(common.BytesToAddress(k[:length.Addr]))
+		c.storage[common.BytesToHash(k[length.Addr:])] = common.BytesToHash(v)
+	}
+	return changes, nil
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+)
+
+func TestGetStateDiffsInBlockRange(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
+	api := NewErigonAPI(base, m.DB, nil)
+	debugAPI := NewPrivateDebugAPI(base, m.DB, 0, m.Dirs.Tmp)
+
+	stateDiffs := func(from, to rpc.BlockNumber) ([]BlockStateDiffs, error) {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		if err := api.GetStateDiffsInBlockRange(m.Ctx, from, to, stream); err != nil {
+			return nil, err
+		}
+		require.NoError(t, stream.Flush())
+		var result []BlockStateDiffs
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+		return result, nil
+	}
+
+	t.Run("changes chain up", func(t *testing.T) {
+		tx, err := m.DB.BeginRo(m.Ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		result, err := stateDiffs(0, 10)
+		require.NoError(t, err)
+		require.Equal(t, 11, len(result))
+
+		balances := map[common.Address]*hexutil.Big{}
+		slots := map[common.Address]map[common.Hash]common.Hash{}
+		var deployed bool
+		for i, block := range result {
+			require.Equal(t, uint64(i), uint64(block.BlockNumber))
+			hash, err := rawdb.ReadCanonicalHash(tx, uint64(i))
+			require.NoError(t, err)
+			require.Equal(t, hash, block.BlockHash)
+
+			n := rpc.BlockNumber(i)
+			modified, err := debugAPI.GetModifiedAccountsByNumber(m.Ctx, n, nil)
+			require.NoError(t, err)
+			for addr := range block.Accounts {
+				require.Contains(t, modified, addr, "block %d", i)
+			}
+
+			for addr, diff := range block.Accounts {
+				if last, ok := balances[addr]; ok {
+					require.Equal(t, last, diff.Balance.From, "balance of %x in block %d", addr, i)
+				}
+				balances[addr] = diff.Balance.To
+				if diff.Code != nil && len(diff.Code.To) > 0 {
+					deployed = true
+				}
+				if slots[addr] == nil {
+					slots[addr] = map[common.Hash]common.Hash{}
+				}
+				for loc, slot := range diff.Storage {
+					require.Equal(t, slots[addr][loc], slot.From, "slot %x of %x in block %d", loc, addr, i)
+					slots[addr][loc] = slot.To
+				}
+			}
+		}
+		require.True(t, deployed)
+	})
+	t.Run("genesis", func(t *testing.T) {
+		result, err := stateDiffs(0, 0)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(result))
+		diff, ok := result[0].Accounts[common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")]
+		require.True(t, ok)
+		require.Equal(t, "0x7ce66c50e2840000", diff.Balance.To.String())
+	})
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := stateDiffs(2, 1)
+		require.Error(t, err)
+
+		_, err = stateDiffs(0, 1_000_000)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+// SyncStage is the state of a stage of the staged sync
+type SyncStage struct {
+	Stage            string            `json:"stage"`
+	Running          bool              `json:"running"`
+	CurrentBlock     hexutil.Uint64    `json:"currentBlock"`
+	TargetBlock      hexutil.Uint64    `json:"targetBlock"`
+	ItemsPerSecond   float64           `json:"itemsPerSecond"`
+	EtaSeconds       *float64          `json:"etaSeconds,omitempty"`
+	LastUnwindPoint  *hexutil.Uint64   `json:"lastUnwindPoint,omitempty"`
+	LastUnwindTime   *time.Time        `json:"lastUnwindTime,omitempty"`
+	LastUnwindReason string            `json:"lastUnwindReason,omitempty"`
+	History          []ThroughputPoint `json:"history,omitempty"`
+}
+
+// ThroughputPoint is a sample of the throughput of a stage
+type ThroughputPoint struct {
+	Time           time.Time      `json:"time"`
+	Block          hexutil.Uint64 `json:"block"`
+	ItemsPerSecond float64        `json:"itemsPerSecond"`
+}
+
+// SyncStages implements erigon_syncStages. Returns the state of the stages of the staged sync, in the order they run.
+// When the RPC daemon doesn't run in the same process as the sync, only the progress of the stages is known,
+// as it is read from the database.
+func (api *ErigonImpl) SyncStages(ctx context.Context) ([]SyncStage, error) {
+	if status := stagedsync.PublishedStatus(); status != nil {
+		if statuses := status.Stages(); statuses != nil {
+			res := make([]SyncStage, len(statuses))
+			for i, st := range statuses {
+				res[i] = syncStageFromStatus(st)
+			}
+			return res, nil
+		}
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res := make([]SyncStage, len(stages.AllStages))
+	var target uint64
+	for i, stage := range stages.AllStages {
+		progress, err := stages.GetStageProgress(tx, stage)
+		if err != nil {
+			return nil, err
+		}
+		if progress > target {
+			target = progress
+		}
+		res[i] = SyncStage{Stage: string(stage), CurrentBlock: hexutil.Uint64(progress), TargetBlock: hexutil.Uint64(target)}
+	}
+	return res, nil
+}
+
+func syncStageFromStatus(st stagedsync.StageStatus) SyncStage {
+	res := SyncStage{
+		Stage:            string(st.Stage),
+		Running:          st.Running,
+		CurrentBlock:     hexutil.Uint64(st.CurrentBlock),
+		TargetBlock:      hexutil.Uint64(st.TargetBlock),
+		ItemsPerSecond:   st.ItemsPerSecond,
+		LastUnwindReason: st.LastUnwindReason,
+	}
+	if st.Eta != nil {
+		eta := st.Eta.Seconds()
+		res.EtaSeconds = &eta
+	}
+	if st.LastUnwindPoint != nil {
+		unwindPoint := hexutil.Uint64(*st.LastUnwindPoint)
+		unwindTime := st.LastUnwindTime
+		res.LastUnwindPoint, res.LastUnwindTime = &unwindPoint, &unwindTime
+	}
+	for _, sample := range st.History {
+		res.History = append(res.History, ThroughputPoint{Time: sample.Time, Block: hexutil.Uint64(sample.Block), ItemsPerSecond: sample.ItemsPerSecond})
+	}
+	return res
+}
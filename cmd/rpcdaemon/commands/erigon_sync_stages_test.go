@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+)
+
+func TestSyncStages(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	agg := m.HistoryV3Components()
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	base := NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
+	api := NewErigonAPI(base, m.DB, nil)
+
+	tx, err := m.DB.BeginRo(m.Ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	head, err := stages.GetStageProgress(tx, stages.Finish)
+	require.NoError(t, err)
+	require.NotZero(t, head)
+
+	check := func(result []SyncStage) {
+		byStage := map[string]SyncStage{}
+		for _, st := range result {
+			byStage[st.Stage] = st
+		}
+		for _, stage := range []stages.SyncStage{stages.Headers, stages.Execution, stages.Finish} {
+			st, ok := byStage[string(stage)]
+			require.True(t, ok, stage)
+			require.False(t, st.Running, stage)
+			require.Equal(t, head, uint64(st.CurrentBlock), stage)
+			require.Equal(t, head, uint64(st.TargetBlock), stage)
+		}
+	}
+
+	// Without the sync running in the same process, the progress is read from the database
+	result, err := api.SyncStages(m.Ctx)
+	require.NoError(t, err)
+	require.Equal(t, len(stages.AllStages), len(result))
+	check(result)
+
+	m.Sync.PublishStatus()
+	result, err = api.SyncStages(m.Ctx)
+	require.NoError(t, err)
+	require.Equal(t, m.Sync.Len(), len(result))
+	check(result)
+}
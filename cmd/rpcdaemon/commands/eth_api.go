@@ -14,10 +14,12 @@ import (
 
 	"github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcfg"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	libstate "github.com/ledgerwatch/erigon-lib/state"
 	types2 "github.com/ledgerwatch/erigon-lib/types"
 
@@ -28,12 +30,15 @@ import (
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	ethFilters "github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/rpc"
 	ethapi2 "github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/log/v3"
+	"golang.org/x/sync/semaphore"
 )
 
 // EthAPI is a collection of functions that are exposed in the
@@ -92,8 +97,9 @@ type EthAPI interface {
 	SendTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
 	Sign(ctx context.Context, _ common.Address, _ hexutil.Bytes) (hexutil.Bytes, error)
 	SignTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
-	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error)
+	GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi2.AccountResult, error)
 	CreateAccessList(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, optimizeGas *bool) (*accessListResult, error)
+	SimulateV1(ctx context.Context, opts SimulationOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) // see ./eth_simulation.go
 
 	// Mining related (see ./eth_mining.go)
 	Coinbase(ctx context.Context) (common.Address, error)
@@ -119,8 +125,14 @@ type BaseAPI struct {
 	_engine      consensus.EngineReader
 
 	evmCallTimeout time.Duration
+	trieRewinds    *semaphore.Weighted // bounds the state trie rewinds of stateTrieAt running at once
 }
 
+// maxConcurrentTrieRewinds is how many state trie rewinds can run at once. A rewind unwinds the hashed state
+// and the intermediate trie hashes of every block it goes back over into memory, instead of rebuilding only
+// the paths of the proven keys, so their number is bounded to keep the memory of the daemon in check.
+const maxConcurrentTrieRewinds = 2
+
 func NewBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader services.FullBlockReader, agg *libstate.AggregatorV3, singleNodeMode bool, evmCallTimeout time.Duration, engine consensus.EngineReader) *BaseAPI {
 	blocksLRUSize := 128 // ~32Mb
 	if !singleNodeMode {
@@ -131,7 +143,7 @@ func NewBaseApi(f *rpchelper.Filters, stateCache kvcache.Cache, blockReader serv
 		panic(err)
 	}
 
-	return &BaseAPI{filters: f, stateCache: stateCache, blocksLRU: blocksLRU, _blockReader: blockReader, _txnReader: blockReader, _agg: agg, evmCallTimeout: evmCallTimeout, _engine: engine}
+	return &BaseAPI{filters: f, stateCache: stateCache, blocksLRU: blocksLRU, _blockReader: blockReader, _txnReader: blockReader, _agg: agg, evmCallTimeout: evmCallTimeout, _engine: engine, trieRewinds: semaphore.NewWeighted(maxConcurrentTrieRewinds)}
 }
 
 func (api *BaseAPI) chainConfig(tx kv.Tx) (*chain.Config, error) {
@@ -291,6 +303,95 @@ func (api *BaseAPI) checkPruneHistory(tx kv.Tx, block uint64) error {
 	return nil
 }
 
+// unwindBatch is a batch whose cursors never report a last key lower than the one of the underlying tx.
+// The cursors of the batch report no last key when the last key of the tx was deleted in the batch, after
+// which the ETL loads of the stages append to the table and skip their deletions.
+type unwindBatch struct {
+	*memdb.MemoryMutation
+	tx kv.Tx
+}
+
+func (b *unwindBatch) RwCursor(table string) (kv.RwCursor, error) {
+	c, err := b.MemoryMutation.RwCursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return &unwindBatchCursor{c, b.tx, table}, nil
+}
+
+type unwindBatchCursor struct {
+	kv.RwCursor
+	tx    kv.Tx
+	table string
+}
+
+func (c *unwindBatchCursor) Last() ([]byte, []byte, error) {
+	k, v, err := c.RwCursor.Last()
+	if err != nil {
+		return nil, nil, err
+	}
+	txCursor, err := c.tx.Cursor(c.table)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer txCursor.Close()
+	txK, txV, err := txCursor.Last()
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Compare(txK, k) > 0 {
+		return txK, txV, nil
+	}
+	return k, v, nil
+}
+
+// stateTrieAt returns a view of tx where the hashed state and the intermediate trie hashes are the ones of the
+// given block, which are rewound in memory, by at most maxRewind blocks, when the block isn't the head of the
+// state trie. At most maxConcurrentTrieRewinds rewinds run at once, the others waiting for one of them to be
+// released. The returned function releases the view.
+func (api *BaseAPI) stateTrieAt(ctx context.Context, tx kv.Tx, blockNum uint64, maxRewind int, tmpDir string) (kv.Tx, func(), error) {
+	trieProgress, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if blockNum > trieProgress {
+		return nil, nil, fmt.Errorf("state trie of block %d isn't available yet, it is at block %d", blockNum, trieProgress)
+	}
+	if blockNum == trieProgress {
+		return tx, func() {}, nil
+	}
+	if trieProgress-blockNum > uint64(maxRewind) {
+		return nil, nil, fmt.Errorf("state trie of block %d is too old, it must be within %d blocks of the head of the state trie (currently %d)", blockNum, maxRewind, trieProgress)
+	}
+	hashStateProgress, err := stages.GetStageProgress(tx, stages.HashState)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = api.trieRewinds.Acquire(ctx, 1); err != nil {
+		return nil, nil, err
+	}
+	historyV3 := api.historyV3(tx)
+	batch := memdb.NewMemoryBatch(tx, tmpDir)
+	release := func() {
+		batch.Rollback()
+		api.trieRewinds.Release(1)
+	}
+	hashStateCfg := stagedsync.StageHashStateCfg(nil, datadir.Dirs{Tmp: tmpDir}, historyV3, api._agg)
+	unwind := &stagedsync.UnwindState{ID: stages.HashState, UnwindPoint: blockNum}
+	if err = stagedsync.UnwindHashStateStage(unwind, &stagedsync.StageState{ID: stages.HashState, BlockNumber: hashStateProgress}, batch, hashStateCfg, ctx); err != nil {
+		release()
+		return nil, nil, err
+	}
+	trieCfg := stagedsync.StageTrieCfg(nil, false, true, false, tmpDir, api._blockReader, nil, historyV3, api._agg)
+	unwind = &stagedsync.UnwindState{ID: stages.IntermediateHashes, UnwindPoint: blockNum}
+	if err = stagedsync.UnwindIntermediateHashesStage(unwind, &stagedsync.StageState{ID: stages.IntermediateHashes, BlockNumber: trieProgress}, &unwindBatch{batch, tx}, trieCfg, ctx); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return batch, release, nil
+}
+
 func (api *BaseAPI) pruneMode(tx kv.Tx) (*prune.Mode, error) {
 	p := api._pruneMode.Load()
 	if p != nil {
@@ -317,10 +418,13 @@ type APIImpl struct {
 	db              kv.RoDB
 	GasCap          uint64
 	ReturnDataLimit int
+
+	MaxGetProofRewindBlockCount int
+	tmpDir                      string
 }
 
 // NewEthAPI returns APIImpl instance
-func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, gascap uint64, returnDataLimit int) *APIImpl {
+func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, gascap uint64, returnDataLimit int, maxGetProofRewindBlockCount int, tmpDir string) *APIImpl {
 	if gascap == 0 {
 		gascap = uint64(math.MaxUint64 / 2)
 	}
@@ -334,6 +438,9 @@ func NewEthAPI(base *BaseAPI, db kv.RoDB, eth rpchelper.ApiBackend, txPool txpoo
 		gasCache:        NewGasPriceCache(),
 		GasCap:          gascap,
 		ReturnDataLimit: returnDataLimit,
+
+		MaxGetProofRewindBlockCount: maxGetProofRewindBlockCount,
+		tmpDir:                      tmpDir,
 	}
 }
 
@@ -358,6 +465,9 @@ type RPCTransaction struct {
 	V                *hexutil.Big       `json:"v"`
 	R                *hexutil.Big       `json:"r"`
 	S                *hexutil.Big       `json:"s"`
+
+	MaxFeePerBlobGas    *hexutil.Big  `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes []common.Hash `json:"blobVersionedHashes,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -413,6 +523,25 @@ func newRPCTransaction(tx types.Transaction, blockHash common.Hash, blockNumber
 		} else {
 			result.GasPrice = nil
 		}
+	case *types.BlobTx:
+		chainId.Set(t.ChainID)
+		result.ChainID = (*hexutil.Big)(chainId.ToBig())
+		result.Tip = (*hexutil.Big)(t.Tip.ToBig())
+		result.FeeCap = (*hexutil.Big)(t.FeeCap.ToBig())
+		result.V = (*hexutil.Big)(t.V.ToBig())
+		result.R = (*hexutil.Big)(t.R.ToBig())
+		result.S = (*hexutil.Big)(t.S.ToBig())
+		result.Accesses = &t.AccessList
+		result.MaxFeePerBlobGas = (*hexutil.Big)(t.MaxFeePerBlobGas.ToBig())
+		result.BlobVersionedHashes = t.BlobVersionedHashes
+		baseFee, overflow := uint256.FromBig(baseFee)
+		if baseFee != nil && !overflow && blockHash != (common.Hash{}) {
+			// price = min(tip + baseFee, gasFeeCap)
+			price := math.Min256(new(uint256.Int).Add(tx.GetTip(), baseFee), tx.GetFeeCap())
+			result.GasPrice = (*hexutil.Big)(price.ToBig())
+		} else {
+			result.GasPrice = nil
+		}
 	}
 	signer := types.LatestSignerForChainID(chainId.ToBig())
 	result.From, _ = tx.Sender(*signer)
@@ -458,6 +587,10 @@ func newRPCPendingTransaction(tx types.Transaction, current *types.Header, confi
 	if current != nil {
 		baseFee = misc.CalcBaseFee(config, current)
 	}
+	// Blob transactions are kept in the pool with their blobs, which aren't returned
+	if wrapper, ok := tx.(*types.BlobTxWrapper); ok {
+		tx = wrapper.Unwrap()
+	}
 	return newRPCTransaction(tx, common.Hash{}, 0, 0, baseFee)
 }
 
@@ -8,6 +8,7 @@ import (
 
 	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -15,6 +16,7 @@ import (
 	"github.com/ledgerwatch/log/v3"
 	"google.golang.org/grpc"
 
+	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/state"
@@ -28,12 +30,33 @@ import (
 	ethapi2 "github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/erigon/turbo/trie"
 )
 
 var latestNumOrHash = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 
 // Call implements eth_call. Executes a new message call immediately without creating a transaction on the block chain.
 func (api *APIImpl) Call(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *ethapi2.StateOverrides) (hexutil.Bytes, error) {
+	result, err := api.doCall(ctx, args, blockNrOrHash, overrides)
+	if err != nil || result == nil {
+		return nil, err
+	}
+
+	if len(result.ReturnData) > api.ReturnDataLimit {
+		return nil, fmt.Errorf("call retuned result on length %d exceeding limit %d", len(result.ReturnData), api.ReturnDataLimit)
+	}
+
+	// If the result contains a revert reason, try to unpack and return it.
+	if len(result.Revert()) > 0 {
+		return nil, ethapi2.NewRevertError(result)
+	}
+
+	return result.Return(), result.Err
+}
+
+// doCall executes a message call at the state of the given block, returning the result of the
+// execution whether it failed or not. The result is nil when the block is unknown.
+func (api *APIImpl) doCall(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *ethapi2.StateOverrides) (*core.ExecutionResult, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -67,21 +90,7 @@ func (api *APIImpl) Call(ctx context.Context, args ethapi2.CallArgs, blockNrOrHa
 		return nil, err
 	}
 	header := block.HeaderNoCopy()
-	result, err := transactions.DoCall(ctx, engine, args, tx, blockNrOrHash, header, overrides, api.GasCap, chainConfig, stateReader, api._blockReader, api.evmCallTimeout)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(result.ReturnData) > api.ReturnDataLimit {
-		return nil, fmt.Errorf("call retuned result on length %d exceeding limit %d", len(result.ReturnData), api.ReturnDataLimit)
-	}
-
-	// If the result contains a revert reason, try to unpack and return it.
-	if len(result.Revert()) > 0 {
-		return nil, ethapi2.NewRevertError(result)
-	}
-
-	return result.Return(), result.Err
+	return transactions.DoCall(ctx, engine, args, tx, blockNrOrHash, header, overrides, api.GasCap, chainConfig, stateReader, api._blockReader, api.evmCallTimeout)
 }
 
 // headerByNumberOrHash - intent to read recent headers only, tries from the lru cache before reading from the db
@@ -301,10 +310,115 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs
 	return hexutil.Uint64(hi), nil
 }
 
-// GetProof not implemented
-func (api *APIImpl) GetProof(ctx context.Context, address libcommon.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error) {
-	var stub interface{}
-	return &stub, fmt.Errorf(NotImplemented, "eth_getProof")
+// GetProof implements eth_getProof. Returns the account and storage values of the given address, along with their
+// Merkle proofs against the state root of the given block. Proofs at past blocks are built on the state trie
+// rewound in memory, within the history kept by the node.
+func (api *APIImpl) GetProof(ctx context.Context, address libcommon.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi2.AccountResult, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	blockNumber, hash, latest, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	header, err := api._blockReader.Header(ctx, tx, hash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", blockNumber)
+	}
+	if !latest {
+		if err = api.checkPruneHistory(tx, blockNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	reader, err := rpchelper.CreateHistoryStateReader(tx, blockNumber+1, 0, api.historyV3(tx), chainConfig.ChainName)
+	if err != nil {
+		return nil, err
+	}
+	account, err := reader.ReadAccountData(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addrHash := crypto.Keccak256Hash(address[:])
+	keyHashes := make([]libcommon.Hash, len(storageKeys))
+	loadList := trie.NewRetainList(0)
+	loadList.AddKey(addrHash[:])
+	for i, key := range storageKeys {
+		keyHashes[i] = crypto.Keccak256Hash(libcommon.HexToHash(key).Bytes())
+		if account != nil && account.Incarnation > 0 {
+			loadList.AddKey(dbutils.GenerateCompositeStorageKey(addrHash, account.Incarnation, keyHashes[i]))
+		}
+	}
+
+	stateTx, release, err := api.stateTrieAt(ctx, tx, blockNumber, api.MaxGetProofRewindBlockCount, api.tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	loader := trie.NewFlatDBTrieLoader("eth_getProof")
+	if err = loader.Reset(loadList, nil, nil, false); err != nil {
+		return nil, err
+	}
+	t, err := loader.CalcTrie(stateTx, ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if root := t.Hash(); root != header.Root {
+		return nil, fmt.Errorf("state root %x doesn't match the one of block %d: %x", root, blockNumber, header.Root)
+	}
+
+	accountProof, err := t.Prove(addrHash[:], 0, false)
+	if err != nil {
+		return nil, err
+	}
+	result := &ethapi2.AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		CodeHash:     trie.EmptyCodeHash,
+		StorageHash:  trie.EmptyRoot,
+		StorageProof: make([]ethapi2.StorageResult, len(storageKeys)),
+	}
+	if account != nil {
+		result.Balance = (*hexutil.Big)(account.Balance.ToBig())
+		result.Nonce = hexutil.Uint64(account.Nonce)
+		result.CodeHash = account.CodeHash
+		if _, ok := t.GetAccount(addrHash[:]); ok {
+			_, result.StorageHash = t.DeepHash(addrHash[:])
+		}
+	}
+	for i, key := range storageKeys {
+		storageKey := dbutils.GenerateCompositeTrieKey(addrHash, keyHashes[i])
+		proof, err := t.Prove(storageKey, 64, true)
+		if err != nil {
+			return nil, err
+		}
+		value := new(big.Int)
+		if v, ok := t.Get(storageKey); ok {
+			value.SetBytes(v)
+		}
+		result.StorageProof[i] = ethapi2.StorageResult{Key: key, Value: (*hexutil.Big)(value), Proof: toHexSlice(proof)}
+	}
+	return result, nil
+}
+
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutility.Encode(b[i])
+	}
+	return r
 }
 
 func (api *APIImpl) tryBlockFromLru(hash libcommon.Hash) *types.Block {
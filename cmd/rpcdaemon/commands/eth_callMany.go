@@ -33,6 +33,7 @@ type BlockOverrides struct {
 	Difficulty  *hexutil.Uint
 	BaseFee     *uint256.Int
 	BlockHash   *map[uint64]common.Hash
+	Withdrawals *[]*types.Withdrawal // Only processed by eth_simulateV1
 }
 
 type Bundle struct {
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/big"
@@ -8,18 +9,22 @@ import (
 	"time"
 
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/common/length"
 
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
 
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/core"
@@ -28,10 +33,12 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/ledgerwatch/erigon/turbo/trie"
 )
 
 func TestEstimateGas(t *testing.T) {
@@ -42,7 +49,7 @@ func TestEstimateGas(t *testing.T) {
 	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
 	mining := txpool.NewMiningClient(conn)
 	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
-	api := NewEthAPI(NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000)
+	api := NewEthAPI(NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
 	var from = libcommon.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
 	var to = libcommon.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
 	if _, err := api.EstimateGas(context.Background(), &ethapi.CallArgs{
@@ -58,7 +65,7 @@ func TestEthCallNonCanonical(t *testing.T) {
 	agg := m.HistoryV3Components()
 	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
 	var from = libcommon.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
 	var to = libcommon.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
 	if _, err := api.Call(context.Background(), ethapi.CallArgs{
@@ -83,7 +90,7 @@ func TestEthCallToPrunedBlock(t *testing.T) {
 	agg := m.HistoryV3Components()
 
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
 
 	callData := hexutil.MustDecode("0x2e64cec1")
 	callDataBytes := hexutil.Bytes(callData)
@@ -398,3 +405,173 @@ func doPrune(t *testing.T, db kv.RwDB, pruneTo uint64) {
 	err = tx.Commit()
 	assert.NoError(t, err)
 }
+
+func TestGetProof(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000, 100_000, m.Dirs.Tmp)
+	ctx := context.Background()
+
+	// Prove every account and storage slot of the head state at every block, along with a missing account
+	storage := map[libcommon.Address][]string{{0xde, 0xad}: nil}
+	var headers []*types.Header
+	err := m.DB.View(ctx, func(tx kv.Tx) error {
+		head := rawdb.ReadCurrentHeader(tx).Number.Uint64()
+		for blockNum := uint64(0); blockNum <= head; blockNum++ {
+			headers = append(headers, rawdb.ReadHeaderByNumber(tx, blockNum))
+		}
+		return tx.ForEach(kv.PlainState, nil, func(k, v []byte) error {
+			address := libcommon.BytesToAddress(k[:length.Addr])
+			if len(k) > length.Addr {
+				storage[address] = append(storage[address], hexutility.Encode(k[length.Addr+length.Incarnation:]))
+			} else if _, ok := storage[address]; !ok {
+				storage[address] = nil
+			}
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	nStorage := 0
+	for _, header := range headers {
+		blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(header.Number.Int64()))
+		for address, keys := range storage {
+			result, err := api.GetProof(ctx, address, keys, blockNrOrHash)
+			require.NoError(t, err, "block %d, address %x", header.Number, address)
+			balance, err := api.GetBalance(ctx, address, blockNrOrHash)
+			require.NoError(t, err)
+			require.Equal(t, balance.String(), result.Balance.String())
+
+			leaf := verifyTestProof(t, header.Root, address[:], result.AccountProof)
+			if leaf == nil {
+				require.Zero(t, result.Balance.ToInt().Sign())
+				require.Equal(t, trie.EmptyRoot, result.StorageHash)
+			} else {
+				requireTestAccountLeaf(t, leaf, result)
+			}
+			require.Len(t, result.StorageProof, len(keys))
+			for i, key := range keys {
+				value, err := api.GetStorageAt(ctx, address, key, blockNrOrHash)
+				require.NoError(t, err)
+				require.Equal(t, key, result.StorageProof[i].Key)
+				require.Equal(t, libcommon.HexToHash(value).Big().String(), result.StorageProof[i].Value.ToInt().String())
+				leaf := verifyTestProof(t, result.StorageHash, libcommon.HexToHash(key).Bytes(), result.StorageProof[i].Proof)
+				if leaf == nil {
+					require.Zero(t, result.StorageProof[i].Value.ToInt().Sign())
+					continue
+				}
+				content, _, err := rlp.SplitString(leaf)
+				require.NoError(t, err)
+				require.Equal(t, result.StorageProof[i].Value.ToInt().String(), new(big.Int).SetBytes(content).String())
+				nStorage++
+			}
+		}
+	}
+	require.NotZero(t, nStorage)
+
+	// Blocks beyond the rewind limit are refused
+	api.MaxGetProofRewindBlockCount = 1
+	_, err = api.GetProof(ctx, libcommon.Address{0xde, 0xad}, nil, rpc.BlockNumberOrHashWithNumber(0))
+	require.Error(t, err)
+	_, err = api.GetProof(ctx, libcommon.Address{0xde, 0xad}, nil, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	require.NoError(t, err)
+
+	// Rewinds wait for a free slot, proofs of the head of the state trie don't need one
+	api.MaxGetProofRewindBlockCount = 100_000
+	require.NoError(t, api.trieRewinds.Acquire(ctx, maxConcurrentTrieRewinds))
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = api.GetProof(timeoutCtx, libcommon.Address{0xde, 0xad}, nil, rpc.BlockNumberOrHashWithNumber(0))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = api.GetProof(ctx, libcommon.Address{0xde, 0xad}, nil, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	require.NoError(t, err)
+	api.trieRewinds.Release(maxConcurrentTrieRewinds)
+	_, err = api.GetProof(ctx, libcommon.Address{0xde, 0xad}, nil, rpc.BlockNumberOrHashWithNumber(0))
+	require.NoError(t, err)
+}
+
+// verifyTestProof checks the proof of key against root and returns the value of its leaf, nil if the proof
+// proves that the key is absent
+func verifyTestProof(t *testing.T, root libcommon.Hash, key []byte, proof []string) []byte {
+	t.Helper()
+	var path []byte
+	for _, b := range crypto.Keccak256(key) {
+		path = append(path, b>>4, b&0x0f)
+	}
+	want := root[:]
+	for i, enc := range proof {
+		node := common.FromHex(enc)
+		if len(node) >= length.Hash {
+			require.Equal(t, want, crypto.Keccak256(node), "node %d of the proof", i)
+		} else {
+			require.Equal(t, want, node, "node %d of the proof", i)
+		}
+		content, _, err := rlp.SplitList(node)
+		require.NoError(t, err)
+		var items [][]byte
+		for len(content) > 0 {
+			kind, item, rest, err := rlp.Split(content)
+			require.NoError(t, err)
+			if kind == rlp.List { // Embedded node
+				item = content[:len(content)-len(rest)]
+			}
+			items = append(items, item)
+			content = rest
+		}
+		var next []byte
+		switch len(items) {
+		case 17:
+			next = items[path[0]]
+			path = path[1:]
+		case 2:
+			compact := items[0]
+			nibbles := []byte{}
+			if compact[0]&0x10 != 0 {
+				nibbles = append(nibbles, compact[0]&0x0f)
+			}
+			for _, b := range compact[1:] {
+				nibbles = append(nibbles, b>>4, b&0x0f)
+			}
+			if !bytes.HasPrefix(path, nibbles) {
+				require.Equal(t, len(proof)-1, i, "proof continues past the absence of the key")
+				return nil
+			}
+			path = path[len(nibbles):]
+			if compact[0]&0x20 != 0 {
+				require.Empty(t, path)
+				require.Equal(t, len(proof)-1, i, "proof continues past the leaf")
+				return items[1]
+			}
+			next = items[1]
+		default:
+			t.Fatalf("invalid node %d of the proof: %d items", i, len(items))
+		}
+		if len(next) == 0 {
+			require.Equal(t, len(proof)-1, i, "proof continues past the absence of the key")
+			return nil
+		}
+		want = next
+	}
+	require.Empty(t, proof, "proof ends before reaching a leaf")
+	return nil
+}
+
+func requireTestAccountLeaf(t *testing.T, leaf []byte, result *ethapi.AccountResult) {
+	t.Helper()
+	content, _, err := rlp.SplitList(leaf)
+	require.NoError(t, err)
+	nonce, content, err := rlp.SplitUint64(content)
+	require.NoError(t, err)
+	balance, content, err := rlp.SplitString(content)
+	require.NoError(t, err)
+	storageRoot, content, err := rlp.SplitString(content)
+	require.NoError(t, err)
+	codeHash, _, err := rlp.SplitString(content)
+	require.NoError(t, err)
+	require.Equal(t, uint64(result.Nonce), nonce)
+	require.Equal(t, result.Balance.ToInt().String(), new(big.Int).SetBytes(balance).String())
+	require.Equal(t, result.StorageHash[:], storageRoot)
+	require.Equal(t, result.CodeHash[:], codeHash)
+}
@@ -0,0 +1,673 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
+	"github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// maxSimulatedBlocks is the maximum number of blocks simulated by a single eth_simulateV1 request.
+const maxSimulatedBlocks = 256
+
+// simulatedTransferAddress is the address of the logs reporting ETH transfers when TraceTransfers is set,
+// the same as geth.
+var simulatedTransferAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+// simulatedTransferTopic is the topic of the ERC-20 Transfer(address,address,uint256) event.
+var simulatedTransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// SimulatedBlock is a block simulated by eth_simulateV1, its calls being executed in order on top of the
+// state of the previous block with the overrides applied. The state field of state overrides replaces the
+// storage of the account, which is then recreated as for contract creations. The withdrawals of the block
+// overrides are processed after the calls, as the block rewards.
+type SimulatedBlock struct {
+	BlockOverrides *BlockOverrides        `json:"blockOverrides"`
+	StateOverrides *ethapi.StateOverrides `json:"stateOverrides"`
+	Calls          []SimulatedCall        `json:"calls"`
+}
+
+// SimulatedCall is a call of a simulated block. A call may carry the signature of the transaction it makes,
+// which is then checked to be the one of its sender, the transaction of the call having the hash of the
+// signed transaction. Calls without signature are not checked.
+type SimulatedCall struct {
+	ethapi.CallArgs
+	V *hexutil.Big `json:"v"`
+	R *hexutil.Big `json:"r"`
+	S *hexutil.Big `json:"s"`
+}
+
+// SimulationOpts are the options of eth_simulateV1.
+type SimulationOpts struct {
+	BlockStateCalls []SimulatedBlock `json:"blockStateCalls"`
+	// TraceTransfers reports ETH transfers, including the ones of internal calls, as logs of
+	// simulatedTransferAddress with the topics and data of an ERC-20 Transfer event
+	TraceTransfers bool `json:"traceTransfers"`
+	// Validation checks calls as if they were transactions: nonces have to match the state, fees have
+	// to cover the base fee and senders have to afford them. Otherwise, fees may be zero.
+	Validation             bool `json:"validation"`
+	ReturnFullTransactions bool `json:"returnFullTransactions"`
+}
+
+// SimulateV1 implements eth_simulateV1. Simulates a sequence of blocks on top of the given block, returning
+// each simulated block along with the results and receipts of its calls. The numbers skipped by the block
+// number overrides are filled with empty blocks, which are returned too. The state roots of the blocks are
+// computed on the state trie of the given block, rewound in memory like for eth_getProof: simulations on top
+// of blocks more than MaxGetProofRewindBlockCount blocks behind the head of the state trie fail.
+func (api *APIImpl) SimulateV1(ctx context.Context, opts SimulationOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, fmt.Errorf("empty blockStateCalls")
+	}
+	if len(opts.BlockStateCalls) > maxSimulatedBlocks {
+		return nil, fmt.Errorf("too many blocks, got %d, max %d", len(opts.BlockStateCalls), maxSimulatedBlocks)
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(start time.Time) { log.Trace("Executing EVM simulateV1 finished", "runtime", time.Since(start)) }(time.Now())
+
+	blockNum, hash, _, err := rpchelper.GetBlockNumber(*blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := api._blockReader.Header(ctx, tx, hash, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNum, hash)
+	}
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, *blockNrOrHash, 0, api.filters, api.stateCache, api.historyV3(tx), chainConfig.ChainName)
+	if err != nil {
+		return nil, err
+	}
+	engine, ok := api.engine().(consensus.Engine)
+	if !ok {
+		return nil, fmt.Errorf("consensus engine %s can't execute blocks", api.engine().Type())
+	}
+	stateTx, release, err := api.stateTrieAt(ctx, tx, blockNum, api.MaxGetProofRewindBlockCount, api.tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	hashedState := newSimulatedHashedState(stateTx, api.tmpDir)
+	defer hashedState.batch.Rollback()
+
+	var cancel context.CancelFunc
+	if api.evmCallTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, api.evmCallTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	sim := &simulator{
+		api:             api,
+		tx:              tx,
+		chainConfig:     chainConfig,
+		engine:          engine,
+		chainReader:     stagedsync.NewChainReaderImpl(chainConfig, tx, api._blockReader),
+		opts:            opts,
+		ibs:             state.New(stateReader),
+		hashedState:     hashedState,
+		simulatedHashes: make(map[uint64]common.Hash),
+		overrideHashes:  make(map[uint64]common.Hash),
+	}
+	results := make([]map[string]interface{}, 0, len(opts.BlockStateCalls))
+	for i, simBlock := range opts.BlockStateCalls {
+		// The skipped numbers are filled with empty blocks, for BLOCKHASH to see the hashes of all the blocks
+		if overrides := simBlock.BlockOverrides; overrides != nil && overrides.BlockNumber != nil {
+			for uint64(*overrides.BlockNumber) > parent.Number.Uint64()+1 {
+				if len(results) == maxSimulatedBlocks {
+					return nil, fmt.Errorf("block %d: too many blocks with the skipped numbers, max %d", i, maxSimulatedBlocks)
+				}
+				header, err := sim.makeHeader(parent, nil)
+				if err != nil {
+					return nil, fmt.Errorf("block %d: %w", i, err)
+				}
+				result, block, err := sim.simulateBlock(ctx, header, SimulatedBlock{})
+				if err != nil {
+					return nil, fmt.Errorf("block %d: empty block %d: %w", i, header.Number.Uint64(), err)
+				}
+				results = append(results, result)
+				parent = block.Header()
+			}
+		}
+		if len(results) == maxSimulatedBlocks {
+			return nil, fmt.Errorf("block %d: too many blocks with the skipped numbers, max %d", i, maxSimulatedBlocks)
+		}
+
+		header, err := sim.makeHeader(parent, simBlock.BlockOverrides)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		result, block, err := sim.simulateBlock(ctx, header, simBlock)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		results = append(results, result)
+		parent = block.Header()
+	}
+
+	return results, nil
+}
+
+// simulator holds the state shared by the blocks of an eth_simulateV1 request.
+type simulator struct {
+	api         *APIImpl
+	tx          kv.Tx
+	chainConfig *chain.Config
+	engine      consensus.Engine
+	chainReader consensus.ChainHeaderReader
+	opts        SimulationOpts
+	ibs         *state.IntraBlockState
+	hashedState *simulatedHashedState
+	// simulatedHashes are the hashes of the blocks simulated so far, by number
+	simulatedHashes map[uint64]common.Hash
+	// overrideHashes are the hashes set by the BlockHash of block overrides, by number
+	overrideHashes map[uint64]common.Hash
+}
+
+// makeHeader makes the header of the block simulated after parent, filling the fields that are not
+// overridden from the parent. Its roots, bloom and gas used are set once the block is executed.
+func (s *simulator) makeHeader(parent *types.Header, overrides *BlockOverrides) (*types.Header, error) {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 12,
+	}
+	if overrides == nil {
+		overrides = &BlockOverrides{}
+	}
+
+	if overrides.BlockNumber != nil {
+		if uint64(*overrides.BlockNumber) <= parent.Number.Uint64() {
+			return nil, fmt.Errorf("block number %d is not greater than the one of the previous block %d", uint64(*overrides.BlockNumber), parent.Number.Uint64())
+		}
+		header.Number.SetUint64(uint64(*overrides.BlockNumber))
+	}
+	if overrides.Timestamp != nil {
+		if uint64(*overrides.Timestamp) <= parent.Time {
+			return nil, fmt.Errorf("timestamp %d is not greater than the one of the previous block %d", uint64(*overrides.Timestamp), parent.Time)
+		}
+		header.Time = uint64(*overrides.Timestamp)
+	}
+	if overrides.Coinbase != nil {
+		header.Coinbase = *overrides.Coinbase
+	}
+	if overrides.Difficulty != nil {
+		header.Difficulty = big.NewInt(int64(*overrides.Difficulty))
+	}
+	if overrides.GasLimit != nil {
+		header.GasLimit = uint64(*overrides.GasLimit)
+	}
+	if overrides.BaseFee != nil {
+		header.BaseFee = overrides.BaseFee.ToBig()
+	} else if s.chainConfig.IsLondon(header.Number.Uint64()) {
+		if s.opts.Validation {
+			header.BaseFee = misc.CalcBaseFee(s.chainConfig, parent)
+		} else {
+			header.BaseFee = new(big.Int)
+		}
+	}
+	if overrides.BlockHash != nil {
+		for blockNum, hash := range *overrides.BlockHash {
+			s.overrideHashes[blockNum] = hash
+		}
+	}
+
+	return header, nil
+}
+
+func (s *simulator) getHash(n uint64) common.Hash {
+	if hash, ok := s.overrideHashes[n]; ok {
+		return hash
+	}
+	if hash, ok := s.simulatedHashes[n]; ok {
+		return hash
+	}
+	hash, err := rawdb.ReadCanonicalHash(s.tx, n)
+	if err != nil {
+		log.Debug("Can't get block hash by number", "number", n, "only-canonical", true)
+	}
+	return hash
+}
+
+// transfer is the TransferFunc of simulated blocks when TraceTransfers is set, adding a log for every transfer.
+func (s *simulator) transfer(db evmtypes.IntraBlockState, sender, recipient common.Address, amount *uint256.Int, bailout bool, firehoseContext *firehose.Context) {
+	core.Transfer(db, sender, recipient, amount, bailout, firehoseContext)
+	if amount.IsZero() {
+		return
+	}
+
+	value := amount.Bytes32()
+	db.AddLog(&types.Log{
+		Address: simulatedTransferAddress,
+		Topics:  []common.Hash{simulatedTransferTopic, common.BytesToHash(sender.Bytes()), common.BytesToHash(recipient.Bytes())},
+		Data:    value[:],
+	}, firehoseContext)
+}
+
+// simulateBlock executes the calls of the block, then finalizes it as the engine does with the block rewards
+// and the withdrawals, its state root being the one of the state resulting from all the simulated blocks.
+func (s *simulator) simulateBlock(ctx context.Context, header *types.Header, simBlock SimulatedBlock) (map[string]interface{}, *types.Block, error) {
+	if simBlock.StateOverrides != nil {
+		overrides, err := s.recreateAccounts(*simBlock.StateOverrides)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := overrides.Override(s.ibs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	blockNum := header.Number.Uint64()
+	var withdrawals []*types.Withdrawal
+	if s.chainConfig.IsShanghai(header.Time) {
+		withdrawals = []*types.Withdrawal{}
+	}
+	if overrides := simBlock.BlockOverrides; overrides != nil && overrides.Withdrawals != nil {
+		if withdrawals == nil {
+			return nil, nil, fmt.Errorf("withdrawals before Shanghai")
+		}
+		withdrawals = *overrides.Withdrawals
+	}
+	var baseFee *uint256.Int
+	if header.BaseFee != nil {
+		var overflow bool
+		if baseFee, overflow = uint256.FromBig(header.BaseFee); overflow {
+			return nil, nil, fmt.Errorf("header.BaseFee uint256 overflow")
+		}
+	}
+	blockCtx := core.NewEVMBlockContext(header, s.getHash, s.api.engine(), nil /* author */)
+	if s.opts.TraceTransfers {
+		blockCtx.Transfer = s.transfer
+	}
+	rules := s.chainConfig.Rules(blockNum, header.Time)
+	vmConfig := vm.Config{NoBaseFee: !s.opts.Validation}
+	gp := new(core.GasPool).AddGas(header.GasLimit)
+	syscall := func(contract common.Address, data []byte) ([]byte, error) {
+		return core.SysCallContract(contract, data, *s.chainConfig, s.ibs, header, s.engine, false /* constCall */, firehose.NoOpContext)
+	}
+	s.engine.Initialize(s.chainConfig, s.chainReader, nil, header, s.ibs, nil, nil, syscall, firehose.NoOpContext)
+
+	var (
+		txs      types.Transactions
+		receipts types.Receipts
+		calls    = make([]map[string]interface{}, 0, len(simBlock.Calls))
+	)
+	for i, call := range simBlock.Calls {
+		args := call.CallArgs
+		// Calls default to the gas left in the block
+		if args.Gas == nil && (s.api.GasCap == 0 || gp.Gas() < s.api.GasCap) {
+			gas := hexutil.Uint64(gp.Gas())
+			args.Gas = &gas
+		}
+		msg, err := args.ToMessage(s.api.GasCap, baseFee)
+		if err != nil {
+			return nil, nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		nonce := s.ibs.GetNonce(msg.From())
+		if args.Nonce != nil {
+			nonce = uint64(*args.Nonce)
+		}
+		msg = types.NewMessage(msg.From(), msg.To(), nonce, msg.Value(), msg.Gas(), msg.GasPrice(), msg.FeeCap(), msg.Tip(), msg.Data(), msg.AccessList(), s.opts.Validation /* checkNonce */, false /* isFree */)
+		txn, err := s.simulatedTransaction(call, msg, blockNum)
+		if err != nil {
+			return nil, nil, fmt.Errorf("call %d: %w", i, err)
+		}
+
+		s.ibs.Prepare(txn.Hash(), common.Hash{}, i)
+		// Unsigned calls of distinct senders may have the same hash, their logs being kept together
+		prevLogs := len(s.ibs.GetLogs(txn.Hash()))
+		evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), s.ibs, s.chainConfig, vmConfig, firehose.NoOpContext)
+		go func() {
+			<-ctx.Done()
+			evm.Cancel()
+		}()
+		result, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, !s.opts.Validation /* gasBailout */)
+		if err != nil {
+			return nil, nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		// If the timer caused an abort, return an appropriate error message
+		if evm.Cancelled() {
+			return nil, nil, fmt.Errorf("execution aborted (timeout = %v)", s.api.evmCallTimeout)
+		}
+		if err = s.ibs.FinalizeTx(rules, s.hashedState); err != nil {
+			return nil, nil, err
+		}
+
+		receipt := &types.Receipt{
+			Type:              txn.Type(),
+			CumulativeGasUsed: header.GasLimit - gp.Gas(),
+			TxHash:            txn.Hash(),
+			GasUsed:           result.UsedGas,
+			Logs:              s.ibs.GetLogs(txn.Hash())[prevLogs:],
+			TransactionIndex:  uint(i),
+		}
+		if result.Failed() {
+			receipt.Status = types.ReceiptStatusFailed
+		} else {
+			receipt.Status = types.ReceiptStatusSuccessful
+		}
+		if msg.To() == nil {
+			receipt.ContractAddress = crypto.CreateAddress(msg.From(), nonce)
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		txs = append(txs, txn)
+		receipts = append(receipts, receipt)
+		calls = append(calls, marshalSimulatedCall(result, receipt))
+	}
+
+	header.GasUsed = header.GasLimit - gp.Gas()
+	if _, _, err := s.engine.Finalize(s.chainConfig, header, s.ibs, txs, nil, receipts, withdrawals, nil, s.chainReader, syscall, firehose.NoOpContext); err != nil {
+		return nil, nil, err
+	}
+	if err := s.ibs.FinalizeTx(rules, s.hashedState); err != nil {
+		return nil, nil, err
+	}
+	root, err := s.hashedState.root(ctx.Done())
+	if err != nil {
+		return nil, nil, err
+	}
+	header.Root = root
+	block := types.NewBlock(header, txs, nil, receipts, withdrawals)
+	blockHash := block.Hash()
+	s.simulatedHashes[blockNum] = blockHash
+
+	// Logs are numbered within their block, and their location is only known now
+	var logIndex uint
+	for _, receipt := range receipts {
+		receipt.BlockHash = blockHash
+		receipt.BlockNumber = header.Number
+		for _, l := range receipt.Logs {
+			l.BlockHash = blockHash
+			l.BlockNumber = blockNum
+			l.Index = logIndex
+			logIndex++
+		}
+	}
+
+	result, err := ethapi.RPCMarshalBlock(block, true, s.opts.ReturnFullTransactions, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	marshalledReceipts := make([]map[string]interface{}, 0, len(receipts))
+	for i, receipt := range receipts {
+		fields, err := marshalReceipt(receipt, txs[i], s.chainConfig, block.HeaderNoCopy(), txs[i].Hash(), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields["from"], _ = txs[i].GetSender()
+		marshalledReceipts = append(marshalledReceipts, fields)
+	}
+	result["calls"] = calls
+	result["receipts"] = marshalledReceipts
+
+	return result, block, nil
+}
+
+// recreateAccounts recreates the accounts of the overrides as for contract creations, with a new incarnation
+// and an empty storage, keeping their nonce, balance and code. Accounts are recreated when the state field
+// replaces their storage, instead of setting the fake storage of the state which isn't written to the hashed
+// state, and when they are given code or storage without having an incarnation, their storage being left out
+// of the state root otherwise. The returned overrides set the replaced storage with stateDiff.
+func (s *simulator) recreateAccounts(overrides ethapi.StateOverrides) (ethapi.StateOverrides, error) {
+	recreated := make(ethapi.StateOverrides, len(overrides))
+	for address, account := range overrides {
+		if account.State != nil && account.StateDiff != nil {
+			return nil, fmt.Errorf("account %s has both 'state' and 'stateDiff'", address.Hex())
+		}
+		noIncarnation := s.ibs.GetIncarnation(address) == 0 && (account.Code != nil || account.StateDiff != nil)
+		if account.State != nil || noIncarnation {
+			nonce, code := s.ibs.GetNonce(address), s.ibs.GetCode(address)
+			s.ibs.CreateAccount(address, true /* contractCreation */, firehose.NoOpContext)
+			s.ibs.SetNonce(address, nonce, firehose.NoOpContext)
+			s.ibs.SetCode(address, code, firehose.NoOpContext)
+		}
+		if account.State != nil {
+			account.State, account.StateDiff = nil, account.State
+		}
+		recreated[address] = account
+	}
+	if err := s.ibs.Error(); err != nil {
+		return nil, err
+	}
+	return recreated, nil
+}
+
+// simulatedTransaction makes the transaction of a call. Without signature, its sender is set from the message,
+// otherwise the signature has to be the one of the sender.
+func (s *simulator) simulatedTransaction(call SimulatedCall, msg types.Message, blockNum uint64) (types.Transaction, error) {
+	commonTx := types.CommonTx{
+		Nonce: msg.Nonce(),
+		Gas:   msg.Gas(),
+		To:    msg.To(),
+		Value: msg.Value(),
+		Data:  msg.Data(),
+	}
+	signed := call.V != nil || call.R != nil || call.S != nil
+	if signed {
+		if call.V == nil || call.R == nil || call.S == nil {
+			return nil, fmt.Errorf("incomplete signature, v, r and s are all required")
+		}
+		if call.From == nil {
+			return nil, fmt.Errorf("signed call without sender")
+		}
+		for _, value := range []struct {
+			in  *hexutil.Big
+			out *uint256.Int
+		}{{call.V, &commonTx.V}, {call.R, &commonTx.R}, {call.S, &commonTx.S}} {
+			if overflow := value.out.SetFromBig(value.in.ToInt()); overflow {
+				return nil, fmt.Errorf("signature value higher than 2^256-1")
+			}
+		}
+	}
+
+	chainID, _ := uint256.FromBig(s.chainConfig.ChainID)
+	var txn types.Transaction
+	switch {
+	case s.chainConfig.IsLondon(blockNum) && call.GasPrice == nil:
+		commonTx.ChainID = chainID
+		txn = &types.DynamicFeeTransaction{
+			CommonTx:   commonTx,
+			Tip:        msg.Tip(),
+			FeeCap:     msg.FeeCap(),
+			AccessList: msg.AccessList(),
+		}
+	case call.AccessList != nil:
+		txn = &types.AccessListTx{
+			LegacyTx:   types.LegacyTx{CommonTx: commonTx, GasPrice: msg.GasPrice()},
+			ChainID:    chainID,
+			AccessList: msg.AccessList(),
+		}
+	default:
+		txn = &types.LegacyTx{CommonTx: commonTx, GasPrice: msg.GasPrice()}
+	}
+	if !signed {
+		txn.SetSender(msg.From())
+		return txn, nil
+	}
+
+	sender, err := types.MakeSigner(s.chainConfig, blockNum).Sender(txn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	if sender != msg.From() {
+		return nil, fmt.Errorf("signature of %x doesn't match the sender %x", sender, msg.From())
+	}
+	return txn, nil
+}
+
+func marshalSimulatedCall(result *core.ExecutionResult, receipt *types.Receipt) map[string]interface{} {
+	fields := map[string]interface{}{
+		"status":     hexutil.Uint64(receipt.Status),
+		"returnData": hexutil.Bytes(result.Return()),
+		"gasUsed":    hexutil.Uint64(result.UsedGas),
+		"logs":       receipt.Logs,
+	}
+	if receipt.Logs == nil {
+		fields["logs"] = types.Logs{}
+	}
+	if result.Err != nil {
+		if errors.Is(result.Err, vm.ErrExecutionReverted) {
+			revertErr := ethapi.NewRevertError(result)
+			fields["returnData"] = hexutil.Bytes(result.Revert())
+			fields["error"] = map[string]interface{}{"code": revertErr.ErrorCode(), "message": revertErr.Error(), "data": revertErr.ErrorData()}
+		} else {
+			fields["error"] = map[string]interface{}{"code": -32015, "message": result.Err.Error()}
+		}
+	}
+	return fields
+}
+
+// simulatedHashedState writes the changes of the simulated blocks to the hashed state of the block they are
+// simulated on, in memory. The state root of each block is computed from it as the IntermediateHashes stage
+// does, only the parts of the state trie holding the keys changed so far being recomputed.
+type simulatedHashedState struct {
+	batch *memdb.MemoryMutation
+	// changed are the hashed keys of the accounts and the storage changed so far, and whether they were created
+	changed map[string]bool
+}
+
+func newSimulatedHashedState(stateTx kv.Tx, tmpDir string) *simulatedHashedState {
+	return &simulatedHashedState{batch: memdb.NewMemoryBatch(stateTx, tmpDir), changed: map[string]bool{}}
+}
+
+func (s *simulatedHashedState) change(table string, key []byte) error {
+	if _, ok := s.changed[string(key)]; ok {
+		return nil
+	}
+	v, err := s.batch.GetOne(table, key)
+	if err != nil {
+		return err
+	}
+	s.changed[string(key)] = len(v) == 0
+	return nil
+}
+
+// wipeStorage removes the storage of the account, with its intermediate hashes, as for the deleted accounts
+// of the IntermediateHashes stage.
+func (s *simulatedHashedState) wipeStorage(addrHash common.Hash) error {
+	for _, table := range []string{kv.HashedStorage, kv.TrieOfStorage} {
+		var keys [][]byte
+		if err := s.batch.ForPrefix(table, addrHash[:], func(k, _ []byte) error {
+			keys = append(keys, common.Copy(k))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if table == kv.HashedStorage {
+				if err := s.change(table, k); err != nil {
+					return err
+				}
+			}
+			if err := s.batch.Delete(table, k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *simulatedHashedState) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	addrHash := crypto.Keccak256Hash(address[:])
+	if err := s.change(kv.HashedAccounts, addrHash[:]); err != nil {
+		return err
+	}
+	value := make([]byte, account.EncodingLengthForStorage())
+	account.EncodeForStorage(value)
+	return s.batch.Put(kv.HashedAccounts, addrHash[:], value)
+}
+
+// UpdateAccountCode does nothing, the code hash being part of the account
+func (s *simulatedHashedState) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+func (s *simulatedHashedState) DeleteAccount(address common.Address, original *accounts.Account) error {
+	addrHash := crypto.Keccak256Hash(address[:])
+	if err := s.change(kv.HashedAccounts, addrHash[:]); err != nil {
+		return err
+	}
+	if err := s.batch.Delete(kv.HashedAccounts, addrHash[:]); err != nil {
+		return err
+	}
+	return s.wipeStorage(addrHash)
+}
+
+// WriteAccountStorage writes the value even when it's the original one, which is the one of the block the
+// simulation starts from, not the one of the previous simulated block.
+func (s *simulatedHashedState) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	storageKey := dbutils.GenerateCompositeStorageKey(crypto.Keccak256Hash(address[:]), incarnation, crypto.Keccak256Hash(key[:]))
+	if err := s.change(kv.HashedStorage, storageKey); err != nil {
+		return err
+	}
+	if value.IsZero() {
+		return s.batch.Delete(kv.HashedStorage, storageKey)
+	}
+	return s.batch.Put(kv.HashedStorage, storageKey, value.Bytes())
+}
+
+func (s *simulatedHashedState) CreateContract(address common.Address) error {
+	return s.wipeStorage(crypto.Keccak256Hash(address[:]))
+}
+
+// root computes the state root of the hashed state, the intermediate hashes of the parts of the trie holding
+// changed keys being skipped.
+func (s *simulatedHashedState) root(quit <-chan struct{}) (common.Hash, error) {
+	retainList := trie.NewRetainList(0)
+	for key, created := range s.changed {
+		retainList.AddKeyWithMarker([]byte(key), created)
+	}
+	loader := trie.NewFlatDBTrieLoader("eth_simulateV1")
+	if err := loader.Reset(retainList, nil, nil, false); err != nil {
+		return common.Hash{}, err
+	}
+	return loader.CalcTrieRoot(s.batch, []byte{}, quit)
+}
@@ -0,0 +1,281 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+)
+
+func TestSimulateV1(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	agg := m.HistoryV3Components()
+	api := NewEthAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
+	ctx := context.Background()
+
+	var (
+		sender    = libcommon.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+		recipient = libcommon.HexToAddress("0x1234")
+		other     = libcommon.HexToAddress("0x5678")
+		value     = (*hexutil.Big)(big.NewInt(1000))
+		timestamp = hexutil.Uint64(2_000_000_000)
+		balance   = (*hexutil.Big)(big.NewInt(1_000_000))
+	)
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	// The recipient of the first block sends back what it received in the second one, which
+	// also funds another account with a state override
+	results, err := api.SimulateV1(ctx, SimulationOpts{
+		BlockStateCalls: []SimulatedBlock{
+			{Calls: []SimulatedCall{{CallArgs: ethapi.CallArgs{From: &sender, To: &recipient, Value: value}}}},
+			{
+				BlockOverrides: &BlockOverrides{Timestamp: &timestamp},
+				StateOverrides: &ethapi.StateOverrides{other: ethapi.Account{Balance: &balance}},
+				Calls: []SimulatedCall{
+					{CallArgs: ethapi.CallArgs{From: &recipient, To: &sender, Value: value}},
+					{CallArgs: ethapi.CallArgs{From: &other, To: &sender, Value: value}},
+				},
+			},
+		},
+		TraceTransfers: true,
+	}, &latest)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	require.Equal(t, (*hexutil.Big)(big.NewInt(12)), results[0]["number"])
+	require.Equal(t, (*hexutil.Big)(big.NewInt(13)), results[1]["number"])
+	require.Equal(t, results[0]["hash"], results[1]["parentHash"])
+	require.Equal(t, hexutil.Uint64(timestamp), results[1]["timestamp"])
+	require.Len(t, results[0]["transactions"], 1)
+	require.Len(t, results[1]["transactions"], 2)
+
+	calls := results[1]["calls"].([]map[string]interface{})
+	require.Len(t, calls, 2)
+	for _, call := range calls {
+		require.Equal(t, hexutil.Uint64(types.ReceiptStatusSuccessful), call["status"])
+		require.Equal(t, hexutil.Uint64(21000), call["gasUsed"])
+		require.Nil(t, call["error"])
+	}
+
+	// Transfers are reported as logs, numbered within their block
+	logs := calls[1]["logs"].(types.Logs)
+	require.Len(t, logs, 1)
+	require.Equal(t, simulatedTransferAddress, logs[0].Address)
+	require.Equal(t, []libcommon.Hash{simulatedTransferTopic, libcommon.BytesToHash(other.Bytes()), libcommon.BytesToHash(sender.Bytes())}, logs[0].Topics)
+	require.Equal(t, libcommon.BigToHash(big.NewInt(1000)).Bytes(), logs[0].Data)
+	require.Equal(t, uint(1), logs[0].Index)
+	require.Equal(t, results[1]["hash"], logs[0].BlockHash)
+
+	receipts := results[1]["receipts"].([]map[string]interface{})
+	require.Len(t, receipts, 2)
+	require.Equal(t, other, receipts[1]["from"])
+	require.Equal(t, hexutil.Uint64(42000), receipts[1]["cumulativeGasUsed"])
+
+	// With validation, nonces have to match the state
+	nonce := hexutil.Uint64(100)
+	_, err = api.SimulateV1(ctx, SimulationOpts{
+		BlockStateCalls: []SimulatedBlock{{Calls: []SimulatedCall{{CallArgs: ethapi.CallArgs{From: &sender, To: &recipient, Nonce: &nonce}}}}},
+		Validation:      true,
+	}, &latest)
+	require.ErrorContains(t, err, "nonce too high")
+
+	// Blocks are strictly ordered
+	past := hexutil.Uint64(1)
+	_, err = api.SimulateV1(ctx, SimulationOpts{
+		BlockStateCalls: []SimulatedBlock{{BlockOverrides: &BlockOverrides{Timestamp: &past}}},
+	}, &latest)
+	require.ErrorContains(t, err, "timestamp 1 is not greater")
+
+	// Skipped numbers are filled with empty blocks, whose hashes are seen by BLOCKHASH
+	var (
+		jump    = hexutil.Uint64(15)
+		hasher  = libcommon.HexToAddress("0x4242")
+		getHash = hexutil.Bytes(hexutil.MustDecode("0x43600190034060005260206000f3")) // Returns blockhash(number - 1)
+	)
+	results, err = api.SimulateV1(ctx, SimulationOpts{
+		BlockStateCalls: []SimulatedBlock{{
+			BlockOverrides: &BlockOverrides{BlockNumber: &jump},
+			StateOverrides: &ethapi.StateOverrides{hasher: ethapi.Account{Code: &getHash}},
+			Calls:          []SimulatedCall{{CallArgs: ethapi.CallArgs{From: &sender, To: &hasher}}},
+		}},
+	}, &latest)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	for i, result := range results {
+		require.Equal(t, (*hexutil.Big)(big.NewInt(int64(12+i))), result["number"])
+		if i > 0 {
+			require.Equal(t, results[i-1]["hash"], result["parentHash"])
+		}
+	}
+	require.Empty(t, results[0]["transactions"])
+	calls = results[3]["calls"].([]map[string]interface{})
+	require.Equal(t, hexutil.Bytes(results[2]["hash"].(libcommon.Hash).Bytes()), calls[0]["returnData"])
+
+	// Withdrawals need Shanghai
+	_, err = api.SimulateV1(ctx, SimulationOpts{
+		BlockStateCalls: []SimulatedBlock{{BlockOverrides: &BlockOverrides{Withdrawals: &[]*types.Withdrawal{{Address: other, Amount: 1}}}}},
+	}, &latest)
+	require.ErrorContains(t, err, "withdrawals before Shanghai")
+
+	// Simulations on top of blocks too far behind the head of the state trie fail
+	limited := NewEthAPI(api.BaseAPI, m.DB, nil, nil, nil, 5000000, 100_000, 1, "")
+	genesis := rpc.BlockNumberOrHashWithNumber(0)
+	_, err = limited.SimulateV1(ctx, SimulationOpts{BlockStateCalls: []SimulatedBlock{{}}}, &genesis)
+	require.ErrorContains(t, err, "state trie of block 0 is too old, it must be within 1 blocks")
+	parent := rpc.BlockNumberOrHashWithNumber(10)
+	_, err = limited.SimulateV1(ctx, SimulationOpts{BlockStateCalls: []SimulatedBlock{{}}}, &parent)
+	require.NoError(t, err)
+}
+
+// The state field of state overrides replaces the whole storage of the account, the slots it doesn't set
+// reading as empty, in the state root of the block too
+func TestSimulateV1ReplacedStorage(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	agg := m.HistoryV3Components()
+	api := NewEthAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
+	ctx := context.Background()
+
+	var (
+		sender  = libcommon.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+		store   = libcommon.HexToAddress("0x4242")
+		sload   = hexutil.Bytes(hexutil.MustDecode("0x6000355460005260206000f3")) // Returns sload(calldata[0:32])
+		slot0   = libcommon.Hash{}
+		slot1   = libcommon.BigToHash(big.NewInt(1))
+		initial = map[libcommon.Hash]uint256.Int{slot0: *uint256.NewInt(1), slot1: *uint256.NewInt(2)}
+	)
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	read := func(slot libcommon.Hash) SimulatedCall {
+		data := hexutil.Bytes(slot.Bytes())
+		return SimulatedCall{CallArgs: ethapi.CallArgs{From: &sender, To: &store, Data: &data}}
+	}
+	simulate := func(second ethapi.Account) []map[string]interface{} {
+		results, err := api.SimulateV1(ctx, SimulationOpts{
+			BlockStateCalls: []SimulatedBlock{
+				{StateOverrides: &ethapi.StateOverrides{store: ethapi.Account{Code: &sload, StateDiff: &initial}}},
+				{StateOverrides: &ethapi.StateOverrides{store: second}, Calls: []SimulatedCall{read(slot0), read(slot1)}},
+			},
+		}, &latest)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		return results
+	}
+
+	results := simulate(ethapi.Account{State: &map[libcommon.Hash]uint256.Int{slot0: *uint256.NewInt(3)}})
+	calls := results[1]["calls"].([]map[string]interface{})
+	require.Equal(t, hexutil.Bytes(libcommon.BigToHash(big.NewInt(3)).Bytes()), calls[0]["returnData"])
+	require.Equal(t, hexutil.Bytes(libcommon.Hash{}.Bytes()), calls[1]["returnData"])
+
+	// The root is the one of the storage set slot by slot
+	diffResults := simulate(ethapi.Account{StateDiff: &map[libcommon.Hash]uint256.Int{slot0: *uint256.NewInt(3), slot1: {}}})
+	require.Equal(t, diffResults[1]["stateRoot"], results[1]["stateRoot"])
+	require.NotEqual(t, results[0]["stateRoot"], results[1]["stateRoot"])
+
+	// The storage set on accounts without storage is part of the root
+	codeResults := simulate(ethapi.Account{})
+	withoutStorage, err := api.SimulateV1(ctx, SimulationOpts{
+		BlockStateCalls: []SimulatedBlock{
+			{StateOverrides: &ethapi.StateOverrides{store: ethapi.Account{Code: &sload}}},
+			{Calls: []SimulatedCall{read(slot0), read(slot1)}},
+		},
+	}, &latest)
+	require.NoError(t, err)
+	require.NotEqual(t, withoutStorage[1]["stateRoot"], codeResults[1]["stateRoot"])
+	require.NotEqual(t, withoutStorage[1]["stateRoot"], results[1]["stateRoot"])
+
+	_, err = api.SimulateV1(ctx, SimulationOpts{
+		BlockStateCalls: []SimulatedBlock{{StateOverrides: &ethapi.StateOverrides{store: ethapi.Account{State: &initial, StateDiff: &initial}}}},
+	}, &latest)
+	require.ErrorContains(t, err, "has both 'state' and 'stateDiff'")
+}
+
+// Simulating the signed transactions of the blocks of the chain gives their roots back, block rewards included
+func TestSimulateV1Roots(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	agg := m.HistoryV3Components()
+	api := NewEthAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, nil, nil, 5000000, 100_000, 100_000, "")
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	head := rawdb.ReadCurrentHeader(tx).Number.Uint64()
+
+	signed := func(block *types.Block, senders []libcommon.Address) []SimulatedCall {
+		calls := make([]SimulatedCall, 0, len(block.Transactions()))
+		for i, txn := range block.Transactions() {
+			var (
+				gas   = hexutil.Uint64(txn.GetGas())
+				nonce = hexutil.Uint64(txn.GetNonce())
+				data  = hexutil.Bytes(txn.GetData())
+			)
+			v, r, s := txn.RawSignatureValues()
+			calls = append(calls, SimulatedCall{
+				CallArgs: ethapi.CallArgs{
+					From:     &senders[i],
+					To:       txn.GetTo(),
+					Gas:      &gas,
+					GasPrice: (*hexutil.Big)(txn.GetPrice().ToBig()),
+					Value:    (*hexutil.Big)(txn.GetValue().ToBig()),
+					Nonce:    &nonce,
+					Data:     &data,
+				},
+				V: (*hexutil.Big)(v.ToBig()),
+				R: (*hexutil.Big)(r.ToBig()),
+				S: (*hexutil.Big)(s.ToBig()),
+			})
+		}
+		return calls
+	}
+
+	for n := uint64(1); n <= head; n++ {
+		hash, err := rawdb.ReadCanonicalHash(tx, n)
+		require.NoError(t, err)
+		block, senders, err := br.BlockWithSenders(ctx, tx, hash, n)
+		require.NoError(t, err)
+		header := block.Header()
+		var (
+			timestamp  = hexutil.Uint64(header.Time)
+			gasLimit   = hexutil.Uint(header.GasLimit)
+			difficulty = hexutil.Uint(header.Difficulty.Uint64())
+		)
+		parent := rpc.BlockNumberOrHashWithHash(header.ParentHash, true)
+		results, err := api.SimulateV1(ctx, SimulationOpts{
+			BlockStateCalls: []SimulatedBlock{{
+				BlockOverrides: &BlockOverrides{Coinbase: &header.Coinbase, Timestamp: &timestamp, GasLimit: &gasLimit, Difficulty: &difficulty},
+				Calls:          signed(block, senders),
+			}},
+			Validation: true,
+		}, &parent)
+		require.NoError(t, err, "block %d", n)
+		require.Equal(t, header.TxHash, results[0]["transactionsRoot"], "block %d", n)
+		require.Equal(t, header.ReceiptHash, results[0]["receiptsRoot"], "block %d", n)
+		require.Equal(t, header.Root, results[0]["stateRoot"], "block %d", n)
+	}
+
+	// A signature has to be the one of the sender
+	hash, err := rawdb.ReadCanonicalHash(tx, 1)
+	require.NoError(t, err)
+	block, senders, err := br.BlockWithSenders(ctx, tx, hash, 1)
+	require.NoError(t, err)
+	calls := signed(block, senders)
+	calls[0].Value = (*hexutil.Big)(big.NewInt(1))
+	genesis := rpc.BlockNumberOrHashWithNumber(0)
+	_, err = api.SimulateV1(ctx, SimulationOpts{BlockStateCalls: []SimulatedBlock{{Calls: calls}}}, &genesis)
+	require.ErrorContains(t, err, "doesn't match the sender")
+}
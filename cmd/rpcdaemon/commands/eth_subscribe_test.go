@@ -46,7 +46,7 @@ func TestEthSubscribe(t *testing.T) {
 	ctx := context.Background()
 	backendServer := privateapi.NewEthBackendServer(ctx, nil, m.DB, m.Notifications.Events, br, nil, nil, nil, false)
 	backendClient := direct.NewEthBackendClientDirect(backendServer)
-	backend := rpcservices.NewRemoteBackend(backendClient, m.DB, br)
+	backend := rpcservices.NewRemoteBackend(backendClient, nil, m.DB, br)
 	ff := rpchelper.New(ctx, backend, nil, nil, func() {})
 
 	newHeads, id := ff.SubscribeNewHeads(16)
@@ -1,15 +1,23 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/big"
 
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
+
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
@@ -17,18 +25,33 @@ import (
 
 type GraphQLAPI interface {
 	GetBlockDetails(ctx context.Context, number rpc.BlockNumber) (map[string]interface{}, error)
+	GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error)
 	GetChainID(ctx context.Context) (*big.Int, error)
+	GetTransactionByHash(ctx context.Context, hash common.Hash) (*RPCTransaction, error)
+	GetTransactionDetails(ctx context.Context, hash common.Hash, withReceipt bool) (map[string]interface{}, error)
+	GetLogs(ctx context.Context, crit filters.FilterCriteria) (types.Logs, error)
+	GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error)
+	GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error)
+	GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error)
+	GetStorageAt(ctx context.Context, address common.Address, index string, blockNrOrHash rpc.BlockNumberOrHash) (string, error)
+	Call(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash) (*core.ExecutionResult, error)
+	EstimateGas(ctx context.Context, args *ethapi.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error)
+	GasPrice(ctx context.Context) (*hexutil.Big, error)
+	MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error)
+	Syncing(ctx context.Context) (interface{}, error)
 }
 
 type GraphQLAPIImpl struct {
 	*BaseAPI
-	db kv.RoDB
+	db  kv.RoDB
+	eth *APIImpl
 }
 
-func NewGraphQLAPI(base *BaseAPI, db kv.RoDB) *GraphQLAPIImpl {
+func NewGraphQLAPI(base *BaseAPI, db kv.RoDB, eth *APIImpl) *GraphQLAPIImpl {
 	return &GraphQLAPIImpl{
 		BaseAPI: base,
 		db:      db,
+		eth:     eth,
 	}
 }
 
@@ -47,14 +70,30 @@ func (api *GraphQLAPIImpl) GetChainID(ctx context.Context) (*big.Int, error) {
 	return response.ChainID, nil
 }
 
+// GetBlockDetails returns the block with the given number under "block", its transactions along
+// with their receipts under "receipts", and its ommers under "ommers".
 func (api *GraphQLAPIImpl) GetBlockDetails(ctx context.Context, blockNumber rpc.BlockNumber) (map[string]interface{}, error) {
+	return api.getBlockDetails(ctx, rpc.BlockNumberOrHashWithNumber(blockNumber))
+}
+
+// GetBlockDetailsByHash is GetBlockDetails for the block with the given hash.
+func (api *GraphQLAPIImpl) GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	return api.getBlockDetails(ctx, rpc.BlockNumberOrHashWithHash(hash, false))
+}
+
+func (api *GraphQLAPIImpl) getBlockDetails(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[string]interface{}, error) {
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	block, senders, err := api.getBlockWithSenders(ctx, blockNumber, tx)
+	pending := false
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		pending = true
+	}
+
+	block, senders, err := api.getBlockWithSenders(ctx, blockNrOrHash, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +101,7 @@ func (api *GraphQLAPIImpl) GetBlockDetails(ctx context.Context, blockNumber rpc.
 		return nil, nil
 	}
 
-	getBlockRes, err := api.delegateGetBlockByNumber(tx, block, blockNumber, false)
+	getBlockRes, err := api.delegateGetBlockByNumber(tx, block, pending, false)
 	if err != nil {
 		return nil, err
 	}
@@ -72,35 +111,203 @@ func (api *GraphQLAPIImpl) GetBlockDetails(ctx context.Context, blockNumber rpc.
 		return nil, err
 	}
 
-	receipts, err := api.getReceipts(ctx, tx, chainConfig, block, senders)
+	if chainConfig.IsLondon(block.NumberU64() + 1) {
+		getBlockRes["nextBaseFeePerGas"] = (*hexutil.Big)(misc.CalcBaseFee(chainConfig, block.HeaderNoCopy()))
+	}
+	rawHeader, err := rlp.EncodeToBytes(block.HeaderNoCopy())
+	if err != nil {
+		return nil, err
+	}
+	getBlockRes["rawHeader"] = hexutil.Bytes(rawHeader)
+	raw, err := rlp.EncodeToBytes(block)
 	if err != nil {
-		return nil, fmt.Errorf("getReceipts error: %w", err)
+		return nil, err
+	}
+	getBlockRes["raw"] = hexutil.Bytes(raw)
+
+	ommers := make([]map[string]interface{}, 0, len(block.Uncles()))
+	for _, uncle := range block.Uncles() {
+		ommer := ethapi.RPCMarshalHeader(uncle)
+		rawOmmer, err := rlp.EncodeToBytes(uncle)
+		if err != nil {
+			return nil, err
+		}
+		ommer["rawHeader"] = hexutil.Bytes(rawOmmer)
+		if td, err := rawdb.ReadTd(tx, uncle.Hash(), uncle.Number.Uint64()); err == nil && td != nil {
+			ommer["totalDifficulty"] = (*hexutil.Big)(td)
+		}
+		ommers = append(ommers, ommer)
 	}
-	result := make([]map[string]interface{}, 0, len(receipts))
-	for _, receipt := range receipts {
-		txn := block.Transactions()[receipt.TransactionIndex]
 
-		transaction := marshalReceipt(receipt, txn, chainConfig, block.HeaderNoCopy(), txn.Hash(), true)
-		transaction["nonce"] = txn.GetNonce()
-		transaction["value"] = txn.GetValue()
-		transaction["data"] = txn.GetData()
-		transaction["gasPrice"] = txn.GetPrice()
+	// The transactions of the pending block are not executed yet, they have no receipt
+	var receipts types.Receipts
+	if !pending {
+		if receipts, err = api.getReceipts(ctx, tx, chainConfig, block, senders); err != nil {
+			return nil, fmt.Errorf("getReceipts error: %w", err)
+		}
+	}
+	result := make([]map[string]interface{}, 0, len(block.Transactions()))
+	for i := range block.Transactions() {
+		transaction, err := marshalGraphQLBlockTransaction(block, i, receipts, senders, chainConfig)
+		if err != nil {
+			return nil, err
+		}
 		result = append(result, transaction)
 	}
 
 	response := map[string]interface{}{}
 	response["block"] = getBlockRes
 	response["receipts"] = result
+	response["ommers"] = ommers
 
 	return response, nil
 }
 
-func (api *GraphQLAPIImpl) getBlockWithSenders(ctx context.Context, number rpc.BlockNumber, tx kv.Tx) (*types.Block, []common.Address, error) {
-	if number == rpc.PendingBlockNumber {
+// GetTransactionDetails returns the fields of the transaction with the given hash, found in its block
+// through the tx-lookup index, along with the hash of the block under "blockHash". The fields of its
+// receipt, which may take the execution of the block, are only there when withReceipt is set. It
+// returns nil when the transaction is in no block.
+func (api *GraphQLAPIImpl) GetTransactionDetails(ctx context.Context, hash common.Hash, withReceipt bool) (map[string]interface{}, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNum, ok, err := api.txnLookup(ctx, tx, hash)
+	if err != nil || !ok {
+		return nil, err
+	}
+	blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	block, senders, err := api._blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	index := -1
+	for i, txn := range block.Transactions() {
+		if txn.Hash() == hash {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, nil
+	}
+
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	var receipts types.Receipts
+	if withReceipt {
+		if receipts, err = api.getReceipts(ctx, tx, chainConfig, block, senders); err != nil {
+			return nil, fmt.Errorf("getReceipts error: %w", err)
+		}
+	}
+	transaction, err := marshalGraphQLBlockTransaction(block, index, receipts, senders, chainConfig)
+	if err != nil {
+		return nil, err
+	}
+	transaction["blockHash"] = block.Hash()
+
+	return transaction, nil
+}
+
+// marshalGraphQLBlockTransaction marshals the transaction of the block at index i, along with its
+// receipt when receipts has it.
+func marshalGraphQLBlockTransaction(block *types.Block, i int, receipts types.Receipts, senders []common.Address, chainConfig *chain.Config) (map[string]interface{}, error) {
+	txn := block.Transactions()[i]
+	var transaction map[string]interface{}
+	if i < len(receipts) {
+		var err error
+		if transaction, err = marshalGraphQLReceipt(receipts[i], txn, chainConfig, block.HeaderNoCopy()); err != nil {
+			return nil, err
+		}
+	} else {
+		transaction = map[string]interface{}{
+			"transactionHash":  txn.Hash(),
+			"transactionIndex": hexutil.Uint64(i),
+			"type":             hexutil.Uint(txn.Type()),
+			"to":               txn.GetTo(),
+		}
+		if i < len(senders) {
+			transaction["from"] = senders[i]
+		} else if sender, err := txn.Sender(*types.LatestSigner(chainConfig)); err == nil {
+			transaction["from"] = sender
+		}
+	}
+	if err := marshalGraphQLTransaction(transaction, txn, block.HeaderNoCopy()); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// marshalGraphQLReceipt is marshalReceipt along with the canonical encoding of the receipt.
+func marshalGraphQLReceipt(receipt *types.Receipt, txn types.Transaction, chainConfig *chain.Config, header *types.Header) (map[string]interface{}, error) {
+	fields, err := marshalReceipt(receipt, txn, chainConfig, header, txn.Hash(), true)
+	if err != nil {
+		return nil, err
+	}
+
+	rawReceipt, err := rlp.EncodeToBytes(receipt)
+	if err != nil {
+		return nil, err
+	}
+	// Typed receipts are encoded as an RLP string holding the type followed by the payload
+	if receipt.Type != types.LegacyTxType {
+		if rawReceipt, _, err = rlp.SplitString(rawReceipt); err != nil {
+			return nil, err
+		}
+	}
+	fields["rawReceipt"] = hexutil.Bytes(rawReceipt)
+
+	return fields, nil
+}
+
+// marshalGraphQLTransaction adds to fields the fields of the transaction not part of its receipt.
+func marshalGraphQLTransaction(fields map[string]interface{}, txn types.Transaction, header *types.Header) error {
+	v, r, s := txn.RawSignatureValues()
+	fields["nonce"] = txn.GetNonce()
+	fields["value"] = txn.GetValue()
+	fields["data"] = hexutil.Bytes(txn.GetData())
+	fields["gas"] = hexutil.Uint64(txn.GetGas())
+	fields["gasPrice"] = txn.GetPrice()
+	fields["v"], fields["r"], fields["s"] = v, r, s
+	fields["accessList"] = txn.GetAccessList()
+
+	if txn.Type() == types.DynamicFeeTxType || txn.Type() == types.BlobTxType {
+		fields["maxFeePerGas"] = txn.GetFeeCap()
+		fields["maxPriorityFeePerGas"] = txn.GetTip()
+	}
+	if header.BaseFee != nil {
+		baseFee, _ := uint256.FromBig(header.BaseFee)
+		tip := txn.GetEffectiveGasTip(baseFee)
+		fields["effectiveTip"] = tip
+		if txn.Type() == types.DynamicFeeTxType || txn.Type() == types.BlobTxType {
+			// The price paid by a dynamic fee transaction is only known once it's in a block
+			fields["gasPrice"] = new(uint256.Int).Add(baseFee, tip)
+		}
+	}
+
+	var raw bytes.Buffer
+	if err := txn.MarshalBinary(&raw); err != nil {
+		return err
+	}
+	fields["raw"] = hexutil.Bytes(raw.Bytes())
+
+	return nil
+}
+
+func (api *GraphQLAPIImpl) getBlockWithSenders(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, tx kv.Tx) (*types.Block, []common.Address, error) {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
 		return api.pendingBlock(), nil, nil
 	}
 
-	blockHeight, blockHash, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(number), tx, api.filters)
+	blockHeight, blockHash, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -109,7 +316,7 @@ func (api *GraphQLAPIImpl) getBlockWithSenders(ctx context.Context, number rpc.B
 	return block, senders, err
 }
 
-func (api *GraphQLAPIImpl) delegateGetBlockByNumber(tx kv.Tx, b *types.Block, number rpc.BlockNumber, inclTx bool) (map[string]interface{}, error) {
+func (api *GraphQLAPIImpl) delegateGetBlockByNumber(tx kv.Tx, b *types.Block, pending bool, inclTx bool) (map[string]interface{}, error) {
 	td, err := rawdb.ReadTd(tx, b.Hash(), b.NumberU64())
 	if err != nil {
 		return nil, err
@@ -122,7 +329,7 @@ func (api *GraphQLAPIImpl) delegateGetBlockByNumber(tx kv.Tx, b *types.Block, nu
 	response["totalDifficulty"] = (*hexutil.Big)(td)
 	response["transactionCount"] = b.Transactions().Len()
 
-	if err == nil && number == rpc.PendingBlockNumber {
+	if err == nil && pending {
 		// Pending blocks need to nil out a few fields
 		for _, field := range []string{"hash", "nonce", "miner"} {
 			response[field] = nil
@@ -131,3 +338,59 @@ func (api *GraphQLAPIImpl) delegateGetBlockByNumber(tx kv.Tx, b *types.Block, nu
 
 	return response, err
 }
+
+// GetTransactionByHash implements eth_getTransactionByHash, the transaction being a pending one when it has no block.
+func (api *GraphQLAPIImpl) GetTransactionByHash(ctx context.Context, hash common.Hash) (*RPCTransaction, error) {
+	return api.eth.GetTransactionByHash(ctx, hash)
+}
+
+// GetLogs implements eth_getLogs.
+func (api *GraphQLAPIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (types.Logs, error) {
+	return api.eth.GetLogs(ctx, crit)
+}
+
+// GetBalance implements eth_getBalance.
+func (api *GraphQLAPIImpl) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	return api.eth.GetBalance(ctx, address, blockNrOrHash)
+}
+
+// GetTransactionCount implements eth_getTransactionCount.
+func (api *GraphQLAPIImpl) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	return api.eth.GetTransactionCount(ctx, address, blockNrOrHash)
+}
+
+// GetCode implements eth_getCode.
+func (api *GraphQLAPIImpl) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	return api.eth.GetCode(ctx, address, blockNrOrHash)
+}
+
+// GetStorageAt implements eth_getStorageAt.
+func (api *GraphQLAPIImpl) GetStorageAt(ctx context.Context, address common.Address, index string, blockNrOrHash rpc.BlockNumberOrHash) (string, error) {
+	return api.eth.GetStorageAt(ctx, address, index, blockNrOrHash)
+}
+
+// Call executes a message call at the state of the given block. Unlike eth_call, a failed call is
+// not an error, its status being part of the result.
+func (api *GraphQLAPIImpl) Call(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash) (*core.ExecutionResult, error) {
+	return api.eth.doCall(ctx, args, blockNrOrHash, nil)
+}
+
+// EstimateGas implements eth_estimateGas.
+func (api *GraphQLAPIImpl) EstimateGas(ctx context.Context, args *ethapi.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
+	return api.eth.EstimateGas(ctx, args, blockNrOrHash)
+}
+
+// GasPrice implements eth_gasPrice.
+func (api *GraphQLAPIImpl) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	return api.eth.GasPrice(ctx)
+}
+
+// MaxPriorityFeePerGas implements eth_maxPriorityFeePerGas.
+func (api *GraphQLAPIImpl) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	return api.eth.MaxPriorityFeePerGas(ctx)
+}
+
+// Syncing implements eth_syncing.
+func (api *GraphQLAPIImpl) Syncing(ctx context.Context) (interface{}, error) {
+	return api.eth.Syncing(ctx)
+}
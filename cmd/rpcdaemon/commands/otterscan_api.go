@@ -31,20 +31,22 @@ import (
 )
 
 // API_LEVEL Must be incremented every time new additions are made
-const API_LEVEL = 8
+const API_LEVEL = 9
 
 type TransactionsWithReceipts struct {
 	Txs       []*RPCTransaction        `json:"txs"`
 	Receipts  []map[string]interface{} `json:"receipts"`
 	FirstPage bool                     `json:"firstPage"`
 	LastPage  bool                     `json:"lastPage"`
+	// NextCursor resumes the search right after this page, nil on its last page
+	NextCursor *hexutil.Bytes `json:"nextCursor,omitempty"`
 }
 
 type OtterscanAPI interface {
 	GetApiLevel() uint8
 	GetInternalOperations(ctx context.Context, hash common.Hash) ([]*InternalOperation, error)
-	SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error)
-	SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error)
+	SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, cursor *hexutil.Bytes) (*TransactionsWithReceipts, error)
+	SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, cursor *hexutil.Bytes) (*TransactionsWithReceipts, error)
 	GetBlockDetails(ctx context.Context, number rpc.BlockNumber) (map[string]interface{}, error)
 	GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error)
 	GetBlockTransactions(ctx context.Context, number rpc.BlockNumber, pageNumber uint8, pageSize uint8) (map[string]interface{}, error)
@@ -172,7 +174,10 @@ func (api *OtterscanAPIImpl) GetInternalOperations(ctx context.Context, hash com
 // they are just returned. But it may return a little more than pageSize if there are more txs
 // than the necessary to fill pageSize in the last found block, i.e., let's say you want pageSize == 25,
 // you already found 24 txs, the next block contains 4 matches, then this function will return 28 txs.
-func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+//
+// The cursor, when set to the one returned with a page, makes the search resume right after it instead
+// of before blockNum.
+func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, cursor *hexutil.Bytes) (*TransactionsWithReceipts, error) {
 	dbtx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -180,7 +185,7 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 	defer dbtx.Rollback()
 
 	if api.historyV3(dbtx) {
-		return api.searchTransactionsBeforeV3(dbtx.(kv.TemporalTx), ctx, addr, blockNum, pageSize)
+		return api.searchTransactionsBeforeV3(dbtx.(kv.TemporalTx), ctx, addr, blockNum, pageSize, cursor)
 	}
 
 	callFromCursor, err := dbtx.Cursor(kv.CallFromIndex)
@@ -201,7 +206,13 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 	}
 
 	isFirstPage := false
-	if blockNum == 0 {
+	if cursor != nil && len(*cursor) > 0 {
+		resume, err := decodeSearchCursor(*cursor, searchBeforeCursor, false)
+		if err != nil {
+			return nil, err
+		}
+		blockNum = resume.pos
+	} else if blockNum == 0 {
 		isFirstPage = true
 	} else {
 		// Internal search code considers blockNum [including], so adjust the value
@@ -218,18 +229,20 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 
 	resultCount := uint16(0)
 	hasMore := true
+	var next *searchCursor
 	for {
 		if resultCount >= pageSize || !hasMore {
 			break
 		}
 
 		var results []*TransactionsWithReceipts
-		results, hasMore, err = api.traceBlocks(ctx, addr, chainConfig, pageSize, resultCount, callFromToProvider)
+		var blocks []uint64
+		results, blocks, hasMore, err = api.traceBlocks(ctx, addr, chainConfig, pageSize, resultCount, callFromToProvider)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, r := range results {
+		for j, r := range results {
 			if r == nil {
 				return nil, errors.New("internal error during search tracing")
 			}
@@ -243,30 +256,47 @@ func (api *OtterscanAPIImpl) SearchTransactionsBefore(ctx context.Context, addr
 
 			resultCount += uint16(len(r.Txs))
 			if resultCount >= pageSize {
+				// The blocks traced past the page are left to the next one
+				hasMore = (hasMore || j < len(results)-1) && blocks[j] > 0
+				if hasMore {
+					next = &searchCursor{kind: searchBeforeCursor, pos: blocks[j] - 1}
+				}
 				break
 			}
 		}
 	}
 
-	return &TransactionsWithReceipts{txs, receipts, isFirstPage, !hasMore}, nil
+	return &TransactionsWithReceipts{Txs: txs, Receipts: receipts, FirstPage: isFirstPage, LastPage: !hasMore, NextCursor: next.hexBytes()}, nil
 }
 
-func (api *OtterscanAPIImpl) searchTransactionsBeforeV3(tx kv.TemporalTx, ctx context.Context, addr common.Address, fromBlockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+func (api *OtterscanAPIImpl) searchTransactionsBeforeV3(tx kv.TemporalTx, ctx context.Context, addr common.Address, fromBlockNum uint64, pageSize uint16, cursor *hexutil.Bytes) (*TransactionsWithReceipts, error) {
 	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
 		return nil, err
 	}
 
 	isFirstPage := false
-	if fromBlockNum == 0 {
-		isFirstPage = true
+	var fromTxNum uint64
+	if cursor != nil && len(*cursor) > 0 {
+		resume, err := decodeSearchCursor(*cursor, searchBeforeCursor, true)
+		if err != nil {
+			return nil, err
+		}
+		fromTxNum = resume.pos
 	} else {
-		// Internal search code considers blockNum [including], so adjust the value
-		fromBlockNum--
-	}
-	fromTxNum, err := rawdbv3.TxNums.Max(tx, fromBlockNum)
-	if err != nil {
-		return nil, err
+		if fromBlockNum == 0 {
+			// The first page starts at the latest block, the indexes can't be walked back from their end
+			isFirstPage = true
+			if fromBlockNum, err = rpchelper.GetLatestBlockNumber(tx); err != nil {
+				return nil, err
+			}
+		} else {
+			// Internal search code considers blockNum [including], so adjust the value
+			fromBlockNum--
+		}
+		if fromTxNum, err = rawdbv3.TxNums.Max(tx, fromBlockNum); err != nil {
+			return nil, err
+		}
 	}
 	itTo, err := tx.IndexRange(temporal.TracesToIdx, addr[:], int(fromTxNum), -1, order.Desc, -1)
 	if err != nil {
@@ -285,6 +315,7 @@ func (api *OtterscanAPIImpl) searchTransactionsBeforeV3(tx kv.TemporalTx, ctx co
 	txs := make([]*RPCTransaction, 0, pageSize)
 	receipts := make([]map[string]interface{}, 0, pageSize)
 	resultCount := uint16(0)
+	var lastTxNum uint64
 
 	for txNumsIter.HasNext() {
 		txNum, blockNum, txIndex, isFinalTxn, blockNumChanged, err := txNumsIter.Next()
@@ -326,17 +357,25 @@ func (api *OtterscanAPIImpl) searchTransactionsBeforeV3(tx kv.TemporalTx, ctx co
 			TransactionIndex: uint(txIndex),
 			BlockNumber:      header.Number, BlockHash: blockHash, Logs: rawLogs,
 		}
-		mReceipt := marshalReceipt(receipt, txn, chainConfig, header, txn.Hash(), true)
+		mReceipt, err := marshalReceipt(receipt, txn, chainConfig, header, txn.Hash(), true)
+		if err != nil {
+			return nil, err
+		}
 		mReceipt["timestamp"] = header.Time
 		receipts = append(receipts, mReceipt)
 
 		resultCount++
 		if resultCount >= pageSize {
+			lastTxNum = txNum
 			break
 		}
 	}
-	hasMore := txNumsIter.HasNext()
-	return &TransactionsWithReceipts{txs, receipts, isFirstPage, !hasMore}, nil
+	hasMore := txNumsIter.HasNext() && lastTxNum > 0
+	var next *searchCursor
+	if hasMore {
+		next = &searchCursor{kind: searchBeforeCursor, temporal: true, pos: lastTxNum - 1}
+	}
+	return &TransactionsWithReceipts{Txs: txs, Receipts: receipts, FirstPage: isFirstPage, LastPage: !hasMore, NextCursor: next.hexBytes()}, nil
 }
 
 // Search transactions that touch a certain address.
@@ -347,7 +386,10 @@ func (api *OtterscanAPIImpl) searchTransactionsBeforeV3(tx kv.TemporalTx, ctx co
 // they are just returned. But it may return a little more than pageSize if there are more txs
 // than the necessary to fill pageSize in the last found block, i.e., let's say you want pageSize == 25,
 // you already found 24 txs, the next block contains 4 matches, then this function will return 28 txs.
-func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+//
+// The cursor, when set to the one returned with a page, makes the search resume right after it instead
+// of after blockNum.
+func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr common.Address, blockNum uint64, pageSize uint16, cursor *hexutil.Bytes) (*TransactionsWithReceipts, error) {
 	dbtx, err := api.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -372,7 +414,13 @@ func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr c
 	}
 
 	isLastPage := false
-	if blockNum == 0 {
+	if cursor != nil && len(*cursor) > 0 {
+		resume, err := decodeSearchCursor(*cursor, searchAfterCursor, false)
+		if err != nil {
+			return nil, err
+		}
+		blockNum = resume.pos
+	} else if blockNum == 0 {
 		isLastPage = true
 	} else {
 		// Internal search code considers blockNum [including], so adjust the value
@@ -389,18 +437,20 @@ func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr c
 
 	resultCount := uint16(0)
 	hasMore := true
+	var next *searchCursor
 	for {
 		if resultCount >= pageSize || !hasMore {
 			break
 		}
 
 		var results []*TransactionsWithReceipts
-		results, hasMore, err = api.traceBlocks(ctx, addr, chainConfig, pageSize, resultCount, callFromToProvider)
+		var blocks []uint64
+		results, blocks, hasMore, err = api.traceBlocks(ctx, addr, chainConfig, pageSize, resultCount, callFromToProvider)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, r := range results {
+		for j, r := range results {
 			if r == nil {
 				return nil, errors.New("internal error during search tracing")
 			}
@@ -410,6 +460,11 @@ func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr c
 
 			resultCount += uint16(len(r.Txs))
 			if resultCount >= pageSize {
+				// The blocks traced past the page are left to the next one
+				hasMore = hasMore || j < len(results)-1
+				if hasMore {
+					next = &searchCursor{kind: searchAfterCursor, pos: blocks[j] + 1}
+				}
 				break
 			}
 		}
@@ -421,10 +476,11 @@ func (api *OtterscanAPIImpl) SearchTransactionsAfter(ctx context.Context, addr c
 		txs[i], txs[lentxs-1-i] = txs[lentxs-1-i], txs[i]
 		receipts[i], receipts[lentxs-1-i] = receipts[lentxs-1-i], receipts[i]
 	}
-	return &TransactionsWithReceipts{txs, receipts, !hasMore, isLastPage}, nil
+	return &TransactionsWithReceipts{Txs: txs, Receipts: receipts, FirstPage: !hasMore, LastPage: isLastPage, NextCursor: next.hexBytes()}, nil
 }
 
-func (api *OtterscanAPIImpl) traceBlocks(ctx context.Context, addr common.Address, chainConfig *chain.Config, pageSize, resultCount uint16, callFromToProvider BlockProvider) ([]*TransactionsWithReceipts, bool, error) {
+// traceBlocks traces the next blocks of the search, returning their results along with their numbers
+func (api *OtterscanAPIImpl) traceBlocks(ctx context.Context, addr common.Address, chainConfig *chain.Config, pageSize, resultCount uint16, callFromToProvider BlockProvider) ([]*TransactionsWithReceipts, []uint64, bool, error) {
 	var wg sync.WaitGroup
 
 	// Estimate the common case of user address having at most 1 interaction/block and
@@ -432,6 +488,7 @@ func (api *OtterscanAPIImpl) traceBlocks(ctx context.Context, addr common.Addres
 	// TODO: this is not optimimal for big contract addresses; implement some better heuristics.
 	estBlocksToTrace := pageSize - resultCount
 	results := make([]*TransactionsWithReceipts, estBlocksToTrace)
+	blocks := make([]uint64, 0, estBlocksToTrace)
 	totalBlocksTraced := 0
 	hasMore := true
 
@@ -440,7 +497,7 @@ func (api *OtterscanAPIImpl) traceBlocks(ctx context.Context, addr common.Addres
 		var err error
 		nextBlock, hasMore, err = callFromToProvider()
 		if err != nil {
-			return nil, false, err
+			return nil, nil, false, err
 		}
 		// TODO: nextBlock == 0 seems redundant with hasMore == false
 		if !hasMore && nextBlock == 0 {
@@ -449,11 +506,12 @@ func (api *OtterscanAPIImpl) traceBlocks(ctx context.Context, addr common.Addres
 
 		wg.Add(1)
 		totalBlocksTraced++
+		blocks = append(blocks, nextBlock)
 		go api.searchTraceBlock(ctx, &wg, addr, chainConfig, i, nextBlock, results)
 	}
 	wg.Wait()
 
-	return results[:totalBlocksTraced], hasMore, nil
+	return results[:totalBlocksTraced], blocks, hasMore, nil
 }
 
 func (api *OtterscanAPIImpl) delegateGetBlockByNumber(tx kv.Tx, b *types.Block, number rpc.BlockNumber, inclTx bool) (map[string]interface{}, error) {
@@ -579,7 +637,10 @@ func (api *OtterscanAPIImpl) GetBlockTransactions(ctx context.Context, number rp
 	result := make([]map[string]interface{}, 0, len(receipts))
 	for _, receipt := range receipts {
 		txn := b.Transactions()[receipt.TransactionIndex]
-		marshalledRcpt := marshalReceipt(receipt, txn, chainConfig, b.HeaderNoCopy(), txn.Hash(), true)
+		marshalledRcpt, err := marshalReceipt(receipt, txn, chainConfig, b.HeaderNoCopy(), txn.Hash(), true)
+		if err != nil {
+			return nil, err
+		}
 		marshalledRcpt["logs"] = nil
 		marshalledRcpt["logsBloom"] = nil
 		result = append(result, marshalledRcpt)
@@ -2,14 +2,23 @@ package commands
 
 import (
 	"bytes"
+	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/RoaringBitmap/roaring/roaring64"
+	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
 	"github.com/stretchr/testify/require"
 )
 
@@ -157,7 +166,7 @@ func TestSearchTransactionsBefore(t *testing.T) {
 	addr := libcommon.HexToAddress("0x537e697c7ab75a26f9ecf0ce810e3154dfcaaf44")
 	t.Run("small page size", func(t *testing.T) {
 		require := require.New(t)
-		results, err := api.SearchTransactionsBefore(m.Ctx, addr, 10, 2)
+		results, err := api.SearchTransactionsBefore(m.Ctx, addr, 10, 2, nil)
 		require.NoError(err)
 		require.False(results.FirstPage)
 		require.False(results.LastPage)
@@ -166,7 +175,7 @@ func TestSearchTransactionsBefore(t *testing.T) {
 	})
 	t.Run("big page size", func(t *testing.T) {
 		require := require.New(t)
-		results, err := api.SearchTransactionsBefore(m.Ctx, addr, 10, 10)
+		results, err := api.SearchTransactionsBefore(m.Ctx, addr, 10, 10, nil)
 		require.NoError(err)
 		require.False(results.FirstPage)
 		require.True(results.LastPage)
@@ -175,7 +184,7 @@ func TestSearchTransactionsBefore(t *testing.T) {
 	})
 	t.Run("filter last block", func(t *testing.T) {
 		require := require.New(t)
-		results, err := api.SearchTransactionsBefore(m.Ctx, addr, 5, 10)
+		results, err := api.SearchTransactionsBefore(m.Ctx, addr, 5, 10, nil)
 
 		require.NoError(err)
 		require.False(results.FirstPage)
@@ -197,5 +206,105 @@ func TestSearchTransactionsBefore(t *testing.T) {
 		require.Equal(libcommon.HexToAddress("0x0D3ab14BBaD3D99F4203bd7a11aCB94882050E7e"), results.Receipts[0]["from"].(libcommon.Address))
 		require.Equal(addr, *results.Receipts[0]["to"].(*libcommon.Address))
 	})
+	t.Run("cursor", func(t *testing.T) {
+		require := require.New(t)
+		all, err := api.SearchTransactionsBefore(m.Ctx, addr, 10, 10, nil)
+		require.NoError(err)
+
+		var txs []*RPCTransaction
+		results, err := api.SearchTransactionsBefore(m.Ctx, addr, 10, 1, nil)
+		require.NoError(err)
+		firstCursor := results.NextCursor
+		for {
+			txs = append(txs, results.Txs...)
+			if results.NextCursor == nil {
+				require.True(results.LastPage)
+				break
+			}
+			require.False(results.LastPage)
+			results, err = api.SearchTransactionsBefore(m.Ctx, addr, 0, 1, results.NextCursor)
+			require.NoError(err)
+			require.False(results.FirstPage)
+		}
+		require.Equal(len(all.Txs), len(txs))
+		for i := range txs {
+			require.Equal(all.Txs[i].Hash, txs[i].Hash)
+		}
+
+		_, err = api.SearchTransactionsAfter(m.Ctx, addr, 0, 1, firstCursor)
+		require.Error(err)
+		_, err = api.SearchTransactionsBefore(m.Ctx, addr, 0, 1, &hexutil.Bytes{0x01})
+		require.Error(err)
+	})
+}
+
+func TestSearchTransactionsBeforeCursor(t *testing.T) {
+	for _, historyV3 := range []bool{false, true} {
+		historyV3 := historyV3
+		t.Run(fmt.Sprintf("historyV3=%t", historyV3), func(t *testing.T) {
+			testSearchTransactionsBeforeCursor(t, historyV3)
+		})
+	}
+}
+
+// testSearchTransactionsBeforeCursor pages through the transactions of an address, cursors being block
+// numbers of the call trace indexes or txNums of the temporal ones
+func testSearchTransactionsBeforeCursor(t *testing.T, historyV3 bool) {
+	require := require.New(t)
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(params.Ether)}},
+	}
+	m := stages.MockWithHistoryV3(t, gspec, ethash.NewFaker(), historyV3)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	api := NewOtterscanAPI(NewBaseApi(nil, nil, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB)
+
+	addr := libcommon.Address{1}
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 5, func(i int, block *core.BlockGen) {
+		signer := types.LatestSigner(m.ChainConfig)
+		for j := 0; j < 3; j++ {
+			txn, err := types.SignTx(types.NewTransaction(block.TxNonce(m.Address), addr, new(uint256.Int), 21000, new(uint256.Int), nil), *signer, m.Key)
+			require.NoError(err)
+			block.AddTx(txn)
+		}
+	}, false /* intermediateHashes */)
+	require.NoError(err)
+	require.NoError(m.InsertChain(chain))
+
+	all, err := api.SearchTransactionsBefore(m.Ctx, addr, 0, 100, nil)
+	require.NoError(err)
+	require.True(all.LastPage)
+	require.Nil(all.NextCursor)
+	require.Equal(15, len(all.Txs))
+
+	var txs []*RPCTransaction
+	results, err := api.SearchTransactionsBefore(m.Ctx, addr, 0, 2, nil)
+	require.NoError(err)
+	require.True(results.FirstPage)
+	for pages := 0; ; pages++ {
+		require.Less(pages, 10)
+		txs = append(txs, results.Txs...)
+		if results.NextCursor == nil {
+			require.True(results.LastPage)
+			break
+		}
+		require.False(results.LastPage)
+		decoded, err := decodeSearchCursor(*results.NextCursor, searchBeforeCursor, historyV3)
+		require.NoError(err)
+		require.Equal(historyV3, decoded.temporal)
+		if historyV3 {
+			// Temporal pages end at the transaction filling them, whole blocks being returned otherwise
+			require.Equal(2, len(results.Txs))
+		}
 
+		results, err = api.SearchTransactionsBefore(m.Ctx, addr, 0, 2, results.NextCursor)
+		require.NoError(err)
+		require.False(results.FirstPage)
+	}
+	require.Equal(len(all.Txs), len(txs))
+	for i := range txs {
+		require.Equal(all.Txs[i].Hash, txs[i].Hash)
+	}
 }
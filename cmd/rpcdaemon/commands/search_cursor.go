@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+)
+
+const searchCursorVersion = 1
+
+// searchCursorKind tells which search a cursor was returned by, a cursor only being valid for the same search
+type searchCursorKind byte
+
+const (
+	traceFilterCursor searchCursorKind = iota + 1
+	searchBeforeCursor
+	searchAfterCursor
+)
+
+// searchCursor is the position a paginated search over the call trace indexes resumes at: the entry of the
+// index, a block number or a txNum on the temporal path, and the number of its results already returned.
+// It is handed to clients as an opaque value.
+type searchCursor struct {
+	kind     searchCursorKind
+	temporal bool   // pos is a txNum of the temporal indexes rather than a block number
+	pos      uint64 // Entry of the index the search resumes at
+	skip     uint64 // Number of results of the entry at pos that were already returned
+}
+
+func (c *searchCursor) encode() hexutil.Bytes {
+	b := make([]byte, 3+8+8)
+	b[0] = searchCursorVersion
+	b[1] = byte(c.kind)
+	if c.temporal {
+		b[2] = 1
+	}
+	binary.BigEndian.PutUint64(b[3:], c.pos)
+	binary.BigEndian.PutUint64(b[11:], c.skip)
+	return b
+}
+
+// hexBytes returns the encoded cursor, nil if there is none
+func (c *searchCursor) hexBytes() *hexutil.Bytes {
+	if c == nil {
+		return nil
+	}
+	b := c.encode()
+	return &b
+}
+
+// decodeSearchCursor decodes a cursor returned by a search of the given kind, on the same kind of indexes
+func decodeSearchCursor(b hexutil.Bytes, kind searchCursorKind, temporal bool) (*searchCursor, error) {
+	if len(b) != 3+8+8 || b[0] != searchCursorVersion || b[2] > 1 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	c := &searchCursor{
+		kind:     searchCursorKind(b[1]),
+		temporal: b[2] == 1,
+		pos:      binary.BigEndian.Uint64(b[3:]),
+		skip:     binary.BigEndian.Uint64(b[11:]),
+	}
+	if c.kind != kind {
+		return nil, fmt.Errorf("invalid cursor: it was returned by another search")
+	}
+	if c.temporal != temporal {
+		return nil, fmt.Errorf("invalid cursor: it was returned by a node with another history layout")
+	}
+	return c, nil
+}
+
+// searchPage decides which of the results of a search are returned, either in the page delimited by the
+// after and count parameters of the request, or in the page starting at a cursor. In the latter case, the
+// page ends at the first result past count, whose position is the cursor of the next page.
+type searchPage struct {
+	kind     searchCursorKind
+	temporal bool
+	paged    bool // The page starts at a cursor rather than after the given number of results
+	after    uint64
+	count    uint64
+	resume   *searchCursor
+
+	pos       uint64 // Entry of the index the current results belong to
+	nSeenAt   uint64 // Number of results seen at pos
+	nSeen     uint64
+	nExported uint64
+	next      *searchCursor
+}
+
+func newSearchPage(kind searchCursorKind, temporal bool, after, count *uint64, cursor *hexutil.Bytes) (*searchPage, error) {
+	p := &searchPage{kind: kind, temporal: temporal, count: ^uint64(0)}
+	if count != nil {
+		p.count = *count
+	}
+	if after != nil {
+		p.after = *after
+	}
+	if cursor == nil {
+		return p, nil
+	}
+	if after != nil {
+		return nil, fmt.Errorf("invalid parameters: after and cursor cannot be used together")
+	}
+	if p.count == 0 {
+		// An empty page would hand back the cursor it started at, and never let the search progress
+		return nil, fmt.Errorf("invalid parameters: count must be positive when paging with a cursor")
+	}
+	p.paged = true
+	if len(*cursor) > 0 {
+		resume, err := decodeSearchCursor(*cursor, kind, temporal)
+		if err != nil {
+			return nil, err
+		}
+		p.resume = resume
+	}
+	return p, nil
+}
+
+// at moves the page to the given entry of the index, the results seen next belonging to it
+func (p *searchPage) at(pos uint64) {
+	if pos != p.pos {
+		p.pos = pos
+		p.nSeenAt = 0
+	}
+}
+
+// seen counts a result of the current entry of the index
+func (p *searchPage) seen() {
+	p.nSeen++
+	p.nSeenAt++
+}
+
+// export tells whether the last result seen is part of the page
+func (p *searchPage) export() bool {
+	if !p.paged {
+		if p.nSeen > p.after && p.nExported < p.count {
+			p.nExported++
+			return true
+		}
+		return false
+	}
+	if p.resume != nil && p.pos == p.resume.pos && p.nSeenAt <= p.resume.skip {
+		return false
+	}
+	if p.nExported >= p.count {
+		if p.next == nil {
+			p.next = &searchCursor{kind: p.kind, temporal: p.temporal, pos: p.pos, skip: p.nSeenAt - 1}
+		}
+		return false
+	}
+	p.nExported++
+	return true
+}
+
+// full tells whether the page is complete, the rest of the search being left to the next page
+func (p *searchPage) full() bool {
+	return p.next != nil
+}
+
+// nextCursor returns the cursor of the next page, nil when the search is complete
+func (p *searchPage) nextCursor() *hexutil.Bytes {
+	return p.next.hexBytes()
+}
@@ -20,12 +20,19 @@ import (
 	"github.com/ledgerwatch/erigon/rlp"
 )
 
+// errBlobTxNotSupported is returned for blob transactions, which the transaction pool can't parse yet
+var errBlobTxNotSupported = errors.New("blob transactions are not supported by the transaction pool")
+
 // SendRawTransaction implements eth_sendRawTransaction. Creates new message call transaction or a contract creation for previously-signed transactions.
 func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
-	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(encodedTx), uint64(len(encodedTx))))
+	// Blob transactions are decoded in their network form, with their blobs, only to be rejected with a clear error
+	txn, err := types.DecodeWrappedTransaction(rlp.NewStream(bytes.NewReader(encodedTx), uint64(len(encodedTx))))
 	if err != nil {
 		return common.Hash{}, err
 	}
+	if txn.Type() == types.BlobTxType {
+		return common.Hash{}, errBlobTxNotSupported
+	}
 
 	// If the transaction fee cap is already specified, ensure the
 	// fee of the given transaction is _reasonable_.
@@ -368,6 +368,16 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	if api.historyV3(dbtx) {
 		return api.filterV3(ctx, dbtx.(kv.TemporalTx), fromBlock, toBlock, req, stream)
 	}
+	page, err := newSearchPage(traceFilterCursor, false, req.After, req.Count, req.Cursor)
+	if err != nil {
+		return err
+	}
+	if page.resume != nil {
+		if page.resume.pos < fromBlock || page.resume.pos > toBlock {
+			return fmt.Errorf("invalid cursor: block %d is out of the range of the filter", page.resume.pos)
+		}
+		fromBlock = page.resume.pos
+	}
 	toBlock++ //+1 because internally Erigon using semantic [from, to), but some RPC have different semantic
 	fromAddresses, toAddresses, allBlocks, err := traceFilterBitmaps(dbtx, req, fromBlock, toBlock)
 	if err != nil {
@@ -380,25 +390,15 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	}
 
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
-	stream.WriteArrayStart()
+	writeTraceFilterStart(stream, page)
 	first := true
 	// Execute all transactions in picked blocks
 
-	count := uint64(^uint(0)) // this just makes it easier to use below
-	if req.Count != nil {
-		count = *req.Count
-	}
-	after := uint64(0) // this just makes it easier to use below
-	if req.After != nil {
-		after = *req.After
-	}
-	nSeen := uint64(0)
-	nExported := uint64(0)
-
 	it := allBlocks.Iterator()
 	isPos := false
-	for it.HasNext() {
+	for it.HasNext() && !page.full() {
 		b := it.Next()
+		page.at(b)
 		// Extract transactions from block
 		hash, hashErr := rawdb.ReadCanonicalHash(dbtx, b)
 		if hashErr != nil {
@@ -463,7 +463,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 			// Check if transaction concerns any of the addresses we wanted
 			for _, pt := range trace.Trace {
 				if includeAll || filter_trace(pt, fromAddresses, toAddresses) {
-					nSeen++
+					page.seen()
 					pt.BlockHash = &blockHash
 					pt.BlockNumber = &blockNumber
 					pt.TransactionHash = &txHash
@@ -480,14 +480,13 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 						stream.WriteObjectEnd()
 						continue
 					}
-					if nSeen > after && nExported < count {
+					if page.export() {
 						if first {
 							first = false
 						} else {
 							stream.WriteMore()
 						}
 						stream.Write(b)
-						nExported++
 					}
 				}
 			}
@@ -501,7 +500,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 
 		minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, block.Header(), block.Uncles())
 		if _, ok := toAddresses[block.Coinbase()]; ok || includeAll {
-			nSeen++
+			page.seen()
 			var tr ParityTrace
 			var rewardAction = &RewardTraceAction{}
 			rewardAction.Author = block.Coinbase()
@@ -526,20 +525,19 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 				stream.WriteObjectEnd()
 				continue
 			}
-			if nSeen > after && nExported < count {
+			if page.export() {
 				if first {
 					first = false
 				} else {
 					stream.WriteMore()
 				}
 				stream.Write(b)
-				nExported++
 			}
 		}
 		for i, uncle := range block.Uncles() {
 			if _, ok := toAddresses[uncle.Coinbase]; ok || includeAll {
 				if i < len(uncleRewards) {
-					nSeen++
+					page.seen()
 					var tr ParityTrace
 					rewardAction := &RewardTraceAction{}
 					rewardAction.Author = uncle.Coinbase
@@ -564,21 +562,19 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 						stream.WriteObjectEnd()
 						continue
 					}
-					if nSeen > after && nExported < count {
+					if page.export() {
 						if first {
 							first = false
 						} else {
 							stream.WriteMore()
 						}
 						stream.Write(b)
-						nExported++
 					}
 				}
 			}
 		}
 	}
-	stream.WriteArrayEnd()
-	return stream.Flush()
+	return writeTraceFilterEnd(stream, page)
 }
 
 func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromBlock, toBlock uint64, req TraceFilterRequest, stream *jsoniter.Stream) error {
@@ -594,6 +590,16 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 	if err != nil {
 		return err
 	}
+	page, err := newSearchPage(traceFilterCursor, true, req.After, req.Count, req.Cursor)
+	if err != nil {
+		return err
+	}
+	if page.resume != nil {
+		if page.resume.pos < fromTxNum || page.resume.pos > toTxNum {
+			return fmt.Errorf("invalid cursor: txNum %d is out of the range of the filter", page.resume.pos)
+		}
+		fromTxNum = page.resume.pos
+	}
 	toTxNum++ //+1 because internally Erigon using semantic [from, to), but some RPC have different semantic
 	fromAddresses, toAddresses, allTxs, err := traceFilterBitmapsV3(dbtx, req, fromTxNum, toTxNum)
 	if err != nil {
@@ -607,21 +613,11 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 	engine := api.engine()
 
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
-	stream.WriteArrayStart()
+	writeTraceFilterStart(stream, page)
 	first := true
 	// Execute all transactions in picked blocks
 
-	count := uint64(^uint(0)) // this just makes it easier to use below
-	if req.Count != nil {
-		count = *req.Count
-	}
-	after := uint64(0) // this just makes it easier to use below
-	if req.After != nil {
-		after = *req.After
-	}
 	vmConfig := vm.Config{}
-	nSeen := uint64(0)
-	nExported := uint64(0)
 	includeAll := len(fromAddresses) == 0 && len(toAddresses) == 0
 	it := MapTxNum2BlockNum(dbtx, allTxs)
 
@@ -634,8 +630,9 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 	stateReader.SetTx(dbtx)
 	noop := state.NewNoopWriter()
 	isPos := false
-	for it.HasNext() {
+	for it.HasNext() && !page.full() {
 		txNum, blockNum, txIndex, isFnalTxn, blockNumChanged, err := it.Next()
+		page.at(txNum)
 		if err != nil {
 			if first {
 				first = false
@@ -703,7 +700,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 			// Block reward section, handle specially
 			minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, lastHeader, body.Uncles)
 			if _, ok := toAddresses[lastHeader.Coinbase]; ok || includeAll {
-				nSeen++
+				page.seen()
 				var tr ParityTrace
 				var rewardAction = &RewardTraceAction{}
 				rewardAction.Author = lastHeader.Coinbase
@@ -728,20 +725,19 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 					stream.WriteObjectEnd()
 					continue
 				}
-				if nSeen > after && nExported < count {
+				if page.export() {
 					if first {
 						first = false
 					} else {
 						stream.WriteMore()
 					}
 					stream.Write(b)
-					nExported++
 				}
 			}
 			for i, uncle := range body.Uncles {
 				if _, ok := toAddresses[uncle.Coinbase]; ok || includeAll {
 					if i < len(uncleRewards) {
-						nSeen++
+						page.seen()
 						var tr ParityTrace
 						rewardAction := &RewardTraceAction{}
 						rewardAction.Author = uncle.Coinbase
@@ -766,14 +762,13 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 							stream.WriteObjectEnd()
 							continue
 						}
-						if nSeen > after && nExported < count {
+						if page.export() {
 							if first {
 								first = false
 							} else {
 								stream.WriteMore()
 							}
 							stream.Write(b)
-							nExported++
 						}
 					}
 				}
@@ -873,7 +868,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 		}
 		for _, pt := range traceResult.Trace {
 			if includeAll || filter_trace(pt, fromAddresses, toAddresses) {
-				nSeen++
+				page.seen()
 				pt.BlockHash = &lastBlockHash
 				pt.BlockNumber = &blockNum
 				pt.TransactionHash = &txHash
@@ -890,19 +885,42 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 					stream.WriteObjectEnd()
 					continue
 				}
-				if nSeen > after && nExported < count {
+				if page.export() {
 					if first {
 						first = false
 					} else {
 						stream.WriteMore()
 					}
 					stream.Write(b)
-					nExported++
 				}
 			}
 		}
 	}
+	return writeTraceFilterEnd(stream, page)
+}
+
+// writeTraceFilterStart starts the result of trace_filter, an array of traces unless a cursor was requested
+func writeTraceFilterStart(stream *jsoniter.Stream, page *searchPage) {
+	if page.paged {
+		stream.WriteObjectStart()
+		stream.WriteObjectField("traces")
+	}
+	stream.WriteArrayStart()
+}
+
+// writeTraceFilterEnd ends the result of trace_filter, with the cursor of the next page if one was requested
+func writeTraceFilterEnd(stream *jsoniter.Stream, page *searchPage) error {
 	stream.WriteArrayEnd()
+	if page.paged {
+		stream.WriteMore()
+		stream.WriteObjectField("nextCursor")
+		if cursor := page.nextCursor(); cursor != nil {
+			stream.WriteString(cursor.String())
+		} else {
+			stream.WriteNil()
+		}
+		stream.WriteObjectEnd()
+	}
 	return stream.Flush()
 }
 
@@ -976,6 +994,9 @@ type TraceFilterRequest struct {
 	Mode        TraceFilterMode   `json:"mode"`
 	After       *uint64           `json:"after"`
 	Count       *uint64           `json:"count"`
+	// Cursor, when set, pages the traces from the cursor returned with the previous page, or from the
+	// start if empty. The result then holds the page of traces along with the cursor of the next one.
+	Cursor *hexutil.Bytes `json:"cursor"`
 }
 
 type TraceFilterMode string
@@ -2,12 +2,14 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"time"
 
 	"github.com/holiman/uint256"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/log/v3"
 
@@ -83,6 +85,11 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 	if config.BorTraceEnabled == nil {
 		config.BorTraceEnabled = newBoolPtr(false)
 	}
+	combine, err := traceCombiner(config)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
 
 	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
@@ -99,7 +106,10 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 
 	signer := types.MakeSigner(chainConfig, block.NumberU64())
 	rules := chainConfig.Rules(block.NumberU64(), block.Time())
-	stream.WriteArrayStart()
+	var results []json.RawMessage
+	if combine == nil {
+		stream.WriteArrayStart()
+	}
 
 	borTx, _, _, _ := rawdb.ReadBorTransactionForBlock(tx, block)
 	txns := block.Transactions()
@@ -108,8 +118,10 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 	}
 
 	for idx, txn := range txns {
-		stream.WriteObjectStart()
-		stream.WriteObjectField("result")
+		if combine == nil {
+			stream.WriteObjectStart()
+			stream.WriteObjectField("result")
+		}
 		select {
 		default:
 		case <-ctx.Done():
@@ -138,6 +150,19 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 			}
 		}
 
+		if combine != nil {
+			result, err := api.traceTxResult(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig)
+			if err == nil {
+				err = ibs.FinalizeTx(rules, state.NewNoopWriter())
+			}
+			if err != nil {
+				stream.WriteNil()
+				return err
+			}
+			results = append(results, result)
+			continue
+		}
+
 		err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
 		if err == nil {
 			err = ibs.FinalizeTx(rules, state.NewNoopWriter())
@@ -159,6 +184,9 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 		}
 		stream.Flush()
 	}
+	if combine != nil {
+		return writeCombined(combine, results, stream)
+	}
 	stream.WriteArrayEnd()
 	stream.Flush()
 	return nil
@@ -351,6 +379,11 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 		stream.WriteNil()
 		return fmt.Errorf("empty bundles")
 	}
+	combine, err := traceCombiner(config)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
 
 	defer func(start time.Time) { log.Trace("Tracing CallMany finished", "runtime", time.Since(start)) }(time.Now())
 
@@ -465,7 +498,10 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 
 	stream.WriteArrayStart()
 	for bundle_index, bundle := range bundles {
-		stream.WriteArrayStart()
+		var results []json.RawMessage
+		if combine == nil {
+			stream.WriteArrayStart()
+		}
 		// first change blockContext
 		blockHeaderOverride(&blockCtx, bundle.BlockOverride, overrideBlockHash)
 		for txn_index, txn := range bundle.Transactions {
@@ -480,6 +516,16 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 			txCtx = core.NewEVMTxContext(msg)
 			ibs := evm.IntraBlockState().(*state.IntraBlockState)
 			ibs.Prepare(common.Hash{}, parent.Hash(), txn_index)
+			if combine != nil {
+				result, err := api.traceTxResult(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig)
+				if err != nil {
+					stream.WriteNil()
+					return err
+				}
+				results = append(results, result)
+				_ = ibs.FinalizeTx(rules, state.NewNoopWriter())
+				continue
+			}
 			err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, evm.IntraBlockState(), config, chainConfig, stream, api.evmCallTimeout)
 
 			if err != nil {
@@ -493,7 +539,13 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 				stream.WriteMore()
 			}
 		}
-		stream.WriteArrayEnd()
+		if combine != nil {
+			if err := writeCombined(combine, results, stream); err != nil {
+				return err
+			}
+		} else {
+			stream.WriteArrayEnd()
+		}
 
 		if bundle_index < len(bundles)-1 {
 			stream.WriteMore()
@@ -505,6 +557,42 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 	return nil
 }
 
+// traceCombiner returns the function combining the results of the tracer of the config over the traced
+// transactions, nil when they are not to be combined.
+func traceCombiner(config *tracers.TraceConfig) (tracers.CombineFunc, error) {
+	if config == nil || config.Combine == nil || !*config.Combine {
+		return nil, nil
+	}
+	if config.Tracer == nil {
+		return nil, fmt.Errorf("combined traces need a tracer")
+	}
+	combine := tracers.Combiner(*config.Tracer)
+	if combine == nil {
+		return nil, fmt.Errorf("results of tracer %s can't be combined", *config.Tracer)
+	}
+	return combine, nil
+}
+
+// traceTxResult traces the message like transactions.TraceTx, returning the result of the tracer
+func (api *PrivateDebugAPIImpl) traceTxResult(ctx context.Context, msg core.Message, blockCtx evmtypes.BlockContext, txCtx evmtypes.TxContext, ibs evmtypes.IntraBlockState, config *tracers.TraceConfig, chainConfig *chain.Config) (json.RawMessage, error) {
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, nil, 4096)
+	if err := transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout); err != nil {
+		return nil, err
+	}
+	return common.Copy(stream.Buffer()), nil
+}
+
+func writeCombined(combine tracers.CombineFunc, results []json.RawMessage, stream *jsoniter.Stream) error {
+	combined, err := combine(results)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	stream.Write(combined)
+	stream.Flush()
+	return nil
+}
+
 func newBoolPtr(bb bool) *bool {
 	b := bb
 	return &b
@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"testing"
 
 	"github.com/holiman/uint256"
@@ -17,6 +18,7 @@ import (
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
@@ -69,3 +71,51 @@ func TestTxPoolContent(t *testing.T) {
 	require.Equal(status["pending"], hexutil.Uint(1))
 	require.Equal(status["queued"], hexutil.Uint(0))
 }
+
+func TestSendRawBlobTransaction(t *testing.T) {
+	m, require := stages.MockWithTxPool(t), require.New(t)
+	if m.HistoryV3 {
+		t.Skip("HistoryV3: please implement StateStream support")
+	}
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 1, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(libcommon.Address{1})
+	}, false /* intermediateHashes */)
+	require.NoError(err)
+	require.NoError(m.InsertChain(chain))
+
+	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, m)
+	txPool := txpool.NewTxpoolClient(conn)
+	ff := rpchelper.New(ctx, nil, txPool, txpool.NewMiningClient(conn), func() {})
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	api := NewEthAPI(NewBaseApi(ff, kvcache.New(kvcache.DefaultCoherentConfig), br, m.HistoryV3Components(), false, rpccfg.DefaultEvmCallTimeout, m.Engine), m.DB, nil, txPool, nil, 5000000, 100_000, 100_000, "")
+	signer := *types.LatestSignerForChainID(m.ChainConfig.ChainID)
+	chainID, _ := uint256.FromBig(m.ChainConfig.ChainID)
+
+	commitments := []types.KZGCommitment{{0x01}}
+	unsigned := types.NewBlobTx(*chainID, 0, libcommon.Address{1}, uint256.NewInt(1234), params.TxGas, uint256.NewInt(params.GWei), uint256.NewInt(10*params.GWei), uint256.NewInt(1), []libcommon.Hash{commitments[0].VersionedHash()}, nil)
+	blobTx, err := types.SignTx(unsigned, signer, m.Key)
+	require.NoError(err)
+	wrapper := &types.BlobTxWrapper{BlobTx: *blobTx.(*types.BlobTx), Blobs: []types.Blob{{0xaa}}, Commitments: commitments, Proofs: []types.KZGProof{{0x0a}}}
+	for _, txn := range []interface{ MarshalBinary(w io.Writer) error }{wrapper, blobTx} {
+		buf := bytes.NewBuffer(nil)
+		require.NoError(txn.MarshalBinary(buf))
+		_, err = api.SendRawTransaction(ctx, buf.Bytes())
+		require.ErrorIs(err, errBlobTxNotSupported)
+	}
+
+	// Other transactions still go through to the pool
+	legacyTx, err := types.SignTx(types.NewTransaction(0, libcommon.Address{1}, uint256.NewInt(1234), params.TxGas, uint256.NewInt(10*params.GWei), nil), signer, m.Key)
+	require.NoError(err)
+	buf := bytes.NewBuffer(nil)
+	require.NoError(legacyTx.MarshalBinary(buf))
+	hash, err := api.SendRawTransaction(ctx, buf.Bytes())
+	require.NoError(err)
+	require.Equal(legacyTx.Hash(), hash)
+
+	reply, err := txPool.All(ctx, &txpool.AllRequest{})
+	require.NoError(err)
+	require.Equal(1, len(reply.Txs))
+	pooled, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(reply.Txs[0].RlpTx), 0))
+	require.NoError(err)
+	require.Equal(legacyTx.Hash(), pooled.Hash())
+}
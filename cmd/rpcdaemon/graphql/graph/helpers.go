@@ -1,21 +1,31 @@
 package graph
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strconv"
+	"strings"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/graphql/graph/model"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/adapter/ethapi"
 )
 
 func convertDataToStringP(abstractMap map[string]interface{}, field string) *string {
 	var result string
 
-	if reflect.ValueOf(abstractMap[field]).IsZero() {
+	if abstractMap[field] == nil || reflect.ValueOf(abstractMap[field]).IsZero() {
 		return nil
 	}
 
@@ -52,6 +62,15 @@ func convertDataToStringP(abstractMap map[string]interface{}, field string) *str
 	return &result
 }
 
+// convertDataToString is convertDataToStringP with the value of zero or missing fields being def.
+func convertDataToString(abstractMap map[string]interface{}, field string, def string) string {
+	if result := convertDataToStringP(abstractMap, field); result != nil {
+		return *result
+	}
+
+	return def
+}
+
 func convertDataToIntP(abstractMap map[string]interface{}, field string) *int {
 	var result int
 
@@ -101,9 +120,9 @@ func convertDataToUint64P(abstractMap map[string]interface{}, field string) *uin
 	case *hexutil.Big:
 		result = v.ToInt().Uint64()
 	case int:
-		result = abstractMap[field].(uint64)
+		result = uint64(v)
 	case uint64:
-		result = abstractMap[field].(uint64)
+		result = v
 	default:
 		fmt.Println("unhandled/uint64", reflect.TypeOf(abstractMap[field]), field, abstractMap[field])
 		result = 0
@@ -124,3 +143,375 @@ func convertStrHexToDec(hexString *string) *string {
 
 	return &result
 }
+
+// convertBlock converts the block details returned by GraphQLAPI.GetBlockDetails.
+func convertBlock(details map[string]interface{}) *model.Block {
+	blk := details["block"].(map[string]interface{})
+	block := convertHeader(blk)
+	block.TransactionCount = convertDataToIntP(blk, "transactionCount")
+	block.NextBaseFeePerGas = convertDataToStringP(blk, "nextBaseFeePerGas")
+	block.Raw = convertDataToString(blk, "raw", "0x")
+
+	block.Ommers = []*model.Block{}
+	if ommers, ok := details["ommers"].([]map[string]interface{}); ok {
+		for _, ommer := range ommers {
+			block.Ommers = append(block.Ommers, convertHeader(ommer))
+		}
+	}
+	ommerCount := len(block.Ommers)
+	block.OmmerCount = &ommerCount
+
+	block.Transactions = []*model.Transaction{}
+	if rcp, ok := details["receipts"].([]map[string]interface{}); ok {
+		for _, transReceipt := range rcp {
+			block.Transactions = append(block.Transactions, convertTransaction(transReceipt, block.Hash))
+		}
+	}
+
+	return block
+}
+
+// convertHeader converts a block header marshalled by ethapi.RPCMarshalHeader, which is all
+// there is of ommers.
+func convertHeader(blk map[string]interface{}) *model.Block {
+	block := &model.Block{}
+	block.Number = *convertDataToUint64P(blk, "number")
+	block.Hash = convertDataToString(blk, "hash", "")
+	block.ParentHash = convertDataToString(blk, "parentHash", "")
+	block.Nonce = convertDataToString(blk, "nonce", "0x0")
+	block.TransactionsRoot = convertDataToString(blk, "transactionsRoot", "")
+	block.StateRoot = convertDataToString(blk, "stateRoot", "")
+	block.ReceiptsRoot = convertDataToString(blk, "receiptsRoot", "")
+	block.MinerAddress = strings.ToLower(convertDataToString(blk, "miner", libcommon.Address{}.String()))
+	block.ExtraData = convertDataToString(blk, "extraData", "0x")
+	block.GasLimit = *convertDataToUint64P(blk, "gasLimit")
+	block.GasUsed = *convertDataToUint64P(blk, "gasUsed")
+	block.BaseFeePerGas = convertDataToStringP(blk, "baseFeePerGas")
+	block.Timestamp = *convertDataToUint64P(blk, "timestamp") // int in the schema but Geth displays in HEX !!!
+	block.LogsBloom = "0x" + convertDataToString(blk, "logsBloom", "")
+	block.MixHash = convertDataToString(blk, "mixHash", libcommon.Hash{}.String())
+	block.Difficulty = convertDataToString(blk, "difficulty", "0x0")
+	block.TotalDifficulty = convertDataToString(blk, "totalDifficulty", "0x0")
+	block.OmmerHash = convertDataToString(blk, "sha3Uncles", "")
+	block.RawHeader = convertDataToString(blk, "rawHeader", "0x")
+
+	return block
+}
+
+// convertTransaction converts a transaction of the block details returned by
+// GraphQLAPI.GetBlockDetails, its receipt fields being set when it was executed.
+func convertTransaction(transReceipt map[string]interface{}, blockHash string) *model.Transaction {
+	trans := &model.Transaction{}
+	trans.Hash = convertDataToString(transReceipt, "transactionHash", "")
+	trans.Nonce = *convertDataToUint64P(transReceipt, "nonce")
+	trans.Index = convertDataToIntP(transReceipt, "transactionIndex")
+	trans.FromAddress = strings.ToLower(convertDataToString(transReceipt, "from", libcommon.Address{}.String()))
+	// To address could be nil in case of contract creation
+	if address := convertDataToStringP(transReceipt, "to"); address != nil {
+		to := strings.ToLower(*address)
+		trans.ToAddress = &to
+	}
+	trans.Value = convertDataToString(transReceipt, "value", "0x0")
+	trans.GasPrice = convertDataToString(transReceipt, "gasPrice", "0x0")
+	trans.MaxFeePerGas = convertDataToStringP(transReceipt, "maxFeePerGas")
+	trans.MaxPriorityFeePerGas = convertDataToStringP(transReceipt, "maxPriorityFeePerGas")
+	if _, ok := transReceipt["effectiveTip"]; ok {
+		effectiveTip := convertDataToString(transReceipt, "effectiveTip", "0x0")
+		trans.EffectiveTip = &effectiveTip
+	}
+	trans.Gas = *convertDataToUint64P(transReceipt, "gas")
+	trans.InputData = convertDataToString(transReceipt, "data", "0x")
+	trans.R = convertDataToString(transReceipt, "r", "0x0")
+	trans.S = convertDataToString(transReceipt, "s", "0x0")
+	trans.V = convertDataToString(transReceipt, "v", "0x0")
+	trans.Type = convertDataToIntP(transReceipt, "type")
+	if accessList, ok := transReceipt["accessList"].(types2.AccessList); ok {
+		trans.AccessList = convertAccessList(accessList)
+	}
+	trans.Raw = convertDataToString(transReceipt, "raw", "0x")
+	if blockHash != "" {
+		trans.BlockHash = &blockHash
+	}
+
+	if _, executed := transReceipt["status"]; executed {
+		trans.Status = convertDataToUint64P(transReceipt, "status")
+		trans.GasUsed = convertDataToUint64P(transReceipt, "gasUsed")
+		trans.CumulativeGasUsed = convertDataToUint64P(transReceipt, "cumulativeGasUsed")
+		effectiveGasPrice := convertDataToString(transReceipt, "effectiveGasPrice", "0x0")
+		trans.EffectiveGasPrice = &effectiveGasPrice
+		trans.RawReceipt = convertDataToString(transReceipt, "rawReceipt", "0x")
+		if address := convertDataToStringP(transReceipt, "contractAddress"); address != nil {
+			createdContract := strings.ToLower(*address)
+			trans.CreatedContractAddress = &createdContract
+		}
+
+		trans.Logs = make([]*model.Log, 0)
+		if logs, ok := transReceipt["logs"].([]*types.Log); ok {
+			for _, log := range logs {
+				trans.Logs = append(trans.Logs, convertLog(log, trans))
+			}
+		}
+	} else {
+		trans.RawReceipt = "0x"
+	}
+
+	return trans
+}
+
+// convertRPCTransaction converts a transaction that was not executed yet.
+func convertRPCTransaction(txn *commands.RPCTransaction) *model.Transaction {
+	trans := &model.Transaction{}
+	trans.Hash = txn.Hash.String()
+	trans.Nonce = uint64(txn.Nonce)
+	trans.FromAddress = strings.ToLower(txn.From.String())
+	if txn.To != nil {
+		to := strings.ToLower(txn.To.String())
+		trans.ToAddress = &to
+	}
+	trans.Value = bigToString(txn.Value)
+	trans.GasPrice = bigToString(txn.GasPrice)
+	if txn.FeeCap != nil {
+		maxFeePerGas := txn.FeeCap.String()
+		trans.MaxFeePerGas = &maxFeePerGas
+		// The price paid by a dynamic fee transaction is only known once it's in a block
+		trans.GasPrice = maxFeePerGas
+	}
+	if txn.Tip != nil {
+		maxPriorityFeePerGas := txn.Tip.String()
+		trans.MaxPriorityFeePerGas = &maxPriorityFeePerGas
+	}
+	trans.Gas = uint64(txn.Gas)
+	trans.InputData = txn.Input.String()
+	trans.R = bigToString(txn.R)
+	trans.S = bigToString(txn.S)
+	trans.V = bigToString(txn.V)
+	txType := int(txn.Type)
+	trans.Type = &txType
+	if txn.Accesses != nil {
+		trans.AccessList = convertAccessList(*txn.Accesses)
+	}
+	trans.Raw = "0x"
+	trans.RawReceipt = "0x"
+
+	return trans
+}
+
+func convertLog(log *types.Log, trans *model.Transaction) *model.Log {
+	topics := make([]string, 0, len(log.Topics))
+	for _, topic := range log.Topics {
+		topics = append(topics, topic.String())
+	}
+
+	return &model.Log{
+		Index:           int(log.Index),
+		Topics:          topics,
+		Data:            hexutil.Bytes(log.Data).String(),
+		Address:         strings.ToLower(log.Address.String()),
+		TransactionHash: log.TxHash.String(),
+		Tx:              trans,
+	}
+}
+
+func convertAccessList(accessList types2.AccessList) []*model.AccessTuple {
+	result := make([]*model.AccessTuple, 0, len(accessList))
+	for _, tuple := range accessList {
+		storageKeys := make([]string, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			storageKeys = append(storageKeys, key.String())
+		}
+		result = append(result, &model.AccessTuple{Address: strings.ToLower(tuple.Address.String()), StorageKeys: storageKeys})
+	}
+
+	return result
+}
+
+func convertCallData(data model.CallData) (ethapi.CallArgs, error) {
+	var args ethapi.CallArgs
+	if data.From != nil {
+		from := libcommon.HexToAddress(*data.From)
+		args.From = &from
+	}
+	if data.To != nil {
+		to := libcommon.HexToAddress(*data.To)
+		args.To = &to
+	}
+	if data.Gas != nil {
+		args.Gas = (*hexutil.Uint64)(data.Gas)
+	}
+	for _, field := range []struct {
+		in  *string
+		out **hexutil.Big
+	}{
+		{data.GasPrice, &args.GasPrice},
+		{data.MaxFeePerGas, &args.MaxFeePerGas},
+		{data.MaxPriorityFeePerGas, &args.MaxPriorityFeePerGas},
+		{data.Value, &args.Value},
+	} {
+		if field.in == nil {
+			continue
+		}
+		value, err := parseBigInt(*field.in)
+		if err != nil {
+			return args, err
+		}
+		*field.out = (*hexutil.Big)(value)
+	}
+	if data.Data != nil {
+		input, err := hexutil.Decode(*data.Data)
+		if err != nil {
+			return args, err
+		}
+		args.Data = (*hexutil.Bytes)(&input)
+	}
+
+	return args, nil
+}
+
+// parseBigInt parses a BigInt input, decimal or 0x prefixed hexadecimal.
+func parseBigInt(in string) (*big.Int, error) {
+	if strings.HasPrefix(in, "0x") || strings.HasPrefix(in, "0X") {
+		return hexutil.DecodeBig(in)
+	}
+	value, ok := new(big.Int).SetString(in, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid BigInt %q", in)
+	}
+
+	return value, nil
+}
+
+func convertTopics(in [][]string) [][]libcommon.Hash {
+	topics := make([][]libcommon.Hash, 0, len(in))
+	for _, alternatives := range in {
+		hashes := make([]libcommon.Hash, 0, len(alternatives))
+		for _, topic := range alternatives {
+			hashes = append(hashes, libcommon.HexToHash(topic))
+		}
+		topics = append(topics, hashes)
+	}
+
+	return topics
+}
+
+func convertAddresses(in []string) []libcommon.Address {
+	addresses := make([]libcommon.Address, 0, len(in))
+	for _, address := range in {
+		addresses = append(addresses, libcommon.HexToAddress(address))
+	}
+
+	return addresses
+}
+
+// blockNumberOrLatest returns the block of the given number, the latest one when nil.
+func blockNumberOrLatest(block *uint64) rpc.BlockNumberOrHash {
+	if block == nil {
+		return rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	}
+
+	return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(*block))
+}
+
+func bigToString(value *hexutil.Big) string {
+	if value == nil {
+		return "0x0"
+	}
+
+	return value.String()
+}
+
+// receiptFields are the fields of a transaction read from its receipt.
+var receiptFields = map[string]bool{
+	"status":            true,
+	"gasUsed":           true,
+	"cumulativeGasUsed": true,
+	"effectiveGasPrice": true,
+	"createdContract":   true,
+	"logs":              true,
+	"rawReceipt":        true,
+}
+
+// receiptRequested tells whether the query selects a field of the receipt of the transaction being
+// resolved, all of them being assumed selected outside of a query.
+func receiptRequested(ctx context.Context) bool {
+	if graphql.GetFieldContext(ctx) == nil {
+		return true
+	}
+	for _, field := range graphql.CollectAllFields(ctx) {
+		if receiptFields[field] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maxBlocksRange is the largest number of blocks returned by a blocks query.
+const maxBlocksRange = 1024
+
+func (r *Resolver) blockByNumber(ctx context.Context, number rpc.BlockNumber) (*model.Block, error) {
+	res, err := r.GraphQLAPI.GetBlockDetails(ctx, number)
+	if err != nil || res == nil {
+		return nil, err
+	}
+
+	return convertBlock(res), nil
+}
+
+func (r *Resolver) blockByHash(ctx context.Context, hash string) (*model.Block, error) {
+	res, err := r.GraphQLAPI.GetBlockDetailsByHash(ctx, libcommon.HexToHash(hash))
+	if err != nil || res == nil {
+		return nil, err
+	}
+
+	return convertBlock(res), nil
+}
+
+func (r *Resolver) latestBlockNumber(ctx context.Context) (uint64, error) {
+	latest, err := r.blockByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || latest == nil {
+		return 0, err
+	}
+
+	return latest.Number, nil
+}
+
+func (r *Resolver) call(ctx context.Context, data model.CallData, blockNrOrHash rpc.BlockNumberOrHash) (*model.CallResult, error) {
+	args, err := convertCallData(data)
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.GraphQLAPI.Call(ctx, args, blockNrOrHash)
+	if err != nil || result == nil {
+		return nil, err
+	}
+
+	var status uint64
+	if !result.Failed() {
+		status = 1
+	}
+
+	return &model.CallResult{
+		Data:    hexutil.Bytes(result.Return()).String(),
+		GasUsed: result.UsedGas,
+		Status:  status,
+	}, nil
+}
+
+func (r *Resolver) estimateGas(ctx context.Context, data model.CallData, blockNrOrHash rpc.BlockNumberOrHash) (uint64, error) {
+	args, err := convertCallData(data)
+	if err != nil {
+		return 0, err
+	}
+	gas, err := r.GraphQLAPI.EstimateGas(ctx, &args, &blockNrOrHash)
+
+	return uint64(gas), err
+}
+
+// blockNrOrHash returns the block to read the state of block from, the pending block has no hash.
+func blockNrOrHash(block *model.Block) rpc.BlockNumberOrHash {
+	if block.Hash == "" {
+		return rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	}
+
+	return rpc.BlockNumberOrHashWithHash(libcommon.HexToHash(block.Hash), false)
+}
@@ -0,0 +1,90 @@
+package model
+
+import "github.com/ledgerwatch/erigon/rpc"
+
+// The types of this file are bound to the schema instead of generated ones, their fields that
+// depend on the state or on other blocks being resolved lazily, by the resolvers of the graph
+// package, from the fields that are not part of the schema.
+
+// Account is an account at the state of a block.
+type Account struct {
+	Address string `json:"address"`
+	// BlockNrOrHash is the block whose state the account is read from
+	BlockNrOrHash rpc.BlockNumberOrHash `json:"-"`
+}
+
+type Block struct {
+	Number            uint64         `json:"number"`
+	Hash              string         `json:"hash"`
+	Nonce             string         `json:"nonce"`
+	TransactionsRoot  string         `json:"transactionsRoot"`
+	TransactionCount  *int           `json:"transactionCount"`
+	StateRoot         string         `json:"stateRoot"`
+	ReceiptsRoot      string         `json:"receiptsRoot"`
+	ExtraData         string         `json:"extraData"`
+	GasLimit          uint64         `json:"gasLimit"`
+	GasUsed           uint64         `json:"gasUsed"`
+	BaseFeePerGas     *string        `json:"baseFeePerGas"`
+	NextBaseFeePerGas *string        `json:"nextBaseFeePerGas"`
+	Timestamp         uint64         `json:"timestamp"`
+	LogsBloom         string         `json:"logsBloom"`
+	MixHash           string         `json:"mixHash"`
+	Difficulty        string         `json:"difficulty"`
+	TotalDifficulty   string         `json:"totalDifficulty"`
+	OmmerCount        *int           `json:"ommerCount"`
+	Ommers            []*Block       `json:"ommers"`
+	OmmerHash         string         `json:"ommerHash"`
+	Transactions      []*Transaction `json:"transactions"`
+	RawHeader         string         `json:"rawHeader"`
+	Raw               string         `json:"raw"`
+
+	ParentHash   string `json:"-"`
+	MinerAddress string `json:"-"`
+}
+
+type Transaction struct {
+	Hash                 string         `json:"hash"`
+	Nonce                uint64         `json:"nonce"`
+	Index                *int           `json:"index"`
+	Value                string         `json:"value"`
+	GasPrice             string         `json:"gasPrice"`
+	MaxFeePerGas         *string        `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *string        `json:"maxPriorityFeePerGas"`
+	EffectiveTip         *string        `json:"effectiveTip"`
+	Gas                  uint64         `json:"gas"`
+	InputData            string         `json:"inputData"`
+	Status               *uint64        `json:"status"`
+	GasUsed              *uint64        `json:"gasUsed"`
+	CumulativeGasUsed    *uint64        `json:"cumulativeGasUsed"`
+	EffectiveGasPrice    *string        `json:"effectiveGasPrice"`
+	Logs                 []*Log         `json:"logs"`
+	R                    string         `json:"r"`
+	S                    string         `json:"s"`
+	V                    string         `json:"v"`
+	Type                 *int           `json:"type"`
+	AccessList           []*AccessTuple `json:"accessList"`
+	Raw                  string         `json:"raw"`
+	RawReceipt           string         `json:"rawReceipt"`
+
+	FromAddress            string  `json:"-"`
+	ToAddress              *string `json:"-"`
+	CreatedContractAddress *string `json:"-"`
+	// BlockHash is nil for pending transactions
+	BlockHash *string `json:"-"`
+}
+
+type Log struct {
+	Index  int      `json:"index"`
+	Topics []string `json:"topics"`
+	Data   string   `json:"data"`
+
+	Address         string `json:"-"`
+	TransactionHash string `json:"-"`
+	// Tx is the transaction of the log when it's known, it's resolved from TransactionHash otherwise
+	Tx *Transaction `json:"-"`
+}
+
+type Pending struct {
+	TransactionCount int            `json:"transactionCount"`
+	Transactions     []*Transaction `json:"transactions"`
+}
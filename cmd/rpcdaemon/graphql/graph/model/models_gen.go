@@ -7,48 +7,6 @@ type AccessTuple struct {
 	StorageKeys []string `json:"storageKeys"`
 }
 
-type Account struct {
-	Address          string `json:"address"`
-	Balance          string `json:"balance"`
-	TransactionCount uint64 `json:"transactionCount"`
-	Code             string `json:"code"`
-	Storage          string `json:"storage"`
-}
-
-type Block struct {
-	Number            uint64         `json:"number"`
-	Hash              string         `json:"hash"`
-	Parent            *Block         `json:"parent"`
-	Nonce             string         `json:"nonce"`
-	TransactionsRoot  string         `json:"transactionsRoot"`
-	TransactionCount  *int           `json:"transactionCount"`
-	StateRoot         string         `json:"stateRoot"`
-	ReceiptsRoot      string         `json:"receiptsRoot"`
-	Miner             *Account       `json:"miner"`
-	ExtraData         string         `json:"extraData"`
-	GasLimit          uint64         `json:"gasLimit"`
-	GasUsed           uint64         `json:"gasUsed"`
-	BaseFeePerGas     *string        `json:"baseFeePerGas"`
-	NextBaseFeePerGas *string        `json:"nextBaseFeePerGas"`
-	Timestamp         uint64         `json:"timestamp"`
-	LogsBloom         string         `json:"logsBloom"`
-	MixHash           string         `json:"mixHash"`
-	Difficulty        string         `json:"difficulty"`
-	TotalDifficulty   string         `json:"totalDifficulty"`
-	OmmerCount        *int           `json:"ommerCount"`
-	Ommers            []*Block       `json:"ommers"`
-	OmmerAt           *Block         `json:"ommerAt"`
-	OmmerHash         string         `json:"ommerHash"`
-	Transactions      []*Transaction `json:"transactions"`
-	TransactionAt     *Transaction   `json:"transactionAt"`
-	Logs              []*Log         `json:"logs"`
-	Account           *Account       `json:"account"`
-	Call              *CallResult    `json:"call"`
-	EstimateGas       uint64         `json:"estimateGas"`
-	RawHeader         string         `json:"rawHeader"`
-	Raw               string         `json:"raw"`
-}
-
 type BlockFilterCriteria struct {
 	Addresses []string   `json:"addresses"`
 	Topics    [][]string `json:"topics"`
@@ -78,53 +36,8 @@ type FilterCriteria struct {
 	Topics    [][]string `json:"topics"`
 }
 
-type Log struct {
-	Index       int          `json:"index"`
-	Account     *Account     `json:"account"`
-	Topics      []string     `json:"topics"`
-	Data        string       `json:"data"`
-	Transaction *Transaction `json:"transaction"`
-}
-
-type Pending struct {
-	TransactionCount int            `json:"transactionCount"`
-	Transactions     []*Transaction `json:"transactions"`
-	Account          *Account       `json:"account"`
-	Call             *CallResult    `json:"call"`
-	EstimateGas      uint64         `json:"estimateGas"`
-}
-
 type SyncState struct {
 	StartingBlock uint64 `json:"startingBlock"`
 	CurrentBlock  uint64 `json:"currentBlock"`
 	HighestBlock  uint64 `json:"highestBlock"`
 }
-
-type Transaction struct {
-	Hash                 string         `json:"hash"`
-	Nonce                uint64         `json:"nonce"`
-	Index                *int           `json:"index"`
-	From                 *Account       `json:"from"`
-	To                   *Account       `json:"to"`
-	Value                string         `json:"value"`
-	GasPrice             string         `json:"gasPrice"`
-	MaxFeePerGas         *string        `json:"maxFeePerGas"`
-	MaxPriorityFeePerGas *string        `json:"maxPriorityFeePerGas"`
-	EffectiveTip         *string        `json:"effectiveTip"`
-	Gas                  uint64         `json:"gas"`
-	InputData            string         `json:"inputData"`
-	Block                *Block         `json:"block"`
-	Status               *uint64        `json:"status"`
-	GasUsed              *uint64        `json:"gasUsed"`
-	CumulativeGasUsed    *uint64        `json:"cumulativeGasUsed"`
-	EffectiveGasPrice    *string        `json:"effectiveGasPrice"`
-	CreatedContract      *Account       `json:"createdContract"`
-	Logs                 []*Log         `json:"logs"`
-	R                    string         `json:"r"`
-	S                    string         `json:"s"`
-	V                    string         `json:"v"`
-	Type                 *int           `json:"type"`
-	AccessList           []*AccessTuple `json:"accessList"`
-	Raw                  string         `json:"raw"`
-	RawReceipt           string         `json:"rawReceipt"`
-}
@@ -2,24 +2,162 @@ package graph
 
 // This file will be automatically regenerated based on the schema, any resolver implementations
 // will be copied through when generating and any unknown code will be moved to the end.
-// Code generated by github.com/99designs/gqlgen version v0.17.24
+// Code generated by github.com/99designs/gqlgen version v0.17.25
 
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/graphql/graph/model"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/eth/filters"
 	"github.com/ledgerwatch/erigon/rpc"
 )
 
+// Balance is the resolver for the balance field.
+func (r *accountResolver) Balance(ctx context.Context, obj *model.Account) (string, error) {
+	balance, err := r.GraphQLAPI.GetBalance(ctx, libcommon.HexToAddress(obj.Address), obj.BlockNrOrHash)
+	if err != nil {
+		return "", err
+	}
+
+	return balance.String(), nil
+}
+
+// TransactionCount is the resolver for the transactionCount field.
+func (r *accountResolver) TransactionCount(ctx context.Context, obj *model.Account) (uint64, error) {
+	nonce, err := r.GraphQLAPI.GetTransactionCount(ctx, libcommon.HexToAddress(obj.Address), obj.BlockNrOrHash)
+	if err != nil || nonce == nil {
+		return 0, err
+	}
+
+	return uint64(*nonce), nil
+}
+
+// Code is the resolver for the code field.
+func (r *accountResolver) Code(ctx context.Context, obj *model.Account) (string, error) {
+	code, err := r.GraphQLAPI.GetCode(ctx, libcommon.HexToAddress(obj.Address), obj.BlockNrOrHash)
+	if err != nil {
+		return "", err
+	}
+
+	return code.String(), nil
+}
+
+// Storage is the resolver for the storage field.
+func (r *accountResolver) Storage(ctx context.Context, obj *model.Account, slot string) (string, error) {
+	return r.GraphQLAPI.GetStorageAt(ctx, libcommon.HexToAddress(obj.Address), slot, obj.BlockNrOrHash)
+}
+
+// Parent is the resolver for the parent field.
+func (r *blockResolver) Parent(ctx context.Context, obj *model.Block) (*model.Block, error) {
+	if obj.Number == 0 {
+		return nil, nil
+	}
+
+	return r.blockByHash(ctx, obj.ParentHash)
+}
+
+// Miner is the resolver for the miner field.
+func (r *blockResolver) Miner(ctx context.Context, obj *model.Block, block *uint64) (*model.Account, error) {
+	return &model.Account{Address: obj.MinerAddress, BlockNrOrHash: blockNumberOrLatest(block)}, nil
+}
+
+// OmmerAt is the resolver for the ommerAt field.
+func (r *blockResolver) OmmerAt(ctx context.Context, obj *model.Block, index int) (*model.Block, error) {
+	if index < 0 || index >= len(obj.Ommers) {
+		return nil, nil
+	}
+
+	return obj.Ommers[index], nil
+}
+
+// TransactionAt is the resolver for the transactionAt field.
+func (r *blockResolver) TransactionAt(ctx context.Context, obj *model.Block, index int) (*model.Transaction, error) {
+	if index < 0 || index >= len(obj.Transactions) {
+		return nil, nil
+	}
+
+	return obj.Transactions[index], nil
+}
+
+// Logs is the resolver for the logs field.
+func (r *blockResolver) Logs(ctx context.Context, obj *model.Block, filter model.BlockFilterCriteria) ([]*model.Log, error) {
+	hash := libcommon.HexToHash(obj.Hash)
+	logs, err := r.GraphQLAPI.GetLogs(ctx, filters.FilterCriteria{
+		BlockHash: &hash,
+		Addresses: convertAddresses(filter.Addresses),
+		Topics:    convertTopics(filter.Topics),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Log, 0, len(logs))
+	for _, log := range logs {
+		var trans *model.Transaction
+		if int(log.TxIndex) < len(obj.Transactions) {
+			trans = obj.Transactions[log.TxIndex]
+		}
+		result = append(result, convertLog(log, trans))
+	}
+
+	return result, nil
+}
+
+// Account is the resolver for the account field.
+func (r *blockResolver) Account(ctx context.Context, obj *model.Block, address string) (*model.Account, error) {
+	return &model.Account{Address: strings.ToLower(address), BlockNrOrHash: blockNrOrHash(obj)}, nil
+}
+
+// Call is the resolver for the call field.
+func (r *blockResolver) Call(ctx context.Context, obj *model.Block, data model.CallData) (*model.CallResult, error) {
+	return r.call(ctx, data, blockNrOrHash(obj))
+}
+
+// EstimateGas is the resolver for the estimateGas field.
+func (r *blockResolver) EstimateGas(ctx context.Context, obj *model.Block, data model.CallData) (uint64, error) {
+	return r.estimateGas(ctx, data, blockNrOrHash(obj))
+}
+
+// Account is the resolver for the account field.
+func (r *logResolver) Account(ctx context.Context, obj *model.Log, block *uint64) (*model.Account, error) {
+	return &model.Account{Address: obj.Address, BlockNrOrHash: blockNumberOrLatest(block)}, nil
+}
+
+// Transaction is the resolver for the transaction field.
+func (r *logResolver) Transaction(ctx context.Context, obj *model.Log) (*model.Transaction, error) {
+	if obj.Tx != nil {
+		return obj.Tx, nil
+	}
+
+	return r.Query().Transaction(ctx, obj.TransactionHash)
+}
+
 // SendRawTransaction is the resolver for the sendRawTransaction field.
 func (r *mutationResolver) SendRawTransaction(ctx context.Context, data string) (string, error) {
 	panic(fmt.Errorf("not implemented: SendRawTransaction - sendRawTransaction"))
 }
 
+// Account is the resolver for the account field.
+func (r *pendingResolver) Account(ctx context.Context, obj *model.Pending, address string) (*model.Account, error) {
+	return &model.Account{Address: strings.ToLower(address), BlockNrOrHash: rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)}, nil
+}
+
+// Call is the resolver for the call field.
+func (r *pendingResolver) Call(ctx context.Context, obj *model.Pending, data model.CallData) (*model.CallResult, error) {
+	return r.call(ctx, data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+}
+
+// EstimateGas is the resolver for the estimateGas field.
+func (r *pendingResolver) EstimateGas(ctx context.Context, obj *model.Pending, data model.CallData) (uint64, error) {
+	return r.estimateGas(ctx, data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+}
+
 // Block is the resolver for the block field.
 func (r *queryResolver) Block(ctx context.Context, number *string, hash *string) (*model.Block, error) {
 	var blockNumber rpc.BlockNumber
@@ -40,12 +178,8 @@ func (r *queryResolver) Block(ctx context.Context, number *string, hash *string)
 				return nil, err
 			}
 		}
-	} else {
-		if hash != nil {
-			blockHash, _ := hexutil.DecodeBig(*hash)
-			fmt.Println("TODO/GraphQL/Implement me, get Block by hash=", blockHash)
-			hash = nil
-		}
+	} else if hash != nil {
+		return r.blockByHash(ctx, *hash)
 	}
 
 	if number == nil && hash == nil {
@@ -54,123 +188,147 @@ func (r *queryResolver) Block(ctx context.Context, number *string, hash *string)
 		blockNumber = rpc.LatestBlockNumber
 	}
 
-	res, err := r.GraphQLAPI.GetBlockDetails(ctx, blockNumber)
+	block, err := r.blockByNumber(ctx, blockNumber)
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
 
-	block := &model.Block{}
-	absBlk := res["block"]
+	return block, ctx.Err()
+}
 
-	if absBlk != nil {
-		blk := absBlk.(map[string]interface{})
+// Blocks is the resolver for the blocks field.
+func (r *queryResolver) Blocks(ctx context.Context, from *uint64, to *uint64) ([]*model.Block, error) {
+	end, err := r.latestBlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if to != nil && *to < end {
+		end = *to
+	}
+	var start uint64
+	if from != nil {
+		start = *from
+	}
+	if start > end {
+		return []*model.Block{}, nil
+	}
+	if end-start >= maxBlocksRange {
+		return nil, fmt.Errorf("block range %d-%d is larger than %d blocks", start, end, maxBlocksRange)
+	}
 
-		block.Difficulty = *convertDataToStringP(blk, "difficulty")
-		block.ExtraData = *convertDataToStringP(blk, "extraData")
-		block.GasLimit = uint64(*convertDataToUint64P(blk, "gasLimit"))
-		block.GasUsed = *convertDataToUint64P(blk, "gasUsed")
-		block.Hash = *convertDataToStringP(blk, "hash")
-		block.Miner = &model.Account{}
-		address := convertDataToStringP(blk, "miner")
-		if address != nil {
-			block.Miner.Address = strings.ToLower(*address)
-		}
-		mixHash := convertDataToStringP(blk, "mixHash")
-		if mixHash != nil {
-			block.MixHash = *mixHash
+	blocks := make([]*model.Block, 0, end-start+1)
+	for number := start; number <= end; number++ {
+		block, err := r.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
 		}
-		blockNonce := convertDataToStringP(blk, "nonce")
-		if blockNonce != nil {
-			block.Nonce = *blockNonce
-		}
-		block.Number = *convertDataToUint64P(blk, "number")
-		block.Ommers = []*model.Block{}
-		block.Parent = &model.Block{}
-		block.Parent.Hash = *convertDataToStringP(blk, "parentHash")
-		block.ReceiptsRoot = *convertDataToStringP(blk, "receiptsRoot")
-		block.StateRoot = *convertDataToStringP(blk, "stateRoot")
-		block.Timestamp = *convertDataToUint64P(blk, "timestamp") // int in the schema but Geth displays in HEX !!!
-		block.TransactionCount = convertDataToIntP(blk, "transactionCount")
-		block.TransactionsRoot = *convertDataToStringP(blk, "transactionsRoot")
-		block.TotalDifficulty = *convertDataToStringP(blk, "totalDifficulty")
-		block.Transactions = []*model.Transaction{}
-
-		block.LogsBloom = "0x" + *convertDataToStringP(blk, "logsBloom")
-		block.OmmerHash = "" // OmmerHash:     gointerfaces.ConvertHashToH256(header.UncleHash),
-
-		/*
-			Missing Block fields to fill :
-			- ommerHash
-		*/
-
-		absRcp := res["receipts"]
-		rcp := absRcp.([]map[string]interface{})
-		for _, transReceipt := range rcp {
-			trans := &model.Transaction{}
-			trans.CumulativeGasUsed = convertDataToUint64P(transReceipt, "cumulativeGasUsed")
-			trans.InputData = *convertDataToStringP(transReceipt, "data")
-			trans.EffectiveGasPrice = convertDataToStringP(transReceipt, "effectiveGasPrice")
-			trans.GasPrice = *convertDataToStringP(transReceipt, "gasPrice")
-			trans.GasUsed = convertDataToUint64P(transReceipt, "gasUsed")
-			trans.Hash = *convertDataToStringP(transReceipt, "transactionHash")
-			trans.Index = convertDataToIntP(transReceipt, "transactionIndex")
-			trans.Nonce = *convertDataToUint64P(transReceipt, "nonce")
-			trans.Status = convertDataToUint64P(transReceipt, "status")
-			trans.Type = convertDataToIntP(transReceipt, "type")
-			trans.Value = *convertDataToStringP(transReceipt, "value")
-			trans.Logs = make([]*model.Log, 0)
-
-			trans.From = &model.Account{}
-			trans.From.Address = strings.ToLower(*convertDataToStringP(transReceipt, "from"))
-
-			trans.To = &model.Account{}
-			address := convertDataToStringP(transReceipt, "to")
-			// To address could be nil in case of contract creation
-			if address != nil {
-				trans.To.Address = strings.ToLower(*convertDataToStringP(transReceipt, "to"))
-			}
-
-			block.Transactions = append(block.Transactions, trans)
+		if block == nil {
+			break
 		}
+		blocks = append(blocks, block)
 	}
 
-	return block, ctx.Err()
-}
-
-// Blocks is the resolver for the blocks field.
-func (r *queryResolver) Blocks(ctx context.Context, from *uint64, to *uint64) ([]*model.Block, error) {
-	panic(fmt.Errorf("not implemented: Blocks - blocks"))
+	return blocks, ctx.Err()
 }
 
 // Pending is the resolver for the pending field.
 func (r *queryResolver) Pending(ctx context.Context) (*model.Pending, error) {
-	panic(fmt.Errorf("not implemented: Pending - pending"))
+	pending := &model.Pending{Transactions: []*model.Transaction{}}
+	block, err := r.blockByNumber(ctx, rpc.PendingBlockNumber)
+	if err != nil || block == nil {
+		return pending, err
+	}
+	pending.Transactions = block.Transactions
+	for _, trans := range pending.Transactions {
+		trans.BlockHash = nil
+	}
+	pending.TransactionCount = len(pending.Transactions)
+
+	return pending, nil
 }
 
 // Transaction is the resolver for the transaction field.
 func (r *queryResolver) Transaction(ctx context.Context, hash string) (*model.Transaction, error) {
-	panic(fmt.Errorf("not implemented: Transaction - transaction"))
+	details, err := r.GraphQLAPI.GetTransactionDetails(ctx, libcommon.HexToHash(hash), receiptRequested(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if details != nil {
+		return convertTransaction(details, convertDataToString(details, "blockHash", "")), nil
+	}
+
+	// The transactions which are not in a block yet are looked up in the pool
+	txn, err := r.GraphQLAPI.GetTransactionByHash(ctx, libcommon.HexToHash(hash))
+	if err != nil || txn == nil || txn.BlockHash != nil {
+		return nil, err
+	}
+
+	return convertRPCTransaction(txn), nil
 }
 
 // Logs is the resolver for the logs field.
 func (r *queryResolver) Logs(ctx context.Context, filter model.FilterCriteria) ([]*model.Log, error) {
-	panic(fmt.Errorf("not implemented: Logs - logs"))
+	crit := filters.FilterCriteria{
+		Addresses: convertAddresses(filter.Addresses),
+		Topics:    convertTopics(filter.Topics),
+	}
+	if filter.FromBlock != nil {
+		crit.FromBlock = new(big.Int).SetUint64(*filter.FromBlock)
+	}
+	if filter.ToBlock != nil {
+		crit.ToBlock = new(big.Int).SetUint64(*filter.ToBlock)
+	}
+
+	logs, err := r.GraphQLAPI.GetLogs(ctx, crit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Log, 0, len(logs))
+	for _, log := range logs {
+		result = append(result, convertLog(log, nil))
+	}
+
+	return result, nil
 }
 
 // GasPrice is the resolver for the gasPrice field.
 func (r *queryResolver) GasPrice(ctx context.Context) (string, error) {
-	panic(fmt.Errorf("not implemented: GasPrice - gasPrice"))
+	gasPrice, err := r.GraphQLAPI.GasPrice(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return gasPrice.String(), nil
 }
 
 // MaxPriorityFeePerGas is the resolver for the maxPriorityFeePerGas field.
 func (r *queryResolver) MaxPriorityFeePerGas(ctx context.Context) (string, error) {
-	panic(fmt.Errorf("not implemented: MaxPriorityFeePerGas - maxPriorityFeePerGas"))
+	tipCap, err := r.GraphQLAPI.MaxPriorityFeePerGas(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return tipCap.String(), nil
 }
 
 // Syncing is the resolver for the syncing field.
 func (r *queryResolver) Syncing(ctx context.Context) (*model.SyncState, error) {
-	panic(fmt.Errorf("not implemented: Syncing - syncing"))
+	res, err := r.GraphQLAPI.Syncing(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Not syncing
+	progress, ok := res.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	return &model.SyncState{
+		CurrentBlock: *convertDataToUint64P(progress, "currentBlock"),
+		HighestBlock: *convertDataToUint64P(progress, "highestBlock"),
+	}, nil
 }
 
 // ChainID is the resolver for the chainID field.
@@ -180,11 +338,63 @@ func (r *queryResolver) ChainID(ctx context.Context) (string, error) {
 	return "0x" + strconv.FormatUint(chainID.Uint64(), 16), err
 }
 
+// From is the resolver for the from field.
+func (r *transactionResolver) From(ctx context.Context, obj *model.Transaction, block *uint64) (*model.Account, error) {
+	return &model.Account{Address: obj.FromAddress, BlockNrOrHash: blockNumberOrLatest(block)}, nil
+}
+
+// To is the resolver for the to field.
+func (r *transactionResolver) To(ctx context.Context, obj *model.Transaction, block *uint64) (*model.Account, error) {
+	if obj.ToAddress == nil {
+		return nil, nil
+	}
+
+	return &model.Account{Address: *obj.ToAddress, BlockNrOrHash: blockNumberOrLatest(block)}, nil
+}
+
+// Block is the resolver for the block field.
+func (r *transactionResolver) Block(ctx context.Context, obj *model.Transaction) (*model.Block, error) {
+	if obj.BlockHash == nil {
+		return nil, nil
+	}
+
+	return r.blockByHash(ctx, *obj.BlockHash)
+}
+
+// CreatedContract is the resolver for the createdContract field.
+func (r *transactionResolver) CreatedContract(ctx context.Context, obj *model.Transaction, block *uint64) (*model.Account, error) {
+	if obj.CreatedContractAddress == nil {
+		return nil, nil
+	}
+
+	return &model.Account{Address: *obj.CreatedContractAddress, BlockNrOrHash: blockNumberOrLatest(block)}, nil
+}
+
+// Account returns AccountResolver implementation.
+func (r *Resolver) Account() AccountResolver { return &accountResolver{r} }
+
+// Block returns BlockResolver implementation.
+func (r *Resolver) Block() BlockResolver { return &blockResolver{r} }
+
+// Log returns LogResolver implementation.
+func (r *Resolver) Log() LogResolver { return &logResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Pending returns PendingResolver implementation.
+func (r *Resolver) Pending() PendingResolver { return &pendingResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// Transaction returns TransactionResolver implementation.
+func (r *Resolver) Transaction() TransactionResolver { return &transactionResolver{r} }
+
+type accountResolver struct{ *Resolver }
+type blockResolver struct{ *Resolver }
+type logResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
+type pendingResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type transactionResolver struct{ *Resolver }
@@ -0,0 +1,186 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/graphql/graph"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+)
+
+func loadSchema(t *testing.T, path string) *ast.Schema {
+	t.Helper()
+	source, err := os.ReadFile(path)
+	require.NoError(t, err)
+	schema, gqlErr := gqlparser.LoadSchema(&ast.Source{Name: path, Input: string(source)})
+	if gqlErr != nil {
+		t.Fatalf("loading %s: %v", path, gqlErr)
+	}
+	return schema
+}
+
+// TestSchemaMatchesReference checks every type, field and argument of the geth reference schema is served.
+func TestSchemaMatchesReference(t *testing.T) {
+	ref := loadSchema(t, "geth-schema.graphqls.ref")
+	schema := loadSchema(t, "graph/schema.graphqls")
+
+	for name, refType := range ref.Types {
+		if refType.BuiltIn || name == "Mutation" {
+			continue
+		}
+		typ, ok := schema.Types[name]
+		if !ok {
+			t.Errorf("type %s is missing", name)
+			continue
+		}
+		for _, refField := range refType.Fields {
+			field := typ.Fields.ForName(refField.Name)
+			if field == nil {
+				t.Errorf("field %s.%s is missing", name, refField.Name)
+				continue
+			}
+			for _, refArg := range refField.Arguments {
+				if field.Arguments.ForName(refArg.Name) == nil {
+					t.Errorf("argument %s.%s(%s) is missing", name, refField.Name, refArg.Name)
+				}
+			}
+		}
+	}
+}
+
+// transactionDetailsSpy records whether the transactions looked up by hash were loaded with their receipt
+type transactionDetailsSpy struct {
+	commands.GraphQLAPI
+	withReceipt []bool
+}
+
+func (s *transactionDetailsSpy) GetTransactionDetails(ctx context.Context, hash libcommon.Hash, withReceipt bool) (map[string]interface{}, error) {
+	s.withReceipt = append(s.withReceipt, withReceipt)
+	return s.GraphQLAPI.GetTransactionDetails(ctx, hash, withReceipt)
+}
+
+func newTestHandler(t *testing.T) (http.Handler, *transactionDetailsSpy) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	agg := m.HistoryV3Components()
+	br := snapshotsync.NewBlockReaderWithSnapshots(m.BlockSnapshots, m.TransactionsV3)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.MockWithTxPool(t))
+	txPool := txpool.NewTxpoolClient(conn)
+	mining := txpool.NewMiningClient(conn)
+	ff := rpchelper.New(ctx, nil, txPool, mining, func() {})
+	base := commands.NewBaseApi(ff, stateCache, br, agg, false, rpccfg.DefaultEvmCallTimeout, m.Engine)
+	eth := commands.NewEthAPI(base, m.DB, nil, txPool, mining, 5000000, 100_000, 100_000, "")
+
+	spy := &transactionDetailsSpy{GraphQLAPI: commands.NewGraphQLAPI(base, m.DB, eth)}
+	resolver := graph.Resolver{GraphQLAPI: spy}
+	return handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &resolver})), spy
+}
+
+func query(t *testing.T, h http.Handler, q string) map[string]interface{} {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": q})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(context.Background()))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []interface{}          `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Empty(t, resp.Errors, q)
+	return resp.Data
+}
+
+func TestGraphQLResolvers(t *testing.T) {
+	h, spy := newTestHandler(t)
+
+	// The head of the test chain is block 11, block 1 sends 0.001 ether to 0x01.
+	latest := query(t, h, `{block{number hash parent{number} ommerCount transactionCount}}`)["block"].(map[string]interface{})
+	require.EqualValues(t, 11, latest["number"])
+	require.EqualValues(t, 10, latest["parent"].(map[string]interface{})["number"])
+	require.EqualValues(t, 0, latest["transactionCount"])
+
+	byHash := query(t, h, `{block(hash:"`+latest["hash"].(string)+`"){number}}`)["block"].(map[string]interface{})
+	require.EqualValues(t, 11, byHash["number"])
+
+	require.Nil(t, query(t, h, `{block(number:1000){number}}`)["block"])
+
+	blocks := query(t, h, `{blocks(from:3,to:5){number}}`)["blocks"].([]interface{})
+	require.Len(t, blocks, 3)
+	require.EqualValues(t, 5, blocks[2].(map[string]interface{})["number"])
+	require.Len(t, query(t, h, `{blocks(from:10){number}}`)["blocks"], 2)
+
+	block1 := query(t, h, `{block(number:1){transactions{hash index status gasUsed to{address} block{number}}}}`)["block"].(map[string]interface{})
+	txs := block1["transactions"].([]interface{})
+	require.Len(t, txs, 1)
+	tx := txs[0].(map[string]interface{})
+	require.EqualValues(t, 1, tx["status"])
+	require.EqualValues(t, 21000, tx["gasUsed"])
+	require.Equal(t, "0x0100000000000000000000000000000000000000", tx["to"].(map[string]interface{})["address"])
+	require.EqualValues(t, 1, tx["block"].(map[string]interface{})["number"])
+
+	byTxHash := query(t, h, `{transaction(hash:"`+tx["hash"].(string)+`"){hash index value from{address}}}`)["transaction"].(map[string]interface{})
+	require.Equal(t, tx["hash"], byTxHash["hash"])
+	require.Equal(t, "0x38d7ea4c68000", byTxHash["value"])
+	require.Equal(t, "0x71562b71999873db5b286df957af199ec94617f7", byTxHash["from"].(map[string]interface{})["address"])
+	require.EqualValues(t, 0, byTxHash["index"])
+	require.Equal(t, []bool{false}, spy.withReceipt)
+
+	// The receipt is only loaded when one of its fields is requested
+	withReceipt := query(t, h, `{transaction(hash:"`+tx["hash"].(string)+`"){status gasUsed cumulativeGasUsed rawReceipt block{number}}}`)["transaction"].(map[string]interface{})
+	require.Equal(t, []bool{false, true}, spy.withReceipt)
+	require.EqualValues(t, 1, withReceipt["status"])
+	require.EqualValues(t, 21000, withReceipt["gasUsed"])
+	require.EqualValues(t, 21000, withReceipt["cumulativeGasUsed"])
+	require.NotEqual(t, "0x", withReceipt["rawReceipt"])
+	require.EqualValues(t, 1, withReceipt["block"].(map[string]interface{})["number"])
+
+	require.Nil(t, query(t, h, `{transaction(hash:"0x0000000000000000000000000000000000000000000000000000000000000001"){hash}}`)["transaction"])
+
+	// Block 10 deploys and destructs a contract, emitting an event
+	logs := query(t, h, `{logs(filter:{fromBlock:0,toBlock:10}){index topics transaction{hash status}}}`)["logs"].([]interface{})
+	require.Len(t, logs, 1)
+	require.EqualValues(t, 1, logs[0].(map[string]interface{})["transaction"].(map[string]interface{})["status"])
+	blockLogs := query(t, h, `{block(number:10){logs(filter:{}){index transaction{index}}}}`)["block"].(map[string]interface{})["logs"].([]interface{})
+	require.Len(t, blockLogs, 1)
+
+	// The balance of 0x01 grows with blocks 1 and 2
+	account := query(t, h, `{block(number:1){account(address:"0x0100000000000000000000000000000000000000"){balance transactionCount}}}`)["block"].(map[string]interface{})["account"].(map[string]interface{})
+	require.Equal(t, "0x38d7ea4c68000", account["balance"])
+	account = query(t, h, `{block(number:2){account(address:"0x0100000000000000000000000000000000000000"){balance}}}`)["block"].(map[string]interface{})["account"].(map[string]interface{})
+	require.Equal(t, "0x71afd498d0000", account["balance"])
+
+	call := query(t, h, `{block{call(data:{from:"0x71562b71999873db5b286df957af199ec94617f7",to:"0x0100000000000000000000000000000000000000"}){data status gasUsed}}}`)["block"].(map[string]interface{})["call"].(map[string]interface{})
+	require.EqualValues(t, 1, call["status"])
+	require.Equal(t, "0x", call["data"])
+	estimate := query(t, h, `{block{estimateGas(data:{from:"0x71562b71999873db5b286df957af199ec94617f7",to:"0x0100000000000000000000000000000000000000"})}}`)["block"].(map[string]interface{})
+	require.EqualValues(t, 21000, estimate["estimateGas"])
+
+	data := query(t, h, `{gasPrice maxPriorityFeePerGas syncing{currentBlock} pending{transactionCount} chainID}`)
+	require.NotEmpty(t, data["gasPrice"])
+	require.NotEmpty(t, data["maxPriorityFeePerGas"])
+	require.Equal(t, "0x539", data["chainID"])
+	require.EqualValues(t, 0, data["pending"].(map[string]interface{})["transactionCount"])
+}
@@ -19,24 +19,30 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentry/reputation"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/services"
 )
 
 type RemoteBackend struct {
 	remoteEthBackend remote.ETHBACKENDClient
+	peerReputations  reputation.PeerReputationClient
 	log              log.Logger
 	version          gointerfaces.Version
 	db               kv.RoDB
 	blockReader      services.FullBlockReader
 }
 
-func NewRemoteBackend(client remote.ETHBACKENDClient, db kv.RoDB, blockReader services.FullBlockReader) *RemoteBackend {
+// NewRemoteBackend returns the backend calling the node with client, and its sentries with peerReputations,
+// which may be nil when the peer reputations are not served.
+func NewRemoteBackend(client remote.ETHBACKENDClient, peerReputations reputation.PeerReputationClient, db kv.RoDB, blockReader services.FullBlockReader) *RemoteBackend {
 	return &RemoteBackend{
 		remoteEthBackend: client,
+		peerReputations:  peerReputations,
 		version:          gointerfaces.VersionFromProto(privateapi.EthBackendAPIVersion),
 		log:              log.New("remote_service", "eth_backend"),
 		db:               db,
@@ -263,6 +269,40 @@ func (back *RemoteBackend) NodeInfo(ctx context.Context, limit uint32) ([]p2p.No
 	return ret, nil
 }
 
+var errNoPeerReputations = errors.New("peer reputations are not served")
+
+func (back *RemoteBackend) PeerReputations(ctx context.Context) ([]*reputation.Peer, error) {
+	if back.peerReputations == nil {
+		return nil, errNoPeerReputations
+	}
+	reply, err := back.peerReputations.Peers(ctx, &reputation.PeersRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("PeerReputationClient.Peers() error: %w", err)
+	}
+	return reply.Peers, nil
+}
+
+func (back *RemoteBackend) BanPeer(ctx context.Context, id enode.ID, seconds uint64, reason string) error {
+	if back.peerReputations == nil {
+		return errNoPeerReputations
+	}
+	if _, err := back.peerReputations.Ban(ctx, &reputation.BanRequest{ID: id.Bytes(), Seconds: seconds, Reason: reason}); err != nil {
+		return fmt.Errorf("PeerReputationClient.Ban() error: %w", err)
+	}
+	return nil
+}
+
+func (back *RemoteBackend) UnbanPeer(ctx context.Context, id enode.ID) (bool, error) {
+	if back.peerReputations == nil {
+		return false, errNoPeerReputations
+	}
+	reply, err := back.peerReputations.Unban(ctx, &reputation.UnbanRequest{ID: id.Bytes()})
+	if err != nil {
+		return false, fmt.Errorf("PeerReputationClient.Unban() error: %w", err)
+	}
+	return reply.Banned, nil
+}
+
 func (back *RemoteBackend) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
 	rpcPeers, err := back.remoteEthBackend.Peers(ctx, &emptypb.Empty{})
 	if err != nil {
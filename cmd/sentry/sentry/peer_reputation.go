@@ -0,0 +1,413 @@
+package sentry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentry/reputation"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// PenaltyReason is a kind of misbehaviour the peers are scored for
+type PenaltyReason int
+
+const (
+	InvalidDataPenalty   PenaltyReason = iota // Invalid headers, bodies or blocks, reported by the core with PenalizePeer
+	SlowResponsePenalty                       // No response to a request before its deadline
+	EmptyResponsePenalty                      // Empty response to a request for headers, bodies or receipts
+	UselessBodiesPenalty                      // Bodies which weren't requested from the peer, or more bodies than requested
+	penaltyReasons
+)
+
+var penaltyNames = [penaltyReasons]string{
+	InvalidDataPenalty:   "invalid data",
+	SlowResponsePenalty:  "slow response",
+	EmptyResponsePenalty: "empty response",
+	UselessBodiesPenalty: "useless bodies",
+}
+
+var penaltyScores = [penaltyReasons]float64{
+	InvalidDataPenalty:   40,
+	SlowResponsePenalty:  5,
+	EmptyResponsePenalty: 5,
+	UselessBodiesPenalty: 10,
+}
+
+func (r PenaltyReason) String() string {
+	if r < 0 || r >= penaltyReasons {
+		return fmt.Sprintf("penalty %d", int(r))
+	}
+	return penaltyNames[r]
+}
+
+const (
+	suspiciousScore    = 30             // Peers with at least this score are only chosen for requests when there are not enough other peers
+	banScore           = 100            // Peers reaching this score are disconnected and banned
+	scoreHalfLife      = time.Hour      // The scores are halved every hour, for occasional penalties to be forgotten
+	autoBanDuration    = 12 * time.Hour // Duration of the bans of the peers reaching banScore
+	DefaultBanDuration = 24 * time.Hour // Duration of the bans requested without a duration
+	maxScoredPeers     = 4096           // When more peers are scored, the ones with a negligible score are forgotten
+	maxBodiesRequests  = 16             // Number of bodies requests remembered per peer, to recognise useless bodies
+)
+
+type peerScore struct {
+	score     float64
+	updated   time.Time
+	penalties [penaltyReasons]uint64
+}
+
+func (s *peerScore) decay(now time.Time) float64 {
+	if elapsed := now.Sub(s.updated); elapsed > 0 {
+		s.score *= math.Exp2(-float64(elapsed) / float64(scoreHalfLife))
+		s.updated = now
+	}
+	return s.score
+}
+
+// reputations keeps the scores of the peers of a sentry, and its bans.
+// Bans are persisted in the node database once the p2p server is started.
+type reputations struct {
+	lock   sync.Mutex
+	scores map[enode.ID]*peerScore
+	bans   map[enode.ID]enode.Ban
+	db     *enode.DB
+}
+
+func newReputations() *reputations {
+	return &reputations{scores: map[enode.ID]*peerScore{}, bans: map[enode.ID]enode.Ban{}}
+}
+
+// setDB loads the bans stored in the node database, and stores there the bans made before it was opened
+func (r *reputations) setDB(db *enode.DB) error {
+	stored, err := db.Bans()
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, ban := range r.bans {
+		if err := db.BanNode(ban.ID, ban.Until, ban.Reason); err != nil {
+			return err
+		}
+	}
+	for _, ban := range stored {
+		if _, ok := r.bans[ban.ID]; !ok {
+			r.bans[ban.ID] = ban
+		}
+	}
+	r.db = db
+	return nil
+}
+
+// penalize adds the penalty to the score of the peer, and reports whether the peer has reached banScore
+func (r *reputations) penalize(id enode.ID, reason PenaltyReason, count int, now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	s, ok := r.scores[id]
+	if !ok {
+		if len(r.scores) >= maxScoredPeers {
+			r.forget(now)
+		}
+		s = &peerScore{updated: now}
+		r.scores[id] = s
+	}
+	s.decay(now)
+	s.score += penaltyScores[reason] * float64(count)
+	s.penalties[reason] += uint64(count)
+	return s.score >= banScore
+}
+
+// forget removes the scores which have decayed to almost nothing
+func (r *reputations) forget(now time.Time) {
+	for id, s := range r.scores {
+		if s.decay(now) < 1 {
+			delete(r.scores, id)
+		}
+	}
+}
+
+func (r *reputations) score(id enode.ID, now time.Time) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if s, ok := r.scores[id]; ok {
+		return s.decay(now)
+	}
+	return 0
+}
+
+func (r *reputations) suspicious(id enode.ID, now time.Time) bool {
+	return r.score(id, now) >= suspiciousScore
+}
+
+func (r *reputations) ban(id enode.ID, until time.Time, reason string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.bans[id] = enode.Ban{ID: id, Until: until, Reason: reason}
+	if r.db != nil {
+		return r.db.BanNode(id, until, reason)
+	}
+	return nil
+}
+
+// unban lifts the ban of the peer, and resets its score. It reports whether the peer was banned.
+func (r *reputations) unban(id enode.ID) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, found := r.bans[id]
+	delete(r.bans, id)
+	delete(r.scores, id)
+	if r.db != nil {
+		stored, err := r.db.UnbanNode(id)
+		if err != nil {
+			return false, err
+		}
+		found = found || stored
+	}
+	return found, nil
+}
+
+func (r *reputations) banned(id enode.ID, now time.Time) (enode.Ban, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ban, ok := r.bans[id]
+	if !ok {
+		return ban, false
+	}
+	if !ban.Until.After(now) {
+		delete(r.bans, id)
+		return ban, false
+	}
+	return ban, true
+}
+
+// PeerReputation is the reputation of a peer known to a sentry
+type PeerReputation struct {
+	ID          enode.ID
+	Connected   bool
+	Score       float64
+	Penalties   map[PenaltyReason]uint64
+	BannedUntil *time.Time
+	BanReason   string
+}
+
+// PeerReputations returns the reputation of the peers which have been penalised or banned, sorted by ID
+func (ss *GrpcServer) PeerReputations() []PeerReputation {
+	now := time.Now()
+	connected := map[enode.ID]bool{}
+	ss.rangePeers(func(peerInfo *PeerInfo) bool {
+		connected[peerInfo.peer.ID()] = true
+		return true
+	})
+
+	r := ss.reputation
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	byID := map[enode.ID]*PeerReputation{}
+	get := func(id enode.ID) *PeerReputation {
+		rep, ok := byID[id]
+		if !ok {
+			rep = &PeerReputation{ID: id, Connected: connected[id], Penalties: map[PenaltyReason]uint64{}}
+			byID[id] = rep
+		}
+		return rep
+	}
+	for id, s := range r.scores {
+		rep := get(id)
+		rep.Score = s.decay(now)
+		for reason, count := range s.penalties {
+			if count > 0 {
+				rep.Penalties[PenaltyReason(reason)] = count
+			}
+		}
+	}
+	for id, ban := range r.bans {
+		if ban.Until.After(now) {
+			rep, until := get(id), ban.Until
+			rep.BannedUntil, rep.BanReason = &until, ban.Reason
+		}
+	}
+	res := make([]PeerReputation, 0, len(byID))
+	for _, rep := range byID {
+		res = append(res, *rep)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID.String() < res[j].ID.String() })
+	return res
+}
+
+// BanPeer bans the peer until the ban expires, disconnecting it if it is connected.
+// The ban is kept in the node database, so that it survives restarts.
+func (ss *GrpcServer) BanPeer(id enode.ID, duration time.Duration, reason string) error {
+	if duration <= 0 {
+		duration = DefaultBanDuration
+	}
+	if err := ss.reputation.ban(id, time.Now().Add(duration), reason); err != nil {
+		return fmt.Errorf("storing ban of peer %s: %w", id.TerminalString(), err)
+	}
+	ss.rangePeers(func(peerInfo *PeerInfo) bool {
+		if peerInfo.peer.ID() == id {
+			ss.removePeer(peerInfo.ID())
+			return false
+		}
+		return true
+	})
+	return nil
+}
+
+// UnbanPeer lifts the ban of the peer and forgets its penalties. It reports whether the peer was banned.
+func (ss *GrpcServer) UnbanPeer(id enode.ID) (bool, error) {
+	return ss.reputation.unban(id)
+}
+
+// penalize scores the misbehaviour of the peer, and bans the peer once it reaches banScore.
+// Static and trusted peers are scored, but not banned.
+func (ss *GrpcServer) penalize(peerInfo *PeerInfo, reason PenaltyReason, count int) {
+	if ss.reputation == nil {
+		return
+	}
+	id := peerInfo.peer.ID()
+	if !ss.reputation.penalize(id, reason, count, time.Now()) {
+		return
+	}
+	if network := peerInfo.peer.Info().Network; network.Static || network.Trusted {
+		return
+	}
+	log.Debug("[p2p] Banning peer", "peerId", id.TerminalString(), "name", peerInfo.peer.Name(), "last penalty", reason)
+	if err := ss.BanPeer(id, autoBanDuration, fmt.Sprintf("score reached %d, last penalty: %s", banScore, reason)); err != nil {
+		log.Warn("[p2p] Could not ban peer", "peerId", id.TerminalString(), "err", err)
+	}
+}
+
+// clearDeadlines removes the passed deadlines of the peer, penalising it for the requests it didn't respond to in time.
+// It returns the number of deadlines left.
+func (ss *GrpcServer) clearDeadlines(peerInfo *PeerInfo, now time.Time, givePermit bool) int {
+	deadlines := peerInfo.ClearDeadlines(now, givePermit)
+	if expired := peerInfo.takeExpiredDeadlines(); expired > 0 {
+		ss.penalize(peerInfo, SlowResponsePenalty, expired)
+	}
+	return deadlines
+}
+
+// suspicious reports whether the peer has been penalised too much to be chosen for requests before other peers
+func (ss *GrpcServer) suspicious(peerInfo *PeerInfo, now time.Time) bool {
+	return ss.reputation != nil && ss.reputation.suspicious(peerInfo.peer.ID(), now)
+}
+
+func (ss *GrpcServer) banned(id enode.ID) (enode.Ban, bool) {
+	if ss.reputation == nil {
+		return enode.Ban{}, false
+	}
+	return ss.reputation.banned(id, time.Now())
+}
+
+// splitPacket66 splits an eth/66 request or response into its request id and the content of its list of items
+func splitPacket66(b []byte) (requestID uint64, items []byte, err error) {
+	content, _, err := rlp.SplitList(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	requestID, rest, err := rlp.SplitUint64(content)
+	if err != nil {
+		return 0, nil, err
+	}
+	items, _, err = rlp.SplitList(rest)
+	return requestID, items, err
+}
+
+// isEmptyResponse reports whether an eth/66 response has no items
+func isEmptyResponse(b []byte) bool {
+	_, items, err := splitPacket66(b)
+	return err == nil && len(items) == 0
+}
+
+// bodiesPenalty checks a bodies response against the bodies requested from the peer
+func bodiesPenalty(peerInfo *PeerInfo, b []byte) (PenaltyReason, bool) {
+	requestID, items, err := splitPacket66(b)
+	if err != nil {
+		return 0, false
+	}
+	bodies, err := rlp.CountValues(items)
+	if err != nil {
+		return 0, false
+	}
+	requested, ok := peerInfo.takeBodiesRequest(requestID)
+	switch {
+	case !ok || bodies > requested:
+		return UselessBodiesPenalty, true
+	case bodies == 0:
+		return EmptyResponsePenalty, true
+	}
+	return 0, false
+}
+
+// ReputationServer returns the server of the PeerReputation gRPC service of the sentry, registered along its
+// sentry service, which the node calls for the admin RPC
+func (ss *GrpcServer) ReputationServer() reputation.PeerReputationServer {
+	return reputationServer{ss}
+}
+
+type reputationServer struct {
+	ss *GrpcServer
+}
+
+func (s reputationServer) Peers(context.Context, *reputation.PeersRequest) (*reputation.PeersReply, error) {
+	protocol := uint32(s.ss.Protocols[0].Version)
+	var reply reputation.PeersReply
+	for _, rep := range s.ss.PeerReputations() {
+		peer := &reputation.Peer{
+			ID:        rep.ID.Bytes(),
+			Protocol:  protocol,
+			Connected: rep.Connected,
+			Score:     rep.Score,
+			BanReason: rep.BanReason,
+		}
+		for reason := PenaltyReason(0); reason < penaltyReasons; reason++ {
+			if count := rep.Penalties[reason]; count > 0 {
+				peer.Penalties = append(peer.Penalties, &reputation.Penalty{Reason: reason.String(), Count: count})
+			}
+		}
+		if rep.BannedUntil != nil {
+			peer.BannedUntil = rep.BannedUntil.UnixNano()
+		}
+		reply.Peers = append(reply.Peers, peer)
+	}
+	return &reply, nil
+}
+
+func (s reputationServer) Ban(_ context.Context, req *reputation.BanRequest) (*reputation.BanReply, error) {
+	id, err := nodeID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ss.BanPeer(id, time.Duration(req.Seconds)*time.Second, req.Reason); err != nil {
+		return nil, err
+	}
+	return &reputation.BanReply{}, nil
+}
+
+func (s reputationServer) Unban(_ context.Context, req *reputation.UnbanRequest) (*reputation.UnbanReply, error) {
+	id, err := nodeID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	banned, err := s.ss.UnbanPeer(id)
+	if err != nil {
+		return nil, err
+	}
+	return &reputation.UnbanReply{Banned: banned}, nil
+}
+
+func nodeID(b []byte) (enode.ID, error) {
+	var id enode.ID
+	if len(b) != len(id) {
+		return id, fmt.Errorf("invalid node id length: %d", len(b))
+	}
+	copy(id[:], b)
+	return id, nil
+}
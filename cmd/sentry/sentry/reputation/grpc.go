@@ -0,0 +1,212 @@
+package reputation
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// codecName is the gRPC content subtype of the messages of this package, which aren't generated protobuf
+// messages the default codec could marshal. Servers pick the codec of a call from its content subtype, so the
+// service can be registered on the gRPC servers of the sentries and of the private API along the other ones.
+const codecName = "reputation"
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return m.appendTo(nil), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	return m.unmarshal(data)
+}
+
+func (codec) Name() string { return codecName }
+
+// PeerReputationClient is the client API of the PeerReputation service.
+type PeerReputationClient interface {
+	Peers(ctx context.Context, in *PeersRequest, opts ...grpc.CallOption) (*PeersReply, error)
+	Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanReply, error)
+	Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanReply, error)
+}
+
+type peerReputationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPeerReputationClient(cc grpc.ClientConnInterface) PeerReputationClient {
+	return &peerReputationClient{cc}
+}
+
+func (c *peerReputationClient) Peers(ctx context.Context, in *PeersRequest, opts ...grpc.CallOption) (*PeersReply, error) {
+	out := new(PeersReply)
+	if err := c.cc.Invoke(ctx, "/reputation.PeerReputation/Peers", in, out, append(opts, grpc.CallContentSubtype(codecName))...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerReputationClient) Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanReply, error) {
+	out := new(BanReply)
+	if err := c.cc.Invoke(ctx, "/reputation.PeerReputation/Ban", in, out, append(opts, grpc.CallContentSubtype(codecName))...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerReputationClient) Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanReply, error) {
+	out := new(UnbanReply)
+	if err := c.cc.Invoke(ctx, "/reputation.PeerReputation/Unban", in, out, append(opts, grpc.CallContentSubtype(codecName))...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PeerReputationServer is the server API of the PeerReputation service.
+type PeerReputationServer interface {
+	Peers(context.Context, *PeersRequest) (*PeersReply, error)
+	Ban(context.Context, *BanRequest) (*BanReply, error)
+	Unban(context.Context, *UnbanRequest) (*UnbanReply, error)
+}
+
+func RegisterPeerReputationServer(s grpc.ServiceRegistrar, srv PeerReputationServer) {
+	s.RegisterService(&peerReputationServiceDesc, srv)
+}
+
+var peerReputationServiceDesc = grpc.ServiceDesc{
+	ServiceName: "reputation.PeerReputation",
+	HandlerType: (*PeerReputationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Peers",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(PeersRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(PeerReputationServer).Peers(ctx, req.(*PeersRequest))
+				}
+				return intercept(ctx, srv, "Peers", in, handler, interceptor)
+			},
+		},
+		{
+			MethodName: "Ban",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(BanRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(PeerReputationServer).Ban(ctx, req.(*BanRequest))
+				}
+				return intercept(ctx, srv, "Ban", in, handler, interceptor)
+			},
+		},
+		{
+			MethodName: "Unban",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(UnbanRequest)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(PeerReputationServer).Unban(ctx, req.(*UnbanRequest))
+				}
+				return intercept(ctx, srv, "Unban", in, handler, interceptor)
+			},
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "reputation.proto",
+}
+
+func intercept(ctx context.Context, srv interface{}, method string, in interface{}, handler grpc.UnaryHandler, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	if interceptor == nil {
+		return handler(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/reputation.PeerReputation/" + method}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PeerReputationClientDirect calls a server running in the same process, like the clients of the direct
+// package of erigon-lib.
+type PeerReputationClientDirect struct {
+	server PeerReputationServer
+}
+
+func NewPeerReputationClientDirect(server PeerReputationServer) *PeerReputationClientDirect {
+	return &PeerReputationClientDirect{server: server}
+}
+
+func (c *PeerReputationClientDirect) Peers(ctx context.Context, in *PeersRequest, opts ...grpc.CallOption) (*PeersReply, error) {
+	return c.server.Peers(ctx, in)
+}
+
+func (c *PeerReputationClientDirect) Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*BanReply, error) {
+	return c.server.Ban(ctx, in)
+}
+
+func (c *PeerReputationClientDirect) Unban(ctx context.Context, in *UnbanRequest, opts ...grpc.CallOption) (*UnbanReply, error) {
+	return c.server.Unban(ctx, in)
+}
+
+// SentriesServer serves the peer reputations of all the sentries of a node, each of them being reached with
+// its client.
+type SentriesServer struct {
+	sentries []PeerReputationClient
+}
+
+func NewSentriesServer(sentries []PeerReputationClient) *SentriesServer {
+	return &SentriesServer{sentries: sentries}
+}
+
+// Peers returns the peers of every sentry, a peer known to several sentries being returned once per sentry
+func (s *SentriesServer) Peers(ctx context.Context, in *PeersRequest) (*PeersReply, error) {
+	var reply PeersReply
+	for _, sentry := range s.sentries {
+		peers, err := sentry.Peers(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("peer reputations of sentry: %w", err)
+		}
+		reply.Peers = append(reply.Peers, peers.Peers...)
+	}
+	return &reply, nil
+}
+
+// Ban bans the peer from every sentry
+func (s *SentriesServer) Ban(ctx context.Context, in *BanRequest) (*BanReply, error) {
+	for _, sentry := range s.sentries {
+		if _, err := sentry.Ban(ctx, in); err != nil {
+			return nil, fmt.Errorf("ban peer on sentry: %w", err)
+		}
+	}
+	return &BanReply{}, nil
+}
+
+// Unban lifts the ban of the peer on every sentry, the peer was banned if any of them banned it
+func (s *SentriesServer) Unban(ctx context.Context, in *UnbanRequest) (*UnbanReply, error) {
+	var reply UnbanReply
+	for _, sentry := range s.sentries {
+		unbanned, err := sentry.Unban(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("unban peer on sentry: %w", err)
+		}
+		reply.Banned = reply.Banned || unbanned.Banned
+	}
+	return &reply, nil
+}
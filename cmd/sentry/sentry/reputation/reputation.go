@@ -0,0 +1,180 @@
+// Package reputation contains the gRPC service exposing the peer reputations of the sentries, defined in
+// `reputation.proto`, along with the Go types of its messages and their wire encoding.
+package reputation
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type PeersRequest struct{}
+
+func (m *PeersRequest) appendTo(b []byte) []byte { return b }
+
+func (m *PeersRequest) unmarshal(b []byte) error { return consumeFields(b, skipField) }
+
+type PeersReply struct {
+	Peers []*Peer
+}
+
+func (m *PeersReply) appendTo(b []byte) []byte {
+	for _, peer := range m.Peers {
+		b = appendMessage(b, 1, peer)
+	}
+	return b
+}
+
+func (m *PeersReply) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			peer := &Peer{}
+			m.Peers = append(m.Peers, peer)
+			return consumeMessage(b, typ, peer)
+		}
+		return -1, nil
+	})
+}
+
+// Peer is the reputation of a peer known to a sentry.
+type Peer struct {
+	ID        []byte // Node ID of the peer
+	Protocol  uint32 // Version of the eth protocol of the sentry knowing the peer
+	Connected bool
+	Score     float64
+	Penalties []*Penalty
+	// BannedUntil is the Unix time in nanoseconds when the ban of the peer expires, 0 when the peer isn't banned
+	BannedUntil int64
+	BanReason   string
+}
+
+func (m *Peer) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.ID)
+	b = appendUint64(b, 2, uint64(m.Protocol))
+	b = appendBool(b, 3, m.Connected)
+	b = appendDouble(b, 4, m.Score)
+	for _, penalty := range m.Penalties {
+		b = appendMessage(b, 5, penalty)
+	}
+	b = appendUint64(b, 6, uint64(m.BannedUntil))
+	b = appendString(b, 7, m.BanReason)
+	return b
+}
+
+func (m *Peer) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.ID)
+		case 2:
+			var v uint64
+			n, err := consumeUint64(b, typ, &v)
+			m.Protocol = uint32(v)
+			return n, err
+		case 3:
+			return consumeBool(b, typ, &m.Connected)
+		case 4:
+			return consumeDouble(b, typ, &m.Score)
+		case 5:
+			penalty := &Penalty{}
+			m.Penalties = append(m.Penalties, penalty)
+			return consumeMessage(b, typ, penalty)
+		case 6:
+			var v uint64
+			n, err := consumeUint64(b, typ, &v)
+			m.BannedUntil = int64(v)
+			return n, err
+		case 7:
+			return consumeString(b, typ, &m.BanReason)
+		}
+		return -1, nil
+	})
+}
+
+// Penalty is the number of times a peer was penalised for a reason.
+type Penalty struct {
+	Reason string
+	Count  uint64
+}
+
+func (m *Penalty) appendTo(b []byte) []byte {
+	b = appendString(b, 1, m.Reason)
+	return appendUint64(b, 2, m.Count)
+}
+
+func (m *Penalty) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(b, typ, &m.Reason)
+		case 2:
+			return consumeUint64(b, typ, &m.Count)
+		}
+		return -1, nil
+	})
+}
+
+// BanRequest bans the peer for the given number of seconds, the default duration of the sentries when 0.
+type BanRequest struct {
+	ID      []byte
+	Seconds uint64
+	Reason  string
+}
+
+func (m *BanRequest) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.ID)
+	b = appendUint64(b, 2, m.Seconds)
+	return appendString(b, 3, m.Reason)
+}
+
+func (m *BanRequest) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.ID)
+		case 2:
+			return consumeUint64(b, typ, &m.Seconds)
+		case 3:
+			return consumeString(b, typ, &m.Reason)
+		}
+		return -1, nil
+	})
+}
+
+type BanReply struct{}
+
+func (m *BanReply) appendTo(b []byte) []byte { return b }
+
+func (m *BanReply) unmarshal(b []byte) error { return consumeFields(b, skipField) }
+
+type UnbanRequest struct {
+	ID []byte
+}
+
+func (m *UnbanRequest) appendTo(b []byte) []byte { return appendBytes(b, 1, m.ID) }
+
+func (m *UnbanRequest) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.ID)
+		}
+		return -1, nil
+	})
+}
+
+// UnbanReply tells whether the peer was banned.
+type UnbanReply struct {
+	Banned bool
+}
+
+func (m *UnbanReply) appendTo(b []byte) []byte { return appendBool(b, 1, m.Banned) }
+
+func (m *UnbanReply) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBool(b, typ, &m.Banned)
+		}
+		return -1, nil
+	})
+}
@@ -0,0 +1,178 @@
+package reputation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var errInvalidWireType = errors.New("invalid wire type")
+
+// message is implemented by every type of this package, it's the hand-written
+// equivalent of what `protoc-gen-go` would produce for the schema.
+type message interface {
+	appendTo(b []byte) []byte
+	unmarshal(b []byte) error
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendUint64(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(v))
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func appendMessage(b []byte, num protowire.Number, m message) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.appendTo(nil))
+}
+
+// consumeFields walks over all fields of `b`, calling `field` for each of them. The
+// callback returns the number of bytes it consumed for the field's value, or -1 if the
+// field is unknown in which case it's skipped.
+func consumeFields(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := field(num, typ, b)
+		if err != nil {
+			return fmt.Errorf("field %d: %w", num, err)
+		}
+
+		if n < 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return fmt.Errorf("field %d: %w", num, protowire.ParseError(n))
+			}
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// skipField is the callback of consumeFields for messages without fields
+func skipField(protowire.Number, protowire.Type, []byte) (int, error) {
+	return -1, nil
+}
+
+func consumeBytes(b []byte, typ protowire.Type, out *[]byte) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	// Copying ensures we do not retain the input buffer and that empty values are always nil
+	*out = append([]byte(nil), v...)
+	return n, nil
+}
+
+func consumeString(b []byte, typ protowire.Type, out *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeString(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	*out = v
+	return n, nil
+}
+
+func consumeUint64(b []byte, typ protowire.Type, out *uint64) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	*out = v
+	return n, nil
+}
+
+func consumeBool(b []byte, typ protowire.Type, out *bool) (int, error) {
+	var v uint64
+	n, err := consumeUint64(b, typ, &v)
+	*out = protowire.DecodeBool(v)
+	return n, err
+}
+
+func consumeDouble(b []byte, typ protowire.Type, out *float64) (int, error) {
+	if typ != protowire.Fixed64Type {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeFixed64(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	*out = math.Float64frombits(v)
+	return n, nil
+}
+
+func consumeMessage(b []byte, typ protowire.Type, m message) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	return n, m.unmarshal(v)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/stages/bodydownload"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+	"github.com/ledgerwatch/erigon/turbo/stages/receiptdownload"
 )
 
 // Methods of sentry called by Core
@@ -78,6 +79,43 @@ func (cs *MultiClient) SendBodyRequest(ctx context.Context, req *bodydownload.Bo
 	return [64]byte{}, false
 }
 
+func (cs *MultiClient) SendReceiptRequest(ctx context.Context, req *receiptdownload.ReceiptRequest) (peerID [64]byte, ok bool) {
+	// if sentry not found peers to send such message, try next one. stop if found.
+	for i, ok, next := cs.randSentryIndex(); ok; i, ok = next() {
+		if !cs.sentries[i].Ready() {
+			continue
+		}
+
+		bytes, err := rlp.EncodeToBytes(&eth.GetReceiptsPacket66{
+			RequestId:         req.RequestID,
+			GetReceiptsPacket: req.Hashes,
+		})
+		if err != nil {
+			log.Error("Could not encode receipts request", "err", err)
+			return [64]byte{}, false
+		}
+		outreq := proto_sentry.SendMessageByMinBlockRequest{
+			MinBlock: req.BlockNums[len(req.BlockNums)-1],
+			Data: &proto_sentry.OutboundMessageData{
+				Id:   proto_sentry.MessageId_GET_RECEIPTS_66,
+				Data: bytes,
+			},
+			MaxPeers: 1,
+		}
+
+		sentPeers, err1 := cs.sentries[i].SendMessageByMinBlock(ctx, &outreq, &grpc.EmptyCallOption{})
+		if err1 != nil {
+			log.Error("Could not send receipts request", "err", err1)
+			return [64]byte{}, false
+		}
+		if sentPeers == nil || len(sentPeers.Peers) == 0 {
+			continue
+		}
+		return ConvertH512ToPeerID(sentPeers.Peers[0]), true
+	}
+	return [64]byte{}, false
+}
+
 func (cs *MultiClient) SendHeaderRequest(ctx context.Context, req *headerdownload.HeaderRequest) (peerID [64]byte, ok bool) {
 	// if sentry not found peers to send such message, try next one. stop if found.
 	for i, ok, next := cs.randSentryIndex(); ok; i, ok = next() {
@@ -31,6 +31,7 @@ import (
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentry/reputation"
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/core/forkid"
@@ -68,11 +69,20 @@ type PeerInfo struct {
 	// if this queue is full (means peer is slow) - old messages will be dropped
 	// channel closed on peer remove
 	tasks chan func()
+
+	expiredDeadlines int             // Deadlines passed since the last call to takeExpiredDeadlines
+	bodiesRequests   []bodiesRequest // Latest bodies requests sent to the peer, oldest first
+}
+
+type bodiesRequest struct {
+	id     uint64
+	bodies int
 }
 
 type PeerRef struct {
-	pi     *PeerInfo
-	height uint64
+	pi         *PeerInfo
+	height     uint64
+	suspicious bool
 }
 
 // PeersByMinBlock is the priority queue of peers. Used to select certain number of peers considered to be "best available"
@@ -83,8 +93,11 @@ func (bp PeersByMinBlock) Len() int {
 	return len(bp)
 }
 
-// Less (part of heap.Interface) compares two peers
+// Less (part of heap.Interface) compares two peers. Suspicious peers are worse than any other peer, whatever their height.
 func (bp PeersByMinBlock) Less(i, j int) bool {
+	if bp[i].suspicious != bp[j].suspicious {
+		return bp[i].suspicious
+	}
 	return bp[i].height < bp[j].height
 }
 
@@ -182,9 +195,43 @@ func (pi *PeerInfo) ClearDeadlines(now time.Time, givePermit bool) int {
 		cutOff++
 	}
 	pi.deadlines = pi.deadlines[cutOff:]
+	pi.expiredDeadlines += firstNotPassed
 	return len(pi.deadlines)
 }
 
+// takeExpiredDeadlines returns the number of deadlines which passed without a response, since its previous call
+func (pi *PeerInfo) takeExpiredDeadlines() int {
+	pi.lock.Lock()
+	defer pi.lock.Unlock()
+	expired := pi.expiredDeadlines
+	pi.expiredDeadlines = 0
+	return expired
+}
+
+// addBodiesRequest remembers a request for the given number of bodies, for takeBodiesRequest to match the response
+func (pi *PeerInfo) addBodiesRequest(id uint64, bodies int) {
+	pi.lock.Lock()
+	defer pi.lock.Unlock()
+	if len(pi.bodiesRequests) == maxBodiesRequests {
+		pi.bodiesRequests = append(pi.bodiesRequests[:0], pi.bodiesRequests[1:]...)
+	}
+	pi.bodiesRequests = append(pi.bodiesRequests, bodiesRequest{id: id, bodies: bodies})
+}
+
+// takeBodiesRequest returns the number of bodies requested by the request with the given id, and forgets the request.
+// It returns false if the request wasn't sent to the peer, or its response was already received.
+func (pi *PeerInfo) takeBodiesRequest(id uint64) (int, bool) {
+	pi.lock.Lock()
+	defer pi.lock.Unlock()
+	for i, req := range pi.bodiesRequests {
+		if req.id == id {
+			pi.bodiesRequests = append(pi.bodiesRequests[:i], pi.bodiesRequests[i+1:]...)
+			return req.bodies, true
+		}
+	}
+	return 0, false
+}
+
 func (pi *PeerInfo) LatestDeadline() time.Time {
 	pi.lock.RLock()
 	defer pi.lock.RUnlock()
@@ -333,6 +380,7 @@ func runPeer(
 	peerInfo *PeerInfo,
 	send func(msgId proto_sentry.MessageId, peerID [64]byte, b []byte),
 	hasSubscribers func(msgId proto_sentry.MessageId) bool,
+	penalize func(peerInfo *PeerInfo, reason PenaltyReason, count int),
 ) error {
 	printTime := time.Now().Add(time.Minute)
 	peerPrinted := false
@@ -392,6 +440,9 @@ func runPeer(
 			if _, err := io.ReadFull(msg.Payload, b); err != nil {
 				log.Error(fmt.Sprintf("%s: reading msg into bytes: %v", peerID, err))
 			}
+			if isEmptyResponse(b) {
+				penalize(peerInfo, EmptyResponsePenalty, 1)
+			}
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 		case eth.GetBlockBodiesMsg:
 			if !hasSubscribers(eth.ToProto[protocol][msg.Code]) {
@@ -411,6 +462,9 @@ func runPeer(
 			if _, err := io.ReadFull(msg.Payload, b); err != nil {
 				log.Error(fmt.Sprintf("%s: reading msg into bytes: %v", peerID, err))
 			}
+			if reason, ok := bodiesPenalty(peerInfo, b); ok {
+				penalize(peerInfo, reason, 1)
+			}
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 		case eth.GetNodeDataMsg:
 			if protocol >= eth.ETH67 {
@@ -444,6 +498,9 @@ func runPeer(
 			if _, err := io.ReadFull(msg.Payload, b); err != nil {
 				log.Error(fmt.Sprintf("%s: reading msg into bytes: %v", peerID, err))
 			}
+			if isEmptyResponse(b) {
+				penalize(peerInfo, EmptyResponsePenalty, 1)
+			}
 			send(eth.ToProto[protocol][msg.Code], peerID, b)
 			//log.Info(fmt.Sprintf("[%s] ReceiptsMsg", peerID))
 		case eth.NewBlockHashesMsg:
@@ -514,6 +571,9 @@ func runPeer(
 		}
 		msg.Discard()
 		peerInfo.ClearDeadlines(time.Now(), givePermit)
+		if expired := peerInfo.takeExpiredDeadlines(); expired > 0 {
+			penalize(peerInfo, SlowResponsePenalty, expired)
+		}
 	}
 }
 
@@ -532,6 +592,7 @@ func grpcSentryServer(ctx context.Context, sentryAddr string, ss *GrpcServer, he
 	}
 	grpcServer := grpcutil.NewServer(100, nil)
 	proto_sentry.RegisterSentryServer(grpcServer, ss)
+	reputation.RegisterPeerReputationServer(grpcServer, ss.ReputationServer())
 	var healthServer *health.Server
 	if healthCheck {
 		healthServer = health.NewServer()
@@ -554,6 +615,7 @@ func NewGrpcServer(ctx context.Context, dialCandidates func() enode.Iterator, re
 		ctx:          ctx,
 		p2p:          cfg,
 		peersStreams: NewPeersStreams(),
+		reputation:   newReputations(),
 	}
 
 	protocols := []uint{protocol}
@@ -579,6 +641,10 @@ func NewGrpcServer(ctx context.Context, dialCandidates func() enode.Iterator, re
 					log.Trace("[p2p] peer already has connection", "peerId", printablePeerID)
 					return nil
 				}
+				if ban, ok := ss.banned(peer.ID()); ok {
+					log.Trace("[p2p] banned peer", "peerId", printablePeerID, "until", ban.Until, "reason", ban.Reason)
+					return fmt.Errorf("[p2p] peer %s is banned until %s", printablePeerID, ban.Until.Format(time.RFC3339))
+				}
 				log.Debug("[p2p] start with peer", "peerId", printablePeerID)
 
 				peerInfo := NewPeerInfo(peer, rw)
@@ -605,6 +671,7 @@ func NewGrpcServer(ctx context.Context, dialCandidates func() enode.Iterator, re
 					peerInfo,
 					ss.send,
 					ss.hasSubscribers,
+					ss.penalize,
 				) // runPeer never returns a nil error
 				log.Trace("[p2p] error while running peer", "peerId", printablePeerID, "err", err)
 				ss.sendGonePeerToClients(gointerfaces.ConvertHashToH512(peerID))
@@ -664,6 +731,8 @@ type GrpcServer struct {
 	messageStreamsLock   sync.RWMutex
 	peersStreams         *PeersStreams
 	p2p                  *p2p.Config
+
+	reputation *reputations // Scores of the peers and bans, nil when not tracked
 }
 
 func (ss *GrpcServer) rangePeers(f func(peerInfo *PeerInfo) bool) {
@@ -742,6 +811,10 @@ func (ss *GrpcServer) PenalizePeer(_ context.Context, req *proto_sentry.Penalize
 	//log.Warn("Received penalty", "kind", req.GetPenalty().Descriptor().FullName, "from", fmt.Sprintf("%s", req.GetPeerId()))
 	peerID := ConvertH512ToPeerID(req.PeerId)
 	peerInfo := ss.getPeer(peerID)
+	if peerInfo != nil {
+		// The core only penalizes peers for invalid data, other penalties are found by the sentry itself
+		ss.penalize(peerInfo, InvalidDataPenalty, 1)
+	}
 	if ss.statusData != nil && peerInfo != nil && !peerInfo.peer.Info().Network.Static && !peerInfo.peer.Info().Network.Trusted {
 		ss.removePeer(peerID)
 		printablePeerID := hex.EncodeToString(peerID[:])[:8]
@@ -765,11 +838,11 @@ func (ss *GrpcServer) findBestPeersWithPermit(peerCount int) []*PeerInfo {
 	var pokePeer *PeerInfo // Peer with the earliest dealine, to be "poked" by the request
 	var pokeDeadline time.Time
 	ss.rangePeers(func(peerInfo *PeerInfo) bool {
-		deadlines := peerInfo.ClearDeadlines(now, false /* givePermit */)
+		deadlines := ss.clearDeadlines(peerInfo, now, false /* givePermit */)
 		height := peerInfo.Height()
 		//fmt.Printf("%d deadlines for peer %s\n", deadlines, peerID)
-		if deadlines < maxPermitsPerPeer {
-			heap.Push(&byMinBlock, PeerRef{pi: peerInfo, height: height})
+		if deadlines < maxPermitsPerPeer && !peerInfo.Removed() {
+			heap.Push(&byMinBlock, PeerRef{pi: peerInfo, height: height, suspicious: ss.suspicious(peerInfo, now)})
 			if byMinBlock.Len() > peerCount {
 				// Remove the worst peer
 				peerRef := heap.Pop(&byMinBlock).(PeerRef)
@@ -796,19 +869,23 @@ func (ss *GrpcServer) findBestPeersWithPermit(peerCount int) []*PeerInfo {
 }
 
 func (ss *GrpcServer) findPeerByMinBlock(minBlock uint64) (*PeerInfo, bool) {
-	// Choose a peer that we can send this request to, with maximum number of permits
+	// Choose a peer that we can send this request to, with maximum number of permits.
+	// Suspicious peers are only chosen when there are no other peers with permits.
 	var foundPeerInfo *PeerInfo
 	var maxPermits int
+	var foundSuspicious bool
 	now := time.Now()
 	ss.rangePeers(func(peerInfo *PeerInfo) bool {
 		if peerInfo.Height() >= minBlock {
-			deadlines := peerInfo.ClearDeadlines(now, false /* givePermit */)
+			deadlines := ss.clearDeadlines(peerInfo, now, false /* givePermit */)
 			//fmt.Printf("%d deadlines for peer %s\n", deadlines, peerID)
-			if deadlines < maxPermitsPerPeer {
+			if deadlines < maxPermitsPerPeer && !peerInfo.Removed() {
 				permits := maxPermitsPerPeer - deadlines
-				if permits > maxPermits {
+				suspicious := ss.suspicious(peerInfo, now)
+				if foundPeerInfo == nil || (foundSuspicious && !suspicious) || (foundSuspicious == suspicious && permits > maxPermits) {
 					maxPermits = permits
 					foundPeerInfo = peerInfo
+					foundSuspicious = suspicious
 				}
 			}
 		}
@@ -825,9 +902,19 @@ func (ss *GrpcServer) SendMessageByMinBlock(_ context.Context, inreq *proto_sent
 		msgcode != eth.GetPooledTransactionsMsg {
 		return reply, fmt.Errorf("sendMessageByMinBlock not implemented for message Id: %s", inreq.Data.Id)
 	}
+	// Bodies requests are remembered to recognise useless bodies in the responses
+	rememberRequest := func(*PeerInfo) {}
+	if msgcode == eth.GetBlockBodiesMsg {
+		if requestID, hashes, err := splitPacket66(inreq.Data.Data); err == nil {
+			if bodies, err := rlp.CountValues(hashes); err == nil {
+				rememberRequest = func(peerInfo *PeerInfo) { peerInfo.addBodiesRequest(requestID, bodies) }
+			}
+		}
+	}
 	if inreq.MaxPeers == 1 {
 		peerInfo, found := ss.findPeerByMinBlock(inreq.MinBlock)
 		if found {
+			rememberRequest(peerInfo)
 			ss.writePeer("sendMessageByMinBlock", peerInfo, msgcode, inreq.Data.Data, 30*time.Second)
 			reply.Peers = []*proto_types.H512{gointerfaces.ConvertHashToH512(peerInfo.ID())}
 			return reply, nil
@@ -836,6 +923,7 @@ func (ss *GrpcServer) SendMessageByMinBlock(_ context.Context, inreq *proto_sent
 	peerInfos := ss.findBestPeersWithPermit(int(inreq.MaxPeers))
 	reply.Peers = make([]*proto_types.H512, len(peerInfos))
 	for i, peerInfo := range peerInfos {
+		rememberRequest(peerInfo)
 		ss.writePeer("sendMessageByMinBlock", peerInfo, msgcode, inreq.Data.Data, 15*time.Second)
 		reply.Peers[i] = gointerfaces.ConvertHashToH512(peerInfo.ID())
 	}
@@ -971,6 +1059,11 @@ func (ss *GrpcServer) SetStatus(ctx context.Context, statusData *proto_sentry.St
 		}
 
 		ss.P2pServer = srv
+		if ss.reputation != nil {
+			if err := ss.reputation.setDB(srv.LocalNode().Database()); err != nil {
+				log.Warn("[p2p] Could not load the banned peers", "err", err)
+			}
+		}
 	}
 
 	ss.P2pServer.LocalNode().Set(eth.CurrentENREntryFromForks(statusData.ForkData.HeightForks, statusData.ForkData.TimeForks, genesisHash, statusData.MaxBlockHeight, statusData.MaxBlockTime))
@@ -3,6 +3,8 @@ package sentry
 import (
 	"context"
 	"math/big"
+	"net"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -14,12 +16,19 @@ import (
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
 
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentry/reputation"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/forkid"
 	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/p2p/enode"
+	"github.com/ledgerwatch/erigon/rlp"
 )
 
 func testSentryServer(db kv.Getter, genesis *core.Genesis, genesisHash libcommon.Hash) *GrpcServer {
@@ -180,3 +189,180 @@ func TestSentryServerImpl_SetStatusInitPanic(t *testing.T) {
 		t.Fatalf("error expected")
 	}
 }
+
+func TestPeerReputation(t *testing.T) {
+	ss := &GrpcServer{ctx: context.Background(), reputation: newReputations()}
+	addPeer := func(b byte, height uint64) *PeerInfo {
+		peerInfo := NewPeerInfo(p2p.NewPeer(enode.ID{b}, [64]byte{b}, "test", nil), nil)
+		peerInfo.SetIncreasedHeight(height)
+		ss.GoodPeers.Store(peerInfo.ID(), peerInfo)
+		t.Cleanup(peerInfo.Close)
+		return peerInfo
+	}
+	slow, fast := addPeer(1, 100), addPeer(2, 50)
+	fast.AddDeadline(time.Now().Add(time.Minute))
+
+	// The peer with most permits is chosen
+	found, ok := ss.findPeerByMinBlock(10)
+	require.True(t, ok)
+	require.Equal(t, enode.ID{1}, found.peer.ID())
+
+	// Requests go to other peers once a peer is suspicious, even when they have fewer permits and the peer is higher
+	slow.AddDeadline(time.Now().Add(-time.Second))
+	ss.penalize(slow, EmptyResponsePenalty, 6)
+	found, ok = ss.findPeerByMinBlock(10)
+	require.True(t, ok)
+	require.Equal(t, enode.ID{2}, found.peer.ID())
+	require.Equal(t, []*PeerInfo{fast}, ss.findBestPeersWithPermit(1))
+	require.InDelta(t, 35, ss.reputation.score(enode.ID{1}, time.Now()), 0.01)
+
+	// Suspicious peers are still chosen when there are no other peers
+	found, ok = ss.findPeerByMinBlock(80)
+	require.True(t, ok)
+	require.Equal(t, enode.ID{1}, found.peer.ID())
+
+	// Invalid data reported by the core gets the peer banned
+	for i := 0; i < 2; i++ {
+		_, err := ss.PenalizePeer(context.Background(), &proto_sentry.PenalizePeerRequest{PeerId: gointerfaces.ConvertHashToH512(slow.ID())})
+		require.NoError(t, err)
+	}
+	require.True(t, slow.Removed())
+	require.Nil(t, ss.getPeer(slow.ID()))
+	ban, banned := ss.banned(enode.ID{1})
+	require.True(t, banned)
+	require.WithinDuration(t, time.Now().Add(autoBanDuration), ban.Until, time.Minute)
+
+	reputations := ss.PeerReputations()
+	require.Equal(t, 1, len(reputations))
+	require.Equal(t, map[PenaltyReason]uint64{SlowResponsePenalty: 1, EmptyResponsePenalty: 6, InvalidDataPenalty: 2}, reputations[0].Penalties)
+	require.NotNil(t, reputations[0].BannedUntil)
+
+	found, ok = ss.findPeerByMinBlock(80)
+	require.False(t, ok)
+	require.Nil(t, found)
+}
+
+func TestPeerBansPersistence(t *testing.T) {
+	path, tmpDir := filepath.Join(t.TempDir(), "nodes"), t.TempDir()
+	db, err := enode.OpenDB(path, tmpDir)
+	require.NoError(t, err)
+
+	r := newReputations()
+	require.NoError(t, r.ban(enode.ID{1}, time.Now().Add(time.Hour), "before start"))
+	require.NoError(t, r.setDB(db))
+	require.NoError(t, r.ban(enode.ID{2}, time.Now().Add(time.Hour), "after start"))
+	require.NoError(t, r.ban(enode.ID{3}, time.Now().Add(time.Hour), "unbanned"))
+	unbanned, err := r.unban(enode.ID{3})
+	require.NoError(t, err)
+	require.True(t, unbanned)
+	db.Close()
+
+	db, err = enode.OpenDB(path, tmpDir)
+	require.NoError(t, err)
+	defer db.Close()
+	r = newReputations()
+	require.NoError(t, r.setDB(db))
+	for _, id := range []enode.ID{{1}, {2}} {
+		_, banned := r.banned(id, time.Now())
+		require.True(t, banned)
+	}
+	_, banned := r.banned(enode.ID{3}, time.Now())
+	require.False(t, banned)
+	_, banned = r.banned(enode.ID{1}, time.Now().Add(2*time.Hour))
+	require.False(t, banned)
+}
+
+func TestPeerReputationsOverGrpc(t *testing.T) {
+	ss := &GrpcServer{ctx: context.Background(), reputation: newReputations(), Protocols: []p2p.Protocol{{Version: eth.ETH66}}}
+	peerInfo := NewPeerInfo(p2p.NewPeer(enode.ID{1}, [64]byte{1}, "test", nil), nil)
+	ss.GoodPeers.Store(peerInfo.ID(), peerInfo)
+	t.Cleanup(peerInfo.Close)
+	ss.penalize(peerInfo, EmptyResponsePenalty, 2)
+
+	dial := func(register func(*grpc.Server)) *grpc.ClientConn {
+		server := grpc.NewServer()
+		register(server)
+		listener := bufconn.Listen(1024 * 1024)
+		go server.Serve(listener) //nolint:errcheck
+		conn, err := grpc.DialContext(context.Background(), "", grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			conn.Close()
+			server.Stop()
+		})
+		return conn
+	}
+	// The sentry serves its reputations along the sentry service, and the node those of its sentries to the RPC daemon
+	sentryConn := dial(func(server *grpc.Server) {
+		proto_sentry.RegisterSentryServer(server, ss)
+		reputation.RegisterPeerReputationServer(server, ss.ReputationServer())
+	})
+	nodeConn := dial(func(server *grpc.Server) {
+		reputation.RegisterPeerReputationServer(server, reputation.NewSentriesServer([]reputation.PeerReputationClient{reputation.NewPeerReputationClient(sentryConn)}))
+	})
+	client := reputation.NewPeerReputationClient(nodeConn)
+
+	count, err := proto_sentry.NewSentryClient(sentryConn).PeerCount(context.Background(), &proto_sentry.PeerCountRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), count.Count)
+
+	peers, err := client.Peers(context.Background(), &reputation.PeersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(peers.Peers))
+	require.Equal(t, enode.ID{1}.Bytes(), peers.Peers[0].ID)
+	require.Equal(t, uint32(eth.ETH66), peers.Peers[0].Protocol)
+	require.True(t, peers.Peers[0].Connected)
+	require.InDelta(t, 10, peers.Peers[0].Score, 0.01)
+	require.Equal(t, []*reputation.Penalty{{Reason: EmptyResponsePenalty.String(), Count: 2}}, peers.Peers[0].Penalties)
+	require.Zero(t, peers.Peers[0].BannedUntil)
+
+	_, err = client.Ban(context.Background(), &reputation.BanRequest{ID: enode.ID{1}.Bytes(), Seconds: 60, Reason: "test"})
+	require.NoError(t, err)
+	require.True(t, peerInfo.Removed())
+	peers, err = client.Peers(context.Background(), &reputation.PeersRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(peers.Peers))
+	require.False(t, peers.Peers[0].Connected)
+	require.Equal(t, "test", peers.Peers[0].BanReason)
+	require.WithinDuration(t, time.Now().Add(time.Minute), time.Unix(0, peers.Peers[0].BannedUntil), 10*time.Second)
+
+	_, err = client.Ban(context.Background(), &reputation.BanRequest{ID: []byte{1}})
+	require.ErrorContains(t, err, "invalid node id length: 1")
+
+	unbanned, err := client.Unban(context.Background(), &reputation.UnbanRequest{ID: enode.ID{1}.Bytes()})
+	require.NoError(t, err)
+	require.True(t, unbanned.Banned)
+	unbanned, err = client.Unban(context.Background(), &reputation.UnbanRequest{ID: enode.ID{1}.Bytes()})
+	require.NoError(t, err)
+	require.False(t, unbanned.Banned)
+}
+
+func TestUselessBodies(t *testing.T) {
+	peerInfo := NewPeerInfo(p2p.NewPeer(enode.ID{1}, [64]byte{1}, "test", nil), nil)
+	defer peerInfo.Close()
+	bodies := func(requestID uint64, count int) []byte {
+		packet := &eth.BlockBodiesPacket66{RequestId: requestID}
+		for i := 0; i < count; i++ {
+			packet.BlockBodiesPacket = append(packet.BlockBodiesPacket, &types.Body{})
+		}
+		b, err := rlp.EncodeToBytes(packet)
+		require.NoError(t, err)
+		return b
+	}
+	peerInfo.addBodiesRequest(1, 2)
+	peerInfo.addBodiesRequest(2, 2)
+	peerInfo.addBodiesRequest(3, 2)
+
+	_, penalized := bodiesPenalty(peerInfo, bodies(1, 2))
+	require.False(t, penalized)
+	reason, penalized := bodiesPenalty(peerInfo, bodies(1, 2)) // Already delivered
+	require.True(t, penalized)
+	require.Equal(t, UselessBodiesPenalty, reason)
+	reason, _ = bodiesPenalty(peerInfo, bodies(2, 3))
+	require.Equal(t, UselessBodiesPenalty, reason)
+	reason, _ = bodiesPenalty(peerInfo, bodies(3, 0))
+	require.Equal(t, EmptyResponsePenalty, reason)
+	require.True(t, isEmptyResponse(bodies(4, 0)))
+	require.False(t, isEmptyResponse(bodies(4, 1)))
+}
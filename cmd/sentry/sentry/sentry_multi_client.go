@@ -30,6 +30,7 @@ import (
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentry/reputation"
 	"github.com/ledgerwatch/erigon/consensus"
 	"github.com/ledgerwatch/erigon/core/forkid"
 	"github.com/ledgerwatch/erigon/core/types"
@@ -40,6 +41,7 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/stages/bodydownload"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+	"github.com/ledgerwatch/erigon/turbo/stages/receiptdownload"
 )
 
 type sentryMessageStream grpc.ClientStream
@@ -103,6 +105,7 @@ func (cs *MultiClient) RecvMessageLoop(
 		eth.ToProto[eth.ETH66][eth.BlockBodiesMsg],
 		eth.ToProto[eth.ETH66][eth.NewBlockHashesMsg],
 		eth.ToProto[eth.ETH66][eth.NewBlockMsg],
+		eth.ToProto[eth.ETH66][eth.ReceiptsMsg],
 	}
 	streamFactory := func(streamCtx context.Context, sentry direct.SentryClient) (sentryMessageStream, error) {
 		return sentry.Messages(streamCtx, &proto_sentry.MessagesRequest{Ids: ids}, grpc.WaitForReady(true))
@@ -247,6 +250,7 @@ type MultiClient struct {
 	lock                              sync.RWMutex
 	Hd                                *headerdownload.HeaderDownload
 	Bd                                *bodydownload.BodyDownload
+	Rd                                *receiptdownload.ReceiptDownload
 	IsMock                            bool
 	forkValidator                     *engineapi.ForkValidator
 	nodeName                          string
@@ -305,6 +309,7 @@ func NewMultiClient(
 		nodeName:                          nodeName,
 		Hd:                                hd,
 		Bd:                                bd,
+		Rd:                                receiptdownload.NewReceiptDownload(receiptdownload.DefaultMaxAttempts),
 		sentries:                          sentries,
 		db:                                db,
 		Engine:                            engine,
@@ -574,7 +579,12 @@ func (cs *MultiClient) blockBodies66(ctx context.Context, inreq *proto_sentry.In
 	return nil
 }
 
-func (cs *MultiClient) receipts66(_ context.Context, _ *proto_sentry.InboundMessage, _ direct.SentryClient) error {
+func (cs *MultiClient) receipts66(_ context.Context, inreq *proto_sentry.InboundMessage, _ direct.SentryClient) error {
+	var request eth.ReceiptsPacket66
+	if err := rlp.DecodeBytes(inreq.Data, &request); err != nil {
+		return fmt.Errorf("decode ReceiptsPacket66: %w", err)
+	}
+	cs.Rd.DeliverReceipts(request.RequestId, request.ReceiptsPacket, uint64(len(inreq.Data)), ConvertH512ToPeerID(inreq.PeerId))
 	return nil
 }
 
@@ -798,7 +808,9 @@ func (cs *MultiClient) makeStatusData() *proto_sentry.StatusData {
 	}
 }
 
-func GrpcClient(ctx context.Context, sentryAddr string) (*direct.SentryClientRemote, error) {
+// GrpcClient connects to the sentry at sentryAddr, returning the client of its sentry service along with the
+// one of its peer reputation service
+func GrpcClient(ctx context.Context, sentryAddr string) (*direct.SentryClientRemote, reputation.PeerReputationClient, error) {
 	// creating grpc client connection
 	var dialOpts []grpc.DialOption
 
@@ -814,7 +826,7 @@ func GrpcClient(ctx context.Context, sentryAddr string) (*direct.SentryClientRem
 	dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	conn, err := grpc.DialContext(ctx, sentryAddr, dialOpts...)
 	if err != nil {
-		return nil, fmt.Errorf("creating client connection to sentry P2P: %w", err)
+		return nil, nil, fmt.Errorf("creating client connection to sentry P2P: %w", err)
 	}
-	return direct.NewSentryClientRemote(proto_sentry.NewSentryClient(conn)), nil
+	return direct.NewSentryClientRemote(proto_sentry.NewSentryClient(conn)), reputation.NewPeerReputationClient(conn), nil
 }
@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"time"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/datadir"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcfg"
+	kv2 "github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	libstate "github.com/ledgerwatch/erigon-lib/state"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/spf13/cobra"
+
+	"github.com/ledgerwatch/erigon/cmd/state/exec3"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/state/historyv2read"
+	"github.com/ledgerwatch/erigon/core/state/temporal"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/firehose/backfill"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+)
+
+var (
+	backfillFrom       uint64
+	backfillTo         uint64
+	backfillBundleSize uint64
+	backfillOutputDir  string
+	backfillFormat     string
+	backfillShards     int
+	backfillShard      int
+)
+
+func init() {
+	withDataDir(firehoseBackfillCmd)
+	firehoseBackfillCmd.Flags().Uint64Var(&backfillFrom, "from", 0, "first block of the range to backfill")
+	firehoseBackfillCmd.Flags().Uint64Var(&backfillTo, "to", 0, "last block of the range to backfill (inclusive)")
+	firehoseBackfillCmd.Flags().Uint64Var(&backfillBundleSize, "bundle-size", 100, "number of blocks per bundle file, bundles are aligned on multiples of it")
+	firehoseBackfillCmd.Flags().StringVar(&backfillOutputDir, "output-dir", "", "directory the bundle files are written to, defaults to <datadir>/firehose-backfill")
+	firehoseBackfillCmd.Flags().StringVar(&backfillFormat, "format", string(firehose.TextOutputFormat), "firehose output format, one of \"text\" or \"protobuf\"")
+	firehoseBackfillCmd.Flags().IntVar(&backfillShards, "shards", 1, "number of shards the bundles of the range are split into, each run by its own process")
+	firehoseBackfillCmd.Flags().IntVar(&backfillShard, "shard", 0, "index of the shard this process backfills, between 0 and --shards - 1")
+	must(firehoseBackfillCmd.MarkFlagRequired("to"))
+	rootCmd.AddCommand(firehoseBackfillCmd)
+}
+
+var firehoseBackfillCmd = &cobra.Command{
+	Use:   "firehoseBackfill",
+	Short: "Re-executes historical blocks and writes their Firehose output to bundle files",
+	Long: `Re-executes the blocks of the range [--from, --to] against historical state and writes their
+Firehose output to files of --bundle-size blocks, named after the range they contain. Bundles already
+present in the output directory are skipped, so an interrupted backfill resumes where it stopped.
+
+Disjoint parts of a range can be backfilled in parallel by running one process per shard, all with
+the same range and --shards and a different --shard, against the same datadir.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := log.New()
+		outputDir := backfillOutputDir
+		if outputDir == "" {
+			outputDir = filepath.Join(datadirCli, "firehose-backfill")
+		}
+		return FirehoseBackfill(cmd.Context(), genesis, logger, backfillFrom, backfillTo, backfillBundleSize, outputDir, firehose.OutputFormat(backfillFormat), backfillShards, backfillShard)
+	},
+}
+
+// FirehoseBackfill re-executes the blocks of the range [from, to] in read-only mode and writes
+// the Firehose output of the bundles of the given shard to outputDir.
+func FirehoseBackfill(ctx context.Context, genesis *core.Genesis, logger log.Logger, from, to, bundleSize uint64, outputDir string, format firehose.OutputFormat, shards, shard int) error {
+	bundles, err := backfill.Bundles(from, to, bundleSize)
+	if err != nil {
+		return err
+	}
+	if bundles, err = backfill.Shard(bundles, shards, shard); err != nil {
+		return err
+	}
+	if len(bundles) == 0 {
+		log.Info("Nothing to backfill for this shard", "shard", shard, "shards", shards)
+		return nil
+	}
+
+	writer, err := backfill.NewBundleWriter(outputDir, format)
+	if err != nil {
+		return err
+	}
+	defer writer.Abort()
+
+	dirs := datadir.New(datadirCli)
+	db, err := kv2.NewMDBX(logger).Path(dirs.Chaindata).Readonly().Open()
+	if err != nil {
+		return fmt.Errorf("opening chaindata as read only: %w", err)
+	}
+	defer db.Close()
+
+	var chainDb kv.RoDB = db
+	if kvcfg.HistoryV3.FromDB(db) {
+		agg, err := libstate.NewAggregatorV3(ctx, dirs.SnapHistory, dirs.Tmp, ethconfig.HistoryV3AggregationStep, db)
+		if err != nil {
+			return fmt.Errorf("create history aggregator: %w", err)
+		}
+		defer agg.Close()
+		if err = agg.OpenFolder(); err != nil {
+			return err
+		}
+		if chainDb, err = temporal.New(db, agg, accounts.ConvertV3toV2, historyv2read.RestoreCodeHash, accounts.DecodeIncarnationFromStorage, systemcontracts.SystemContractCodeLookup[genesis.Config.ChainName]); err != nil {
+			return err
+		}
+	}
+	historyV3 := kvcfg.HistoryV3.FromDB(chainDb)
+
+	allSnapshots := snapshotsync.NewRoSnapshots(ethconfig.NewSnapCfg(true, false, true), path.Join(datadirCli, "snapshots"))
+	defer allSnapshots.Close()
+	if err := allSnapshots.ReopenFolder(); err != nil {
+		return fmt.Errorf("reopen snapshot segments: %w", err)
+	}
+	blockReader := snapshotsync.NewBlockReaderWithSnapshots(allSnapshots, kvcfg.TransactionsV3.FromDB(chainDb))
+	engine := initConsensusEngine(genesis.Config, allSnapshots)
+
+	// The node's finality is way ahead of historical blocks, none is advertised for them
+	firehose.Enabled = true
+	firehose.SetSyncingBehindFinalized(true)
+	firehose.SetSyncPrinter(writer)
+
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+
+	for _, bundle := range bundles {
+		exists, err := writer.Exists(bundle)
+		if err != nil {
+			return err
+		}
+		if exists {
+			log.Info("Skipping bundle already backfilled", "bundle", bundle)
+			continue
+		}
+
+		if err := writer.Open(bundle); err != nil {
+			return err
+		}
+		firehose.MaybeSyncContext().InitVersion(params.VersionWithCommit(params.GitCommit), params.FirehoseVersion(), params.Variant)
+
+		if err := chainDb.View(ctx, func(tx kv.Tx) error {
+			for blockNum := bundle.From; blockNum <= bundle.To; blockNum++ {
+				if err := backfillBlock(ctx, tx, blockReader, engine, genesis, historyV3, blockNum); err != nil {
+					return fmt.Errorf("block %d: %w", blockNum, err)
+				}
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-logEvery.C:
+					log.Info("Backfilling", "block", blockNum, "bundle", bundle)
+				default:
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if err := writer.Commit(); err != nil {
+			return err
+		}
+		log.Info("Backfilled bundle", "bundle", bundle, "file", writer.Path(bundle))
+	}
+
+	return nil
+}
+
+func backfillBlock(ctx context.Context, tx kv.Tx, blockReader services.FullBlockReader, engine consensus.Engine, genesis *core.Genesis, historyV3 bool, blockNum uint64) error {
+	chainConfig := genesis.Config
+	if blockNum == 0 {
+		genesisBlock, _, err := genesis.ToBlock("")
+		if err != nil {
+			return err
+		}
+		firehose.MaybeSyncContext().RecordGenesisBlock(genesisBlock, core.FirehoseGenesisRecorder(genesis))
+		return nil
+	}
+
+	blockHash, err := blockReader.CanonicalHash(ctx, tx, blockNum)
+	if err != nil {
+		return err
+	}
+	block, _, err := blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return fmt.Errorf("block not found")
+	}
+
+	stateReader, err := rpchelper.CreateHistoryStateReader(tx, blockNum, 0, historyV3, chainConfig.ChainName)
+	if err != nil {
+		return err
+	}
+
+	getHeader := func(hash libcommon.Hash, number uint64) *types.Header {
+		h, err := blockReader.Header(ctx, tx, hash, number)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}
+	getHashFn := core.GetHashFn(block.Header(), getHeader)
+	chainReader := exec3.NewChainReader(chainConfig, tx, blockReader)
+	epochReader := exec3.NewEpochReader(tx)
+	stateWriter := state.NewNoopWriter()
+	vmConfig := vm.Config{}
+
+	if _, isPoSa := engine.(consensus.PoSA); isPoSa {
+		_, err = core.ExecuteBlockEphemerallyForBSC(chainConfig, &vmConfig, getHashFn, engine, block, stateReader, stateWriter, epochReader, chainReader, nil)
+	} else if chainConfig.Bor != nil {
+		_, err = core.ExecuteBlockEphemerallyBor(chainConfig, &vmConfig, getHashFn, engine, block, stateReader, stateWriter, epochReader, chainReader, nil)
+	} else {
+		_, err = core.ExecuteBlockEphemerally(chainConfig, &vmConfig, getHashFn, engine, block, stateReader, stateWriter, epochReader, chainReader, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	firehose.MaybeSyncContext().RecordNewBlock(block.Header())
+	return nil
+}
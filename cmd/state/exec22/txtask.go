@@ -7,6 +7,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
+	"github.com/ledgerwatch/erigon/firehose"
 )
 
 // ReadWriteSet contains ReadSet, WriteSet and BalanceIncrease of a transaction,
@@ -45,6 +46,15 @@ type TxTask struct {
 	TraceTos           map[libcommon.Address]struct{}
 
 	UsedGas uint64
+
+	// Firehose records the execution of the task when Firehose is enabled, it's merged into
+	// the sync context when the task is applied, in transaction order
+	Firehose *firehose.Context
+}
+
+// Block assembles the block the task belongs to
+func (t *TxTask) Block() *types.Block {
+	return types.NewBlockFromStorage(t.BlockHash, t.Header, t.Txs, t.Uncles, t.Withdrawals)
 }
 
 type TxTaskQueue []*TxTask
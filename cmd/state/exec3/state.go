@@ -140,6 +140,13 @@ func (rw *Worker) RunTxTaskNoLock(txTask *exec22.TxTask) {
 	rw.ibs.Reset()
 	ibs := rw.ibs
 
+	// Tasks are executed concurrently and possibly more than once, each run records into its own buffer
+	firehoseContext := firehose.NoOpContext
+	if firehose.MaybeSyncContext().Enabled() && txTask.BlockNum > 0 {
+		firehoseContext = firehose.NewTxBufferContext()
+	}
+	txTask.Firehose = firehoseContext
+
 	rules := txTask.Rules
 	daoForkTx := rw.chainConfig.DAOForkSupport && rw.chainConfig.DAOForkBlock != nil && rw.chainConfig.DAOForkBlock.Uint64() == txTask.BlockNum && txTask.TxIndex == -1
 	var err error
@@ -155,7 +162,7 @@ func (rw *Worker) RunTxTaskNoLock(txTask *exec22.TxTask) {
 		rules = &chain.Rules{}
 	} else if daoForkTx {
 		//fmt.Printf("txNum=%d, blockNum=%d, DAO fork\n", txTask.TxNum, txTask.BlockNum)
-		misc.ApplyDAOHardFork(ibs, firehose.NoOpContext)
+		misc.ApplyDAOHardFork(ibs, firehoseContext)
 		ibs.SoftFinalise()
 	} else if txTask.TxIndex == -1 {
 		// Block initialisation
@@ -164,18 +171,18 @@ func (rw *Worker) RunTxTaskNoLock(txTask *exec22.TxTask) {
 			systemcontracts.UpgradeBuildInSystemContract(rw.chainConfig, header.Number, ibs)
 		}
 		syscall := func(contract libcommon.Address, data []byte) ([]byte, error) {
-			return core.SysCallContract(contract, data, *rw.chainConfig, ibs, header, rw.engine, false /* constCall */, firehose.NoOpContext)
+			return core.SysCallContract(contract, data, *rw.chainConfig, ibs, header, rw.engine, false /* constCall */, firehoseContext)
 		}
-		rw.engine.Initialize(rw.chainConfig, rw.chain, rw.epoch, header, ibs, txTask.Txs, txTask.Uncles, syscall, firehose.NoOpContext)
+		rw.engine.Initialize(rw.chainConfig, rw.chain, rw.epoch, header, ibs, txTask.Txs, txTask.Uncles, syscall, firehoseContext)
 	} else if txTask.Final {
 		if txTask.BlockNum > 0 {
 			//fmt.Printf("txNum=%d, blockNum=%d, finalisation of the block\n", txTask.TxNum, txTask.BlockNum)
 			// End of block transaction in a block
 			syscall := func(contract libcommon.Address, data []byte) ([]byte, error) {
-				return core.SysCallContract(contract, data, *rw.chainConfig, ibs, header, rw.engine, false /* constCall */, firehose.NoOpContext)
+				return core.SysCallContract(contract, data, *rw.chainConfig, ibs, header, rw.engine, false /* constCall */, firehoseContext)
 			}
 
-			if _, _, err := rw.engine.Finalize(rw.chainConfig, types.CopyHeader(header), ibs, txTask.Txs, txTask.Uncles, nil /* receipts */, txTask.Withdrawals, rw.epoch, rw.chain, syscall, firehose.NoOpContext); err != nil {
+			if _, _, err := rw.engine.Finalize(rw.chainConfig, types.CopyHeader(header), ibs, txTask.Txs, txTask.Uncles, nil /* receipts */, txTask.Withdrawals, rw.epoch, rw.chain, syscall, firehoseContext); err != nil {
 				//fmt.Printf("error=%v\n", err)
 				txTask.Error = err
 			} else {
@@ -209,17 +216,29 @@ func (rw *Worker) RunTxTaskNoLock(txTask *exec22.TxTask) {
 			blockContext = core.NewEVMBlockContext(header, getHashFn, rw.engine, nil /* author */)
 		}
 		rw.evm.ResetBetweenBlocks(blockContext, core.NewEVMTxContext(msg), ibs, vmConfig, rules)
+		rw.evm.SetFirehoseContext(firehoseContext)
 		vmenv := rw.evm
 
+		if firehoseContext.Enabled() {
+			firehoseContext.StartTransaction(txTask.Tx, header.BaseFee)
+			firehoseContext.RecordTrxFrom(msg.From())
+		}
+
 		applyRes, err := core.ApplyMessage(vmenv, msg, rw.taskGasPool, true /* refunds */, false /* gasBailout */)
 		if err != nil {
 			txTask.Error = err
+			if firehoseContext.Enabled() {
+				firehoseContext.RecordFailedTransaction(err)
+			}
 			//fmt.Printf("error=%v\n", err)
 		} else {
 			txTask.UsedGas = applyRes.UsedGas
 			// Update the state with pending changes
 			ibs.SoftFinalise()
 			txTask.Logs = ibs.GetLogs(txHash)
+			if firehoseContext.Enabled() {
+				firehoseContext.EndTransaction(firehoseReceipt(txTask, applyRes))
+			}
 			txTask.TraceFroms = rw.callTracer.Froms()
 			txTask.TraceTos = rw.callTracer.Tos()
 		}
@@ -257,6 +276,56 @@ func (rw *Worker) RunTxTaskNoLock(txTask *exec22.TxTask) {
 	}
 }
 
+// firehoseReceipt is the receipt of a transaction task as built by serial execution, its cumulative
+// gas used is relative to the task and rebased on the block's when the task's records are merged.
+func firehoseReceipt(txTask *exec22.TxTask, result *core.ExecutionResult) *types.Receipt {
+	return core.MakeReceipt(txTask.Header, txTask.Tx, txTask.TxAsMessage.From(), result, result.UsedGas, txTask.Logs, txTask.TxIndex)
+}
+
+// FirehoseApply merges the Firehose records of an applied task into the sync context. Workers execute
+// tasks in any order but they're applied in transaction order, merging them there produces the same
+// output as serial execution.
+func (rw *Worker) FirehoseApply(txTask *exec22.TxTask) {
+	if txTask.BlockNum == 0 {
+		// The genesis block is recorded when it's written
+		return
+	}
+
+	firehoseContext := firehose.MaybeSyncContext()
+	if !firehoseContext.Enabled() {
+		if firehose.BlockProgressEnabled && txTask.Final {
+			firehose.SyncContext().FinalizeBlock(txTask.Block())
+		}
+		return
+	}
+
+	if txTask.TxIndex == -1 {
+		firehoseContext.StartBlock(txTask.Block())
+	}
+	if txTask.Final {
+		firehoseContext.FinalizeBlock(txTask.Block())
+	}
+	if txTask.Firehose != nil {
+		firehoseContext.MergeTxBuffer(txTask.Firehose)
+		txTask.Firehose = nil
+	}
+	if txTask.Final {
+		core.FirehoseEndBlock(firehoseContext, txTask.Block(), rw.chain)
+		firehoseContext.RecordNewBlock(txTask.Header)
+	}
+}
+
+// FirehoseCancel cancels the block of a task that can't be applied, the tasks of the block applied
+// so far having already been merged into the sync context.
+func (rw *Worker) FirehoseCancel(txTask *exec22.TxTask, err error) {
+	if txTask.BlockNum == 0 || txTask.TxIndex == -1 || !firehose.MaybeSyncContext().Enabled() {
+		// The block is started when its initialisation task is applied
+		return
+	}
+
+	firehose.MaybeSyncContext().CancelBlock(txTask.Block(), err)
+}
+
 type ChainReader struct {
 	config      *chain.Config
 	tx          kv.Tx
@@ -267,9 +336,15 @@ func NewChainReader(config *chain.Config, tx kv.Tx, blockReader services.FullBlo
 	return ChainReader{config: config, tx: tx, blockReader: blockReader}
 }
 
-func (cr ChainReader) Config() *chain.Config                 { return cr.config }
-func (cr ChainReader) CurrentHeader() *types.Header          { panic("") }
-func (cr ChainReader) CurrentFinalizedHeader() *types.Header { panic("") }
+func (cr ChainReader) Config() *chain.Config        { return cr.config }
+func (cr ChainReader) CurrentHeader() *types.Header { panic("") }
+func (cr ChainReader) CurrentFinalizedHeader() *types.Header {
+	hash := rawdb.ReadForkchoiceFinalized(cr.tx)
+	if hash == (libcommon.Hash{}) {
+		return nil
+	}
+	return cr.GetHeaderByHash(hash)
+}
 func (cr ChainReader) GetHeader(hash libcommon.Hash, number uint64) *types.Header {
 	if cr.blockReader != nil {
 		h, _ := cr.blockReader.Header(context.Background(), cr.tx, hash, number)
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/ledgerwatch/erigon-lib/chain"
 	"github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/direct"
@@ -15,6 +16,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/grpcutil"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	proto_sentry "github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon-lib/kv/remotedb"
 	"github.com/ledgerwatch/erigon-lib/kv/remotedbserver"
@@ -28,6 +30,9 @@ import (
 
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/common/paths"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/firehose"
+	firehosemempool "github.com/ledgerwatch/erigon/firehose/mempool"
 	"github.com/ledgerwatch/erigon/turbo/debug"
 	logging2 "github.com/ledgerwatch/erigon/turbo/logging"
 )
@@ -50,6 +55,9 @@ var (
 	priceLimit   uint64
 	accountSlots uint64
 	priceBump    uint64
+
+	firehoseMempool      bool
+	firehoseOutputFormat string
 )
 
 func init() {
@@ -72,6 +80,8 @@ func init() {
 	rootCmd.PersistentFlags().Uint64Var(&accountSlots, "txpool.accountslots", txpool.DefaultConfig.AccountSlots, "Minimum number of executable transaction slots guaranteed per account")
 	rootCmd.PersistentFlags().Uint64Var(&priceBump, "txpool.pricebump", txpool.DefaultConfig.PriceBump, "Price bump percentage to replace an already existing transaction")
 	rootCmd.Flags().StringSliceVar(&traceSenders, utils.TxPoolTraceSendersFlag.Name, []string{}, utils.TxPoolTraceSendersFlag.Usage)
+	rootCmd.Flags().BoolVar(&firehoseMempool, "firehose-mempool", false, "Print Firehose mempool records of the transactions added, replaced, dropped and included to standard output")
+	rootCmd.Flags().StringVar(&firehoseOutputFormat, "firehose-output-format", string(firehose.TextOutputFormat), "Encoding of the Firehose mempool records, either 'text' or 'protobuf'")
 }
 
 var rootCmd = &cobra.Command{
@@ -171,6 +181,12 @@ func doTxpool(ctx context.Context) error {
 		return err
 	}
 
+	if firehoseMempool {
+		if err := startFirehoseMempool(ctx, direct.NewTxPoolClient(txpoolGrpcServer), kvClient, coreDB); err != nil {
+			return err
+		}
+	}
+
 	notifyMiner := func() {}
 	txpool.MainLoop(ctx, txPoolDB, coreDB, txPool, newTxs, send, txpoolGrpcServer.NewSlotsStreams, notifyMiner)
 
@@ -187,3 +203,35 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// startFirehoseMempool records the transactions flowing through the pool as Firehose mempool
+// events printed to standard output, the pool following the chain through stateChanges.
+func startFirehoseMempool(ctx context.Context, pool firehosemempool.Pool, stateChanges firehosemempool.StateChangesClient, coreDB kv.RoDB) error {
+	var chainConfig *chain.Config
+	if err := coreDB.View(ctx, func(tx kv.Tx) error {
+		genesisHash, err := rawdb.ReadCanonicalHash(tx, 0)
+		if err != nil {
+			return err
+		}
+		chainConfig, err = rawdb.ReadChainConfig(tx, genesisHash)
+		return err
+	}); err != nil {
+		return fmt.Errorf("firehose mempool read chain config: %w", err)
+	}
+	if chainConfig == nil {
+		return errors.New("firehose mempool: chain config not found, the node is not initialized")
+	}
+
+	printer, err := firehose.NewPrinter(firehose.OutputFormat(firehoseOutputFormat), os.Stdout)
+	if err != nil {
+		return err
+	}
+	firehose.Enabled = true
+	firehose.MempoolEnabled = true
+	firehose.SetMempoolPrinter(printer)
+
+	observer := firehosemempool.NewObserver(pool, stateChanges, chainConfig, firehose.MaybeMempoolContext())
+	go observer.Run(ctx)
+	log.Info("Firehose mempool instrumentation started", "output_format", firehoseOutputFormat)
+	return nil
+}
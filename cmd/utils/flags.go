@@ -373,6 +373,16 @@ var (
 		Name:  "rpc.accessList",
 		Usage: "Specify granular (method-by-method) API allowlist",
 	}
+	RpcRateLimitFlag = cli.StringFlag{
+		Name:  "rpc.ratelimit",
+		Usage: "Specify a JSON file with per-client rate limits and per-method costs of the API calls",
+	}
+
+	RpcMaxGetProofRewindBlockCount = cli.IntFlag{
+		Name:  "rpc.maxgetproofrewinddepth",
+		Usage: "Sets a limit on the number of blocks eth_getProof and eth_simulateV1 can rewind the state trie by to work on a past block, the rewind being held in memory",
+		Value: 1_000,
+	}
 
 	RpcGasCapFlag = cli.UintFlag{
 		Name:  "rpc.gascap",
@@ -677,6 +687,10 @@ var (
 		Name:  "downloader.verify",
 		Usage: "verify snapshots on startup. it will not report founded problems but just re-download broken pieces",
 	}
+	SnapVerifyFlag = cli.BoolFlag{
+		Name:  "snap.verify",
+		Usage: "check segments and their indices on startup, and refuse to start if they are broken. `erigon snapshots verify` repairs them",
+	}
 	DisableIPV6 = cli.BoolFlag{
 		Name:  "downloader.disable.ipv6",
 		Usage: "Turns off ipv6 for the downlaoder",
@@ -1458,6 +1472,7 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	cfg.Snapshot.Produce = !ctx.Bool(SnapStopFlag.Name)
 	cfg.Snapshot.NoDownloader = ctx.Bool(NoDownloaderFlag.Name)
 	cfg.Snapshot.Verify = ctx.Bool(DownloaderVerifyFlag.Name)
+	cfg.Snapshot.VerifySegments = ctx.Bool(SnapVerifyFlag.Name)
 	cfg.Snapshot.DownloaderAddr = strings.TrimSpace(ctx.String(DownloaderAddrFlag.Name))
 	if cfg.Snapshot.DownloaderAddr == "" {
 		downloadRateStr := ctx.String(TorrentDownloadRateFlag.Name)
@@ -776,18 +776,22 @@ func (c *AuRa) Initialize(config *chain.Config, chain consensus.ChainHeaderReade
 	state *state.IntraBlockState, txs []types.Transaction, uncles []*types.Header, syscall consensus.SystemCall, firehoseContext *firehose.Context,
 ) {
 	blockNum := header.Number.Uint64()
-	for address, rewrittenCode := range c.cfg.RewriteBytecode[blockNum] {
-		state.SetCode(address, rewrittenCode, firehoseContext)
+	if rewrittenCodes := c.cfg.RewriteBytecode[blockNum]; len(rewrittenCodes) > 0 {
+		firehoseContext.StartSystemCall(firehose.SystemCallReason("aura_rewrite_bytecode"))
+		for address, rewrittenCode := range rewrittenCodes {
+			state.SetCode(address, rewrittenCode, firehoseContext)
+		}
+		firehoseContext.EndSystemCall()
 	}
 
 	c.certifierLock.Lock()
 	if c.cfg.Registrar != nil && c.certifier == nil && config.IsLondon(blockNum) {
-		c.certifier = getCertifier(*c.cfg.Registrar, syscall)
+		c.certifier = getCertifier(*c.cfg.Registrar, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("aura_get_certifier")))
 	}
 	c.certifierLock.Unlock()
 
 	if blockNum == 1 {
-		proof, err := c.GenesisEpochData(header, syscall)
+		proof, err := c.GenesisEpochData(header, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("aura_genesis_epoch_data")))
 		if err != nil {
 			panic(err)
 		}
@@ -817,7 +821,7 @@ func (c *AuRa) Initialize(config *chain.Config, chain consensus.ChainHeaderReade
 	if !isEpochBegin {
 		return
 	}
-	err = c.cfg.Validators.onEpochBegin(isEpochBegin, header, syscall)
+	err = c.cfg.Validators.onEpochBegin(isEpochBegin, header, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("aura_epoch_begin")))
 	if err != nil {
 		log.Warn("[aura] initialize block: on epoch begin", "err", err)
 		return
@@ -827,6 +831,9 @@ func (c *AuRa) Initialize(config *chain.Config, chain consensus.ChainHeaderReade
 }
 
 func (c *AuRa) ApplyRewards(header *types.Header, state *state.IntraBlockState, syscall consensus.SystemCall, firehoseContext *firehose.Context) error {
+	firehoseContext.StartSystemCall(firehose.SystemCallReason("block_reward"))
+	defer firehoseContext.EndSystemCall()
+
 	beneficiaries, kinds, rewards, err := calculateRewards(c, header, syscall)
 	if err != nil {
 		return err
@@ -873,7 +880,7 @@ func (c *AuRa) Finalize(config *chain.Config, header *types.Header, state *state
 	}
 	// check_and_lock_block -> check_epoch_end_signal END
 
-	finalized := buildFinality(c.EpochManager, chain, e, c.cfg.Validators, header, syscall)
+	finalized := buildFinality(c.EpochManager, chain, e, c.cfg.Validators, header, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("aura_build_finality")))
 	c.EpochManager.finalityChecker.print(header.Number.Uint64())
 	epochEndProof, err := isEpochEnd(chain, e, finalized, header)
 	if err != nil {
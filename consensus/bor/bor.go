@@ -472,6 +472,9 @@ func (c *Bor) verifyCascadingFields(chain consensus.ChainHeaderReader, header *t
 	if header.WithdrawalsHash != nil {
 		return consensus.ErrUnexpectedWithdrawals
 	}
+	if header.BlobGasUsed != nil || header.ExcessBlobGas != nil {
+		return consensus.ErrUnexpectedBlobGas
+	}
 
 	if parent.Time+c.config.CalculatePeriod(number) > header.Time {
 		return ErrInvalidTimestamp
@@ -771,14 +774,14 @@ func (c *Bor) Finalize(config *chain.Config, header *types.Header, state *state.
 	if isSprintStart(headerNumber, c.config.CalculateSprint(headerNumber)) {
 		cx := statefull.ChainContext{Chain: chain, Bor: c}
 		// check and commit span
-		if err := c.checkAndCommitSpan(state, header, cx, syscall); err != nil {
+		if err := c.checkAndCommitSpan(state, header, cx, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("bor_commit_span"))); err != nil {
 			log.Error("Error while committing span", "err", err)
 			return nil, types.Receipts{}, err
 		}
 
 		if c.HeimdallClient != nil {
 			// commit states
-			_, err = c.CommitStates(state, header, cx, syscall)
+			_, err = c.CommitStates(state, header, cx, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("bor_commit_state")))
 			if err != nil {
 				log.Error("Error while committing states", "err", err)
 				return nil, types.Receipts{}, err
@@ -824,10 +827,12 @@ func (c *Bor) changeContractCodeIfNeeded(headerNumber uint64, state *state.Intra
 				return fmt.Errorf("failed to decode genesis alloc: %v", err)
 			}
 
+			firehoseContext.StartSystemCall(firehose.SystemCallReason("bor_contract_code_change"))
 			for addr, account := range allocs {
 				log.Trace("change contract code", "address", addr)
 				state.SetCode(addr, account.Code, firehoseContext)
 			}
+			firehoseContext.EndSystemCall()
 		}
 	}
 
@@ -847,7 +852,7 @@ func (c *Bor) FinalizeAndAssemble(chainConfig *chain.Config, header *types.Heade
 		cx := statefull.ChainContext{Chain: chain, Bor: c}
 
 		// check and commit span
-		err := c.checkAndCommitSpan(state, header, cx, syscall)
+		err := c.checkAndCommitSpan(state, header, cx, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("bor_commit_span")))
 		if err != nil {
 			log.Error("Error while committing span", "err", err)
 			return nil, nil, types.Receipts{}, err
@@ -855,7 +860,7 @@ func (c *Bor) FinalizeAndAssemble(chainConfig *chain.Config, header *types.Heade
 
 		if c.HeimdallClient != nil {
 			// commit states
-			_, err = c.CommitStates(state, header, cx, syscall)
+			_, err = c.CommitStates(state, header, cx, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("bor_commit_state")))
 			if err != nil {
 				log.Error("Error while committing states", "err", err)
 				return nil, nil, types.Receipts{}, err
@@ -81,6 +81,9 @@ func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 	if header.WithdrawalsHash != nil {
 		return consensus.ErrUnexpectedWithdrawals
 	}
+	if header.BlobGasUsed != nil || header.ExcessBlobGas != nil {
+		return consensus.ErrUnexpectedBlobGas
+	}
 
 	// If all checks passed, validate any special fields for hard forks
 	if err := misc.VerifyForkHashes(c.chainConfig, header, false); err != nil {
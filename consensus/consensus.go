@@ -74,6 +74,18 @@ type EpochReader interface {
 }
 
 type SystemCall func(contract libcommon.Address, data []byte) ([]byte, error)
+
+// FirehoseSystemCall wraps syscall so that each of its calls is reported to Firehose as a system
+// call with the given reason.
+func FirehoseSystemCall(syscall SystemCall, firehoseContext *firehose.Context, reason firehose.SystemCallReason) SystemCall {
+	return func(contract libcommon.Address, data []byte) ([]byte, error) {
+		firehoseContext.StartSystemCall(reason)
+		defer firehoseContext.EndSystemCall()
+
+		return syscall(contract, data)
+	}
+}
+
 type Call func(contract libcommon.Address, data []byte) ([]byte, error)
 
 // Engine is an algorithm agnostic consensus engine.
@@ -41,4 +41,7 @@ var (
 
 	// ErrUnexpectedWithdrawals is returned if a pre-Shanghai block has withdrawals.
 	ErrUnexpectedWithdrawals = errors.New("unexpected withdrawals")
+
+	// ErrUnexpectedBlobGas is returned if a pre-Cancun block has blob gas fields.
+	ErrUnexpectedBlobGas = errors.New("unexpected blob gas fields")
 )
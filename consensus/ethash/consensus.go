@@ -248,6 +248,9 @@ func VerifyHeaderBasics(chain consensus.ChainHeaderReader, header, parent *types
 	if header.WithdrawalsHash != nil {
 		return consensus.ErrUnexpectedWithdrawals
 	}
+	if header.BlobGasUsed != nil || header.ExcessBlobGas != nil {
+		return consensus.ErrUnexpectedBlobGas
+	}
 
 	// If all checks passed, validate any special fields for hard forks
 	if err := misc.VerifyDAOHeaderExtraData(chain.Config(), header); err != nil {
@@ -648,6 +651,9 @@ func AccumulateRewards(config *chain.Config, header *types.Header, uncles []*typ
 // accumulateRewards retrieves rewards for a block and applies them to the coinbase accounts for miner and uncle miners
 func accumulateRewards(config *chain.Config, state *state.IntraBlockState, header *types.Header, uncles []*types.Header, firehoseContext *firehose.Context) {
 	minerReward, uncleRewards := AccumulateRewards(config, header, uncles)
+
+	firehoseContext.StartSystemCall(firehose.SystemCallReason("block_reward"))
+	defer firehoseContext.EndSystemCall()
 	for i, uncle := range uncles {
 		if i < len(uncleRewards) {
 			state.AddBalance(uncle.Coinbase, &uncleRewards[i], false, firehoseContext, firehose.BalanceChangeReason("reward_mine_uncle"))
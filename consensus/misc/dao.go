@@ -77,6 +77,9 @@ func VerifyDAOHeaderExtraData(config *chain.Config, header *types.Header) error
 // rules, transferring all balances of a set of DAO accounts to a single refund
 // contract.
 func ApplyDAOHardFork(statedb *state.IntraBlockState, firehoseContext *firehose.Context) {
+	firehoseContext.StartSystemCall(firehose.SystemCallReason("dao_hard_fork"))
+	defer firehoseContext.EndSystemCall()
+
 	// Retrieve the contract to refund balances into
 	if !statedb.Exist(params.DAORefundContract) {
 		statedb.CreateAccount(params.DAORefundContract, false, firehoseContext)
@@ -0,0 +1,121 @@
+package misc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+var (
+	minBlobGasPrice            = big.NewInt(params.BlobTxMinBlobGasprice)
+	blobGaspriceUpdateFraction = big.NewInt(params.BlobTxBlobGaspriceUpdateFraction)
+)
+
+// VerifyEip4844Header verifies the blob gas fields of a header, which were added in EIP-4844:
+// - their presence
+// - blob gas used check
+// - excess blob gas check
+func VerifyEip4844Header(config *chain.Config, parent, header *types.Header) error {
+	if header.BlobGasUsed == nil {
+		return errors.New("header is missing blobGasUsed")
+	}
+	if header.ExcessBlobGas == nil {
+		return errors.New("header is missing excessBlobGas")
+	}
+	if *header.BlobGasUsed > params.MaxBlobGasPerBlock {
+		return fmt.Errorf("blob gas used %d exceeds maximum allowance %d", *header.BlobGasUsed, params.MaxBlobGasPerBlock)
+	}
+	if *header.BlobGasUsed%params.BlobTxBlobGasPerBlob != 0 {
+		return fmt.Errorf("blob gas used %d not a multiple of blob gas per blob %d", *header.BlobGasUsed, params.BlobTxBlobGasPerBlob)
+	}
+	// Verify the excessBlobGas is correct based on the parent header.
+	if expected := CalcExcessBlobGas(config, parent); *header.ExcessBlobGas != expected {
+		return fmt.Errorf("invalid excessBlobGas: have %d, want %d", *header.ExcessBlobGas, expected)
+	}
+	return nil
+}
+
+// AddBlobGas adds the blob gas consumed by a transaction to the blob gas used by its block so far.
+// It fails if the block consumes more than MaxBlobGasPerBlock.
+func AddBlobGas(blobGasUsed *uint64, tx types.Transaction) error {
+	blobTx, ok := tx.(*types.BlobTx)
+	if !ok {
+		return nil
+	}
+	if used := *blobGasUsed + blobTx.GetBlobGas(); used > params.MaxBlobGasPerBlock {
+		return fmt.Errorf("blob gas used %d exceeds maximum allowance %d", used, params.MaxBlobGasPerBlock)
+	}
+	*blobGasUsed += blobTx.GetBlobGas()
+	return nil
+}
+
+// VerifyBlobGasUsed checks the blob gas consumed by the transactions of a block against its header.
+// Headers without blob gas fields are only valid for blocks without blob transactions.
+func VerifyBlobGasUsed(header *types.Header, blobGasUsed uint64) error {
+	var inHeader uint64
+	if header.BlobGasUsed != nil {
+		inHeader = *header.BlobGasUsed
+	}
+	if blobGasUsed != inHeader {
+		return fmt.Errorf("blob gas used by execution: %d, in header: %d", blobGasUsed, inHeader)
+	}
+	return nil
+}
+
+// VerifyBlockBlobGas checks the blob gas consumed by all the transactions of a block: against the maximum
+// allowance, and against its header.
+func VerifyBlockBlobGas(header *types.Header, txs types.Transactions) error {
+	var blobGasUsed uint64
+	for _, tx := range txs {
+		if err := AddBlobGas(&blobGasUsed, tx); err != nil {
+			return err
+		}
+	}
+	return VerifyBlobGasUsed(header, blobGasUsed)
+}
+
+// CalcExcessBlobGas calculates the excess blob gas of the header following the parent.
+func CalcExcessBlobGas(config *chain.Config, parent *types.Header) uint64 {
+	// If the current block is the first EIP-4844 block, the parent has no blob gas fields.
+	if !config.IsCancun(parent.Time) || parent.ExcessBlobGas == nil || parent.BlobGasUsed == nil {
+		return 0
+	}
+	if *parent.ExcessBlobGas+*parent.BlobGasUsed < params.TargetBlobGasPerBlock {
+		return 0
+	}
+	return *parent.ExcessBlobGas + *parent.BlobGasUsed - params.TargetBlobGasPerBlock
+}
+
+// GetBlobGasPrice returns the price of a unit of blob gas in a block whose header has the given excess
+// blob gas.
+func GetBlobGasPrice(excessBlobGas uint64) (*uint256.Int, error) {
+	price, overflow := uint256.FromBig(fakeExponential(minBlobGasPrice, new(big.Int).SetUint64(excessBlobGas), blobGaspriceUpdateFraction))
+	if overflow {
+		return nil, fmt.Errorf("blob gas price higher than 2^256-1 for excess blob gas %d", excessBlobGas)
+	}
+	return price, nil
+}
+
+// GetBlobGasUsed returns the blob gas consumed by a transaction carrying numBlobs blobs.
+func GetBlobGasUsed(numBlobs int) uint64 {
+	return uint64(numBlobs) * params.BlobTxBlobGasPerBlob
+}
+
+// fakeExponential approximates factor * e ** (numerator / denominator) using Taylor expansion, as
+// defined in EIP-4844.
+func fakeExponential(factor, numerator, denominator *big.Int) *big.Int {
+	output := new(big.Int)
+	accum := new(big.Int).Mul(factor, denominator)
+	for i := int64(1); accum.Sign() > 0; i++ {
+		output.Add(output, accum)
+		accum.Mul(accum, numerator)
+		accum.Div(accum, new(big.Int).Mul(denominator, big.NewInt(i)))
+	}
+	return output.Div(output, denominator)
+}
@@ -0,0 +1,138 @@
+package misc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+func cancunConfig() *chain.Config {
+	config := copyConfig(params.TestChainConfig)
+	config.CancunTime = big.NewInt(10)
+	return config
+}
+
+func TestCalcExcessBlobGas(t *testing.T) {
+	config := cancunConfig()
+	for i, tc := range []struct {
+		parentTime    uint64
+		excessBlobGas uint64
+		blobGasUsed   uint64
+		want          uint64
+	}{
+		// The parent of the first Cancun block has no excess
+		{9, 0, 0, 0},
+		{10, 0, 0, 0},
+		{10, 0, params.TargetBlobGasPerBlock, 0},
+		{10, 0, params.MaxBlobGasPerBlock, params.MaxBlobGasPerBlock - params.TargetBlobGasPerBlock},
+		{10, params.TargetBlobGasPerBlock, params.TargetBlobGasPerBlock, params.TargetBlobGasPerBlock},
+		{10, params.TargetBlobGasPerBlock, 0, 0},
+		{10, params.TargetBlobGasPerBlock + 1, 0, 1},
+	} {
+		parent := &types.Header{Time: tc.parentTime, ExcessBlobGas: &tc.excessBlobGas, BlobGasUsed: &tc.blobGasUsed}
+		if have := CalcExcessBlobGas(config, parent); have != tc.want {
+			t.Errorf("test %d: excess blob gas mismatch: have %d, want %d", i, have, tc.want)
+		}
+	}
+}
+
+func TestGetBlobGasPrice(t *testing.T) {
+	for i, tc := range []struct {
+		excessBlobGas uint64
+		want          uint64
+	}{
+		{0, 1},
+		{2314057, 1},
+		{2314058, 2},
+		{10 * 1024 * 1024, 23},
+	} {
+		price, err := GetBlobGasPrice(tc.excessBlobGas)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if price.Uint64() != tc.want {
+			t.Errorf("test %d: blob gas price mismatch: have %d, want %d", i, price.Uint64(), tc.want)
+		}
+	}
+}
+
+func TestFakeExponential(t *testing.T) {
+	for i, tc := range []struct {
+		factor, numerator, denominator int64
+		want                           int64
+	}{
+		{1, 0, 1, 1},
+		{38493, 0, 1000, 38493},
+		{0, 1234, 2345, 0},
+		{1, 2, 1, 6}, // approximate 7.389
+		{1, 4, 2, 6},
+		{1, 3, 1, 16}, // approximate 20.09
+		{1, 6, 2, 18},
+		{1, 4, 1, 49}, // approximate 54.60
+		{1, 8, 2, 50},
+		{10, 8, 2, 542}, // approximate 540.598
+		{11, 8, 2, 596}, // approximate 600.58
+		{1, 5, 1, 136},  // approximate 148.4
+		{1, 5, 2, 11},   // approximate 12.18
+		{2, 5, 2, 23},   // approximate 24.36
+	} {
+		have := fakeExponential(big.NewInt(tc.factor), big.NewInt(tc.numerator), big.NewInt(tc.denominator))
+		if have.Int64() != tc.want {
+			t.Errorf("test %d: fake exponential mismatch: have %v want %v", i, have, tc.want)
+		}
+	}
+}
+
+func TestVerifyEip4844Header(t *testing.T) {
+	config := cancunConfig()
+	excess, used := uint64(params.TargetBlobGasPerBlock), uint64(params.BlobTxBlobGasPerBlob*2)
+	parent := &types.Header{Time: 10, ExcessBlobGas: &excess, BlobGasUsed: &used}
+
+	want, zero, tooMuch, notMultiple := CalcExcessBlobGas(config, parent), uint64(0), uint64(params.MaxBlobGasPerBlock+params.BlobTxBlobGasPerBlob), uint64(1)
+	if err := VerifyEip4844Header(config, parent, &types.Header{Time: 12, ExcessBlobGas: &want, BlobGasUsed: &used}); err != nil {
+		t.Errorf("valid header: %v", err)
+	}
+	for i, header := range []*types.Header{
+		{Time: 12, BlobGasUsed: &used},
+		{Time: 12, ExcessBlobGas: &want},
+		{Time: 12, ExcessBlobGas: &zero, BlobGasUsed: &used},
+		{Time: 12, ExcessBlobGas: &want, BlobGasUsed: &tooMuch},
+		{Time: 12, ExcessBlobGas: &want, BlobGasUsed: &notMultiple},
+	} {
+		if err := VerifyEip4844Header(config, parent, header); err == nil {
+			t.Errorf("test %d: invalid header accepted", i)
+		}
+	}
+}
+
+func TestVerifyBlockBlobGas(t *testing.T) {
+	blobTx := func(blobs int) types.Transaction {
+		return &types.BlobTx{BlobVersionedHashes: make([]libcommon.Hash, blobs)}
+	}
+	header := func(blobGasUsed uint64) *types.Header { return &types.Header{BlobGasUsed: &blobGasUsed} }
+	perBlob := uint64(params.BlobTxBlobGasPerBlob)
+	maxBlobs := int(params.MaxBlobGasPerBlock / params.BlobTxBlobGasPerBlob)
+
+	for i, tc := range []struct {
+		header *types.Header
+		txs    types.Transactions
+		valid  bool
+	}{
+		{&types.Header{}, types.Transactions{&types.LegacyTx{}}, true},
+		{&types.Header{}, types.Transactions{blobTx(1)}, false},
+		{header(3 * perBlob), types.Transactions{blobTx(1), &types.LegacyTx{}, blobTx(2)}, true},
+		{header(2 * perBlob), types.Transactions{blobTx(1), blobTx(2)}, false},
+		{header(4 * perBlob), types.Transactions{blobTx(1), blobTx(2)}, false},
+		{header(params.MaxBlobGasPerBlock), types.Transactions{blobTx(maxBlobs)}, true},
+		{header(params.MaxBlobGasPerBlock + perBlob), types.Transactions{blobTx(maxBlobs), blobTx(1)}, false},
+	} {
+		if err := VerifyBlockBlobGas(tc.header, tc.txs); (err == nil) != tc.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tc.valid)
+		}
+	}
+}
@@ -385,6 +385,9 @@ func (p *Parlia) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 	if header.WithdrawalsHash != nil {
 		return consensus.ErrUnexpectedWithdrawals
 	}
+	if header.BlobGasUsed != nil || header.ExcessBlobGas != nil {
+		return consensus.ErrUnexpectedBlobGas
+	}
 
 	// If all checks passed, validate any special fields for hard forks
 	if err := misc.VerifyForkHashes(chain.Config(), header, false); err != nil {
@@ -1038,6 +1041,8 @@ func (p *Parlia) getCurrentValidators(header *types.Header, ibs *state.IntraBloc
 		log.Error("Unable to pack tx for getValidators", "err", err)
 		return nil, err
 	}
+	firehoseContext.StartSystemCall(firehose.SystemCallReason("parlia_get_validators"))
+	defer firehoseContext.EndSystemCall()
 	// call
 	msgData := hexutil.Bytes(data)
 	_, returnData, err := p.systemCall(header.Coinbase, systemcontracts.ValidatorContract, msgData[:], ibs, header, u256.Num0, firehoseContext)
@@ -1068,10 +1073,12 @@ func (p *Parlia) distributeIncoming(val libcommon.Address, state *state.IntraBlo
 		return txs, systemTxs, receipts, nil
 	}
 
+	firehoseContext.StartSystemCall(firehose.SystemCallReason("parlia_block_reward"))
 	// CS TODO: confirm the addBalance reason
 	state.SetBalance(consensus.SystemAddress, u256.Num0, firehoseContext, firehose.IgnoredBalanceChangeReason)
 	// CS TODO: confirm the addBalance reason
 	state.AddBalance(coinbase, balance, false, firehoseContext, firehose.BalanceChangeReason("reward_mine_block"))
+	firehoseContext.EndSystemCall()
 
 	doDistributeSysReward := state.GetBalance(systemcontracts.SystemRewardContract).Cmp(maxSystemBalance) < 0
 	if doDistributeSysReward {
@@ -1118,7 +1125,8 @@ func (p *Parlia) slash(spoiledVal libcommon.Address, state *state.IntraBlockStat
 		return nil, nil, nil, err
 	}
 	// apply message
-	return p.applyTransaction(header.Coinbase, systemcontracts.SlashContract, u256.Num0, data, state, header, txIndex, systemTxs, usedGas, mining, firehoseContext)
+	return p.applyTransaction(header.Coinbase, systemcontracts.SlashContract, u256.Num0, data, state, header, txIndex, systemTxs, usedGas, mining,
+		firehoseContext, firehose.SystemCallReason("parlia_slash"))
 }
 
 // init contract
@@ -1148,7 +1156,8 @@ func (p *Parlia) initContract(state *state.IntraBlockState, header *types.Header
 		log.Info("[parlia] init contract", "block hash", header.Hash(), "contract", c)
 		var tx types.Transaction
 		var receipt *types.Receipt
-		if systemTxs, tx, receipt, err = p.applyTransaction(header.Coinbase, c, u256.Num0, data, state, header, len(txs), systemTxs, usedGas, mining, firehoseContext); err != nil {
+		if systemTxs, tx, receipt, err = p.applyTransaction(header.Coinbase, c, u256.Num0, data, state, header, len(txs), systemTxs, usedGas, mining,
+			firehoseContext, firehose.SystemCallReason("parlia_init_contract")); err != nil {
 			return nil, nil, nil, err
 		}
 		txs = append(txs, tx)
@@ -1162,7 +1171,7 @@ func (p *Parlia) distributeToSystem(amount *uint256.Int, state *state.IntraBlock
 	usedGas *uint64, mining bool, firehoseContext *firehose.Context,
 ) (types.Transactions, types.Transaction, *types.Receipt, error) {
 	return p.applyTransaction(header.Coinbase, systemcontracts.SystemRewardContract, amount, nil, state, header,
-		txIndex, systemTxs, usedGas, mining, firehoseContext)
+		txIndex, systemTxs, usedGas, mining, firehoseContext, firehose.SystemCallReason("parlia_distribute_to_system"))
 }
 
 // slash spoiled validators
@@ -1182,11 +1191,12 @@ func (p *Parlia) distributeToValidator(amount *uint256.Int, validator libcommon.
 		return nil, nil, nil, err
 	}
 	// apply message
-	return p.applyTransaction(header.Coinbase, systemcontracts.ValidatorContract, amount, data, state, header, txIndex, systemTxs, usedGas, mining, firehoseContext)
+	return p.applyTransaction(header.Coinbase, systemcontracts.ValidatorContract, amount, data, state, header, txIndex, systemTxs, usedGas, mining,
+		firehoseContext, firehose.SystemCallReason("parlia_distribute_to_validator"))
 }
 
 func (p *Parlia) applyTransaction(from libcommon.Address, to libcommon.Address, value *uint256.Int, data []byte, ibs *state.IntraBlockState, header *types.Header,
-	txIndex int, systemTxs types.Transactions, usedGas *uint64, mining bool, firehoseContext *firehose.Context,
+	txIndex int, systemTxs types.Transactions, usedGas *uint64, mining bool, firehoseContext *firehose.Context, reason firehose.SystemCallReason,
 ) (types.Transactions, types.Transaction, *types.Receipt, error) {
 	nonce := ibs.GetNonce(from)
 	expectedTx := types.Transaction(types.NewTransaction(nonce, to, value, math.MaxUint64/2, u256.Num0, data))
@@ -1232,6 +1242,8 @@ func (p *Parlia) applyTransaction(from libcommon.Address, to libcommon.Address,
 		// move to next
 		systemTxs = systemTxs[1:]
 	}
+	firehoseContext.StartSystemTransaction(reason, expectedTx.Hash())
+	defer firehoseContext.EndSystemCall()
 	ibs.Prepare(expectedTx.Hash(), libcommon.Hash{}, txIndex)
 	gasUsed, _, err := p.systemCall(from, to, data, ibs, header, value, firehoseContext)
 	if err != nil {
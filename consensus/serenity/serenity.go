@@ -135,14 +135,16 @@ func (s *Serenity) Finalize(config *chain.Config, header *types.Header, state *s
 		if err := auraEngine.ApplyRewards(header, state, syscall, firehoseContext); err != nil {
 			return nil, nil, err
 		}
-		if err := auraEngine.ExecuteSystemWithdrawals(withdrawals, syscall); err != nil {
+		if err := auraEngine.ExecuteSystemWithdrawals(withdrawals, consensus.FirehoseSystemCall(syscall, firehoseContext, firehose.SystemCallReason("withdrawals"))); err != nil {
 			return nil, nil, err
 		}
-	} else {
+	} else if len(withdrawals) > 0 {
+		firehoseContext.StartSystemCall(firehose.SystemCallReason("withdrawals"))
 		for _, w := range withdrawals {
 			amountInWei := new(uint256.Int).Mul(uint256.NewInt(w.Amount), uint256.NewInt(params.GWei))
 			state.AddBalance(w.Address, amountInWei, false, firehoseContext, firehose.BalanceChangeReason("withdrawal"))
 		}
+		firehoseContext.EndSystemCall()
 	}
 	return txs, r, nil
 }
@@ -226,6 +228,15 @@ func (s *Serenity) verifyHeader(chain consensus.ChainHeaderReader, header, paren
 	if !shanghai && header.WithdrawalsHash != nil {
 		return consensus.ErrUnexpectedWithdrawals
 	}
+
+	// Verify existence / non-existence of the blob gas fields
+	if chain.Config().IsCancun(header.Time) {
+		if err := misc.VerifyEip4844Header(chain.Config(), parent, header); err != nil {
+			return err
+		}
+	} else if header.BlobGasUsed != nil || header.ExcessBlobGas != nil {
+		return consensus.ErrUnexpectedBlobGas
+	}
 	return nil
 }
 
@@ -0,0 +1,96 @@
+package core_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+)
+
+// blobTxMock returns a mock of a chain with Cancun enabled from genesis, whose single block carries a blob
+// transaction with the given versioned hashes, and a function signing other blob transactions in its place.
+func blobTxMock(t *testing.T) (*stages.MockSentry, *core.ChainPack, func(hashes []libcommon.Hash) types.Transaction) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	config := *params.TestChainConfig
+	config.LondonBlock, config.ShanghaiTime, config.CancunTime = big.NewInt(0), big.NewInt(0), big.NewInt(0)
+	gspec := &core.Genesis{
+		Config: &config,
+		Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+	}
+
+	m := stages.MockWithGenesis(t, gspec, key, false)
+	signer := types.LatestSigner(m.ChainConfig)
+	recipient := libcommon.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	chainID, _ := uint256.FromBig(m.ChainConfig.ChainID)
+	sign := func(hashes []libcommon.Hash) types.Transaction {
+		unsigned := types.NewBlobTx(*chainID, 0, recipient, uint256.NewInt(1), params.TxGas, uint256.NewInt(params.GWei), uint256.NewInt(100*params.GWei), uint256.NewInt(params.GWei), hashes, nil)
+		tx, err := types.SignTx(unsigned, *signer, key)
+		require.NoError(t, err)
+		return tx
+	}
+
+	hashes := []libcommon.Hash{types.KZGCommitment{0x01}.VersionedHash(), types.KZGCommitment{0x02}.VersionedHash()}
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 1, func(i int, b *core.BlockGen) {
+		b.AddTx(sign(hashes))
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	return m, chain, sign
+}
+
+// executeBlobBlock executes the block on top of the genesis of the mock
+func executeBlobBlock(t *testing.T, m *stages.MockSentry, block *types.Block) error {
+	tx, err := m.DB.BeginRo(m.Ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	getHeader := func(hash libcommon.Hash, number uint64) *types.Header { return rawdb.ReadHeader(tx, hash, number) }
+	_, err = core.ExecuteBlockEphemerally(m.ChainConfig, &vm.Config{}, core.GetHashFn(block.Header(), getHeader), m.Engine, block,
+		state.NewPlainStateReader(tx), state.NewNoopWriter(), nil, nil, nil)
+	return err
+}
+
+// Tests that blocks fail to execute when the blob gas used by their transactions doesn't match their header
+func TestBlobGasUsedMismatch(t *testing.T) {
+	m, chain, _ := blobTxMock(t)
+	require.Equal(t, uint64(2*params.BlobTxBlobGasPerBlob), *chain.TopBlock.Header().BlobGasUsed)
+	require.NoError(t, executeBlobBlock(t, m, chain.TopBlock))
+
+	header := types.CopyHeader(chain.Headers[0])
+	*header.BlobGasUsed = params.BlobTxBlobGasPerBlob
+	require.ErrorContains(t, executeBlobBlock(t, m, chain.TopBlock.WithSeal(header)), "blob gas used by execution: 262144, in header: 131072")
+}
+
+// Tests that blocks fail to execute when they carry blob transactions without blob hashes, or with blob hashes
+// of an unsupported version (EIP-4844)
+func TestBlobTxHashesValidity(t *testing.T) {
+	m, chain, sign := blobTxMock(t)
+	withTx := func(tx types.Transaction) *types.Block {
+		header := types.CopyHeader(chain.TopBlock.Header())
+		return types.NewBlockFromStorage(chain.TopBlock.Hash(), header, []types.Transaction{tx}, nil, nil)
+	}
+
+	valid := []libcommon.Hash{types.KZGCommitment{0x01}.VersionedHash(), types.KZGCommitment{0x02}.VersionedHash()}
+	require.NoError(t, executeBlobBlock(t, m, withTx(sign(valid))))
+
+	err := executeBlobBlock(t, m, withTx(sign(nil)))
+	require.ErrorIs(t, err, core.ErrMissingBlobHashes)
+	err = executeBlobBlock(t, m, withTx(sign([]libcommon.Hash{})))
+	require.ErrorIs(t, err, core.ErrMissingBlobHashes)
+
+	wrongVersion := []libcommon.Hash{valid[0], valid[1]}
+	wrongVersion[1][0] = 0x02
+	err = executeBlobBlock(t, m, withTx(sign(wrongVersion)))
+	require.ErrorIs(t, err, core.ErrBlobHashVersion)
+	require.ErrorContains(t, err, "blob 1 has version 2")
+}
@@ -345,6 +345,7 @@ func ExecuteBlockEphemerally(
 	usedGas := new(uint64)
 	gp := new(GasPool)
 	gp.AddGas(block.GasLimit())
+	var blobGasUsed uint64
 
 	var (
 		rejectedTxs     []*RejectedTx
@@ -397,7 +398,11 @@ func ExecuteBlockEphemerally(
 			writeTrace = true
 		}
 
-		receipt, _, err := ApplyTransaction(chainConfig, blockHashFunc, engine, nil, gp, ibs, noop, header, tx, usedGas, *vmConfig, firehoseContext)
+		var receipt *types.Receipt
+		err := misc.AddBlobGas(&blobGasUsed, tx)
+		if err == nil {
+			receipt, _, err = ApplyTransaction(chainConfig, blockHashFunc, engine, nil, gp, ibs, noop, header, tx, usedGas, *vmConfig, firehoseContext)
+		}
 		if writeTrace {
 			if ftracer, ok := vmConfig.Tracer.(vm.FlushableTracer); ok {
 				ftracer.Flush(tx)
@@ -449,6 +454,16 @@ func ExecuteBlockEphemerally(
 		return nil, err
 	}
 
+	if !vmConfig.StatelessExec {
+		if err := misc.VerifyBlobGasUsed(header, blobGasUsed); err != nil {
+			if firehoseContext.Enabled() {
+				firehoseContext.CancelBlock(block, err)
+			}
+
+			return nil, err
+		}
+	}
+
 	var bloom types.Bloom
 	if !vmConfig.NoReceipts {
 		bloom = types.CreateBloom(receipts)
@@ -483,26 +498,7 @@ func ExecuteBlockEphemerally(
 	}
 
 	if firehoseContext.Enabled() {
-		// Calculate the total difficulty of the block
-		ptd := chainReader.GetTd(block.ParentHash(), block.NumberU64()-1)
-		difficulty := block.Difficulty()
-		if difficulty == nil {
-			difficulty = big.NewInt(0)
-		}
-
-		td := ptd
-		if ptd != nil {
-			td = new(big.Int).Add(difficulty, ptd)
-		}
-
-		finalizedBlock := chainReader.CurrentFinalizedHeader()
-		if finalizedBlock != nil && firehose.SyncingBehindFinalized() {
-			// if beaconFinalizedBlockNum is in the future, the 'finalizedBlock' will not progress until we reach it.
-			// we don't want to advertise a super old finalizedBlock when reprocessing.
-			finalizedBlock = nil
-		}
-
-		firehoseContext.EndBlock(block, finalizedBlock, td)
+		FirehoseEndBlock(firehoseContext, block, chainReader)
 	}
 
 	blockLogs := ibs.Logs()
@@ -732,6 +728,35 @@ func ExecuteBlockEphemerallyBor(
 	return execRs, nil
 }
 
+// FirehoseEndBlock ends the block recorded by the Firehose context with its total difficulty and
+// the finalized block known to the node.
+func FirehoseEndBlock(firehoseContext *firehose.Context, block *types.Block, chainReader consensus.ChainHeaderReader) {
+	// Calculate the total difficulty of the block
+	ptd := chainReader.GetTd(block.ParentHash(), block.NumberU64()-1)
+	difficulty := block.Difficulty()
+	if difficulty == nil {
+		difficulty = big.NewInt(0)
+	}
+
+	td := ptd
+	if ptd != nil {
+		td = new(big.Int).Add(difficulty, ptd)
+	}
+
+	_, finalizedBlock := firehoseContext.Forkchoice()
+	if finalizedBlock == nil {
+		// No forkchoice update was received since the node started, use the one persisted by the last one
+		finalizedBlock = chainReader.CurrentFinalizedHeader()
+	}
+	if finalizedBlock != nil && firehose.SyncingBehindFinalized() {
+		// if beaconFinalizedBlockNum is in the future, the 'finalizedBlock' will not progress until we reach it.
+		// we don't want to advertise a super old finalizedBlock when reprocessing.
+		finalizedBlock = nil
+	}
+
+	firehoseContext.EndBlock(block, finalizedBlock, td)
+}
+
 func rlpHash(x interface{}) (h libcommon.Hash) {
 	hw := sha3.NewLegacyKeccak256()
 	rlp.Encode(hw, x) //nolint:errcheck
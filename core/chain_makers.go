@@ -122,6 +122,11 @@ func (b *BlockGen) AddTxWithChain(getHeader func(hash libcommon.Hash, number uin
 	if err != nil {
 		panic(err)
 	}
+	if b.header.BlobGasUsed != nil {
+		if err := misc.AddBlobGas(b.header.BlobGasUsed, tx); err != nil {
+			panic(err)
+		}
+	}
 	b.txs = append(b.txs, tx)
 	b.receipts = append(b.receipts, receipt)
 }
@@ -474,6 +479,11 @@ func MakeEmptyHeader(parent *types.Header, chainConfig *chain.Config, timestamp
 	} else {
 		header.GasLimit = parentGasLimit
 	}
+	if chainConfig.IsCancun(header.Time) {
+		excessBlobGas := misc.CalcExcessBlobGas(chainConfig, parent)
+		header.ExcessBlobGas = &excessBlobGas
+		header.BlobGasUsed = new(uint64)
+	}
 
 	return header
 }
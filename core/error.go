@@ -92,6 +92,17 @@ var (
 	// the base fee of the block.
 	ErrFeeCapTooLow = errors.New("fee cap less than block base fee")
 
+	// ErrBlobFeeCapTooLow is returned if the blob fee cap of an EIP-4844 transaction
+	// is less than the blob gas price of the block.
+	ErrBlobFeeCapTooLow = errors.New("max fee per blob gas less than block blob gas price")
+
+	// ErrMissingBlobHashes is returned if an EIP-4844 transaction has no blob hashes.
+	ErrMissingBlobHashes = types.ErrMissingBlobHashes
+
+	// ErrBlobHashVersion is returned if a blob hash of an EIP-4844 transaction doesn't
+	// start with the version byte of KZG commitments.
+	ErrBlobHashVersion = types.ErrBlobHashVersion
+
 	// ErrSenderNoEOA is returned if the sender of a transaction is a contract.
 	// See EIP-3607: Reject transactions from senders with deployed code.
 	ErrSenderNoEOA = errors.New("sender not an eoa")
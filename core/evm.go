@@ -72,6 +72,8 @@ func NewEVMBlockContext(header *types.Header, blockHashFunc func(n uint64) libco
 		BaseFee:     &baseFee,
 		GasLimit:    header.GasLimit,
 		PrevRanDao:  prevRandDao,
+
+		ExcessBlobGas: header.ExcessBlobGas,
 	}
 }
 
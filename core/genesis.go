@@ -492,6 +492,57 @@ func (g *Genesis) MustWrite(tx kv.RwTx, tmpDir string, history bool) (*types.Blo
 	return b, s
 }
 
+// FirehoseGenesisRecorder returns the function recording the allocations of the genesis as the
+// changes of the Firehose genesis block.
+func FirehoseGenesisRecorder(genesis *Genesis) func(ctx *firehose.Context) {
+	return func(ctx *firehose.Context) {
+		sortedAddrs := make([]libcommon.Address, len(genesis.Alloc))
+		i := 0
+		for addr := range genesis.Alloc {
+			sortedAddrs[i] = addr
+			i++
+		}
+
+		sort.Slice(sortedAddrs, func(i, j int) bool {
+			return bytes.Compare(sortedAddrs[i][:], sortedAddrs[j][:]) <= -1
+		})
+
+		for _, addr := range sortedAddrs {
+			account := genesis.Alloc[addr]
+
+			ctx.RecordNewAccount(addr)
+
+			acountBalance, overflow := uint256.FromBig(account.Balance)
+			if overflow {
+				panic("genesis account balance overflow on big int conversion")
+			}
+			ctx.RecordBalanceChange(addr, u256.Num0, acountBalance, firehose.BalanceChangeReason("genesis_balance"))
+			if len(account.Code) > 0 {
+				ctx.RecordCodeChange(addr, nil, nil, crypto.Keccak256Hash(account.Code), account.Code)
+			}
+
+			if account.Nonce > 0 {
+				ctx.RecordNonceChange(addr, 0, account.Nonce)
+			}
+
+			sortedKeys := make([]libcommon.Hash, 0, len(account.Storage))
+			for key := range account.Storage {
+				sortedKeys = append(sortedKeys, key)
+			}
+
+			sort.Slice(sortedKeys, func(i, j int) bool {
+				return bytes.Compare(sortedKeys[i][:], sortedKeys[j][:]) <= -1
+			})
+
+			for _, key := range sortedKeys {
+				key, value := key, account.Storage[key]
+				val := uint256.NewInt(0).SetBytes(value.Bytes())
+				ctx.RecordStorageChange(addr, &key, u256.Num0, val)
+			}
+		}
+	}
+}
+
 // Write writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Write(tx kv.RwTx, tmpDir string) (*types.Block, *state.IntraBlockState, error) {
@@ -551,42 +602,7 @@ func (g *Genesis) Write(tx kv.RwTx, tmpDir string) (*types.Block, *state.IntraBl
 			panic(fmt.Errorf("invalid Firehose genesis block and actual chain's stored genesis block, the actual genesis block's hash field extracted from Geth's database does not fit with hash of genesis block generated from Firehose determined genesis config, you might need to provide the correct 'genesis.json' file via --firehose-genesis-file"))
 		}
 
-		firehose.MaybeSyncContext().RecordGenesisBlock(block, func(ctx *firehose.Context) {
-			sortedAddrs := make([]libcommon.Address, len(genesis.Alloc))
-			i := 0
-			for addr := range genesis.Alloc {
-				sortedAddrs[i] = addr
-				i++
-			}
-
-			sort.Slice(sortedAddrs, func(i, j int) bool {
-				return bytes.Compare(sortedAddrs[i][:], sortedAddrs[j][:]) <= -1
-			})
-
-			for _, addr := range sortedAddrs {
-				account := genesis.Alloc[addr]
-
-				ctx.RecordNewAccount(addr)
-
-				acountBalance, overflow := uint256.FromBig(account.Balance)
-				if overflow {
-					panic("genesis account balance overflow on big int conversion")
-				}
-				ctx.RecordBalanceChange(addr, u256.Num0, acountBalance, firehose.BalanceChangeReason("genesis_balance"))
-				if len(account.Code) > 0 {
-					ctx.RecordCodeChange(addr, nil, nil, crypto.Keccak256Hash(account.Code), account.Code)
-				}
-
-				if account.Nonce > 0 {
-					ctx.RecordNonceChange(addr, 0, account.Nonce)
-				}
-
-				for key, value := range account.Storage {
-					val := uint256.NewInt(0).SetBytes(value.Bytes())
-					ctx.RecordStorageChange(addr, &key, u256.Num0, val)
-				}
-			}
-		})
+		firehose.MaybeSyncContext().RecordGenesisBlock(block, FirehoseGenesisRecorder(genesis))
 	}
 
 	// We support ethash/serenity for issuance (for now)
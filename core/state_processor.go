@@ -21,6 +21,7 @@ import (
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
@@ -75,29 +76,43 @@ func applyTransaction(config *chain.Config, engine consensus.EngineReader, gp *G
 	// based on the eip phase, we're passing whether the root touch-delete accounts.
 	var receipt *types.Receipt
 	if !cfg.NoReceipts {
-		// by the tx.
-		receipt = &types.Receipt{Type: tx.Type(), CumulativeGasUsed: *usedGas}
-		if result.Failed() {
-			receipt.Status = types.ReceiptStatusFailed
-		} else {
-			receipt.Status = types.ReceiptStatusSuccessful
-		}
-		receipt.TxHash = tx.Hash()
-		receipt.GasUsed = result.UsedGas
-		// if the transaction created a contract, store the creation address in the receipt.
-		if msg.To() == nil {
-			receipt.ContractAddress = crypto.CreateAddress(evm.TxContext().Origin, tx.GetNonce())
-		}
-		// Set the receipt logs and create a bloom for filtering
-		receipt.Logs = ibs.GetLogs(tx.Hash())
-		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
-		receipt.BlockNumber = header.Number
-		receipt.TransactionIndex = uint(ibs.TxIndex())
+		receipt = MakeReceipt(header, tx, msg.From(), result, *usedGas, ibs.GetLogs(tx.Hash()), ibs.TxIndex())
 	}
 
 	return receipt, result.ReturnData, err
 }
 
+// MakeReceipt returns the receipt of a transaction applied with the given result, cumulativeGasUsed including
+// the gas it used. The serial and the parallel executors both build their receipts with it. Pre-Byzantium
+// receipts carry the post state instead of the status, but the intermediate state roots aren't computed
+// during execution so their post state is left empty.
+func MakeReceipt(header *types.Header, tx types.Transaction, from libcommon.Address, result *ExecutionResult, cumulativeGasUsed uint64, logs types.Logs, txIndex int) *types.Receipt {
+	receipt := &types.Receipt{Type: tx.Type(), CumulativeGasUsed: cumulativeGasUsed}
+	if result.Failed() {
+		receipt.Status = types.ReceiptStatusFailed
+	} else {
+		receipt.Status = types.ReceiptStatusSuccessful
+	}
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = result.UsedGas
+	if blobTx, ok := tx.(*types.BlobTx); ok && header.ExcessBlobGas != nil {
+		// The price was already checked against the header when buying gas
+		blobGasPrice, _ := misc.GetBlobGasPrice(*header.ExcessBlobGas)
+		receipt.BlobGasUsed = blobTx.GetBlobGas()
+		receipt.BlobGasPrice = blobGasPrice.ToBig()
+	}
+	// if the transaction created a contract, store the creation address in the receipt.
+	if tx.GetTo() == nil {
+		receipt.ContractAddress = crypto.CreateAddress(from, tx.GetNonce())
+	}
+	// Set the receipt logs and create a bloom for filtering
+	receipt.Logs = logs
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	receipt.BlockNumber = header.Number
+	receipt.TransactionIndex = uint(txIndex)
+	return receipt
+}
+
 // ApplyTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. It returns the receipt
 // for the transaction, gas used and an error if the transaction failed,
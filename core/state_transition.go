@@ -29,6 +29,7 @@ import (
 	cmath "github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/common/u256"
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
@@ -95,6 +96,10 @@ type Message interface {
 	Data() []byte
 	AccessList() types2.AccessList
 
+	// BlobGas is the gas consumed by the blobs of EIP-4844 transactions, paid at most MaxFeePerBlobGas
+	BlobGas() uint64
+	MaxFeePerBlobGas() *uint256.Int
+
 	IsFree() bool
 }
 
@@ -216,6 +221,27 @@ func (st *StateTransition) buyGas(gasBailout bool) error {
 			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
 		}
 	}
+	// The blob gas of EIP-4844 transactions is bought at the blob gas price of the block and burnt
+	if blobGas := st.msg.BlobGas(); blobGas > 0 && st.evm.Context().ExcessBlobGas != nil {
+		blobGasPrice, err := misc.GetBlobGasPrice(*st.evm.Context().ExcessBlobGas)
+		if err != nil {
+			return err
+		}
+		blobFee, overflow := new(uint256.Int).MulOverflow(blobGasPrice, uint256.NewInt(blobGas))
+		if overflow {
+			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+		}
+		if mgval, overflow = new(uint256.Int).AddOverflow(mgval, blobFee); overflow {
+			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+		}
+		maxBlobFee, overflow := new(uint256.Int).MulOverflow(st.msg.MaxFeePerBlobGas(), uint256.NewInt(blobGas))
+		if overflow {
+			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+		}
+		if balanceCheck, overflow = new(uint256.Int).AddOverflow(balanceCheck, maxBlobFee); overflow {
+			return fmt.Errorf("%w: address %v", ErrInsufficientFunds, st.msg.From().Hex())
+		}
+	}
 	var subBalance = false
 	if have, want := st.state.GetBalance(st.msg.From()), balanceCheck; have.Cmp(want) < 0 {
 		if !gasBailout {
@@ -285,6 +311,17 @@ func (st *StateTransition) preCheck(gasBailout bool) error {
 			}
 		}
 	}
+	// Make sure the transaction blob fee cap is greater than the block's blob gas price.
+	if st.msg.BlobGas() > 0 && st.evm.ChainRules().IsCancun && st.evm.Context().ExcessBlobGas != nil {
+		blobGasPrice, err := misc.GetBlobGasPrice(*st.evm.Context().ExcessBlobGas)
+		if err != nil {
+			return err
+		}
+		if st.msg.MaxFeePerBlobGas().Lt(blobGasPrice) {
+			return fmt.Errorf("%w: address %v, maxFeePerBlobGas: %s blobGasPrice: %s", ErrBlobFeeCapTooLow,
+				st.msg.From().Hex(), st.msg.MaxFeePerBlobGas(), blobGasPrice)
+		}
+	}
 	return st.buyGas(gasBailout)
 }
 
@@ -0,0 +1,650 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+
+	"github.com/ledgerwatch/erigon/common/u256"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// BlobTx is an EIP-4844 transaction: a dynamic fee transaction paying for data blobs carried next to it.
+// Blocks only contain the versioned hashes of the blobs, the blobs themselves travel in the network form
+// of the transaction, see BlobTxWrapper.
+type BlobTx struct {
+	DynamicFeeTransaction
+	MaxFeePerBlobGas    *uint256.Int
+	BlobVersionedHashes []libcommon.Hash
+}
+
+func (tx BlobTx) GetBlobHashes() []libcommon.Hash { return tx.BlobVersionedHashes }
+
+// GetBlobGas returns the blob gas consumed by the blobs of the transaction.
+func (tx BlobTx) GetBlobGas() uint64 {
+	return uint64(len(tx.BlobVersionedHashes)) * params.BlobTxBlobGasPerBlob
+}
+
+func (tx BlobTx) Cost() *uint256.Int {
+	total := tx.DynamicFeeTransaction.Cost()
+	blobFee := new(uint256.Int).SetUint64(tx.GetBlobGas())
+	blobFee.Mul(blobFee, tx.MaxFeePerBlobGas)
+	return total.Add(total, blobFee)
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx BlobTx) copy() *BlobTx {
+	cpy := &BlobTx{
+		DynamicFeeTransaction: *tx.DynamicFeeTransaction.copy(),
+		MaxFeePerBlobGas:      new(uint256.Int),
+		BlobVersionedHashes:   make([]libcommon.Hash, len(tx.BlobVersionedHashes)),
+	}
+	copy(cpy.BlobVersionedHashes, tx.BlobVersionedHashes)
+	if tx.MaxFeePerBlobGas != nil {
+		cpy.MaxFeePerBlobGas.Set(tx.MaxFeePerBlobGas)
+	}
+	return cpy
+}
+
+func (tx BlobTx) EncodingSize() int {
+	payloadSize, _, _, _, _ := tx.payloadSize()
+	envelopeSize := payloadSize
+	// Add envelope size and type size
+	if payloadSize >= 56 {
+		envelopeSize += (bits.Len(uint(payloadSize)) + 7) / 8
+	}
+	envelopeSize += 2
+	return envelopeSize
+}
+
+func (tx BlobTx) payloadSize() (payloadSize int, nonceLen, gasLen, accessListLen, blobHashesLen int) {
+	payloadSize, nonceLen, gasLen, accessListLen = tx.DynamicFeeTransaction.payloadSize()
+	// size of MaxFeePerBlobGas
+	payloadSize++
+	payloadSize += rlp.Uint256LenExcludingHead(tx.MaxFeePerBlobGas)
+	// size of BlobVersionedHashes
+	payloadSize++
+	blobHashesLen = blobHashesSize(tx.BlobVersionedHashes)
+	if blobHashesLen >= 56 {
+		payloadSize += (bits.Len(uint(blobHashesLen)) + 7) / 8
+	}
+	payloadSize += blobHashesLen
+	return payloadSize, nonceLen, gasLen, accessListLen, blobHashesLen
+}
+
+func blobHashesSize(hashes []libcommon.Hash) int {
+	return 33 * len(hashes)
+}
+
+func encodeBlobHashes(hashes []libcommon.Hash, w io.Writer, b []byte) error {
+	b[0] = 128 + 32
+	for _, hash := range hashes {
+		if _, err := w.Write(b[:1]); err != nil {
+			return err
+		}
+		if _, err := w.Write(hash[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *BlobTx) WithSignature(signer Signer, sig []byte) (Transaction, error) {
+	cpy := tx.copy()
+	r, s, v, err := signer.SignatureValues(tx, sig)
+	if err != nil {
+		return nil, err
+	}
+	cpy.R.Set(r)
+	cpy.S.Set(s)
+	cpy.V.Set(v)
+	cpy.ChainID = signer.ChainID()
+	return cpy, nil
+}
+
+func (tx *BlobTx) FakeSign(address libcommon.Address) (Transaction, error) {
+	cpy := tx.copy()
+	cpy.R.Set(u256.Num1)
+	cpy.S.Set(u256.Num1)
+	cpy.V.Set(u256.Num4)
+	cpy.from.Store(address)
+	return cpy, nil
+}
+
+// MarshalBinary returns the canonical encoding of the transaction, the one hashed and included in
+// blocks, without the blobs.
+func (tx BlobTx) MarshalBinary(w io.Writer) error {
+	payloadSize, nonceLen, gasLen, accessListLen, blobHashesLen := tx.payloadSize()
+	var b [33]byte
+	// encode TxType
+	b[0] = BlobTxType
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	if err := tx.encodePayload(w, b[:], payloadSize, nonceLen, gasLen, accessListLen, blobHashesLen); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tx BlobTx) encodePayload(w io.Writer, b []byte, payloadSize, nonceLen, gasLen, accessListLen, blobHashesLen int) error {
+	// prefix
+	if err := EncodeStructSizePrefix(payloadSize, w, b); err != nil {
+		return err
+	}
+	// encode ChainID
+	if err := tx.ChainID.EncodeRLP(w); err != nil {
+		return err
+	}
+	// encode Nonce
+	if err := rlp.EncodeInt(tx.Nonce, w, b); err != nil {
+		return err
+	}
+	// encode MaxPriorityFeePerGas
+	if err := tx.Tip.EncodeRLP(w); err != nil {
+		return err
+	}
+	// encode MaxFeePerGas
+	if err := tx.FeeCap.EncodeRLP(w); err != nil {
+		return err
+	}
+	// encode Gas
+	if err := rlp.EncodeInt(tx.Gas, w, b); err != nil {
+		return err
+	}
+	// encode To
+	if tx.To == nil {
+		b[0] = 128
+	} else {
+		b[0] = 128 + 20
+	}
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	if tx.To != nil {
+		if _, err := w.Write(tx.To.Bytes()); err != nil {
+			return err
+		}
+	}
+	// encode Value
+	if err := tx.Value.EncodeRLP(w); err != nil {
+		return err
+	}
+	// encode Data
+	if err := rlp.EncodeString(tx.Data, w, b); err != nil {
+		return err
+	}
+	// prefix
+	if err := EncodeStructSizePrefix(accessListLen, w, b); err != nil {
+		return err
+	}
+	// encode AccessList
+	if err := encodeAccessList(tx.AccessList, w, b); err != nil {
+		return err
+	}
+	// encode MaxFeePerBlobGas
+	if err := tx.MaxFeePerBlobGas.EncodeRLP(w); err != nil {
+		return err
+	}
+	// prefix
+	if err := EncodeStructSizePrefix(blobHashesLen, w, b); err != nil {
+		return err
+	}
+	// encode BlobVersionedHashes
+	if err := encodeBlobHashes(tx.BlobVersionedHashes, w, b); err != nil {
+		return err
+	}
+	// encode V
+	if err := tx.V.EncodeRLP(w); err != nil {
+		return err
+	}
+	// encode R
+	if err := tx.R.EncodeRLP(w); err != nil {
+		return err
+	}
+	// encode S
+	if err := tx.S.EncodeRLP(w); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tx BlobTx) EncodeRLP(w io.Writer) error {
+	payloadSize, nonceLen, gasLen, accessListLen, blobHashesLen := tx.payloadSize()
+	envelopeSize := payloadSize
+	if payloadSize >= 56 {
+		envelopeSize += (bits.Len(uint(payloadSize)) + 7) / 8
+	}
+	// size of struct prefix and TxType
+	envelopeSize += 2
+	var b [33]byte
+	// envelope
+	if err := rlp.EncodeStringSizePrefix(envelopeSize, w, b[:]); err != nil {
+		return err
+	}
+	// encode TxType
+	b[0] = BlobTxType
+	if _, err := w.Write(b[:1]); err != nil {
+		return err
+	}
+	if err := tx.encodePayload(w, b[:], payloadSize, nonceLen, gasLen, accessListLen, blobHashesLen); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tx *BlobTx) DecodeRLP(s *rlp.Stream) error {
+	if _, err := s.List(); err != nil {
+		return err
+	}
+	if err := tx.decodePayload(s); err != nil {
+		return err
+	}
+	return s.ListEnd()
+}
+
+func (tx *BlobTx) decodePayload(s *rlp.Stream) error {
+	var b []byte
+	var err error
+	if b, err = s.Uint256Bytes(); err != nil {
+		return err
+	}
+	tx.ChainID = new(uint256.Int).SetBytes(b)
+	if tx.Nonce, err = s.Uint(); err != nil {
+		return err
+	}
+	if b, err = s.Uint256Bytes(); err != nil {
+		return err
+	}
+	tx.Tip = new(uint256.Int).SetBytes(b)
+	if b, err = s.Uint256Bytes(); err != nil {
+		return err
+	}
+	tx.FeeCap = new(uint256.Int).SetBytes(b)
+	if tx.Gas, err = s.Uint(); err != nil {
+		return err
+	}
+	if b, err = s.Bytes(); err != nil {
+		return err
+	}
+	// Blob transactions can't create contracts
+	if len(b) != 20 {
+		return fmt.Errorf("wrong size for To: %d", len(b))
+	}
+	tx.To = &libcommon.Address{}
+	copy((*tx.To)[:], b)
+	if b, err = s.Uint256Bytes(); err != nil {
+		return err
+	}
+	tx.Value = new(uint256.Int).SetBytes(b)
+	if tx.Data, err = s.Bytes(); err != nil {
+		return err
+	}
+	// decode AccessList
+	tx.AccessList = types2.AccessList{}
+	if err = decodeAccessList(&tx.AccessList, s); err != nil {
+		return err
+	}
+	// decode MaxFeePerBlobGas
+	if b, err = s.Uint256Bytes(); err != nil {
+		return err
+	}
+	tx.MaxFeePerBlobGas = new(uint256.Int).SetBytes(b)
+	// decode BlobVersionedHashes
+	tx.BlobVersionedHashes = []libcommon.Hash{}
+	if err = decodeBlobHashes(&tx.BlobVersionedHashes, s); err != nil {
+		return err
+	}
+	// decode V
+	if b, err = s.Uint256Bytes(); err != nil {
+		return err
+	}
+	tx.V.SetBytes(b)
+	if b, err = s.Uint256Bytes(); err != nil {
+		return err
+	}
+	tx.R.SetBytes(b)
+	if b, err = s.Uint256Bytes(); err != nil {
+		return err
+	}
+	tx.S.SetBytes(b)
+	return nil
+}
+
+func decodeBlobHashes(hashes *[]libcommon.Hash, s *rlp.Stream) error {
+	if _, err := s.List(); err != nil {
+		return fmt.Errorf("open BlobVersionedHashes: %w", err)
+	}
+	var b []byte
+	var err error
+	for b, err = s.Bytes(); err == nil; b, err = s.Bytes() {
+		if len(b) != 32 {
+			return fmt.Errorf("wrong size for blob versioned hash: %d", len(b))
+		}
+		*hashes = append(*hashes, libcommon.BytesToHash(b))
+	}
+	if !errors.Is(err, rlp.EOL) {
+		return fmt.Errorf("read blob versioned hash: %w", err)
+	}
+	if err = s.ListEnd(); err != nil {
+		return fmt.Errorf("close BlobVersionedHashes: %w", err)
+	}
+	return nil
+}
+
+// AsMessage returns the transaction as a core.Message.
+func (tx BlobTx) AsMessage(s Signer, baseFee *big.Int, rules *chain.Rules) (Message, error) {
+	msg := Message{
+		nonce:            tx.Nonce,
+		gasLimit:         tx.Gas,
+		gasPrice:         *tx.FeeCap,
+		tip:              *tx.Tip,
+		feeCap:           *tx.FeeCap,
+		to:               tx.To,
+		amount:           *tx.Value,
+		data:             tx.Data,
+		accessList:       tx.AccessList,
+		checkNonce:       true,
+		maxFeePerBlobGas: *tx.MaxFeePerBlobGas,
+		blobHashes:       tx.BlobVersionedHashes,
+	}
+	if !rules.IsCancun {
+		return msg, errors.New("blob transactions require Cancun")
+	}
+	if len(tx.BlobVersionedHashes) == 0 {
+		return msg, ErrMissingBlobHashes
+	}
+	for i, hash := range tx.BlobVersionedHashes {
+		if hash[0] != params.BlobTxHashVersion {
+			return msg, fmt.Errorf("%w: blob %d has version %d, expected %d", ErrBlobHashVersion, i, hash[0], params.BlobTxHashVersion)
+		}
+	}
+	if baseFee != nil {
+		overflow := msg.gasPrice.SetFromBig(baseFee)
+		if overflow {
+			return msg, fmt.Errorf("gasPrice higher than 2^256-1")
+		}
+	}
+	msg.gasPrice.Add(&msg.gasPrice, tx.Tip)
+	if msg.gasPrice.Gt(tx.FeeCap) {
+		msg.gasPrice.Set(tx.FeeCap)
+	}
+
+	var err error
+	msg.from, err = tx.Sender(s)
+	return msg, err
+}
+
+// Hash computes the hash (but not for signatures!)
+func (tx *BlobTx) Hash() libcommon.Hash {
+	if hash := tx.hash.Load(); hash != nil {
+		return *hash.(*libcommon.Hash)
+	}
+	hash := prefixedRlpHash(BlobTxType, []interface{}{
+		tx.ChainID,
+		tx.Nonce,
+		tx.Tip,
+		tx.FeeCap,
+		tx.Gas,
+		tx.To,
+		tx.Value,
+		tx.Data,
+		tx.AccessList,
+		tx.MaxFeePerBlobGas,
+		tx.BlobVersionedHashes,
+		tx.V, tx.R, tx.S,
+	})
+	tx.hash.Store(&hash)
+	return hash
+}
+
+func (tx BlobTx) SigningHash(chainID *big.Int) libcommon.Hash {
+	return prefixedRlpHash(
+		BlobTxType,
+		[]interface{}{
+			chainID,
+			tx.Nonce,
+			tx.Tip,
+			tx.FeeCap,
+			tx.Gas,
+			tx.To,
+			tx.Value,
+			tx.Data,
+			tx.AccessList,
+			tx.MaxFeePerBlobGas,
+			tx.BlobVersionedHashes,
+		})
+}
+
+// accessors for innerTx.
+func (tx BlobTx) Type() byte { return BlobTxType }
+
+func (tx *BlobTx) Sender(signer Signer) (libcommon.Address, error) {
+	if sc := tx.from.Load(); sc != nil {
+		return sc.(libcommon.Address), nil
+	}
+	addr, err := signer.Sender(tx)
+	if err != nil {
+		return libcommon.Address{}, err
+	}
+	tx.from.Store(addr)
+	return addr, nil
+}
+
+type (
+	Blob          [params.BlobTxFieldElementsPerBlob * params.BlobTxBytesPerFieldElement]byte
+	KZGCommitment [48]byte
+	KZGProof      [48]byte
+)
+
+// VersionedHash returns the versioned hash of the commitment, the one referenced by blob transactions.
+func (c KZGCommitment) VersionedHash() libcommon.Hash {
+	h := libcommon.Hash(sha256.Sum256(c[:]))
+	h[0] = params.BlobTxHashVersion
+	return h
+}
+
+// BlobTxWrapper is the network form of a blob transaction, the one gossiped and handed to the
+// transaction pool: the transaction along with its blobs, their KZG commitments and proofs. It is
+// encoded as BlobTxType || rlp([tx_payload, blobs, commitments, proofs]), and hashes and executes like
+// the transaction it wraps.
+type BlobTxWrapper struct {
+	BlobTx
+	Blobs       []Blob
+	Commitments []KZGCommitment
+	Proofs      []KZGProof
+}
+
+// ValidateBlobs checks that the wrapper carries a blob, commitment and proof for each versioned hash
+// of the transaction, and that the hashes match the commitments. The KZG proofs binding the blobs to
+// their commitments are not verified here.
+func (w *BlobTxWrapper) ValidateBlobs() error {
+	hashes := w.BlobVersionedHashes
+	if len(hashes) == 0 {
+		return errors.New("blob transaction without blobs")
+	}
+	if len(hashes) > params.MaxBlobGasPerBlock/params.BlobTxBlobGasPerBlob {
+		return fmt.Errorf("too many blobs in transaction: %d", len(hashes))
+	}
+	if len(w.Blobs) != len(hashes) || len(w.Commitments) != len(hashes) || len(w.Proofs) != len(hashes) {
+		return fmt.Errorf("mismatched blobs: %d hashes, %d blobs, %d commitments, %d proofs",
+			len(hashes), len(w.Blobs), len(w.Commitments), len(w.Proofs))
+	}
+	for i, commitment := range w.Commitments {
+		if commitment.VersionedHash() != hashes[i] {
+			return fmt.Errorf("blob %d: versioned hash %x does not match commitment", i, hashes[i])
+		}
+	}
+	return nil
+}
+
+// Unwrap returns the transaction without its blobs, as included in blocks.
+func (w *BlobTxWrapper) Unwrap() *BlobTx {
+	return &w.BlobTx
+}
+
+func (w BlobTxWrapper) sidecar() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, list := range []interface{}{w.Blobs, w.Commitments, w.Proofs} {
+		if err := rlp.Encode(&buf, list); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (w BlobTxWrapper) payloadSize() (payloadSize int, sidecar []byte, err error) {
+	txPayloadSize, _, _, _, _ := w.BlobTx.payloadSize()
+	payloadSize = 1 + txPayloadSize
+	if txPayloadSize >= 56 {
+		payloadSize += (bits.Len(uint(txPayloadSize)) + 7) / 8
+	}
+	if sidecar, err = w.sidecar(); err != nil {
+		return 0, nil, err
+	}
+	return payloadSize + len(sidecar), sidecar, nil
+}
+
+func (w BlobTxWrapper) EncodingSize() int {
+	payloadSize, _, _ := w.payloadSize()
+	envelopeSize := payloadSize
+	// Add envelope size and type size
+	if payloadSize >= 56 {
+		envelopeSize += (bits.Len(uint(payloadSize)) + 7) / 8
+	}
+	envelopeSize += 2
+	return envelopeSize
+}
+
+// MarshalBinary returns the network encoding of the transaction.
+func (w BlobTxWrapper) MarshalBinary(wr io.Writer) error {
+	payloadSize, sidecar, err := w.payloadSize()
+	if err != nil {
+		return err
+	}
+	var b [33]byte
+	// encode TxType
+	b[0] = BlobTxType
+	if _, err := wr.Write(b[:1]); err != nil {
+		return err
+	}
+	return w.encodePayload(wr, b[:], payloadSize, sidecar)
+}
+
+func (w BlobTxWrapper) encodePayload(wr io.Writer, b []byte, payloadSize int, sidecar []byte) error {
+	// prefix
+	if err := EncodeStructSizePrefix(payloadSize, wr, b); err != nil {
+		return err
+	}
+	txPayloadSize, nonceLen, gasLen, accessListLen, blobHashesLen := w.BlobTx.payloadSize()
+	if err := w.BlobTx.encodePayload(wr, b, txPayloadSize, nonceLen, gasLen, accessListLen, blobHashesLen); err != nil {
+		return err
+	}
+	_, err := wr.Write(sidecar)
+	return err
+}
+
+func (w BlobTxWrapper) EncodeRLP(wr io.Writer) error {
+	payloadSize, sidecar, err := w.payloadSize()
+	if err != nil {
+		return err
+	}
+	envelopeSize := payloadSize
+	if payloadSize >= 56 {
+		envelopeSize += (bits.Len(uint(payloadSize)) + 7) / 8
+	}
+	// size of struct prefix and TxType
+	envelopeSize += 2
+	var b [33]byte
+	// envelope
+	if err := rlp.EncodeStringSizePrefix(envelopeSize, wr, b[:]); err != nil {
+		return err
+	}
+	// encode TxType
+	b[0] = BlobTxType
+	if _, err := wr.Write(b[:1]); err != nil {
+		return err
+	}
+	return w.encodePayload(wr, b[:], payloadSize, sidecar)
+}
+
+func (w *BlobTxWrapper) DecodeRLP(s *rlp.Stream) error {
+	if _, err := s.List(); err != nil {
+		return err
+	}
+	if err := w.BlobTx.DecodeRLP(s); err != nil {
+		return err
+	}
+	return w.decodeSidecar(s)
+}
+
+func (w *BlobTxWrapper) decodeSidecar(s *rlp.Stream) error {
+	if err := s.Decode(&w.Blobs); err != nil {
+		return fmt.Errorf("read Blobs: %w", err)
+	}
+	if err := s.Decode(&w.Commitments); err != nil {
+		return fmt.Errorf("read Commitments: %w", err)
+	}
+	if err := s.Decode(&w.Proofs); err != nil {
+		return fmt.Errorf("read Proofs: %w", err)
+	}
+	return s.ListEnd()
+}
+
+// decodeNetworkBlobTx decodes the payload of a blob transaction in its network form, a *BlobTxWrapper,
+// or in its canonical form, a *BlobTx, told apart by their first field: the transaction payload list
+// or the chain id.
+func decodeNetworkBlobTx(s *rlp.Stream) (Transaction, error) {
+	if _, err := s.List(); err != nil {
+		return nil, err
+	}
+	kind, _, err := s.Kind()
+	if err != nil {
+		return nil, err
+	}
+	if kind != rlp.List {
+		tx := &BlobTx{}
+		if err := tx.decodePayload(s); err != nil {
+			return nil, err
+		}
+		return tx, s.ListEnd()
+	}
+	w := &BlobTxWrapper{}
+	if err := w.BlobTx.DecodeRLP(s); err != nil {
+		return nil, err
+	}
+	if err := w.decodeSidecar(s); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewBlobTx creates an unsigned blob transaction.
+func NewBlobTx(chainID uint256.Int, nonce uint64, to libcommon.Address, amount *uint256.Int, gasLimit uint64, gasTip *uint256.Int, gasFeeCap *uint256.Int, blobFeeCap *uint256.Int, blobHashes []libcommon.Hash, data []byte) *BlobTx {
+	return &BlobTx{
+		DynamicFeeTransaction: DynamicFeeTransaction{
+			CommonTx: CommonTx{
+				ChainID: &chainID,
+				Nonce:   nonce,
+				To:      &to,
+				Value:   amount,
+				Gas:     gasLimit,
+				Data:    data,
+			},
+			Tip:    gasTip,
+			FeeCap: gasFeeCap,
+		},
+		MaxFeePerBlobGas:    blobFeeCap,
+		BlobVersionedHashes: blobHashes,
+	}
+}
@@ -0,0 +1,166 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+func signedBlobTx(t *testing.T, commitments []KZGCommitment) *BlobTx {
+	t.Helper()
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	require.NoError(t, err)
+
+	hashes := make([]libcommon.Hash, len(commitments))
+	for i, commitment := range commitments {
+		hashes[i] = commitment.VersionedHash()
+	}
+	recipient := libcommon.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	unsigned := NewBlobTx(*uint256.NewInt(1), 7, recipient, uint256.NewInt(10), 21_000, uint256.NewInt(2), uint256.NewInt(30), uint256.NewInt(5), hashes, []byte("abcdef"))
+	unsigned.AccessList = types2.AccessList{{Address: recipient, StorageKeys: []libcommon.Hash{{0x01}}}}
+
+	tx, err := SignTx(unsigned, *LatestSignerForChainID(libcommon.Big1), key)
+	require.NoError(t, err)
+	return tx.(*BlobTx)
+}
+
+func TestBlobTxCoding(t *testing.T) {
+	tx := signedBlobTx(t, []KZGCommitment{{0x01}, {0x02}})
+	signer := LatestSignerForChainID(libcommon.Big1)
+	from, err := signer.Sender(tx)
+	require.NoError(t, err)
+
+	// Canonical encoding
+	var buf bytes.Buffer
+	require.NoError(t, tx.MarshalBinary(&buf))
+	require.Equal(t, byte(BlobTxType), buf.Bytes()[0])
+	require.Equal(t, tx.EncodingSize(), buf.Len())
+	parsed, err := UnmarshalTransactionFromBinary(buf.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, assertEqual(tx, parsed))
+	blobTx, ok := parsed.(*BlobTx)
+	require.True(t, ok)
+	assert.Equal(t, tx.MaxFeePerBlobGas, blobTx.MaxFeePerBlobGas)
+	assert.Equal(t, tx.BlobVersionedHashes, blobTx.BlobVersionedHashes)
+	assert.Equal(t, uint64(2*131072), blobTx.GetBlobGas())
+	parsedFrom, err := signer.Sender(parsed)
+	require.NoError(t, err)
+	assert.Equal(t, from, parsedFrom)
+
+	// RLP envelope, as in block bodies
+	encoded, err := rlp.EncodeToBytes(tx)
+	require.NoError(t, err)
+	parsed, err = DecodeTransaction(rlp.NewStream(bytes.NewReader(encoded), uint64(len(encoded))))
+	require.NoError(t, err)
+	require.NoError(t, assertEqual(tx, parsed))
+
+	// Block body
+	encoded, err = rlp.EncodeToBytes(&Body{Transactions: []Transaction{tx}})
+	require.NoError(t, err)
+	var body Body
+	require.NoError(t, rlp.DecodeBytes(encoded, &body))
+	require.Len(t, body.Transactions, 1)
+	require.NoError(t, assertEqual(tx, body.Transactions[0]))
+
+	// JSON
+	parsed, err = encodeDecodeJSON(tx)
+	require.NoError(t, err)
+	require.NoError(t, assertEqual(tx, parsed))
+	assert.Equal(t, tx.BlobVersionedHashes, parsed.(*BlobTx).BlobVersionedHashes)
+	assert.Equal(t, tx.MaxFeePerBlobGas, parsed.(*BlobTx).MaxFeePerBlobGas)
+}
+
+func TestBlobTxWrapperCoding(t *testing.T) {
+	commitments := []KZGCommitment{{0x01}, {0x02}}
+	wrapper := &BlobTxWrapper{
+		BlobTx:      *signedBlobTx(t, commitments),
+		Blobs:       []Blob{{0xaa}, {0xbb}},
+		Commitments: commitments,
+		Proofs:      []KZGProof{{0x0a}, {0x0b}},
+	}
+	require.NoError(t, wrapper.ValidateBlobs())
+
+	var buf bytes.Buffer
+	require.NoError(t, wrapper.MarshalBinary(&buf))
+	require.Equal(t, wrapper.EncodingSize(), buf.Len())
+
+	// The network form only decodes as such
+	_, err := UnmarshalTransactionFromBinary(buf.Bytes())
+	require.Error(t, err)
+	parsed, err := UnmarshalWrappedTransactionFromBinary(buf.Bytes())
+	require.NoError(t, err)
+	parsedWrapper, ok := parsed.(*BlobTxWrapper)
+	require.True(t, ok)
+	assert.Equal(t, wrapper.Hash(), parsedWrapper.Hash())
+	assert.Equal(t, wrapper.Blobs, parsedWrapper.Blobs)
+	assert.Equal(t, wrapper.Commitments, parsedWrapper.Commitments)
+	assert.Equal(t, wrapper.Proofs, parsedWrapper.Proofs)
+	require.NoError(t, parsedWrapper.ValidateBlobs())
+
+	// RLP envelope, as in pooled transactions
+	encoded, err := rlp.EncodeToBytes(wrapper)
+	require.NoError(t, err)
+	parsed, err = DecodeWrappedTransaction(rlp.NewStream(bytes.NewReader(encoded), uint64(len(encoded))))
+	require.NoError(t, err)
+	assert.Equal(t, wrapper.Hash(), parsed.Hash())
+
+	// Unwrapped, it's the canonical transaction
+	var canonical bytes.Buffer
+	require.NoError(t, wrapper.Unwrap().MarshalBinary(&canonical))
+	parsed, err = UnmarshalWrappedTransactionFromBinary(canonical.Bytes())
+	require.NoError(t, err)
+	_, ok = parsed.(*BlobTx)
+	assert.True(t, ok)
+	assert.Equal(t, wrapper.Hash(), parsed.Hash())
+
+	// Senders are recovered from the wrapped transaction
+	signer := LatestSignerForChainID(libcommon.Big1)
+	from, err := signer.Sender(wrapper)
+	require.NoError(t, err)
+	unwrappedFrom, err := signer.Sender(wrapper.Unwrap())
+	require.NoError(t, err)
+	assert.Equal(t, unwrappedFrom, from)
+}
+
+func TestBlobTxWrapperValidateBlobs(t *testing.T) {
+	commitments := []KZGCommitment{{0x01}, {0x02}}
+	wrapper := &BlobTxWrapper{
+		BlobTx:      *signedBlobTx(t, commitments),
+		Blobs:       []Blob{{0xaa}},
+		Commitments: commitments,
+		Proofs:      []KZGProof{{0x0a}, {0x0b}},
+	}
+	require.ErrorContains(t, wrapper.ValidateBlobs(), "mismatched blobs")
+
+	wrapper.Blobs = []Blob{{0xaa}, {0xbb}}
+	wrapper.Commitments = []KZGCommitment{{0x01}, {0x03}}
+	require.ErrorContains(t, wrapper.ValidateBlobs(), "does not match commitment")
+
+	wrapper.BlobVersionedHashes = nil
+	require.Error(t, wrapper.ValidateBlobs())
+}
+
+func TestBlobTxSigner(t *testing.T) {
+	tx := signedBlobTx(t, []KZGCommitment{{0x01}})
+
+	// Signers of chains without Cancun don't know blob transactions
+	signer := *LatestSignerForChainID(libcommon.Big1)
+	signer.blob = false
+	_, err := signer.Sender(tx)
+	require.ErrorContains(t, err, "not supported")
+
+	signer.blob = true
+	_, err = signer.Sender(tx)
+	require.NoError(t, err)
+
+	_, err = LatestSignerForChainID(libcommon.Big2).Sender(tx)
+	require.ErrorIs(t, err, ErrInvalidChainId)
+}
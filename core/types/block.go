@@ -100,6 +100,9 @@ type Header struct {
 	BaseFee         *big.Int        `json:"baseFeePerGas"`   // EIP-1559
 	WithdrawalsHash *libcommon.Hash `json:"withdrawalsRoot"` // EIP-4895
 
+	BlobGasUsed   *uint64 `json:"blobGasUsed"`   // EIP-4844
+	ExcessBlobGas *uint64 `json:"excessBlobGas"` // EIP-4844
+
 	// The verkle proof is ignored in legacy headers
 	Verkle        bool
 	VerkleProof   []byte
@@ -165,6 +168,15 @@ func (h *Header) EncodingSize() int {
 		encodingSize += 33
 	}
 
+	if h.BlobGasUsed != nil {
+		encodingSize++
+		encodingSize += rlp.IntLenExcludingHead(*h.BlobGasUsed)
+	}
+	if h.ExcessBlobGas != nil {
+		encodingSize++
+		encodingSize += rlp.IntLenExcludingHead(*h.ExcessBlobGas)
+	}
+
 	if h.Verkle {
 		// Encoding of Verkle Proof
 		encodingSize++
@@ -307,6 +319,17 @@ func (h *Header) EncodeRLP(w io.Writer) error {
 		}
 	}
 
+	if h.BlobGasUsed != nil {
+		if err := rlp.EncodeInt(*h.BlobGasUsed, w, b[:]); err != nil {
+			return err
+		}
+	}
+	if h.ExcessBlobGas != nil {
+		if err := rlp.EncodeInt(*h.ExcessBlobGas, w, b[:]); err != nil {
+			return err
+		}
+	}
+
 	if h.Verkle {
 		if err := rlp.EncodeString(h.VerkleProof, w, b[:]); err != nil {
 			return err
@@ -452,6 +475,27 @@ func (h *Header) DecodeRLP(s *rlp.Stream) error {
 	h.WithdrawalsHash = new(libcommon.Hash)
 	h.WithdrawalsHash.SetBytes(b)
 
+	// BlobGasUsed
+	var blobGasUsed uint64
+	if blobGasUsed, err = s.Uint(); err != nil {
+		if errors.Is(err, rlp.EOL) {
+			h.BlobGasUsed = nil
+			if err := s.ListEnd(); err != nil {
+				return fmt.Errorf("close header struct (no BlobGasUsed): %w", err)
+			}
+			return nil
+		}
+		return fmt.Errorf("read BlobGasUsed: %w", err)
+	}
+	h.BlobGasUsed = &blobGasUsed
+
+	// ExcessBlobGas
+	var excessBlobGas uint64
+	if excessBlobGas, err = s.Uint(); err != nil {
+		return fmt.Errorf("read ExcessBlobGas: %w", err)
+	}
+	h.ExcessBlobGas = &excessBlobGas
+
 	if h.Verkle {
 		if h.VerkleProof, err = s.Bytes(); err != nil {
 			return fmt.Errorf("read VerkleProof: %w", err)
@@ -479,6 +523,9 @@ type headerMarshaling struct {
 	Extra      hexutil.Bytes
 	BaseFee    *hexutil.Big
 	Hash       libcommon.Hash `json:"hash"` // adds call to Hash() in MarshalJSON
+
+	BlobGasUsed   *hexutil.Uint64
+	ExcessBlobGas *hexutil.Uint64
 }
 
 // Hash returns the block hash of the header, which is simply the keccak256 hash of its
@@ -499,6 +546,12 @@ func (h *Header) Size() common.StorageSize {
 	if h.WithdrawalsHash != nil {
 		s += common.StorageSize(32)
 	}
+	if h.BlobGasUsed != nil {
+		s += common.StorageSize(8)
+	}
+	if h.ExcessBlobGas != nil {
+		s += common.StorageSize(8)
+	}
 	return s
 }
 
@@ -1101,6 +1154,8 @@ func (bb Body) payloadSize() (payloadSize int, txsLen, unclesLen, withdrawalsLen
 			txLen = t.EncodingSize()
 		case *DynamicFeeTransaction:
 			txLen = t.EncodingSize()
+		case *BlobTx:
+			txLen = t.EncodingSize()
 		}
 		if txLen >= 56 {
 			txsLen += bitsToBytes(bits.Len(uint(txLen)))
@@ -1172,6 +1227,10 @@ func (bb Body) EncodeRLP(w io.Writer) error {
 			if err := t.EncodeRLP(w); err != nil {
 				return err
 			}
+		case *BlobTx:
+			if err := t.EncodeRLP(w); err != nil {
+				return err
+			}
 		}
 	}
 	// encode Uncles
@@ -1360,6 +1419,14 @@ func CopyHeader(h *Header) *Header {
 		cpy.WithdrawalsHash = new(libcommon.Hash)
 		cpy.WithdrawalsHash.SetBytes(h.WithdrawalsHash.Bytes())
 	}
+	if h.BlobGasUsed != nil {
+		blobGasUsed := *h.BlobGasUsed
+		cpy.BlobGasUsed = &blobGasUsed
+	}
+	if h.ExcessBlobGas != nil {
+		excessBlobGas := *h.ExcessBlobGas
+		cpy.ExcessBlobGas = &excessBlobGas
+	}
 	return &cpy
 }
 
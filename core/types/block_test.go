@@ -442,6 +442,49 @@ func TestWithdrawalsEncoding(t *testing.T) {
 	assert.Equal(t, block2, &decoded2)
 }
 
+func TestBlobGasHeaderEncoding(t *testing.T) {
+	blobGasUsed, excessBlobGas := uint64(2*131072), uint64(393216)
+	header := Header{
+		ParentHash:      libcommon.HexToHash("0x8b00fcf1e541d371a3a1b79cc999a85cc3db5ee5637b5159646e1acd3613fd15"),
+		Difficulty:      libcommon.Big0,
+		Number:          big.NewInt(20_000_000),
+		GasLimit:        30_000_000,
+		Time:            1666343339,
+		Extra:           make([]byte, 0),
+		BaseFee:         big.NewInt(7_000_000_000),
+		WithdrawalsHash: &libcommon.Hash{0x01},
+		BlobGasUsed:     &blobGasUsed,
+		ExcessBlobGas:   &excessBlobGas,
+	}
+
+	encoded, err := rlp.EncodeToBytes(&header)
+	require.NoError(t, err)
+
+	var decoded Header
+	require.NoError(t, rlp.DecodeBytes(encoded, &decoded))
+	assert.Equal(t, header.Hash(), decoded.Hash())
+	require.NotNil(t, decoded.BlobGasUsed)
+	require.NotNil(t, decoded.ExcessBlobGas)
+	assert.Equal(t, blobGasUsed, *decoded.BlobGasUsed)
+	assert.Equal(t, excessBlobGas, *decoded.ExcessBlobGas)
+
+	encodedJSON, err := json.Marshal(&header)
+	require.NoError(t, err)
+	var decodedJSON Header
+	require.NoError(t, json.Unmarshal(encodedJSON, &decodedJSON))
+	assert.Equal(t, header.Hash(), decodedJSON.Hash())
+
+	// Blob gas fields come after the withdrawals root, a header without them keeps its encoding
+	header.BlobGasUsed, header.ExcessBlobGas = nil, nil
+	encoded, err = rlp.EncodeToBytes(&header)
+	require.NoError(t, err)
+	decoded = Header{}
+	require.NoError(t, rlp.DecodeBytes(encoded, &decoded))
+	assert.Nil(t, decoded.BlobGasUsed)
+	assert.Nil(t, decoded.ExcessBlobGas)
+	assert.Equal(t, header.Hash(), decoded.Hash())
+}
+
 func TestBlockRawBodyPreShanghai(t *testing.T) {
 	require := require.New(t)
 
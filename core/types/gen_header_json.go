@@ -34,6 +34,8 @@ func (h Header) MarshalJSON() ([]byte, error) {
 		Nonce           BlockNonce        `json:"nonce"`
 		BaseFee         *hexutil.Big      `json:"baseFeePerGas"`
 		WithdrawalsHash *libcommon.Hash   `json:"withdrawalsRoot"`
+		BlobGasUsed     *hexutil.Uint64   `json:"blobGasUsed"`
+		ExcessBlobGas   *hexutil.Uint64   `json:"excessBlobGas"`
 		Hash            libcommon.Hash    `json:"hash"`
 	}
 	var enc Header
@@ -54,6 +56,8 @@ func (h Header) MarshalJSON() ([]byte, error) {
 	enc.Nonce = h.Nonce
 	enc.BaseFee = (*hexutil.Big)(h.BaseFee)
 	enc.WithdrawalsHash = h.WithdrawalsHash
+	enc.BlobGasUsed = (*hexutil.Uint64)(h.BlobGasUsed)
+	enc.ExcessBlobGas = (*hexutil.Uint64)(h.ExcessBlobGas)
 	enc.Hash = h.Hash()
 	return json.Marshal(&enc)
 }
@@ -78,6 +82,8 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		Nonce           *BlockNonce        `json:"nonce"`
 		BaseFee         *hexutil.Big       `json:"baseFeePerGas"`
 		WithdrawalsHash *libcommon.Hash    `json:"withdrawalsRoot"`
+		BlobGasUsed     *hexutil.Uint64    `json:"blobGasUsed"`
+		ExcessBlobGas   *hexutil.Uint64    `json:"excessBlobGas"`
 	}
 	var dec Header
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -144,5 +150,11 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		h.BaseFee = (*big.Int)(dec.BaseFee)
 	}
 	h.WithdrawalsHash = dec.WithdrawalsHash
+	if dec.BlobGasUsed != nil {
+		h.BlobGasUsed = (*uint64)(dec.BlobGasUsed)
+	}
+	if dec.ExcessBlobGas != nil {
+		h.ExcessBlobGas = (*uint64)(dec.ExcessBlobGas)
+	}
 	return nil
 }
@@ -26,6 +26,8 @@ func (r Receipt) MarshalJSON() ([]byte, error) {
 		TxHash            libcommon.Hash    `json:"transactionHash" gencodec:"required"`
 		ContractAddress   libcommon.Address `json:"contractAddress"`
 		GasUsed           hexutil.Uint64    `json:"gasUsed" gencodec:"required"`
+		BlobGasUsed       hexutil.Uint64    `json:"blobGasUsed,omitempty"`
+		BlobGasPrice      *hexutil.Big      `json:"blobGasPrice,omitempty"`
 		BlockHash         libcommon.Hash    `json:"blockHash,omitempty"`
 		BlockNumber       *hexutil.Big      `json:"blockNumber,omitempty"`
 		TransactionIndex  hexutil.Uint      `json:"transactionIndex"`
@@ -40,6 +42,8 @@ func (r Receipt) MarshalJSON() ([]byte, error) {
 	enc.TxHash = r.TxHash
 	enc.ContractAddress = r.ContractAddress
 	enc.GasUsed = hexutil.Uint64(r.GasUsed)
+	enc.BlobGasUsed = hexutil.Uint64(r.BlobGasUsed)
+	enc.BlobGasPrice = (*hexutil.Big)(r.BlobGasPrice)
 	enc.BlockHash = r.BlockHash
 	enc.BlockNumber = (*hexutil.Big)(r.BlockNumber)
 	enc.TransactionIndex = hexutil.Uint(r.TransactionIndex)
@@ -58,6 +62,8 @@ func (r *Receipt) UnmarshalJSON(input []byte) error {
 		TxHash            *libcommon.Hash    `json:"transactionHash" gencodec:"required"`
 		ContractAddress   *libcommon.Address `json:"contractAddress"`
 		GasUsed           *hexutil.Uint64    `json:"gasUsed" gencodec:"required"`
+		BlobGasUsed       *hexutil.Uint64    `json:"blobGasUsed,omitempty"`
+		BlobGasPrice      *hexutil.Big       `json:"blobGasPrice,omitempty"`
 		BlockHash         *libcommon.Hash    `json:"blockHash,omitempty"`
 		BlockNumber       *hexutil.Big       `json:"blockNumber,omitempty"`
 		TransactionIndex  *hexutil.Uint      `json:"transactionIndex"`
@@ -98,6 +104,12 @@ func (r *Receipt) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'gasUsed' for Receipt")
 	}
 	r.GasUsed = uint64(*dec.GasUsed)
+	if dec.BlobGasUsed != nil {
+		r.BlobGasUsed = uint64(*dec.BlobGasUsed)
+	}
+	if dec.BlobGasPrice != nil {
+		r.BlobGasPrice = (*big.Int)(dec.BlobGasPrice)
+	}
 	if dec.BlockHash != nil {
 		r.BlockHash = *dec.BlockHash
 	}
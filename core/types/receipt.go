@@ -62,6 +62,8 @@ type Receipt struct {
 	TxHash          libcommon.Hash    `json:"transactionHash" gencodec:"required" codec:"-"`
 	ContractAddress libcommon.Address `json:"contractAddress" codec:"-"`
 	GasUsed         uint64            `json:"gasUsed" gencodec:"required" codec:"-"`
+	BlobGasUsed     uint64            `json:"blobGasUsed,omitempty" codec:"-"`
+	BlobGasPrice    *big.Int          `json:"blobGasPrice,omitempty" codec:"-"`
 
 	// Inclusion information: These fields provide information about the inclusion of the
 	// transaction corresponding to this receipt.
@@ -76,6 +78,8 @@ type receiptMarshaling struct {
 	Status            hexutil.Uint64
 	CumulativeGasUsed hexutil.Uint64
 	GasUsed           hexutil.Uint64
+	BlobGasUsed       hexutil.Uint64
+	BlobGasPrice      *hexutil.Big
 	BlockNumber       *hexutil.Big
 	TransactionIndex  hexutil.Uint
 }
@@ -246,7 +250,7 @@ func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
 		}
 		r.Type = b[0]
 		switch r.Type {
-		case AccessListTxType, DynamicFeeTxType:
+		case AccessListTxType, DynamicFeeTxType, BlobTxType:
 			if err := r.decodePayload(s); err != nil {
 				return err
 			}
@@ -302,6 +306,10 @@ func (r *Receipt) Copy() *Receipt {
 	contractAddress := libcommon.BytesToAddress(r.ContractAddress.Bytes())
 	blockHash := libcommon.BytesToHash(r.BlockHash.Bytes())
 	blockNumber := big.NewInt(0).Set(r.BlockNumber)
+	var blobGasPrice *big.Int
+	if r.BlobGasPrice != nil {
+		blobGasPrice = new(big.Int).Set(r.BlobGasPrice)
+	}
 
 	return &Receipt{
 		Type:              r.Type,
@@ -313,6 +321,8 @@ func (r *Receipt) Copy() *Receipt {
 		TxHash:            txHash,
 		ContractAddress:   contractAddress,
 		GasUsed:           r.GasUsed,
+		BlobGasUsed:       r.BlobGasUsed,
+		BlobGasPrice:      blobGasPrice,
 		BlockHash:         blockHash,
 		BlockNumber:       blockNumber,
 		TransactionIndex:  r.TransactionIndex,
@@ -442,6 +452,11 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 		if err := rlp.Encode(w, data); err != nil {
 			panic(err)
 		}
+	case BlobTxType:
+		w.WriteByte(BlobTxType)
+		if err := rlp.Encode(w, data); err != nil {
+			panic(err)
+		}
 	default:
 		// For unsupported types, write nothing. Since this is for
 		// DeriveSha, the error will be caught matching the derived hash
@@ -35,6 +35,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rlp"
 )
 
@@ -43,6 +44,8 @@ var (
 	ErrUnexpectedProtection = errors.New("transaction type does not supported EIP-155 protected signatures")
 	ErrInvalidTxType        = errors.New("transaction type not valid in this context")
 	ErrTxTypeNotSupported   = errors.New("transaction type not supported")
+	ErrMissingBlobHashes    = errors.New("blob transaction without blob hashes")
+	ErrBlobHashVersion      = errors.New("blob hash version not supported")
 )
 
 // Transaction types.
@@ -50,6 +53,7 @@ const (
 	LegacyTxType = iota
 	AccessListTxType
 	DynamicFeeTxType
+	BlobTxType
 )
 
 // Transaction is an Ethereum transaction.
@@ -120,7 +124,19 @@ func (tm TransactionMisc) From() *atomic.Value {
 	return &tm.from
 }
 
+// DecodeTransaction decodes a transaction as included in blocks.
 func DecodeTransaction(s *rlp.Stream) (Transaction, error) {
+	return decodeTransaction(s, false)
+}
+
+// DecodeWrappedTransaction decodes a transaction in its network form: blob transactions come with their
+// blobs, see BlobTxWrapper, other types are the same as in blocks. Blob transactions without their blobs
+// are accepted too, and decoded as in blocks.
+func DecodeWrappedTransaction(s *rlp.Stream) (Transaction, error) {
+	return decodeTransaction(s, true)
+}
+
+func decodeTransaction(s *rlp.Stream, wrapped bool) (Transaction, error) {
 	kind, size, err := s.Kind()
 	if err != nil {
 		return nil, err
@@ -156,6 +172,18 @@ func DecodeTransaction(s *rlp.Stream) (Transaction, error) {
 			return nil, err
 		}
 		tx = t
+	case BlobTxType:
+		if wrapped {
+			if tx, err = decodeNetworkBlobTx(s); err != nil {
+				return nil, err
+			}
+		} else {
+			t := &BlobTx{}
+			if err = t.DecodeRLP(s); err != nil {
+				return nil, err
+			}
+			tx = t
+		}
 	default:
 		return nil, fmt.Errorf("%w, got: %d", rlp.ErrUnknownTxTypePrefix, b[0])
 	}
@@ -172,6 +200,11 @@ func UnmarshalTransactionFromBinary(data []byte) (Transaction, error) {
 	return DecodeTransaction(s)
 }
 
+func UnmarshalWrappedTransactionFromBinary(data []byte) (Transaction, error) {
+	s := rlp.NewStream(bytes.NewReader(data), uint64(len(data)))
+	return DecodeWrappedTransaction(s)
+}
+
 func MarshalTransactionsBinary(txs Transactions) ([][]byte, error) {
 	var err error
 	var buf bytes.Buffer
@@ -465,6 +498,9 @@ type Message struct {
 	accessList types2.AccessList
 	checkNonce bool
 	isFree     bool
+
+	maxFeePerBlobGas uint256.Int
+	blobHashes       []libcommon.Hash
 }
 
 func NewMessage(from libcommon.Address, to *libcommon.Address, nonce uint64, amount *uint256.Int, gasLimit uint64, gasPrice *uint256.Int, feeCap, tip *uint256.Int, data []byte, accessList types2.AccessList, checkNonce bool, isFree bool) Message {
@@ -510,6 +546,14 @@ func (m *Message) SetIsFree(isFree bool) {
 	m.isFree = isFree
 }
 
+func (m Message) MaxFeePerBlobGas() *uint256.Int { return &m.maxFeePerBlobGas }
+func (m Message) BlobHashes() []libcommon.Hash   { return m.blobHashes }
+
+// BlobGas returns the blob gas consumed by the blobs of an EIP-4844 transaction, 0 for other types.
+func (m Message) BlobGas() uint64 {
+	return uint64(len(m.blobHashes)) * params.BlobTxBlobGasPerBlob
+}
+
 func (m *Message) ChangeGas(globalGasCap, desiredGas uint64) {
 	gas := globalGasCap
 	if gas == 0 {
@@ -50,6 +50,8 @@ func MakeSigner(config *chain.Config, blockNumber uint64) *Signer {
 		signer.protected = true
 		signer.accesslist = true
 		signer.dynamicfee = true
+		// Cancun is scheduled by time, blob transactions are rejected before it when turned into messages
+		signer.blob = config.CancunTime != nil
 		signer.chainID.Set(&chainId)
 		signer.chainIDMul.Mul(&chainId, u256.Num2)
 	case config.IsBerlin(blockNumber):
@@ -93,6 +95,9 @@ func LatestSigner(config *chain.Config) *Signer {
 	signer.chainID.Set(chainId)
 	signer.chainIDMul.Mul(chainId, u256.Num2)
 	if config.ChainID != nil {
+		if config.CancunTime != nil {
+			signer.blob = true
+		}
 		if config.LondonBlock != nil {
 			signer.dynamicfee = true
 		}
@@ -107,8 +112,8 @@ func LatestSigner(config *chain.Config) *Signer {
 }
 
 // LatestSignerForChainID returns the 'most permissive' Signer available. Specifically,
-// this marks support for EIP-155 replay protection, EIP-2718 typed transactions and EIP-1559 dynamic fee
-// transaction types if chainID is non-nil.
+// this marks support for EIP-155 replay protection, EIP-2718 typed transactions, EIP-1559 dynamic fee
+// and EIP-4844 blob transaction types if chainID is non-nil.
 //
 // Use this in transaction-handling code where the current block number and fork
 // configuration are unknown. If you have a ChainConfig, use LatestSigner instead.
@@ -128,6 +133,7 @@ func LatestSignerForChainID(chainID *big.Int) *Signer {
 	signer.protected = true
 	signer.accesslist = true
 	signer.dynamicfee = true
+	signer.blob = true
 	return &signer
 }
 
@@ -174,10 +180,11 @@ type Signer struct {
 	protected           bool // Whether this signer should allow transactions with replay protection via chainId
 	accesslist          bool // Whether this signer should allow transactions with access list, superseeds protected
 	dynamicfee          bool // Whether this signer should allow transactions with basefee and tip (instead of gasprice), superseeds accesslist
+	blob                bool // Whether this signer should allow transactions with data blobs, superseeds dynamicfee
 }
 
 func (sg Signer) String() string {
-	return fmt.Sprintf("Signer[chainId=%s,malleable=%t,unprotected=%t,protected=%t,accesslist=%t,dynamicfee=%t", &sg.chainID, sg.maleable, sg.unprotected, sg.protected, sg.accesslist, sg.dynamicfee)
+	return fmt.Sprintf("Signer[chainId=%s,malleable=%t,unprotected=%t,protected=%t,accesslist=%t,dynamicfee=%t,blob=%t", &sg.chainID, sg.maleable, sg.unprotected, sg.protected, sg.accesslist, sg.dynamicfee, sg.blob)
 }
 
 // Sender returns the sender address of the transaction.
@@ -240,6 +247,22 @@ func (sg Signer) SenderWithContext(context *secp256k1.Context, tx Transaction) (
 		// id, add 27 to become equivalent to unprotected Homestead signatures.
 		V.Add(&t.V, u256.Num27)
 		R, S = &t.R, &t.S
+	case *BlobTx:
+		if !sg.blob {
+			return libcommon.Address{}, fmt.Errorf("blob tx is not supported by signer %s", sg)
+		}
+		if t.ChainID == nil {
+			if !sg.chainID.IsZero() {
+				return libcommon.Address{}, ErrInvalidChainId
+			}
+		} else if !t.ChainID.Eq(&sg.chainID) {
+			return libcommon.Address{}, ErrInvalidChainId
+		}
+		// Blob txs use 0 and 1 as their recovery id too
+		V.Add(&t.V, u256.Num27)
+		R, S = &t.R, &t.S
+	case *BlobTxWrapper:
+		return sg.SenderWithContext(context, &t.BlobTx)
 	default:
 		return libcommon.Address{}, ErrTxTypeNotSupported
 	}
@@ -272,6 +295,13 @@ func (sg Signer) SignatureValues(tx Transaction, sig []byte) (R, S, V *uint256.I
 			return nil, nil, nil, ErrInvalidChainId
 		}
 		R, S, V = decodeSignature(sig)
+	case *BlobTx:
+		if t.ChainID != nil && !t.ChainID.IsZero() && !t.ChainID.Eq(&sg.chainID) {
+			return nil, nil, nil, ErrInvalidChainId
+		}
+		R, S, V = decodeSignature(sig)
+	case *BlobTxWrapper:
+		return sg.SignatureValues(&t.BlobTx, sig)
 	default:
 		return nil, nil, nil, ErrTxTypeNotSupported
 	}
@@ -289,7 +319,8 @@ func (sg Signer) Equal(other Signer) bool {
 		sg.unprotected == other.unprotected &&
 		sg.protected == other.protected &&
 		sg.accesslist == other.accesslist &&
-		sg.dynamicfee == other.dynamicfee
+		sg.dynamicfee == other.dynamicfee &&
+		sg.blob == other.blob
 }
 
 func decodeSignature(sig []byte) (r, s, v *uint256.Int) {
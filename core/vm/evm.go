@@ -591,3 +591,9 @@ func (evm *EVM) IntraBlockState() evmtypes.IntraBlockState {
 func (evm *EVM) FirehoseContext() *firehose.Context {
 	return evm.firehoseContext
 }
+
+// SetFirehoseContext changes the Firehose context the EVM records into, it's used by
+// executors reusing an EVM across transactions recorded into different contexts.
+func (evm *EVM) SetFirehoseContext(firehoseContext *firehose.Context) {
+	evm.firehoseContext = firehoseContext
+}
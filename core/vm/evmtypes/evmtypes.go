@@ -31,6 +31,8 @@ type BlockContext struct {
 	Difficulty  *big.Int          // Provides information for DIFFICULTY
 	BaseFee     *uint256.Int      // Provides information for BASEFEE
 	PrevRanDao  *libcommon.Hash   // Provides information for PREVRANDAO
+
+	ExcessBlobGas *uint64 // Prices the blob gas of EIP-4844 transactions, nil before Cancun
 }
 
 // TxContext provides the EVM with information about a transaction.
@@ -0,0 +1,47 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+)
+
+func SetupSyncStagesAccess() {
+	http.HandleFunc("/debug/metrics/sync/stages", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		writeSyncStages(w, r)
+	})
+}
+
+// writeSyncStages writes a line per stage: name, whether it is running, current and target blocks, blocks per second,
+// estimated time remaining and the last unwind. The optional stage argument selects a single stage.
+func writeSyncStages(w io.Writer, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		fmt.Fprintf(w, "ERROR: parsing arguments: %v\n", err)
+		return
+	}
+	status := stagedsync.PublishedStatus()
+	if status == nil {
+		fmt.Fprintf(w, "ERROR: staged sync is not running in this process\n")
+		return
+	}
+	stage := r.Form.Get("stage")
+	fmt.Fprintf(w, "SUCCESS\n")
+	for _, st := range status.Stages() {
+		if stage != "" && string(st.Stage) != stage {
+			continue
+		}
+		eta := "-"
+		if st.Eta != nil {
+			eta = st.Eta.String()
+		}
+		unwind := "-"
+		if st.LastUnwindPoint != nil {
+			unwind = fmt.Sprintf("to %d at %s, %s", *st.LastUnwindPoint, st.LastUnwindTime.Format("2006-01-02 15:04:05"), st.LastUnwindReason)
+		}
+		fmt.Fprintf(w, "%s | running=%t | current=%d | target=%d | items/s=%.2f | eta=%s | last unwind: %s\n",
+			st.Stage, st.Running, st.CurrentBlock, st.TargetBlock, st.ItemsPerSecond, eta, unwind)
+	}
+}
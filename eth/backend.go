@@ -77,6 +77,7 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/handshake"
 	"github.com/ledgerwatch/erigon/cmd/sentinel/sentinel/service"
 	"github.com/ledgerwatch/erigon/cmd/sentry/sentry"
+	"github.com/ledgerwatch/erigon/cmd/sentry/sentry/reputation"
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/consensus"
 	"github.com/ledgerwatch/erigon/consensus/bor"
@@ -98,6 +99,8 @@ import (
 	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 	"github.com/ledgerwatch/erigon/ethstats"
+	"github.com/ledgerwatch/erigon/firehose"
+	firehosemempool "github.com/ledgerwatch/erigon/firehose/mempool"
 	"github.com/ledgerwatch/erigon/node"
 	"github.com/ledgerwatch/erigon/p2p"
 	"github.com/ledgerwatch/erigon/params"
@@ -149,6 +152,8 @@ type Ethereum struct {
 	sentryCancel   context.CancelFunc
 	sentriesClient *sentry.MultiClient
 	sentryServers  []*sentry.GrpcServer
+	// peerReputations serves the peer reputations of all the sentries on the private API
+	peerReputations *reputation.SentriesServer
 
 	stagedSync      *stagedsync.Sync
 	syncStages      []*stagedsync.Stage
@@ -324,13 +329,15 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 	backend.gasPrice, _ = uint256.FromBig(config.Miner.GasPrice)
 
 	var sentries []direct.SentryClient
+	var reputations []reputation.PeerReputationClient
 	if len(stack.Config().P2P.SentryAddr) > 0 {
 		for _, addr := range stack.Config().P2P.SentryAddr {
-			sentryClient, err := sentry.GrpcClient(backend.sentryCtx, addr)
+			sentryClient, reputationClient, err := sentry.GrpcClient(backend.sentryCtx, addr)
 			if err != nil {
 				return nil, err
 			}
 			sentries = append(sentries, sentryClient)
+			reputations = append(reputations, reputationClient)
 		}
 	} else {
 		var readNodeInfo = func() *eth.NodeInfo {
@@ -386,6 +393,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 			server := sentry.NewGrpcServer(backend.sentryCtx, discovery, readNodeInfo, &cfg, protocol)
 			backend.sentryServers = append(backend.sentryServers, server)
 			sentries = append(sentries, direct.NewSentryClientDirect(protocol, server))
+			reputations = append(reputations, reputation.NewPeerReputationClientDirect(server.ReputationServer()))
 		}
 
 		go func() {
@@ -416,6 +424,8 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 		}()
 	}
 
+	backend.peerReputations = reputation.NewSentriesServer(reputations)
+
 	inMemoryExecution := func(batch kv.RwTx, header *types.Header, body *types.RawBody, unwindPoint uint64, headersChain []*types.Header, bodiesChain []*types.RawBody,
 		notifications *shards.Notifications) error {
 		// Needs its own notifications to not update RPC daemon and txpool about pending blocks
@@ -558,6 +568,7 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 			ethBackendRPC,
 			backend.txPool2GrpcServer,
 			miningRPC,
+			backend.peerReputations,
 			stack.Config().PrivateApiAddr,
 			stack.Config().PrivateApiRateLimit,
 			creds,
@@ -638,6 +649,10 @@ func New(stack *node.Node, config *ethconfig.Config, logger log.Logger) (*Ethere
 				default:
 				}
 			})
+		if mempoolContext := firehose.MaybeMempoolContext(); mempoolContext.Enabled() {
+			observer := firehosemempool.NewObserver(direct.NewTxPoolClient(backend.txPool2GrpcServer), stateDiffClient, backend.chainConfig, mempoolContext)
+			go observer.Run(backend.sentryCtx)
+		}
 	}
 	go func() {
 		defer debug.LogPanic()
@@ -697,6 +712,7 @@ func (backend *Ethereum) Init(stack *node.Node, config *ethconfig.Config) error
 	var err error
 
 	backend.stagedSync = stagedsync.New(backend.syncStages, backend.syncUnwindOrder, backend.syncPruneOrder)
+	backend.stagedSync.PublishStatus()
 
 	backend.sentriesClient.Hd.StartPoSDownloader(backend.sentryCtx, backend.sentriesClient.SendHeaderRequest, backend.sentriesClient.Penalize)
 
@@ -732,7 +748,7 @@ func (backend *Ethereum) Init(stack *node.Node, config *ethconfig.Config) error
 	}
 	// start HTTP API
 	httpRpcCfg := stack.Config().Http
-	ethRpcClient, txPoolRpcClient, miningRpcClient, stateCache, ff, err := cli.EmbeddedServices(ctx, chainKv, httpRpcCfg.StateCache, blockReader, ethBackendRPC, backend.txPool2GrpcServer, miningRPC, stateDiffClient)
+	ethRpcClient, txPoolRpcClient, miningRpcClient, stateCache, ff, err := cli.EmbeddedServices(ctx, chainKv, httpRpcCfg.StateCache, blockReader, ethBackendRPC, backend.peerReputations, backend.txPool2GrpcServer, miningRPC, stateDiffClient)
 	if err != nil {
 		return err
 	}
@@ -979,6 +995,11 @@ func (s *Ethereum) NodesInfo(limit int) (*remote.NodesInfoReply, error) {
 func (s *Ethereum) setUpBlockReader(ctx context.Context, dirs datadir.Dirs, snConfig ethconfig.Snapshot, downloaderCfg *downloadercfg.Cfg, notifications *shards.Events, transactionsV3 bool) (services.FullBlockReader, *snapshotsync.RoSnapshots, *libstate.AggregatorV3, error) {
 	allSnapshots := snapshotsync.NewRoSnapshots(snConfig, dirs.Snap)
 	var err error
+	if snConfig.VerifySegments {
+		if err = snapshotsync.CheckSegments(ctx, dirs.Snap); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 	if !snConfig.NoDownloader {
 		allSnapshots.OptimisticalyReopenWithDB(s.chainDB)
 	}
@@ -136,6 +136,7 @@ type Snapshot struct {
 	Produce        bool // produce new snapshots
 	NoDownloader   bool // possible to use snapshots without calling Downloader
 	Verify         bool // verify snapshots on startup
+	VerifySegments bool // check segments and their indices on startup, see snapshotsync.VerifySegments
 	DownloaderAddr string
 }
 
@@ -263,6 +264,10 @@ type Sync struct {
 
 	BodyCacheLimit             datasize.ByteSize
 	BodyDownloadTimeoutSeconds int // TODO: change to duration
+
+	// DownloadReceipts makes the Receipts stage download the receipts from peers, and the Execution stage skip blocks.
+	// For nodes which serve logs and receipts without the state.
+	DownloadReceipts bool
 }
 
 // Chains where snapshots are enabled by default
@@ -208,6 +208,10 @@ func (tp TransactionsPacket) EncodeRLP(w io.Writer) error {
 			txLen = t.EncodingSize()
 		case *types.DynamicFeeTransaction:
 			txLen = t.EncodingSize()
+		case *types.BlobTx:
+			txLen = t.EncodingSize()
+		case *types.BlobTxWrapper:
+			txLen = t.EncodingSize()
 		}
 		if txLen >= 56 {
 			txsLen += (bits.Len(uint(txLen)) + 7) / 8
@@ -237,6 +241,14 @@ func (tp TransactionsPacket) EncodeRLP(w io.Writer) error {
 			if err := t.EncodeRLP(w); err != nil {
 				return err
 			}
+		case *types.BlobTx:
+			if err := t.EncodeRLP(w); err != nil {
+				return err
+			}
+		case *types.BlobTxWrapper:
+			if err := t.EncodeRLP(w); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -531,6 +543,10 @@ func (ptp PooledTransactionsPacket) EncodeRLP(w io.Writer) error {
 			txLen = t.EncodingSize()
 		case *types.DynamicFeeTransaction:
 			txLen = t.EncodingSize()
+		case *types.BlobTx:
+			txLen = t.EncodingSize()
+		case *types.BlobTxWrapper:
+			txLen = t.EncodingSize()
 		}
 		if txLen >= 56 {
 			txsLen += (bits.Len(uint(txLen)) + 7) / 8
@@ -560,6 +576,14 @@ func (ptp PooledTransactionsPacket) EncodeRLP(w io.Writer) error {
 			if err := t.EncodeRLP(w); err != nil {
 				return err
 			}
+		case *types.BlobTx:
+			if err := t.EncodeRLP(w); err != nil {
+				return err
+			}
+		case *types.BlobTxWrapper:
+			if err := t.EncodeRLP(w); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -571,7 +595,7 @@ func (ptp *PooledTransactionsPacket) DecodeRLP(s *rlp.Stream) error {
 		return err
 	}
 	var tx types.Transaction
-	for tx, err = types.DecodeTransaction(s); err == nil; tx, err = types.DecodeTransaction(s) {
+	for tx, err = types.DecodeWrappedTransaction(s); err == nil; tx, err = types.DecodeWrappedTransaction(s) {
 		*ptp = append(*ptp, tx)
 	}
 	if !errors.Is(err, rlp.EOL) {
@@ -605,6 +629,10 @@ func (ptp66 PooledTransactionsPacket66) EncodeRLP(w io.Writer) error {
 			txLen = t.EncodingSize()
 		case *types.DynamicFeeTransaction:
 			txLen = t.EncodingSize()
+		case *types.BlobTx:
+			txLen = t.EncodingSize()
+		case *types.BlobTxWrapper:
+			txLen = t.EncodingSize()
 		}
 		if txLen >= 56 {
 			txsLen += (bits.Len(uint(txLen)) + 7) / 8
@@ -651,6 +679,14 @@ func (ptp66 PooledTransactionsPacket66) EncodeRLP(w io.Writer) error {
 			if err := t.EncodeRLP(w); err != nil {
 				return err
 			}
+		case *types.BlobTx:
+			if err := t.EncodeRLP(w); err != nil {
+				return err
+			}
+		case *types.BlobTxWrapper:
+			if err := t.EncodeRLP(w); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -668,7 +704,7 @@ func (ptp66 *PooledTransactionsPacket66) DecodeRLP(s *rlp.Stream) error {
 		return err
 	}
 	var tx types.Transaction
-	for tx, err = types.DecodeTransaction(s); err == nil; tx, err = types.DecodeTransaction(s) {
+	for tx, err = types.DecodeWrappedTransaction(s); err == nil; tx, err = types.DecodeWrappedTransaction(s) {
 		ptp66.PooledTransactionsPacket = append(ptp66.PooledTransactionsPacket, tx)
 	}
 	if !errors.Is(err, rlp.EOL) {
@@ -7,7 +7,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 )
 
-func DefaultStages(ctx context.Context, snapshots SnapshotsCfg, headers HeadersCfg, cumulativeIndex CumulativeIndexCfg, blockHashCfg BlockHashesCfg, bodies BodiesCfg, senders SendersCfg, exec ExecuteBlockCfg, hashState HashStateCfg, trieCfg TrieCfg, history HistoryCfg, logIndex LogIndexCfg, callTraces CallTracesCfg, txLookup TxLookupCfg, finish FinishCfg, test bool) []*Stage {
+func DefaultStages(ctx context.Context, snapshots SnapshotsCfg, headers HeadersCfg, cumulativeIndex CumulativeIndexCfg, blockHashCfg BlockHashesCfg, bodies BodiesCfg, senders SendersCfg, receipts ReceiptsCfg, exec ExecuteBlockCfg, hashState HashStateCfg, trieCfg TrieCfg, history HistoryCfg, logIndex LogIndexCfg, callTraces CallTracesCfg, txLookup TxLookupCfg, finish FinishCfg, test bool) []*Stage {
 	return []*Stage{
 		{
 			ID:          stages.Snapshots,
@@ -93,10 +93,29 @@ func DefaultStages(ctx context.Context, snapshots SnapshotsCfg, headers HeadersC
 				return PruneSendersStage(p, tx, senders, ctx)
 			},
 		},
+		{
+			ID:                  stages.Receipts,
+			Description:         "Download receipts instead of executing blocks",
+			DisabledDescription: "Receipts are not stored in the DB with --experimental.history.v3",
+			Disabled:            receipts.historyV3,
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				return ReceiptsForward(s, ctx, tx, receipts, quiet)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return UnwindReceiptsStage(u, s, tx, receipts, ctx)
+			},
+			Prune: func(firstCycle bool, p *PruneState, tx kv.RwTx) error {
+				return PruneReceiptsStage(p, tx, receipts, ctx)
+			},
+		},
 		{
 			ID:          stages.Execution,
 			Description: "Execute blocks w/o hash checks",
 			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if receipts.downloading() {
+					// The receipts of the blocks were downloaded instead
+					return nil
+				}
 				return SpawnExecuteBlocksStage(s, u, tx, 0, ctx, exec, firstCycle, quiet)
 			},
 			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
@@ -309,8 +328,9 @@ var DefaultForwardOrder = UnwindOrder{
 	stages.BlockHashes,
 	stages.Bodies,
 
-	// Stages below don't use Internet
+	// Stages below don't use Internet, except Receipts when it downloads them
 	stages.Senders,
+	stages.Receipts,
 	stages.Execution,
 	stages.Translation,
 	stages.HashState,
@@ -344,6 +364,7 @@ var DefaultUnwindOrder = UnwindOrder{
 
 	stages.Translation,
 	stages.Execution,
+	stages.Receipts,
 	stages.Senders,
 
 	stages.Bodies,
@@ -377,6 +398,7 @@ var DefaultPruneOrder = PruneOrder{
 
 	stages.Translation,
 	stages.Execution,
+	stages.Receipts,
 	stages.Senders,
 
 	stages.Bodies,
@@ -34,6 +34,7 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/state/exec3"
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/rawdb/rawdbhelpers"
@@ -582,7 +583,13 @@ Loop:
 		}
 
 		rules := chainConfig.Rules(blockNum, b.Time())
-		var gasUsed uint64
+		var gasUsed, blobGasUsed uint64
+		if parallel {
+			// Blob gas doesn't depend on the execution, the block is checked before its transactions are scheduled
+			if err := misc.VerifyBlockBlobGas(header, txs); err != nil {
+				return fmt.Errorf("%w, headerNum=%d, %x", err, header.Number.Uint64(), header.Hash())
+			}
+		}
 		for txIndex := -1; txIndex <= len(txs); txIndex++ {
 
 			// Do not oversend, wait for the result heap to go under certain size
@@ -633,6 +640,17 @@ Loop:
 				count++
 				applyWorker.RunTxTask(txTask)
 				if err := func() error {
+					if txTask.Tx != nil {
+						if err := misc.AddBlobGas(&blobGasUsed, txTask.Tx); err != nil {
+							return fmt.Errorf("%w, headerNum=%d, %x", err, txTask.Header.Number.Uint64(), txTask.Header.Hash())
+						}
+					}
+					if txTask.Final {
+						if err := misc.VerifyBlobGasUsed(txTask.Header, blobGasUsed); err != nil {
+							return fmt.Errorf("%w, headerNum=%d, %x", err, txTask.Header.Number.Uint64(), txTask.Header.Hash())
+						}
+						blobGasUsed = 0
+					}
 					if txTask.Final && !isPoSa {
 						gasUsed += txTask.UsedGas
 						if gasUsed != txTask.Header.GasUsed {
@@ -657,6 +675,7 @@ Loop:
 							return err
 						}
 					}
+					applyWorker.FirehoseCancel(txTask, err)
 					u.UnwindTo(blockNum-1, header.Hash())
 					break Loop
 				}
@@ -664,6 +683,7 @@ Loop:
 				if err := rs.ApplyState(applyTx, txTask, agg); err != nil {
 					return fmt.Errorf("StateV3.Apply: %w", err)
 				}
+				applyWorker.FirehoseApply(txTask)
 				triggerCount.Add(rs.CommitTxNum(txTask.Sender, txTask.TxNum))
 				outputTxNum.Inc()
 
@@ -794,6 +814,7 @@ func processResultQueue(rws *exec22.TxTaskQueue, outputTxNumIn uint64, rs *state
 			// resolve first conflict right here: it's faster and conflict-free
 			applyWorker.RunTxTask(txTask)
 			if txTask.Error != nil {
+				applyWorker.FirehoseCancel(txTask, txTask.Error)
 				return resultSize, outputTxNum, conflicts, processedBlockNum, txTask.Error
 			}
 			i++
@@ -802,6 +823,7 @@ func processResultQueue(rws *exec22.TxTaskQueue, outputTxNumIn uint64, rs *state
 		if err := rs.ApplyState(applyTx, txTask, agg); err != nil {
 			return resultSize, outputTxNum, conflicts, processedBlockNum, fmt.Errorf("StateV3.Apply: %w", err)
 		}
+		applyWorker.FirehoseApply(txTask)
 		triggerCount.Add(rs.CommitTxNum(txTask.Sender, txTask.TxNum))
 		outputTxNum++
 		onSuccess()
@@ -2,6 +2,7 @@ package stagedsync
 
 import (
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
 	"github.com/ledgerwatch/erigon-lib/kv"
 
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
@@ -52,6 +53,7 @@ func (s *StageState) Update(db kv.Putter, newBlockNum uint64) error {
 	if m, ok := syncMetrics[s.ID]; ok {
 		m.Set(newBlockNum)
 	}
+	s.state.reportProgress(s.ID, newBlockNum)
 	return stages.SaveStageProgress(db, s.ID, newBlockNum)
 }
 func (s *StageState) UpdatePrune(db kv.Putter, blockNum uint64) error {
@@ -64,6 +66,19 @@ func (s *StageState) ExecutionAt(db kv.Getter) (uint64, error) {
 	return execution, err
 }
 
+// ReceiptsAt gets the highest block which has receipts, produced either by the "Execution" stage or downloaded by the "Receipts" stage.
+func (s *StageState) ReceiptsAt(db kv.Getter) (uint64, error) {
+	execution, err := stages.GetStageProgress(db, stages.Execution)
+	if err != nil {
+		return 0, err
+	}
+	receipts, err := stages.GetStageProgress(db, stages.Receipts)
+	if err != nil {
+		return 0, err
+	}
+	return cmp.Max(execution, receipts), nil
+}
+
 // IntermediateHashesAt gets the current state of the "IntermediateHashes" stage.
 // A block is fully validated after the IntermediateHashes stage is passed successfully.
 func (s *StageState) IntermediateHashesAt(db kv.Getter) (uint64, error) {
@@ -42,6 +42,7 @@ import (
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/ethdb/olddb"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/firehose"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/shards"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
@@ -138,6 +139,7 @@ func executeBlock(
 	vmConfig vm.Config, // emit copy, because will modify it
 	writeChangesets bool,
 	writeReceipts bool,
+	writeKeptLogs bool, // Receipts are written only for the logs kept past the pruning horizon
 	writeCallTraces bool,
 	initialCycle bool,
 	stateStream bool,
@@ -179,6 +181,14 @@ func executeBlock(
 	if err != nil {
 		return err
 	}
+
+	// Blocks executed by this stage are always on top of the canonical chain
+	if !vmConfig.ReadOnly {
+		if firehoseContext := firehose.MaybeSyncContext(); firehoseContext.Enabled() {
+			firehoseContext.RecordNewBlock(block.Header())
+		}
+	}
+
 	receipts = execRs.Receipts
 	stateSyncReceipt = execRs.StateSyncReceipt
 
@@ -192,6 +202,12 @@ func executeBlock(
 				return err
 			}
 		}
+	} else if writeKeptLogs {
+		if kept := cfg.prune.KeepLogs.FilterReceipts(receipts); kept != nil {
+			if err = rawdb.AppendReceipts(tx, blockNum, kept); err != nil {
+				return err
+			}
+		}
 	}
 
 	if cfg.changeSetHook != nil {
@@ -387,6 +403,11 @@ func SpawnExecuteBlocksStage(s *StageState, u Unwinder, tx kv.RwTx, toBlock uint
 		return err
 	}
 	nextStagesExpectData := nextStageProgress > 0 // Incremental move of next stages depend on fully written ChangeSets, Receipts, CallTraceSet
+	// Blocks which receipts were downloaded by the Receipts stage, before it fell back to execution, already have them
+	receiptsProgress, err := stages.GetStageProgress(tx, stages.Receipts)
+	if err != nil {
+		return err
+	}
 
 	logPrefix := s.LogPrefix()
 	var to = prevStageProgress
@@ -446,9 +467,10 @@ Loop:
 
 		// Incremental move of next stages depend on fully written ChangeSets, Receipts, CallTraceSet
 		writeChangeSets := nextStagesExpectData || blockNum > cfg.prune.History.PruneTo(to)
-		writeReceipts := nextStagesExpectData || blockNum > cfg.prune.Receipts.PruneTo(to)
+		writeReceipts := (nextStagesExpectData || blockNum > cfg.prune.Receipts.PruneTo(to)) && blockNum > receiptsProgress
+		writeKeptLogs := !writeReceipts && blockNum > receiptsProgress && cfg.prune.KeepLogs.Enabled()
 		writeCallTraces := nextStagesExpectData || blockNum > cfg.prune.CallTraces.PruneTo(to)
-		if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, initialCycle, stateStream); err != nil {
+		if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeKeptLogs, writeCallTraces, initialCycle, stateStream); err != nil {
 			if !errors.Is(err, context.Canceled) {
 				log.Warn(fmt.Sprintf("[%s] Execution failed", logPrefix), "block", blockNum, "hash", block.Hash().String(), "err", err)
 				if cfg.hd != nil {
@@ -566,6 +588,11 @@ func UnwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 	if err = unwindExecutionStage(u, s, tx, ctx, cfg, initialCycle); err != nil {
 		return err
 	}
+	if firehoseContext := firehose.MaybeSyncContext(); firehoseContext.Enabled() {
+		if err = recordFirehoseUndoBlocks(firehoseContext, u, s, tx, ctx, cfg); err != nil {
+			return err
+		}
+	}
 	if err = u.Done(tx); err != nil {
 		return err
 	}
@@ -578,6 +605,25 @@ func UnwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 	return nil
 }
 
+// recordFirehoseUndoBlocks tells Firehose consumers about every executed block removed from the
+// canonical chain, newest first. Canonical markers of unwound blocks are still there, the Headers
+// stage being unwound after this one.
+func recordFirehoseUndoBlocks(firehoseContext *firehose.Context, u *UnwindState, s *StageState, tx kv.Tx, ctx context.Context, cfg ExecuteBlockCfg) error {
+	for blockNum := s.BlockNumber; blockNum > u.UnwindPoint; blockNum-- {
+		header, err := cfg.blockReader.HeaderByNumber(ctx, tx, blockNum)
+		if err != nil {
+			return fmt.Errorf("reading unwound header %d: %w", blockNum, err)
+		}
+		if header == nil {
+			return fmt.Errorf("unwound header %d not found", blockNum)
+		}
+
+		firehoseContext.RecordUndoBlock(header)
+	}
+
+	return nil
+}
+
 func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context.Context, cfg ExecuteBlockCfg, initialCycle bool) error {
 	logPrefix := s.LogPrefix()
 	stateBucket := kv.PlainState
@@ -762,14 +808,10 @@ func PruneExecutionStage(s *PruneState, tx kv.RwTx, cfg ExecuteBlockCfg, ctx con
 		}
 
 		if cfg.prune.Receipts.Enabled() {
-			if err = rawdb.PruneTable(tx, kv.Receipts, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxInt32); err != nil {
-				return err
-			}
 			if err = rawdb.PruneTable(tx, kv.BorReceipts, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxUint32); err != nil {
 				return err
 			}
-			// LogIndex.Prune will read everything what not pruned here
-			if err = rawdb.PruneTable(tx, kv.Log, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxInt32); err != nil {
+			if err = pruneReceipts(s, tx, cfg.prune, ctx); err != nil {
 				return err
 			}
 		}
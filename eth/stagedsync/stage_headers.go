@@ -276,9 +276,38 @@ func writeForkChoiceHashes(
 		rawdb.WriteForkchoiceFinalized(tx, forkChoice.FinalizedBlockHash)
 	}
 
+	if firehoseContext := firehose.MaybeSyncContext(); firehoseContext.Enabled() {
+		if err := recordFirehoseForkchoice(firehoseContext, forkChoice, tx, cfg); err != nil {
+			return false, err
+		}
+	}
+
 	return true, nil
 }
 
+// recordFirehoseForkchoice hands the safe and finalized blocks of a canonical forkchoice to
+// Firehose, so they are the ones advertised along blocks rather than header heuristics.
+func recordFirehoseForkchoice(firehoseContext *firehose.Context, forkChoice *engineapi.ForkChoiceMessage, tx kv.Tx, cfg HeadersCfg) error {
+	readHeader := func(hash libcommon.Hash) (*types.Header, error) {
+		if hash == (libcommon.Hash{}) {
+			return nil, nil
+		}
+		return cfg.blockReader.HeaderByHash(context.Background(), tx, hash)
+	}
+
+	safe, err := readHeader(forkChoice.SafeBlockHash)
+	if err != nil {
+		return fmt.Errorf("reading safe header %x: %w", forkChoice.SafeBlockHash, err)
+	}
+	finalized, err := readHeader(forkChoice.FinalizedBlockHash)
+	if err != nil {
+		return fmt.Errorf("reading finalized header %x: %w", forkChoice.FinalizedBlockHash, err)
+	}
+
+	firehoseContext.RecordForkchoice(safe, finalized)
+	return nil
+}
+
 func startHandlingForkChoice(
 	forkChoice *engineapi.ForkChoiceMessage,
 	requestStatus engineapi.RequestStatus,
@@ -1132,9 +1161,15 @@ func NewChainReaderImpl(config *chain.Config, tx kv.Getter, blockReader services
 	return &ChainReaderImpl{config, tx, blockReader}
 }
 
-func (cr ChainReaderImpl) Config() *chain.Config                 { return cr.config }
-func (cr ChainReaderImpl) CurrentHeader() *types.Header          { panic("") }
-func (cr ChainReaderImpl) CurrentFinalizedHeader() *types.Header { panic("") }
+func (cr ChainReaderImpl) Config() *chain.Config        { return cr.config }
+func (cr ChainReaderImpl) CurrentHeader() *types.Header { panic("") }
+func (cr ChainReaderImpl) CurrentFinalizedHeader() *types.Header {
+	hash := rawdb.ReadForkchoiceFinalized(cr.tx)
+	if hash == (libcommon.Hash{}) {
+		return nil
+	}
+	return cr.GetHeaderByHash(hash)
+}
 func (cr ChainReaderImpl) GetHeader(hash libcommon.Hash, number uint64) *types.Header {
 	if cr.blockReader != nil {
 		h, _ := cr.blockReader.Header(context.Background(), cr.tx, hash, number)
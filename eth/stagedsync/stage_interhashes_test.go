@@ -404,3 +404,79 @@ func TestHiveTrieRoot(t *testing.T) {
 
 	assert.Equal(t, regeneratedRoot, incrementalRoot)
 }
+
+func TestCalcTrieWithRetainList(t *testing.T) {
+	db, tx := memdb.NewTestTx(t)
+	ctx := context.Background()
+
+	hash1 := libcommon.HexToHash("0xB000000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash1, 3*params.Ether, 0))
+
+	hash2 := libcommon.HexToHash("0xB040000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash2, 1*params.Ether, 0))
+
+	incarnation := uint64(1)
+	hash3 := libcommon.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash3, 2*params.Ether, incarnation))
+
+	loc1 := libcommon.HexToHash("0x1200000000000000000000000000000000000000000000000000000000000000")
+	loc2 := libcommon.HexToHash("0x1400000000000000000000000000000000000000000000000000000000000000")
+	loc3 := libcommon.HexToHash("0x3000000000000000000000000000000000000000000000000000000000E00000")
+
+	assert.Nil(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc1), common.FromHex("0x42")))
+	assert.Nil(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc2), common.FromHex("0x01")))
+	assert.Nil(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc3), common.FromHex("0x127a89")))
+
+	hash4 := libcommon.HexToHash("0xB310000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash4, 8*params.Ether, 0))
+
+	hash5 := libcommon.HexToHash("0xB320000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash5, 1*params.Ether, 0))
+
+	historyV3 := false
+	blockReader := snapshotsync.NewBlockReaderWithSnapshots(nil, false)
+	cfg := StageTrieCfg(db, false, true, false, t.TempDir(), blockReader, nil, historyV3, nil)
+	expectedRoot, err := RegenerateIntermediateHashes("IH", tx, cfg, libcommon.Hash{} /* expectedRootHash */, ctx)
+	require.NoError(t, err)
+
+	rl := trie.NewRetainList(0)
+	rl.AddKey(hash1[:])
+	rl.AddKey(hash3[:])
+	rl.AddKey(dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc3))
+	loader := trie.NewFlatDBTrieLoader("test")
+	require.NoError(t, loader.Reset(rl, nil, nil, false))
+	tr, err := loader.CalcTrie(tx, nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, tr.Hash())
+
+	// The retained accounts and storage are in the trie, the branches off their paths are hashed
+	acc, ok := tr.GetAccount(hash1[:])
+	require.True(t, ok)
+	require.Equal(t, uint64(3*params.Ether), acc.Balance.Uint64())
+	acc, ok = tr.GetAccount(hash3[:])
+	require.True(t, ok)
+	require.Equal(t, uint64(2*params.Ether), acc.Balance.Uint64())
+	value, ok := tr.Get(dbutils.GenerateCompositeTrieKey(hash3, loc3))
+	require.True(t, ok)
+	require.Equal(t, common.FromHex("0x127a89"), value)
+	_, ok = tr.GetAccount(hash4[:])
+	require.False(t, ok)
+	_, ok = tr.Get(dbutils.GenerateCompositeTrieKey(hash3, loc2))
+	require.False(t, ok)
+
+	// The witness of the retained keys rebuilds a trie of the same root
+	wl := trie.NewRetainListBuilder()
+	wl.AddTouch(hash1[:])
+	wl.AddTouch(hash3[:])
+	wl.AddStorageTouch(dbutils.GenerateCompositeTrieKey(hash3, loc3))
+	witness, err := tr.ExtractWitness(false, wl.Build())
+	require.NoError(t, err)
+	fromWitness, err := trie.BuildTrieFromWitness(witness, false)
+	require.NoError(t, err)
+	require.Equal(t, expectedRoot, fromWitness.Hash())
+	value, ok = fromWitness.Get(dbutils.GenerateCompositeTrieKey(hash3, loc3))
+	require.True(t, ok)
+	require.Equal(t, common.FromHex("0x127a89"), value)
+	_, ok = fromWitness.GetAccount(hash5[:])
+	require.False(t, ok)
+}
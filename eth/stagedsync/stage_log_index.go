@@ -13,6 +13,7 @@ import (
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/dbg"
 	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
@@ -59,10 +60,10 @@ func SpawnLogIndex(s *StageState, tx kv.RwTx, cfg LogIndexCfg, ctx context.Conte
 		defer tx.Rollback()
 	}
 
-	endBlock, err := s.ExecutionAt(tx)
+	endBlock, err := s.ReceiptsAt(tx)
 	logPrefix := s.LogPrefix()
 	if err != nil {
-		return fmt.Errorf("getting last executed block: %w", err)
+		return fmt.Errorf("getting last block with receipts: %w", err)
 	}
 	// if prematureEndBlock is nonzero and less than the latest executed block,
 	// then we only run the log index stage until prematureEndBlock
@@ -78,7 +79,8 @@ func SpawnLogIndex(s *StageState, tx kv.RwTx, cfg LogIndexCfg, ctx context.Conte
 
 	startBlock := s.BlockNumber
 	pruneTo := cfg.prune.Receipts.PruneTo(endBlock)
-	if startBlock < pruneTo {
+	if startBlock < pruneTo && !cfg.prune.KeepLogs.Enabled() {
+		// Past the pruning horizon, only the kept logs are in the DB, and they get indexed
 		startBlock = pruneTo
 	}
 	if startBlock > 0 {
@@ -353,37 +355,74 @@ func truncateBitmaps(tx kv.RwTx, bucket string, inMem map[string]struct{}, to ui
 	return nil
 }
 
-func pruneOldLogChunks(tx kv.RwTx, bucket string, inMem *etl.Collector, pruneTo uint64, ctx context.Context) error {
-	logEvery := time.NewTicker(logInterval)
-	defer logEvery.Stop()
-
+// pruneOldLogChunks removes the pruned blocks from the bitmaps of the topics or addresses, the collector having
+// the block numbers appended to the keys. Bitmaps keep the blocks of the logs which stay.
+func pruneOldLogChunks(tx kv.RwTx, bucket string, inMem *etl.Collector, ctx context.Context) error {
 	c, err := tx.RwCursor(bucket)
 	if err != nil {
 		return err
 	}
 	defer c.Close()
 
-	if err := inMem.Load(tx, bucket, func(key, v []byte, table etl.CurrentTableReader, next etl.LoadNextFunc) error {
-		for k, _, err := c.Seek(key); k != nil; k, _, err = c.Next() {
+	var key []byte
+	pruned := roaring.New()
+	flush := func() error {
+		if key == nil {
+			return nil
+		}
+		defer pruned.Clear()
+		seek := make([]byte, len(key)+4)
+		copy(seek, key)
+		binary.BigEndian.PutUint32(seek[len(key):], pruned.Minimum())
+		for k, chunk, err := c.Seek(seek); k != nil; k, chunk, err = c.Next() {
 			if err != nil {
 				return err
 			}
-			blockNum := uint64(binary.BigEndian.Uint32(k[len(key):]))
-			if !bytes.HasPrefix(k, key) || blockNum >= pruneTo {
+			if !bytes.HasPrefix(k, key) {
+				break
+			}
+			last := binary.BigEndian.Uint32(k[len(key):])
+			bm := roaring.New()
+			if err = bm.UnmarshalBinary(chunk); err != nil {
+				return err
+			}
+			if bm.Intersects(pruned) {
+				bm.AndNot(pruned)
+				if bm.IsEmpty() {
+					err = c.DeleteCurrent()
+				} else {
+					// The key of the chunk stays, it's the upper bound of its blocks
+					chunk, err = bm.ToBytes()
+					if err == nil {
+						err = c.Put(libcommon.Copy(k), chunk)
+					}
+				}
+				if err != nil {
+					return fmt.Errorf("failed to prune %x: %w", key, err)
+				}
+			}
+			if last >= pruned.Maximum() {
 				break
 			}
+		}
+		return nil
+	}
 
-			if err = c.DeleteCurrent(); err != nil {
-				return fmt.Errorf("failed delete, block=%d: %w", blockNum, err)
+	if err := inMem.Load(tx, bucket, func(k, v []byte, table etl.CurrentTableReader, next etl.LoadNextFunc) error {
+		if !bytes.Equal(k[:len(k)-4], key) {
+			if err := flush(); err != nil {
+				return err
 			}
+			key = libcommon.Copy(k[:len(k)-4])
 		}
+		pruned.Add(binary.BigEndian.Uint32(k[len(k)-4:]))
 		return nil
 	}, etl.TransformArgs{
 		Quit: ctx.Done(),
 	}); err != nil {
 		return err
 	}
-	return nil
+	return flush()
 }
 
 func PruneLogIndex(s *PruneState, tx kv.RwTx, cfg LogIndexCfg, ctx context.Context) (err error) {
@@ -401,8 +440,13 @@ func PruneLogIndex(s *PruneState, tx kv.RwTx, cfg LogIndexCfg, ctx context.Conte
 		defer tx.Rollback()
 	}
 
+	var from uint64
+	if cfg.prune.KeepLogs.Enabled() {
+		// Blocks before the previous horizon have only the kept logs left
+		from = prunedTo(s, cfg.prune.Receipts)
+	}
 	pruneTo := cfg.prune.Receipts.PruneTo(s.ForwardProgress)
-	if err = pruneLogIndex(logPrefix, tx, cfg.tmpdir, pruneTo, ctx); err != nil {
+	if err = pruneLogIndex(logPrefix, tx, cfg.tmpdir, from, pruneTo, cfg.prune.KeepLogs, ctx); err != nil {
 		return err
 	}
 	if err = s.Done(tx); err != nil {
@@ -417,7 +461,7 @@ func PruneLogIndex(s *PruneState, tx kv.RwTx, cfg LogIndexCfg, ctx context.Conte
 	return nil
 }
 
-func pruneLogIndex(logPrefix string, tx kv.RwTx, tmpDir string, pruneTo uint64, ctx context.Context) error {
+func pruneLogIndex(logPrefix string, tx kv.RwTx, tmpDir string, from, pruneTo uint64, keep prune.LogRetention, ctx context.Context) error {
 	logEvery := time.NewTicker(logInterval)
 	defer logEvery.Stop()
 
@@ -427,6 +471,29 @@ func pruneLogIndex(logPrefix string, tx kv.RwTx, tmpDir string, pruneTo uint64,
 	addrs := etl.NewCollector(logPrefix, tmpDir, etl.NewOldestEntryBuffer(bufferSize))
 	defer addrs.Close()
 
+	// The block is pruned from the index of a topic or an address unless some of its logs with it stay
+	var block uint64
+	prunedKeys, keptKeys := map[string]struct{}{}, map[string]struct{}{}
+	collectBlock := func() error {
+		for key := range prunedKeys {
+			if _, ok := keptKeys[key]; ok {
+				continue
+			}
+			collector := topics
+			if len(key) == length.Addr {
+				collector = addrs
+			}
+			k := make([]byte, len(key)+4)
+			copy(k, key)
+			binary.BigEndian.PutUint32(k[len(key):], uint32(block))
+			if err := collector.Collect(k, nil); err != nil {
+				return err
+			}
+		}
+		prunedKeys, keptKeys = map[string]struct{}{}, map[string]struct{}{}
+		return nil
+	}
+
 	reader := bytes.NewReader(nil)
 	{
 		c, err := tx.Cursor(kv.Log)
@@ -435,7 +502,7 @@ func pruneLogIndex(logPrefix string, tx kv.RwTx, tmpDir string, pruneTo uint64,
 		}
 		defer c.Close()
 
-		for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+		for k, v, err := c.Seek(hexutility.EncodeTs(from)); k != nil; k, v, err = c.Next() {
 			if err != nil {
 				return err
 			}
@@ -450,30 +517,38 @@ func pruneLogIndex(logPrefix string, tx kv.RwTx, tmpDir string, pruneTo uint64,
 				return libcommon.ErrStopped
 			default:
 			}
+			if blockNum != block {
+				if err := collectBlock(); err != nil {
+					return err
+				}
+				block = blockNum
+			}
 
 			var logs types.Logs
 			reader.Reset(v)
 			if err := cbor.Unmarshal(&logs, reader); err != nil {
 				return fmt.Errorf("receipt unmarshal failed: %w, block=%d", err, binary.BigEndian.Uint64(k))
 			}
-
+			keys := prunedKeys
+			if keep.KeepLogs(logs) {
+				keys = keptKeys
+			}
 			for _, l := range logs {
 				for _, topic := range l.Topics {
-					if err := topics.Collect(topic.Bytes(), nil); err != nil {
-						return err
-					}
-				}
-				if err := addrs.Collect(l.Address.Bytes(), nil); err != nil {
-					return err
+					keys[string(topic.Bytes())] = struct{}{}
 				}
+				keys[string(l.Address.Bytes())] = struct{}{}
 			}
 		}
+		if err := collectBlock(); err != nil {
+			return err
+		}
 	}
 
-	if err := pruneOldLogChunks(tx, kv.LogTopicIndex, topics, pruneTo, ctx); err != nil {
+	if err := pruneOldLogChunks(tx, kv.LogTopicIndex, topics, ctx); err != nil {
 		return err
 	}
-	if err := pruneOldLogChunks(tx, kv.LogAddressIndex, addrs, pruneTo, ctx); err != nil {
+	if err := pruneOldLogChunks(tx, kv.LogAddressIndex, addrs, ctx); err != nil {
 		return err
 	}
 	return nil
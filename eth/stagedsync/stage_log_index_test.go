@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/RoaringBitmap/roaring"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 
 	"github.com/stretchr/testify/require"
@@ -132,7 +134,7 @@ func TestPruneLogIndex(t *testing.T) {
 	require.NoError(err)
 
 	// Mode test
-	err = pruneLogIndex("", tx, tmpDir, 50, ctx)
+	err = pruneLogIndex("", tx, tmpDir, 0, 50, prune.LogRetention{}, ctx)
 	require.NoError(err)
 
 	{
@@ -171,7 +173,7 @@ func TestUnwindLogIndex(t *testing.T) {
 	require.NoError(err)
 
 	// Mode test
-	err = pruneLogIndex("", tx, tmpDir, 50, ctx)
+	err = pruneLogIndex("", tx, tmpDir, 0, 50, prune.LogRetention{}, ctx)
 	require.NoError(err)
 
 	// Unwind test
@@ -189,3 +191,81 @@ func TestUnwindLogIndex(t *testing.T) {
 		require.True(m.Maximum() <= 700)
 	}
 }
+
+func TestPruneKeepLogs(t *testing.T) {
+	require, tmpDir, ctx := require.New(t), t.TempDir(), context.Background()
+	_, tx := memdb.NewTestTx(t)
+
+	kept, other := libcommon.Address{1}, libcommon.Address{2}
+	for i := uint64(0); i < 100; i++ {
+		receipts := types.Receipts{{Logs: types.Logs{{Address: other, Topics: []libcommon.Hash{{1}}}}}}
+		if i%10 == 0 {
+			// The kept logs share a topic with the pruned ones
+			receipts = append(receipts, &types.Receipt{Logs: types.Logs{{Address: kept, Topics: []libcommon.Hash{{1}, {2}}}}})
+		}
+		require.NoError(rawdb.AppendReceipts(tx, i, receipts))
+	}
+	require.NoError(promoteLogIndex("logPrefix", tx, 0, 0, StageLogIndexCfg(nil, prune.DefaultMode, ""), ctx))
+
+	pm := prune.Mode{Receipts: prune.Distance(50), KeepLogs: prune.LogRetention{Addresses: []libcommon.Address{kept}}}
+	s := &PruneState{ID: stages.Execution, ForwardProgress: 100}
+	require.NoError(pruneLogIndex("", tx, tmpDir, 0, pm.Receipts.PruneTo(s.ForwardProgress), pm.KeepLogs, ctx))
+	require.NoError(pruneReceipts(s, tx, pm, ctx))
+
+	checkBlock := func(blockNum uint64, pruned bool) {
+		receipts := rawdb.ReadRawReceipts(tx, blockNum)
+		switch {
+		case pruned && blockNum%10 != 0:
+			require.Nil(receipts, blockNum)
+		case pruned:
+			require.Len(receipts, 2, blockNum)
+			require.Empty(receipts[0].Logs, blockNum)
+			require.Equal(kept, receipts[1].Logs[0].Address, blockNum)
+		default:
+			require.NotEmpty(receipts, blockNum)
+			require.Equal(other, receipts[0].Logs[0].Address, blockNum)
+		}
+	}
+	for i := uint64(0); i < 100; i++ {
+		checkBlock(i, i < 50)
+	}
+	// Only the pruned blocks are removed from the indexes, the ones with kept logs stay
+	checkIndexes := func(pruneTo uint64) {
+		expect := map[string]*roaring.Bitmap{
+			string(kept[:]):                   roaring.New(),
+			string(other[:]):                  roaring.New(),
+			string(libcommon.Hash{1}.Bytes()): roaring.New(),
+			string(libcommon.Hash{2}.Bytes()): roaring.New(),
+		}
+		for i := uint32(0); i < 100; i++ {
+			if i%10 == 0 {
+				expect[string(kept[:])].Add(i)
+				expect[string(libcommon.Hash{1}.Bytes())].Add(i)
+				expect[string(libcommon.Hash{2}.Bytes())].Add(i)
+			}
+			if uint64(i) >= pruneTo {
+				expect[string(other[:])].Add(i)
+				expect[string(libcommon.Hash{1}.Bytes())].Add(i)
+			}
+		}
+		for key, want := range expect {
+			table := kv.LogAddressIndex
+			if len(key) == length.Hash {
+				table = kv.LogTopicIndex
+			}
+			m, err := bitmapdb.Get(tx, table, []byte(key), 0, 10_000_000)
+			require.NoError(err)
+			require.Equal(want.ToArray(), m.ToArray(), "%x", key)
+		}
+	}
+	checkIndexes(50)
+
+	// Next pruning starts from the previous horizon
+	s.PruneProgress, s.ForwardProgress = s.ForwardProgress, 110
+	require.NoError(pruneLogIndex("", tx, tmpDir, prunedTo(s, pm.Receipts), pm.Receipts.PruneTo(s.ForwardProgress), pm.KeepLogs, ctx))
+	require.NoError(pruneReceipts(s, tx, pm, ctx))
+	for i := uint64(0); i < 100; i++ {
+		checkBlock(i, i < 60)
+	}
+	checkIndexes(60)
+}
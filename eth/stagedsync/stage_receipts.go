@@ -0,0 +1,327 @@
+package stagedsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/stages/receiptdownload"
+)
+
+type ReceiptsCfg struct {
+	db             kv.RwDB
+	rd             *receiptdownload.ReceiptDownload
+	receiptReqSend func(context.Context, *receiptdownload.ReceiptRequest) ([64]byte, bool)
+	timeout        int
+	prune          prune.Mode
+	blockReader    services.FullBlockReader
+	download       bool
+	historyV3      bool
+}
+
+func StageReceiptsCfg(db kv.RwDB, rd *receiptdownload.ReceiptDownload, receiptReqSend func(context.Context, *receiptdownload.ReceiptRequest) ([64]byte, bool), timeout int, prune prune.Mode, blockReader services.FullBlockReader, download bool, historyV3 bool) ReceiptsCfg {
+	return ReceiptsCfg{db: db, rd: rd, receiptReqSend: receiptReqSend, timeout: timeout, prune: prune, blockReader: blockReader, download: download, historyV3: historyV3}
+}
+
+// downloading tells if receipts are downloaded from peers, in which case blocks aren't executed
+func (cfg ReceiptsCfg) downloading() bool {
+	return cfg.download && !cfg.historyV3 && !cfg.rd.FellBack()
+}
+
+// ReceiptsForward downloads the receipts of the blocks which weren't executed, and checks them against the ReceiptHash
+// of the headers. If the peers don't serve the receipts of a block, it gives up and lets the Execution stage produce
+// the receipts of the following blocks.
+func ReceiptsForward(
+	s *StageState,
+	ctx context.Context,
+	tx kv.RwTx,
+	cfg ReceiptsCfg,
+	quiet bool,
+) error {
+	if !cfg.downloading() {
+		return nil
+	}
+	var err error
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	sendersProgress, err := stages.GetStageProgress(tx, stages.Senders)
+	if err != nil {
+		return err
+	}
+	executionProgress, err := s.ExecutionAt(tx)
+	if err != nil {
+		return err
+	}
+	// Executed blocks already have their receipts, and there is no point downloading the ones which would be pruned,
+	// unless some of their logs are to be kept
+	progress := cmp.Max(s.BlockNumber, executionProgress)
+	if cfg.prune.Receipts.Enabled() && !cfg.prune.KeepLogs.Enabled() {
+		progress = cmp.Max(progress, cfg.prune.Receipts.PruneTo(sendersProgress))
+	}
+	if progress >= sendersProgress {
+		return nil
+	}
+
+	logPrefix := s.LogPrefix()
+	timeout := cfg.timeout
+	if sendersProgress <= progress+16 {
+		// When processing small number of blocks, we can afford wasting more bandwidth but get receipts quicker
+		timeout = 1
+	} else if !quiet {
+		log.Info(fmt.Sprintf("[%s] Downloading receipts...", logPrefix), "from", progress, "to", sendersProgress)
+	}
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+	timer := time.NewTimer(1 * time.Second) // Check periodically even in the absence of incoming messages
+	defer timer.Stop()
+
+	cfg.rd.Reset(progress+1, sendersProgress)
+	var prevDeliveredCount, prevWastedCount float64
+	var notServed error
+	stopped := false
+	for !stopped {
+		// Deliveries go first, for the blocks the peers answered without to be requested again right away
+		cfg.rd.GetDeliveries()
+		currentTime := uint64(time.Now().Unix())
+		for i := 0; i <= requestLoopCutOff; i++ {
+			req, err := cfg.rd.RequestMoreReceipts(tx, cfg.blockReader, currentTime)
+			if errors.Is(err, receiptdownload.ErrNotServed) {
+				notServed = err
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("request more receipts: %w", err)
+			}
+			if req == nil {
+				break
+			}
+			// Requests which no peer took are retried after the timeout as well, counting as failed attempts
+			peer, sentToPeer := cfg.receiptReqSend(ctx, req)
+			cfg.rd.RequestSent(req, currentTime+uint64(timeout), peer)
+			if !sentToPeer {
+				break
+			}
+		}
+
+		written := progress
+		for {
+			blockNum, receipts, ok := cfg.rd.NextReceipts()
+			if !ok {
+				break
+			}
+			if cfg.prune.Receipts.Enabled() && blockNum <= cfg.prune.Receipts.PruneTo(sendersProgress) {
+				// Past the pruning horizon only the receipts of the blocks with the logs to keep are written
+				if receipts = cfg.prune.KeepLogs.FilterReceipts(receipts); receipts == nil {
+					progress = blockNum
+					continue
+				}
+			}
+			if err = rawdb.WriteReceipts(tx, blockNum, receipts); err != nil {
+				return err
+			}
+			progress = blockNum
+		}
+		if progress > written {
+			if err = s.Update(tx, progress); err != nil {
+				return fmt.Errorf("saving Receipts progress: %w", err)
+			}
+		}
+
+		if progress == sendersProgress {
+			break
+		}
+		if notServed != nil {
+			log.Warn(fmt.Sprintf("[%s] Peers don't serve receipts, falling back to execution", logPrefix), "block", progress, "err", notServed)
+			cfg.rd.FallBack()
+			break
+		}
+
+		timer.Reset(1 * time.Second)
+		select {
+		case <-ctx.Done():
+			stopped = true
+		case <-logEvery.C:
+			deliveredCount, wastedCount := cfg.rd.DeliveryCounts()
+			if !quiet {
+				logDownloadingReceipts(logPrefix, progress, sendersProgress, prevDeliveredCount, deliveredCount, prevWastedCount, wastedCount)
+			}
+			prevDeliveredCount, prevWastedCount = deliveredCount, wastedCount
+		case <-timer.C:
+			log.Trace("RequestQueueTime (receipts) ticked")
+		case <-cfg.rd.DeliveryNotify:
+			log.Trace("receiptLoop woken up by the incoming request")
+		}
+	}
+
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	if stopped {
+		return libcommon.ErrStopped
+	}
+	return nil
+}
+
+func logDownloadingReceipts(logPrefix string, committed, target uint64, prevDeliveredCount, deliveredCount, prevWastedCount, wastedCount float64) {
+	speed := (deliveredCount - prevDeliveredCount) / float64(logInterval/time.Second)
+	wastedSpeed := (wastedCount - prevWastedCount) / float64(logInterval/time.Second)
+	log.Info(fmt.Sprintf("[%s] Downloading receipts", logPrefix),
+		"block_num", committed,
+		"delivery/sec", libcommon.ByteCount(uint64(speed)),
+		"wasted/sec", libcommon.ByteCount(uint64(wastedSpeed)),
+		"remaining", target-committed,
+	)
+}
+
+func UnwindReceiptsStage(u *UnwindState, s *StageState, tx kv.RwTx, cfg ReceiptsCfg, ctx context.Context) (err error) {
+	if u.UnwindPoint >= s.BlockNumber {
+		return nil
+	}
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	if err = rawdb.TruncateReceipts(tx, u.UnwindPoint+1); err != nil {
+		return fmt.Errorf("truncate receipts: %w", err)
+	}
+	if err = u.Done(tx); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func PruneReceiptsStage(s *PruneState, tx kv.RwTx, cfg ReceiptsCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	if cfg.prune.Receipts.Enabled() {
+		if err = pruneReceipts(s, tx, cfg.prune, ctx); err != nil {
+			return err
+		}
+	}
+	if err = s.Done(tx); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneReceipts deletes the receipts and the logs of the blocks before the pruning horizon. The logs kept by
+// prune.Mode.KeepLogs stay, together with the receipts of their blocks, and as they stay forever, the blocks
+// before the previous horizon are not visited again.
+func pruneReceipts(s *PruneState, tx kv.RwTx, pruneMode prune.Mode, ctx context.Context) error {
+	pruneTo := pruneMode.Receipts.PruneTo(s.ForwardProgress)
+	if !pruneMode.KeepLogs.Enabled() {
+		if err := rawdb.PruneTable(tx, kv.Receipts, pruneTo, ctx, math.MaxInt32); err != nil {
+			return err
+		}
+		// LogIndex.Prune will read everything what not pruned here
+		return rawdb.PruneTable(tx, kv.Log, pruneTo, ctx, math.MaxInt32)
+	}
+
+	receipts, err := tx.RwCursor(kv.Receipts)
+	if err != nil {
+		return err
+	}
+	defer receipts.Close()
+	logs, err := tx.RwCursor(kv.Log)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	reader := bytes.NewReader(nil)
+	for k, _, err := receipts.Seek(hexutility.EncodeTs(prunedTo(s, pruneMode.Receipts))); k != nil; k, _, err = receipts.Next() {
+		if err != nil {
+			return err
+		}
+		blockNum := binary.BigEndian.Uint64(k)
+		if blockNum >= pruneTo {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return libcommon.ErrStopped
+		default:
+		}
+
+		keepReceipts := false
+		for lk, lv, err := logs.Seek(k); lk != nil && bytes.HasPrefix(lk, k); lk, lv, err = logs.Next() {
+			if err != nil {
+				return err
+			}
+			var txLogs types.Logs
+			reader.Reset(lv)
+			if err = cbor.Unmarshal(&txLogs, reader); err != nil {
+				return fmt.Errorf("logs unmarshal failed: %w, block=%d", err, blockNum)
+			}
+			if pruneMode.KeepLogs.KeepLogs(txLogs) {
+				keepReceipts = true
+				continue
+			}
+			if err = logs.DeleteCurrent(); err != nil {
+				return fmt.Errorf("failed to remove logs for block %d: %w", blockNum, err)
+			}
+		}
+		if keepReceipts {
+			continue
+		}
+		if err = receipts.DeleteCurrent(); err != nil {
+			return fmt.Errorf("failed to remove receipts for block %d: %w", blockNum, err)
+		}
+	}
+	return nil
+}
+
+// prunedTo is the pruning horizon of the previous pruning of the stage
+func prunedTo(s *PruneState, amount prune.BlockAmount) uint64 {
+	if s.PruneProgress == 0 {
+		return 0
+	}
+	return amount.PruneTo(s.PruneProgress)
+}
@@ -55,7 +55,7 @@ func SpawnTxLookup(s *StageState, tx kv.RwTx, toBlock uint64, cfg TxLookupCfg, c
 		defer tx.Rollback()
 	}
 	logPrefix := s.LogPrefix()
-	endBlock, err := s.ExecutionAt(tx)
+	endBlock, err := s.ReceiptsAt(tx)
 	if err != nil {
 		return err
 	}
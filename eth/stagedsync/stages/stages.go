@@ -35,6 +35,7 @@ var (
 	BlockHashes         SyncStage = "BlockHashes"     // Headers Number are written, fills blockHash => number bucket
 	Bodies              SyncStage = "Bodies"          // Block bodies are downloaded, TxHash and UncleHash are getting verified
 	Senders             SyncStage = "Senders"         // "From" recovered from signatures, bodies re-written
+	Receipts            SyncStage = "Receipts"        // Receipts are downloaded instead of being produced by execution, their ReceiptHash is verified
 	Execution           SyncStage = "Execution"       // Executing each block w/o buildinf a trie
 	Translation         SyncStage = "Translation"     // Translation each marked for translation contract (from EVM to TEVM)
 	VerkleTrie          SyncStage = "VerkleTrie"
@@ -64,6 +65,7 @@ var AllStages = []SyncStage{
 	BlockHashes,
 	Bodies,
 	Senders,
+	Receipts,
 	Execution,
 	Translation,
 	HashState,
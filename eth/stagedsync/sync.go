@@ -24,6 +24,9 @@ type Sync struct {
 	currentStage uint
 	timings      []Timing
 	logPrefixes  []string
+
+	status       *SyncStatus
+	unwindReason string
 }
 
 type Timing struct {
@@ -35,6 +38,7 @@ type Timing struct {
 
 func (s *Sync) Len() int                 { return len(s.stages) }
 func (s *Sync) PrevUnwindPoint() *uint64 { return s.prevUnwindPoint }
+func (s *Sync) Status() *SyncStatus      { return s.status }
 
 func (s *Sync) NewUnwindState(id stages.SyncStage, unwindPoint, currentProgress uint64) *UnwindState {
 	return &UnwindState{id, unwindPoint, currentProgress, libcommon.Hash{}, s}
@@ -109,6 +113,15 @@ func (s *Sync) UnwindTo(unwindPoint uint64, badBlock libcommon.Hash) {
 	log.Info("UnwindTo", "block", unwindPoint, "bad_block_hash", badBlock.String())
 	s.unwindPoint = &unwindPoint
 	s.badBlock = badBlock
+	s.unwindReason = s.status.unwindReason(badBlock)
+}
+
+// reportProgress updates the live status of the stage, see StageState.Update
+func (s *Sync) reportProgress(id stages.SyncStage, blockNum uint64) {
+	if s == nil {
+		return
+	}
+	s.status.stageProgressed(id, blockNum, time.Now())
 }
 
 func (s *Sync) IsDone() bool {
@@ -162,6 +175,7 @@ func New(stagesList []*Stage, unwindOrder UnwindOrder, pruneOrder PruneOrder) *S
 		unwindOrder:  unwindStages,
 		pruningOrder: pruneStages,
 		logPrefixes:  logPrefixes,
+		status:       newSyncStatus(stagesList),
 	}
 }
 
@@ -193,6 +207,9 @@ func (s *Sync) RunUnwind(db kv.RwDB, tx kv.RwTx) error {
 	if s.unwindPoint == nil {
 		return nil
 	}
+	if err := s.status.init(tx, db); err != nil {
+		return err
+	}
 	for j := 0; j < len(s.unwindOrder); j++ {
 		if s.unwindOrder[j] == nil || s.unwindOrder[j].Disabled || s.unwindOrder[j].Unwind == nil {
 			continue
@@ -212,6 +229,9 @@ func (s *Sync) RunUnwind(db kv.RwDB, tx kv.RwTx) error {
 func (s *Sync) Run(db kv.RwDB, tx kv.RwTx, firstCycle bool, quiet bool) error {
 	s.prevUnwindPoint = nil
 	s.timings = s.timings[:0]
+	if err := s.status.init(tx, db); err != nil {
+		return err
+	}
 
 	for !s.IsDone() {
 		var badBlockUnwind bool
@@ -354,6 +374,8 @@ func (s *Sync) runStage(stage *Stage, db kv.RwDB, tx kv.RwTx, firstCycle bool, b
 	if err != nil {
 		return err
 	}
+	s.status.stageStarted(stage.ID, stageState.BlockNumber, start)
+	defer func() { s.status.stageFinished(stage.ID, time.Now()) }()
 
 	if err = stage.Forward(firstCycle, badBlockUnwind, stageState, s, tx, quiet); err != nil {
 		wrappedError := fmt.Errorf("[%s] %w", s.LogPrefix(), err)
@@ -396,6 +418,8 @@ func (s *Sync) unwindStage(firstCycle bool, stage *Stage, db kv.RwDB, tx kv.RwTx
 		return fmt.Errorf("[%s] %w", s.LogPrefix(), err)
 	}
 
+	s.status.stageUnwound(stage.ID, unwind.UnwindPoint, s.unwindReason, time.Now())
+
 	took := time.Since(start)
 	if took > 60*time.Second {
 		logPrefix := s.LogPrefix()
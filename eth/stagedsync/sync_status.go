@@ -0,0 +1,218 @@
+package stagedsync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+)
+
+const (
+	statusSampleInterval = 10 * time.Second // Throughput is sampled at most that often while a stage runs, and when it finishes
+	statusHistoryLen     = 64               // Number of throughput samples kept per stage
+)
+
+// StageStatus is the live state of a stage of the staged sync
+type StageStatus struct {
+	Stage        stages.SyncStage
+	Running      bool
+	CurrentBlock uint64
+	// TargetBlock is the highest block the stage can move forward to, which is the progress of the stages before it.
+	// Stages without stages before them have their own progress as target.
+	TargetBlock    uint64
+	ItemsPerSecond float64        // Blocks processed per second, measured by the last throughput sample
+	Eta            *time.Duration // Estimated time to reach the target, nil when it can't be estimated
+
+	LastUnwindPoint  *uint64
+	LastUnwindTime   time.Time
+	LastUnwindReason string
+
+	History []ThroughputSample // Oldest first
+}
+
+type ThroughputSample struct {
+	Time           time.Time
+	Block          uint64
+	ItemsPerSecond float64
+}
+
+// SyncStatus tracks the state of the stages of a staged sync, to be read while the sync is running
+type SyncStatus struct {
+	lock        sync.RWMutex
+	stages      []*StageStatus // In the forward order
+	byID        map[stages.SyncStage]*StageStatus
+	samples     map[stages.SyncStage]ThroughputSample // Last sample of every stage, even when it isn't in the history
+	running     *StageStatus
+	initialised bool
+}
+
+func newSyncStatus(stagesList []*Stage) *SyncStatus {
+	s := &SyncStatus{
+		byID:    make(map[stages.SyncStage]*StageStatus, len(stagesList)),
+		samples: make(map[stages.SyncStage]ThroughputSample, len(stagesList)),
+	}
+	for _, stage := range stagesList {
+		st := &StageStatus{Stage: stage.ID}
+		s.stages = append(s.stages, st)
+		s.byID[stage.ID] = st
+	}
+	return s
+}
+
+var publishedStatus atomic.Value
+
+// PublishStatus makes the status of the sync available to the RPC and the diagnostics running in the same process.
+// Only the main staged sync of the process is to be published.
+func (s *Sync) PublishStatus() { publishedStatus.Store(s.status) }
+
+// PublishedStatus returns the status of the main staged sync of the process, or nil if there is none
+func PublishedStatus() *SyncStatus {
+	status, _ := publishedStatus.Load().(*SyncStatus)
+	return status
+}
+
+// Stages returns the state of the stages in the forward order, nil until the sync starts
+func (s *SyncStatus) Stages() []StageStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if !s.initialised {
+		return nil
+	}
+	res := make([]StageStatus, len(s.stages))
+	for i, st := range s.stages {
+		res[i] = *st
+		res[i].History = append([]ThroughputSample(nil), st.History...)
+		if st.TargetBlock > st.CurrentBlock && st.ItemsPerSecond > 0 {
+			eta := time.Duration(float64(st.TargetBlock-st.CurrentBlock) / st.ItemsPerSecond * float64(time.Second))
+			res[i].Eta = &eta
+		}
+	}
+	return res
+}
+
+// init reads the progress of the stages from the database, for the stages which didn't run yet to have it
+func (s *SyncStatus) init(tx kv.Tx, db kv.RoDB) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.initialised {
+		return nil
+	}
+	read := func(tx kv.Tx) error {
+		for _, st := range s.stages {
+			progress, err := stages.GetStageProgress(tx, st.Stage)
+			if err != nil {
+				return err
+			}
+			st.CurrentBlock, st.TargetBlock = progress, progress
+		}
+		return nil
+	}
+	if tx != nil {
+		if err := read(tx); err != nil {
+			return err
+		}
+	} else if err := db.View(context.Background(), read); err != nil {
+		return err
+	}
+	s.initialised = true
+	return nil
+}
+
+func (s *SyncStatus) stageStarted(id stages.SyncStage, current uint64, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st, ok := s.byID[id]
+	if !ok {
+		return
+	}
+	st.Running, st.CurrentBlock, st.TargetBlock = true, current, current
+	for _, prev := range s.stages {
+		if prev == st {
+			break
+		}
+		if prev.CurrentBlock > st.TargetBlock {
+			st.TargetBlock = prev.CurrentBlock
+		}
+	}
+	s.running = st
+	s.samples[id] = ThroughputSample{Time: now, Block: current}
+}
+
+func (s *SyncStatus) stageProgressed(id stages.SyncStage, block uint64, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st, ok := s.byID[id]
+	if !ok {
+		return
+	}
+	st.CurrentBlock = block
+	if block > st.TargetBlock {
+		st.TargetBlock = block
+	}
+	if now.Sub(s.samples[id].Time) >= statusSampleInterval {
+		s.sample(st, now)
+	}
+}
+
+func (s *SyncStatus) stageFinished(id stages.SyncStage, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st, ok := s.byID[id]
+	if !ok {
+		return
+	}
+	if st.CurrentBlock > s.samples[id].Block {
+		s.sample(st, now)
+	}
+	st.Running = false
+	s.running = nil
+}
+
+func (s *SyncStatus) sample(st *StageStatus, now time.Time) {
+	prev := s.samples[st.Stage]
+	var itemsPerSecond float64
+	if elapsed := now.Sub(prev.Time).Seconds(); elapsed > 0 && st.CurrentBlock > prev.Block {
+		itemsPerSecond = float64(st.CurrentBlock-prev.Block) / elapsed
+	}
+	sample := ThroughputSample{Time: now, Block: st.CurrentBlock, ItemsPerSecond: itemsPerSecond}
+	st.ItemsPerSecond = itemsPerSecond
+	if len(st.History) == statusHistoryLen {
+		st.History = append(st.History[:0], st.History[1:]...)
+	}
+	st.History = append(st.History, sample)
+	s.samples[st.Stage] = sample
+}
+
+func (s *SyncStatus) stageUnwound(id stages.SyncStage, unwindPoint uint64, reason string, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st, ok := s.byID[id]
+	if !ok {
+		return
+	}
+	st.CurrentBlock = unwindPoint
+	st.LastUnwindPoint = &unwindPoint
+	st.LastUnwindTime = now
+	st.LastUnwindReason = reason
+	s.samples[id] = ThroughputSample{Time: now, Block: unwindPoint}
+}
+
+// unwindReason describes why the unwind to the block is requested
+func (s *SyncStatus) unwindReason(badBlock libcommon.Hash) string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	by := "the sync loop"
+	if s.running != nil {
+		by = fmt.Sprintf("the %s stage", s.running.Stage)
+	}
+	if badBlock != (libcommon.Hash{}) {
+		return fmt.Sprintf("bad block %x found by %s", badBlock, by)
+	}
+	return "requested by " + by
+}
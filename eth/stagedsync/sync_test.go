@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -617,3 +618,81 @@ func TestSyncInterruptLongUnwind(t *testing.T) {
 func unwindOf(s stages.SyncStage) stages.SyncStage {
 	return stages.SyncStage(append([]byte(s), 0xF0))
 }
+
+func TestSyncStatus(t *testing.T) {
+	unwound := false
+	s := []*Stage{
+		{
+			ID:          stages.Headers,
+			Description: "Downloading headers",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if s.BlockNumber < 2000 {
+					return s.Update(tx, 2000)
+				}
+				return nil
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return u.Done(tx)
+			},
+		},
+		{
+			ID:          stages.Bodies,
+			Description: "Downloading block bodiess",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx, quiet bool) error {
+				if !unwound {
+					unwound = true
+					u.UnwindTo(500, libcommon.Hash{1})
+					return s.Update(tx, 1000)
+				}
+				return s.Update(tx, 1500)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return u.Done(tx)
+			},
+		},
+	}
+	state := New(s, []stages.SyncStage{s[1].ID, s[0].ID}, nil)
+	assert.Nil(t, state.Status().Stages())
+	db, tx := memdb.NewTestTx(t)
+	err := state.Run(db, tx, true /* initialCycle */, false /* quiet */)
+	assert.NoError(t, err)
+
+	// The sync stops after the unwind caused by the bad block
+	statuses := state.Status().Stages()
+	assert.Equal(t, 2, len(statuses))
+	for _, st := range statuses {
+		assert.Equal(t, 500, int(st.CurrentBlock))
+	}
+	err = state.Run(db, tx, false /* initialCycle */, false /* quiet */)
+	assert.NoError(t, err)
+
+	statuses = state.Status().Stages()
+	headers, bodies := statuses[0], statuses[1]
+	assert.Equal(t, stages.Headers, headers.Stage)
+	assert.False(t, headers.Running)
+	assert.Equal(t, 2000, int(headers.CurrentBlock))
+	assert.Equal(t, 2000, int(headers.TargetBlock))
+	assert.Equal(t, stages.Bodies, bodies.Stage)
+	assert.Equal(t, 1500, int(bodies.CurrentBlock))
+	assert.Equal(t, 2000, int(bodies.TargetBlock))
+	for _, st := range statuses {
+		if assert.NotNil(t, st.LastUnwindPoint) {
+			assert.Equal(t, 500, int(*st.LastUnwindPoint))
+			assert.Equal(t, fmt.Sprintf("bad block %x found by the Bodies stage", libcommon.Hash{1}), st.LastUnwindReason)
+		}
+	}
+
+	// Throughput is sampled as the stage progresses
+	start := time.Now()
+	state.status.stageStarted(stages.Bodies, 1500, start)
+	state.status.stageProgressed(stages.Bodies, 1550, start.Add(time.Second))
+	state.status.stageProgressed(stages.Bodies, 1600, start.Add(statusSampleInterval))
+	bodies = state.Status().Stages()[1]
+	assert.True(t, bodies.Running)
+	assert.Equal(t, 1600, int(bodies.CurrentBlock))
+	assert.Equal(t, 10.0, bodies.ItemsPerSecond)
+	if assert.NotNil(t, bodies.Eta) {
+		assert.Equal(t, 40*time.Second, *bodies.Eta)
+	}
+	assert.Equal(t, 1, len(bodies.History)-len(statuses[1].History))
+}
@@ -16,6 +16,7 @@ type TraceConfig struct {
 	Reexec         *uint64
 	NoRefunds      *bool // Turns off gas refunds when tracing
 	StateOverrides *ethapi.StateOverrides
+	Combine        *bool // Combines the results of the transactions of a block or bundle into one
 
 	BorTraceEnabled *bool
 	BorTx           *bool
@@ -0,0 +1,123 @@
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/tests"
+)
+
+// tracerTest is a fixture of a tracer whose result isn't a callTrace, kept as raw json.
+type tracerTest struct {
+	Genesis      *core.Genesis   `json:"genesis"`
+	Context      *callContext    `json:"context"`
+	Input        string          `json:"input"`
+	TracerConfig json.RawMessage `json:"tracerConfig"`
+	Result       json.RawMessage `json:"result"`
+}
+
+// readTracerTests reads the fixtures of the given directory, by test name.
+func readTracerTests(t *testing.T, dirPath string) map[string]*tracerTest {
+	t.Helper()
+	files, err := os.ReadDir(filepath.Join("testdata", dirPath))
+	require.NoError(t, err)
+	result := make(map[string]*tracerTest)
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		blob, err := os.ReadFile(filepath.Join("testdata", dirPath, file.Name()))
+		require.NoError(t, err)
+		test := new(tracerTest)
+		require.NoError(t, json.Unmarshal(blob, test))
+		result[camel(strings.TrimSuffix(file.Name(), ".json"))] = test
+	}
+	return result
+}
+
+// runTracer executes the transaction of a fixture with the given tracer.
+func runTracer(t *testing.T, test *tracerTest, tracerName string, ctx *tracers.Context, cfg json.RawMessage) json.RawMessage {
+	t.Helper()
+	tx, err := types.UnmarshalTransactionFromBinary(common.FromHex(test.Input))
+	require.NoError(t, err)
+	var (
+		signer    = types.MakeSigner(test.Genesis.Config, uint64(test.Context.Number))
+		origin, _ = signer.Sender(tx)
+		txContext = evmtypes.TxContext{
+			Origin:   origin,
+			GasPrice: tx.GetPrice(),
+		}
+		context = evmtypes.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Coinbase:    test.Context.Miner,
+			BlockNumber: uint64(test.Context.Number),
+			Time:        uint64(test.Context.Time),
+			Difficulty:  (*big.Int)(test.Context.Difficulty),
+			GasLimit:    uint64(test.Context.GasLimit),
+		}
+		_, dbTx    = memdb.NewTestTx(t)
+		rules      = test.Genesis.Config.Rules(context.BlockNumber, context.Time)
+		statedb, _ = tests.MakePreState(rules, dbTx, test.Genesis.Alloc, uint64(test.Context.Number))
+	)
+	if test.Genesis.BaseFee != nil {
+		context.BaseFee, _ = uint256.FromBig(test.Genesis.BaseFee)
+	}
+	tracer, err := tracers.New(tracerName, ctx, cfg)
+	require.NoError(t, err)
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer}, firehose.NoOpContext)
+	msg, err := tx.AsMessage(*signer, test.Genesis.BaseFee, rules)
+	require.NoError(t, err)
+	_, err = core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.GetGas()), true /* refunds */, false /* gasBailout */)
+	require.NoError(t, err)
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+	return res
+}
+
+func TestFlatCallTracerNative(t *testing.T) {
+	for name, test := range readTracerTests(t, "flat_call_tracer") {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			tx, err := types.UnmarshalTransactionFromBinary(common.FromHex(test.Input))
+			require.NoError(t, err)
+			ctx := &tracers.Context{
+				BlockHash:   libcommon.HexToHash("0x01"),
+				BlockNumber: uint64(test.Context.Number),
+				TxIndex:     3,
+				TxHash:      tx.Hash(),
+			}
+
+			res := runTracer(t, test, "flatCallTracer", ctx, test.TracerConfig)
+			require.JSONEq(t, string(test.Result), string(res))
+		})
+	}
+}
+
+func TestFlatCallTracerWithMux(t *testing.T) {
+	test := readTracerTests(t, "token_transfer_tracer")[camel("multi_contracts")]
+	ctx := new(tracers.Context)
+
+	res := runTracer(t, test, "muxTracer", ctx, json.RawMessage(`{"flatCallTracer":{},"tokenTransferTracer":{}}`))
+	var mux map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(res, &mux))
+	require.JSONEq(t, string(runTracer(t, test, "flatCallTracer", ctx, nil)), string(mux["flatCallTracer"]))
+	require.JSONEq(t, string(runTracer(t, test, "tokenTransferTracer", ctx, nil)), string(mux["tokenTransferTracer"]))
+}
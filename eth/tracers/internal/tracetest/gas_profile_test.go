@@ -0,0 +1,304 @@
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/tests"
+)
+
+type gasProfileAccess struct {
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+type gasProfileOpcode struct {
+	Count uint64            `json:"count"`
+	Gas   uint64            `json:"gas"`
+	Warm  *gasProfileAccess `json:"warm"`
+	Cold  *gasProfileAccess `json:"cold"`
+}
+
+type gasProfileResult struct {
+	Transactions uint64 `json:"transactions"`
+	GasUsed      uint64 `json:"gasUsed"`
+	IntrinsicGas uint64 `json:"intrinsicGas"`
+	ExecutionGas uint64 `json:"executionGas"`
+	Refund       uint64 `json:"refund"`
+	Contracts    []struct {
+		Address  libcommon.Address            `json:"address"`
+		Selector string                       `json:"selector"`
+		Create   bool                         `json:"create"`
+		Calls    uint64                       `json:"calls"`
+		Gas      uint64                       `json:"gas"`
+		Opcodes  map[string]*gasProfileOpcode `json:"opcodes"`
+	} `json:"contracts"`
+	Depths []struct {
+		Depth int    `json:"depth"`
+		Calls uint64 `json:"calls"`
+		Gas   uint64 `json:"gas"`
+	} `json:"depths"`
+}
+
+// TestGasProfileTracer tests that the gas of the profiles of the callTracer fixtures adds up to the gas
+// used by their transactions.
+func TestGasProfileTracer(t *testing.T) {
+	for _, dirPath := range []string{"call_tracer", "call_tracer_withLog"} {
+		for name, test := range readTracerTests(t, dirPath) {
+			test := test
+			t.Run(dirPath+"/"+name, func(t *testing.T) {
+				t.Parallel()
+				var call callTrace
+				require.NoError(t, json.Unmarshal(test.Result, &call))
+				var profile gasProfileResult
+				require.NoError(t, json.Unmarshal(runTracer(t, test, "gasProfileTracer", nil, nil), &profile))
+
+				require.Equal(t, uint64(*call.GasUsed), profile.GasUsed)
+				require.Equal(t, profile.GasUsed, profile.IntrinsicGas+profile.ExecutionGas-profile.Refund)
+				var contractsGas, depthsGas uint64
+				for _, contract := range profile.Contracts {
+					contractsGas += contract.Gas
+					if len(contract.Opcodes) == 0 {
+						continue // Precompiled contracts run no opcodes
+					}
+					var opcodesGas uint64
+					for _, opcode := range contract.Opcodes {
+						opcodesGas += opcode.Gas
+					}
+					require.Equal(t, contract.Gas, opcodesGas, "contract %x", contract.Address)
+				}
+				for i, depth := range profile.Depths {
+					require.Equal(t, i, depth.Depth)
+					depthsGas += depth.Gas
+				}
+				require.Equal(t, profile.ExecutionGas, contractsGas)
+				require.Equal(t, profile.ExecutionGas, depthsGas)
+			})
+		}
+	}
+}
+
+// TestGasProfileTracerStorage tests the warm and cold split of the storage accesses: the contract reads
+// slot 0 twice, writes slot 1, then calls a function of another contract reading its slot 0.
+func TestGasProfileTracerStorage(t *testing.T) {
+	var (
+		to     = libcommon.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		callee = libcommon.HexToAddress("0x000000000000000000000000000000000000cafe")
+	)
+	privkey, err := crypto.HexToECDSA("0000000000000000deadbeef00000000000000000000000000000000deadbeef")
+	require.NoError(t, err)
+	signer := types.LatestSigner(params.MainnetChainConfig)
+	tx, err := types.SignNewTx(privkey, *signer, &types.LegacyTx{
+		GasPrice: uint256.NewInt(0),
+		CommonTx: types.CommonTx{
+			Gas: 100000,
+			To:  &to,
+		},
+	})
+	require.NoError(t, err)
+	origin, _ := signer.Sender(tx)
+	txContext := evmtypes.TxContext{
+		Origin:   origin,
+		GasPrice: uint256.NewInt(1),
+	}
+	context := evmtypes.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: params.MainnetChainConfig.BerlinBlock.Uint64(),
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+
+	code := []byte{
+		byte(vm.PUSH1), 0x0, byte(vm.SLOAD), byte(vm.POP), // cold
+		byte(vm.PUSH1), 0x0, byte(vm.SLOAD), byte(vm.POP), // warm
+		byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x1, byte(vm.SSTORE), // cold, from zero to non-zero
+		byte(vm.PUSH4), 0x12, 0x34, 0x56, 0x78, byte(vm.PUSH1), 0xe0, byte(vm.SHL), byte(vm.PUSH1), 0x0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.PUSH1), 0x4, byte(vm.PUSH1), 0x0, byte(vm.DUP1), // outs zero, ins 0:4, value zero
+		byte(vm.PUSH2), 0xca, 0xfe, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+	}
+	alloc := core.GenesisAlloc{
+		to: core.GenesisAccount{
+			Nonce: 1,
+			Code:  code,
+		},
+		callee: core.GenesisAccount{
+			Nonce: 1,
+			Code:  []byte{byte(vm.PUSH1), 0x0, byte(vm.SLOAD), byte(vm.POP)},
+		},
+		origin: core.GenesisAccount{
+			Balance: big.NewInt(500000000000000),
+		},
+	}
+	rules := params.MainnetChainConfig.Rules(context.BlockNumber, context.Time)
+	_, dbTx := memdb.NewTestTx(t)
+	statedb, _ := tests.MakePreState(rules, dbTx, alloc, context.BlockNumber)
+	tracer, err := tracers.New("gasProfileTracer", nil, nil)
+	require.NoError(t, err)
+	evm := vm.NewEVM(context, txContext, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer}, firehose.NoOpContext)
+	msg, err := tx.AsMessage(*signer, nil, rules)
+	require.NoError(t, err)
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.GetGas()))
+	res, err := st.TransitionDb(true /* refunds */, false /* gasBailout */)
+	require.NoError(t, err)
+
+	blob, err := tracer.GetResult()
+	require.NoError(t, err)
+	var profile gasProfileResult
+	require.NoError(t, json.Unmarshal(blob, &profile))
+	require.Equal(t, res.UsedGas, profile.GasUsed)
+	require.Equal(t, params.TxGas, profile.IntrinsicGas)
+	require.Zero(t, profile.Refund)
+
+	require.Len(t, profile.Contracts, 2)
+	caller, called := profile.Contracts[0], profile.Contracts[1]
+	require.Equal(t, to, caller.Address)
+	require.Empty(t, caller.Selector)
+	require.Equal(t, &gasProfileOpcode{Count: 2, Gas: 2200, Warm: &gasProfileAccess{1, 100}, Cold: &gasProfileAccess{1, 2100}}, caller.Opcodes["SLOAD"])
+	require.Equal(t, &gasProfileOpcode{Count: 1, Gas: 22100, Cold: &gasProfileAccess{1, 22100}}, caller.Opcodes["SSTORE"])
+	// The gas of the call includes the cold access to the callee, not the gas spent by the callee
+	require.Equal(t, uint64(params.ColdAccountAccessCostEIP2929), caller.Opcodes["CALL"].Gas)
+
+	require.Equal(t, callee, called.Address)
+	require.Equal(t, "0x12345678", called.Selector)
+	require.Equal(t, uint64(1), called.Calls)
+	require.Equal(t, &gasProfileOpcode{Count: 1, Gas: 2100, Cold: &gasProfileAccess{1, 2100}}, called.Opcodes["SLOAD"])
+	require.Equal(t, uint64(2100+3+2), called.Gas)
+
+	require.Len(t, profile.Depths, 2)
+	require.Equal(t, caller.Gas, profile.Depths[0].Gas)
+	require.Equal(t, called.Gas, profile.Depths[1].Gas)
+	require.Equal(t, profile.ExecutionGas, caller.Gas+called.Gas)
+}
+
+// TestGasProfileTracerCombined tests the profiles of two transactions calling a contract which reads its
+// slot 0, then calls twice another contract reading its slot 0 and reverting, and their combination. The
+// first transaction has no access list, so the reverted reads are cold both times. The second one has
+// the slots in its access list, so all the reads are warm.
+func TestGasProfileTracerCombined(t *testing.T) {
+	var (
+		to     = libcommon.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		callee = libcommon.HexToAddress("0x000000000000000000000000000000000000cafe")
+	)
+	privkey, err := crypto.HexToECDSA("0000000000000000deadbeef00000000000000000000000000000000deadbeef")
+	require.NoError(t, err)
+	signer := types.LatestSigner(params.MainnetChainConfig)
+	origin := crypto.PubkeyToAddress(privkey.PublicKey)
+	context := evmtypes.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: params.MainnetChainConfig.BerlinBlock.Uint64(),
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+
+	call := []byte{
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), // no input nor output, value zero
+		byte(vm.PUSH2), 0xca, 0xfe, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+	}
+	code := append([]byte{byte(vm.PUSH1), 0x0, byte(vm.SLOAD), byte(vm.POP)}, append(call, call...)...)
+	alloc := core.GenesisAlloc{
+		to: core.GenesisAccount{
+			Nonce: 1,
+			Code:  code,
+		},
+		callee: core.GenesisAccount{
+			Nonce: 1,
+			Code:  []byte{byte(vm.PUSH1), 0x0, byte(vm.SLOAD), byte(vm.POP), byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.REVERT)},
+		},
+		origin: core.GenesisAccount{
+			Balance: big.NewInt(500000000000000),
+		},
+	}
+	rules := params.MainnetChainConfig.Rules(context.BlockNumber, context.Time)
+	_, dbTx := memdb.NewTestTx(t)
+	statedb, _ := tests.MakePreState(rules, dbTx, alloc, context.BlockNumber)
+
+	common := types.CommonTx{Gas: 100000, To: &to}
+	accessListTx := &types.AccessListTx{
+		LegacyTx:   types.LegacyTx{CommonTx: common, GasPrice: uint256.NewInt(0)},
+		ChainID:    uint256.NewInt(params.MainnetChainConfig.ChainID.Uint64()),
+		AccessList: types2.AccessList{{Address: to, StorageKeys: []libcommon.Hash{{}}}, {Address: callee, StorageKeys: []libcommon.Hash{{}}}},
+	}
+	accessListTx.Nonce = 1
+	var (
+		results []json.RawMessage
+		gasUsed uint64
+	)
+	for _, unsigned := range []types.Transaction{&types.LegacyTx{CommonTx: common, GasPrice: uint256.NewInt(0)}, accessListTx} {
+		tx, err := types.SignNewTx(privkey, *signer, unsigned)
+		require.NoError(t, err)
+		msg, err := tx.AsMessage(*signer, nil, rules)
+		require.NoError(t, err)
+		tracer, err := tracers.New("gasProfileTracer", &tracers.Context{AccessList: msg.AccessList()}, nil)
+		require.NoError(t, err)
+		evm := vm.NewEVM(context, evmtypes.TxContext{Origin: origin, GasPrice: uint256.NewInt(1)}, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer}, firehose.NoOpContext)
+		st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.GetGas()))
+		res, err := st.TransitionDb(true /* refunds */, false /* gasBailout */)
+		require.NoError(t, err)
+		gasUsed += res.UsedGas
+
+		result, err := tracer.GetResult()
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+
+	cold := &gasProfileAccess{1, params.ColdSloadCostEIP2929}
+	warm := &gasProfileAccess{1, params.WarmStorageReadCostEIP2929}
+	checkProfile := func(result json.RawMessage, transactions uint64, callerSload, calleeSload *gasProfileOpcode) gasProfileResult {
+		var profile gasProfileResult
+		require.NoError(t, json.Unmarshal(result, &profile))
+		require.Equal(t, transactions, profile.Transactions)
+		require.Len(t, profile.Contracts, 2)
+		caller, called := profile.Contracts[0], profile.Contracts[1]
+		if caller.Address != to {
+			caller, called = called, caller
+		}
+		require.Equal(t, to, caller.Address)
+		require.Equal(t, transactions, caller.Calls)
+		require.Equal(t, callerSload, caller.Opcodes["SLOAD"])
+		require.Equal(t, callee, called.Address)
+		require.Equal(t, 2*transactions, called.Calls)
+		require.Equal(t, calleeSload, called.Opcodes["SLOAD"])
+		require.Equal(t, profile.ExecutionGas, caller.Gas+called.Gas)
+		require.Len(t, profile.Depths, 2)
+		require.Equal(t, 2*transactions, profile.Depths[1].Calls)
+		return profile
+	}
+	first := checkProfile(results[0], 1,
+		&gasProfileOpcode{Count: 1, Gas: cold.Gas, Cold: cold},
+		&gasProfileOpcode{Count: 2, Gas: 2 * cold.Gas, Cold: &gasProfileAccess{2, 2 * cold.Gas}})
+	second := checkProfile(results[1], 1,
+		&gasProfileOpcode{Count: 1, Gas: warm.Gas, Warm: warm},
+		&gasProfileOpcode{Count: 2, Gas: 2 * warm.Gas, Warm: &gasProfileAccess{2, 2 * warm.Gas}})
+
+	combine := tracers.Combiner("gasProfileTracer")
+	require.NotNil(t, combine)
+	combined, err := combine(results)
+	require.NoError(t, err)
+	profile := checkProfile(combined, 2,
+		&gasProfileOpcode{Count: 2, Gas: cold.Gas + warm.Gas, Warm: warm, Cold: cold},
+		&gasProfileOpcode{Count: 4, Gas: 2*cold.Gas + 2*warm.Gas, Warm: &gasProfileAccess{2, 2 * warm.Gas}, Cold: &gasProfileAccess{2, 2 * cold.Gas}})
+	require.Equal(t, gasUsed, profile.GasUsed)
+	require.Equal(t, first.IntrinsicGas+second.IntrinsicGas, profile.IntrinsicGas)
+	require.Equal(t, first.ExecutionGas+second.ExecutionGas, profile.ExecutionGas)
+	require.Equal(t, first.Depths[0].Gas+second.Depths[0].Gas, profile.Depths[0].Gas)
+	require.Equal(t, first.Depths[1].Gas+second.Depths[1].Gas, profile.Depths[1].Gas)
+}
@@ -0,0 +1,111 @@
+package tracetest
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/core/vm/evmtypes"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/tests"
+)
+
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+func TestTokenTransferTracer(t *testing.T) {
+	for name, test := range readTracerTests(t, "token_transfer_tracer") {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			res := runTracer(t, test, "tokenTransferTracer", nil, test.TracerConfig)
+			require.JSONEq(t, string(test.Result), string(res))
+		})
+	}
+}
+
+// TestTokenTransferTracerOrder tests that ETH and token transfers are reported in execution order: the
+// contract emits an ERC-20 transfer, sends 1 wei to 0xff, then emits an ERC-721 transfer.
+func TestTokenTransferTracerOrder(t *testing.T) {
+	var to = libcommon.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	privkey, err := crypto.HexToECDSA("0000000000000000deadbeef00000000000000000000000000000000deadbeef")
+	require.NoError(t, err)
+	signer := types.LatestSigner(params.MainnetChainConfig)
+	tx, err := types.SignNewTx(privkey, *signer, &types.LegacyTx{
+		GasPrice: uint256.NewInt(0),
+		CommonTx: types.CommonTx{
+			Gas:   100000,
+			To:    &to,
+			Value: uint256.NewInt(2),
+		},
+	})
+	require.NoError(t, err)
+	origin, _ := signer.Sender(tx)
+	txContext := evmtypes.TxContext{
+		Origin:   origin,
+		GasPrice: uint256.NewInt(1),
+	}
+	context := evmtypes.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: 8000000,
+		Time:        5,
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+
+	code := []byte{
+		byte(vm.PUSH1), 0x5, byte(vm.PUSH1), 0x0, byte(vm.MSTORE), // mem[0:32] = 5
+		byte(vm.PUSH1), 0xbb, byte(vm.PUSH1), 0xaa, byte(vm.PUSH32)}
+	code = append(code, transferTopic.Bytes()...)
+	code = append(code,
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x0, byte(vm.LOG3), // Transfer(0xaa, 0xbb, 5)
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), // in and outs zero
+		byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0xff, byte(vm.GAS), // value=1,address=0xff, gas=GAS
+		byte(vm.CALL), byte(vm.POP),
+		byte(vm.PUSH1), 0x7, byte(vm.PUSH1), 0xcc, byte(vm.PUSH1), 0xbb, byte(vm.PUSH32))
+	code = append(code, transferTopic.Bytes()...)
+	code = append(code,
+		byte(vm.PUSH1), 0x0, byte(vm.DUP1), byte(vm.LOG4), // Transfer(0xbb, 0xcc, 7)
+	)
+	alloc := core.GenesisAlloc{
+		to: core.GenesisAccount{
+			Nonce:   1,
+			Code:    code,
+			Balance: big.NewInt(1),
+		},
+		origin: core.GenesisAccount{
+			Balance: big.NewInt(500000000000000),
+		},
+	}
+	rules := params.MainnetChainConfig.Rules(context.BlockNumber, context.Time)
+	_, dbTx := memdb.NewTestTx(t)
+	statedb, _ := tests.MakePreState(rules, dbTx, alloc, context.BlockNumber)
+	tracer, err := tracers.New("tokenTransferTracer", nil, nil)
+	require.NoError(t, err)
+	evm := vm.NewEVM(context, txContext, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer}, firehose.NoOpContext)
+	msg, err := tx.AsMessage(*signer, nil, rules)
+	require.NoError(t, err)
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.GetGas()))
+	_, err = st.TransitionDb(true /* refunds */, false /* gasBailout */)
+	require.NoError(t, err)
+
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+	want := `[` +
+		`{"type":"eth","from":"0x682a80a6f560eec50d54e63cbeda1c324c5f8d1b","to":"0x00000000000000000000000000000000deadbeef","value":"0x2"},` +
+		`{"type":"erc20","token":"0x00000000000000000000000000000000deadbeef","from":"0x00000000000000000000000000000000000000aa","to":"0x00000000000000000000000000000000000000bb","value":"0x5"},` +
+		`{"type":"eth","from":"0x00000000000000000000000000000000deadbeef","to":"0x00000000000000000000000000000000000000ff","value":"0x1"},` +
+		`{"type":"erc721","token":"0x00000000000000000000000000000000deadbeef","from":"0x00000000000000000000000000000000000000bb","to":"0x00000000000000000000000000000000000000cc","tokenId":"0x7"}` +
+		`]`
+	require.Equal(t, want, string(res))
+}
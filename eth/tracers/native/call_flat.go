@@ -0,0 +1,316 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("flatCallTracer", newFlatCallTracer)
+}
+
+var parityErrorMapping = map[string]string{
+	"contract creation code storage out of gas": "Out of gas",
+	"out of gas":                      "Out of gas",
+	"gas uint64 overflow":             "Out of gas",
+	"max code size exceeded":          "Out of gas",
+	"invalid jump destination":        "Bad jump destination",
+	"execution reverted":              "Reverted",
+	"return data out of bounds":       "Out of bounds",
+	"stack limit reached 1024 (1023)": "Out of stack",
+	"precompiled failed":              "Built-in failed",
+	"invalid input length":            "Built-in failed",
+}
+
+var parityErrorMappingStartingWith = map[string]string{
+	"invalid opcode:": "Bad instruction",
+	"stack underflow": "Stack underflow",
+}
+
+// flatCallAction is the action of a Parity-style trace, the fields being set depending on its type.
+type flatCallAction struct {
+	SelfDestructed *libcommon.Address `json:"address,omitempty"`
+	Balance        *hexutil.Big       `json:"balance,omitempty"`
+	CallType       string             `json:"callType,omitempty"`
+	From           *libcommon.Address `json:"from,omitempty"`
+	Gas            *hexutil.Uint64    `json:"gas,omitempty"`
+	Init           *hexutil.Bytes     `json:"init,omitempty"`
+	Input          *hexutil.Bytes     `json:"input,omitempty"`
+	RefundAddress  *libcommon.Address `json:"refundAddress,omitempty"`
+	To             *libcommon.Address `json:"to,omitempty"`
+	Value          *hexutil.Big       `json:"value,omitempty"`
+}
+
+type flatCallResult struct {
+	Address *libcommon.Address `json:"address,omitempty"`
+	Code    *hexutil.Bytes     `json:"code,omitempty"`
+	GasUsed *hexutil.Uint64    `json:"gasUsed,omitempty"`
+	Output  *hexutil.Bytes     `json:"output,omitempty"`
+}
+
+// flatCallFrame is a Parity-style trace, as returned by trace_transaction.
+type flatCallFrame struct {
+	Action              flatCallAction  `json:"action"`
+	BlockHash           libcommon.Hash  `json:"blockHash"`
+	BlockNumber         uint64          `json:"blockNumber"`
+	Error               string          `json:"error,omitempty"`
+	Result              *flatCallResult `json:"result,omitempty"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     libcommon.Hash  `json:"transactionHash"`
+	TransactionPosition uint64          `json:"transactionPosition"`
+	Type                string          `json:"type"`
+}
+
+// flatCallTracer reports the calls of a transaction as a flat list of Parity-style traces. It
+// is built on top of callTracer, the nested call frames being flattened once the transaction ends.
+type flatCallTracer struct {
+	tracer *callTracer
+	config flatCallTracerConfig
+	ctx    *tracers.Context
+	// precompiles tells, for each frame entered, whether it's a call to a precompiled contract
+	precompiles []bool
+	interrupt   bool
+}
+
+type flatCallTracerConfig struct {
+	ConvertParityErrors bool `json:"convertParityErrors"` // If true, call tracer converts errors to parity format
+	IncludePrecompiles  bool `json:"includePrecompiles"`  // If true, call tracer includes calls to precompiled contracts
+}
+
+// newFlatCallTracer returns a new flatCallTracer.
+func newFlatCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config flatCallTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	tracer, err := newCallTracer(ctx, json.RawMessage(`{}`))
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = new(tracers.Context)
+	}
+
+	return &flatCallTracer{tracer: tracer.(*callTracer), config: config, ctx: ctx}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *flatCallTracer) CaptureStart(env vm.VMInterface, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.tracer.CaptureStart(env, from, to, precompile, create, input, gas, value, code)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *flatCallTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.tracer.CaptureEnd(output, gasUsed, err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *flatCallTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.tracer.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *flatCallTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	t.tracer.CaptureFault(pc, op, gas, cost, scope, depth, err)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *flatCallTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	if t.interrupt {
+		return
+	}
+	t.tracer.CaptureEnter(typ, from, to, precompile, create, input, gas, value, code)
+	t.precompiles = append(t.precompiles, precompile)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *flatCallTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt || len(t.precompiles) == 0 {
+		return
+	}
+	t.tracer.CaptureExit(output, gasUsed, err)
+
+	precompile := t.precompiles[len(t.precompiles)-1]
+	t.precompiles = t.precompiles[:len(t.precompiles)-1]
+	// Parity traces don't include calls to precompiled contracts
+	if precompile && !t.config.IncludePrecompiles {
+		parent := &t.tracer.callstack[len(t.tracer.callstack)-1]
+		parent.Calls = parent.Calls[:len(parent.Calls)-1]
+	}
+}
+
+func (t *flatCallTracer) CaptureTxStart(gasLimit uint64) {
+	t.tracer.CaptureTxStart(gasLimit)
+}
+
+func (t *flatCallTracer) CaptureTxEnd(restGas uint64) {
+	t.tracer.CaptureTxEnd(restGas)
+}
+
+// GetResult returns the json-encoded flat list of call traces, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
+	if len(t.tracer.callstack) != 1 {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+
+	flat, err := flatFromNested(&t.tracer.callstack[0], []int{}, t.config.ConvertParityErrors, t.ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := json.Marshal(flat)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.tracer.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *flatCallTracer) Stop(err error) {
+	t.tracer.Stop(err)
+	t.interrupt = true
+}
+
+// flatFromNested flattens a call frame and its children, depth first.
+func flatFromNested(input *callFrame, traceAddress []int, convertErrs bool, ctx *tracers.Context) ([]flatCallFrame, error) {
+	var frame *flatCallFrame
+	switch input.Type {
+	case vm.CREATE, vm.CREATE2:
+		frame = newFlatCreate(input)
+	case vm.SELFDESTRUCT:
+		frame = newFlatSelfdestruct(input)
+	case vm.CALL, vm.STATICCALL, vm.CALLCODE, vm.DELEGATECALL:
+		frame = newFlatCall(input)
+	default:
+		return nil, fmt.Errorf("unrecognized call frame type: %s", input.Type)
+	}
+
+	frame.Error = input.Error
+	if convertErrs {
+		convertErrorToParity(frame)
+	}
+	// The output of reverted calls holds the revert reason, results of other failures are discarded
+	if input.Error != "" && input.Error != vm.ErrExecutionReverted.Error() {
+		frame.Result = nil
+	}
+	frame.TraceAddress = traceAddress
+	frame.Subtraces = len(input.Calls)
+	frame.BlockHash = ctx.BlockHash
+	frame.BlockNumber = ctx.BlockNumber
+	frame.TransactionHash = ctx.TxHash
+	frame.TransactionPosition = uint64(ctx.TxIndex)
+
+	output := []flatCallFrame{*frame}
+	for i := range input.Calls {
+		childAddress := make([]int, len(traceAddress)+1)
+		copy(childAddress, traceAddress)
+		childAddress[len(traceAddress)] = i
+		flat, err := flatFromNested(&input.Calls[i], childAddress, convertErrs, ctx)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, flat...)
+	}
+	return output, nil
+}
+
+func newFlatCreate(input *callFrame) *flatCallFrame {
+	var (
+		from    = input.From
+		to      = input.To
+		gas     = hexutil.Uint64(input.Gas)
+		gasUsed = hexutil.Uint64(input.GasUsed)
+		init    = hexutil.Bytes(input.Input)
+		code    = hexutil.Bytes(input.Output)
+	)
+	return &flatCallFrame{
+		Type:   strings.ToLower(vm.CREATE.String()),
+		Action: flatCallAction{From: &from, Gas: &gas, Init: &init, Value: (*hexutil.Big)(input.Value)},
+		Result: &flatCallResult{GasUsed: &gasUsed, Address: &to, Code: &code},
+	}
+}
+
+func newFlatCall(input *callFrame) *flatCallFrame {
+	var (
+		from    = input.From
+		to      = input.To
+		gas     = hexutil.Uint64(input.Gas)
+		gasUsed = hexutil.Uint64(input.GasUsed)
+		data    = hexutil.Bytes(input.Input)
+		output  = hexutil.Bytes(input.Output)
+	)
+	return &flatCallFrame{
+		Type: strings.ToLower(vm.CALL.String()),
+		Action: flatCallAction{
+			CallType: strings.ToLower(input.Type.String()),
+			From:     &from,
+			To:       &to,
+			Gas:      &gas,
+			Input:    &data,
+			Value:    (*hexutil.Big)(input.Value),
+		},
+		Result: &flatCallResult{GasUsed: &gasUsed, Output: &output},
+	}
+}
+
+func newFlatSelfdestruct(input *callFrame) *flatCallFrame {
+	var (
+		from = input.From
+		to   = input.To
+	)
+	return &flatCallFrame{
+		Type: "suicide",
+		Action: flatCallAction{
+			SelfDestructed: &from,
+			Balance:        (*hexutil.Big)(input.Value),
+			RefundAddress:  &to,
+		},
+	}
+}
+
+func convertErrorToParity(call *flatCallFrame) {
+	if call.Error == "" {
+		return
+	}
+
+	if parityError, ok := parityErrorMapping[call.Error]; ok {
+		call.Error = parityError
+		return
+	}
+	for gethError, parityError := range parityErrorMappingStartingWith {
+		if strings.HasPrefix(call.Error, gethError) {
+			call.Error = parityError
+			return
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync/atomic"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/hexutility"
+
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("gasProfileTracer", newGasProfileTracer)
+	tracers.RegisterCombiner("gasProfileTracer", combineGasProfiles)
+}
+
+// gasProfile is the result of the gasProfileTracer. The gas used by the transactions is the sum of their
+// intrinsic gas and of the gas of their execution, less the refund.
+type gasProfile struct {
+	Transactions uint64                `json:"transactions"`
+	GasUsed      uint64                `json:"gasUsed"`
+	IntrinsicGas uint64                `json:"intrinsicGas"`
+	ExecutionGas uint64                `json:"executionGas"`
+	Refund       uint64                `json:"refund"`
+	Contracts    []*contractGasProfile `json:"contracts"`
+	Depths       []*depthGasProfile    `json:"depths"`
+}
+
+// contractGasProfile is the gas spent by the code of a contract, called with a function selector, without
+// the gas of the calls it makes. Contracts are identified by the address of their code, which is the one
+// of the library for delegated calls.
+type contractGasProfile struct {
+	Address  libcommon.Address         `json:"address"`
+	Selector string                    `json:"selector,omitempty"` // Unset for calls without one
+	Create   bool                      `json:"create,omitempty"`   // Set for the init code of the contract
+	Calls    uint64                    `json:"calls"`
+	Gas      uint64                    `json:"gas"`
+	Opcodes  map[string]*opcodeProfile `json:"opcodes,omitempty"`
+}
+
+// opcodeProfile is the gas spent on an opcode. The accesses of SLOAD and SSTORE are split between the
+// warm ones, to slots of the access list of the transaction or already accessed by it (EIP-2929), and the
+// cold ones.
+type opcodeProfile struct {
+	Count uint64         `json:"count"`
+	Gas   uint64         `json:"gas"`
+	Warm  *accessProfile `json:"warm,omitempty"`
+	Cold  *accessProfile `json:"cold,omitempty"`
+}
+
+type accessProfile struct {
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+// depthGasProfile is the gas spent by the code run at a call depth, 0 being the transaction itself
+type depthGasProfile struct {
+	Depth int    `json:"depth"`
+	Calls uint64 `json:"calls"`
+	Gas   uint64 `json:"gas"`
+}
+
+type contractGasProfileKey struct {
+	address  libcommon.Address
+	selector string
+	create   bool
+}
+
+type storageSlot struct {
+	address libcommon.Address
+	key     libcommon.Hash
+}
+
+// gasProfileFrame is a call being executed. The gas of an opcode is only known once the next one starts,
+// or the call ends, as the difference of the gas left, less the gas used by the calls the opcode made.
+type gasProfileFrame struct {
+	profile   *contractGasProfile
+	gas       uint64 // Gas given to the call
+	callsGas  uint64 // Gas used by the calls made by the frame
+	skip      bool   // Set for selfdestructs, which run no code
+	op        vm.OpCode
+	opPending bool
+	opGas     uint64        // Gas left before the pending opcode
+	opCalls   uint64        // Gas used by the calls made by the pending opcode
+	opAccess  int           // Access list status of the slot of a pending SLOAD or SSTORE
+	accessed  []storageSlot // Slots first accessed by the frame, cold again if it reverts
+}
+
+const (
+	noAccess = iota
+	warmAccess
+	coldAccess
+)
+
+// gasProfileTracer aggregates the gas spent by a transaction per contract and function selector, per
+// opcode within them, and per call depth. Unlike the struct logger, the size of its result doesn't grow
+// with the number of steps executed. The profiles of the transactions of a block or bundle are combined
+// into one with the `combine` option of the trace config.
+type gasProfileTracer struct {
+	noopTracer
+	env       vm.VMInterface
+	gasLimit  uint64
+	profile   gasProfile
+	contracts map[contractGasProfileKey]*contractGasProfile
+	depths    []*depthGasProfile
+	frames    []*gasProfileFrame
+	accessed  map[storageSlot]struct{} // Warm storage slots
+	interrupt uint32                   // Atomic flag to signal execution interruption
+	reason    error                    // Textual reason for the interruption
+}
+
+// newGasProfileTracer returns a new gasProfileTracer. The storage slots of the access list of the
+// transaction are warm from its start.
+func newGasProfileTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	t := &gasProfileTracer{
+		profile:   gasProfile{Transactions: 1},
+		contracts: map[contractGasProfileKey]*contractGasProfile{},
+		accessed:  map[storageSlot]struct{}{},
+	}
+	if ctx != nil {
+		for _, tuple := range ctx.AccessList {
+			for _, key := range tuple.StorageKeys {
+				t.accessed[storageSlot{address: tuple.Address, key: key}] = struct{}{}
+			}
+		}
+	}
+	return t, nil
+}
+
+// CaptureTxStart implements the EVMLogger interface to record the gas limit of the transaction.
+func (t *gasProfileTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureTxEnd implements the EVMLogger interface to record the gas used by the transaction.
+func (t *gasProfileTracer) CaptureTxEnd(restGas uint64) {
+	t.profile.GasUsed = t.gasLimit - restGas
+	if t.profile.IntrinsicGas+t.profile.ExecutionGas > t.profile.GasUsed {
+		t.profile.Refund = t.profile.IntrinsicGas + t.profile.ExecutionGas - t.profile.GasUsed
+	}
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *gasProfileTracer) CaptureStart(env vm.VMInterface, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.env = env
+	if t.gasLimit > gas {
+		t.profile.IntrinsicGas = t.gasLimit - gas
+	}
+	t.enter(to, create, input, gas)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *gasProfileTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) != 1 {
+		return
+	}
+	t.exit(gasUsed, err != nil)
+	t.profile.ExecutionGas = gasUsed
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *gasProfileTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	if typ == vm.SELFDESTRUCT {
+		t.frames = append(t.frames, &gasProfileFrame{skip: true})
+		return
+	}
+	t.enter(to, create, input, gas)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *gasProfileTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 || len(t.frames) <= 1 {
+		return
+	}
+	if t.frames[len(t.frames)-1].skip {
+		t.frames = t.frames[:len(t.frames)-1]
+		return
+	}
+	t.exit(gasUsed, err != nil)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *gasProfileTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	if frame.opPending {
+		t.settle(frame, gas)
+	}
+	frame.op, frame.opPending, frame.opGas, frame.opAccess = op, true, gas, noAccess
+	if (op == vm.SLOAD || op == vm.SSTORE) && err == nil && t.env.ChainRules().IsBerlin {
+		slot := storageSlot{address: scope.Contract.Address(), key: scope.Stack.Back(0).Bytes32()}
+		frame.opAccess = warmAccess
+		if _, warm := t.accessed[slot]; !warm {
+			frame.opAccess = coldAccess
+			t.accessed[slot] = struct{}{}
+			frame.accessed = append(frame.accessed, slot)
+		}
+	}
+}
+
+func (t *gasProfileTracer) enter(to libcommon.Address, create bool, input []byte, gas uint64) {
+	key := contractGasProfileKey{address: to, create: create}
+	if !create && len(input) >= 4 {
+		key.selector = hexutility.Encode(input[:4])
+	}
+	profile, ok := t.contracts[key]
+	if !ok {
+		profile = &contractGasProfile{Address: to, Selector: key.selector, Create: create}
+		t.contracts[key] = profile
+	}
+	profile.Calls++
+	t.depth(len(t.frames)).Calls++
+	t.frames = append(t.frames, &gasProfileFrame{profile: profile, gas: gas})
+}
+
+// exit ends the current frame, whose code spent the gas it used less the gas used by its calls. The slots
+// it accessed are cold again if it failed, as its changes to the access list are reverted.
+func (t *gasProfileTracer) exit(gasUsed uint64, failed bool) {
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+	if frame.opPending {
+		var gasLeft uint64
+		if frame.gas > gasUsed {
+			gasLeft = frame.gas - gasUsed
+		}
+		t.settle(frame, gasLeft)
+	}
+	var gas uint64
+	if gasUsed > frame.callsGas {
+		gas = gasUsed - frame.callsGas
+	}
+	frame.profile.Gas += gas
+	t.depth(len(t.frames)).Gas += gas
+	if failed {
+		for _, slot := range frame.accessed {
+			delete(t.accessed, slot)
+		}
+	}
+	if len(t.frames) > 0 {
+		parent := t.frames[len(t.frames)-1]
+		parent.callsGas += gasUsed
+		parent.opCalls += gasUsed
+		if !failed {
+			parent.accessed = append(parent.accessed, frame.accessed...)
+		}
+	}
+}
+
+// settle charges the pending opcode of the frame with the gas spent until gasLeft was left
+func (t *gasProfileTracer) settle(frame *gasProfileFrame, gasLeft uint64) {
+	var gas uint64
+	if frame.opGas > gasLeft+frame.opCalls {
+		gas = frame.opGas - gasLeft - frame.opCalls
+	}
+	frame.opPending, frame.opCalls = false, 0
+
+	if frame.profile.Opcodes == nil {
+		frame.profile.Opcodes = map[string]*opcodeProfile{}
+	}
+	profile, ok := frame.profile.Opcodes[frame.op.String()]
+	if !ok {
+		profile = &opcodeProfile{}
+		frame.profile.Opcodes[frame.op.String()] = profile
+	}
+	profile.Count++
+	profile.Gas += gas
+	switch frame.opAccess {
+	case warmAccess:
+		addAccess(&profile.Warm, &accessProfile{Count: 1, Gas: gas})
+	case coldAccess:
+		addAccess(&profile.Cold, &accessProfile{Count: 1, Gas: gas})
+	}
+}
+
+// add adds the gas of other, a profile of the same contract and selector, to the profile
+func (p *contractGasProfile) add(other *contractGasProfile) {
+	p.Calls += other.Calls
+	p.Gas += other.Gas
+	for op, opProfile := range other.Opcodes {
+		if p.Opcodes == nil {
+			p.Opcodes = map[string]*opcodeProfile{}
+		}
+		profile, ok := p.Opcodes[op]
+		if !ok {
+			profile = &opcodeProfile{}
+			p.Opcodes[op] = profile
+		}
+		profile.Count += opProfile.Count
+		profile.Gas += opProfile.Gas
+		addAccess(&profile.Warm, opProfile.Warm)
+		addAccess(&profile.Cold, opProfile.Cold)
+	}
+}
+
+func addAccess(profile **accessProfile, other *accessProfile) {
+	if other == nil {
+		return
+	}
+	if *profile == nil {
+		*profile = &accessProfile{}
+	}
+	(*profile).Count += other.Count
+	(*profile).Gas += other.Gas
+}
+
+func (t *gasProfileTracer) depth(depth int) *depthGasProfile {
+	for len(t.depths) <= depth {
+		t.depths = append(t.depths, &depthGasProfile{Depth: len(t.depths)})
+	}
+	return t.depths[depth]
+}
+
+// GetResult returns the json-encoded gas profile, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *gasProfileTracer) GetResult() (json.RawMessage, error) {
+	res, err := marshalGasProfile(t.profile, t.contracts, t.depths)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *gasProfileTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// combineGasProfiles combines the gas profiles of several transactions into the profile of all of them
+func combineGasProfiles(results []json.RawMessage) (json.RawMessage, error) {
+	var (
+		combined  gasProfile
+		contracts = map[contractGasProfileKey]*contractGasProfile{}
+		depths    []*depthGasProfile
+	)
+	for _, result := range results {
+		var profile gasProfile
+		if err := json.Unmarshal(result, &profile); err != nil {
+			return nil, err
+		}
+		combined.Transactions += profile.Transactions
+		combined.GasUsed += profile.GasUsed
+		combined.IntrinsicGas += profile.IntrinsicGas
+		combined.ExecutionGas += profile.ExecutionGas
+		combined.Refund += profile.Refund
+		for _, contract := range profile.Contracts {
+			key := contractGasProfileKey{address: contract.Address, selector: contract.Selector, create: contract.Create}
+			if _, ok := contracts[key]; !ok {
+				contracts[key] = &contractGasProfile{Address: contract.Address, Selector: contract.Selector, Create: contract.Create}
+			}
+			contracts[key].add(contract)
+		}
+		for _, depth := range profile.Depths {
+			for len(depths) <= depth.Depth {
+				depths = append(depths, &depthGasProfile{Depth: len(depths)})
+			}
+			depths[depth.Depth].Calls += depth.Calls
+			depths[depth.Depth].Gas += depth.Gas
+		}
+	}
+	return marshalGasProfile(combined, contracts, depths)
+}
+
+func marshalGasProfile(profile gasProfile, contracts map[contractGasProfileKey]*contractGasProfile, depths []*depthGasProfile) (json.RawMessage, error) {
+	profile.Contracts = make([]*contractGasProfile, 0, len(contracts))
+	for _, contract := range contracts {
+		profile.Contracts = append(profile.Contracts, contract)
+	}
+	// Most expensive first
+	sort.Slice(profile.Contracts, func(i, j int) bool {
+		a, b := profile.Contracts[i], profile.Contracts[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		if c := bytes.Compare(a.Address[:], b.Address[:]); c != 0 {
+			return c < 0
+		}
+		if a.Selector != b.Selector {
+			return a.Selector < b.Selector
+		}
+		return !a.Create && b.Create
+	})
+	profile.Depths = depths
+	if profile.Depths == nil {
+		profile.Depths = []*depthGasProfile{}
+	}
+	res, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), nil
+}
@@ -0,0 +1,240 @@
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("tokenTransferTracer", newTokenTransferTracer)
+}
+
+var (
+	// transferTopic is the topic of both ERC-20 and ERC-721 Transfer events, told apart by their number
+	// of indexed parameters
+	transferTopic       = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	transferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+const (
+	transferTypeEth     = "eth"
+	transferTypeERC20   = "erc20"
+	transferTypeERC721  = "erc721"
+	transferTypeERC1155 = "erc1155"
+)
+
+// tokenTransfer is a transfer of ETH or of a token. Token is the contract of a token transfer, Operator the
+// one of an ERC-1155 transfer, and TokenID is set for ERC-721 and ERC-1155 transfers.
+type tokenTransfer struct {
+	Type     string             `json:"type"`
+	Token    *libcommon.Address `json:"token,omitempty"`
+	Operator *libcommon.Address `json:"operator,omitempty"`
+	From     libcommon.Address  `json:"from"`
+	To       libcommon.Address  `json:"to"`
+	TokenID  *hexutil.Big       `json:"tokenId,omitempty"`
+	Value    *hexutil.Big       `json:"value,omitempty"`
+}
+
+// tokenTransferTracer reports the ETH transfers of a transaction, including the ones of internal calls,
+// and the transfers of ERC-20, ERC-721 and ERC-1155 tokens decoded from their events, in execution order.
+// Transfers of failed calls are left out.
+type tokenTransferTracer struct {
+	noopTracer
+	// frames holds the transfers of each call frame entered, which are kept once it succeeds
+	frames    [][]tokenTransfer
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newTokenTransferTracer returns a new tokenTransferTracer.
+func newTokenTransferTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &tokenTransferTracer{}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *tokenTransferTracer) CaptureStart(env vm.VMInterface, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.frames = [][]tokenTransfer{nil}
+	t.addEthTransfer(from, to, value)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *tokenTransferTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if err != nil && len(t.frames) > 0 {
+		t.frames[0] = nil
+	}
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *tokenTransferTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	t.frames = append(t.frames, nil)
+	// Delegated calls run with the value of their caller, which isn't transferred again
+	switch typ {
+	case vm.CALL, vm.CREATE, vm.CREATE2, vm.SELFDESTRUCT:
+		t.addEthTransfer(from, to, value)
+	}
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *tokenTransferTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 || len(t.frames) <= 1 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+	if err == nil {
+		t.frames[len(t.frames)-1] = append(t.frames[len(t.frames)-1], frame...)
+	}
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *tokenTransferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || op < vm.LOG3 || op > vm.LOG4 || len(t.frames) == 0 {
+		return
+	}
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+
+	stackData := scope.Stack.Data
+	if len(stackData) < 2+int(op-vm.LOG0) {
+		return
+	}
+	mStart := stackData[len(stackData)-1]
+	mSize := stackData[len(stackData)-2]
+	topics := make([]libcommon.Hash, int(op-vm.LOG0))
+	for i := range topics {
+		topics[i] = libcommon.Hash(stackData[len(stackData)-2-(i+1)].Bytes32())
+	}
+	data := scope.Memory.GetCopy(int64(mStart.Uint64()), int64(mSize.Uint64()))
+
+	token := scope.Contract.Address()
+	frame := &t.frames[len(t.frames)-1]
+	*frame = append(*frame, decodeTokenTransfers(token, topics, data)...)
+}
+
+// addEthTransfer adds the transfer of value, if any, to the current frame.
+func (t *tokenTransferTracer) addEthTransfer(from, to libcommon.Address, value *uint256.Int) {
+	if value == nil || value.IsZero() {
+		return
+	}
+	frame := &t.frames[len(t.frames)-1]
+	*frame = append(*frame, tokenTransfer{Type: transferTypeEth, From: from, To: to, Value: (*hexutil.Big)(value.ToBig())})
+}
+
+// GetResult returns the json-encoded list of transfers, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *tokenTransferTracer) GetResult() (json.RawMessage, error) {
+	transfers := []tokenTransfer{}
+	if len(t.frames) > 0 {
+		transfers = append(transfers, t.frames[0]...)
+	}
+	res, err := json.Marshal(transfers)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *tokenTransferTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// decodeTokenTransfers decodes the transfers of an event emitted by token, none if it isn't a transfer
+// event of the ERC-20, ERC-721 or ERC-1155 standards.
+func decodeTokenTransfers(token libcommon.Address, topics []libcommon.Hash, data []byte) []tokenTransfer {
+	switch {
+	case len(topics) == 3 && topics[0] == transferTopic && len(data) == 32:
+		return []tokenTransfer{{
+			Type:  transferTypeERC20,
+			Token: &token,
+			From:  libcommon.BytesToAddress(topics[1].Bytes()),
+			To:    libcommon.BytesToAddress(topics[2].Bytes()),
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(data)),
+		}}
+	case len(topics) == 4 && topics[0] == transferTopic && len(data) == 0:
+		return []tokenTransfer{{
+			Type:    transferTypeERC721,
+			Token:   &token,
+			From:    libcommon.BytesToAddress(topics[1].Bytes()),
+			To:      libcommon.BytesToAddress(topics[2].Bytes()),
+			TokenID: (*hexutil.Big)(topics[3].Big()),
+		}}
+	case len(topics) == 4 && topics[0] == transferSingleTopic && len(data) == 64:
+		operator := libcommon.BytesToAddress(topics[1].Bytes())
+		return []tokenTransfer{{
+			Type:     transferTypeERC1155,
+			Token:    &token,
+			Operator: &operator,
+			From:     libcommon.BytesToAddress(topics[2].Bytes()),
+			To:       libcommon.BytesToAddress(topics[3].Bytes()),
+			TokenID:  (*hexutil.Big)(new(big.Int).SetBytes(data[:32])),
+			Value:    (*hexutil.Big)(new(big.Int).SetBytes(data[32:])),
+		}}
+	case len(topics) == 4 && topics[0] == transferBatchTopic:
+		ids, ok := decodeUint256Array(data, 0)
+		if !ok {
+			return nil
+		}
+		values, ok := decodeUint256Array(data, 1)
+		if !ok || len(values) != len(ids) {
+			return nil
+		}
+		operator := libcommon.BytesToAddress(topics[1].Bytes())
+		transfers := make([]tokenTransfer, 0, len(ids))
+		for i := range ids {
+			transfers = append(transfers, tokenTransfer{
+				Type:     transferTypeERC1155,
+				Token:    &token,
+				Operator: &operator,
+				From:     libcommon.BytesToAddress(topics[2].Bytes()),
+				To:       libcommon.BytesToAddress(topics[3].Bytes()),
+				TokenID:  (*hexutil.Big)(ids[i]),
+				Value:    (*hexutil.Big)(values[i]),
+			})
+		}
+		return transfers
+	}
+	return nil
+}
+
+// decodeUint256Array decodes the ABI-encoded uint256[] argument at the given position of data.
+func decodeUint256Array(data []byte, position int) ([]*big.Int, bool) {
+	word := func(offset uint64) (*big.Int, bool) {
+		if offset+32 < offset || offset+32 > uint64(len(data)) {
+			return nil, false
+		}
+		return new(big.Int).SetBytes(data[offset : offset+32]), true
+	}
+
+	offset, ok := word(uint64(position) * 32)
+	if !ok || !offset.IsUint64() {
+		return nil, false
+	}
+	length, ok := word(offset.Uint64())
+	if !ok || !length.IsUint64() || length.Uint64() > uint64(len(data))/32 {
+		return nil, false
+	}
+	values := make([]*big.Int, length.Uint64())
+	for i := range values {
+		if values[i], ok = word(offset.Uint64() + 32*uint64(i+1)); !ok {
+			return nil, false
+		}
+	}
+	return values, true
+}
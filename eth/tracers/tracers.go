@@ -22,6 +22,7 @@ import (
 	"errors"
 
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
 
 	"github.com/ledgerwatch/erigon/core/vm"
 )
@@ -29,9 +30,11 @@ import (
 // Context contains some contextual infos for a transaction execution that is not
 // available from within the EVM object.
 type Context struct {
-	BlockHash libcommon.Hash // Hash of the block the tx is contained within (zero if dangling tx or call)
-	TxIndex   int            // Index of the transaction within a block (zero if dangling tx or call)
-	TxHash    libcommon.Hash // Hash of the transaction being traced (zero if dangling call)
+	BlockHash   libcommon.Hash    // Hash of the block the tx is contained within (zero if dangling tx or call)
+	BlockNumber uint64            // Number of the block the tx is contained within
+	TxIndex     int               // Index of the transaction within a block (zero if dangling tx or call)
+	TxHash      libcommon.Hash    // Hash of the transaction being traced (zero if dangling call)
+	AccessList  types2.AccessList // Access list of the transaction being traced (EIP-2930)
 }
 
 // Tracer interface extends vm.EVMLogger and additionally
@@ -45,8 +48,12 @@ type Tracer interface {
 
 type lookupFunc func(string, *Context, json.RawMessage) (Tracer, error)
 
+// CombineFunc combines the results of a tracer over several transactions into a single result.
+type CombineFunc func([]json.RawMessage) (json.RawMessage, error)
+
 var (
-	lookups []lookupFunc
+	lookups   []lookupFunc
+	combiners = map[string]CombineFunc{}
 )
 
 // RegisterLookup registers a method as a lookup for tracers, meaning that
@@ -71,3 +78,16 @@ func New(code string, ctx *Context, cfg json.RawMessage) (Tracer, error) {
 	}
 	return nil, errors.New("tracer not found")
 }
+
+// RegisterCombiner registers the function combining the results of the named
+// tracer, for the results of the transactions of a block or bundle to be
+// returned as one.
+func RegisterCombiner(name string, combine CombineFunc) {
+	combiners[name] = combine
+}
+
+// Combiner returns the function combining the results of the named tracer,
+// nil if its results can't be combined.
+func Combiner(name string) CombineFunc {
+	return combiners[name]
+}
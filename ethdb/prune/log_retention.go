@@ -0,0 +1,194 @@
+package prune
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// kvPruneKeepLogs is the key in kv.DatabaseInfo of the LogRetention the node was created with
+var kvPruneKeepLogs = []byte("pruneReceiptsKeepLogs")
+
+// LogRetention lists the contracts and the event topics whose logs are kept when receipts are pruned,
+// together with the receipts of their blocks and their entries in the LogAddressIndex and LogTopicIndex.
+// Logs of other contracts and topics are pruned as usual.
+// Both lists are sorted and have no duplicates, so that they can be compared with reflect.DeepEqual
+type LogRetention struct {
+	Addresses []libcommon.Address
+	Topics    []libcommon.Hash
+}
+
+func (r LogRetention) Enabled() bool { return len(r.Addresses) > 0 || len(r.Topics) > 0 }
+
+// KeepLog tells if the log is emitted by one of the contracts or has one of the topics
+func (r LogRetention) KeepLog(l *types.Log) bool {
+	if i := sort.Search(len(r.Addresses), func(i int) bool { return bytes.Compare(r.Addresses[i][:], l.Address[:]) >= 0 }); i < len(r.Addresses) && r.Addresses[i] == l.Address {
+		return true
+	}
+	for _, topic := range l.Topics {
+		if i := sort.Search(len(r.Topics), func(i int) bool { return bytes.Compare(r.Topics[i][:], topic[:]) >= 0 }); i < len(r.Topics) && r.Topics[i] == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepLogs tells if the logs of a transaction are kept, which is the case when any of them is
+func (r LogRetention) KeepLogs(logs types.Logs) bool {
+	for _, l := range logs {
+		if r.KeepLog(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterReceipts returns the receipts of a block to keep past the pruning horizon: the logs of transactions
+// which aren't kept are dropped. Returns nil when no logs of the block are kept.
+func (r LogRetention) FilterReceipts(receipts types.Receipts) types.Receipts {
+	var filtered types.Receipts
+	for i, receipt := range receipts {
+		if !r.KeepLogs(receipt.Logs) {
+			continue
+		}
+		if filtered == nil {
+			filtered = make(types.Receipts, len(receipts))
+			for j, rr := range receipts {
+				withoutLogs := *rr
+				withoutLogs.Logs = nil
+				filtered[j] = &withoutLogs
+			}
+		}
+		filtered[i].Logs = receipt.Logs
+	}
+	return filtered
+}
+
+func (r LogRetention) String() string {
+	var s string
+	if len(r.Addresses) > 0 {
+		addresses := make([]string, len(r.Addresses))
+		for i, addr := range r.Addresses {
+			addresses[i] = addr.Hex()
+		}
+		s += " --prune.r.keep.addresses=" + strings.Join(addresses, ",")
+	}
+	if len(r.Topics) > 0 {
+		topics := make([]string, len(r.Topics))
+		for i, topic := range r.Topics {
+			topics[i] = topic.Hex()
+		}
+		s += " --prune.r.keep.topics=" + strings.Join(topics, ",")
+	}
+	return s
+}
+
+func logRetentionFromCli(addresses, topics []string) (LogRetention, error) {
+	var r LogRetention
+	for _, s := range addresses {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if !libcommon.IsHexAddress(s) {
+			return LogRetention{}, fmt.Errorf("invalid address of the contract to keep logs of: %s", s)
+		}
+		r.Addresses = append(r.Addresses, libcommon.HexToAddress(s))
+	}
+	for _, s := range topics {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != length.Hash {
+			return LogRetention{}, fmt.Errorf("invalid topic to keep logs of: %s", s)
+		}
+		r.Topics = append(r.Topics, libcommon.BytesToHash(b))
+	}
+	r.normalise()
+	return r, nil
+}
+
+func (r *LogRetention) normalise() {
+	sort.Slice(r.Addresses, func(i, j int) bool { return bytes.Compare(r.Addresses[i][:], r.Addresses[j][:]) < 0 })
+	r.Addresses = dedup(r.Addresses)
+	sort.Slice(r.Topics, func(i, j int) bool { return bytes.Compare(r.Topics[i][:], r.Topics[j][:]) < 0 })
+	r.Topics = dedup(r.Topics)
+}
+
+func dedup[T comparable](sorted []T) []T {
+	if len(sorted) == 0 {
+		return nil
+	}
+	res := sorted[:1]
+	for _, v := range sorted[1:] {
+		if v != res[len(res)-1] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// encode serialises the retention as the number of addresses, followed by the addresses and the topics,
+// so that the value is never empty, even when nothing is kept
+func (r LogRetention) encode() []byte {
+	v := make([]byte, 4, 4+len(r.Addresses)*length.Addr+len(r.Topics)*length.Hash)
+	binary.BigEndian.PutUint32(v, uint32(len(r.Addresses)))
+	for _, addr := range r.Addresses {
+		v = append(v, addr[:]...)
+	}
+	for _, topic := range r.Topics {
+		v = append(v, topic[:]...)
+	}
+	return v
+}
+
+func decodeLogRetention(v []byte) (LogRetention, error) {
+	var r LogRetention
+	if len(v) < 4 {
+		return r, fmt.Errorf("unexpected length of logs retention: %d", len(v))
+	}
+	addrLen := int(binary.BigEndian.Uint32(v)) * length.Addr
+	v = v[4:]
+	if len(v) < addrLen || (len(v)-addrLen)%length.Hash != 0 {
+		return r, fmt.Errorf("unexpected length of logs retention: %d", len(v)+4)
+	}
+	for ; addrLen > 0; addrLen -= length.Addr {
+		r.Addresses = append(r.Addresses, libcommon.BytesToAddress(v[:length.Addr]))
+		v = v[length.Addr:]
+	}
+	for ; len(v) > 0; v = v[length.Hash:] {
+		r.Topics = append(r.Topics, libcommon.BytesToHash(v[:length.Hash]))
+	}
+	return r, nil
+}
+
+func getLogRetention(db kv.Getter) (LogRetention, error) {
+	v, err := db.GetOne(kv.DatabaseInfo, kvPruneKeepLogs)
+	if err != nil {
+		return LogRetention{}, err
+	}
+	if len(v) == 0 {
+		return LogRetention{}, nil
+	}
+	return decodeLogRetention(v)
+}
+
+func setLogRetentionOnEmpty(db kv.GetPut, r LogRetention) error {
+	v, err := db.GetOne(kv.DatabaseInfo, kvPruneKeepLogs)
+	if err != nil {
+		return err
+	}
+	if len(v) == 0 {
+		return db.Put(kv.DatabaseInfo, kvPruneKeepLogs, r.encode())
+	}
+	return nil
+}
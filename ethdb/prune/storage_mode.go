@@ -35,7 +35,7 @@ type Experiments struct {
 }
 
 func FromCli(chainId uint64, flags string, exactHistory, exactReceipts, exactTxIndex, exactCallTraces,
-	beforeH, beforeR, beforeT, beforeC uint64, experiments []string) (Mode, error) {
+	beforeH, beforeR, beforeT, beforeC uint64, keepLogAddresses, keepLogTopics []string, experiments []string) (Mode, error) {
 	mode := DefaultMode
 
 	if flags != "default" && flags != "disabled" {
@@ -85,6 +85,14 @@ func FromCli(chainId uint64, flags string, exactHistory, exactReceipts, exactTxI
 		// Default --prune=r to pruning receipts before the Beacon Chain genesis
 		mode.Receipts = Before(pruneBlockBefore)
 	}
+	keepLogs, err := logRetentionFromCli(keepLogAddresses, keepLogTopics)
+	if err != nil {
+		return DefaultMode, err
+	}
+	if keepLogs.Enabled() && !mode.Receipts.Enabled() {
+		return DefaultMode, errors.New("keeping logs of contracts or topics requires pruning of receipts")
+	}
+	mode.KeepLogs = keepLogs
 	if beforeT > 0 {
 		mode.TxIndex = Before(beforeT)
 	}
@@ -156,6 +164,10 @@ func Get(db kv.Getter) (Mode, error) {
 		prune.CallTraces = blockAmount
 	}
 
+	if prune.KeepLogs, err = getLogRetention(db); err != nil {
+		return prune, err
+	}
+
 	return prune, nil
 }
 
@@ -165,6 +177,7 @@ type Mode struct {
 	Receipts    BlockAmount
 	TxIndex     BlockAmount
 	CallTraces  BlockAmount
+	KeepLogs    LogRetention // Logs to keep past the pruning horizon of receipts
 	Experiments Experiments
 }
 
@@ -236,6 +249,7 @@ func (m Mode) String() string {
 		} else {
 			long += fmt.Sprintf(" --prune.r.%s=%d", m.Receipts.dbType(), m.Receipts.toValue())
 		}
+		long += m.KeepLogs.String()
 	}
 	if m.TxIndex.Enabled() {
 		if m.TxIndex.useDefaultValue() {
@@ -280,6 +294,11 @@ func Override(db kv.RwTx, sm Mode) error {
 		return err
 	}
 
+	err = db.Put(kv.DatabaseInfo, kvPruneKeepLogs, sm.KeepLogs.encode())
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -316,6 +335,17 @@ func setIfNotExist(db kv.GetPut, pm Mode) error {
 		pm = DefaultMode
 	}
 
+	// Databases created before logs could be kept have no retention stored, and their pruned receipts
+	// have none of their logs left
+	keepLogs := pm.KeepLogs
+	receipts, err := get(db, kv.PruneReceipts)
+	if err != nil {
+		return err
+	}
+	if receipts != nil && receipts.Enabled() {
+		keepLogs = LogRetention{}
+	}
+
 	pruneDBData := map[string]BlockAmount{
 		string(kv.PruneHistory):    pm.History,
 		string(kv.PruneReceipts):   pm.Receipts,
@@ -330,7 +360,7 @@ func setIfNotExist(db kv.GetPut, pm Mode) error {
 		}
 	}
 
-	return nil
+	return setLogRetentionOnEmpty(db, keepLogs)
 }
 
 func createBlockAmount(pruneType []byte, v []byte) (BlockAmount, error) {
@@ -5,8 +5,11 @@ import (
 	"strconv"
 	"testing"
 
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/erigon/common/math"
+	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,16 +18,16 @@ func TestSetStorageModeIfNotExist(t *testing.T) {
 	prune, err := Get(tx)
 	assert.NoError(t, err)
 	assert.Equal(t, Mode{true, Distance(math.MaxUint64), Distance(math.MaxUint64),
-		Distance(math.MaxUint64), Distance(math.MaxUint64), Experiments{}}, prune)
+		Distance(math.MaxUint64), Distance(math.MaxUint64), LogRetention{}, Experiments{}}, prune)
 
 	err = setIfNotExist(tx, Mode{true, Distance(1), Distance(2),
-		Before(3), Before(4), Experiments{}})
+		Before(3), Before(4), LogRetention{}, Experiments{}})
 	assert.NoError(t, err)
 
 	prune, err = Get(tx)
 	assert.NoError(t, err)
 	assert.Equal(t, Mode{true, Distance(1), Distance(2),
-		Before(3), Before(4), Experiments{}}, prune)
+		Before(3), Before(4), LogRetention{}, Experiments{}}, prune)
 }
 
 var distanceTests = []struct {
@@ -73,3 +76,52 @@ func TestBeforePruneTo(t *testing.T) {
 		})
 	}
 }
+
+func TestKeepLogs(t *testing.T) {
+	addr1, addr2 := "0x00000000000000000000000000000000000000A1", "0x00000000000000000000000000000000000000a2"
+	topic := "0x0000000000000000000000000000000000000000000000000000000000000001"
+
+	_, err := FromCli(1, "default", 0, 0, 0, 0, 0, 0, 0, 0, []string{addr1}, nil, nil)
+	assert.Error(t, err, "receipts are not pruned")
+	_, err = FromCli(1, "r", 0, 0, 0, 0, 0, 0, 0, 0, []string{"0x01"}, nil, nil)
+	assert.Error(t, err)
+	_, err = FromCli(1, "r", 0, 0, 0, 0, 0, 0, 0, 0, nil, []string{addr1}, nil)
+	assert.Error(t, err)
+
+	mode, err := FromCli(1, "r", 0, 0, 0, 0, 0, 0, 0, 0, []string{addr2, addr1, addr2, ""}, []string{topic}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, LogRetention{
+		Addresses: []libcommon.Address{libcommon.HexToAddress(addr1), libcommon.HexToAddress(addr2)},
+		Topics:    []libcommon.Hash{libcommon.HexToHash(topic)},
+	}, mode.KeepLogs)
+	assert.Contains(t, mode.String(), "--prune.r.keep.topics="+topic)
+	assert.True(t, mode.KeepLogs.KeepLog(&types.Log{Address: libcommon.HexToAddress(addr2)}))
+	assert.True(t, mode.KeepLogs.KeepLog(&types.Log{Topics: []libcommon.Hash{{2}, libcommon.HexToHash(topic)}}))
+	assert.False(t, mode.KeepLogs.KeepLog(&types.Log{Address: libcommon.Address{1}, Topics: []libcommon.Hash{{2}}}))
+
+	_, tx := memdb.NewTestTx(t)
+	pm, err := EnsureNotChanged(tx, mode)
+	assert.NoError(t, err)
+	assert.Equal(t, mode, pm)
+
+	// Logs to keep can't be changed after the node is created
+	changed := mode
+	changed.KeepLogs = LogRetention{Addresses: mode.KeepLogs.Addresses[:1]}
+	_, err = EnsureNotChanged(tx, changed)
+	assert.Error(t, err)
+
+	// But can be overridden
+	assert.NoError(t, Override(tx, changed))
+	pm, err = Get(tx)
+	assert.NoError(t, err)
+	assert.Equal(t, changed.KeepLogs, pm.KeepLogs)
+
+	// Receipts pruned by a node which didn't keep logs have none left
+	_, tx = memdb.NewTestTx(t)
+	assert.NoError(t, setOnEmpty(tx, kv.PruneReceipts, mode.Receipts))
+	_, err = EnsureNotChanged(tx, mode)
+	assert.Error(t, err)
+	pm, err = Get(tx)
+	assert.NoError(t, err)
+	assert.False(t, pm.KeepLogs.Enabled())
+}
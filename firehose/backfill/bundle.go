@@ -0,0 +1,198 @@
+// Package backfill splits a historical block range into fixed-size bundles of Firehose
+// output written to files, so old blocks can be re-instrumented without resyncing a node.
+//
+// Bundle boundaries are aligned on multiples of the bundle size, whatever the requested
+// range, so runs over disjoint ranges (shards) produce files that never overlap and a run
+// interrupted midway resumes by skipping the bundles already written.
+package backfill
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ledgerwatch/erigon/firehose"
+)
+
+// Range is an inclusive range of block numbers.
+type Range struct {
+	From uint64
+	To   uint64
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("[%d, %d]", r.From, r.To)
+}
+
+// Bundles splits the inclusive range [from, to] into bundles of `size` blocks aligned on
+// multiples of `size`, the first and last bundles being truncated to the range.
+func Bundles(from, to, size uint64) ([]Range, error) {
+	if size == 0 {
+		return nil, errors.New("bundle size must be greater than 0")
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range, from %d is after to %d", from, to)
+	}
+
+	var bundles []Range
+	for start := from; start <= to; {
+		end := start - start%size + size - 1
+		if end > to || end < start /* overflow */ {
+			end = to
+		}
+		bundles = append(bundles, Range{From: start, To: end})
+		if end == to {
+			break
+		}
+		start = end + 1
+	}
+
+	return bundles, nil
+}
+
+// Shard returns the contiguous part of bundles that shard `index` out of `count` is
+// responsible of, shards are as even as possible and never overlap.
+func Shard(bundles []Range, count, index int) ([]Range, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("invalid shard count %d, must be greater than 0", count)
+	}
+	if index < 0 || index >= count {
+		return nil, fmt.Errorf("invalid shard index %d, must be between 0 and %d", index, count-1)
+	}
+
+	start, end := len(bundles)*index/count, len(bundles)*(index+1)/count
+	return bundles[start:end], nil
+}
+
+var fileExtensions = map[firehose.OutputFormat]string{
+	firehose.TextOutputFormat:     "dmlog",
+	firehose.ProtobufOutputFormat: "pb",
+}
+
+// BundleWriter is a firehose.Printer writing the records of each bundle to its own file
+// in a directory. A bundle is written to a temporary file renamed once committed, so the
+// directory only ever contains complete bundles.
+type BundleWriter struct {
+	dir    string
+	format firehose.OutputFormat
+
+	bundle  *Range
+	file    *os.File
+	writer  *bufio.Writer
+	printer firehose.Printer
+}
+
+func NewBundleWriter(dir string, format firehose.OutputFormat) (*BundleWriter, error) {
+	if _, ok := fileExtensions[format]; !ok {
+		return nil, fmt.Errorf("unknown firehose output format %q, valid values are %q and %q", format, firehose.TextOutputFormat, firehose.ProtobufOutputFormat)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create bundle directory: %w", err)
+	}
+
+	return &BundleWriter{dir: dir, format: format}, nil
+}
+
+// Path is the file the given bundle is written to once committed.
+func (w *BundleWriter) Path(bundle Range) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%010d-%010d.%s", bundle.From, bundle.To, fileExtensions[w.format]))
+}
+
+// Exists tells if the given bundle was already committed.
+func (w *BundleWriter) Exists(bundle Range) (bool, error) {
+	_, err := os.Stat(w.Path(bundle))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Open starts writing the given bundle, records printed until Commit or Abort go to it.
+func (w *BundleWriter) Open(bundle Range) error {
+	if w.bundle != nil {
+		return fmt.Errorf("bundle %s is still open", w.bundle)
+	}
+
+	file, err := os.Create(w.Path(bundle) + ".tmp")
+	if err != nil {
+		return fmt.Errorf("create bundle file: %w", err)
+	}
+
+	w.writer = bufio.NewWriter(file)
+	printer, err := firehose.NewPrinter(w.format, w.writer)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.bundle, w.file, w.printer = &bundle, file, printer
+	return nil
+}
+
+// Commit completes the bundle being written.
+func (w *BundleWriter) Commit() error {
+	if w.bundle == nil {
+		return errors.New("no bundle is open")
+	}
+	defer w.reset()
+
+	if err := w.writer.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("write bundle %s: %w", w.bundle, err)
+	}
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("sync bundle %s: %w", w.bundle, err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close bundle %s: %w", w.bundle, err)
+	}
+
+	return os.Rename(w.file.Name(), w.Path(*w.bundle))
+}
+
+// Abort discards the bundle being written, if any.
+func (w *BundleWriter) Abort() {
+	if w.bundle == nil {
+		return
+	}
+	defer w.reset()
+
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+func (w *BundleWriter) reset() {
+	w.bundle, w.file, w.writer, w.printer = nil, nil, nil, nil
+}
+
+func (w *BundleWriter) Disabled() bool {
+	return false
+}
+
+func (w *BundleWriter) Print(input ...string) {
+	if w.printer == nil {
+		panic("printing a firehose record while no bundle is open")
+	}
+
+	w.printer.Print(input...)
+}
+
+func (w *BundleWriter) PrintRecord(record *firehose.Record) {
+	if w.printer == nil {
+		panic("printing a firehose record while no bundle is open")
+	}
+
+	if printer, ok := w.printer.(firehose.RecordPrinter); ok {
+		printer.PrintRecord(record)
+		return
+	}
+
+	w.printer.Print(record.Fields()...)
+}
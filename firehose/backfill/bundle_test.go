@@ -0,0 +1,101 @@
+package backfill
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundles(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to uint64
+		size     uint64
+		want     []Range
+		wantErr  bool
+	}{
+		{name: "aligned", from: 0, to: 199, size: 100, want: []Range{{0, 99}, {100, 199}}},
+		{name: "unaligned", from: 150, to: 320, size: 100, want: []Range{{150, 199}, {200, 299}, {300, 320}}},
+		{name: "single block", from: 42, to: 42, size: 100, want: []Range{{42, 42}}},
+		{name: "size of one", from: 5, to: 7, size: 1, want: []Range{{5, 5}, {6, 6}, {7, 7}}},
+		{name: "zero size", from: 0, to: 10, size: 0, wantErr: true},
+		{name: "inverted range", from: 10, to: 0, size: 100, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundles, err := Bundles(test.from, test.to, test.size)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, bundles)
+		})
+	}
+}
+
+func TestShard(t *testing.T) {
+	bundles, err := Bundles(0, 999, 100)
+	require.NoError(t, err)
+
+	var all []Range
+	for i := 0; i < 3; i++ {
+		shard, err := Shard(bundles, 3, i)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, len(shard), 3)
+		all = append(all, shard...)
+	}
+	// Shards are disjoint and cover every bundle
+	assert.Equal(t, bundles, all)
+
+	_, err = Shard(bundles, 3, 3)
+	require.Error(t, err)
+	_, err = Shard(bundles, 0, 0)
+	require.Error(t, err)
+}
+
+func TestBundleWriter(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewBundleWriter(dir, firehose.TextOutputFormat)
+	require.NoError(t, err)
+
+	bundle := Range{From: 100, To: 199}
+	exists, err := writer.Exists(bundle)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, writer.Open(bundle))
+	require.Error(t, writer.Open(bundle))
+	writer.Print("BEGIN_BLOCK", "100")
+	// Nothing is visible before the bundle is committed
+	exists, err = writer.Exists(bundle)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, writer.Commit())
+
+	exists, err = writer.Exists(bundle)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	content, err := os.ReadFile(filepath.Join(dir, "0000000100-0000000199.dmlog"))
+	require.NoError(t, err)
+	assert.Equal(t, "FIRE BEGIN_BLOCK 100\n", string(content))
+
+	aborted := Range{From: 200, To: 299}
+	require.NoError(t, writer.Open(aborted))
+	writer.Print("BEGIN_BLOCK", "200")
+	writer.Abort()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Panics(t, func() { writer.Print("BEGIN_BLOCK", "201") })
+
+	_, err = NewBundleWriter(dir, firehose.OutputFormat("xml"))
+	require.Error(t, err)
+}
@@ -7,24 +7,28 @@ import (
 	"math/big"
 	"os"
 	"runtime/debug"
-	"strconv"
 	"strings"
+	"sync"
 
 	types2 "github.com/ledgerwatch/erigon-lib/types"
 
 	"github.com/holiman/uint256"
 	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	erigonmath "github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/common/u256"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/firehose/pb"
 	"go.uber.org/atomic"
 )
 
 // NoOpContext can be used when no recording should happen for a given code path
 var NoOpContext *Context
 
-var syncContext *Context = NewContext(&DelegateToWriterPrinter{writer: os.Stdout})
+var syncPrinter = &lockedPrinter{printer: &DelegateToWriterPrinter{writer: os.Stdout}}
+var syncContext *Context = NewContext(syncPrinter)
+var mempoolContext *Context = NewContext(&DelegateToWriterPrinter{writer: os.Stdout})
 
 // MaybeSyncContext is used when syncing blocks with the network for mindreader consumption, there
 // is always a single active sync context use for the whole syncing process, should not be used
@@ -51,6 +55,55 @@ func SyncContext() *Context {
 	return syncContext
 }
 
+// MaybeMempoolContext is used by the transaction pool observer to record pending transactions
+// events. It prints to its own printer, see SetMempoolPrinter, so that mempool records never
+// appear between the records of a block.
+func MaybeMempoolContext() *Context {
+	if !Enabled || !MempoolEnabled {
+		return NoOpContext
+	}
+
+	return mempoolContext
+}
+
+// SetSyncPrinter replaces the sync context by one printing to the given printer.
+// It must be called at initialization time, before any block is instrumented.
+func SetSyncPrinter(printer Printer) {
+	syncPrinter = &lockedPrinter{printer: printer}
+	syncContext = NewContext(syncPrinter)
+}
+
+// SetMempoolPrinter replaces the mempool context by one printing to the given printer, which
+// must not be the printer of the sync context. It must be called at initialization time, before
+// the transaction pool is observed.
+func SetMempoolPrinter(printer Printer) {
+	mempoolContext = NewContext(printer)
+}
+
+// lockedPrinter serializes the records of the contexts sharing a printer.
+type lockedPrinter struct {
+	lock    sync.Mutex
+	printer Printer
+}
+
+func (p *lockedPrinter) Disabled() bool {
+	return false
+}
+
+func (p *lockedPrinter) Print(input ...string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.printer.Print(input...)
+}
+
+func (p *lockedPrinter) PrintRecord(record *Record) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	printRecord(p.printer, record)
+}
+
 // Context is a block level data container used throughout firehose instrumentation to
 // keep active state about current instrumentation. This contains method to deal with
 // block, transaction and call metadata required for proper functionning of Firehose
@@ -59,22 +112,33 @@ type Context struct {
 	printer Printer
 
 	blockLogIndex   uint64
-	activeCallIndex string
-	nextCallIndex   uint64
+	activeCallIndex uint32
+	nextCallIndex   uint32
 	callIndexStack  *ExtendedStack
 
 	seenBlock            *atomic.Bool
 	inBlock              *atomic.Bool
 	inTransaction        *atomic.Bool
 	totalOrderingCounter *atomic.Uint64
+
+	// mergedGasUsed is the block's gas used by the transaction buffers merged so far
+	mergedGasUsed uint64
+
+	// systemCallDepth counts the system calls started and not ended yet, inSystemCall being
+	// set when the outermost one was printed, as it is when not within a transaction
+	systemCallDepth int
+	inSystemCall    bool
+
+	forkchoiceLock sync.Mutex
+	safeBlock      *types.Header
+	finalizedBlock *types.Header
 }
 
 func NewContext(printer Printer) *Context {
 	ctx := &Context{
 		printer: printer,
 
-		activeCallIndex: "0",
-		callIndexStack:  &ExtendedStack{},
+		callIndexStack: &ExtendedStack{},
 
 		seenBlock:            atomic.NewBool(false),
 		inBlock:              atomic.NewBool(false),
@@ -91,7 +155,18 @@ func (ctx *Context) InitVersion(nodeVersion, dmVersion, variant string) {
 	if ctx == nil {
 		return
 	}
-	ctx.printer.Print("INIT", dmVersion, variant, nodeVersion)
+	ctx.print(&Record{
+		Kind:    "INIT",
+		Message: &pb.Init{FirehoseVersion: dmVersion, Variant: variant, NodeVersion: nodeVersion, SchemaVersion: SchemaVersion},
+		fields: func() []string {
+			return []string{dmVersion, variant, nodeVersion, Uint64(SchemaVersion)}
+		},
+	})
+}
+
+// print hands a record to the printer of the context.
+func (ctx *Context) print(record *Record) {
+	printRecord(ctx.printer, record)
 }
 
 func NewSpeculativeExecutionContext() *Context {
@@ -129,12 +204,13 @@ func (ctx *Context) RecordGenesisBlock(block *types.Block, recordGenesisAlloc fu
 	root := block.Root()
 
 	ctx.StartBlock(block)
-	ctx.StartTransactionRaw(libcommon.Hash{}, &zero, &uint256.Int{}, nil, nil, nil, 0, &uint256.Int{}, 0, nil, nil, nil, nil, 0)
+	ctx.StartTransactionRaw(libcommon.Hash{}, &zero, &uint256.Int{}, nil, nil, nil, 0, &uint256.Int{}, 0, nil, nil, nil, nil, 0, nil, nil)
 	ctx.RecordTrxFrom(zero)
 	recordGenesisAlloc(ctx)
 	ctx.EndTransaction(&types.Receipt{PostState: root[:]})
 	ctx.FinalizeBlock(block)
 	ctx.EndBlock(block, nil, block.Difficulty())
+	ctx.RecordNewBlock(block.Header())
 }
 
 func (ctx *Context) StartBlock(block *types.Block) {
@@ -144,13 +220,25 @@ func (ctx *Context) StartBlock(block *types.Block) {
 
 	ctx.seenBlock.Store(true)
 	ctx.totalOrderingCounter.Store(0)
-	ctx.printer.Print("BEGIN_BLOCK", Uint64(block.NumberU64()))
+	ctx.mergedGasUsed = 0
+
+	number := block.NumberU64()
+	ctx.print(&Record{
+		Kind:    "BEGIN_BLOCK",
+		Message: &pb.Block{Number: number},
+		fields:  func() []string { return []string{Uint64(number)} },
+	})
 }
 
 func (ctx *Context) FinalizeBlock(block *types.Block) {
 	// We must not check if the finalize block is actually in the a block since
 	// when firehose block progress only is enabled, it would hit a panic
-	ctx.printer.Print("FINALIZE_BLOCK", Uint64(block.NumberU64()))
+	number := block.NumberU64()
+	ctx.print(&Record{
+		Kind:    "FINALIZE_BLOCK",
+		Message: &pb.FinalizeBlock{Number: number},
+		fields:  func() []string { return []string{Uint64(number)} },
+	})
 }
 
 // ExitBlock is used when an abnormal condition is encountered while processing
@@ -166,22 +254,39 @@ func (ctx *Context) ExitBlock() {
 func (ctx *Context) EndBlock(block *types.Block, finalizedBlockHeader *types.Header, totalDifficulty *big.Int) {
 	ctx.ExitBlock()
 
-	endData := map[string]interface{}{
-		"header":          block.Header(),
-		"uncles":          block.Body().Uncles,
-		"totalDifficulty": (*hexutil.Big)(totalDifficulty),
-	}
-
+	// The safe block is never behind the finalized one, it's only advertised along it
+	var safeBlockHeader *types.Header
 	if finalizedBlockHeader != nil {
-		endData["finalizedBlockNum"] = (*hexutil.Big)(finalizedBlockHeader.Number)
-		endData["finalizedBlockHash"] = finalizedBlockHeader.Hash()
+		safeBlockHeader, _ = ctx.Forkchoice()
 	}
 
-	ctx.printer.Print("END_BLOCK",
-		Uint64(block.NumberU64()),
-		Uint64(uint64(block.Size())),
-		JSON(endData),
-	)
+	ctx.print(&Record{
+		Kind:    "END_BLOCK",
+		Message: endBlockMessage(block, finalizedBlockHeader, safeBlockHeader, totalDifficulty),
+		fields: func() []string {
+			endData := map[string]interface{}{
+				"header":          block.Header(),
+				"uncles":          block.Body().Uncles,
+				"totalDifficulty": (*hexutil.Big)(totalDifficulty),
+			}
+
+			if finalizedBlockHeader != nil {
+				endData["finalizedBlockNum"] = (*hexutil.Big)(finalizedBlockHeader.Number)
+				endData["finalizedBlockHash"] = finalizedBlockHeader.Hash()
+
+				if safeBlockHeader != nil {
+					endData["safeBlockNum"] = (*hexutil.Big)(safeBlockHeader.Number)
+					endData["safeBlockHash"] = safeBlockHeader.Hash()
+				}
+			}
+
+			return []string{
+				Uint64(block.NumberU64()),
+				Uint64(uint64(block.Size())),
+				JSON(endData),
+			}
+		},
+	})
 }
 
 // CancelBlock emit a Firehose CANCEL_BLOCK event that tells the console reader to discard any
@@ -194,10 +299,127 @@ func (ctx *Context) CancelBlock(block *types.Block, err error) {
 
 	ctx.ExitBlock()
 
-	ctx.printer.Print("CANCEL_BLOCK",
-		Uint64(block.NumberU64()),
-		err.Error(),
-	)
+	number := block.NumberU64()
+	ctx.print(&Record{
+		Kind:    "CANCEL_BLOCK",
+		Message: &pb.Block{Number: number},
+		fields:  func() []string { return []string{Uint64(number), err.Error()} },
+	})
+}
+
+// RecordForkchoice stores the safe and finalized blocks of the latest Engine API forkchoice
+// update, a nil header (unknown to the consensus layer) keeps the previously recorded one.
+// When one of them advances, a FINALITY_UPDATE event is emitted right away so consumers learn
+// about finality even when no new block follows.
+func (ctx *Context) RecordForkchoice(safeBlockHeader, finalizedBlockHeader *types.Header) {
+	if ctx == nil {
+		return
+	}
+
+	ctx.forkchoiceLock.Lock()
+	advanced := headerAdvanced(ctx.safeBlock, safeBlockHeader) || headerAdvanced(ctx.finalizedBlock, finalizedBlockHeader)
+	if safeBlockHeader != nil {
+		ctx.safeBlock = safeBlockHeader
+	}
+	if finalizedBlockHeader != nil {
+		ctx.finalizedBlock = finalizedBlockHeader
+	}
+	safeBlockHeader, finalizedBlockHeader = ctx.safeBlock, ctx.finalizedBlock
+	ctx.forkchoiceLock.Unlock()
+
+	if !advanced {
+		return
+	}
+
+	if ctx.inBlock.Load() {
+		panic("recording FINALITY_UPDATE while in block scope")
+	}
+
+	safeNum, safeHash := headerNumberAndHash(safeBlockHeader)
+	finalizedNum, finalizedHash := headerNumberAndHash(finalizedBlockHeader)
+	ctx.print(&Record{
+		Kind: "FINALITY_UPDATE",
+		Message: &pb.FinalityUpdate{
+			SafeBlockNumber:      safeNum,
+			SafeBlockHash:        bytesOrNil(safeHash),
+			FinalizedBlockNumber: finalizedNum,
+			FinalizedBlockHash:   bytesOrNil(finalizedHash),
+		},
+		fields: func() []string {
+			return []string{Uint64(safeNum), Hex(safeHash), Uint64(finalizedNum), Hex(finalizedHash)}
+		},
+	})
+}
+
+// Forkchoice returns the safe and finalized blocks of the latest forkchoice update recorded
+// by RecordForkchoice, they are nil until the first one.
+func (ctx *Context) Forkchoice() (safeBlockHeader, finalizedBlockHeader *types.Header) {
+	if ctx == nil {
+		return nil, nil
+	}
+
+	ctx.forkchoiceLock.Lock()
+	defer ctx.forkchoiceLock.Unlock()
+
+	return ctx.safeBlock, ctx.finalizedBlock
+}
+
+func headerAdvanced(previous, current *types.Header) bool {
+	if current == nil {
+		return false
+	}
+
+	return previous == nil || current.Number.Cmp(previous.Number) > 0
+}
+
+func headerNumberAndHash(header *types.Header) (uint64, []byte) {
+	if header == nil {
+		return 0, nil
+	}
+
+	return header.Number.Uint64(), header.Hash().Bytes()
+}
+
+// RecordNewBlock emit a Firehose NEW_BLOCK event once a block ended by END_BLOCK is applied on
+// top of the canonical chain. Together with UNDO_BLOCK, it lets consumers follow the canonical
+// chain from the stream alone without reconstructing fork choice themselves.
+func (ctx *Context) RecordNewBlock(header *types.Header) {
+	if ctx == nil {
+		return
+	}
+
+	ctx.recordForkStep("NEW_BLOCK", header)
+}
+
+// RecordUndoBlock emit a Firehose UNDO_BLOCK event for a previously emitted block that was
+// removed from the canonical chain by an unwind. When several blocks are removed at once,
+// it must be called for each of them, newest first.
+func (ctx *Context) RecordUndoBlock(header *types.Header) {
+	if ctx == nil {
+		return
+	}
+
+	ctx.recordForkStep("UNDO_BLOCK", header)
+}
+
+func (ctx *Context) recordForkStep(kind string, header *types.Header) {
+	if ctx.inBlock.Load() {
+		panic(fmt.Sprintf("recording %s while in block scope", kind))
+	}
+
+	step := pb.StepNew
+	if kind == "UNDO_BLOCK" {
+		step = pb.StepUndo
+	}
+
+	number, hash := header.Number.Uint64(), header.Hash()
+	ctx.print(&Record{
+		Kind:    kind,
+		Message: &pb.ForkStep{Step: step, Number: number, Hash: hash.Bytes(), ParentHash: header.ParentHash.Bytes()},
+		fields: func() []string {
+			return []string{Uint64(number), Hash(hash), Hash(header.ParentHash)}
+		},
+	})
 }
 
 // Transaction methods
@@ -209,6 +431,7 @@ func (ctx *Context) StartTransaction(tx types.Transaction, baseFee *big.Int) {
 
 	hash := tx.Hash()
 	v, r, s := tx.RawSignatureValues()
+	maxFeePerBlobGas, blobHashes := blobFields(tx)
 
 	ctx.StartTransactionRaw(
 		hash,
@@ -225,6 +448,8 @@ func (ctx *Context) StartTransaction(tx types.Transaction, baseFee *big.Int) {
 		maxFeePerGas(tx),
 		maxPriorityFeePerGas(tx),
 		tx.Type(),
+		maxFeePerBlobGas,
+		blobHashes,
 	)
 }
 
@@ -233,7 +458,7 @@ func maxFeePerGas(tx types.Transaction) *big.Int {
 	case types.LegacyTxType, types.AccessListTxType:
 		return nil
 
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.BlobTxType:
 		// return tx.GasFeeCap()
 		return tx.GetFeeCap().ToBig()
 	}
@@ -246,7 +471,7 @@ func maxPriorityFeePerGas(tx types.Transaction) *big.Int {
 	case types.LegacyTxType, types.AccessListTxType:
 		return nil
 
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.BlobTxType:
 		// May not be correct
 		// return tx.GasTipCap()
 		return tx.GetTip().ToBig()
@@ -260,7 +485,7 @@ func gasPrice(tx types.Transaction, baseFee *big.Int) *uint256.Int {
 	case types.LegacyTxType, types.AccessListTxType:
 		return tx.GetPrice()
 
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.BlobTxType:
 		if baseFee == nil {
 			return tx.GetPrice()
 		}
@@ -276,6 +501,32 @@ func gasPrice(tx types.Transaction, baseFee *big.Int) *uint256.Int {
 	panic(errUnhandledTransactionType("gasPrice", tx.Type()))
 }
 
+// blobFields returns the blob gas fee cap and the blob versioned hashes of a blob transaction, in
+// its canonical or network form, and nothing for other types.
+func blobFields(tx types.Transaction) (*big.Int, []libcommon.Hash) {
+	switch tx := tx.(type) {
+	case *types.BlobTx:
+		return tx.MaxFeePerBlobGas.ToBig(), tx.BlobVersionedHashes
+	case *types.BlobTxWrapper:
+		return tx.MaxFeePerBlobGas.ToBig(), tx.BlobVersionedHashes
+	}
+
+	if tx.Type() == types.BlobTxType {
+		panic(errUnhandledTransactionType("blobFields", tx.Type()))
+	}
+	return nil, nil
+}
+
+// blobHashesBytes concatenates the given blob versioned hashes.
+func blobHashesBytes(hashes []libcommon.Hash) []byte {
+	out := make([]byte, 0, len(hashes)*length.Hash)
+	for _, hash := range hashes {
+		out = append(out, hash[:]...)
+	}
+
+	return out
+}
+
 func errUnhandledTransactionType(tag string, value uint8) error {
 	return fmt.Errorf("unhandled transaction type's %d for firehose.%s(), carefully review the patch, if this new transaction type add new fields, think about adding them to Firehose Block format, when you see this message, it means something changed in the chain model and great care and thinking most be put here to properly understand the changes and the consequences they bring for the instrumentation", value, tag)
 }
@@ -293,6 +544,8 @@ func (ctx *Context) StartTransactionRaw(
 	maxFeePerGas *big.Int,
 	maxPriorityFeePerGas *big.Int,
 	txType uint8,
+	maxFeePerBlobGas *big.Int,
+	blobHashes []libcommon.Hash,
 ) {
 	if ctx == nil {
 		return
@@ -300,39 +553,55 @@ func (ctx *Context) StartTransactionRaw(
 
 	ctx.openTransaction()
 
-	// We start assuming the "null" value (i.e. a dot character), and update if `to` is set
-	toAsString := "."
+	ordinal := ctx.totalOrderingCounter.Inc()
+	trx := &pb.TransactionTrace{
+		Hash:                 hash.Bytes(),
+		Value:                bytesOrNil(value.Bytes()),
+		V:                    bytesOrNil(v),
+		R:                    bytesOrNil(r),
+		S:                    bytesOrNil(s),
+		GasLimit:             gasLimit,
+		GasPrice:             bytesOrNil(gasPrice.Bytes()),
+		Nonce:                nonce,
+		Input:                bytesOrNil(data),
+		AccessList:           accessListToProto(accessList),
+		MaxFeePerGas:         bigIntBytes(maxFeePerGas),
+		MaxPriorityFeePerGas: bigIntBytes(maxPriorityFeePerGas),
+		Type:                 uint32(txType),
+		BeginOrdinal:         ordinal,
+
+		MaxFeePerBlobGas: bigIntBytes(maxFeePerBlobGas),
+		BlobHashes:       blobHashesToProto(blobHashes),
+	}
 	if to != nil {
-		toAsString = Addr(*to)
-	}
-
-	maxFeePerGasAsString := "."
-	if maxFeePerGas != nil {
-		maxFeePerGasAsString = Hex(maxFeePerGas.Bytes())
-	}
-
-	maxPriorityFeePerGasAsString := "."
-	if maxPriorityFeePerGas != nil {
-		maxPriorityFeePerGasAsString = Hex(maxPriorityFeePerGas.Bytes())
-	}
-
-	ctx.printer.Print("BEGIN_APPLY_TRX",
-		Hash(hash),
-		toAsString,
-		Hex(value.Bytes()),
-		Hex(v),
-		Hex(r),
-		Hex(s),
-		Uint64(gasLimit),
-		Hex(gasPrice.Bytes()),
-		Uint64(nonce),
-		Hex(data),
-		Hex(accessList.marshal()),
-		maxFeePerGasAsString,
-		maxPriorityFeePerGasAsString,
-		Uint8(txType),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+		trx.To = to.Bytes()
+	}
+
+	ctx.print(&Record{
+		Kind:    "BEGIN_APPLY_TRX",
+		Message: trx,
+		fields: func() []string {
+			return []string{
+				Hash(hash),
+				Hex(trx.To),
+				Hex(trx.Value),
+				Hex(trx.V),
+				Hex(trx.R),
+				Hex(trx.S),
+				Uint64(gasLimit),
+				Hex(trx.GasPrice),
+				Uint64(nonce),
+				Hex(trx.Input),
+				Hex(accessList.marshal()),
+				Hex(trx.MaxFeePerGas),
+				Hex(trx.MaxPriorityFeePerGas),
+				Uint8(txType),
+				Hex(trx.MaxFeePerBlobGas),
+				Hex(blobHashesBytes(blobHashes)),
+				Uint64(ordinal),
+			}
+		},
+	})
 }
 
 func (ctx *Context) openTransaction() {
@@ -351,9 +620,11 @@ func (ctx *Context) RecordTrxFrom(from libcommon.Address) {
 		panic("the RecordTrxFrom should have been call within a transaction, something is deeply wrong")
 	}
 
-	ctx.printer.Print("TRX_FROM",
-		Addr(from),
-	)
+	ctx.print(&Record{
+		Kind:    "TRX_FROM",
+		Message: &pb.TransactionTrace{From: from.Bytes()},
+		fields:  func() []string { return []string{Addr(from)} },
+	})
 }
 
 func (ctx *Context) RecordFailedTransaction(err error) {
@@ -361,10 +632,12 @@ func (ctx *Context) RecordFailedTransaction(err error) {
 		return
 	}
 
-	ctx.printer.Print("FAILED_APPLY_TRX",
-		err.Error(),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+	reason, ordinal := err.Error(), ctx.totalOrderingCounter.Inc()
+	ctx.print(&Record{
+		Kind:    "FAILED_APPLY_TRX",
+		Message: &pb.TransactionTrace{Failed: true, FailureReason: reason, EndOrdinal: ordinal},
+		fields:  func() []string { return []string{reason, Uint64(ordinal)} },
+	})
 	if !ctx.inTransaction.CompareAndSwap(true, false) {
 		panic("exiting a transaction while not already within a transaction scope")
 	}
@@ -388,22 +661,108 @@ func (ctx *Context) EndTransaction(receipt *types.Receipt) {
 		}
 	}
 
-	ctx.printer.Print(
-		"END_APPLY_TRX",
-		Uint64(receipt.GasUsed),
-		Hex(receipt.PostState),
-		Uint64(receipt.CumulativeGasUsed),
-		Hex(receipt.Bloom[:]),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-		JSON(logItems),
-	)
+	cumulativeGasUsed, ordinal := receipt.CumulativeGasUsed, ctx.totalOrderingCounter.Inc()
+	trx := &pb.TransactionTrace{
+		GasUsed:           receipt.GasUsed,
+		PostState:         bytesOrNil(receipt.PostState),
+		CumulativeGasUsed: cumulativeGasUsed,
+		LogsBloom:         bytesOrNil(receipt.Bloom[:]),
+		BlobGasUsed:       receipt.BlobGasUsed,
+		BlobGasPrice:      bigIntBytes(receipt.BlobGasPrice),
+		EndOrdinal:        ordinal,
+		ReceiptLogs:       logsToProto(receipt.Logs),
+	}
+
+	ctx.print(&Record{
+		Kind:    "END_APPLY_TRX",
+		Message: trx,
+		fields: func() []string {
+			return []string{
+				Uint64(trx.GasUsed),
+				Hex(trx.PostState),
+				Uint64(cumulativeGasUsed),
+				Hex(trx.LogsBloom),
+				Uint64(trx.BlobGasUsed),
+				Hex(trx.BlobGasPrice),
+				Uint64(ordinal),
+				JSON(logItems),
+			}
+		},
+	})
+
+	ctx.resetCalls()
+}
 
+func (ctx *Context) resetCalls() {
 	ctx.nextCallIndex = 0
-	ctx.activeCallIndex = "0"
+	ctx.activeCallIndex = 0
 	ctx.callIndexStack = &ExtendedStack{}
 	ctx.callIndexStack.Push(ctx.activeCallIndex)
 }
 
+// System call methods
+
+// StartSystemCall opens the scope of a state mutation done outside of any transaction by the
+// protocol or the consensus engine, like block rewards or validator contract calls. Calls and
+// changes recorded until EndSystemCall are reported as a pseudo-transaction of the block with
+// the given reason.
+//
+// Scopes don't nest, a system call started within a transaction or another system call is
+// recorded as part of it.
+func (ctx *Context) StartSystemCall(reason SystemCallReason) {
+	ctx.startSystemCall(reason, nil)
+}
+
+// StartSystemTransaction is StartSystemCall for a mutation that is carried by a transaction of
+// the block, like the system transactions of Parlia, `hash` being the transaction's hash.
+func (ctx *Context) StartSystemTransaction(reason SystemCallReason, hash libcommon.Hash) {
+	ctx.startSystemCall(reason, hash.Bytes())
+}
+
+func (ctx *Context) startSystemCall(reason SystemCallReason, hash []byte) {
+	if ctx == nil {
+		return
+	}
+
+	ctx.systemCallDepth++
+	if ctx.systemCallDepth > 1 || ctx.inTransaction.Load() {
+		return
+	}
+
+	ctx.inSystemCall = true
+	ordinal := ctx.totalOrderingCounter.Inc()
+	ctx.print(&Record{
+		Kind:    "BEGIN_SYSTEM_CALL",
+		Message: &pb.SystemCall{Reason: string(reason), TrxHash: hash, BeginOrdinal: ordinal},
+		fields:  func() []string { return []string{string(reason), Hex(hash), Uint64(ordinal)} },
+	})
+}
+
+func (ctx *Context) EndSystemCall() {
+	if ctx == nil {
+		return
+	}
+
+	if ctx.systemCallDepth == 0 {
+		panic("exiting a system call while not already within a system call scope")
+	}
+
+	ctx.systemCallDepth--
+	if ctx.systemCallDepth > 0 || !ctx.inSystemCall {
+		return
+	}
+
+	ctx.inSystemCall = false
+	ordinal := ctx.totalOrderingCounter.Inc()
+	ctx.print(&Record{
+		Kind:    "END_SYSTEM_CALL",
+		Message: &pb.SystemCall{EndOrdinal: ordinal},
+		fields:  func() []string { return []string{Uint64(ordinal)} },
+	})
+
+	ctx.resetCalls()
+}
+
 // Call methods
 
 func (ctx *Context) StartCall(callType string) {
@@ -411,24 +770,25 @@ func (ctx *Context) StartCall(callType string) {
 		return
 	}
 
-	ctx.printer.Print("EVM_RUN_CALL",
-		callType,
-		ctx.openCall(),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
-
+	index, ordinal := ctx.openCall(), ctx.totalOrderingCounter.Inc()
+	ctx.print(&Record{
+		Kind:      "EVM_RUN_CALL",
+		CallIndex: index,
+		Message:   &pb.Call{CallType: callType, Index: index, BeginOrdinal: ordinal},
+		fields:    func() []string { return []string{callType, Uint32(index), Uint64(ordinal)} },
+	})
 }
 
-func (ctx *Context) openCall() string {
+func (ctx *Context) openCall() uint32 {
 	ctx.nextCallIndex++
-	ctx.activeCallIndex = strconv.FormatUint(ctx.nextCallIndex, 10)
+	ctx.activeCallIndex = ctx.nextCallIndex
 
 	ctx.callIndexStack.Push(ctx.activeCallIndex)
 
 	return ctx.activeCallIndex
 }
 
-func (ctx *Context) callIndex() string {
+func (ctx *Context) callIndex() uint32 {
 	if ctx.seenBlock.Load() && !ctx.inBlock.Load() {
 		debug.PrintStack()
 		panic("should have been call in a block, something is deeply wrong")
@@ -442,15 +802,23 @@ func (ctx *Context) RecordCallParams(callType string, caller libcommon.Address,
 		return
 	}
 
-	ctx.printer.Print("EVM_PARAM",
-		callType,
-		ctx.callIndex(),
-		Addr(caller),
-		Addr(callee),
-		Hex(value.ToBig().Bytes()),
-		Uint64(gasLimit),
-		Hex(input),
-	)
+	index := ctx.callIndex()
+	call := &pb.Call{
+		CallType: callType,
+		Caller:   caller.Bytes(),
+		Address:  callee.Bytes(),
+		Value:    bytesOrNil(value.Bytes()),
+		GasLimit: gasLimit,
+		Input:    bytesOrNil(input),
+	}
+	ctx.print(&Record{
+		Kind:      "EVM_PARAM",
+		CallIndex: index,
+		Message:   call,
+		fields: func() []string {
+			return []string{callType, Uint32(index), Addr(caller), Addr(callee), Hex(call.Value), Uint64(gasLimit), Hex(call.Input)}
+		},
+	})
 }
 
 func (ctx *Context) RecordCallWithoutCode() {
@@ -458,9 +826,13 @@ func (ctx *Context) RecordCallWithoutCode() {
 		return
 	}
 
-	ctx.printer.Print("ACCOUNT_WITHOUT_CODE",
-		ctx.callIndex(),
-	)
+	index := ctx.callIndex()
+	ctx.print(&Record{
+		Kind:      "ACCOUNT_WITHOUT_CODE",
+		CallIndex: index,
+		Message:   &pb.Call{AccountWithoutCode: true},
+		fields:    func() []string { return []string{Uint32(index)} },
+	})
 }
 
 func (ctx *Context) RecordCallFailed(gasLeft uint64, reason string) {
@@ -468,11 +840,13 @@ func (ctx *Context) RecordCallFailed(gasLeft uint64, reason string) {
 		return
 	}
 
-	ctx.printer.Print("EVM_CALL_FAILED",
-		ctx.callIndex(),
-		Uint64(gasLeft),
-		reason,
-	)
+	index := ctx.callIndex()
+	ctx.print(&Record{
+		Kind:      "EVM_CALL_FAILED",
+		CallIndex: index,
+		Message:   &pb.Call{Failed: true, FailureGasLeft: gasLeft, FailureReason: reason},
+		fields:    func() []string { return []string{Uint32(index), Uint64(gasLeft), reason} },
+	})
 }
 
 func (ctx *Context) RecordCallReverted() {
@@ -480,12 +854,16 @@ func (ctx *Context) RecordCallReverted() {
 		return
 	}
 
-	ctx.printer.Print("EVM_REVERTED",
-		ctx.callIndex(),
-	)
+	index := ctx.callIndex()
+	ctx.print(&Record{
+		Kind:      "EVM_REVERTED",
+		CallIndex: index,
+		Message:   &pb.Call{Reverted: true},
+		fields:    func() []string { return []string{Uint32(index)} },
+	})
 }
 
-func (ctx *Context) closeCall() string {
+func (ctx *Context) closeCall() uint32 {
 	previousIndex := ctx.callIndexStack.MustPop()
 	ctx.activeCallIndex = ctx.callIndexStack.MustPeek()
 
@@ -497,12 +875,20 @@ func (ctx *Context) EndCall(gasLeft uint64, returnValue []byte) {
 		return
 	}
 
-	ctx.printer.Print("EVM_END_CALL",
-		ctx.closeCall(),
-		Uint64(gasLeft),
-		Hex(returnValue),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+	ctx.endCall(gasLeft, returnValue)
+}
+
+func (ctx *Context) endCall(gasLeft uint64, returnValue []byte) {
+	index, ordinal := ctx.closeCall(), ctx.totalOrderingCounter.Inc()
+	call := &pb.Call{GasLeft: gasLeft, ReturnData: bytesOrNil(returnValue), EndOrdinal: ordinal}
+	ctx.print(&Record{
+		Kind:      "EVM_END_CALL",
+		CallIndex: index,
+		Message:   call,
+		fields: func() []string {
+			return []string{Uint32(index), Uint64(gasLeft), Hex(call.ReturnData), Uint64(ordinal)}
+		},
+	})
 }
 
 // EndFailedCall is works similarly to EndCall but actualy also prints extra required line
@@ -523,12 +909,7 @@ func (ctx *Context) EndFailedCall(gasLeft uint64, reverted bool, reason string)
 		gasLeft = 0
 	}
 
-	ctx.printer.Print("EVM_END_CALL",
-		ctx.closeCall(),
-		Uint64(gasLeft),
-		Hex(nil),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+	ctx.endCall(gasLeft, nil)
 }
 
 // In-call methods
@@ -538,11 +919,14 @@ func (ctx *Context) RecordKeccak(hashOfdata libcommon.Hash, data []byte) {
 		return
 	}
 
-	ctx.printer.Print("EVM_KECCAK",
-		ctx.callIndex(),
-		Hash(hashOfdata),
-		Hex(data),
-	)
+	index := ctx.callIndex()
+	preimage := &pb.KeccakPreimage{Hash: hashOfdata.Bytes(), Data: bytesOrNil(data)}
+	ctx.print(&Record{
+		Kind:      "EVM_KECCAK",
+		CallIndex: index,
+		Message:   preimage,
+		fields:    func() []string { return []string{Uint32(index), Hash(hashOfdata), Hex(preimage.Data)} },
+	})
 }
 
 func (ctx *Context) RecordGasRefund(gasOld, gasRefund uint64) {
@@ -551,13 +935,7 @@ func (ctx *Context) RecordGasRefund(gasOld, gasRefund uint64) {
 	}
 
 	if gasRefund != 0 {
-		ctx.printer.Print("GAS_CHANGE",
-			ctx.callIndex(),
-			Uint64(gasOld),
-			Uint64(gasOld+gasRefund),
-			string(RefundAfterExecutionGasChangeReason),
-			Uint64(ctx.totalOrderingCounter.Inc()),
-		)
+		ctx.recordGasChange(gasOld, gasOld+gasRefund, RefundAfterExecutionGasChangeReason)
 	}
 }
 
@@ -567,29 +945,43 @@ func (ctx *Context) RecordGasConsume(gasOld, gasConsumed uint64, reason GasChang
 	}
 
 	if gasConsumed != 0 && reason != IgnoredGasChangeReason {
-		ctx.printer.Print("GAS_CHANGE",
-			ctx.callIndex(),
-			Uint64(gasOld),
-			Uint64(gasOld-gasConsumed),
-			string(reason),
-			Uint64(ctx.totalOrderingCounter.Inc()),
-		)
+		ctx.recordGasChange(gasOld, gasOld-gasConsumed, reason)
 	}
 }
 
+func (ctx *Context) recordGasChange(gasOld, gasNew uint64, reason GasChangeReason) {
+	index, ordinal := ctx.callIndex(), ctx.totalOrderingCounter.Inc()
+	ctx.print(&Record{
+		Kind:      "GAS_CHANGE",
+		CallIndex: index,
+		Message:   &pb.GasChange{OldValue: gasOld, NewValue: gasNew, Reason: string(reason), Ordinal: ordinal},
+		fields: func() []string {
+			return []string{Uint32(index), Uint64(gasOld), Uint64(gasNew), string(reason), Uint64(ordinal)}
+		},
+	})
+}
+
 func (ctx *Context) RecordStorageChange(addr libcommon.Address, key *libcommon.Hash, oldData, newData *uint256.Int) {
 	if ctx == nil {
 		return
 	}
 
-	ctx.printer.Print("STORAGE_CHANGE",
-		ctx.callIndex(),
-		Addr(addr),
-		Hash(*key),
-		Hash(libcommon.BigToHash(oldData.ToBig())),
-		Hash(libcommon.BigToHash(newData.ToBig())),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+	index, ordinal := ctx.callIndex(), ctx.totalOrderingCounter.Inc()
+	storageKey, oldValue, newValue := *key, libcommon.Hash(oldData.Bytes32()), libcommon.Hash(newData.Bytes32())
+	ctx.print(&Record{
+		Kind:      "STORAGE_CHANGE",
+		CallIndex: index,
+		Message: &pb.StorageChange{
+			Address:  addr.Bytes(),
+			Key:      storageKey.Bytes(),
+			OldValue: oldValue.Bytes(),
+			NewValue: newValue.Bytes(),
+			Ordinal:  ordinal,
+		},
+		fields: func() []string {
+			return []string{Uint32(index), Addr(addr), Hash(storageKey), Hash(oldValue), Hash(newValue), Uint64(ordinal)}
+		},
+	})
 }
 
 func (ctx *Context) RecordBalanceChange(addr libcommon.Address, oldBalance, newBalance *uint256.Int, reason BalanceChangeReason) {
@@ -603,14 +995,22 @@ func (ctx *Context) RecordBalanceChange(addr libcommon.Address, oldBalance, newB
 		//           reduce a lot the storage space at the expense of CPU time to compute the delta and recomputed
 		//           the new balance in place where it's required. This would need to be computed (the space
 		//           savings) to see if it make sense to apply it or not.
-		ctx.printer.Print("BALANCE_CHANGE",
-			ctx.callIndex(),
-			Addr(addr),
-			Hex(oldBalance.Bytes()),
-			Hex(newBalance.Bytes()),
-			string(reason),
-			Uint64(ctx.totalOrderingCounter.Inc()),
-		)
+		index, ordinal := ctx.callIndex(), ctx.totalOrderingCounter.Inc()
+		change := &pb.BalanceChange{
+			Address:  addr.Bytes(),
+			OldValue: bytesOrNil(oldBalance.Bytes()),
+			NewValue: bytesOrNil(newBalance.Bytes()),
+			Reason:   string(reason),
+			Ordinal:  ordinal,
+		}
+		ctx.print(&Record{
+			Kind:      "BALANCE_CHANGE",
+			CallIndex: index,
+			Message:   change,
+			fields: func() []string {
+				return []string{Uint32(index), Addr(addr), Hex(change.OldValue), Hex(change.NewValue), string(reason), Uint64(ordinal)}
+			},
+		})
 	}
 }
 
@@ -619,23 +1019,34 @@ func (ctx *Context) RecordLog(log *types.Log) {
 		return
 	}
 
-	strtopics := make([]string, len(log.Topics))
-	for idx, topic := range log.Topics {
-		strtopics[idx] = Hash(topic)
-	}
-
-	ctx.printer.Print("ADD_LOG",
-		ctx.callIndex(),
-		ctx.logIndexInBlock(),
-		Addr(log.Address),
-		strings.Join(strtopics, ","),
-		Hex(log.Data),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+	index, logIndex, ordinal := ctx.callIndex(), ctx.logIndexInBlock(), ctx.totalOrderingCounter.Inc()
+	protoLog := logToProto(log)
+	protoLog.BlockIndex = uint32(logIndex)
+	protoLog.Ordinal = ordinal
+	ctx.print(&Record{
+		Kind:      "ADD_LOG",
+		CallIndex: index,
+		Message:   protoLog,
+		fields: func() []string {
+			strtopics := make([]string, len(protoLog.Topics))
+			for idx, topic := range protoLog.Topics {
+				strtopics[idx] = Hex(topic)
+			}
+
+			return []string{
+				Uint32(index),
+				Uint64(logIndex),
+				Hex(protoLog.Address),
+				strings.Join(strtopics, ","),
+				Hex(protoLog.Data),
+				Uint64(ordinal),
+			}
+		},
+	})
 }
 
-func (ctx *Context) logIndexInBlock() string {
-	current := strconv.FormatUint(ctx.blockLogIndex, 10)
+func (ctx *Context) logIndexInBlock() uint64 {
+	current := ctx.blockLogIndex
 	ctx.blockLogIndex++
 	return current
 }
@@ -646,12 +1057,14 @@ func (ctx *Context) RecordSuicide(addr libcommon.Address, suicided bool, balance
 	}
 
 	// This infers a balance change, a reduction from this account. In the `opSuicide` op code, the corresponding AddBalance is emitted.
-	ctx.printer.Print("SUICIDE_CHANGE",
-		ctx.callIndex(),
-		Addr(addr),
-		Bool(suicided),
-		Hex(balanceBeforeSuicide.Bytes()),
-	)
+	index := ctx.callIndex()
+	change := &pb.SuicideChange{Address: addr.Bytes(), Suicided: suicided, BalanceBefore: bytesOrNil(balanceBeforeSuicide.Bytes())}
+	ctx.print(&Record{
+		Kind:      "SUICIDE_CHANGE",
+		CallIndex: index,
+		Message:   change,
+		fields:    func() []string { return []string{Uint32(index), Addr(addr), Bool(suicided), Hex(change.BalanceBefore)} },
+	})
 
 	if balanceBeforeSuicide.Sign() != 0 {
 		// We need to explicit add a balance change removing the suicided contract balance since
@@ -666,11 +1079,13 @@ func (ctx *Context) RecordNewAccount(addr libcommon.Address) {
 		return
 	}
 
-	ctx.printer.Print("CREATED_ACCOUNT",
-		ctx.callIndex(),
-		Addr(addr),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+	index, ordinal := ctx.callIndex(), ctx.totalOrderingCounter.Inc()
+	ctx.print(&Record{
+		Kind:      "CREATED_ACCOUNT",
+		CallIndex: index,
+		Message:   &pb.AccountCreation{Address: addr.Bytes(), Ordinal: ordinal},
+		fields:    func() []string { return []string{Uint32(index), Addr(addr), Uint64(ordinal)} },
+	})
 }
 
 func (ctx *Context) RecordCodeChange(addr libcommon.Address, oldCodeHash, oldCode []byte, newCodeHash libcommon.Hash, newCode []byte) {
@@ -678,15 +1093,23 @@ func (ctx *Context) RecordCodeChange(addr libcommon.Address, oldCodeHash, oldCod
 		return
 	}
 
-	ctx.printer.Print("CODE_CHANGE",
-		ctx.callIndex(),
-		Addr(addr),
-		Hex(oldCodeHash),
-		Hex(oldCode),
-		Hash(newCodeHash),
-		Hex(newCode),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+	index, ordinal := ctx.callIndex(), ctx.totalOrderingCounter.Inc()
+	change := &pb.CodeChange{
+		Address: addr.Bytes(),
+		OldHash: bytesOrNil(oldCodeHash),
+		OldCode: bytesOrNil(oldCode),
+		NewHash: newCodeHash.Bytes(),
+		NewCode: bytesOrNil(newCode),
+		Ordinal: ordinal,
+	}
+	ctx.print(&Record{
+		Kind:      "CODE_CHANGE",
+		CallIndex: index,
+		Message:   change,
+		fields: func() []string {
+			return []string{Uint32(index), Addr(addr), Hex(change.OldHash), Hex(change.OldCode), Hash(newCodeHash), Hex(change.NewCode), Uint64(ordinal)}
+		},
+	})
 }
 
 func (ctx *Context) RecordNonceChange(addr libcommon.Address, oldNonce, newNonce uint64) {
@@ -694,52 +1117,166 @@ func (ctx *Context) RecordNonceChange(addr libcommon.Address, oldNonce, newNonce
 		return
 	}
 
-	ctx.printer.Print("NONCE_CHANGE",
-		ctx.callIndex(),
-		Addr(addr),
-		Uint64(oldNonce),
-		Uint64(newNonce),
-		Uint64(ctx.totalOrderingCounter.Inc()),
-	)
+	index, ordinal := ctx.callIndex(), ctx.totalOrderingCounter.Inc()
+	ctx.print(&Record{
+		Kind:      "NONCE_CHANGE",
+		CallIndex: index,
+		Message:   &pb.NonceChange{Address: addr.Bytes(), OldValue: oldNonce, NewValue: newNonce, Ordinal: ordinal},
+		fields: func() []string {
+			return []string{Uint32(index), Addr(addr), Uint64(oldNonce), Uint64(newNonce), Uint64(ordinal)}
+		},
+	})
 }
 
 // Mempool methods
 
-func (ctx *Context) RecordTrxPool(eventType string, tx types.Transaction, err error) {
+// TrxPoolDropReason tells why a transaction left the pool without being included nor replaced.
+type TrxPoolDropReason string
+
+var (
+	// TrxPoolNonceTooLow is used when a new block moved the nonce of the sender past the transaction
+	TrxPoolNonceTooLow TrxPoolDropReason = "nonce_too_low"
+	// TrxPoolEvicted is used when the pool discarded the transaction, on overflow or when it became invalid
+	TrxPoolEvicted TrxPoolDropReason = "evicted"
+)
+
+// RecordTrxPoolAdded records a transaction entering the pool. The sender is recovered
+// with the given signer, when recovery fails the sender is empty and the error recorded.
+func (ctx *Context) RecordTrxPoolAdded(tx types.Transaction, signer *types.Signer) {
 	if ctx == nil {
 		return
 	}
 
-	signer := types.LatestSignerForChainID(tx.GetChainID().ToBig())
+	trx, senderErr := trxPoolTransaction(tx, signer)
+	ctx.print(&Record{
+		Kind:    "TRX_POOL_ADDED",
+		Message: &pb.MempoolEvent{Type: pb.MempoolEventAdded, Transaction: trx, SenderError: errorString(senderErr)},
+		fields:  func() []string { return append(trxPoolFields(tx, trx), trxPoolError(senderErr)) },
+	})
+}
 
-	fromAsString := "."
-	from, sigErr := signer.Sender(tx)
-	if sigErr == nil {
-		fromAsString = Addr(from)
+// RecordTrxPoolReplaced records a transaction entering the pool in place of the transaction
+// `replaced` of the same sender and nonce.
+func (ctx *Context) RecordTrxPoolReplaced(tx types.Transaction, signer *types.Signer, replaced libcommon.Hash) {
+	if ctx == nil {
+		return
 	}
 
-	toAsString := "."
-	if tx.GetTo() != nil {
-		toAsString = Addr(*tx.GetTo())
+	trx, senderErr := trxPoolTransaction(tx, signer)
+	ctx.print(&Record{
+		Kind:    "TRX_POOL_REPLACED",
+		Message: &pb.MempoolEvent{Type: pb.MempoolEventReplaced, Transaction: trx, Hash: replaced.Bytes(), SenderError: errorString(senderErr)},
+		fields:  func() []string { return append(trxPoolFields(tx, trx), Hash(replaced), trxPoolError(senderErr)) },
+	})
+}
+
+// RecordTrxPoolDropped records a transaction leaving the pool without being included nor replaced.
+func (ctx *Context) RecordTrxPoolDropped(hash libcommon.Hash, reason TrxPoolDropReason) {
+	if ctx == nil {
+		return
 	}
 
+	ctx.print(&Record{
+		Kind:    "TRX_POOL_DROPPED",
+		Message: &pb.MempoolEvent{Type: pb.MempoolEventDropped, Hash: hash.Bytes(), Reason: string(reason)},
+		fields:  func() []string { return []string{Hash(hash), string(reason)} },
+	})
+}
+
+// RecordTrxPoolIncluded records a transaction leaving the pool because it was included in
+// the canonical block `blockNum`.
+func (ctx *Context) RecordTrxPoolIncluded(hash libcommon.Hash, blockNum uint64, blockHash libcommon.Hash) {
+	if ctx == nil {
+		return
+	}
+
+	ctx.print(&Record{
+		Kind:    "TRX_POOL_INCLUDED",
+		Message: &pb.MempoolEvent{Type: pb.MempoolEventIncluded, Hash: hash.Bytes(), BlockNumber: blockNum, BlockHash: blockHash.Bytes()},
+		fields:  func() []string { return []string{Hash(hash), Uint64(blockNum), Hash(blockHash)} },
+	})
+}
+
+// trxPoolTransaction is the transaction of a mempool event, laid out like the ones of blocks
+// without the ordinal (the gas price of dynamic fee transactions is their fee cap), along with
+// the error recovering its sender, in which case the sender is empty.
+func trxPoolTransaction(tx types.Transaction, signer *types.Signer) (*pb.TransactionTrace, error) {
 	v, r, s := tx.RawSignatureValues()
+	maxFeePerBlobGas, blobHashes := blobFields(tx)
+
+	trx := &pb.TransactionTrace{
+		Hash:                 tx.Hash().Bytes(),
+		Value:                bytesOrNil(tx.GetValue().Bytes()),
+		V:                    bytesOrNil(v.Bytes()),
+		R:                    bytesOrNil(r.Bytes()),
+		S:                    bytesOrNil(s.Bytes()),
+		GasLimit:             tx.GetGas(),
+		GasPrice:             bytesOrNil(gasPrice(tx, nil).Bytes()),
+		Nonce:                tx.GetNonce(),
+		Input:                bytesOrNil(tx.GetData()),
+		AccessList:           accessListToProto(AccessList(tx.GetAccessList())),
+		MaxFeePerGas:         bigIntBytes(maxFeePerGas(tx)),
+		MaxPriorityFeePerGas: bigIntBytes(maxPriorityFeePerGas(tx)),
+		Type:                 uint32(tx.Type()),
+
+		MaxFeePerBlobGas: bigIntBytes(maxFeePerBlobGas),
+		BlobHashes:       blobHashesToProto(blobHashes),
+	}
+	if to := tx.GetTo(); to != nil {
+		trx.To = to.Bytes()
+	}
 
-	//todo: handle error message
-	ctx.printer.Print(
-		eventType,
-		Hash(tx.Hash()),
-		fromAsString,
-		toAsString,
-		Hex(tx.GetValue().Bytes()),
-		Hex(v.Bytes()),
-		Hex(r.Bytes()),
-		Hex(s.Bytes()),
-		Uint64(tx.GetGas()),
-		Hex(tx.GetPrice().Bytes()),
-		Uint64(tx.GetNonce()),
-		Hex(tx.GetData()),
-	)
+	from, senderErr := signer.Sender(tx)
+	if senderErr == nil {
+		trx.From = from.Bytes()
+	}
+
+	return trx, senderErr
+}
+
+// trxPoolFields are the fields of the transaction of a mempool event, the ones of `BEGIN_APPLY_TRX`
+// with the sender in place of the ordinal.
+func trxPoolFields(tx types.Transaction, trx *pb.TransactionTrace) []string {
+	_, blobHashes := blobFields(tx)
+
+	return []string{
+		Hex(trx.Hash),
+		Hex(trx.To),
+		Hex(trx.Value),
+		Hex(trx.V),
+		Hex(trx.R),
+		Hex(trx.S),
+		Uint64(trx.GasLimit),
+		Hex(trx.GasPrice),
+		Uint64(trx.Nonce),
+		Hex(trx.Input),
+		Hex(AccessList(tx.GetAccessList()).marshal()),
+		Hex(trx.MaxFeePerGas),
+		Hex(trx.MaxPriorityFeePerGas),
+		Uint8(tx.Type()),
+		Hex(trx.MaxFeePerBlobGas),
+		Hex(blobHashesBytes(blobHashes)),
+		Hex(trx.From),
+	}
+}
+
+// errorString is the message of an error, empty when there is none.
+func errorString(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return ""
+}
+
+// trxPoolError is the field of an error, it's always the last field of the record since the
+// message is free-form.
+func trxPoolError(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	return "."
 }
 
 type AccessList types2.AccessList
@@ -787,3 +1324,46 @@ func (l AccessList) marshal() (out []byte) {
 
 	return out[0:offset]
 }
+
+// unmarshal is the inverse of `marshal`, it reads back an access list from its Firehose
+// binary format.
+func (l *AccessList) unmarshal(in []byte) error {
+	count, n := binary.Uvarint(in)
+	if n <= 0 {
+		return fmt.Errorf("invalid access list length")
+	}
+	in = in[n:]
+
+	*l = nil
+	for i := uint64(0); i < count; i++ {
+		if len(in) < 20 {
+			return fmt.Errorf("access list tuple %d: not enough bytes for address", i)
+		}
+
+		tuple := types2.AccessTuple{Address: libcommon.BytesToAddress(in[:20])}
+		in = in[20:]
+
+		keyCount, n := binary.Uvarint(in)
+		if n <= 0 {
+			return fmt.Errorf("access list tuple %d: invalid storage keys length", i)
+		}
+		in = in[n:]
+
+		if uint64(len(in)) < keyCount*32 {
+			return fmt.Errorf("access list tuple %d: not enough bytes for storage keys", i)
+		}
+
+		for j := uint64(0); j < keyCount; j++ {
+			tuple.StorageKeys = append(tuple.StorageKeys, libcommon.BytesToHash(in[:32]))
+			in = in[32:]
+		}
+
+		*l = append(*l, tuple)
+	}
+
+	if len(in) != 0 {
+		return fmt.Errorf("access list has %d trailing bytes", len(in))
+	}
+
+	return nil
+}
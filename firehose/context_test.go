@@ -115,6 +115,28 @@ func hash(t *testing.T, in string) libcommon.Hash {
 	return libcommon.HexToHash(in)
 }
 
+func TestRecordForkchoice(t *testing.T) {
+	printer := NewToBufferPrinter()
+	ctx := NewContext(printer)
+
+	header := func(number int64) *types.Header { return &types.Header{Number: big.NewInt(number)} }
+	safe, finalized := ctx.Forkchoice()
+	assert.Nil(t, safe)
+	assert.Nil(t, finalized)
+
+	ctx.RecordForkchoice(header(5), nil)
+	ctx.RecordForkchoice(header(5), header(3))
+	// No progress, the update is stored but not recorded
+	ctx.RecordForkchoice(header(4), header(3))
+
+	safe, finalized = ctx.Forkchoice()
+	assert.Equal(t, header(4), safe)
+	assert.Equal(t, header(3), finalized)
+
+	assert.Equal(t, "DMLOG FINALITY_UPDATE 5 "+Hash(header(5).Hash())+" 0 .\n"+
+		"DMLOG FINALITY_UPDATE 5 "+Hash(header(5).Hash())+" 3 "+Hash(header(3).Hash())+"\n", printer.Buffer().String())
+}
+
 func TestEndBlock_DataMarshaling(t *testing.T) {
 	header := &types.Header{}
 	uncles := []*types.Block{}
@@ -130,5 +152,5 @@ func TestEndBlock_DataMarshaling(t *testing.T) {
 		"finalizedBlockHash": finalizedBlock.Header().Hash(),
 	}
 
-	require.Equal(t, `{"finalizedBlockHash":"0x38b1c79e3acb45df1ac1fbd4d70e08c655874fc592c5c86342a29baf4f001769","finalizedBlockNum":"0x800","header":{"parentHash":"0x0000000000000000000000000000000000000000000000000000000000000000","sha3Uncles":"0x0000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","stateRoot":"0x0000000000000000000000000000000000000000000000000000000000000000","transactionsRoot":"0x0000000000000000000000000000000000000000000000000000000000000000","receiptsRoot":"0x0000000000000000000000000000000000000000000000000000000000000000","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","difficulty":null,"number":null,"gasLimit":"0x0","gasUsed":"0x0","timestamp":"0x0","extraData":"0x","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","baseFeePerGas":null,"withdrawalsRoot":null,"hash":"0xc3bd2d00745c03048a5616146a96f5ff78e54efb9e5b04af208cdaff6f3830ee"},"totalDifficulty":"0x400","uncles":[]}`, JSON(endData))
+	require.Equal(t, `{"finalizedBlockHash":"0x38b1c79e3acb45df1ac1fbd4d70e08c655874fc592c5c86342a29baf4f001769","finalizedBlockNum":"0x800","header":{"parentHash":"0x0000000000000000000000000000000000000000000000000000000000000000","sha3Uncles":"0x0000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","stateRoot":"0x0000000000000000000000000000000000000000000000000000000000000000","transactionsRoot":"0x0000000000000000000000000000000000000000000000000000000000000000","receiptsRoot":"0x0000000000000000000000000000000000000000000000000000000000000000","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","difficulty":null,"number":null,"gasLimit":"0x0","gasUsed":"0x0","timestamp":"0x0","extraData":"0x","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","baseFeePerGas":null,"withdrawalsRoot":null,"blobGasUsed":null,"excessBlobGas":null,"hash":"0xc3bd2d00745c03048a5616146a96f5ff78e54efb9e5b04af208cdaff6f3830ee"},"totalDifficulty":"0x400","uncles":[]}`, JSON(endData))
 }
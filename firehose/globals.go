@@ -11,6 +11,10 @@ var Enabled = false
 // speculative execution.
 var SyncInstrumentationEnabled = true
 
+// MempoolEnabled determines if the transactions entering and leaving the transaction pool are
+// recorded, it's disabled by default as the pending flow is much bigger than the blocks one.
+var MempoolEnabled = false
+
 // BlockProgressEnabled enable output of finalize block line only.
 //
 // Currently, when taking backups, the best way to know about current
@@ -0,0 +1,322 @@
+// Package mempool records the transactions flowing through a transaction pool as Firehose
+// mempool events.
+//
+// The pool lives in erigon-lib and exposes no hook for its discards, so the observer follows
+// the two streams driving and driven by the pool, served the same way by the pool embedded in
+// Erigon and by `cmd/txpool`: the `OnAdd` stream of the transactions entering its pending and
+// base fee sub-pools, and the state changes of the chain the pool applies on each new block.
+//
+// A transaction entering the pool at the sender and nonce of a tracked transaction replaced
+// it, the pool keeping a single transaction per sender and nonce. A new block includes the
+// tracked transactions it carries, and drops the ones of its senders below their new nonce,
+// as the pool does. The transactions the pool evicts, because their fee became too low or a
+// sub-pool overflowed, leave no event: after each block, the tracked transactions are looked
+// up by hash, and the ones the pool doesn't know anymore are dropped as evicted at the next
+// block, once it's known they were not included in it.
+package mempool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/chain"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/ledgerwatch/log/v3"
+	"google.golang.org/grpc"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// retryDelay is the delay before subscribing again to a stream which failed.
+const retryDelay = 3 * time.Second
+
+// Pool is the part of the transaction pool gRPC client used by the observer.
+type Pool interface {
+	OnAdd(ctx context.Context, in *txpool_proto.OnAddRequest, opts ...grpc.CallOption) (txpool_proto.Txpool_OnAddClient, error)
+	Transactions(ctx context.Context, in *txpool_proto.TransactionsRequest, opts ...grpc.CallOption) (*txpool_proto.TransactionsReply, error)
+}
+
+// StateChangesClient is the stream of the chain state changes the pool follows.
+type StateChangesClient interface {
+	StateChanges(ctx context.Context, in *remote.StateChangeRequest, opts ...grpc.CallOption) (remote.KV_StateChangesClient, error)
+}
+
+type pooledTx struct {
+	sender  libcommon.Address
+	nonce   uint64
+	missing bool // Unknown of the pool at the last block
+}
+
+// Observer records the transactions entering and leaving a transaction pool.
+type Observer struct {
+	pool         Pool
+	stateChanges StateChangesClient
+	signer       *types.Signer
+	ctx          *firehose.Context
+
+	txs     map[libcommon.Hash]*pooledTx
+	senders map[libcommon.Address]map[uint64]libcommon.Hash
+}
+
+// NewObserver creates an observer of pool recording to ctx, following the state changes of the
+// chain the pool is attached to.
+func NewObserver(pool Pool, stateChanges StateChangesClient, chainConfig *chain.Config, ctx *firehose.Context) *Observer {
+	return &Observer{
+		pool:         pool,
+		stateChanges: stateChanges,
+		signer:       types.LatestSigner(chainConfig),
+		ctx:          ctx,
+		txs:          map[libcommon.Hash]*pooledTx{},
+		senders:      map[libcommon.Address]map[uint64]libcommon.Hash{},
+	}
+}
+
+// Run follows the pool and the chain until ctx is done. The streams are subscribed to again
+// when they fail, the events in between being lost.
+func (o *Observer) Run(ctx context.Context) {
+	added := make(chan *txpool_proto.OnAddReply, 128)
+	changes := make(chan *remote.StateChangeBatch, 128)
+	go follow(ctx, "pool additions", func() error { return o.receiveAdded(ctx, added) })
+	go follow(ctx, "state changes", func() error { return o.receiveStateChanges(ctx, changes) })
+
+	for {
+		// Additions go first, a transaction added just before the block including it is known when the block comes
+		select {
+		case reply := <-added:
+			o.onAdded(reply)
+			continue
+		default:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case reply := <-added:
+			o.onAdded(reply)
+		case batch := <-changes:
+			if err := o.onStateChanges(ctx, batch); err != nil && ctx.Err() == nil {
+				log.Warn("Firehose mempool lookup of pooled transactions failed", "err", err)
+			}
+		}
+	}
+}
+
+func follow(ctx context.Context, stream string, receive func() error) {
+	for {
+		err := receive()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn("Firehose mempool stream interrupted, subscribing again", "stream", stream, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (o *Observer) receiveAdded(ctx context.Context, added chan<- *txpool_proto.OnAddReply) error {
+	stream, err := o.pool.OnAdd(ctx, &txpool_proto.OnAddRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		return err
+	}
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		select {
+		case added <- reply:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (o *Observer) receiveStateChanges(ctx context.Context, changes chan<- *remote.StateChangeBatch) error {
+	stream, err := o.stateChanges.StateChanges(ctx, &remote.StateChangeRequest{WithStorage: false, WithTransactions: true}, grpc.WaitForReady(true))
+	if err != nil {
+		return err
+	}
+	for {
+		batch, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		select {
+		case changes <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// onAdded records the transactions entering the pool, as added or replacing the transaction of
+// the same sender and nonce.
+func (o *Observer) onAdded(reply *txpool_proto.OnAddReply) {
+	for _, rlpTx := range reply.RplTxs {
+		if len(rlpTx) == 0 {
+			continue
+		}
+		tx, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(rlpTx), uint64(len(rlpTx))))
+		if err != nil {
+			log.Warn("Firehose mempool could not decode added transaction", "err", err)
+			continue
+		}
+		hash := tx.Hash()
+		if pooled, known := o.txs[hash]; known {
+			pooled.missing = false
+			continue
+		}
+
+		sender, err := tx.Sender(*o.signer)
+		if err != nil {
+			// Without sender, the transaction can't be told included or dropped by nonce, nor replaced
+			o.ctx.RecordTrxPoolAdded(tx, o.signer)
+			continue
+		}
+		nonces := o.senders[sender]
+		if nonces == nil {
+			nonces = map[uint64]libcommon.Hash{}
+			o.senders[sender] = nonces
+		}
+		replaced, taken := nonces[tx.GetNonce()]
+		if taken {
+			delete(o.txs, replaced)
+		}
+		nonces[tx.GetNonce()] = hash
+		o.txs[hash] = &pooledTx{sender: sender, nonce: tx.GetNonce()}
+
+		if taken {
+			o.ctx.RecordTrxPoolReplaced(tx, o.signer, replaced)
+		} else {
+			o.ctx.RecordTrxPoolAdded(tx, o.signer)
+		}
+	}
+}
+
+// onStateChanges records the transactions included by the new blocks, and the ones dropped by
+// the new nonces of their senders, then looks up the remaining ones in the pool to find the
+// evicted ones. The transactions of unwound blocks go back to the pool and come back as added.
+func (o *Observer) onStateChanges(ctx context.Context, batch *remote.StateChangeBatch) error {
+	for _, change := range batch.ChangeBatch {
+		if change.Direction != remote.Direction_FORWARD {
+			continue
+		}
+
+		blockHash := gointerfaces.ConvertH256ToHash(change.BlockHash)
+		for _, rlpTx := range change.Txs {
+			tx, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(rlpTx), uint64(len(rlpTx))))
+			if err != nil {
+				log.Warn("Firehose mempool could not decode block transaction", "block", change.BlockHeight, "err", err)
+				continue
+			}
+			if o.forget(tx.Hash()) {
+				o.ctx.RecordTrxPoolIncluded(tx.Hash(), change.BlockHeight, blockHash)
+			}
+		}
+
+		for _, accountChange := range change.Changes {
+			if accountChange.Action != remote.Action_UPSERT && accountChange.Action != remote.Action_UPSERT_CODE {
+				continue
+			}
+			nonces := o.senders[gointerfaces.ConvertH160toAddress(accountChange.Address)]
+			if len(nonces) == 0 {
+				continue
+			}
+			var account accounts.Account
+			if err := account.DecodeForStorage(accountChange.Data); err != nil {
+				log.Warn("Firehose mempool could not decode account", "block", change.BlockHeight, "err", err)
+				continue
+			}
+			var dropped []libcommon.Hash
+			for nonce, hash := range nonces {
+				if nonce < account.Nonce {
+					dropped = append(dropped, hash)
+				}
+			}
+			for _, hash := range sortHashes(dropped) {
+				o.forget(hash)
+				o.ctx.RecordTrxPoolDropped(hash, firehose.TrxPoolNonceTooLow)
+			}
+		}
+	}
+
+	return o.settleMissing(ctx)
+}
+
+// settleMissing drops as evicted the transactions the pool didn't know at the previous block
+// and still doesn't, and marks the ones it doesn't know anymore.
+func (o *Observer) settleMissing(ctx context.Context) error {
+	if len(o.txs) == 0 {
+		return nil
+	}
+
+	hashes := make([]libcommon.Hash, 0, len(o.txs))
+	for hash := range o.txs {
+		hashes = append(hashes, hash)
+	}
+	sortHashes(hashes)
+	request := &txpool_proto.TransactionsRequest{Hashes: make([]*types2.H256, len(hashes))}
+	for i, hash := range hashes {
+		request.Hashes[i] = gointerfaces.ConvertHashToH256(hash)
+	}
+	reply, err := o.pool.Transactions(ctx, request)
+	if err != nil {
+		return err
+	}
+	if len(reply.RlpTxs) != len(hashes) {
+		return fmt.Errorf("pool returned %d transactions for %d hashes", len(reply.RlpTxs), len(hashes))
+	}
+
+	for i, hash := range hashes {
+		pooled := o.txs[hash]
+		known := len(reply.RlpTxs[i]) > 0
+		switch {
+		case known:
+			pooled.missing = false
+		case pooled.missing:
+			o.forget(hash)
+			o.ctx.RecordTrxPoolDropped(hash, firehose.TrxPoolEvicted)
+		default:
+			pooled.missing = true
+		}
+	}
+
+	return nil
+}
+
+// forget stops tracking the transaction, it reports whether it was tracked.
+func (o *Observer) forget(hash libcommon.Hash) bool {
+	pooled, ok := o.txs[hash]
+	if !ok {
+		return false
+	}
+	delete(o.txs, hash)
+
+	if nonces := o.senders[pooled.sender]; nonces[pooled.nonce] == hash {
+		delete(nonces, pooled.nonce)
+		if len(nonces) == 0 {
+			delete(o.senders, pooled.sender)
+		}
+	}
+	return true
+}
+
+// sortHashes gives a deterministic order to the events of a batch.
+func sortHashes(hashes []libcommon.Hash) []libcommon.Hash {
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	return hashes
+}
@@ -0,0 +1,264 @@
+package mempool
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// fakePool knows the transactions in its set, and streams the additions sent to its channel.
+type fakePool struct {
+	known map[libcommon.Hash]bool
+	added chan *txpool_proto.OnAddReply
+}
+
+func (p *fakePool) OnAdd(ctx context.Context, _ *txpool_proto.OnAddRequest, _ ...grpc.CallOption) (txpool_proto.Txpool_OnAddClient, error) {
+	return &fakeStream[txpool_proto.OnAddReply]{ctx: ctx, replies: p.added}, nil
+}
+
+func (p *fakePool) Transactions(_ context.Context, request *txpool_proto.TransactionsRequest, _ ...grpc.CallOption) (*txpool_proto.TransactionsReply, error) {
+	reply := &txpool_proto.TransactionsReply{RlpTxs: make([][]byte, len(request.Hashes))}
+	for i, hash := range request.Hashes {
+		if p.known[gointerfaces.ConvertH256ToHash(hash)] {
+			reply.RlpTxs[i] = []byte{0x01}
+		}
+	}
+	return reply, nil
+}
+
+type fakeStateChanges struct {
+	batches chan *remote.StateChangeBatch
+}
+
+func (c *fakeStateChanges) StateChanges(ctx context.Context, _ *remote.StateChangeRequest, _ ...grpc.CallOption) (remote.KV_StateChangesClient, error) {
+	return &fakeStream[remote.StateChangeBatch]{ctx: ctx, replies: c.batches}, nil
+}
+
+type fakeStream[T any] struct {
+	grpc.ClientStream
+	ctx     context.Context
+	replies chan *T
+}
+
+func (s *fakeStream[T]) Recv() (*T, error) {
+	select {
+	case reply, ok := <-s.replies:
+		if !ok {
+			return nil, io.EOF
+		}
+		return reply, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func TestObserver(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := testKey(t, "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"), testKey(t, "8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+
+	pending := testTx(t, alice, 0, 1)
+	replacement := testTx(t, alice, 0, 2)
+	mined := testTx(t, alice, 1, 1)
+	evicted := testTx(t, alice, 2, 1)
+	stale := testTx(t, bob, 0, 1)
+	transient := testTx(t, bob, 5, 1)
+
+	pool := &fakePool{known: map[libcommon.Hash]bool{}}
+	printer := firehose.NewToBufferPrinter()
+	observer := NewObserver(pool, nil, params.MainnetChainConfig, firehose.NewContext(printer))
+
+	observer.onAdded(addReply(t, pool, pending, mined, evicted, stale))
+	assert.Equal(t, []string{
+		"TRX_POOL_ADDED " + pending.Hash().Hex()[2:],
+		"TRX_POOL_ADDED " + mined.Hash().Hex()[2:],
+		"TRX_POOL_ADDED " + evicted.Hash().Hex()[2:],
+		"TRX_POOL_ADDED " + stale.Hash().Hex()[2:],
+	}, records(printer))
+
+	// Announced again, a known transaction is not an event
+	observer.onAdded(addReply(t, pool, mined))
+	assert.Empty(t, records(printer))
+
+	// Entering at the same sender and nonce, a transaction replaces the pooled one
+	observer.onAdded(addReply(t, pool, replacement))
+	assert.Equal(t, []string{"TRX_POOL_REPLACED " + replacement.Hash().Hex()[2:] + " " + pending.Hash().Hex()[2:]}, lastFields(records(printer)))
+
+	// A transaction entering and leaving the pool between two blocks is recorded all the same
+	observer.onAdded(addReply(t, pool, transient))
+	delete(pool.known, transient.Hash())
+	delete(pool.known, evicted.Hash())
+	assert.Equal(t, []string{"TRX_POOL_ADDED " + transient.Hash().Hex()[2:]}, records(printer))
+
+	// The block includes its transactions, drops the ones below the new nonces of the senders, and the ones unknown
+	// of the pool wait for the next block to be told evicted
+	delete(pool.known, mined.Hash())
+	blockHash := libcommon.Hash{0x12}
+	require.NoError(t, observer.onStateChanges(ctx, stateChanges(t, 12, blockHash, []types.Transaction{mined}, accountNonce{alice, 2}, accountNonce{bob, 3})))
+	assert.Equal(t, []string{
+		"TRX_POOL_INCLUDED " + mined.Hash().Hex()[2:] + " 12 " + blockHash.Hex()[2:],
+		"TRX_POOL_DROPPED " + replacement.Hash().Hex()[2:] + " nonce_too_low",
+		"TRX_POOL_DROPPED " + stale.Hash().Hex()[2:] + " nonce_too_low",
+	}, records(printer))
+
+	require.NoError(t, observer.onStateChanges(ctx, stateChanges(t, 13, libcommon.Hash{0x13}, nil)))
+	assert.ElementsMatch(t, []string{
+		"TRX_POOL_DROPPED " + evicted.Hash().Hex()[2:] + " evicted",
+		"TRX_POOL_DROPPED " + transient.Hash().Hex()[2:] + " evicted",
+	}, records(printer))
+	assert.Empty(t, observer.txs)
+	assert.Empty(t, observer.senders)
+
+	require.NoError(t, observer.onStateChanges(ctx, stateChanges(t, 14, libcommon.Hash{0x14}, nil)))
+	assert.Empty(t, records(printer))
+}
+
+func TestObserverRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	alice := testKey(t, "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	tx := testTx(t, alice, 0, 1)
+
+	pool := &fakePool{known: map[libcommon.Hash]bool{}, added: make(chan *txpool_proto.OnAddReply)}
+	changes := &fakeStateChanges{batches: make(chan *remote.StateChangeBatch)}
+	printer := &syncedPrinter{ToBufferPrinter: firehose.NewToBufferPrinter()}
+	go NewObserver(pool, changes, params.MainnetChainConfig, firehose.NewContext(printer)).Run(ctx)
+
+	pool.added <- addReply(t, pool, tx)
+	delete(pool.known, tx.Hash())
+	changes.batches <- stateChanges(t, 1, libcommon.Hash{0x01}, []types.Transaction{tx})
+
+	require.Eventually(t, func() bool {
+		printer.lock.Lock()
+		defer printer.lock.Unlock()
+		return strings.Contains(printer.Buffer().String(), "TRX_POOL_INCLUDED")
+	}, 5*time.Second, 10*time.Millisecond)
+	printer.lock.Lock()
+	defer printer.lock.Unlock()
+	assert.Equal(t, []string{
+		"TRX_POOL_ADDED " + tx.Hash().Hex()[2:],
+		"TRX_POOL_INCLUDED " + tx.Hash().Hex()[2:] + " 1 " + libcommon.Hash{0x01}.Hex()[2:],
+	}, records(printer.ToBufferPrinter))
+}
+
+type syncedPrinter struct {
+	*firehose.ToBufferPrinter
+	lock sync.Mutex
+}
+
+func (p *syncedPrinter) Print(input ...string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.ToBufferPrinter.Print(input...)
+}
+
+// addReply is the pool announcing the transactions it has just added
+func addReply(t *testing.T, pool *fakePool, txs ...types.Transaction) *txpool_proto.OnAddReply {
+	t.Helper()
+
+	reply := &txpool_proto.OnAddReply{}
+	for _, tx := range txs {
+		var buffer bytes.Buffer
+		require.NoError(t, tx.MarshalBinary(&buffer))
+		reply.RplTxs = append(reply.RplTxs, buffer.Bytes())
+		pool.known[tx.Hash()] = true
+	}
+	return reply
+}
+
+// accountNonce is the nonce of the account of a key after a block.
+type accountNonce struct {
+	key   *ecdsa.PrivateKey
+	nonce uint64
+}
+
+// stateChanges is the batch of a new block with the transactions and the new nonces of the senders,
+// in the given order
+func stateChanges(t *testing.T, blockNum uint64, blockHash libcommon.Hash, txs []types.Transaction, nonces ...accountNonce) *remote.StateChangeBatch {
+	t.Helper()
+
+	change := &remote.StateChange{Direction: remote.Direction_FORWARD, BlockHeight: blockNum, BlockHash: gointerfaces.ConvertHashToH256(blockHash)}
+	for _, tx := range txs {
+		var buffer bytes.Buffer
+		require.NoError(t, tx.MarshalBinary(&buffer))
+		change.Txs = append(change.Txs, buffer.Bytes())
+	}
+	for _, nonce := range nonces {
+		account := accounts.NewAccount()
+		account.Nonce = nonce.nonce
+		data := make([]byte, account.EncodingLengthForStorage())
+		account.EncodeForStorage(data)
+		change.Changes = append(change.Changes, &remote.AccountChange{
+			Address: gointerfaces.ConvertAddressToH160(crypto.PubkeyToAddress(nonce.key.PublicKey)),
+			Action:  remote.Action_UPSERT,
+			Data:    data,
+		})
+	}
+	return &remote.StateChangeBatch{ChangeBatch: []*remote.StateChange{change}}
+}
+
+// records returns the records printed since the last call, the transaction fields of added and
+// replaced transactions are trimmed to their hash, and the replaced hash for the latter.
+func records(printer *firehose.ToBufferPrinter) (out []string) {
+	defer printer.Buffer().Reset()
+
+	for _, line := range strings.Split(strings.TrimSpace(printer.Buffer().String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(strings.TrimPrefix(line, "DMLOG "), " ")
+		if fields[0] == "TRX_POOL_ADDED" {
+			fields = fields[:2]
+		}
+		out = append(out, strings.Join(fields, " "))
+	}
+
+	return out
+}
+
+// lastFields keeps the record name, the hash and the replaced hash of replaced records.
+func lastFields(records []string) (out []string) {
+	for _, record := range records {
+		fields := strings.Split(record, " ")
+		// The replaced hash follows the sender, before the sender recovery error
+		out = append(out, strings.Join([]string{fields[0], fields[1], fields[len(fields)-2]}, " "))
+	}
+	return out
+}
+
+func testKey(t *testing.T, hex string) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := crypto.HexToECDSA(hex)
+	require.NoError(t, err)
+	return key
+}
+
+func testTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, gasPrice uint64) types.Transaction {
+	t.Helper()
+
+	tx := types.NewTransaction(nonce, libcommon.Address{0xcc}, uint256.NewInt(1), 21_000, uint256.NewInt(gasPrice), nil)
+	signed, err := types.SignTx(tx, *types.LatestSigner(params.MainnetChainConfig), key)
+	require.NoError(t, err)
+	return signed
+}
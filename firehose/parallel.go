@@ -0,0 +1,194 @@
+package firehose
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ledgerwatch/erigon/firehose/pb"
+)
+
+// Parallel executors (Erigon 3 `exec3`) run the tasks of a block concurrently, so they cannot
+// share the block's Context which tracks ordinals, log indexes and call indexes sequentially.
+// Instead, each task records into its own buffer context created by NewTxBufferContext and
+// the buffers are merged into the block's Context in transaction order with MergeTxBuffer,
+// the merged output being identical to the one of a serial execution.
+
+// blockOrdinalFields is the position of the block-wide ordinal in records that have one.
+var blockOrdinalFields = func() map[string]int {
+	out := map[string]int{}
+	for kind, schema := range Schema {
+		for i, field := range schema.Fields {
+			if field == "ordinal" {
+				out[kind] = i
+			}
+		}
+	}
+	return out
+}()
+
+var (
+	addLogIndexField              = Schema["ADD_LOG"].Index("block_index")
+	endApplyTrxGasUsedField       = Schema["END_APPLY_TRX"].Index("gas_used")
+	endApplyTrxCumulativeGasField = Schema["END_APPLY_TRX"].Index("cumulative_gas_used")
+)
+
+// messageOrdinal returns the block-wide ordinal of the message of a record, nil for the
+// records without one.
+func messageOrdinal(kind string, message interface{}) *uint64 {
+	switch msg := message.(type) {
+	case *pb.TransactionTrace:
+		switch kind {
+		case "BEGIN_APPLY_TRX":
+			return &msg.BeginOrdinal
+		case "FAILED_APPLY_TRX", "END_APPLY_TRX":
+			return &msg.EndOrdinal
+		}
+	case *pb.SystemCall:
+		switch kind {
+		case "BEGIN_SYSTEM_CALL":
+			return &msg.BeginOrdinal
+		case "END_SYSTEM_CALL":
+			return &msg.EndOrdinal
+		}
+	case *pb.Call:
+		switch kind {
+		case "EVM_RUN_CALL":
+			return &msg.BeginOrdinal
+		case "EVM_END_CALL":
+			return &msg.EndOrdinal
+		}
+	case *pb.GasChange:
+		return &msg.Ordinal
+	case *pb.StorageChange:
+		return &msg.Ordinal
+	case *pb.BalanceChange:
+		return &msg.Ordinal
+	case *pb.Log:
+		return &msg.Ordinal
+	case *pb.AccountCreation:
+		return &msg.Ordinal
+	case *pb.CodeChange:
+		return &msg.Ordinal
+	case *pb.NonceChange:
+		return &msg.Ordinal
+	}
+
+	return nil
+}
+
+type recordingPrinter struct {
+	records []*Record
+}
+
+func (p *recordingPrinter) Disabled() bool {
+	return false
+}
+
+func (p *recordingPrinter) Print(input ...string) {
+	p.records = append(p.records, &Record{Kind: input[0], input: input})
+}
+
+func (p *recordingPrinter) PrintRecord(record *Record) {
+	p.records = append(p.records, record)
+}
+
+// NewTxBufferContext returns a Context recording a single task of a parallel executor, be
+// it a transaction or the block initialisation or finalisation, in memory. It's already
+// within block scope, and ordinals, log indexes as well as the cumulative gas used of the
+// transaction receipt are relative to the start of the task.
+func NewTxBufferContext() *Context {
+	ctx := NewContext(&recordingPrinter{})
+	ctx.seenBlock.Store(true)
+	ctx.inBlock.Store(true)
+
+	return ctx
+}
+
+// MergeTxBuffer prints the records of a context created by NewTxBufferContext, rebasing its
+// relative ordinals, log indexes and cumulative gas used on the ones of the block. Buffers
+// must be merged in transaction order, within the block scope of this context.
+func (ctx *Context) MergeTxBuffer(buffer *Context) {
+	if ctx == nil {
+		return
+	}
+
+	printer, ok := buffer.printer.(*recordingPrinter)
+	if !ok {
+		panic("merging a context that was not created by NewTxBufferContext")
+	}
+
+	if !ctx.inBlock.Load() {
+		panic("merging a transaction buffer while not within a block scope")
+	}
+
+	ordinalBase := ctx.totalOrderingCounter.Load()
+	logIndexBase := ctx.blockLogIndex
+	gasUsedBase := ctx.mergedGasUsed
+
+	for _, record := range printer.records {
+		printRecord(ctx.printer, rebaseRecord(record, ordinalBase, logIndexBase, gasUsedBase))
+
+		if record.Kind == "END_APPLY_TRX" {
+			if trx, ok := record.Message.(*pb.TransactionTrace); ok {
+				ctx.mergedGasUsed += trx.GasUsed
+			} else {
+				ctx.mergedGasUsed += mustParseUint64(record.Fields()[1+endApplyTrxGasUsedField])
+			}
+		}
+	}
+
+	ctx.totalOrderingCounter.Add(buffer.totalOrderingCounter.Load())
+	ctx.blockLogIndex += buffer.blockLogIndex
+}
+
+// rebaseRecord returns the record of a buffer with its ordinal, log index and cumulative gas
+// used rebased on the ones of the block, in its message as well as in its text fields.
+func rebaseRecord(record *Record, ordinalBase, logIndexBase, gasUsedBase uint64) *Record {
+	if ordinal := messageOrdinal(record.Kind, record.Message); ordinal != nil {
+		*ordinal += ordinalBase
+	}
+
+	switch msg := record.Message.(type) {
+	case *pb.Log:
+		msg.BlockIndex += uint32(logIndexBase)
+	case *pb.TransactionTrace:
+		if record.Kind == "END_APPLY_TRX" {
+			msg.CumulativeGasUsed += gasUsedBase
+		}
+	}
+
+	kind := record.Kind
+	return &Record{
+		Kind:      kind,
+		CallIndex: record.CallIndex,
+		Message:   record.Message,
+		fields: func() []string {
+			fields := append([]string(nil), record.Fields()[1:]...)
+			if i, ok := blockOrdinalFields[kind]; ok {
+				fields[i] = rebaseUint64(fields[i], ordinalBase)
+			}
+
+			switch kind {
+			case "ADD_LOG":
+				fields[addLogIndexField] = rebaseUint64(fields[addLogIndexField], logIndexBase)
+			case "END_APPLY_TRX":
+				fields[endApplyTrxCumulativeGasField] = rebaseUint64(fields[endApplyTrxCumulativeGasField], gasUsedBase)
+			}
+
+			return fields
+		},
+	}
+}
+
+func rebaseUint64(value string, base uint64) string {
+	return Uint64(mustParseUint64(value) + base)
+}
+
+func mustParseUint64(value string) uint64 {
+	out, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		panic(fmt.Errorf("invalid recorded number %q: %w", value, err))
+	}
+
+	return out
+}
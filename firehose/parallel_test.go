@@ -0,0 +1,110 @@
+package firehose
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/firehose/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeTxBuffer_IdenticalToSerial(t *testing.T) {
+	callee := testAddr(0xcc)
+	var txs []types.Transaction
+	for i := 0; i < 3; i++ {
+		txs = append(txs, types.NewTransaction(uint64(i), callee, uint256.NewInt(10), 100_000, uint256.NewInt(5), []byte{byte(i)}))
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(7), Difficulty: big.NewInt(2), BaseFee: big.NewInt(7)}, txs, nil, nil, nil)
+
+	gasUsed := func(i int) uint64 { return 21_000 + uint64(i)*1_000 }
+
+	// The output is checked in both formats, protobuf printers assembling the messages of the records
+	var serialMessages, mergedMessages []*pb.Message
+	serialPrinter := NewToBufferPrinter()
+	serial := NewContext(NewTeePrinter(serialPrinter, NewProtobufHandlerPrinter(func(msg *pb.Message) {
+		serialMessages = append(serialMessages, msg)
+	})))
+	serial.StartBlock(block)
+	recordTestInitialize(serial)
+	var cumulativeGasUsed uint64
+	for i, tx := range txs {
+		cumulativeGasUsed += gasUsed(i)
+		recordTestTx(serial, tx, i, gasUsed(i), cumulativeGasUsed)
+	}
+	serial.FinalizeBlock(block)
+	recordTestFinalize(serial)
+	serial.EndBlock(block, nil, big.NewInt(2))
+
+	// Tasks are executed in any order by the workers, the last transaction first here
+	initialize, finalize := NewTxBufferContext(), NewTxBufferContext()
+	txBuffers := make([]*Context, len(txs))
+	for i := len(txs) - 1; i >= 0; i-- {
+		txBuffers[i] = NewTxBufferContext()
+		recordTestTx(txBuffers[i], txs[i], i, gasUsed(i), gasUsed(i))
+	}
+	recordTestFinalize(finalize)
+	recordTestInitialize(initialize)
+
+	mergedPrinter := NewToBufferPrinter()
+	merged := NewContext(NewTeePrinter(mergedPrinter, NewProtobufHandlerPrinter(func(msg *pb.Message) {
+		mergedMessages = append(mergedMessages, msg)
+	})))
+	merged.StartBlock(block)
+	merged.MergeTxBuffer(initialize)
+	for _, buffer := range txBuffers {
+		merged.MergeTxBuffer(buffer)
+	}
+	merged.FinalizeBlock(block)
+	merged.MergeTxBuffer(finalize)
+	merged.EndBlock(block, nil, big.NewInt(2))
+
+	require.NotEmpty(t, serialPrinter.Buffer().String())
+	assert.Equal(t, serialPrinter.Buffer().String(), mergedPrinter.Buffer().String())
+
+	require.Len(t, serialMessages, 1)
+	assert.Equal(t, serialMessages, mergedMessages)
+}
+
+func TestMergeTxBuffer_OutsideBlock(t *testing.T) {
+	buffer := NewTxBufferContext()
+	recordTestInitialize(buffer)
+
+	assert.Panics(t, func() { NewContext(NewToBufferPrinter()).MergeTxBuffer(buffer) })
+	assert.Panics(t, func() {
+		ctx := NewContext(NewToBufferPrinter())
+		ctx.StartBlock(types.NewBlockWithHeader(testHeader(1)))
+		ctx.MergeTxBuffer(NewContext(NewToBufferPrinter()))
+	})
+}
+
+func recordTestInitialize(ctx *Context) {
+	ctx.RecordBalanceChange(testAddr(0xee), uint256.NewInt(0), uint256.NewInt(1), BalanceChangeReason("withdrawal"))
+}
+
+func recordTestFinalize(ctx *Context) {
+	ctx.RecordBalanceChange(testAddr(0xee), uint256.NewInt(1), uint256.NewInt(3), BalanceChangeReason("reward_mine_block"))
+}
+
+func recordTestTx(ctx *Context, tx types.Transaction, i int, gasUsed, cumulativeGasUsed uint64) {
+	callee := testAddr(0xcc)
+	log := &types.Log{Address: callee, Topics: []libcommon.Hash{{byte(i)}}, Data: []byte{byte(i)}}
+
+	ctx.StartTransaction(tx, big.NewInt(7))
+	ctx.RecordTrxFrom(testAddr(0xaa))
+	ctx.RecordNonceChange(testAddr(0xaa), uint64(i), uint64(i+1))
+	ctx.StartCall("CALL")
+	ctx.RecordCallParams("CALL", testAddr(0xaa), callee, uint256.NewInt(10), 79_000, []byte{byte(i)})
+	ctx.RecordGasConsume(79_000, 2_100, GasChangeReason("state_cold_access"))
+	ctx.RecordStorageChange(callee, &libcommon.Hash{byte(i)}, uint256.NewInt(0), uint256.NewInt(uint64(i)))
+	ctx.RecordLog(log)
+	// Every other transaction emits a second log, the block log index must not be a multiple of the transaction index
+	if i%2 == 0 {
+		ctx.RecordLog(log)
+	}
+	ctx.EndCall(25_000, nil)
+	ctx.EndTransaction(&types.Receipt{GasUsed: gasUsed, CumulativeGasUsed: cumulativeGasUsed, Logs: []*types.Log{log}})
+}
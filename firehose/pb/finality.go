@@ -0,0 +1,36 @@
+package pb
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type FinalityUpdate struct {
+	SafeBlockNumber      uint64
+	SafeBlockHash        []byte
+	FinalizedBlockNumber uint64
+	FinalizedBlockHash   []byte
+}
+
+func (m *FinalityUpdate) appendTo(b []byte) []byte {
+	b = appendUint64(b, 1, m.SafeBlockNumber)
+	b = appendBytes(b, 2, m.SafeBlockHash)
+	b = appendUint64(b, 3, m.FinalizedBlockNumber)
+	b = appendBytes(b, 4, m.FinalizedBlockHash)
+	return b
+}
+
+func (m *FinalityUpdate) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeUint64(b, typ, &m.SafeBlockNumber)
+		case 2:
+			return consumeBytes(b, typ, &m.SafeBlockHash)
+		case 3:
+			return consumeUint64(b, typ, &m.FinalizedBlockNumber)
+		case 4:
+			return consumeBytes(b, typ, &m.FinalizedBlockHash)
+		}
+		return -1, nil
+	})
+}
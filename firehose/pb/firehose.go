@@ -0,0 +1,998 @@
+// Package pb contains the Go types of the Firehose protobuf output format defined
+// in `firehose.proto` along with their wire encoding.
+package pb
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Message is the envelope of every record written to the protobuf stream, exactly
+// one of its fields is set (it's the `payload` oneof of the schema).
+type Message struct {
+	Init           *Init
+	Block          *Block
+	FinalizeBlock  *FinalizeBlock
+	ForkStep       *ForkStep
+	FinalityUpdate *FinalityUpdate
+	MempoolEvent   *MempoolEvent
+}
+
+func (m *Message) Marshal() []byte {
+	return m.appendTo(nil)
+}
+
+func (m *Message) Unmarshal(b []byte) error {
+	return m.unmarshal(b)
+}
+
+func (m *Message) appendTo(b []byte) []byte {
+	switch {
+	case m.Init != nil:
+		b = appendMessage(b, 1, m.Init)
+	case m.Block != nil:
+		b = appendMessage(b, 2, m.Block)
+	case m.FinalizeBlock != nil:
+		b = appendMessage(b, 3, m.FinalizeBlock)
+	case m.ForkStep != nil:
+		b = appendMessage(b, 4, m.ForkStep)
+	case m.FinalityUpdate != nil:
+		b = appendMessage(b, 5, m.FinalityUpdate)
+	case m.MempoolEvent != nil:
+		b = appendMessage(b, 6, m.MempoolEvent)
+	}
+
+	return b
+}
+
+func (m *Message) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			m.Init = &Init{}
+			return consumeMessage(b, typ, m.Init)
+		case 2:
+			m.Block = &Block{}
+			return consumeMessage(b, typ, m.Block)
+		case 3:
+			m.FinalizeBlock = &FinalizeBlock{}
+			return consumeMessage(b, typ, m.FinalizeBlock)
+		case 4:
+			m.ForkStep = &ForkStep{}
+			return consumeMessage(b, typ, m.ForkStep)
+		case 5:
+			m.FinalityUpdate = &FinalityUpdate{}
+			return consumeMessage(b, typ, m.FinalityUpdate)
+		case 6:
+			m.MempoolEvent = &MempoolEvent{}
+			return consumeMessage(b, typ, m.MempoolEvent)
+		}
+		return -1, nil
+	})
+}
+
+type Init struct {
+	FirehoseVersion string
+	Variant         string
+	NodeVersion     string
+	SchemaVersion   uint64
+}
+
+func (m *Init) appendTo(b []byte) []byte {
+	b = appendString(b, 1, m.FirehoseVersion)
+	b = appendString(b, 2, m.Variant)
+	b = appendString(b, 3, m.NodeVersion)
+	b = appendUint64(b, 4, m.SchemaVersion)
+	return b
+}
+
+func (m *Init) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(b, typ, &m.FirehoseVersion)
+		case 2:
+			return consumeString(b, typ, &m.Variant)
+		case 3:
+			return consumeString(b, typ, &m.NodeVersion)
+		case 4:
+			return consumeUint64(b, typ, &m.SchemaVersion)
+		}
+		return -1, nil
+	})
+}
+
+type FinalizeBlock struct {
+	Number uint64
+}
+
+func (m *FinalizeBlock) appendTo(b []byte) []byte {
+	return appendUint64(b, 1, m.Number)
+}
+
+func (m *FinalizeBlock) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 {
+			return consumeUint64(b, typ, &m.Number)
+		}
+		return -1, nil
+	})
+}
+
+type Block struct {
+	Number               uint64
+	Size                 uint64
+	Header               *BlockHeader
+	Uncles               []*BlockHeader
+	TotalDifficulty      []byte
+	FinalizedBlockNumber uint64
+	FinalizedBlockHash   []byte
+	Transactions         []*TransactionTrace
+
+	// Changes and UnscopedCalls are recorded outside of any transaction or system
+	// call, none are expected once every state mutation has its own scope.
+	Changes       *Changes
+	UnscopedCalls []*Call
+
+	SafeBlockNumber uint64
+	SafeBlockHash   []byte
+
+	SystemCalls []*SystemCall
+}
+
+func (m *Block) Marshal() []byte {
+	return m.appendTo(nil)
+}
+
+func (m *Block) Unmarshal(b []byte) error {
+	return m.unmarshal(b)
+}
+
+func (m *Block) appendTo(b []byte) []byte {
+	b = appendUint64(b, 1, m.Number)
+	b = appendUint64(b, 2, m.Size)
+	if m.Header != nil {
+		b = appendMessage(b, 3, m.Header)
+	}
+	for _, uncle := range m.Uncles {
+		b = appendMessage(b, 4, uncle)
+	}
+	b = appendBytes(b, 5, m.TotalDifficulty)
+	b = appendUint64(b, 6, m.FinalizedBlockNumber)
+	b = appendBytes(b, 7, m.FinalizedBlockHash)
+	for _, trx := range m.Transactions {
+		b = appendMessage(b, 8, trx)
+	}
+	if m.Changes != nil {
+		b = appendMessage(b, 9, m.Changes)
+	}
+	for _, call := range m.UnscopedCalls {
+		b = appendMessage(b, 10, call)
+	}
+	b = appendUint64(b, 11, m.SafeBlockNumber)
+	b = appendBytes(b, 12, m.SafeBlockHash)
+	for _, systemCall := range m.SystemCalls {
+		b = appendMessage(b, 13, systemCall)
+	}
+	return b
+}
+
+func (m *Block) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeUint64(b, typ, &m.Number)
+		case 2:
+			return consumeUint64(b, typ, &m.Size)
+		case 3:
+			m.Header = &BlockHeader{}
+			return consumeMessage(b, typ, m.Header)
+		case 4:
+			uncle := &BlockHeader{}
+			m.Uncles = append(m.Uncles, uncle)
+			return consumeMessage(b, typ, uncle)
+		case 5:
+			return consumeBytes(b, typ, &m.TotalDifficulty)
+		case 6:
+			return consumeUint64(b, typ, &m.FinalizedBlockNumber)
+		case 7:
+			return consumeBytes(b, typ, &m.FinalizedBlockHash)
+		case 8:
+			trx := &TransactionTrace{}
+			m.Transactions = append(m.Transactions, trx)
+			return consumeMessage(b, typ, trx)
+		case 9:
+			m.Changes = &Changes{}
+			return consumeMessage(b, typ, m.Changes)
+		case 10:
+			call := &Call{}
+			m.UnscopedCalls = append(m.UnscopedCalls, call)
+			return consumeMessage(b, typ, call)
+		case 11:
+			return consumeUint64(b, typ, &m.SafeBlockNumber)
+		case 12:
+			return consumeBytes(b, typ, &m.SafeBlockHash)
+		case 13:
+			systemCall := &SystemCall{}
+			m.SystemCalls = append(m.SystemCalls, systemCall)
+			return consumeMessage(b, typ, systemCall)
+		}
+		return -1, nil
+	})
+}
+
+// SystemCall is a state mutation done outside of any transaction by the protocol or the
+// consensus engine, with the calls and changes it's made of.
+type SystemCall struct {
+	Reason       string
+	TrxHash      []byte
+	BeginOrdinal uint64
+	EndOrdinal   uint64
+	Calls        []*Call
+	Changes      *Changes
+}
+
+func (m *SystemCall) appendTo(b []byte) []byte {
+	b = appendString(b, 1, m.Reason)
+	b = appendBytes(b, 2, m.TrxHash)
+	b = appendUint64(b, 3, m.BeginOrdinal)
+	b = appendUint64(b, 4, m.EndOrdinal)
+	for _, call := range m.Calls {
+		b = appendMessage(b, 5, call)
+	}
+	if m.Changes != nil {
+		b = appendMessage(b, 6, m.Changes)
+	}
+	return b
+}
+
+func (m *SystemCall) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(b, typ, &m.Reason)
+		case 2:
+			return consumeBytes(b, typ, &m.TrxHash)
+		case 3:
+			return consumeUint64(b, typ, &m.BeginOrdinal)
+		case 4:
+			return consumeUint64(b, typ, &m.EndOrdinal)
+		case 5:
+			call := &Call{}
+			m.Calls = append(m.Calls, call)
+			return consumeMessage(b, typ, call)
+		case 6:
+			m.Changes = &Changes{}
+			return consumeMessage(b, typ, m.Changes)
+		}
+		return -1, nil
+	})
+}
+
+type BlockHeader struct {
+	ParentHash       []byte
+	UncleHash        []byte
+	Coinbase         []byte
+	StateRoot        []byte
+	TransactionsRoot []byte
+	ReceiptRoot      []byte
+	LogsBloom        []byte
+	Difficulty       []byte
+	Number           []byte
+	GasLimit         uint64
+	GasUsed          uint64
+	Timestamp        uint64
+	ExtraData        []byte
+	MixHash          []byte
+	Nonce            []byte
+	BaseFeePerGas    []byte
+	WithdrawalsRoot  []byte
+	Hash             []byte
+
+	// Blob gas fields of EIP-4844, zero before Cancun.
+	BlobGasUsed   uint64
+	ExcessBlobGas uint64
+}
+
+func (m *BlockHeader) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.ParentHash)
+	b = appendBytes(b, 2, m.UncleHash)
+	b = appendBytes(b, 3, m.Coinbase)
+	b = appendBytes(b, 4, m.StateRoot)
+	b = appendBytes(b, 5, m.TransactionsRoot)
+	b = appendBytes(b, 6, m.ReceiptRoot)
+	b = appendBytes(b, 7, m.LogsBloom)
+	b = appendBytes(b, 8, m.Difficulty)
+	b = appendBytes(b, 9, m.Number)
+	b = appendUint64(b, 10, m.GasLimit)
+	b = appendUint64(b, 11, m.GasUsed)
+	b = appendUint64(b, 12, m.Timestamp)
+	b = appendBytes(b, 13, m.ExtraData)
+	b = appendBytes(b, 14, m.MixHash)
+	b = appendBytes(b, 15, m.Nonce)
+	b = appendBytes(b, 16, m.BaseFeePerGas)
+	b = appendBytes(b, 17, m.WithdrawalsRoot)
+	b = appendBytes(b, 18, m.Hash)
+	b = appendUint64(b, 19, m.BlobGasUsed)
+	b = appendUint64(b, 20, m.ExcessBlobGas)
+	return b
+}
+
+func (m *BlockHeader) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.ParentHash)
+		case 2:
+			return consumeBytes(b, typ, &m.UncleHash)
+		case 3:
+			return consumeBytes(b, typ, &m.Coinbase)
+		case 4:
+			return consumeBytes(b, typ, &m.StateRoot)
+		case 5:
+			return consumeBytes(b, typ, &m.TransactionsRoot)
+		case 6:
+			return consumeBytes(b, typ, &m.ReceiptRoot)
+		case 7:
+			return consumeBytes(b, typ, &m.LogsBloom)
+		case 8:
+			return consumeBytes(b, typ, &m.Difficulty)
+		case 9:
+			return consumeBytes(b, typ, &m.Number)
+		case 10:
+			return consumeUint64(b, typ, &m.GasLimit)
+		case 11:
+			return consumeUint64(b, typ, &m.GasUsed)
+		case 12:
+			return consumeUint64(b, typ, &m.Timestamp)
+		case 13:
+			return consumeBytes(b, typ, &m.ExtraData)
+		case 14:
+			return consumeBytes(b, typ, &m.MixHash)
+		case 15:
+			return consumeBytes(b, typ, &m.Nonce)
+		case 16:
+			return consumeBytes(b, typ, &m.BaseFeePerGas)
+		case 17:
+			return consumeBytes(b, typ, &m.WithdrawalsRoot)
+		case 18:
+			return consumeBytes(b, typ, &m.Hash)
+		case 19:
+			return consumeUint64(b, typ, &m.BlobGasUsed)
+		case 20:
+			return consumeUint64(b, typ, &m.ExcessBlobGas)
+		}
+		return -1, nil
+	})
+}
+
+type TransactionTrace struct {
+	Hash                 []byte
+	To                   []byte
+	Value                []byte
+	V                    []byte
+	R                    []byte
+	S                    []byte
+	GasLimit             uint64
+	GasPrice             []byte
+	Nonce                uint64
+	Input                []byte
+	AccessList           []*AccessTuple
+	MaxFeePerGas         []byte
+	MaxPriorityFeePerGas []byte
+	Type                 uint32
+	BeginOrdinal         uint64
+	From                 []byte
+
+	GasUsed           uint64
+	PostState         []byte
+	CumulativeGasUsed uint64
+	LogsBloom         []byte
+	EndOrdinal        uint64
+	ReceiptLogs       []*Log
+
+	Failed        bool
+	FailureReason string
+
+	Calls []*Call
+
+	// Changes recorded within the transaction but before its root call started.
+	Changes *Changes
+
+	// Blob transactions (EIP-4844) fields, empty for other types.
+	MaxFeePerBlobGas []byte
+	BlobHashes       [][]byte
+	BlobGasUsed      uint64
+	BlobGasPrice     []byte
+}
+
+func (m *TransactionTrace) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Hash)
+	b = appendBytes(b, 2, m.To)
+	b = appendBytes(b, 3, m.Value)
+	b = appendBytes(b, 4, m.V)
+	b = appendBytes(b, 5, m.R)
+	b = appendBytes(b, 6, m.S)
+	b = appendUint64(b, 7, m.GasLimit)
+	b = appendBytes(b, 8, m.GasPrice)
+	b = appendUint64(b, 9, m.Nonce)
+	b = appendBytes(b, 10, m.Input)
+	for _, tuple := range m.AccessList {
+		b = appendMessage(b, 11, tuple)
+	}
+	b = appendBytes(b, 12, m.MaxFeePerGas)
+	b = appendBytes(b, 13, m.MaxPriorityFeePerGas)
+	b = appendUint64(b, 14, uint64(m.Type))
+	b = appendUint64(b, 15, m.BeginOrdinal)
+	b = appendBytes(b, 16, m.From)
+	b = appendUint64(b, 17, m.GasUsed)
+	b = appendBytes(b, 18, m.PostState)
+	b = appendUint64(b, 19, m.CumulativeGasUsed)
+	b = appendBytes(b, 20, m.LogsBloom)
+	b = appendUint64(b, 21, m.EndOrdinal)
+	for _, log := range m.ReceiptLogs {
+		b = appendMessage(b, 22, log)
+	}
+	b = appendBool(b, 23, m.Failed)
+	b = appendString(b, 24, m.FailureReason)
+	for _, call := range m.Calls {
+		b = appendMessage(b, 25, call)
+	}
+	if m.Changes != nil {
+		b = appendMessage(b, 26, m.Changes)
+	}
+	b = appendBytes(b, 27, m.MaxFeePerBlobGas)
+	b = appendRepeatedBytes(b, 28, m.BlobHashes)
+	b = appendUint64(b, 29, m.BlobGasUsed)
+	b = appendBytes(b, 30, m.BlobGasPrice)
+	return b
+}
+
+func (m *TransactionTrace) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Hash)
+		case 2:
+			return consumeBytes(b, typ, &m.To)
+		case 3:
+			return consumeBytes(b, typ, &m.Value)
+		case 4:
+			return consumeBytes(b, typ, &m.V)
+		case 5:
+			return consumeBytes(b, typ, &m.R)
+		case 6:
+			return consumeBytes(b, typ, &m.S)
+		case 7:
+			return consumeUint64(b, typ, &m.GasLimit)
+		case 8:
+			return consumeBytes(b, typ, &m.GasPrice)
+		case 9:
+			return consumeUint64(b, typ, &m.Nonce)
+		case 10:
+			return consumeBytes(b, typ, &m.Input)
+		case 11:
+			tuple := &AccessTuple{}
+			m.AccessList = append(m.AccessList, tuple)
+			return consumeMessage(b, typ, tuple)
+		case 12:
+			return consumeBytes(b, typ, &m.MaxFeePerGas)
+		case 13:
+			return consumeBytes(b, typ, &m.MaxPriorityFeePerGas)
+		case 14:
+			return consumeUint32(b, typ, &m.Type)
+		case 15:
+			return consumeUint64(b, typ, &m.BeginOrdinal)
+		case 16:
+			return consumeBytes(b, typ, &m.From)
+		case 17:
+			return consumeUint64(b, typ, &m.GasUsed)
+		case 18:
+			return consumeBytes(b, typ, &m.PostState)
+		case 19:
+			return consumeUint64(b, typ, &m.CumulativeGasUsed)
+		case 20:
+			return consumeBytes(b, typ, &m.LogsBloom)
+		case 21:
+			return consumeUint64(b, typ, &m.EndOrdinal)
+		case 22:
+			log := &Log{}
+			m.ReceiptLogs = append(m.ReceiptLogs, log)
+			return consumeMessage(b, typ, log)
+		case 23:
+			return consumeBool(b, typ, &m.Failed)
+		case 24:
+			return consumeString(b, typ, &m.FailureReason)
+		case 25:
+			call := &Call{}
+			m.Calls = append(m.Calls, call)
+			return consumeMessage(b, typ, call)
+		case 26:
+			m.Changes = &Changes{}
+			return consumeMessage(b, typ, m.Changes)
+		case 27:
+			return consumeBytes(b, typ, &m.MaxFeePerBlobGas)
+		case 28:
+			return consumeRepeatedBytes(b, typ, &m.BlobHashes)
+		case 29:
+			return consumeUint64(b, typ, &m.BlobGasUsed)
+		case 30:
+			return consumeBytes(b, typ, &m.BlobGasPrice)
+		}
+		return -1, nil
+	})
+}
+
+type AccessTuple struct {
+	Address     []byte
+	StorageKeys [][]byte
+}
+
+func (m *AccessTuple) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Address)
+	b = appendRepeatedBytes(b, 2, m.StorageKeys)
+	return b
+}
+
+func (m *AccessTuple) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Address)
+		case 2:
+			return consumeRepeatedBytes(b, typ, &m.StorageKeys)
+		}
+		return -1, nil
+	})
+}
+
+type Call struct {
+	CallType           string
+	Index              uint32
+	BeginOrdinal       uint64
+	Caller             []byte
+	Address            []byte
+	Value              []byte
+	GasLimit           uint64
+	Input              []byte
+	AccountWithoutCode bool
+	Failed             bool
+	FailureGasLeft     uint64
+	FailureReason      string
+	Reverted           bool
+	GasLeft            uint64
+	ReturnData         []byte
+	EndOrdinal         uint64
+	Changes            *Changes
+}
+
+func (m *Call) appendTo(b []byte) []byte {
+	b = appendString(b, 1, m.CallType)
+	b = appendUint64(b, 2, uint64(m.Index))
+	b = appendUint64(b, 3, m.BeginOrdinal)
+	b = appendBytes(b, 4, m.Caller)
+	b = appendBytes(b, 5, m.Address)
+	b = appendBytes(b, 6, m.Value)
+	b = appendUint64(b, 7, m.GasLimit)
+	b = appendBytes(b, 8, m.Input)
+	b = appendBool(b, 9, m.AccountWithoutCode)
+	b = appendBool(b, 10, m.Failed)
+	b = appendUint64(b, 11, m.FailureGasLeft)
+	b = appendString(b, 12, m.FailureReason)
+	b = appendBool(b, 13, m.Reverted)
+	b = appendUint64(b, 14, m.GasLeft)
+	b = appendBytes(b, 15, m.ReturnData)
+	b = appendUint64(b, 16, m.EndOrdinal)
+	if m.Changes != nil {
+		b = appendMessage(b, 17, m.Changes)
+	}
+	return b
+}
+
+func (m *Call) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(b, typ, &m.CallType)
+		case 2:
+			return consumeUint32(b, typ, &m.Index)
+		case 3:
+			return consumeUint64(b, typ, &m.BeginOrdinal)
+		case 4:
+			return consumeBytes(b, typ, &m.Caller)
+		case 5:
+			return consumeBytes(b, typ, &m.Address)
+		case 6:
+			return consumeBytes(b, typ, &m.Value)
+		case 7:
+			return consumeUint64(b, typ, &m.GasLimit)
+		case 8:
+			return consumeBytes(b, typ, &m.Input)
+		case 9:
+			return consumeBool(b, typ, &m.AccountWithoutCode)
+		case 10:
+			return consumeBool(b, typ, &m.Failed)
+		case 11:
+			return consumeUint64(b, typ, &m.FailureGasLeft)
+		case 12:
+			return consumeString(b, typ, &m.FailureReason)
+		case 13:
+			return consumeBool(b, typ, &m.Reverted)
+		case 14:
+			return consumeUint64(b, typ, &m.GasLeft)
+		case 15:
+			return consumeBytes(b, typ, &m.ReturnData)
+		case 16:
+			return consumeUint64(b, typ, &m.EndOrdinal)
+		case 17:
+			m.Changes = &Changes{}
+			return consumeMessage(b, typ, m.Changes)
+		}
+		return -1, nil
+	})
+}
+
+type Changes struct {
+	BalanceChanges   []*BalanceChange
+	StorageChanges   []*StorageChange
+	NonceChanges     []*NonceChange
+	CodeChanges      []*CodeChange
+	GasChanges       []*GasChange
+	Logs             []*Log
+	KeccakPreimages  []*KeccakPreimage
+	AccountCreations []*AccountCreation
+	Suicides         []*SuicideChange
+}
+
+func (m *Changes) appendTo(b []byte) []byte {
+	for _, change := range m.BalanceChanges {
+		b = appendMessage(b, 1, change)
+	}
+	for _, change := range m.StorageChanges {
+		b = appendMessage(b, 2, change)
+	}
+	for _, change := range m.NonceChanges {
+		b = appendMessage(b, 3, change)
+	}
+	for _, change := range m.CodeChanges {
+		b = appendMessage(b, 4, change)
+	}
+	for _, change := range m.GasChanges {
+		b = appendMessage(b, 5, change)
+	}
+	for _, log := range m.Logs {
+		b = appendMessage(b, 6, log)
+	}
+	for _, preimage := range m.KeccakPreimages {
+		b = appendMessage(b, 7, preimage)
+	}
+	for _, creation := range m.AccountCreations {
+		b = appendMessage(b, 8, creation)
+	}
+	for _, suicide := range m.Suicides {
+		b = appendMessage(b, 9, suicide)
+	}
+	return b
+}
+
+func (m *Changes) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			change := &BalanceChange{}
+			m.BalanceChanges = append(m.BalanceChanges, change)
+			return consumeMessage(b, typ, change)
+		case 2:
+			change := &StorageChange{}
+			m.StorageChanges = append(m.StorageChanges, change)
+			return consumeMessage(b, typ, change)
+		case 3:
+			change := &NonceChange{}
+			m.NonceChanges = append(m.NonceChanges, change)
+			return consumeMessage(b, typ, change)
+		case 4:
+			change := &CodeChange{}
+			m.CodeChanges = append(m.CodeChanges, change)
+			return consumeMessage(b, typ, change)
+		case 5:
+			change := &GasChange{}
+			m.GasChanges = append(m.GasChanges, change)
+			return consumeMessage(b, typ, change)
+		case 6:
+			log := &Log{}
+			m.Logs = append(m.Logs, log)
+			return consumeMessage(b, typ, log)
+		case 7:
+			preimage := &KeccakPreimage{}
+			m.KeccakPreimages = append(m.KeccakPreimages, preimage)
+			return consumeMessage(b, typ, preimage)
+		case 8:
+			creation := &AccountCreation{}
+			m.AccountCreations = append(m.AccountCreations, creation)
+			return consumeMessage(b, typ, creation)
+		case 9:
+			suicide := &SuicideChange{}
+			m.Suicides = append(m.Suicides, suicide)
+			return consumeMessage(b, typ, suicide)
+		}
+		return -1, nil
+	})
+}
+
+type BalanceChange struct {
+	Address  []byte
+	OldValue []byte
+	NewValue []byte
+	Reason   string
+	Ordinal  uint64
+}
+
+func (m *BalanceChange) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Address)
+	b = appendBytes(b, 2, m.OldValue)
+	b = appendBytes(b, 3, m.NewValue)
+	b = appendString(b, 4, m.Reason)
+	b = appendUint64(b, 5, m.Ordinal)
+	return b
+}
+
+func (m *BalanceChange) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Address)
+		case 2:
+			return consumeBytes(b, typ, &m.OldValue)
+		case 3:
+			return consumeBytes(b, typ, &m.NewValue)
+		case 4:
+			return consumeString(b, typ, &m.Reason)
+		case 5:
+			return consumeUint64(b, typ, &m.Ordinal)
+		}
+		return -1, nil
+	})
+}
+
+type StorageChange struct {
+	Address  []byte
+	Key      []byte
+	OldValue []byte
+	NewValue []byte
+	Ordinal  uint64
+}
+
+func (m *StorageChange) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Address)
+	b = appendBytes(b, 2, m.Key)
+	b = appendBytes(b, 3, m.OldValue)
+	b = appendBytes(b, 4, m.NewValue)
+	b = appendUint64(b, 5, m.Ordinal)
+	return b
+}
+
+func (m *StorageChange) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Address)
+		case 2:
+			return consumeBytes(b, typ, &m.Key)
+		case 3:
+			return consumeBytes(b, typ, &m.OldValue)
+		case 4:
+			return consumeBytes(b, typ, &m.NewValue)
+		case 5:
+			return consumeUint64(b, typ, &m.Ordinal)
+		}
+		return -1, nil
+	})
+}
+
+type NonceChange struct {
+	Address  []byte
+	OldValue uint64
+	NewValue uint64
+	Ordinal  uint64
+}
+
+func (m *NonceChange) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Address)
+	b = appendUint64(b, 2, m.OldValue)
+	b = appendUint64(b, 3, m.NewValue)
+	b = appendUint64(b, 4, m.Ordinal)
+	return b
+}
+
+func (m *NonceChange) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Address)
+		case 2:
+			return consumeUint64(b, typ, &m.OldValue)
+		case 3:
+			return consumeUint64(b, typ, &m.NewValue)
+		case 4:
+			return consumeUint64(b, typ, &m.Ordinal)
+		}
+		return -1, nil
+	})
+}
+
+type CodeChange struct {
+	Address []byte
+	OldHash []byte
+	OldCode []byte
+	NewHash []byte
+	NewCode []byte
+	Ordinal uint64
+}
+
+func (m *CodeChange) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Address)
+	b = appendBytes(b, 2, m.OldHash)
+	b = appendBytes(b, 3, m.OldCode)
+	b = appendBytes(b, 4, m.NewHash)
+	b = appendBytes(b, 5, m.NewCode)
+	b = appendUint64(b, 6, m.Ordinal)
+	return b
+}
+
+func (m *CodeChange) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Address)
+		case 2:
+			return consumeBytes(b, typ, &m.OldHash)
+		case 3:
+			return consumeBytes(b, typ, &m.OldCode)
+		case 4:
+			return consumeBytes(b, typ, &m.NewHash)
+		case 5:
+			return consumeBytes(b, typ, &m.NewCode)
+		case 6:
+			return consumeUint64(b, typ, &m.Ordinal)
+		}
+		return -1, nil
+	})
+}
+
+type GasChange struct {
+	OldValue uint64
+	NewValue uint64
+	Reason   string
+	Ordinal  uint64
+}
+
+func (m *GasChange) appendTo(b []byte) []byte {
+	b = appendUint64(b, 1, m.OldValue)
+	b = appendUint64(b, 2, m.NewValue)
+	b = appendString(b, 3, m.Reason)
+	b = appendUint64(b, 4, m.Ordinal)
+	return b
+}
+
+func (m *GasChange) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeUint64(b, typ, &m.OldValue)
+		case 2:
+			return consumeUint64(b, typ, &m.NewValue)
+		case 3:
+			return consumeString(b, typ, &m.Reason)
+		case 4:
+			return consumeUint64(b, typ, &m.Ordinal)
+		}
+		return -1, nil
+	})
+}
+
+type Log struct {
+	Address    []byte
+	Topics     [][]byte
+	Data       []byte
+	BlockIndex uint32
+	Ordinal    uint64
+}
+
+func (m *Log) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Address)
+	b = appendRepeatedBytes(b, 2, m.Topics)
+	b = appendBytes(b, 3, m.Data)
+	b = appendUint64(b, 4, uint64(m.BlockIndex))
+	b = appendUint64(b, 5, m.Ordinal)
+	return b
+}
+
+func (m *Log) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Address)
+		case 2:
+			return consumeRepeatedBytes(b, typ, &m.Topics)
+		case 3:
+			return consumeBytes(b, typ, &m.Data)
+		case 4:
+			return consumeUint32(b, typ, &m.BlockIndex)
+		case 5:
+			return consumeUint64(b, typ, &m.Ordinal)
+		}
+		return -1, nil
+	})
+}
+
+type KeccakPreimage struct {
+	Hash []byte
+	Data []byte
+}
+
+func (m *KeccakPreimage) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Hash)
+	b = appendBytes(b, 2, m.Data)
+	return b
+}
+
+func (m *KeccakPreimage) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Hash)
+		case 2:
+			return consumeBytes(b, typ, &m.Data)
+		}
+		return -1, nil
+	})
+}
+
+type AccountCreation struct {
+	Address []byte
+	Ordinal uint64
+}
+
+func (m *AccountCreation) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Address)
+	b = appendUint64(b, 2, m.Ordinal)
+	return b
+}
+
+func (m *AccountCreation) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Address)
+		case 2:
+			return consumeUint64(b, typ, &m.Ordinal)
+		}
+		return -1, nil
+	})
+}
+
+type SuicideChange struct {
+	Address       []byte
+	Suicided      bool
+	BalanceBefore []byte
+}
+
+func (m *SuicideChange) appendTo(b []byte) []byte {
+	b = appendBytes(b, 1, m.Address)
+	b = appendBool(b, 2, m.Suicided)
+	b = appendBytes(b, 3, m.BalanceBefore)
+	return b
+}
+
+func (m *SuicideChange) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeBytes(b, typ, &m.Address)
+		case 2:
+			return consumeBool(b, typ, &m.Suicided)
+		case 3:
+			return consumeBytes(b, typ, &m.BalanceBefore)
+		}
+		return -1, nil
+	})
+}
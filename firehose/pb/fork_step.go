@@ -0,0 +1,61 @@
+package pb
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type ForkStep struct {
+	Step       Step
+	Number     uint64
+	Hash       []byte
+	ParentHash []byte
+}
+
+func (m *ForkStep) appendTo(b []byte) []byte {
+	b = appendUint64(b, 1, uint64(m.Step))
+	b = appendUint64(b, 2, m.Number)
+	b = appendBytes(b, 3, m.Hash)
+	b = appendBytes(b, 4, m.ParentHash)
+	return b
+}
+
+func (m *ForkStep) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeStep(b, typ, &m.Step)
+		case 2:
+			return consumeUint64(b, typ, &m.Number)
+		case 3:
+			return consumeBytes(b, typ, &m.Hash)
+		case 4:
+			return consumeBytes(b, typ, &m.ParentHash)
+		}
+		return -1, nil
+	})
+}
+
+type Step int32
+
+const (
+	StepUnset Step = 0
+	StepNew   Step = 1
+	StepUndo  Step = 2
+)
+
+func (s Step) String() string {
+	switch s {
+	case StepNew:
+		return "STEP_NEW"
+	case StepUndo:
+		return "STEP_UNDO"
+	}
+	return "STEP_UNSET"
+}
+
+func consumeStep(b []byte, typ protowire.Type, out *Step) (int, error) {
+	var v uint64
+	n, err := consumeUint64(b, typ, &v)
+	*out = Step(v)
+	return n, err
+}
@@ -0,0 +1,82 @@
+package pb
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type MempoolEventType int32
+
+const (
+	MempoolEventUnset    MempoolEventType = 0
+	MempoolEventAdded    MempoolEventType = 1
+	MempoolEventReplaced MempoolEventType = 2
+	MempoolEventDropped  MempoolEventType = 3
+	MempoolEventIncluded MempoolEventType = 4
+)
+
+func (t MempoolEventType) String() string {
+	switch t {
+	case MempoolEventAdded:
+		return "MEMPOOL_EVENT_ADDED"
+	case MempoolEventReplaced:
+		return "MEMPOOL_EVENT_REPLACED"
+	case MempoolEventDropped:
+		return "MEMPOOL_EVENT_DROPPED"
+	case MempoolEventIncluded:
+		return "MEMPOOL_EVENT_INCLUDED"
+	}
+	return "MEMPOOL_EVENT_UNSET"
+}
+
+type MempoolEvent struct {
+	Type        MempoolEventType
+	Transaction *TransactionTrace
+	Hash        []byte
+	Reason      string
+	BlockNumber uint64
+	BlockHash   []byte
+	SenderError string
+}
+
+func (m *MempoolEvent) appendTo(b []byte) []byte {
+	b = appendUint64(b, 1, uint64(m.Type))
+	if m.Transaction != nil {
+		b = appendMessage(b, 2, m.Transaction)
+	}
+	b = appendBytes(b, 3, m.Hash)
+	b = appendString(b, 4, m.Reason)
+	b = appendUint64(b, 5, m.BlockNumber)
+	b = appendBytes(b, 6, m.BlockHash)
+	b = appendString(b, 7, m.SenderError)
+	return b
+}
+
+func (m *MempoolEvent) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeMempoolEventType(b, typ, &m.Type)
+		case 2:
+			m.Transaction = &TransactionTrace{}
+			return consumeMessage(b, typ, m.Transaction)
+		case 3:
+			return consumeBytes(b, typ, &m.Hash)
+		case 4:
+			return consumeString(b, typ, &m.Reason)
+		case 5:
+			return consumeUint64(b, typ, &m.BlockNumber)
+		case 6:
+			return consumeBytes(b, typ, &m.BlockHash)
+		case 7:
+			return consumeString(b, typ, &m.SenderError)
+		}
+		return -1, nil
+	})
+}
+
+func consumeMempoolEventType(b []byte, typ protowire.Type, out *MempoolEventType) (int, error) {
+	var v uint64
+	n, err := consumeUint64(b, typ, &v)
+	*out = MempoolEventType(v)
+	return n, err
+}
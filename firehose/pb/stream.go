@@ -0,0 +1,82 @@
+package pb
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type StreamRequest struct {
+	StartBlock    uint64
+	Cursor        string
+	FinalizedOnly bool
+}
+
+func (m *StreamRequest) Marshal() []byte {
+	return m.appendTo(nil)
+}
+
+func (m *StreamRequest) Unmarshal(b []byte) error {
+	return m.unmarshal(b)
+}
+
+func (m *StreamRequest) appendTo(b []byte) []byte {
+	b = appendUint64(b, 1, m.StartBlock)
+	b = appendString(b, 2, m.Cursor)
+	b = appendBool(b, 3, m.FinalizedOnly)
+	return b
+}
+
+func (m *StreamRequest) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeUint64(b, typ, &m.StartBlock)
+		case 2:
+			return consumeString(b, typ, &m.Cursor)
+		case 3:
+			return consumeBool(b, typ, &m.FinalizedOnly)
+		}
+		return -1, nil
+	})
+}
+
+type StreamResponse struct {
+	Block  *Block
+	Cursor string
+	Error  string
+	Step   Step
+}
+
+func (m *StreamResponse) Marshal() []byte {
+	return m.appendTo(nil)
+}
+
+func (m *StreamResponse) Unmarshal(b []byte) error {
+	return m.unmarshal(b)
+}
+
+func (m *StreamResponse) appendTo(b []byte) []byte {
+	if m.Block != nil {
+		b = appendMessage(b, 1, m.Block)
+	}
+	b = appendString(b, 2, m.Cursor)
+	b = appendString(b, 3, m.Error)
+	b = appendUint64(b, 4, uint64(m.Step))
+	return b
+}
+
+func (m *StreamResponse) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			m.Block = &Block{}
+			return consumeMessage(b, typ, m.Block)
+		case 2:
+			return consumeString(b, typ, &m.Cursor)
+		case 3:
+			return consumeString(b, typ, &m.Error)
+		case 4:
+			return consumeStep(b, typ, &m.Step)
+		}
+		return -1, nil
+	})
+}
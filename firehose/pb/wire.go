@@ -0,0 +1,239 @@
+package pb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var errInvalidWireType = errors.New("invalid wire type")
+
+// message is implemented by every type of this package, it's the hand-written
+// equivalent of what `protoc-gen-go` would produce for the schema.
+type message interface {
+	appendTo(b []byte) []byte
+	unmarshal(b []byte) error
+}
+
+// Delimited is implemented by the top-level messages, the ones that are exchanged as
+// standalone length-prefixed payloads.
+type Delimited interface {
+	Marshal() []byte
+	Unmarshal(b []byte) error
+}
+
+// WriteDelimited writes the message to the writer prefixed by its length encoded as
+// an unsigned varint, the framing used by Firehose protobuf output.
+func WriteDelimited(writer io.Writer, msg Delimited) error {
+	payload := msg.Marshal()
+
+	out := make([]byte, 0, protowire.SizeVarint(uint64(len(payload)))+len(payload))
+	out = protowire.AppendVarint(out, uint64(len(payload)))
+	out = append(out, payload...)
+
+	_, err := writer.Write(out)
+	return err
+}
+
+// ReadDelimited reads the next length-prefixed message from the reader into `msg`,
+// returns `io.EOF` when the stream ended cleanly between two messages.
+func ReadDelimited(reader *bufio.Reader, msg Delimited) error {
+	length, err := readUvarint(reader)
+	if err != nil {
+		return err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return fmt.Errorf("read message payload: %w", err)
+	}
+
+	return msg.Unmarshal(payload)
+}
+
+func readUvarint(reader *bufio.Reader) (uint64, error) {
+	var buf []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(buf) > 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+
+		buf = append(buf, b)
+		if b < 0x80 {
+			break
+		}
+	}
+
+	v, n := protowire.ConsumeVarint(buf)
+	if n < 0 {
+		return 0, fmt.Errorf("read message length: %w", protowire.ParseError(n))
+	}
+
+	return v, nil
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendRepeatedBytes(b []byte, num protowire.Number, values [][]byte) []byte {
+	for _, v := range values {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, v)
+	}
+
+	return b
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendUint64(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(v))
+}
+
+func appendMessage(b []byte, num protowire.Number, m message) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.appendTo(nil))
+}
+
+// consumeFields walks over all fields of `b`, calling `field` for each of them. The
+// callback returns the number of bytes it consumed for the field's value, or -1 if the
+// field is unknown in which case it's skipped.
+func consumeFields(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := field(num, typ, b)
+		if err != nil {
+			return fmt.Errorf("field %d: %w", num, err)
+		}
+
+		if n < 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return fmt.Errorf("field %d: %w", num, protowire.ParseError(n))
+			}
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+func consumeBytes(b []byte, typ protowire.Type, out *[]byte) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	// Copying ensures we do not retain the input buffer and that empty values are always nil
+	*out = append([]byte(nil), v...)
+	return n, nil
+}
+
+func consumeRepeatedBytes(b []byte, typ protowire.Type, out *[][]byte) (int, error) {
+	var v []byte
+	n, err := consumeBytes(b, typ, &v)
+	if err != nil {
+		return 0, err
+	}
+
+	*out = append(*out, v)
+	return n, nil
+}
+
+func consumeString(b []byte, typ protowire.Type, out *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeString(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	*out = v
+	return n, nil
+}
+
+func consumeUint64(b []byte, typ protowire.Type, out *uint64) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	*out = v
+	return n, nil
+}
+
+func consumeUint32(b []byte, typ protowire.Type, out *uint32) (int, error) {
+	var v uint64
+	n, err := consumeUint64(b, typ, &v)
+	*out = uint32(v)
+	return n, err
+}
+
+func consumeBool(b []byte, typ protowire.Type, out *bool) (int, error) {
+	var v uint64
+	n, err := consumeUint64(b, typ, &v)
+	*out = protowire.DecodeBool(v)
+	return n, err
+}
+
+func consumeMessage(b []byte, typ protowire.Type, m message) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, errInvalidWireType
+	}
+
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	return n, m.unmarshal(v)
+}
@@ -18,6 +18,100 @@ type Printer interface {
 	Print(input ...string)
 }
 
+// RecordPrinter is implemented by the printers building their output from the values of the
+// records rather than from their text fields, like the ProtobufPrinter. A Context hands them
+// its records with PrintRecord instead of Print.
+type RecordPrinter interface {
+	Printer
+	PrintRecord(record *Record)
+}
+
+// Record is a record made by a Context, its text fields are only formatted when asked for.
+type Record struct {
+	Kind string
+
+	// CallIndex is the index of the call the record is scoped to: the call itself for the
+	// records of a call, like EVM_PARAM, and the active call for the records of changes, 0
+	// when no call was active.
+	CallIndex uint32
+
+	// Message is the protobuf message made by the record or, for a record completing the
+	// message of a previous one like END_APPLY_TRX, the message with the fields it sets.
+	// Records without message are assembled from their text fields.
+	Message interface{}
+
+	fields func() []string
+	input  []string
+}
+
+// Fields returns the fields of the record as they are handed to Printer.Print, its kind first.
+func (r *Record) Fields() []string {
+	if r.input == nil {
+		r.input = append([]string{r.Kind}, r.fields()...)
+	}
+
+	return r.input
+}
+
+// printRecord hands the record to the printer, as a value if the printer builds its output
+// from them.
+func printRecord(printer Printer, record *Record) {
+	if printer, ok := printer.(RecordPrinter); ok {
+		printer.PrintRecord(record)
+		return
+	}
+
+	printer.Print(record.Fields()...)
+}
+
+// OutputFormat is the encoding used by a Printer writing Firehose records.
+type OutputFormat string
+
+const (
+	// TextOutputFormat prints each record as a space-separated "FIRE" line.
+	TextOutputFormat OutputFormat = "text"
+
+	// ProtobufOutputFormat prints each block as a length-prefixed protobuf message.
+	ProtobufOutputFormat OutputFormat = "protobuf"
+)
+
+// NewPrinter returns a Printer writing records to the writer in the given format.
+func NewPrinter(format OutputFormat, writer io.Writer) (Printer, error) {
+	switch format {
+	case TextOutputFormat:
+		return &DelegateToWriterPrinter{writer: writer}, nil
+	case ProtobufOutputFormat:
+		return NewProtobufPrinter(writer), nil
+	}
+
+	return nil, fmt.Errorf("unknown firehose output format %q, valid values are %q and %q", format, TextOutputFormat, ProtobufOutputFormat)
+}
+
+// TeePrinter prints every record to all of its printers, in order.
+type TeePrinter struct {
+	printers []Printer
+}
+
+func NewTeePrinter(printers ...Printer) *TeePrinter {
+	return &TeePrinter{printers: printers}
+}
+
+func (p *TeePrinter) Disabled() bool {
+	return false
+}
+
+func (p *TeePrinter) Print(input ...string) {
+	for _, printer := range p.printers {
+		printer.Print(input...)
+	}
+}
+
+func (p *TeePrinter) PrintRecord(record *Record) {
+	for _, printer := range p.printers {
+		printRecord(printer, record)
+	}
+}
+
 type DelegateToWriterPrinter struct {
 	writer io.Writer
 }
@@ -109,6 +203,10 @@ func Uint8(in uint8) string {
 	return strconv.FormatUint(uint64(in), 10)
 }
 
+func Uint32(in uint32) string {
+	return strconv.FormatUint(uint64(in), 10)
+}
+
 func Uint64(in uint64) string {
 	return strconv.FormatUint(in, 10)
 }
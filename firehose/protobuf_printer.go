@@ -0,0 +1,1239 @@
+package firehose
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/firehose/pb"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// ProtobufPrinter is a Printer that, instead of writing each record as a text line,
+// assembles the records of a block into a single `pb.Block` message and writes it
+// to the writer, length-prefixed, once the block ends. Records happening outside
+// of a block (like `INIT`) are written right away as their own message. The records
+// of a Context carry their message, which is used as is, their text fields being
+// never formatted.
+//
+// Cancelled blocks are never written, same goes for blocks that were exited without
+// being ended, they are discarded when the next block starts.
+//
+// A record which can't be assembled is logged, and the block it belongs to is dropped
+// rather than written incomplete, the records up to the end of the block being ignored.
+type ProtobufPrinter struct {
+	writer    io.Writer
+	assembler *Assembler
+
+	dropping bool // In a block dropped because of an invalid record
+}
+
+func NewProtobufPrinter(writer io.Writer) *ProtobufPrinter {
+	p := &ProtobufPrinter{writer: writer}
+	p.assembler = NewAssembler(p.write)
+
+	return p
+}
+
+// NewProtobufHandlerPrinter returns a ProtobufPrinter that hands each assembled message
+// to `handler` instead of writing it.
+func NewProtobufHandlerPrinter(handler func(msg *pb.Message)) *ProtobufPrinter {
+	return &ProtobufPrinter{assembler: NewAssembler(handler)}
+}
+
+func (p *ProtobufPrinter) Disabled() bool {
+	return false
+}
+
+func (p *ProtobufPrinter) Print(input ...string) {
+	kind := ""
+	if len(input) > 0 {
+		kind = input[0]
+	}
+
+	p.assemble(kind, func() error { return p.assembler.Record(input) })
+}
+
+// PrintRecord assembles the message of the record as made by the Context, without
+// going through its text fields.
+func (p *ProtobufPrinter) PrintRecord(record *Record) {
+	p.assemble(record.Kind, func() error { return p.assembler.assemble(record) })
+}
+
+func (p *ProtobufPrinter) assemble(kind string, assemble func() error) {
+	if p.dropping {
+		if !endsDroppedBlock(kind) {
+			return
+		}
+		p.dropping = false
+		if kind != "BEGIN_BLOCK" {
+			return
+		}
+	}
+
+	if err := assemble(); err != nil {
+		if number, dropped := p.assembler.discardBlock(); dropped {
+			p.dropping = true
+			log.Error("Firehose protobuf printer dropped a block with an invalid record", "block", number, "record", kind, "err", err)
+			return
+		}
+		log.Error("Firehose protobuf printer dropped an invalid record", "record", kind, "err", err)
+	}
+}
+
+// endsDroppedBlock tells the records after which the records of a dropped block are not ignored anymore.
+func endsDroppedBlock(kind string) bool {
+	return kind == "END_BLOCK" || kind == "CANCEL_BLOCK" || kind == "BEGIN_BLOCK"
+}
+
+func (p *ProtobufPrinter) write(msg *pb.Message) {
+	buffer := bytes.NewBuffer(nil)
+	// Writing to a bytes.Buffer never fails
+	_ = pb.WriteDelimited(buffer, msg)
+
+	data := buffer.Bytes()
+	var written int
+	var err error
+	loops := 10
+	for i := 0; i < loops; i++ {
+		written, err = p.writer.Write(data)
+
+		if len(data) == written {
+			return
+		}
+
+		data = data[written:]
+
+		if i == loops-1 {
+			break
+		}
+	}
+
+	errstr := fmt.Sprintf("\nFIREHOSE FAILED WRITING %dx: %s\n", loops, err)
+	// nolint
+	os.WriteFile("/tmp/firehose_writer_failed_print.log", []byte(errstr), 0644)
+}
+
+// Assembler turns the records produced by a Context into typed protobuf messages. Records
+// are either given as values, by the ProtobufPrinter, or in the exact text form they are
+// handed to Printer.Print, in which case they are checked against their declared Schema.
+type Assembler struct {
+	emit func(msg *pb.Message)
+
+	block      *pb.Block
+	trx        *pb.TransactionTrace
+	systemCall *pb.SystemCall
+	calls      map[uint32]*pb.Call
+}
+
+func NewAssembler(emit func(msg *pb.Message)) *Assembler {
+	return &Assembler{emit: emit}
+}
+
+// discardBlock drops the block being assembled, it returns its number and whether there was one.
+func (a *Assembler) discardBlock() (uint64, bool) {
+	if a.block == nil {
+		return 0, false
+	}
+
+	number := a.block.Number
+	a.block = nil
+	a.trx = nil
+	a.systemCall = nil
+	return number, true
+}
+
+// Record applies a text record, the messages it completes being emitted right away.
+func (a *Assembler) Record(input []string) error {
+	record, err := parseRecord(input)
+	if err != nil {
+		return err
+	}
+
+	return a.assemble(record)
+}
+
+// assemble applies a record, the messages it completes being emitted right away. A record
+// without message is applied from its text fields.
+func (a *Assembler) assemble(record *Record) error {
+	if record.Message == nil {
+		return a.Record(record.Fields())
+	}
+
+	if err := a.apply(record); err != nil {
+		return fmt.Errorf("%s: %w", record.Kind, err)
+	}
+
+	return nil
+}
+
+// recordMessage returns the message of a record, which is of a type known for each kind.
+func recordMessage[T any](record *Record) (T, error) {
+	message, ok := record.Message.(T)
+	if !ok {
+		return message, fmt.Errorf("unexpected message %T", record.Message)
+	}
+
+	return message, nil
+}
+
+func (a *Assembler) apply(record *Record) error {
+	switch record.Kind {
+	case "INIT":
+		init, err := recordMessage[*pb.Init](record)
+		if err != nil {
+			return err
+		}
+
+		a.emit(&pb.Message{Init: init})
+
+	case "BEGIN_BLOCK":
+		begin, err := recordMessage[*pb.Block](record)
+		if err != nil {
+			return err
+		}
+
+		// A block still pending at this point was exited without being ended nor cancelled, drop it
+		a.block = &pb.Block{Number: begin.Number}
+		a.trx = nil
+		a.systemCall = nil
+		a.calls = map[uint32]*pb.Call{}
+
+	case "FINALIZE_BLOCK":
+		finalize, err := recordMessage[*pb.FinalizeBlock](record)
+		if err != nil {
+			return err
+		}
+
+		if a.block == nil {
+			// Block progress only mode, there is no block being assembled
+			a.emit(&pb.Message{FinalizeBlock: finalize})
+			return nil
+		}
+
+		if finalize.Number != a.block.Number {
+			return fmt.Errorf("finalizing block #%d while in block #%d", finalize.Number, a.block.Number)
+		}
+
+	case "END_BLOCK":
+		end, err := recordMessage[*pb.Block](record)
+		if err != nil {
+			return err
+		}
+
+		block, err := a.activeBlock()
+		if err != nil {
+			return err
+		}
+
+		if end.Number != block.Number {
+			return fmt.Errorf("ending block #%d while in block #%d", end.Number, block.Number)
+		}
+
+		block.Size = end.Size
+		block.Header = end.Header
+		block.Uncles = end.Uncles
+		block.TotalDifficulty = end.TotalDifficulty
+		block.FinalizedBlockNumber = end.FinalizedBlockNumber
+		block.FinalizedBlockHash = end.FinalizedBlockHash
+		block.SafeBlockNumber = end.SafeBlockNumber
+		block.SafeBlockHash = end.SafeBlockHash
+
+		a.block = nil
+		a.trx = nil
+		a.systemCall = nil
+		a.emit(&pb.Message{Block: block})
+
+	case "CANCEL_BLOCK":
+		a.block = nil
+		a.trx = nil
+		a.systemCall = nil
+
+	case "NEW_BLOCK", "UNDO_BLOCK":
+		step, err := recordMessage[*pb.ForkStep](record)
+		if err != nil {
+			return err
+		}
+
+		if a.block != nil {
+			return fmt.Errorf("fork step while in block #%d", a.block.Number)
+		}
+
+		a.emit(&pb.Message{ForkStep: step})
+
+	case "FINALITY_UPDATE":
+		update, err := recordMessage[*pb.FinalityUpdate](record)
+		if err != nil {
+			return err
+		}
+
+		if a.block != nil {
+			return fmt.Errorf("finality update while in block #%d", a.block.Number)
+		}
+
+		a.emit(&pb.Message{FinalityUpdate: update})
+
+	case "BEGIN_APPLY_TRX":
+		begin, err := recordMessage[*pb.TransactionTrace](record)
+		if err != nil {
+			return err
+		}
+
+		if _, err := a.activeBlock(); err != nil {
+			return err
+		}
+		if a.systemCall != nil {
+			return fmt.Errorf("within a system call")
+		}
+
+		a.trx = begin
+		a.block.Transactions = append(a.block.Transactions, a.trx)
+		a.calls = map[uint32]*pb.Call{}
+
+	case "TRX_FROM":
+		from, err := recordMessage[*pb.TransactionTrace](record)
+		if err != nil {
+			return err
+		}
+
+		trx, err := a.activeTransaction()
+		if err != nil {
+			return err
+		}
+
+		trx.From = from.From
+
+	case "FAILED_APPLY_TRX":
+		failed, err := recordMessage[*pb.TransactionTrace](record)
+		if err != nil {
+			return err
+		}
+
+		trx, err := a.activeTransaction()
+		if err != nil {
+			return err
+		}
+
+		trx.Failed = true
+		trx.FailureReason = failed.FailureReason
+		trx.EndOrdinal = failed.EndOrdinal
+		a.trx = nil
+
+	case "END_APPLY_TRX":
+		end, err := recordMessage[*pb.TransactionTrace](record)
+		if err != nil {
+			return err
+		}
+
+		trx, err := a.activeTransaction()
+		if err != nil {
+			return err
+		}
+
+		trx.GasUsed = end.GasUsed
+		trx.PostState = end.PostState
+		trx.CumulativeGasUsed = end.CumulativeGasUsed
+		trx.LogsBloom = end.LogsBloom
+		trx.BlobGasUsed = end.BlobGasUsed
+		trx.BlobGasPrice = end.BlobGasPrice
+		trx.EndOrdinal = end.EndOrdinal
+		trx.ReceiptLogs = end.ReceiptLogs
+
+		a.trx = nil
+		a.calls = map[uint32]*pb.Call{}
+
+	case "BEGIN_SYSTEM_CALL":
+		begin, err := recordMessage[*pb.SystemCall](record)
+		if err != nil {
+			return err
+		}
+
+		if _, err := a.activeBlock(); err != nil {
+			return err
+		}
+		if a.trx != nil || a.systemCall != nil {
+			return fmt.Errorf("already within a transaction or system call")
+		}
+
+		a.systemCall = begin
+
+	case "END_SYSTEM_CALL":
+		end, err := recordMessage[*pb.SystemCall](record)
+		if err != nil {
+			return err
+		}
+
+		if a.systemCall == nil {
+			return fmt.Errorf("not within a system call")
+		}
+
+		a.systemCall.EndOrdinal = end.EndOrdinal
+		a.block.SystemCalls = append(a.block.SystemCalls, a.systemCall)
+
+		a.systemCall = nil
+		a.calls = map[uint32]*pb.Call{}
+
+	case "EVM_RUN_CALL":
+		call, err := recordMessage[*pb.Call](record)
+		if err != nil {
+			return err
+		}
+
+		if _, err := a.activeBlock(); err != nil {
+			return err
+		}
+
+		a.calls[call.Index] = call
+		switch {
+		case a.trx != nil:
+			a.trx.Calls = append(a.trx.Calls, call)
+		case a.systemCall != nil:
+			a.systemCall.Calls = append(a.systemCall.Calls, call)
+		default:
+			a.block.UnscopedCalls = append(a.block.UnscopedCalls, call)
+		}
+
+	case "EVM_PARAM":
+		params, err := recordMessage[*pb.Call](record)
+		if err != nil {
+			return err
+		}
+
+		call, err := a.call(record.CallIndex)
+		if err != nil {
+			return err
+		}
+
+		if params.CallType != call.CallType {
+			return fmt.Errorf("call type %q differs from call #%d type %q", params.CallType, call.Index, call.CallType)
+		}
+
+		call.Caller = params.Caller
+		call.Address = params.Address
+		call.Value = params.Value
+		call.GasLimit = params.GasLimit
+		call.Input = params.Input
+
+	case "ACCOUNT_WITHOUT_CODE":
+		call, err := a.call(record.CallIndex)
+		if err != nil {
+			return err
+		}
+
+		call.AccountWithoutCode = true
+
+	case "EVM_CALL_FAILED":
+		failed, err := recordMessage[*pb.Call](record)
+		if err != nil {
+			return err
+		}
+
+		call, err := a.call(record.CallIndex)
+		if err != nil {
+			return err
+		}
+
+		call.Failed = true
+		call.FailureGasLeft = failed.FailureGasLeft
+		call.FailureReason = failed.FailureReason
+
+	case "EVM_REVERTED":
+		call, err := a.call(record.CallIndex)
+		if err != nil {
+			return err
+		}
+
+		call.Reverted = true
+
+	case "EVM_END_CALL":
+		end, err := recordMessage[*pb.Call](record)
+		if err != nil {
+			return err
+		}
+
+		call, err := a.call(record.CallIndex)
+		if err != nil {
+			return err
+		}
+
+		call.GasLeft = end.GasLeft
+		call.ReturnData = end.ReturnData
+		call.EndOrdinal = end.EndOrdinal
+
+	case "EVM_KECCAK", "GAS_CHANGE", "STORAGE_CHANGE", "BALANCE_CHANGE", "ADD_LOG", "SUICIDE_CHANGE", "CREATED_ACCOUNT", "CODE_CHANGE", "NONCE_CHANGE":
+		changes, err := a.changes(record.CallIndex)
+		if err != nil {
+			return err
+		}
+
+		switch change := record.Message.(type) {
+		case *pb.KeccakPreimage:
+			changes.KeccakPreimages = append(changes.KeccakPreimages, change)
+		case *pb.GasChange:
+			changes.GasChanges = append(changes.GasChanges, change)
+		case *pb.StorageChange:
+			changes.StorageChanges = append(changes.StorageChanges, change)
+		case *pb.BalanceChange:
+			changes.BalanceChanges = append(changes.BalanceChanges, change)
+		case *pb.Log:
+			changes.Logs = append(changes.Logs, change)
+		case *pb.SuicideChange:
+			changes.Suicides = append(changes.Suicides, change)
+		case *pb.AccountCreation:
+			changes.AccountCreations = append(changes.AccountCreations, change)
+		case *pb.CodeChange:
+			changes.CodeChanges = append(changes.CodeChanges, change)
+		case *pb.NonceChange:
+			changes.NonceChanges = append(changes.NonceChanges, change)
+		default:
+			return fmt.Errorf("unexpected message %T", record.Message)
+		}
+
+	// Mempool records are not part of any block, they are emitted as soon as received
+	case "TRX_POOL_ADDED", "TRX_POOL_REPLACED", "TRX_POOL_DROPPED", "TRX_POOL_INCLUDED":
+		event, err := recordMessage[*pb.MempoolEvent](record)
+		if err != nil {
+			return err
+		}
+
+		a.emit(&pb.Message{MempoolEvent: event})
+
+	default:
+		return fmt.Errorf("unknown record kind")
+	}
+
+	return nil
+}
+
+// parseRecord turns a text record into the record made by the Context, checking it against
+// its declared Schema.
+func parseRecord(input []string) (*Record, error) {
+	if len(input) == 0 {
+		return nil, fmt.Errorf("empty record")
+	}
+
+	f := &recordFields{kind: input[0], values: input[1:]}
+	schema, found := Schema[f.kind]
+	if !found {
+		return nil, fmt.Errorf("unknown record kind %q", f.kind)
+	}
+	if len(f.values) != len(schema.Fields) {
+		return nil, fmt.Errorf("%s: expected %d fields, got %d", f.kind, len(schema.Fields), len(f.values))
+	}
+
+	record, err := f.record()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.kind, err)
+	}
+
+	if f.err != nil {
+		return nil, fmt.Errorf("%s: %w", f.kind, f.err)
+	}
+
+	record.Kind = f.kind
+	record.input = input
+	return record, nil
+}
+
+func (f *recordFields) record() (*Record, error) {
+	switch f.kind {
+	case "INIT":
+		return &Record{Message: &pb.Init{
+			FirehoseVersion: f.string(0),
+			Variant:         f.string(1),
+			NodeVersion:     f.string(2),
+			SchemaVersion:   f.uint64(3),
+		}}, nil
+
+	case "BEGIN_BLOCK", "CANCEL_BLOCK":
+		return &Record{Message: &pb.Block{Number: f.uint64(0)}}, nil
+
+	case "FINALIZE_BLOCK":
+		return &Record{Message: &pb.FinalizeBlock{Number: f.uint64(0)}}, nil
+
+	case "END_BLOCK":
+		block := &pb.Block{Number: f.uint64(0), Size: f.uint64(1)}
+		if err := decodeEndBlockData(block, f.string(2)); err != nil {
+			return nil, err
+		}
+
+		return &Record{Message: block}, nil
+
+	case "NEW_BLOCK", "UNDO_BLOCK":
+		step := pb.StepNew
+		if f.kind == "UNDO_BLOCK" {
+			step = pb.StepUndo
+		}
+
+		return &Record{Message: &pb.ForkStep{
+			Step:       step,
+			Number:     f.uint64(0),
+			Hash:       f.hex(1),
+			ParentHash: f.hex(2),
+		}}, nil
+
+	case "FINALITY_UPDATE":
+		return &Record{Message: &pb.FinalityUpdate{
+			SafeBlockNumber:      f.uint64(0),
+			SafeBlockHash:        f.hex(1),
+			FinalizedBlockNumber: f.uint64(2),
+			FinalizedBlockHash:   f.hex(3),
+		}}, nil
+
+	case "BEGIN_APPLY_TRX":
+		trx, err := f.transaction()
+		if err != nil {
+			return nil, err
+		}
+
+		trx.BeginOrdinal = f.uint64(16)
+		return &Record{Message: trx}, nil
+
+	case "TRX_FROM":
+		return &Record{Message: &pb.TransactionTrace{From: f.hex(0)}}, nil
+
+	case "FAILED_APPLY_TRX":
+		return &Record{Message: &pb.TransactionTrace{
+			Failed:        true,
+			FailureReason: f.string(0),
+			EndOrdinal:    f.uint64(1),
+		}}, nil
+
+	case "END_APPLY_TRX":
+		trx := &pb.TransactionTrace{
+			GasUsed:           f.uint64(0),
+			PostState:         f.hex(1),
+			CumulativeGasUsed: f.uint64(2),
+			LogsBloom:         f.hex(3),
+			BlobGasUsed:       f.uint64(4),
+			BlobGasPrice:      f.hex(5),
+			EndOrdinal:        f.uint64(6),
+		}
+
+		var err error
+		if trx.ReceiptLogs, err = decodeReceiptLogs(f.string(7)); err != nil {
+			return nil, err
+		}
+
+		return &Record{Message: trx}, nil
+
+	case "BEGIN_SYSTEM_CALL":
+		return &Record{Message: &pb.SystemCall{
+			Reason:       f.string(0),
+			TrxHash:      f.hex(1),
+			BeginOrdinal: f.uint64(2),
+		}}, nil
+
+	case "END_SYSTEM_CALL":
+		return &Record{Message: &pb.SystemCall{EndOrdinal: f.uint64(0)}}, nil
+
+	case "EVM_RUN_CALL":
+		index := f.uint32(1)
+		return &Record{CallIndex: index, Message: &pb.Call{
+			CallType:     f.string(0),
+			Index:        index,
+			BeginOrdinal: f.uint64(2),
+		}}, nil
+
+	case "EVM_PARAM":
+		return &Record{CallIndex: f.uint32(1), Message: &pb.Call{
+			CallType: f.string(0),
+			Caller:   f.hex(2),
+			Address:  f.hex(3),
+			Value:    f.hex(4),
+			GasLimit: f.uint64(5),
+			Input:    f.hex(6),
+		}}, nil
+
+	case "ACCOUNT_WITHOUT_CODE":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.Call{AccountWithoutCode: true}}, nil
+
+	case "EVM_CALL_FAILED":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.Call{
+			Failed:         true,
+			FailureGasLeft: f.uint64(1),
+			FailureReason:  f.string(2),
+		}}, nil
+
+	case "EVM_REVERTED":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.Call{Reverted: true}}, nil
+
+	case "EVM_END_CALL":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.Call{
+			GasLeft:    f.uint64(1),
+			ReturnData: f.hex(2),
+			EndOrdinal: f.uint64(3),
+		}}, nil
+
+	case "EVM_KECCAK":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.KeccakPreimage{
+			Hash: f.hex(1),
+			Data: f.hex(2),
+		}}, nil
+
+	case "GAS_CHANGE":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.GasChange{
+			OldValue: f.uint64(1),
+			NewValue: f.uint64(2),
+			Reason:   f.string(3),
+			Ordinal:  f.uint64(4),
+		}}, nil
+
+	case "STORAGE_CHANGE":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.StorageChange{
+			Address:  f.hex(1),
+			Key:      f.hex(2),
+			OldValue: f.hex(3),
+			NewValue: f.hex(4),
+			Ordinal:  f.uint64(5),
+		}}, nil
+
+	case "BALANCE_CHANGE":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.BalanceChange{
+			Address:  f.hex(1),
+			OldValue: f.hex(2),
+			NewValue: f.hex(3),
+			Reason:   f.string(4),
+			Ordinal:  f.uint64(5),
+		}}, nil
+
+	case "ADD_LOG":
+		var topics [][]byte
+		if joined := f.string(3); joined != "" {
+			for _, topic := range strings.Split(joined, ",") {
+				topics = append(topics, f.hexValue(topic))
+			}
+		}
+
+		return &Record{CallIndex: f.uint32(0), Message: &pb.Log{
+			Address:    f.hex(2),
+			Topics:     topics,
+			Data:       f.hex(4),
+			BlockIndex: f.uint32(1),
+			Ordinal:    f.uint64(5),
+		}}, nil
+
+	case "SUICIDE_CHANGE":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.SuicideChange{
+			Address:       f.hex(1),
+			Suicided:      f.bool(2),
+			BalanceBefore: f.hex(3),
+		}}, nil
+
+	case "CREATED_ACCOUNT":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.AccountCreation{
+			Address: f.hex(1),
+			Ordinal: f.uint64(2),
+		}}, nil
+
+	case "CODE_CHANGE":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.CodeChange{
+			Address: f.hex(1),
+			OldHash: f.hex(2),
+			OldCode: f.hex(3),
+			NewHash: f.hex(4),
+			NewCode: f.hex(5),
+			Ordinal: f.uint64(6),
+		}}, nil
+
+	case "NONCE_CHANGE":
+		return &Record{CallIndex: f.uint32(0), Message: &pb.NonceChange{
+			Address:  f.hex(1),
+			OldValue: f.uint64(2),
+			NewValue: f.uint64(3),
+			Ordinal:  f.uint64(4),
+		}}, nil
+
+	case "TRX_POOL_ADDED":
+		trx, err := f.transaction()
+		if err != nil {
+			return nil, err
+		}
+
+		trx.From = f.hex(16)
+		return &Record{Message: &pb.MempoolEvent{
+			Type:        pb.MempoolEventAdded,
+			Transaction: trx,
+			SenderError: f.optionalString(17),
+		}}, nil
+
+	case "TRX_POOL_REPLACED":
+		trx, err := f.transaction()
+		if err != nil {
+			return nil, err
+		}
+
+		trx.From = f.hex(16)
+		return &Record{Message: &pb.MempoolEvent{
+			Type:        pb.MempoolEventReplaced,
+			Transaction: trx,
+			Hash:        f.hex(17),
+			SenderError: f.optionalString(18),
+		}}, nil
+
+	case "TRX_POOL_DROPPED":
+		return &Record{Message: &pb.MempoolEvent{
+			Type:   pb.MempoolEventDropped,
+			Hash:   f.hex(0),
+			Reason: f.string(1),
+		}}, nil
+
+	case "TRX_POOL_INCLUDED":
+		return &Record{Message: &pb.MempoolEvent{
+			Type:        pb.MempoolEventIncluded,
+			Hash:        f.hex(0),
+			BlockNumber: f.uint64(1),
+			BlockHash:   f.hex(2),
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unknown record kind")
+}
+
+// transaction decodes the first fields of a `BEGIN_APPLY_TRX` record, which are the ones of
+// the transaction of `TRX_POOL_ADDED` and `TRX_POOL_REPLACED` records too.
+func (f *recordFields) transaction() (*pb.TransactionTrace, error) {
+	var accessList AccessList
+	if err := accessList.unmarshal(f.hex(10)); err != nil {
+		return nil, err
+	}
+
+	return &pb.TransactionTrace{
+		Hash:                 f.hex(0),
+		To:                   f.hex(1),
+		Value:                f.hex(2),
+		V:                    f.hex(3),
+		R:                    f.hex(4),
+		S:                    f.hex(5),
+		GasLimit:             f.uint64(6),
+		GasPrice:             f.hex(7),
+		Nonce:                f.uint64(8),
+		Input:                f.hex(9),
+		AccessList:           accessListToProto(accessList),
+		MaxFeePerGas:         f.hex(11),
+		MaxPriorityFeePerGas: f.hex(12),
+		Type:                 uint32(f.uint64(13)),
+
+		MaxFeePerBlobGas: f.hex(14),
+		BlobHashes:       splitBlobHashes(f.hex(15)),
+	}, nil
+}
+
+// splitBlobHashes splits the concatenated blob versioned hashes of a record.
+func splitBlobHashes(in []byte) (out [][]byte) {
+	for len(in) >= length.Hash {
+		out = append(out, in[:length.Hash])
+		in = in[length.Hash:]
+	}
+
+	return out
+}
+
+func (a *Assembler) activeBlock() (*pb.Block, error) {
+	if a.block == nil {
+		return nil, fmt.Errorf("not within a block")
+	}
+
+	return a.block, nil
+}
+
+func (a *Assembler) activeTransaction() (*pb.TransactionTrace, error) {
+	if a.trx == nil {
+		return nil, fmt.Errorf("not within a transaction")
+	}
+
+	return a.trx, nil
+}
+
+func (a *Assembler) call(index uint32) (*pb.Call, error) {
+	if a.block == nil {
+		return nil, fmt.Errorf("not within a block")
+	}
+
+	call, found := a.calls[index]
+	if !found {
+		return nil, fmt.Errorf("unknown call index %d", index)
+	}
+
+	return call, nil
+}
+
+// changes returns the container of the changes recorded while call `index` was active,
+// index 0 meaning no call was active so the changes belong to the transaction, the
+// system call or the block itself.
+func (a *Assembler) changes(index uint32) (*pb.Changes, error) {
+	if index != 0 {
+		call, err := a.call(index)
+		if err != nil {
+			return nil, err
+		}
+
+		if call.Changes == nil {
+			call.Changes = &pb.Changes{}
+		}
+		return call.Changes, nil
+	}
+
+	if a.trx != nil {
+		if a.trx.Changes == nil {
+			a.trx.Changes = &pb.Changes{}
+		}
+		return a.trx.Changes, nil
+	}
+
+	if a.systemCall != nil {
+		if a.systemCall.Changes == nil {
+			a.systemCall.Changes = &pb.Changes{}
+		}
+		return a.systemCall.Changes, nil
+	}
+
+	block, err := a.activeBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Changes == nil {
+		block.Changes = &pb.Changes{}
+	}
+	return block.Changes, nil
+}
+
+func accessListToProto(l AccessList) (out []*pb.AccessTuple) {
+	for _, tuple := range l {
+		protoTuple := &pb.AccessTuple{Address: bytesOrNil(tuple.Address[:])}
+		for _, key := range tuple.StorageKeys {
+			protoTuple.StorageKeys = append(protoTuple.StorageKeys, bytesOrNil(key[:]))
+		}
+
+		out = append(out, protoTuple)
+	}
+
+	return out
+}
+
+type endBlockData struct {
+	Header             *headerData     `json:"header"`
+	Uncles             []*headerData   `json:"uncles"`
+	TotalDifficulty    *hexutil.Big    `json:"totalDifficulty"`
+	FinalizedBlockNum  *hexutil.Big    `json:"finalizedBlockNum"`
+	FinalizedBlockHash *libcommon.Hash `json:"finalizedBlockHash"`
+	SafeBlockNum       *hexutil.Big    `json:"safeBlockNum"`
+	SafeBlockHash      *libcommon.Hash `json:"safeBlockHash"`
+}
+
+// headerData mirrors the JSON encoding of `types.Header`, it's decoded through a
+// dedicated type to keep the `hash` field the encoding carries.
+type headerData struct {
+	ParentHash      libcommon.Hash    `json:"parentHash"`
+	UncleHash       libcommon.Hash    `json:"sha3Uncles"`
+	Coinbase        libcommon.Address `json:"miner"`
+	Root            libcommon.Hash    `json:"stateRoot"`
+	TxHash          libcommon.Hash    `json:"transactionsRoot"`
+	ReceiptHash     libcommon.Hash    `json:"receiptsRoot"`
+	Bloom           types.Bloom       `json:"logsBloom"`
+	Difficulty      *hexutil.Big      `json:"difficulty"`
+	Number          *hexutil.Big      `json:"number"`
+	GasLimit        hexutil.Uint64    `json:"gasLimit"`
+	GasUsed         hexutil.Uint64    `json:"gasUsed"`
+	Time            hexutil.Uint64    `json:"timestamp"`
+	Extra           hexutil.Bytes     `json:"extraData"`
+	MixDigest       libcommon.Hash    `json:"mixHash"`
+	Nonce           types.BlockNonce  `json:"nonce"`
+	BaseFee         *hexutil.Big      `json:"baseFeePerGas"`
+	WithdrawalsHash *libcommon.Hash   `json:"withdrawalsRoot"`
+	Hash            libcommon.Hash    `json:"hash"`
+
+	BlobGasUsed   *hexutil.Uint64 `json:"blobGasUsed"`
+	ExcessBlobGas *hexutil.Uint64 `json:"excessBlobGas"`
+}
+
+func (h *headerData) toProto() *pb.BlockHeader {
+	header := &pb.BlockHeader{
+		ParentHash:       bytesOrNil(h.ParentHash[:]),
+		UncleHash:        bytesOrNil(h.UncleHash[:]),
+		Coinbase:         bytesOrNil(h.Coinbase[:]),
+		StateRoot:        bytesOrNil(h.Root[:]),
+		TransactionsRoot: bytesOrNil(h.TxHash[:]),
+		ReceiptRoot:      bytesOrNil(h.ReceiptHash[:]),
+		LogsBloom:        bytesOrNil(h.Bloom[:]),
+		Difficulty:       bigBytes(h.Difficulty),
+		Number:           bigBytes(h.Number),
+		GasLimit:         uint64(h.GasLimit),
+		GasUsed:          uint64(h.GasUsed),
+		Timestamp:        uint64(h.Time),
+		ExtraData:        bytesOrNil(h.Extra),
+		MixHash:          bytesOrNil(h.MixDigest[:]),
+		Nonce:            bytesOrNil(h.Nonce[:]),
+		BaseFeePerGas:    bigBytes(h.BaseFee),
+		Hash:             bytesOrNil(h.Hash[:]),
+	}
+
+	if h.WithdrawalsHash != nil {
+		header.WithdrawalsRoot = bytesOrNil(h.WithdrawalsHash[:])
+	}
+	if h.BlobGasUsed != nil {
+		header.BlobGasUsed = uint64(*h.BlobGasUsed)
+	}
+	if h.ExcessBlobGas != nil {
+		header.ExcessBlobGas = uint64(*h.ExcessBlobGas)
+	}
+
+	return header
+}
+
+func decodeEndBlockData(block *pb.Block, in string) error {
+	var data endBlockData
+	if err := json.Unmarshal([]byte(in), &data); err != nil {
+		return fmt.Errorf("decode end block data: %w", err)
+	}
+
+	if data.Header != nil {
+		block.Header = data.Header.toProto()
+	}
+
+	for _, uncle := range data.Uncles {
+		block.Uncles = append(block.Uncles, uncle.toProto())
+	}
+
+	block.TotalDifficulty = bigBytes(data.TotalDifficulty)
+	if data.FinalizedBlockHash != nil {
+		block.FinalizedBlockHash = bytesOrNil(data.FinalizedBlockHash[:])
+		if data.FinalizedBlockNum != nil {
+			block.FinalizedBlockNumber = data.FinalizedBlockNum.ToInt().Uint64()
+		}
+	}
+	if data.SafeBlockHash != nil {
+		block.SafeBlockHash = bytesOrNil(data.SafeBlockHash[:])
+		if data.SafeBlockNum != nil {
+			block.SafeBlockNumber = data.SafeBlockNum.ToInt().Uint64()
+		}
+	}
+
+	return nil
+}
+
+type receiptLogData struct {
+	Address libcommon.Address `json:"address"`
+	Topics  []libcommon.Hash  `json:"topics"`
+	Data    hexutil.Bytes     `json:"data"`
+}
+
+func decodeReceiptLogs(in string) (out []*pb.Log, err error) {
+	var logs []receiptLogData
+	if err := json.Unmarshal([]byte(in), &logs); err != nil {
+		return nil, fmt.Errorf("decode receipt logs: %w", err)
+	}
+
+	for _, log := range logs {
+		protoLog := &pb.Log{Address: bytesOrNil(log.Address[:]), Data: bytesOrNil(log.Data)}
+		for _, topic := range log.Topics {
+			protoLog.Topics = append(protoLog.Topics, bytesOrNil(topic[:]))
+		}
+
+		out = append(out, protoLog)
+	}
+
+	return out, nil
+}
+
+// headerToProto is the header of a block, as decoded from the JSON encoding of `types.Header`.
+func headerToProto(h *types.Header) *pb.BlockHeader {
+	header := &pb.BlockHeader{
+		ParentHash:       bytesOrNil(h.ParentHash[:]),
+		UncleHash:        bytesOrNil(h.UncleHash[:]),
+		Coinbase:         bytesOrNil(h.Coinbase[:]),
+		StateRoot:        bytesOrNil(h.Root[:]),
+		TransactionsRoot: bytesOrNil(h.TxHash[:]),
+		ReceiptRoot:      bytesOrNil(h.ReceiptHash[:]),
+		LogsBloom:        bytesOrNil(h.Bloom[:]),
+		Difficulty:       bigIntBytes(h.Difficulty),
+		Number:           bigIntBytes(h.Number),
+		GasLimit:         h.GasLimit,
+		GasUsed:          h.GasUsed,
+		Timestamp:        h.Time,
+		ExtraData:        bytesOrNil(h.Extra),
+		MixHash:          bytesOrNil(h.MixDigest[:]),
+		Nonce:            bytesOrNil(h.Nonce[:]),
+		BaseFeePerGas:    bigIntBytes(h.BaseFee),
+	}
+
+	hash := h.Hash()
+	header.Hash = bytesOrNil(hash[:])
+	if h.WithdrawalsHash != nil {
+		header.WithdrawalsRoot = bytesOrNil(h.WithdrawalsHash[:])
+	}
+	if h.BlobGasUsed != nil {
+		header.BlobGasUsed = *h.BlobGasUsed
+	}
+	if h.ExcessBlobGas != nil {
+		header.ExcessBlobGas = *h.ExcessBlobGas
+	}
+
+	return header
+}
+
+// endBlockMessage is the message of an `END_BLOCK` record, the safe block being only
+// advertised along the finalized one.
+func endBlockMessage(block *types.Block, finalized, safe *types.Header, totalDifficulty *big.Int) *pb.Block {
+	out := &pb.Block{
+		Number:          block.NumberU64(),
+		Size:            uint64(block.Size()),
+		Header:          headerToProto(block.Header()),
+		TotalDifficulty: bigIntBytes(totalDifficulty),
+	}
+
+	for _, uncle := range block.Body().Uncles {
+		out.Uncles = append(out.Uncles, headerToProto(uncle))
+	}
+
+	if finalized != nil {
+		hash := finalized.Hash()
+		out.FinalizedBlockNumber = finalized.Number.Uint64()
+		out.FinalizedBlockHash = bytesOrNil(hash[:])
+
+		if safe != nil {
+			hash := safe.Hash()
+			out.SafeBlockNumber = safe.Number.Uint64()
+			out.SafeBlockHash = bytesOrNil(hash[:])
+		}
+	}
+
+	return out
+}
+
+// logToProto is a log as found in receipts, without its position in the block.
+func logToProto(log *types.Log) *pb.Log {
+	out := &pb.Log{Address: bytesOrNil(log.Address[:]), Data: bytesOrNil(log.Data)}
+	for _, topic := range log.Topics {
+		out.Topics = append(out.Topics, bytesOrNil(topic[:]))
+	}
+
+	return out
+}
+
+func logsToProto(logs types.Logs) (out []*pb.Log) {
+	for _, log := range logs {
+		out = append(out, logToProto(log))
+	}
+
+	return out
+}
+
+func blobHashesToProto(hashes []libcommon.Hash) (out [][]byte) {
+	for _, hash := range hashes {
+		out = append(out, bytesOrNil(hash[:]))
+	}
+
+	return out
+}
+
+func bigIntBytes(in *big.Int) []byte {
+	if in == nil {
+		return nil
+	}
+
+	return bytesOrNil(in.Bytes())
+}
+
+func bigBytes(in *hexutil.Big) []byte {
+	if in == nil {
+		return nil
+	}
+
+	return bytesOrNil(in.ToInt().Bytes())
+}
+
+// bytesOrNil copies the input normalizing empty values to nil, which is how the protobuf
+// decoding represents them.
+func bytesOrNil(in []byte) []byte {
+	if len(in) == 0 {
+		return nil
+	}
+
+	return append([]byte(nil), in...)
+}
+
+// recordFields gives typed access to the fields of a record, the first decoding error
+// is kept and reported once the record has been fully applied.
+type recordFields struct {
+	kind   string
+	values []string
+	err    error
+}
+
+func (f *recordFields) string(i int) string {
+	return f.values[i]
+}
+
+// optionalString is a free-form string field where "." is the empty value.
+func (f *recordFields) optionalString(i int) string {
+	if f.values[i] == "." {
+		return ""
+	}
+
+	return f.values[i]
+}
+
+func (f *recordFields) hex(i int) []byte {
+	return f.hexValue(f.values[i])
+}
+
+// hexValue decodes a value produced by `Hex`, `Hash`, `Addr` or `BigInt`, where "." is
+// the empty value.
+func (f *recordFields) hexValue(in string) []byte {
+	if in == "." {
+		return nil
+	}
+
+	out, err := hex.DecodeString(in)
+	if err != nil {
+		f.setErr(fmt.Errorf("invalid hex value %q: %w", in, err))
+		return nil
+	}
+
+	return bytesOrNil(out)
+}
+
+func (f *recordFields) uint64(i int) uint64 {
+	out, err := strconv.ParseUint(f.values[i], 10, 64)
+	if err != nil {
+		f.setErr(fmt.Errorf("invalid field %d: %w", i, err))
+	}
+
+	return out
+}
+
+func (f *recordFields) uint32(i int) uint32 {
+	out, err := strconv.ParseUint(f.values[i], 10, 32)
+	if err != nil {
+		f.setErr(fmt.Errorf("invalid field %d: %w", i, err))
+	}
+
+	return uint32(out)
+}
+
+func (f *recordFields) bool(i int) bool {
+	out, err := strconv.ParseBool(f.values[i])
+	if err != nil {
+		f.setErr(fmt.Errorf("invalid field %d: %w", i, err))
+	}
+
+	return out
+}
+
+func (f *recordFields) setErr(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
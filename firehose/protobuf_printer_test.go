@@ -0,0 +1,531 @@
+package firehose
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	types2 "github.com/ledgerwatch/erigon-lib/types"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/firehose/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProtobufPrinter_Messages checks the messages assembled from the records of a Context, as
+// well as the ones assembled from their text form by the reader, against fixed messages.
+func TestProtobufPrinter_Messages(t *testing.T) {
+	chain := newTestChain()
+	expected := testChainMessages(chain)
+
+	protoBuffer := bytes.NewBuffer(nil)
+	recordTestChain(NewContext(NewProtobufPrinter(protoBuffer)), chain)
+	require.Equal(t, expected, readAllMessages(t, protoBuffer))
+
+	textPrinter := NewToBufferPrinter()
+	recordTestChain(NewContext(textPrinter), chain)
+	require.Equal(t, expected, assembleText(t, textPrinter.Buffer().String()))
+}
+
+func TestProtobufPrinter_Mempool(t *testing.T) {
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	pending, err := types.SignTx(types.NewTransaction(0, testAddr(0xcc), uint256.NewInt(10), 21_000, uint256.NewInt(5), nil), *signer, key)
+	require.NoError(t, err)
+	replacement, err := types.SignTx(types.NewTransaction(0, testAddr(0xcc), uint256.NewInt(10), 21_000, uint256.NewInt(6), nil), *signer, key)
+	require.NoError(t, err)
+	unsigned := types.NewTransaction(1, testAddr(0xcc), uint256.NewInt(10), 21_000, uint256.NewInt(5), nil)
+	_, unsignedErr := signer.Sender(unsigned)
+	require.Error(t, unsignedErr)
+
+	record := func(ctx *Context) {
+		ctx.RecordTrxPoolAdded(pending, signer)
+		ctx.RecordTrxPoolAdded(unsigned, signer)
+		ctx.RecordTrxPoolReplaced(replacement, signer, pending.Hash())
+		ctx.RecordTrxPoolDropped(unsigned.Hash(), TrxPoolEvicted)
+		ctx.RecordTrxPoolIncluded(replacement.Hash(), 12, testHeader(12).Hash())
+	}
+
+	signed := func(tx types.Transaction, gasPrice byte) *pb.TransactionTrace {
+		v, r, s := tx.RawSignatureValues()
+		return &pb.TransactionTrace{
+			Hash:     tx.Hash().Bytes(),
+			To:       testAddr(0xcc).Bytes(),
+			Value:    []byte{0x0a},
+			V:        v.Bytes(),
+			R:        r.Bytes(),
+			S:        s.Bytes(),
+			GasLimit: 21_000,
+			GasPrice: []byte{gasPrice},
+			From:     sender.Bytes(),
+		}
+	}
+
+	expected := []*pb.Message{
+		{MempoolEvent: &pb.MempoolEvent{Type: pb.MempoolEventAdded, Transaction: signed(pending, 0x05)}},
+		{MempoolEvent: &pb.MempoolEvent{
+			Type: pb.MempoolEventAdded,
+			Transaction: &pb.TransactionTrace{
+				Hash:     unsigned.Hash().Bytes(),
+				To:       testAddr(0xcc).Bytes(),
+				Value:    []byte{0x0a},
+				GasLimit: 21_000,
+				GasPrice: []byte{0x05},
+				Nonce:    1,
+			},
+			SenderError: unsignedErr.Error(),
+		}},
+		{MempoolEvent: &pb.MempoolEvent{Type: pb.MempoolEventReplaced, Transaction: signed(replacement, 0x06), Hash: pending.Hash().Bytes()}},
+		{MempoolEvent: &pb.MempoolEvent{Type: pb.MempoolEventDropped, Hash: unsigned.Hash().Bytes(), Reason: "evicted"}},
+		{MempoolEvent: &pb.MempoolEvent{
+			Type:        pb.MempoolEventIncluded,
+			Hash:        replacement.Hash().Bytes(),
+			BlockNumber: 12,
+			BlockHash:   testHeader(12).Hash().Bytes(),
+		}},
+	}
+
+	protoBuffer := bytes.NewBuffer(nil)
+	record(NewContext(NewProtobufPrinter(protoBuffer)))
+	require.Equal(t, expected, readAllMessages(t, protoBuffer))
+
+	textPrinter := NewToBufferPrinter()
+	record(NewContext(textPrinter))
+	require.Equal(t, expected, assembleText(t, textPrinter.Buffer().String()))
+}
+
+func TestProtobufPrinter_InvalidRecord(t *testing.T) {
+	protoBuffer := bytes.NewBuffer(nil)
+	printer := NewProtobufPrinter(protoBuffer)
+	ctx := NewContext(printer)
+
+	block := func(number int64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number), Difficulty: big.NewInt(1)})
+	}
+	first, second := block(1), block(2)
+
+	// The block of an invalid record is dropped, its remaining records being ignored
+	ctx.StartBlock(first)
+	printer.Print("BEGIN_APPLY_TRX", "not enough fields")
+	ctx.RecordBalanceChange(testAddr(0xaa), uint256.NewInt(0), uint256.NewInt(1), BalanceChangeReason("reward_mine_block"))
+	ctx.EndBlock(first, nil, big.NewInt(1))
+
+	// Outside of a block, only the invalid record is dropped
+	printer.Print("UNKNOWN_RECORD")
+
+	ctx.StartBlock(second)
+	ctx.EndBlock(second, nil, big.NewInt(2))
+
+	messages := readAllMessages(t, protoBuffer)
+	require.Len(t, messages, 1)
+	require.NotNil(t, messages[0].Block)
+	assert.Equal(t, uint64(2), messages[0].Block.Number)
+}
+
+func TestNewPrinter_UnknownFormat(t *testing.T) {
+	_, err := NewPrinter(OutputFormat("xml"), io.Discard)
+	require.Error(t, err)
+}
+
+// testChain holds the blocks and transactions recorded by recordTestChain.
+type testChain struct {
+	genesis, block, cancelled *types.Block
+	uncle                     *types.Header
+
+	legacyTrx, dynamicTrx, blobTrx types.Transaction
+}
+
+func newTestChain() *testChain {
+	chainID := uint256.NewInt(1)
+	callee := testAddr(0xcc)
+	legacyTrx := types.NewTransaction(0, callee, uint256.NewInt(10), 100_000, uint256.NewInt(5), []byte{0x01, 0x02})
+	dynamicTrx := types.NewEIP1559Transaction(*chainID, 1, callee, uint256.NewInt(0), 50_000, nil, uint256.NewInt(2), uint256.NewInt(20), nil)
+	dynamicTrx.AccessList = types2.AccessList{
+		{Address: callee, StorageKeys: []libcommon.Hash{{0x01}, {0x02}}},
+	}
+
+	blobTrx := types.NewBlobTx(*chainID, 2, callee, uint256.NewInt(0), 21_000, uint256.NewInt(2), uint256.NewInt(20), uint256.NewInt(3), []libcommon.Hash{{0x01, 0xaa}, {0x01, 0xbb}}, nil)
+
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1)})
+	blobGasUsed, excessBlobGas := uint64(2*131072), uint64(393216)
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Difficulty: big.NewInt(2),
+		GasLimit:   8_000_000,
+		BaseFee:    big.NewInt(7),
+		Extra:      []byte("firehose"),
+
+		BlobGasUsed:   &blobGasUsed,
+		ExcessBlobGas: &excessBlobGas,
+	}
+	uncle := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1)}
+	block := types.NewBlock(header, []types.Transaction{legacyTrx, dynamicTrx, blobTrx}, []*types.Header{uncle}, nil, nil)
+
+	return &testChain{
+		genesis:    genesis,
+		block:      block,
+		cancelled:  types.NewBlockWithHeader(&types.Header{ParentHash: block.Hash(), Number: big.NewInt(2)}),
+		uncle:      uncle,
+		legacyTrx:  legacyTrx,
+		dynamicTrx: dynamicTrx,
+		blobTrx:    blobTrx,
+	}
+}
+
+// recordTestChain drives the context through a genesis block, a forkchoice update, a block
+// exercising every kind of record, a cancelled block, the undo of the block, a finality update
+// and a standalone block finalization.
+func recordTestChain(ctx *Context, chain *testChain) {
+	ctx.InitVersion("erigon/test", "2.2", "geth")
+
+	ctx.RecordGenesisBlock(chain.genesis, func(ctx *Context) {
+		ctx.RecordBalanceChange(testAddr(0xaa), uint256.NewInt(0), uint256.NewInt(1_000_000), BalanceChangeReason("genesis_balance"))
+	})
+
+	ctx.RecordForkchoice(testHeader(1010), testHeader(1000))
+	// Finality did not advance, nothing is recorded
+	ctx.RecordForkchoice(testHeader(1010), testHeader(1000))
+
+	callee := testAddr(0xcc)
+	block, header := chain.block, chain.block.Header()
+	ctx.StartBlock(block)
+
+	ctx.StartTransaction(chain.legacyTrx, header.BaseFee)
+	ctx.RecordTrxFrom(testAddr(0xaa))
+	ctx.RecordNonceChange(testAddr(0xaa), 0, 1)
+	ctx.RecordBalanceChange(testAddr(0xaa), uint256.NewInt(1_000_000), uint256.NewInt(500_000), BalanceChangeReason("gas_buy"))
+	ctx.StartCall("CALL")
+	ctx.RecordCallParams("CALL", testAddr(0xaa), callee, uint256.NewInt(10), 79_000, []byte{0x01, 0x02})
+	ctx.RecordKeccak(libcommon.Hash{0xde, 0xad}, []byte("preimage"))
+	ctx.RecordGasConsume(79_000, 20_000, GasChangeReason("state_cold_access"))
+	ctx.RecordStorageChange(callee, &libcommon.Hash{0x01}, uint256.NewInt(0), uint256.NewInt(42))
+	ctx.RecordLog(&types.Log{Address: callee, Topics: []libcommon.Hash{{0x0a}, {0x0b}}, Data: []byte{0xff}})
+	ctx.StartCall("CREATE")
+	ctx.RecordCallParams("CREATE", callee, testAddr(0xdd), uint256.NewInt(0), 30_000, nil)
+	ctx.RecordNewAccount(testAddr(0xdd))
+	ctx.RecordCodeChange(testAddr(0xdd), nil, nil, libcommon.Hash{0xc0}, []byte{0x60, 0x00})
+	ctx.EndFailedCall(1_000, false, "out of gas")
+	ctx.RecordGasRefund(30_000, 500)
+	ctx.RecordSuicide(callee, true, uint256.NewInt(10))
+	ctx.EndCall(25_000, []byte{0x01})
+	ctx.EndTransaction(&types.Receipt{
+		GasUsed:           75_000,
+		CumulativeGasUsed: 75_000,
+		Logs:              []*types.Log{{Address: callee, Topics: []libcommon.Hash{{0x0a}, {0x0b}}, Data: []byte{0xff}}},
+	})
+
+	ctx.StartTransaction(chain.dynamicTrx, header.BaseFee)
+	ctx.RecordTrxFrom(testAddr(0xbb))
+	ctx.StartCall("STATIC")
+	ctx.RecordCallParams("STATIC", testAddr(0xbb), callee, EmptyValue, 40_000, nil)
+	ctx.RecordCallWithoutCode()
+	ctx.EndCall(40_000, nil)
+	ctx.EndTransaction(&types.Receipt{GasUsed: 21_000, CumulativeGasUsed: 96_000, PostState: []byte{0x01}})
+
+	ctx.StartTransaction(chain.blobTrx, header.BaseFee)
+	ctx.RecordTrxFrom(testAddr(0xbb))
+	ctx.EndTransaction(&types.Receipt{GasUsed: 21_000, CumulativeGasUsed: 117_000, BlobGasUsed: *header.BlobGasUsed, BlobGasPrice: big.NewInt(2)})
+
+	ctx.FinalizeBlock(block)
+	ctx.RecordBalanceChange(testAddr(0xee), uint256.NewInt(0), uint256.NewInt(2), BalanceChangeReason("reward_mine_block"))
+	ctx.EndBlock(block, testHeader(1000), big.NewInt(512))
+	ctx.RecordNewBlock(header)
+
+	ctx.StartBlock(chain.cancelled)
+	ctx.StartTransaction(chain.legacyTrx, nil)
+	ctx.RecordTrxFrom(testAddr(0xaa))
+	ctx.RecordFailedTransaction(errors.New("nonce too low"))
+	ctx.CancelBlock(chain.cancelled, errors.New("invalid block: nonce too low"))
+	ctx.RecordUndoBlock(header)
+	ctx.RecordForkchoice(nil, testHeader(1064))
+
+	ctx.FinalizeBlock(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3)}))
+}
+
+// testChainMessages are the messages of the records of recordTestChain: INIT, the genesis block,
+// a finality update, block #1 (block #2 is cancelled) each block followed by their new step, the
+// undo of block #1, a finality update and a standalone finalize block.
+func testChainMessages(chain *testChain) []*pb.Message {
+	zeros := func(n int) []byte { return make([]byte, n) }
+	word := func(prefix ...byte) []byte { return append(append([]byte(nil), prefix...), zeros(32-len(prefix))...) }
+	aa, bb, cc, dd, ee := testAddr(0xaa).Bytes(), testAddr(0xbb).Bytes(), testAddr(0xcc).Bytes(), testAddr(0xdd).Bytes(), testAddr(0xee).Bytes()
+
+	emptyHeader := func(hash libcommon.Hash) *pb.BlockHeader {
+		return &pb.BlockHeader{
+			ParentHash:       zeros(32),
+			UncleHash:        zeros(32),
+			Coinbase:         zeros(20),
+			StateRoot:        zeros(32),
+			TransactionsRoot: zeros(32),
+			ReceiptRoot:      zeros(32),
+			LogsBloom:        zeros(256),
+			Difficulty:       []byte{0x01},
+			MixHash:          zeros(32),
+			Nonce:            zeros(8),
+			Hash:             hash.Bytes(),
+		}
+	}
+
+	header := chain.block.Header()
+	safe, finalized := testHeader(1010), testHeader(1000)
+	genesisStep := &pb.ForkStep{Step: pb.StepNew, Number: 0, Hash: chain.genesis.Hash().Bytes(), ParentHash: zeros(32)}
+	blockStep := &pb.ForkStep{Step: pb.StepNew, Number: 1, Hash: chain.block.Hash().Bytes(), ParentHash: chain.genesis.Hash().Bytes()}
+	undoStep := &pb.ForkStep{Step: pb.StepUndo, Number: 1, Hash: chain.block.Hash().Bytes(), ParentHash: chain.genesis.Hash().Bytes()}
+	log := &pb.Log{Address: cc, Topics: [][]byte{word(0x0a), word(0x0b)}, Data: []byte{0xff}}
+
+	return []*pb.Message{
+		{Init: &pb.Init{FirehoseVersion: "2.2", Variant: "geth", NodeVersion: "erigon/test", SchemaVersion: SchemaVersion}},
+		{Block: &pb.Block{
+			Number: 0,
+			Size:   501,
+			Header: emptyHeader(chain.genesis.Hash()),
+			Transactions: []*pb.TransactionTrace{{
+				Hash:         zeros(32),
+				To:           zeros(20),
+				BeginOrdinal: 1,
+				From:         zeros(20),
+				Changes: &pb.Changes{BalanceChanges: []*pb.BalanceChange{
+					{Address: aa, NewValue: []byte{0x0f, 0x42, 0x40}, Reason: "genesis_balance", Ordinal: 2},
+				}},
+				PostState:  zeros(32),
+				LogsBloom:  zeros(256),
+				EndOrdinal: 3,
+			}},
+			TotalDifficulty: []byte{0x01},
+		}},
+		{ForkStep: genesisStep},
+		{FinalityUpdate: &pb.FinalityUpdate{
+			SafeBlockNumber:      1010,
+			SafeBlockHash:        safe.Hash().Bytes(),
+			FinalizedBlockNumber: 1000,
+			FinalizedBlockHash:   finalized.Hash().Bytes(),
+		}},
+		{Block: &pb.Block{
+			Number: 1,
+			Size:   1295,
+			Header: &pb.BlockHeader{
+				ParentHash:       chain.genesis.Hash().Bytes(),
+				UncleHash:        header.UncleHash.Bytes(),
+				Coinbase:         zeros(20),
+				StateRoot:        zeros(32),
+				TransactionsRoot: header.TxHash.Bytes(),
+				ReceiptRoot:      header.ReceiptHash.Bytes(),
+				LogsBloom:        zeros(256),
+				Difficulty:       []byte{0x02},
+				Number:           []byte{0x01},
+				GasLimit:         8_000_000,
+				ExtraData:        []byte("firehose"),
+				MixHash:          zeros(32),
+				Nonce:            zeros(8),
+				BaseFeePerGas:    []byte{0x07},
+				Hash:             chain.block.Hash().Bytes(),
+				BlobGasUsed:      262144,
+				ExcessBlobGas:    393216,
+			},
+			Uncles: []*pb.BlockHeader{emptyHeader(chain.uncle.Hash())},
+			Transactions: []*pb.TransactionTrace{
+				{
+					Hash:         chain.legacyTrx.Hash().Bytes(),
+					To:           cc,
+					Value:        []byte{0x0a},
+					GasLimit:     100_000,
+					GasPrice:     []byte{0x05},
+					Input:        []byte{0x01, 0x02},
+					BeginOrdinal: 1,
+					From:         aa,
+					Changes: &pb.Changes{
+						NonceChanges: []*pb.NonceChange{{Address: aa, OldValue: 0, NewValue: 1, Ordinal: 2}},
+						BalanceChanges: []*pb.BalanceChange{
+							{Address: aa, OldValue: []byte{0x0f, 0x42, 0x40}, NewValue: []byte{0x07, 0xa1, 0x20}, Reason: "gas_buy", Ordinal: 3},
+						},
+					},
+					Calls: []*pb.Call{
+						{
+							CallType:     "CALL",
+							Index:        1,
+							BeginOrdinal: 4,
+							Caller:       aa,
+							Address:      cc,
+							Value:        []byte{0x0a},
+							GasLimit:     79_000,
+							Input:        []byte{0x01, 0x02},
+							Changes: &pb.Changes{
+								KeccakPreimages: []*pb.KeccakPreimage{{Hash: word(0xde, 0xad), Data: []byte("preimage")}},
+								GasChanges: []*pb.GasChange{
+									{OldValue: 79_000, NewValue: 59_000, Reason: "state_cold_access", Ordinal: 5},
+									{OldValue: 30_000, NewValue: 30_500, Reason: "refund_after_execution", Ordinal: 13},
+								},
+								StorageChanges: []*pb.StorageChange{
+									{Address: cc, Key: word(0x01), OldValue: zeros(32), NewValue: append(zeros(31), 42), Ordinal: 6},
+								},
+								Logs:     []*pb.Log{{Address: cc, Topics: log.Topics, Data: log.Data, BlockIndex: 0, Ordinal: 7}},
+								Suicides: []*pb.SuicideChange{{Address: cc, Suicided: true, BalanceBefore: []byte{0x0a}}},
+								BalanceChanges: []*pb.BalanceChange{
+									{Address: cc, OldValue: []byte{0x0a}, Reason: "suicide_withdraw", Ordinal: 14},
+								},
+							},
+							GasLeft:    25_000,
+							ReturnData: []byte{0x01},
+							EndOrdinal: 15,
+						},
+						{
+							CallType:     "CREATE",
+							Index:        2,
+							BeginOrdinal: 8,
+							Caller:       cc,
+							Address:      dd,
+							GasLimit:     30_000,
+							Changes: &pb.Changes{
+								AccountCreations: []*pb.AccountCreation{{Address: dd, Ordinal: 9}},
+								CodeChanges: []*pb.CodeChange{
+									{Address: dd, NewHash: word(0xc0), NewCode: []byte{0x60, 0x00}, Ordinal: 10},
+								},
+								GasChanges: []*pb.GasChange{{OldValue: 1_000, NewValue: 0, Reason: "failed_execution", Ordinal: 11}},
+							},
+							Failed:         true,
+							FailureGasLeft: 1_000,
+							FailureReason:  "out of gas",
+							EndOrdinal:     12,
+						},
+					},
+					GasUsed:           75_000,
+					CumulativeGasUsed: 75_000,
+					LogsBloom:         zeros(256),
+					EndOrdinal:        16,
+					ReceiptLogs:       []*pb.Log{log},
+				},
+				{
+					Hash:                 chain.dynamicTrx.Hash().Bytes(),
+					To:                   cc,
+					GasLimit:             50_000,
+					GasPrice:             []byte{0x09},
+					Nonce:                1,
+					AccessList:           []*pb.AccessTuple{{Address: cc, StorageKeys: [][]byte{word(0x01), word(0x02)}}},
+					MaxFeePerGas:         []byte{0x14},
+					MaxPriorityFeePerGas: []byte{0x02},
+					Type:                 uint32(types.DynamicFeeTxType),
+					BeginOrdinal:         17,
+					From:                 bb,
+					Calls: []*pb.Call{{
+						CallType:           "STATIC",
+						Index:              1,
+						BeginOrdinal:       18,
+						Caller:             bb,
+						Address:            cc,
+						GasLimit:           40_000,
+						AccountWithoutCode: true,
+						GasLeft:            40_000,
+						EndOrdinal:         19,
+					}},
+					GasUsed:           21_000,
+					PostState:         []byte{0x01},
+					CumulativeGasUsed: 96_000,
+					LogsBloom:         zeros(256),
+					EndOrdinal:        20,
+				},
+				{
+					Hash:                 chain.blobTrx.Hash().Bytes(),
+					To:                   cc,
+					GasLimit:             21_000,
+					GasPrice:             []byte{0x09},
+					Nonce:                2,
+					MaxFeePerGas:         []byte{0x14},
+					MaxPriorityFeePerGas: []byte{0x02},
+					Type:                 uint32(types.BlobTxType),
+					MaxFeePerBlobGas:     []byte{0x03},
+					BlobHashes:           [][]byte{word(0x01, 0xaa), word(0x01, 0xbb)},
+					BeginOrdinal:         21,
+					From:                 bb,
+					GasUsed:              21_000,
+					CumulativeGasUsed:    117_000,
+					LogsBloom:            zeros(256),
+					BlobGasUsed:          262144,
+					BlobGasPrice:         []byte{0x02},
+					EndOrdinal:           22,
+				},
+			},
+			Changes: &pb.Changes{BalanceChanges: []*pb.BalanceChange{
+				{Address: ee, NewValue: []byte{0x02}, Reason: "reward_mine_block", Ordinal: 23},
+			}},
+			TotalDifficulty:      []byte{0x02, 0x00},
+			FinalizedBlockNumber: 1000,
+			FinalizedBlockHash:   finalized.Hash().Bytes(),
+			SafeBlockNumber:      1010,
+			SafeBlockHash:        safe.Hash().Bytes(),
+		}},
+		{ForkStep: blockStep},
+		{ForkStep: undoStep},
+		// The safe block is kept when the consensus layer does not know it
+		{FinalityUpdate: &pb.FinalityUpdate{
+			SafeBlockNumber:      1010,
+			SafeBlockHash:        safe.Hash().Bytes(),
+			FinalizedBlockNumber: 1064,
+			FinalizedBlockHash:   testHeader(1064).Hash().Bytes(),
+		}},
+		{FinalizeBlock: &pb.FinalizeBlock{Number: 3}},
+	}
+}
+
+func testHeader(number int64) *types.Header {
+	return &types.Header{Number: big.NewInt(number)}
+}
+
+func testAddr(b byte) libcommon.Address {
+	return libcommon.Address{b}
+}
+
+// assembleText assembles the messages of the records of a text output, like the reader does.
+func assembleText(t *testing.T, text string) (out []*pb.Message) {
+	t.Helper()
+
+	assembler := NewAssembler(func(msg *pb.Message) { out = append(out, msg) })
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		require.NoError(t, assembler.Record(splitTextRecord(t, line)), "line %q", line)
+	}
+
+	return out
+}
+
+// splitTextRecord splits a text line into the fields given to `Printer.Print`.
+func splitTextRecord(t *testing.T, line string) []string {
+	t.Helper()
+
+	prefix := "DMLOG "
+	require.True(t, strings.HasPrefix(line, prefix), "line %q", line)
+	line = strings.TrimPrefix(line, prefix)
+
+	kind, rest, _ := strings.Cut(line, " ")
+	schema, found := Schema[kind]
+	require.True(t, found, "line %q", line)
+	fields, err := schema.Split(rest)
+	require.NoError(t, err, "line %q", line)
+
+	return append([]string{kind}, fields...)
+}
+
+func readAllMessages(t *testing.T, in io.Reader) (out []*pb.Message) {
+	t.Helper()
+
+	reader := bufio.NewReader(in)
+	for {
+		msg := &pb.Message{}
+		err := pb.ReadDelimited(reader, msg)
+		if err == io.EOF {
+			return out
+		}
+		require.NoError(t, err)
+
+		out = append(out, msg)
+	}
+}
@@ -0,0 +1,106 @@
+// Package reader parses the text output of Firehose instrumentation back into typed protobuf
+// messages, the same ones the protobuf output format is made of.
+//
+// The reader validates the stream as it goes: it must start with an INIT record advertising
+// the schema version of this tree, every record must match its declared schema
+// (`firehose.Schema`) and records must come in an order that makes sense (no transaction
+// outside a block, no call outside a transaction, etc.).
+package reader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/firehose/pb"
+)
+
+const linePrefix = "FIRE "
+
+// ErrUnsupportedSchema is returned when the stream was produced with another schema version.
+var ErrUnsupportedSchema = errors.New("unsupported firehose schema version")
+
+// Reader reads the messages of a Firehose text stream. Lines not starting with the Firehose
+// prefix, like the logs of the node when both are printed to the same output, are skipped.
+type Reader struct {
+	in        *bufio.Reader
+	assembler *firehose.Assembler
+	line      uint64
+
+	initialized bool
+	pending     []*pb.Message
+}
+
+func NewReader(in io.Reader) *Reader {
+	r := &Reader{in: bufio.NewReader(in)}
+	r.assembler = firehose.NewAssembler(func(msg *pb.Message) {
+		r.pending = append(r.pending, msg)
+	})
+
+	return r
+}
+
+// Next returns the next message of the stream, io.EOF once all were read. A block message is
+// returned once its END_BLOCK record is read.
+func (r *Reader) Next() (*pb.Message, error) {
+	for len(r.pending) == 0 {
+		record, err := r.nextRecord()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := r.assembler.Record(record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+	}
+
+	msg := r.pending[0]
+	r.pending = r.pending[1:]
+	if msg.Init != nil {
+		if msg.Init.SchemaVersion != firehose.SchemaVersion {
+			return nil, fmt.Errorf("line %d: %w %d, expected %d", r.line, ErrUnsupportedSchema, msg.Init.SchemaVersion, firehose.SchemaVersion)
+		}
+		r.initialized = true
+	}
+
+	return msg, nil
+}
+
+// nextRecord reads the next Firehose line and splits it into the fields handed to
+// `firehose.Printer.Print`.
+func (r *Reader) nextRecord() ([]string, error) {
+	for {
+		line, err := r.in.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		r.line++
+
+		line = strings.TrimSuffix(line, "\n")
+		if !strings.HasPrefix(line, linePrefix) {
+			continue
+		}
+
+		kind, rest, _ := strings.Cut(strings.TrimPrefix(line, linePrefix), " ")
+		if !r.initialized && kind != "INIT" {
+			return nil, fmt.Errorf("line %d: expected INIT record first, got %s", r.line, kind)
+		}
+
+		schema, found := firehose.Schema[kind]
+		if !found {
+			return nil, fmt.Errorf("line %d: unknown record kind %q", r.line, kind)
+		}
+		fields, err := schema.Split(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", r.line, kind, err)
+		}
+
+		return append([]string{kind}, fields...), nil
+	}
+}
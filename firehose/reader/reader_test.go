@@ -0,0 +1,461 @@
+package reader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/firehose/pb"
+)
+
+var (
+	caller = libcommon.Address{0xaa}
+	callee = libcommon.Address{0xcc}
+)
+
+func TestReader_RoundTrip(t *testing.T) {
+	t.Run("RecordGenesisBlock", func(t *testing.T) {
+		genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1)})
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.RecordGenesisBlock(genesis, func(ctx *firehose.Context) {
+				ctx.RecordBalanceChange(caller, uint256.NewInt(0), uint256.NewInt(100), firehose.BalanceChangeReason("genesis_balance"))
+			})
+		})
+
+		require.Len(t, msgs, 2)
+		require.NotNil(t, msgs[0].Block)
+		assert.Equal(t, genesis.Hash().Bytes(), msgs[0].Block.Header.Hash)
+		require.Len(t, msgs[0].Block.Transactions, 1)
+		assert.Equal(t, []*pb.BalanceChange{{Address: caller.Bytes(), NewValue: []byte{100}, Reason: "genesis_balance", Ordinal: 2}}, msgs[0].Block.Transactions[0].Changes.BalanceChanges)
+		assert.Equal(t, &pb.ForkStep{Step: pb.StepNew, Number: 0, Hash: genesis.Hash().Bytes(), ParentHash: make([]byte, 32)}, msgs[1].ForkStep)
+	})
+
+	t.Run("RecordForkchoice", func(t *testing.T) {
+		safe, finalized := testHeader(20), testHeader(10)
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.RecordForkchoice(safe, finalized)
+		})
+
+		require.Len(t, msgs, 1)
+		assert.Equal(t, &pb.FinalityUpdate{
+			SafeBlockNumber:      20,
+			SafeBlockHash:        safe.Hash().Bytes(),
+			FinalizedBlockNumber: 10,
+			FinalizedBlockHash:   finalized.Hash().Bytes(),
+		}, msgs[0].FinalityUpdate)
+	})
+
+	t.Run("RecordNewBlock", func(t *testing.T) {
+		header := testHeader(5)
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.RecordNewBlock(header)
+		})
+
+		require.Len(t, msgs, 1)
+		assert.Equal(t, &pb.ForkStep{Step: pb.StepNew, Number: 5, Hash: header.Hash().Bytes(), ParentHash: make([]byte, 32)}, msgs[0].ForkStep)
+	})
+
+	t.Run("RecordUndoBlock", func(t *testing.T) {
+		header := testHeader(5)
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.RecordUndoBlock(header)
+		})
+
+		require.Len(t, msgs, 1)
+		assert.Equal(t, &pb.ForkStep{Step: pb.StepUndo, Number: 5, Hash: header.Hash().Bytes(), ParentHash: make([]byte, 32)}, msgs[0].ForkStep)
+	})
+
+	t.Run("RecordTrxFrom", func(t *testing.T) {
+		trx := readTransaction(t, func(ctx *firehose.Context) {})
+		assert.Equal(t, caller.Bytes(), trx.From)
+	})
+
+	t.Run("RecordFailedTransaction", func(t *testing.T) {
+		block := types.NewBlockWithHeader(testHeader(1))
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.StartBlock(block)
+			ctx.StartTransaction(testTransaction(), nil)
+			ctx.RecordTrxFrom(caller)
+			ctx.RecordFailedTransaction(errors.New("nonce too low: address 0xaa, tx: 0 state: 1"))
+			ctx.FinalizeBlock(block)
+			ctx.EndBlock(block, nil, big.NewInt(1))
+		})
+
+		require.Len(t, msgs, 1)
+		require.Len(t, msgs[0].Block.Transactions, 1)
+		trx := msgs[0].Block.Transactions[0]
+		assert.True(t, trx.Failed)
+		assert.Equal(t, "nonce too low: address 0xaa, tx: 0 state: 1", trx.FailureReason)
+		assert.Equal(t, uint64(2), trx.EndOrdinal)
+	})
+
+	t.Run("RecordCallParams", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {})
+		assert.Equal(t, "CALL", call.CallType)
+		assert.Equal(t, caller.Bytes(), call.Caller)
+		assert.Equal(t, callee.Bytes(), call.Address)
+		assert.Equal(t, []byte{10}, call.Value)
+		assert.Equal(t, uint64(90_000), call.GasLimit)
+		assert.Equal(t, []byte{0x01, 0x02}, call.Input)
+	})
+
+	t.Run("RecordCallWithoutCode", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordCallWithoutCode()
+		})
+		assert.True(t, call.AccountWithoutCode)
+	})
+
+	t.Run("RecordCallFailed", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordCallFailed(1_000, "execution reverted: not the owner")
+		})
+		assert.True(t, call.Failed)
+		assert.Equal(t, uint64(1_000), call.FailureGasLeft)
+		assert.Equal(t, "execution reverted: not the owner", call.FailureReason)
+	})
+
+	t.Run("RecordCallReverted", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordCallReverted()
+		})
+		assert.True(t, call.Reverted)
+	})
+
+	t.Run("RecordKeccak", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordKeccak(crypto.Keccak256Hash([]byte("preimage")), []byte("preimage"))
+		})
+		assert.Equal(t, []*pb.KeccakPreimage{{Hash: crypto.Keccak256([]byte("preimage")), Data: []byte("preimage")}}, call.Changes.KeccakPreimages)
+	})
+
+	t.Run("RecordGasRefund", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordGasRefund(1_000, 200)
+		})
+		assert.Equal(t, []*pb.GasChange{{OldValue: 1_000, NewValue: 1_200, Reason: string(firehose.RefundAfterExecutionGasChangeReason), Ordinal: 3}}, call.Changes.GasChanges)
+	})
+
+	t.Run("RecordGasConsume", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordGasConsume(1_000, 300, firehose.GasChangeReason("state_cold_access"))
+		})
+		assert.Equal(t, []*pb.GasChange{{OldValue: 1_000, NewValue: 700, Reason: "state_cold_access", Ordinal: 3}}, call.Changes.GasChanges)
+	})
+
+	t.Run("RecordStorageChange", func(t *testing.T) {
+		key := libcommon.Hash{0x01}
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordStorageChange(callee, &key, uint256.NewInt(1), uint256.NewInt(2))
+		})
+		assert.Equal(t, []*pb.StorageChange{{
+			Address:  callee.Bytes(),
+			Key:      key.Bytes(),
+			OldValue: libcommon.BigToHash(big.NewInt(1)).Bytes(),
+			NewValue: libcommon.BigToHash(big.NewInt(2)).Bytes(),
+			Ordinal:  3,
+		}}, call.Changes.StorageChanges)
+	})
+
+	t.Run("RecordBalanceChange", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordBalanceChange(callee, uint256.NewInt(1), uint256.NewInt(11), firehose.BalanceChangeReason("transfer"))
+		})
+		assert.Equal(t, []*pb.BalanceChange{{Address: callee.Bytes(), OldValue: []byte{1}, NewValue: []byte{11}, Reason: "transfer", Ordinal: 3}}, call.Changes.BalanceChanges)
+	})
+
+	t.Run("RecordLog", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordLog(&types.Log{Address: callee, Topics: []libcommon.Hash{{0x0a}, {0x0b}}, Data: []byte{0xff}})
+			ctx.RecordLog(&types.Log{Address: callee})
+		})
+		assert.Equal(t, []*pb.Log{
+			{Address: callee.Bytes(), Topics: [][]byte{libcommon.Hash{0x0a}.Bytes(), libcommon.Hash{0x0b}.Bytes()}, Data: []byte{0xff}, BlockIndex: 0, Ordinal: 3},
+			{Address: callee.Bytes(), BlockIndex: 1, Ordinal: 4},
+		}, call.Changes.Logs)
+	})
+
+	t.Run("RecordSuicide", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordSuicide(callee, true, uint256.NewInt(5))
+		})
+		assert.Equal(t, []*pb.SuicideChange{{Address: callee.Bytes(), Suicided: true, BalanceBefore: []byte{5}}}, call.Changes.Suicides)
+		assert.Equal(t, []*pb.BalanceChange{{Address: callee.Bytes(), OldValue: []byte{5}, Reason: "suicide_withdraw", Ordinal: 3}}, call.Changes.BalanceChanges)
+	})
+
+	t.Run("RecordNewAccount", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordNewAccount(callee)
+		})
+		assert.Equal(t, []*pb.AccountCreation{{Address: callee.Bytes(), Ordinal: 3}}, call.Changes.AccountCreations)
+	})
+
+	t.Run("RecordCodeChange", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordCodeChange(callee, nil, nil, crypto.Keccak256Hash([]byte{0x60, 0x00}), []byte{0x60, 0x00})
+		})
+		assert.Equal(t, []*pb.CodeChange{{
+			Address: callee.Bytes(),
+			NewHash: crypto.Keccak256([]byte{0x60, 0x00}),
+			NewCode: []byte{0x60, 0x00},
+			Ordinal: 3,
+		}}, call.Changes.CodeChanges)
+	})
+
+	t.Run("RecordNonceChange", func(t *testing.T) {
+		call := readCall(t, func(ctx *firehose.Context) {
+			ctx.RecordNonceChange(caller, 1, 2)
+		})
+		assert.Equal(t, []*pb.NonceChange{{Address: caller.Bytes(), OldValue: 1, NewValue: 2, Ordinal: 3}}, call.Changes.NonceChanges)
+	})
+
+	t.Run("StartSystemCall", func(t *testing.T) {
+		block := types.NewBlockWithHeader(testHeader(1))
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.StartBlock(block)
+			ctx.StartSystemCall(firehose.SystemCallReason("bor_commit_state"))
+			ctx.StartCall("CALL")
+			ctx.RecordCallParams("CALL", caller, callee, uint256.NewInt(0), 90_000, []byte{0x01})
+			// Started within another system call, it's part of it
+			ctx.StartSystemCall(firehose.SystemCallReason("dao_hard_fork"))
+			ctx.RecordBalanceChange(caller, uint256.NewInt(0), uint256.NewInt(10), firehose.BalanceChangeReason("dao_refund_contract"))
+			ctx.EndSystemCall()
+			ctx.EndCall(80_000, nil)
+			ctx.EndSystemCall()
+			ctx.StartSystemCall(firehose.SystemCallReason("block_reward"))
+			ctx.RecordBalanceChange(caller, uint256.NewInt(10), uint256.NewInt(20), firehose.BalanceChangeReason("reward_mine_block"))
+			ctx.EndSystemCall()
+			ctx.FinalizeBlock(block)
+			ctx.EndBlock(block, nil, big.NewInt(1))
+		})
+
+		require.Len(t, msgs, 1)
+		systemCalls := msgs[0].Block.SystemCalls
+		require.Len(t, systemCalls, 2)
+		assert.Empty(t, msgs[0].Block.UnscopedCalls)
+
+		commit := systemCalls[0]
+		assert.Equal(t, "bor_commit_state", commit.Reason)
+		assert.Nil(t, commit.TrxHash)
+		assert.Equal(t, uint64(1), commit.BeginOrdinal)
+		assert.Equal(t, uint64(5), commit.EndOrdinal)
+		require.Len(t, commit.Calls, 1)
+		assert.Equal(t, uint32(1), commit.Calls[0].Index)
+		assert.Equal(t, []*pb.BalanceChange{{Address: caller.Bytes(), NewValue: []byte{10}, Reason: "dao_refund_contract", Ordinal: 3}}, commit.Calls[0].Changes.BalanceChanges)
+
+		reward := systemCalls[1]
+		assert.Equal(t, "block_reward", reward.Reason)
+		assert.Empty(t, reward.Calls)
+		assert.Equal(t, []*pb.BalanceChange{{Address: caller.Bytes(), OldValue: []byte{10}, NewValue: []byte{20}, Reason: "reward_mine_block", Ordinal: 7}}, reward.Changes.BalanceChanges)
+	})
+
+	t.Run("StartSystemTransaction", func(t *testing.T) {
+		block := types.NewBlockWithHeader(testHeader(1))
+		hash := libcommon.Hash{0x01}
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.StartBlock(block)
+			ctx.StartSystemTransaction(firehose.SystemCallReason("parlia_distribute_to_validator"), hash)
+			ctx.RecordNonceChange(caller, 1, 2)
+			ctx.EndSystemCall()
+			ctx.FinalizeBlock(block)
+			ctx.EndBlock(block, nil, big.NewInt(1))
+		})
+
+		require.Len(t, msgs, 1)
+		require.Len(t, msgs[0].Block.SystemCalls, 1)
+		assert.Equal(t, hash.Bytes(), msgs[0].Block.SystemCalls[0].TrxHash)
+	})
+
+	t.Run("StartSystemCall within transaction", func(t *testing.T) {
+		trx := readTransaction(t, func(ctx *firehose.Context) {
+			ctx.StartSystemCall(firehose.SystemCallReason("bor_last_state_id"))
+			ctx.RecordNonceChange(caller, 1, 2)
+			ctx.EndSystemCall()
+		})
+		assert.Len(t, trx.Calls[0].Changes.NonceChanges, 1)
+	})
+
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	require.NoError(t, err)
+	signed, err := types.SignTx(testTransaction(), *signer, key)
+	require.NoError(t, err)
+
+	t.Run("RecordTrxPoolAdded", func(t *testing.T) {
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.RecordTrxPoolAdded(signed, signer)
+			// Not signed, the sender cannot be recovered
+			ctx.RecordTrxPoolAdded(testTransaction(), signer)
+		})
+
+		require.Len(t, msgs, 2)
+		added := msgs[0].MempoolEvent
+		assert.Equal(t, pb.MempoolEventAdded, added.Type)
+		assert.Equal(t, signed.Hash().Bytes(), added.Transaction.Hash)
+		assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey).Bytes(), added.Transaction.From)
+		assert.Equal(t, uint64(21_000), added.Transaction.GasLimit)
+		assert.Empty(t, added.SenderError)
+
+		invalid := msgs[1].MempoolEvent
+		assert.Nil(t, invalid.Transaction.From)
+		assert.NotEmpty(t, invalid.SenderError)
+	})
+
+	t.Run("RecordTrxPoolReplaced", func(t *testing.T) {
+		replaced := libcommon.Hash{0x01}
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.RecordTrxPoolReplaced(signed, signer, replaced)
+		})
+
+		require.Len(t, msgs, 1)
+		event := msgs[0].MempoolEvent
+		assert.Equal(t, pb.MempoolEventReplaced, event.Type)
+		assert.Equal(t, signed.Hash().Bytes(), event.Transaction.Hash)
+		assert.Equal(t, replaced.Bytes(), event.Hash)
+	})
+
+	t.Run("RecordTrxPoolDropped", func(t *testing.T) {
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.RecordTrxPoolDropped(signed.Hash(), firehose.TrxPoolNonceTooLow)
+		})
+
+		require.Len(t, msgs, 1)
+		assert.Equal(t, &pb.MempoolEvent{Type: pb.MempoolEventDropped, Hash: signed.Hash().Bytes(), Reason: "nonce_too_low"}, msgs[0].MempoolEvent)
+	})
+
+	t.Run("RecordTrxPoolIncluded", func(t *testing.T) {
+		msgs := readAll(t, func(ctx *firehose.Context) {
+			ctx.RecordTrxPoolIncluded(signed.Hash(), 5, testHeader(5).Hash())
+		})
+
+		require.Len(t, msgs, 1)
+		assert.Equal(t, &pb.MempoolEvent{Type: pb.MempoolEventIncluded, Hash: signed.Hash().Bytes(), BlockNumber: 5, BlockHash: testHeader(5).Hash().Bytes()}, msgs[0].MempoolEvent)
+	})
+}
+
+func TestReader_Validation(t *testing.T) {
+	init := fmt.Sprintf("FIRE INIT 2.2 geth erigon/test %d\n", firehose.SchemaVersion)
+
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{name: "missing init", in: "FIRE BEGIN_BLOCK 1\n", wantErr: "expected INIT record first"},
+		{name: "unsupported schema", in: "FIRE INIT 2.2 geth erigon/test 99\n", wantErr: ErrUnsupportedSchema.Error()},
+		{name: "unknown kind", in: init + "FIRE BEGIN_EPOCH 1\n", wantErr: `unknown record kind "BEGIN_EPOCH"`},
+		{name: "missing field", in: init + "FIRE NEW_BLOCK 1 aa\n", wantErr: "expected 3 fields, got 2"},
+		{name: "extra field", in: init + "FIRE BEGIN_BLOCK 1 2\n", wantErr: "expected 1 fields, got 2"},
+		{name: "missing field around free-form", in: init + "FIRE EVM_CALL_FAILED 1\n", wantErr: "expected 3 fields"},
+		{name: "out of block", in: init + "FIRE TRX_FROM aa\n", wantErr: "TRX_FROM"},
+		{name: "system call end without begin", in: init + "FIRE BEGIN_BLOCK 1\nFIRE END_SYSTEM_CALL 1\n", wantErr: "not within a system call"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := NewReader(strings.NewReader(test.in))
+			var err error
+			for err == nil {
+				_, err = reader.Next()
+			}
+
+			require.NotEqual(t, io.EOF, err)
+			assert.ErrorContains(t, err, test.wantErr)
+		})
+	}
+}
+
+func TestReader_SkipsOtherLines(t *testing.T) {
+	reader := NewReader(strings.NewReader(fmt.Sprintf("INFO[04-01|10:00:00.000] Starting Erigon\nFIRE INIT 2.2 geth erigon/test %d\n\nFIRE FINALIZE_BLOCK 7", firehose.SchemaVersion)))
+
+	msg, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, &pb.Init{FirehoseVersion: "2.2", Variant: "geth", NodeVersion: "erigon/test", SchemaVersion: firehose.SchemaVersion}, msg.Init)
+
+	msg, err = reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, &pb.FinalizeBlock{Number: 7}, msg.FinalizeBlock)
+
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+// readAll records with a context printing text records and reads them back, the INIT record
+// is checked and not returned.
+func readAll(t *testing.T, record func(ctx *firehose.Context)) (out []*pb.Message) {
+	t.Helper()
+
+	buffer := bytes.NewBuffer(nil)
+	printer, err := firehose.NewPrinter(firehose.TextOutputFormat, buffer)
+	require.NoError(t, err)
+	ctx := firehose.NewContext(printer)
+	ctx.InitVersion("erigon/test", "2.2", "geth")
+	record(ctx)
+
+	reader := NewReader(buffer)
+	for {
+		msg, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		out = append(out, msg)
+	}
+
+	require.NotEmpty(t, out)
+	require.NotNil(t, out[0].Init)
+	return out[1:]
+}
+
+// readTransaction records a block with a single transaction, the records of `record` being
+// made within the root call of the transaction.
+func readTransaction(t *testing.T, record func(ctx *firehose.Context)) *pb.TransactionTrace {
+	t.Helper()
+
+	block := types.NewBlockWithHeader(testHeader(1))
+	msgs := readAll(t, func(ctx *firehose.Context) {
+		ctx.StartBlock(block)
+		ctx.StartTransaction(testTransaction(), nil)
+		ctx.RecordTrxFrom(caller)
+		ctx.StartCall("CALL")
+		ctx.RecordCallParams("CALL", caller, callee, uint256.NewInt(10), 90_000, []byte{0x01, 0x02})
+		record(ctx)
+		ctx.EndCall(80_000, nil)
+		ctx.EndTransaction(&types.Receipt{GasUsed: 21_000, CumulativeGasUsed: 21_000})
+		ctx.FinalizeBlock(block)
+		ctx.EndBlock(block, nil, big.NewInt(1))
+	})
+
+	require.Len(t, msgs, 1)
+	require.NotNil(t, msgs[0].Block)
+	require.Len(t, msgs[0].Block.Transactions, 1)
+	return msgs[0].Block.Transactions[0]
+}
+
+func readCall(t *testing.T, record func(ctx *firehose.Context)) *pb.Call {
+	t.Helper()
+
+	trx := readTransaction(t, record)
+	require.Len(t, trx.Calls, 1)
+	return trx.Calls[0]
+}
+
+func testTransaction() types.Transaction {
+	return types.NewTransaction(0, callee, uint256.NewInt(10), 21_000, uint256.NewInt(5), nil)
+}
+
+func testHeader(number int64) *types.Header {
+	return &types.Header{Number: big.NewInt(number)}
+}
@@ -0,0 +1,113 @@
+package firehose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SchemaVersion is the version of the record schema declared by Schema, it's advertised in
+// the INIT record. It must be bumped on any change to the fields of a record, consumers
+// refusing a stream whose version they don't know instead of misreading its fields.
+const SchemaVersion = 3
+
+// RecordSchema declares the fields of a record kind, in the order they are printed.
+type RecordSchema struct {
+	Fields []string
+
+	// FreeForm is the name of the field that can contain spaces, a free-form message like an
+	// error, empty when there is none. Records are split on spaces around it.
+	FreeForm string
+}
+
+// Index returns the position of the given field, it panics if the record has no such field.
+func (s RecordSchema) Index(field string) int {
+	for i, name := range s.Fields {
+		if name == field {
+			return i
+		}
+	}
+
+	panic(fmt.Errorf("record has no field %q", field))
+}
+
+// Split splits the space-separated fields of a text record, without its kind.
+func (s RecordSchema) Split(in string) ([]string, error) {
+	if s.FreeForm == "" {
+		fields := strings.Split(in, " ")
+		if len(fields) != len(s.Fields) {
+			return nil, fmt.Errorf("expected %d fields, got %d", len(s.Fields), len(fields))
+		}
+
+		return fields, nil
+	}
+
+	freeForm := s.Index(s.FreeForm)
+	fields := make([]string, len(s.Fields))
+	for i := 0; i < freeForm; i++ {
+		field, rest, found := strings.Cut(in, " ")
+		if !found {
+			return nil, fmt.Errorf("expected %d fields, got fewer", len(s.Fields))
+		}
+		fields[i], in = field, rest
+	}
+	for i := len(s.Fields) - 1; i > freeForm; i-- {
+		cut := strings.LastIndexByte(in, ' ')
+		if cut < 0 {
+			return nil, fmt.Errorf("expected %d fields, got fewer", len(s.Fields))
+		}
+		fields[i], in = in[cut+1:], in[:cut]
+	}
+	fields[freeForm] = in
+
+	return fields, nil
+}
+
+var trxFields = []string{
+	"hash", "to", "value", "v", "r", "s", "gas_limit", "gas_price", "nonce", "input",
+	"access_list", "max_fee_per_gas", "max_priority_fee_per_gas", "type", "max_fee_per_blob_gas",
+	"blob_hashes",
+}
+
+// Schema declares the fields of every record kind printed by a Context. Fields are encoded
+// with the helpers of this package (`Uint64`, `Hex`, `Hash`, `Addr`, `Bool`, `JSON`), "." being
+// the empty value of optional ones.
+var Schema = map[string]RecordSchema{
+	"INIT":            {Fields: []string{"firehose_version", "variant", "node_version", "schema_version"}},
+	"BEGIN_BLOCK":     {Fields: []string{"number"}},
+	"FINALIZE_BLOCK":  {Fields: []string{"number"}},
+	"END_BLOCK":       {Fields: []string{"number", "size", "end_data"}},
+	"CANCEL_BLOCK":    {Fields: []string{"number", "error"}, FreeForm: "error"},
+	"FINALITY_UPDATE": {Fields: []string{"safe_number", "safe_hash", "finalized_number", "finalized_hash"}},
+	"NEW_BLOCK":       {Fields: []string{"number", "hash", "parent_hash"}},
+	"UNDO_BLOCK":      {Fields: []string{"number", "hash", "parent_hash"}},
+
+	"BEGIN_APPLY_TRX":  {Fields: append(append([]string(nil), trxFields...), "ordinal")},
+	"TRX_FROM":         {Fields: []string{"from"}},
+	"FAILED_APPLY_TRX": {Fields: []string{"error", "ordinal"}, FreeForm: "error"},
+	"END_APPLY_TRX":    {Fields: []string{"gas_used", "post_state", "cumulative_gas_used", "logs_bloom", "blob_gas_used", "blob_gas_price", "ordinal", "logs"}},
+
+	"BEGIN_SYSTEM_CALL": {Fields: []string{"reason", "trx_hash", "ordinal"}},
+	"END_SYSTEM_CALL":   {Fields: []string{"ordinal"}},
+
+	"EVM_RUN_CALL":         {Fields: []string{"call_type", "call_index", "ordinal"}},
+	"EVM_PARAM":            {Fields: []string{"call_type", "call_index", "caller", "address", "value", "gas_limit", "input"}},
+	"ACCOUNT_WITHOUT_CODE": {Fields: []string{"call_index"}},
+	"EVM_CALL_FAILED":      {Fields: []string{"call_index", "gas_left", "reason"}, FreeForm: "reason"},
+	"EVM_REVERTED":         {Fields: []string{"call_index"}},
+	"EVM_END_CALL":         {Fields: []string{"call_index", "gas_left", "return_data", "ordinal"}},
+
+	"EVM_KECCAK":      {Fields: []string{"call_index", "hash", "data"}},
+	"GAS_CHANGE":      {Fields: []string{"call_index", "old_value", "new_value", "reason", "ordinal"}},
+	"STORAGE_CHANGE":  {Fields: []string{"call_index", "address", "key", "old_value", "new_value", "ordinal"}},
+	"BALANCE_CHANGE":  {Fields: []string{"call_index", "address", "old_value", "new_value", "reason", "ordinal"}},
+	"ADD_LOG":         {Fields: []string{"call_index", "block_index", "address", "topics", "data", "ordinal"}},
+	"SUICIDE_CHANGE":  {Fields: []string{"call_index", "address", "suicided", "balance"}},
+	"CREATED_ACCOUNT": {Fields: []string{"call_index", "address", "ordinal"}},
+	"CODE_CHANGE":     {Fields: []string{"call_index", "address", "old_hash", "old_code", "new_hash", "new_code", "ordinal"}},
+	"NONCE_CHANGE":    {Fields: []string{"call_index", "address", "old_value", "new_value", "ordinal"}},
+
+	"TRX_POOL_ADDED":    {Fields: append(append([]string(nil), trxFields...), "from", "sender_error"), FreeForm: "sender_error"},
+	"TRX_POOL_REPLACED": {Fields: append(append([]string(nil), trxFields...), "from", "replaced_hash", "sender_error"), FreeForm: "sender_error"},
+	"TRX_POOL_DROPPED":  {Fields: []string{"hash", "reason"}},
+	"TRX_POOL_INCLUDED": {Fields: []string{"hash", "block_number", "block_hash"}},
+}
@@ -0,0 +1,328 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/firehose/pb"
+)
+
+var (
+	// ErrEvicted is returned when reading an entry that was already removed from the buffer.
+	ErrEvicted = errors.New("entry evicted from the buffer")
+
+	// ErrNotYetAvailable is returned when reading an entry that was not appended yet.
+	ErrNotYetAvailable = errors.New("entry not yet available")
+)
+
+// Entries are stored in a file named after their sequence number with a suffix
+// telling their fork step, the content of the file is the block in both cases.
+var entryFileSuffixes = map[pb.Step]string{
+	pb.StepNew:  ".block",
+	pb.StepUndo: ".undo",
+}
+
+// Buffer is a bounded on-disk ring buffer of recent Firehose fork steps, a new
+// canonical block or the undo of a block removed by an unwind. Each appended entry
+// gets the next sequence number and is stored in its own file, once more than
+// `capacity` entries are stored, the oldest ones are removed.
+//
+// Sequence numbers keep increasing across restarts, so they can be used to resume
+// streaming exactly where a client left.
+type Buffer struct {
+	dir      string
+	capacity int
+
+	mu      sync.RWMutex
+	entries []bufferEntry
+	nextSeq uint64
+	notify  chan struct{}
+}
+
+type bufferEntry struct {
+	seq    uint64
+	step   pb.Step
+	number uint64
+	hash   []byte
+}
+
+func OpenBuffer(dir string, capacity int) (*Buffer, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity must be positive, got %d", capacity)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	b := &Buffer{dir: dir, capacity: capacity, notify: make(chan struct{})}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *Buffer) load() error {
+	files, err := os.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			continue
+		}
+
+		if strings.HasSuffix(name, ".tmp") {
+			// Leftover of an append interrupted by a crash
+			if err := os.Remove(filepath.Join(b.dir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		step, seq, ok := parseEntryFileName(name)
+		if !ok {
+			continue
+		}
+
+		block, err := b.readFile(seq, step)
+		if err != nil {
+			// Most probably a partially written file from a crash, there is nothing to recover
+			if err := os.Remove(b.path(seq, step)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b.entries = append(b.entries, newBufferEntry(seq, step, block))
+	}
+
+	sort.Slice(b.entries, func(i, j int) bool { return b.entries[i].seq < b.entries[j].seq })
+	if len(b.entries) > 0 {
+		b.nextSeq = b.entries[len(b.entries)-1].seq + 1
+	}
+
+	return b.evict()
+}
+
+func parseEntryFileName(name string) (pb.Step, uint64, bool) {
+	for step, suffix := range entryFileSuffixes {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, suffix), 10, 64)
+		if err != nil {
+			return pb.StepUnset, 0, false
+		}
+
+		return step, seq, true
+	}
+
+	return pb.StepUnset, 0, false
+}
+
+func newBufferEntry(seq uint64, step pb.Step, block *pb.Block) bufferEntry {
+	entry := bufferEntry{seq: seq, step: step, number: block.Number}
+	if block.Header != nil {
+		entry.hash = block.Header.Hash
+	}
+
+	return entry
+}
+
+// Append stores the block as the newest entry and wakes up all readers waiting for it.
+func (b *Buffer) Append(block *pb.Block) (Cursor, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.append(pb.StepNew, block)
+}
+
+// AppendUndo stores the undo of a block removed from the canonical chain as the newest
+// entry. The undone block is served in full when it's still buffered, otherwise only
+// its number, hash and parent hash are known.
+func (b *Buffer) AppendUndo(number uint64, hash, parentHash []byte) (Cursor, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block := &pb.Block{Number: number, Header: &pb.BlockHeader{Hash: hash, ParentHash: parentHash}}
+	for i := len(b.entries) - 1; i >= 0; i-- {
+		entry := b.entries[i]
+		if entry.step != pb.StepNew || entry.number != number || !bytes.Equal(entry.hash, hash) {
+			continue
+		}
+
+		buffered, err := b.readFile(entry.seq, entry.step)
+		if err != nil {
+			return Cursor{}, err
+		}
+
+		block = buffered
+		break
+	}
+
+	return b.append(pb.StepUndo, block)
+}
+
+func (b *Buffer) append(step pb.Step, block *pb.Block) (Cursor, error) {
+	seq := b.nextSeq
+	tmpPath := b.path(seq, step) + ".tmp"
+	if err := os.WriteFile(tmpPath, block.Marshal(), 0644); err != nil {
+		return Cursor{}, err
+	}
+	if err := os.Rename(tmpPath, b.path(seq, step)); err != nil {
+		return Cursor{}, err
+	}
+
+	entry := newBufferEntry(seq, step, block)
+	b.entries = append(b.entries, entry)
+	b.nextSeq++
+
+	if err := b.evict(); err != nil {
+		return Cursor{}, err
+	}
+
+	close(b.notify)
+	b.notify = make(chan struct{})
+
+	return entry.cursor(), nil
+}
+
+func (b *Buffer) evict() error {
+	for len(b.entries) > b.capacity {
+		if err := os.Remove(b.path(b.entries[0].seq, b.entries[0].step)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		b.entries = b.entries[1:]
+	}
+
+	return nil
+}
+
+// nextSequence returns the sequence number of the next appended entry.
+func (b *Buffer) nextSequence() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.nextSeq
+}
+
+// Read returns the block and fork step of the entry with the given sequence number along
+// with its cursor.
+func (b *Buffer) Read(seq uint64) (*pb.Block, pb.Step, Cursor, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	entry, err := b.entry(seq)
+	if err != nil {
+		return nil, pb.StepUnset, Cursor{}, err
+	}
+
+	block, err := b.readFile(seq, entry.step)
+	if err != nil {
+		return nil, pb.StepUnset, Cursor{}, err
+	}
+
+	return block, entry.step, entry.cursor(), nil
+}
+
+// Wait returns a channel that is closed on the next Append, to avoid missing an entry,
+// it must be obtained before the Read call that returned ErrNotYetAvailable.
+func (b *Buffer) Wait() <-chan struct{} {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.notify
+}
+
+// SeekBlock returns the sequence number of the oldest new block entry with a block number
+// greater or equal to `number`, it returns the next sequence number if there is none yet.
+// It's an error to seek a block older than the oldest entry once entries have been evicted.
+func (b *Buffer) SeekBlock(number uint64) (uint64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.entries) > 0 && b.entries[0].seq > 0 && number < b.entries[0].number {
+		return 0, fmt.Errorf("block #%d is older than the oldest buffered block #%d: %w", number, b.entries[0].number, ErrEvicted)
+	}
+
+	for _, entry := range b.entries {
+		if entry.step == pb.StepNew && entry.number >= number {
+			return entry.seq, nil
+		}
+	}
+
+	return b.nextSeq, nil
+}
+
+// Resume validates that the cursor points to an entry of this buffer and returns the
+// sequence number of the entry following it.
+func (b *Buffer) Resume(cursor Cursor) (uint64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	entry, err := b.entry(cursor.Seq)
+	if err != nil {
+		return 0, err
+	}
+
+	if entry.cursor() != cursor {
+		return 0, fmt.Errorf("cursor %s does not match buffered block #%d", cursor, entry.number)
+	}
+
+	return cursor.Seq + 1, nil
+}
+
+func (b *Buffer) entry(seq uint64) (bufferEntry, error) {
+	if seq >= b.nextSeq {
+		return bufferEntry{}, ErrNotYetAvailable
+	}
+
+	if len(b.entries) == 0 || seq < b.entries[0].seq {
+		return bufferEntry{}, ErrEvicted
+	}
+
+	i := sort.Search(len(b.entries), func(i int) bool { return b.entries[i].seq >= seq })
+	if i == len(b.entries) || b.entries[i].seq != seq {
+		// Entries discarded while loading the buffer leave holes in the sequence
+		return bufferEntry{}, ErrEvicted
+	}
+
+	return b.entries[i], nil
+}
+
+func (b *Buffer) readFile(seq uint64, step pb.Step) (*pb.Block, error) {
+	data, err := os.ReadFile(b.path(seq, step))
+	if err != nil {
+		return nil, err
+	}
+
+	block := &pb.Block{}
+	if err := block.Unmarshal(data); err != nil {
+		return nil, fmt.Errorf("decode buffered block %d: %w", seq, err)
+	}
+
+	return block, nil
+}
+
+func (b *Buffer) path(seq uint64, step pb.Step) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", seq, entryFileSuffixes[step]))
+}
+
+func (e bufferEntry) cursor() Cursor {
+	c := Cursor{Seq: e.seq, Number: e.number}
+	copy(c.Hash[:], e.hash)
+
+	return c
+}
@@ -0,0 +1,137 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon/firehose/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBlock(number uint64) *pb.Block {
+	return &pb.Block{Number: number, Header: &pb.BlockHeader{Hash: []byte{byte(number), 0xff}}}
+}
+
+func TestBuffer_EvictsOldest(t *testing.T) {
+	buffer, err := OpenBuffer(t.TempDir(), 3)
+	require.NoError(t, err)
+
+	for i := uint64(1); i <= 5; i++ {
+		cursor, err := buffer.Append(testBlock(i))
+		require.NoError(t, err)
+		assert.Equal(t, i-1, cursor.Seq)
+		assert.Equal(t, i, cursor.Number)
+	}
+
+	_, _, _, err = buffer.Read(1)
+	assert.ErrorIs(t, err, ErrEvicted)
+
+	_, _, _, err = buffer.Read(5)
+	assert.ErrorIs(t, err, ErrNotYetAvailable)
+
+	block, step, cursor, err := buffer.Read(2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), block.Number)
+	assert.Equal(t, pb.StepNew, step)
+	assert.Equal(t, uint64(2), cursor.Seq)
+
+	_, err = buffer.SeekBlock(1)
+	assert.ErrorIs(t, err, ErrEvicted)
+
+	seq, err := buffer.SeekBlock(4)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), seq)
+
+	seq, err = buffer.SeekBlock(100)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), seq)
+}
+
+func TestBuffer_Reopen(t *testing.T) {
+	dir := t.TempDir()
+
+	buffer, err := OpenBuffer(dir, 10)
+	require.NoError(t, err)
+
+	var last Cursor
+	for i := uint64(1); i <= 3; i++ {
+		last, err = buffer.Append(testBlock(i))
+		require.NoError(t, err)
+	}
+
+	reopened, err := OpenBuffer(dir, 2)
+	require.NoError(t, err)
+
+	next, err := reopened.Resume(last)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), next)
+
+	cursor, err := reopened.Append(testBlock(4))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), cursor.Seq)
+
+	_, _, _, err = reopened.Read(1)
+	assert.ErrorIs(t, err, ErrEvicted)
+}
+
+func TestBuffer_Undo(t *testing.T) {
+	dir := t.TempDir()
+
+	buffer, err := OpenBuffer(dir, 10)
+	require.NoError(t, err)
+
+	for i := uint64(1); i <= 3; i++ {
+		_, err = buffer.Append(testBlock(i))
+		require.NoError(t, err)
+	}
+
+	// The undone block is served in full when still buffered
+	undone := testBlock(3)
+	cursor, err := buffer.AppendUndo(3, undone.Header.Hash, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), cursor.Seq)
+
+	_, err = buffer.AppendUndo(2, []byte{0x02, 0xee}, []byte{0x01, 0xee})
+	require.NoError(t, err)
+
+	// Undo steps are kept across restarts and never used as a starting point
+	reopened, err := OpenBuffer(dir, 10)
+	require.NoError(t, err)
+
+	block, step, _, err := reopened.Read(3)
+	require.NoError(t, err)
+	assert.Equal(t, pb.StepUndo, step)
+	assert.Equal(t, undone, block)
+
+	block, step, _, err = reopened.Read(4)
+	require.NoError(t, err)
+	assert.Equal(t, pb.StepUndo, step)
+	assert.Equal(t, &pb.Block{Number: 2, Header: &pb.BlockHeader{Hash: []byte{0x02, 0xee}, ParentHash: []byte{0x01, 0xee}}}, block)
+
+	seq, err := reopened.SeekBlock(3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+
+	seq, err = reopened.SeekBlock(4)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), seq)
+}
+
+func TestBuffer_ResumeMismatch(t *testing.T) {
+	buffer, err := OpenBuffer(t.TempDir(), 10)
+	require.NoError(t, err)
+
+	cursor, err := buffer.Append(testBlock(1))
+	require.NoError(t, err)
+
+	cursor.Hash[0] = 0xaa
+	_, err = buffer.Resume(cursor)
+	assert.Error(t, err)
+
+	parsed, err := ParseCursor(cursor.String())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, parsed)
+
+	_, err = ParseCursor("not-a-cursor")
+	assert.Error(t, err)
+}
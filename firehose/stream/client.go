@@ -0,0 +1,52 @@
+package stream
+
+import (
+	"bufio"
+	"errors"
+	"net"
+
+	"github.com/ledgerwatch/erigon/firehose/pb"
+)
+
+// Client reads blocks streamed by a Server, see the package documentation for the protocol.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Dial connects to the server and requests blocks starting at `startBlock`, or right
+// after `cursor` when it's not empty. With `finalizedOnly`, blocks are only received
+// once finalized.
+func Dial(address string, startBlock uint64, cursor string, finalizedOnly bool) (*Client, error) {
+	network, addr := parseAddress(address)
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pb.WriteDelimited(conn, &pb.StreamRequest{StartBlock: startBlock, Cursor: cursor, FinalizedOnly: finalizedOnly}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Client{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Recv blocks until the next fork step is received and returns it, an error sent by the
+// server is returned as an error.
+func (c *Client) Recv() (*pb.StreamResponse, error) {
+	response := &pb.StreamResponse{}
+	if err := pb.ReadDelimited(c.reader, response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+
+	return response, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
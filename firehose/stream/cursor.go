@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+const cursorLength = 8 + 8 + 32
+
+// Cursor points to an entry of the Buffer, it's handed to clients as an opaque string
+// along with each block so they can resume streaming right after it. The block number
+// and hash are kept to detect cursors that do not belong to the buffer anymore (like
+// after it was wiped).
+type Cursor struct {
+	Seq    uint64
+	Number uint64
+	Hash   [32]byte
+}
+
+func (c Cursor) String() string {
+	out := make([]byte, cursorLength)
+	binary.BigEndian.PutUint64(out[0:8], c.Seq)
+	binary.BigEndian.PutUint64(out[8:16], c.Number)
+	copy(out[16:], c.Hash[:])
+
+	return base64.RawURLEncoding.EncodeToString(out)
+}
+
+func ParseCursor(in string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(in)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor %q: %w", in, err)
+	}
+
+	if len(raw) != cursorLength {
+		return Cursor{}, fmt.Errorf("invalid cursor %q: expected %d bytes, got %d", in, cursorLength, len(raw))
+	}
+
+	c := Cursor{
+		Seq:    binary.BigEndian.Uint64(raw[0:8]),
+		Number: binary.BigEndian.Uint64(raw[8:16]),
+	}
+	copy(c.Hash[:], raw[16:])
+
+	return c, nil
+}
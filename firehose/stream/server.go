@@ -0,0 +1,410 @@
+// Package stream serves the blocks produced by Firehose instrumentation to multiple
+// clients over a Unix or TCP socket.
+//
+// Blocks are served as soon as they are executed, not once they are final: a block can
+// still be removed from the canonical chain by an unwind, which is sent as an undo step.
+// Each block carries the finalized block known when it was executed, so clients needing
+// irreversible data can hold blocks until they are finalized, or ask the server to do so
+// with the finalized only mode of their request. In this mode, blocks are sent once the
+// finality advertised by the blocks and finality updates that followed them reaches them,
+// and blocks undone before that are never sent. Finality must reach the oldest block held
+// within the capacity of the Buffer, otherwise the client gets an error.
+//
+// A client opens a connection and sends a single length-prefixed `pb.StreamRequest`,
+// the server then answers with a `pb.StreamResponse` per fork step, forever. A step is
+// either a new canonical block or the undo of a block removed from the canonical chain
+// by an unwind, undos being sent newest first. Every response carries an opaque cursor,
+// a client reconnecting with the last cursor it received resumes right after that step
+// without gaps nor duplicates, as long as the step is still within the server's Buffer.
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/firehose/pb"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Listen opens a listener for an address of the form `unix:///path/to/socket` or
+// `tcp://host:port`, a bare `host:port` being considered a TCP address.
+func Listen(address string) (net.Listener, error) {
+	network, addr := parseAddress(address)
+	if network == "unix" {
+		// A socket file left over by a previous run prevents listening
+		if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return net.Listen(network, addr)
+}
+
+func parseAddress(address string) (network string, addr string) {
+	if strings.HasPrefix(address, "unix://") {
+		return "unix", strings.TrimPrefix(address, "unix://")
+	}
+
+	return "tcp", strings.TrimPrefix(address, "tcp://")
+}
+
+// publishQueueSize is the number of published messages waiting to be buffered before
+// Publish blocks, which only happens when the disk can't keep up with execution.
+const publishQueueSize = 1024
+
+type Server struct {
+	buffer    *Buffer
+	published chan *pb.Message
+	pending   sync.WaitGroup // Published messages not buffered yet
+	buffered  chan struct{}  // Closed once the server is closed and the queue is drained
+
+	finalityMu   sync.Mutex
+	finalized    uint64        // Latest finalized block number of the finality updates
+	finalizedSeq uint64        // Sequence number of the entry buffered after the latest finality update
+	finality     chan struct{} // Closed when the finalized block number advances
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewServer(buffer *Buffer) *Server {
+	s := &Server{
+		buffer:    buffer,
+		published: make(chan *pb.Message, publishQueueSize),
+		buffered:  make(chan struct{}),
+		finality:  make(chan struct{}),
+		conns:     map[net.Conn]struct{}{},
+		closed:    make(chan struct{}),
+	}
+	go s.bufferPublished()
+
+	return s
+}
+
+// Publish queues the blocks and undo steps of the Firehose messages to be stored in the
+// buffer, making them available to clients, along with the finality updates which are
+// applied in order with them. Other kind of messages are ignored, a block being a new step
+// by itself, the new fork steps following them are ignored too. It's meant to be used as
+// the handler of a `firehose.ProtobufPrinter`, the blocks are written to disk in the
+// background not to slow down execution.
+func (s *Server) Publish(msg *pb.Message) {
+	if msg.Block == nil && msg.FinalityUpdate == nil && (msg.ForkStep == nil || msg.ForkStep.Step != pb.StepUndo) {
+		return
+	}
+
+	s.pending.Add(1)
+	select {
+	case s.published <- msg:
+	case <-s.closed:
+		s.pending.Done()
+	}
+}
+
+// bufferPublished stores the published messages in the buffer until the server is closed,
+// the messages queued by then being stored before it returns.
+func (s *Server) bufferPublished() {
+	defer close(s.buffered)
+
+	for {
+		select {
+		case msg := <-s.published:
+			s.store(msg)
+		case <-s.closed:
+			for {
+				select {
+				case msg := <-s.published:
+					s.store(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// advanceFinality records the finality of a finality update, which only applies to the
+// entries buffered before it.
+func (s *Server) advanceFinality(finalized uint64) {
+	s.finalityMu.Lock()
+	defer s.finalityMu.Unlock()
+
+	if finalized > s.finalized {
+		s.finalized = finalized
+		s.finalizedSeq = s.buffer.nextSequence()
+		close(s.finality)
+		s.finality = make(chan struct{})
+	}
+}
+
+// latestFinality returns the finalized block number of the latest finality update, along
+// with the sequence number of the entry following it and a channel closed when it advances.
+func (s *Server) latestFinality() (uint64, uint64, <-chan struct{}) {
+	s.finalityMu.Lock()
+	defer s.finalityMu.Unlock()
+
+	return s.finalized, s.finalizedSeq, s.finality
+}
+
+// flush waits for the messages published so far to be buffered.
+func (s *Server) flush() {
+	s.pending.Wait()
+}
+
+func (s *Server) store(msg *pb.Message) {
+	defer s.pending.Done()
+
+	switch {
+	case msg.Block != nil:
+		if _, err := s.buffer.Append(msg.Block); err != nil {
+			log.Error("Firehose stream server failed to buffer block", "number", msg.Block.Number, "err", err)
+		}
+
+	case msg.ForkStep != nil && msg.ForkStep.Step == pb.StepUndo:
+		if _, err := s.buffer.AppendUndo(msg.ForkStep.Number, msg.ForkStep.Hash, msg.ForkStep.ParentHash); err != nil {
+			log.Error("Firehose stream server failed to buffer undo step", "number", msg.ForkStep.Number, "err", err)
+		}
+
+	case msg.FinalityUpdate != nil:
+		s.advanceFinality(msg.FinalityUpdate.FinalizedBlockNumber)
+	}
+}
+
+// Serve accepts connections on the listener until the server is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-s.closed:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+		s.mu.Unlock()
+		return
+	default:
+	}
+
+	close(s.closed)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	<-s.buffered
+	s.wg.Wait()
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	request := &pb.StreamRequest{}
+	if err := pb.ReadDelimited(bufio.NewReader(conn), request); err != nil {
+		log.Debug("Firehose stream client sent an invalid request", "remote", conn.RemoteAddr(), "err", err)
+		return
+	}
+
+	seq, err := s.startSeq(request)
+	if err != nil {
+		_ = pb.WriteDelimited(conn, &pb.StreamResponse{Error: err.Error()})
+		return
+	}
+
+	// Reading from the connection is the only way to notice a client that went away
+	// while we are waiting for the next block, clients send nothing after their request
+	// so anything read is discarded until the connection is closed.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	if request.FinalizedOnly {
+		s.streamFinalized(conn, request, seq, gone)
+		return
+	}
+
+	// A client starting at a block not buffered yet starts at the next entry, the steps
+	// before the block it asked for are skipped.
+	started := request.Cursor != ""
+	for {
+		wait := s.buffer.Wait()
+		block, step, cursor, err := s.buffer.Read(seq)
+		if errors.Is(err, ErrNotYetAvailable) {
+			select {
+			case <-wait:
+				continue
+			case <-gone:
+				return
+			case <-s.closed:
+				return
+			}
+		}
+
+		if err != nil {
+			sendError(conn, err)
+			return
+		}
+
+		if !started {
+			if step != pb.StepNew || block.Number < request.StartBlock {
+				seq++
+				continue
+			}
+			started = true
+		}
+
+		if err := pb.WriteDelimited(conn, &pb.StreamResponse{Block: block, Cursor: cursor.String(), Step: step}); err != nil {
+			return
+		}
+		seq++
+	}
+}
+
+// heldBlock is a block of the finalized only mode waiting to be finalized.
+type heldBlock struct {
+	seq    uint64
+	number uint64
+	hash   []byte
+}
+
+// streamFinalized streams the blocks of the entries starting at `seq` once they are
+// finalized. The blocks not finalized yet are held, in chain order, until the finality
+// reaches them or they are undone.
+func (s *Server) streamFinalized(conn net.Conn, request *pb.StreamRequest, seq uint64, gone <-chan struct{}) {
+	var held []heldBlock
+	// The finality advertised by the blocks and finality updates up to the entry being read,
+	// the blocks undone before an update being never finalized by it
+	var finalized uint64
+	for {
+		updateFinalized, updateSeq, finality := s.latestFinality()
+		if seq >= updateSeq && updateFinalized > finalized {
+			finalized = updateFinalized
+		}
+
+		for len(held) > 0 && held[0].number <= finalized {
+			block, _, cursor, err := s.buffer.Read(held[0].seq)
+			if err != nil {
+				sendError(conn, err)
+				return
+			}
+
+			if err := pb.WriteDelimited(conn, &pb.StreamResponse{Block: block, Cursor: cursor.String(), Step: pb.StepNew}); err != nil {
+				return
+			}
+			held = held[1:]
+		}
+
+		wait := s.buffer.Wait()
+		block, step, _, err := s.buffer.Read(seq)
+		if errors.Is(err, ErrNotYetAvailable) {
+			select {
+			case <-wait:
+				continue
+			case <-finality:
+				continue
+			case <-gone:
+				return
+			case <-s.closed:
+				return
+			}
+		}
+
+		if err != nil {
+			sendError(conn, err)
+			return
+		}
+
+		if step == pb.StepNew && block.FinalizedBlockNumber > finalized {
+			finalized = block.FinalizedBlockNumber
+		}
+
+		hash := blockHash(block)
+		switch {
+		case step == pb.StepNew && (request.Cursor != "" || block.Number >= request.StartBlock):
+			// Held blocks are buffered entries, the oldest one is evicted once there are more
+			// held blocks than buffered entries
+			if len(held) == s.buffer.capacity {
+				sendError(conn, fmt.Errorf("finality is behind more than the %d buffered entries", s.buffer.capacity))
+				return
+			}
+			held = append(held, heldBlock{seq: seq, number: block.Number, hash: hash})
+
+		case step == pb.StepUndo:
+			// Undos are sent newest first, an undo of a block that is not held was skipped
+			if last := len(held) - 1; last >= 0 && held[last].number == block.Number && bytes.Equal(held[last].hash, hash) {
+				held = held[:last]
+			}
+		}
+		seq++
+	}
+}
+
+func blockHash(block *pb.Block) []byte {
+	if block.Header == nil {
+		return nil
+	}
+
+	return block.Header.Hash
+}
+
+// sendError sends the error ending the stream of the client.
+func sendError(conn net.Conn, err error) {
+	if errors.Is(err, ErrEvicted) {
+		err = fmt.Errorf("client is too slow, next block was evicted from the buffer: %w", err)
+	}
+
+	_ = pb.WriteDelimited(conn, &pb.StreamResponse{Error: err.Error()})
+}
+
+func (s *Server) startSeq(request *pb.StreamRequest) (uint64, error) {
+	if request.Cursor != "" {
+		cursor, err := ParseCursor(request.Cursor)
+		if err != nil {
+			return 0, err
+		}
+
+		return s.buffer.Resume(cursor)
+	}
+
+	return s.buffer.SeekBlock(request.StartBlock)
+}
@@ -0,0 +1,266 @@
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/firehose/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startTestServer(t *testing.T, capacity int) (*Server, string) {
+	t.Helper()
+
+	buffer, err := OpenBuffer(t.TempDir(), capacity)
+	require.NoError(t, err)
+
+	// Unix socket paths are limited in length, t.TempDir() can be too long
+	socketDir, err := os.MkdirTemp("", "fh")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(socketDir) })
+
+	address := "unix://" + filepath.Join(socketDir, "firehose.sock")
+	listener, err := Listen(address)
+	require.NoError(t, err)
+
+	server := NewServer(buffer)
+	go server.Serve(listener)
+	t.Cleanup(server.Close)
+
+	return server, address
+}
+
+func recvNumbers(t *testing.T, client *Client, count int) (numbers []uint64, lastCursor string) {
+	t.Helper()
+
+	for i := 0; i < count; i++ {
+		response, err := client.Recv()
+		require.NoError(t, err)
+		require.Equal(t, pb.StepNew, response.Step)
+
+		numbers = append(numbers, response.Block.Number)
+		lastCursor = response.Cursor
+	}
+
+	return numbers, lastCursor
+}
+
+func TestServer_MultipleClientsAndResume(t *testing.T) {
+	server, address := startTestServer(t, 100)
+
+	for i := uint64(1); i <= 3; i++ {
+		server.Publish(&pb.Message{Block: testBlock(i)})
+	}
+	server.flush()
+
+	first, err := Dial(address, 0, "", false)
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := Dial(address, 2, "", false)
+	require.NoError(t, err)
+	defer second.Close()
+
+	numbers, cursor := recvNumbers(t, first, 2)
+	assert.Equal(t, []uint64{1, 2}, numbers)
+	require.NoError(t, first.Close())
+
+	// Live blocks reach clients waiting on the head
+	numbers, _ = recvNumbers(t, second, 2)
+	assert.Equal(t, []uint64{2, 3}, numbers)
+	server.Publish(&pb.Message{Init: &pb.Init{}})
+	server.Publish(&pb.Message{Block: testBlock(4)})
+	numbers, _ = recvNumbers(t, second, 1)
+	assert.Equal(t, []uint64{4}, numbers)
+
+	resumed, err := Dial(address, 0, cursor, false)
+	require.NoError(t, err)
+	defer resumed.Close()
+
+	numbers, _ = recvNumbers(t, resumed, 2)
+	assert.Equal(t, []uint64{3, 4}, numbers)
+}
+
+func TestServer_EvictedStart(t *testing.T) {
+	server, address := startTestServer(t, 2)
+
+	for i := uint64(1); i <= 5; i++ {
+		server.Publish(&pb.Message{Block: testBlock(i)})
+	}
+	server.flush()
+
+	client, err := Dial(address, 1, "", false)
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Recv()
+	assert.ErrorContains(t, err, "older than the oldest buffered block")
+}
+
+func TestServer_ForkSteps(t *testing.T) {
+	server, address := startTestServer(t, 100)
+
+	forkStep := func(step pb.Step, block *pb.Block) *pb.Message {
+		return &pb.Message{ForkStep: &pb.ForkStep{Step: step, Number: block.Number, Hash: block.Header.Hash}}
+	}
+
+	for i := uint64(1); i <= 3; i++ {
+		server.Publish(&pb.Message{Block: testBlock(i)})
+		server.Publish(forkStep(pb.StepNew, testBlock(i)))
+	}
+
+	// Block #3 is replaced by another block at the same height
+	replacement := &pb.Block{Number: 3, Header: &pb.BlockHeader{Hash: []byte{0x03, 0xee}}}
+	server.Publish(forkStep(pb.StepUndo, testBlock(3)))
+	server.Publish(&pb.Message{Block: replacement})
+	server.Publish(forkStep(pb.StepNew, replacement))
+	server.flush()
+
+	client, err := Dial(address, 2, "", false)
+	require.NoError(t, err)
+	defer client.Close()
+
+	var steps []pb.Step
+	var blocks []*pb.Block
+	for i := 0; i < 4; i++ {
+		response, err := client.Recv()
+		require.NoError(t, err)
+
+		steps = append(steps, response.Step)
+		blocks = append(blocks, response.Block)
+	}
+
+	assert.Equal(t, []pb.Step{pb.StepNew, pb.StepNew, pb.StepUndo, pb.StepNew}, steps)
+	assert.Equal(t, []*pb.Block{testBlock(2), testBlock(3), testBlock(3), replacement}, blocks)
+}
+
+func TestServer_StartAfterHead(t *testing.T) {
+	server, address := startTestServer(t, 100)
+
+	for i := uint64(1); i <= 3; i++ {
+		server.Publish(&pb.Message{Block: testBlock(i)})
+	}
+	server.flush()
+
+	client, err := Dial(address, 5, "", false)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// The blocks published before the one the client starts at are not sent
+	server.Publish(&pb.Message{Block: testBlock(4)})
+	server.Publish(&pb.Message{Block: testBlock(5)})
+	server.Publish(&pb.Message{Block: testBlock(6)})
+	numbers, _ := recvNumbers(t, client, 2)
+	assert.Equal(t, []uint64{5, 6}, numbers)
+}
+
+func TestServer_CloseBuffersPublished(t *testing.T) {
+	dir := t.TempDir()
+	buffer, err := OpenBuffer(dir, 100)
+	require.NoError(t, err)
+
+	server := NewServer(buffer)
+	for i := uint64(1); i <= 10; i++ {
+		server.Publish(&pb.Message{Block: testBlock(i)})
+	}
+	server.Close()
+
+	reopened, err := OpenBuffer(dir, 100)
+	require.NoError(t, err)
+	for i := uint64(0); i < 10; i++ {
+		block, _, _, err := reopened.Read(i)
+		require.NoError(t, err)
+		assert.Equal(t, i+1, block.Number)
+	}
+}
+
+func TestServer_FinalizedOnly(t *testing.T) {
+	server, address := startTestServer(t, 100)
+
+	for i := uint64(1); i <= 3; i++ {
+		server.Publish(&pb.Message{Block: testBlock(i)})
+	}
+	server.flush()
+
+	client, err := Dial(address, 1, "", true)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Blocks are held until finality reaches them
+	server.Publish(&pb.Message{FinalityUpdate: &pb.FinalityUpdate{FinalizedBlockNumber: 2}})
+	numbers, cursor := recvNumbers(t, client, 2)
+	assert.Equal(t, []uint64{1, 2}, numbers)
+
+	// Block #3 is replaced before being finalized, it's never sent, and blocks advertise the finality too
+	replacement := &pb.Block{Number: 3, Header: &pb.BlockHeader{Hash: []byte{0x03, 0xee}}}
+	server.Publish(&pb.Message{ForkStep: &pb.ForkStep{Step: pb.StepUndo, Number: 3, Hash: testBlock(3).Header.Hash}})
+	server.Publish(&pb.Message{Block: replacement})
+	server.Publish(&pb.Message{Block: &pb.Block{Number: 4, Header: testBlock(4).Header, FinalizedBlockNumber: 3}})
+
+	response, err := client.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, pb.StepNew, response.Step)
+	assert.Equal(t, replacement, response.Block)
+
+	server.Publish(&pb.Message{FinalityUpdate: &pb.FinalityUpdate{FinalizedBlockNumber: 4}})
+	numbers, _ = recvNumbers(t, client, 1)
+	assert.Equal(t, []uint64{4}, numbers)
+
+	resumed, err := Dial(address, 0, cursor, true)
+	require.NoError(t, err)
+	defer resumed.Close()
+
+	response, err = resumed.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, replacement, response.Block)
+	numbers, _ = recvNumbers(t, resumed, 1)
+	assert.Equal(t, []uint64{4}, numbers)
+}
+
+func TestServer_FinalizedOnlyBehind(t *testing.T) {
+	server, address := startTestServer(t, 2)
+
+	server.Publish(&pb.Message{Block: testBlock(1)})
+	server.Publish(&pb.Message{Block: testBlock(2)})
+	server.flush()
+
+	client, err := Dial(address, 1, "", true)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Once block #1 is received, block #2 is held and stays buffered until block #4 is
+	server.Publish(&pb.Message{FinalityUpdate: &pb.FinalityUpdate{FinalizedBlockNumber: 1}})
+	numbers, _ := recvNumbers(t, client, 1)
+	assert.Equal(t, []uint64{1}, numbers)
+
+	server.Publish(&pb.Message{Block: testBlock(3)})
+	server.Publish(&pb.Message{Block: testBlock(4)})
+
+	_, err = client.Recv()
+	assert.ErrorContains(t, err, "finality is behind more than the 2 buffered entries")
+}
+
+func TestServer_ClientSendingData(t *testing.T) {
+	server, address := startTestServer(t, 100)
+
+	client, err := Dial(address, 0, "", false)
+	require.NoError(t, err)
+	defer client.Close()
+
+	server.Publish(&pb.Message{Block: testBlock(1)})
+	numbers, _ := recvNumbers(t, client, 1)
+	assert.Equal(t, []uint64{1}, numbers)
+
+	// Data sent by the client after its request does not mean it went away
+	_, err = client.conn.Write([]byte{0x01, 0x02})
+	require.NoError(t, err)
+	// Leave time for the server to read the data before the block wakes it up
+	time.Sleep(100 * time.Millisecond)
+
+	server.Publish(&pb.Message{Block: testBlock(2)})
+	numbers, _ = recvNumbers(t, client, 1)
+	assert.Equal(t, []uint64{2}, numbers)
+}
@@ -29,22 +29,22 @@ type ExtendedStack struct {
 	stack.Stack
 }
 
-func (s *ExtendedStack) MustPop() string {
+func (s *ExtendedStack) MustPop() uint32 {
 	popped := s.Pop()
 	if popped == nil {
 		panic("at least one element must exist in the index stack at this point")
 	}
 
-	return popped.(string)
+	return popped.(uint32)
 }
 
-func (s *ExtendedStack) MustPeek() string {
+func (s *ExtendedStack) MustPeek() uint32 {
 	peeked := s.Peek()
 	if peeked == nil {
 		panic("at least one element must exist in the index stack at this point")
 	}
 
-	return peeked.(string)
+	return peeked.(uint32)
 }
 
 // BalanceChangeReason denotes a reason why a given balance change occurred.
@@ -78,3 +78,12 @@ var FailedExecutionGasChangeReason = GasChangeReason("failed_execution")
 
 // IgnoredGasChangeReason **On purposely defined using a different syntax, check `GasChangeReason` type doc above**
 var IgnoredGasChangeReason GasChangeReason = "ignored"
+
+// SystemCallReason denotes why the protocol or the consensus engine mutated the state outside of
+// any transaction, it's the reason of a system call scope.
+//
+// **Important!** For easier extraction of all possible `SystemCallReason`, ensure you always
+//
+//	define valid value using the type wrapper so it matches the extraction
+//	regex `SystemCallReason\("[a-z0-9_]+"\)`.
+type SystemCallReason string
@@ -13,6 +13,8 @@ func Test_validateKnownTransactionTypes(t *testing.T) {
 	}{
 		{"legacy", 0, true, nil},
 		{"access_list", 1, true, nil},
+		{"dynamic_fee", 2, true, nil},
+		{"blob", 3, true, nil},
 		{"inexistant", 255, false, nil},
 	}
 	for _, tt := range tests {
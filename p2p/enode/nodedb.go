@@ -46,6 +46,7 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbBanPrefix    = "ban:" // Bans are keyed by ID only, the full key is "ban:<ID>"
 	dbDiscoverRoot = "v4"
 	dbDiscv5Root   = "v5"
 
@@ -399,6 +400,7 @@ func (db *DB) expirer() {
 		select {
 		case <-tick.C:
 			db.expireNodes()
+			db.expireBans()
 		case <-db.quit:
 			return
 		}
@@ -602,6 +604,100 @@ func (db *DB) QuerySeeds(n int, maxAge time.Duration) []*Node {
 	return nodes
 }
 
+// Ban is a node which is not allowed to connect until the ban expires.
+type Ban struct {
+	ID     ID
+	Until  time.Time
+	Reason string
+}
+
+func banKey(id ID) []byte {
+	return append([]byte(dbBanPrefix), id[:]...)
+}
+
+// BanNode stores the ban of a node, replacing its previous ban if any.
+func (db *DB) BanNode(id ID, until time.Time, reason string) error {
+	blob := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(reason))
+	blob = append(blob[:binary.PutVarint(blob, until.Unix())], reason...)
+	return db.kv.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.Inodes, banKey(id), blob)
+	})
+}
+
+// UnbanNode deletes the ban of a node. It reports whether the node was banned.
+func (db *DB) UnbanNode(id ID) (bool, error) {
+	var found bool
+	err := db.kv.Update(context.Background(), func(tx kv.RwTx) error {
+		v, err := tx.GetOne(kv.Inodes, banKey(id))
+		if err != nil || v == nil {
+			return err
+		}
+		found = true
+		return tx.Delete(kv.Inodes, banKey(id))
+	})
+	return found, err
+}
+
+// Bans retrieves the bans which have not expired yet.
+func (db *DB) Bans() ([]Ban, error) {
+	var (
+		now  = time.Now()
+		bans []Ban
+	)
+	if err := db.kv.View(context.Background(), func(tx kv.Tx) error {
+		c, err := tx.Cursor(kv.Inodes)
+		if err != nil {
+			return err
+		}
+		p := []byte(dbBanPrefix)
+		for k, v, err := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			until, read := binary.Varint(v)
+			if read <= 0 || len(k) != len(p)+len(ID{}) {
+				continue
+			}
+			ban := Ban{Until: time.Unix(until, 0), Reason: string(v[read:])}
+			copy(ban.ID[:], k[len(p):])
+			if ban.Until.After(now) {
+				bans = append(bans, ban)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+// expireBans deletes the bans which have expired.
+func (db *DB) expireBans() {
+	threshold := time.Now().Unix()
+	var toDelete [][]byte
+	if err := db.kv.View(context.Background(), func(tx kv.Tx) error {
+		c, err := tx.Cursor(kv.Inodes)
+		if err != nil {
+			return err
+		}
+		p := []byte(dbBanPrefix)
+		for k, v, err := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			if until, read := binary.Varint(v); read <= 0 || until <= threshold {
+				toDelete = append(toDelete, common.CopyBytes(k))
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Warn("nodeDB.expireBans failed", "err", err)
+	}
+	for _, td := range toDelete {
+		deleteRange(db.kv, td)
+	}
+}
+
 // close flushes and closes the database files.
 func (db *DB) Close() {
 	select {
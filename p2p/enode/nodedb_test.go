@@ -488,3 +488,49 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+// This test checks that bans are stored with their expiry and reason, and that
+// expired bans are neither returned nor kept.
+func TestDBBans(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := OpenDB("", tmpDir)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	var (
+		now     = time.Now().Truncate(time.Second)
+		banned  = ID{0x01}
+		expired = ID{0x02}
+	)
+	if err := db.BanNode(banned, now.Add(time.Hour), "invalid headers"); err != nil {
+		t.Fatalf("failed to ban node: %v", err)
+	}
+	if err := db.BanNode(expired, now.Add(-time.Minute), "slow"); err != nil {
+		t.Fatalf("failed to ban node: %v", err)
+	}
+	bans, err := db.Bans()
+	if err != nil {
+		t.Fatalf("failed to read bans: %v", err)
+	}
+	want := []Ban{{ID: banned, Until: now.Add(time.Hour), Reason: "invalid headers"}}
+	if !reflect.DeepEqual(bans, want) {
+		t.Errorf("bans mismatch: have %v, want %v", bans, want)
+	}
+
+	db.expireBans()
+	if blob := db.fetchUint64(banKey(expired)); blob != 0 {
+		t.Errorf("expired ban shouldn't be present after expiration")
+	}
+
+	if found, err := db.UnbanNode(banned); err != nil || !found {
+		t.Errorf("unban of banned node: found %t, err %v", found, err)
+	}
+	if found, err := db.UnbanNode(banned); err != nil || found {
+		t.Errorf("unban of unbanned node: found %t, err %v", found, err)
+	}
+	if bans, err := db.Bans(); err != nil || len(bans) != 0 {
+		t.Errorf("bans after unban: %v, err %v", bans, err)
+	}
+}
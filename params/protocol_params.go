@@ -130,6 +130,15 @@ const (
 	MaxCodeSize     = 24576           // Maximum bytecode to permit for a contract
 	MaxInitCodeSize = 2 * MaxCodeSize // Maximum initcode to permit in a creation transaction and create instructions
 
+	BlobTxBytesPerFieldElement       = 32      // Size in bytes of a field element of a blob
+	BlobTxFieldElementsPerBlob       = 4096    // Number of field elements stored in a single data blob
+	BlobTxHashVersion                = 0x01    // Version byte of the commitment hash (EIP-4844)
+	BlobTxBlobGasPerBlob             = 1 << 17 // Gas consumption of a single data blob (== blob byte size)
+	BlobTxMinBlobGasprice            = 1       // Minimum gas price for data blobs
+	BlobTxBlobGaspriceUpdateFraction = 3338477 // Controls the maximum rate of change for blob gas price
+	MaxBlobGasPerBlock               = 786432  // Maximum consumable blob gas for data blobs per block
+	TargetBlobGasPerBlock            = 393216  // Target consumable blob gas for data blobs per block (for 1559-like pricing)
+
 	// Precompiled contract gas prices
 
 	TendermintHeaderValidateGas uint64 = 3000 // Gas for validate tendermiint consensus state
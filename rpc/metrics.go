@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/VictoriaMetrics/metrics"
 )
@@ -35,3 +36,25 @@ func newRPCServingTimerMS(method string, valid bool) *metrics.Summary {
 	m := fmt.Sprintf(`rpc_duration_seconds{method="%s",success="%s"}`, method, flag)
 	return metrics.GetOrCreateSummary(m)
 }
+
+// Usage of the clients of a server with a RateLimiter, keyed like the limiter keys them. The series of a
+// client are unregistered when the limiter forgets about it, so that there are at most as many as clients kept.
+func rpcClientRequestsName(client string) string {
+	return fmt.Sprintf(`rpc_client_requests{client="%s"}`, escapeLabelValue(client))
+}
+
+func rpcClientCostName(client string) string {
+	return fmt.Sprintf(`rpc_client_cost{client="%s"}`, escapeLabelValue(client))
+}
+
+func rpcRateLimitedName(client, method string) string {
+	return fmt.Sprintf(`rpc_client_rate_limited{client="%s",method="%s"}`, escapeLabelValue(client), escapeLabelValue(method))
+}
+
+// labelValueEscaper escapes label values the way the Prometheus text format does. Client keys may hold
+// JWT subjects, which are chosen by the clients and are not verified.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
@@ -0,0 +1,267 @@
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/golang-jwt/jwt/v4"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRateLimitClients = 10_000
+	defaultMethodCost       = 1
+)
+
+// RateLimitConfig configures the limits applied by a RateLimiter. Each client gets a token bucket
+// refilled with Rate tokens per second and holding at most Burst tokens, every call taking the cost
+// of its method from it. Methods listed in Methods are additionally limited to a number of calls per
+// second, per client.
+//
+// Clients are identified by the value of the APIKeyHeader header when it is configured and set, then
+// by the subject of the bearer JWT of the request when JwtSubject is set, and by their IP address
+// otherwise. Neither the API key nor the token is verified here: both are meant to be set by a proxy
+// authenticating the clients in front of the server.
+type RateLimitConfig struct {
+	Rate         float64                    `json:"rate"`  // Tokens per second given to each client, 0 for no limit
+	Burst        int                        `json:"burst"` // Size of the bucket of each client, the largest cost by default
+	APIKeyHeader string                     `json:"apiKeyHeader"`
+	JwtSubject   bool                       `json:"jwtSubject"`
+	Costs        map[string]int             `json:"costs"` // Cost of each method, 1 if not listed
+	Methods      map[string]MethodRateLimit `json:"methods"`
+	MaxClients   int                        `json:"maxClients"` // Number of clients whose buckets and metrics are kept
+}
+
+// MethodRateLimit limits the number of calls per second a client can make to a method
+type MethodRateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// RateLimiter charges the calls of each client against its limits. It is shared by all the
+// connections of a server.
+type RateLimiter struct {
+	cfg RateLimitConfig
+
+	lock    sync.Mutex
+	clients *lru.Cache[string, *clientLimiter]
+}
+
+type clientLimiter struct {
+	total   *rate.Limiter
+	methods map[string]*rate.Limiter
+
+	requests, cost *metrics.Counter
+	limited        map[string]*metrics.Counter // Calls refused, by method
+	evicted        bool
+}
+
+// unregister removes the metrics of the client, once the limiter forgot about it
+func (c *clientLimiter) unregister(key string) {
+	c.evicted = true
+	metrics.UnregisterMetric(rpcClientRequestsName(key))
+	metrics.UnregisterMetric(rpcClientCostName(key))
+	for method := range c.limited {
+		metrics.UnregisterMetric(rpcRateLimitedName(key, method))
+	}
+}
+
+// NewRateLimiter checks the given config and creates a limiter applying it
+func NewRateLimiter(cfg RateLimitConfig) (*RateLimiter, error) {
+	if cfg.Rate < 0 {
+		return nil, fmt.Errorf("invalid rate limit: negative rate %f", cfg.Rate)
+	}
+	maxCost := defaultMethodCost
+	for method, cost := range cfg.Costs {
+		if cost < 0 {
+			return nil, fmt.Errorf("invalid rate limit: negative cost %d of %s", cost, method)
+		}
+		if cost > maxCost {
+			maxCost = cost
+		}
+	}
+	if cfg.Burst == 0 {
+		cfg.Burst = maxCost
+		if r := int(math.Ceil(cfg.Rate)); r > cfg.Burst {
+			cfg.Burst = r
+		}
+	}
+	if cfg.Rate > 0 && cfg.Burst < maxCost {
+		return nil, fmt.Errorf("invalid rate limit: burst %d is lower than the largest cost %d", cfg.Burst, maxCost)
+	}
+	methods := make(map[string]MethodRateLimit, len(cfg.Methods))
+	for method, limit := range cfg.Methods {
+		if limit.Rate <= 0 {
+			return nil, fmt.Errorf("invalid rate limit of %s: rate must be positive", method)
+		}
+		if limit.Burst == 0 {
+			limit.Burst = int(math.Ceil(limit.Rate))
+		}
+		methods[method] = limit
+	}
+	cfg.Methods = methods
+	if cfg.MaxClients == 0 {
+		cfg.MaxClients = defaultRateLimitClients
+	}
+	clients, err := lru.NewWithEvict[string, *clientLimiter](cfg.MaxClients, func(key string, c *clientLimiter) { c.unregister(key) })
+	if err != nil {
+		return nil, err
+	}
+	return &RateLimiter{cfg: cfg, clients: clients}, nil
+}
+
+// cost returns the number of tokens a call of the method takes
+func (l *RateLimiter) cost(method string) int {
+	if cost, ok := l.cfg.Costs[method]; ok {
+		return cost
+	}
+	return defaultMethodCost
+}
+
+func (l *RateLimiter) client(key string) *clientLimiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if c, ok := l.clients.Get(key); ok {
+		return c
+	}
+	c := &clientLimiter{
+		total:    rate.NewLimiter(rate.Inf, 0),
+		requests: metrics.GetOrCreateCounter(rpcClientRequestsName(key)),
+		cost:     metrics.GetOrCreateCounter(rpcClientCostName(key)),
+		limited:  map[string]*metrics.Counter{},
+	}
+	if l.cfg.Rate > 0 {
+		c.total = rate.NewLimiter(rate.Limit(l.cfg.Rate), l.cfg.Burst)
+	}
+	if len(l.cfg.Methods) > 0 {
+		c.methods = make(map[string]*rate.Limiter, len(l.cfg.Methods))
+		for method, limit := range l.cfg.Methods {
+			c.methods[method] = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+		}
+	}
+	l.clients.Add(key, c)
+	return c
+}
+
+// rateLimited counts the refused call of the method
+func (l *RateLimiter) rateLimited(key string, c *clientLimiter, method string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	counter, ok := c.limited[method]
+	if !ok {
+		if c.evicted {
+			// Forgotten while the call was charged, its metrics are gone
+			return
+		}
+		counter = metrics.GetOrCreateCounter(rpcRateLimitedName(key, method))
+		c.limited[method] = counter
+	}
+	counter.Inc()
+}
+
+// charge takes a call of the method from the limits of the client, returning a *RateLimitedError
+// when one of them is reached
+func (l *RateLimiter) charge(key, method string) error {
+	cost := l.cost(method)
+	c := l.client(key)
+	now := time.Now()
+	var methodRes *rate.Reservation
+	if limiter, ok := c.methods[method]; ok {
+		methodRes = limiter.ReserveN(now, 1)
+		if delay := methodRes.DelayFrom(now); delay > 0 {
+			methodRes.CancelAt(now)
+			l.rateLimited(key, c, method)
+			return &RateLimitedError{Client: key, Method: method, RetryAfter: delay}
+		}
+	}
+	if cost > 0 {
+		res := c.total.ReserveN(now, cost)
+		if delay := res.DelayFrom(now); delay > 0 {
+			res.CancelAt(now)
+			if methodRes != nil {
+				methodRes.CancelAt(now)
+			}
+			l.rateLimited(key, c, method)
+			return &RateLimitedError{Client: key, RetryAfter: delay}
+		}
+	}
+	c.requests.Inc()
+	c.cost.Add(cost)
+	return nil
+}
+
+// ClientKey identifies the client sending the request
+func (l *RateLimiter) ClientKey(r *http.Request) string {
+	if l.cfg.APIKeyHeader != "" {
+		if key := r.Header.Get(l.cfg.APIKeyHeader); key != "" {
+			// The keys are secrets, only their hash is exposed in the metrics and errors
+			h := sha256.Sum256([]byte(key))
+			return "key:" + hex.EncodeToString(h[:8])
+		}
+	}
+	if l.cfg.JwtSubject {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			claims := jwt.RegisteredClaims{}
+			if _, _, err := jwt.NewParser().ParseUnverified(strings.TrimPrefix(auth, "Bearer "), &claims); err == nil && claims.Subject != "" {
+				return "jwt:" + claims.Subject
+			}
+		}
+	}
+	return remoteClientKey(r.RemoteAddr)
+}
+
+func remoteClientKey(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return "ip:" + host
+	}
+	if remoteAddr == "" {
+		return "local"
+	}
+	return "ip:" + remoteAddr
+}
+
+// RateLimitedError is returned when a client reaches its limit, or its limit on the calls of a method
+type RateLimitedError struct {
+	Client     string
+	Method     string // Set when the limit of the calls of the method was reached
+	RetryAfter time.Duration
+}
+
+const (
+	rateLimitedErrorCode       = -32005
+	methodRateLimitedErrorCode = -32029
+)
+
+func (e *RateLimitedError) ErrorCode() int {
+	if e.Method != "" {
+		return methodRateLimitedErrorCode
+	}
+	return rateLimitedErrorCode
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.Method != "" {
+		return fmt.Sprintf("rate limit of %s exceeded", e.Method)
+	}
+	return "rate limit exceeded"
+}
+
+// ErrorData tells the client when it can retry, in seconds
+func (e *RateLimitedError) ErrorData() interface{} {
+	return map[string]interface{}{
+		"client":     e.Client,
+		"retryAfter": math.Ceil(e.RetryAfter.Seconds()),
+	}
+}
+
+// rateLimitKeyCtxKey is the context key of the rate limit key of the client of an HTTP request
+type rateLimitKeyCtxKey struct{}
@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitedTestServer(t *testing.T, cfg RateLimitConfig) *Server {
+	limiter, err := NewRateLimiter(cfg)
+	require.NoError(t, err)
+	server := newTestServer()
+	server.SetRateLimiter(limiter)
+	return server
+}
+
+func requireRateLimited(t *testing.T, err error, code int) {
+	t.Helper()
+	require.Error(t, err)
+	e, ok := err.(Error)
+	require.True(t, ok, "not an rpc.Error: %#v", err)
+	require.Equal(t, code, e.ErrorCode())
+	de, ok := err.(DataError)
+	require.True(t, ok, "not an rpc.DataError: %#v", err)
+	require.Contains(t, de.ErrorData(), "retryAfter")
+}
+
+func TestRateLimitHTTP(t *testing.T) {
+	server := newRateLimitedTestServer(t, RateLimitConfig{
+		Rate:         0.001,
+		Burst:        4,
+		APIKeyHeader: "X-Api-Key",
+		Costs:        map[string]int{"test_echo": 3, "test_rets": 0},
+		Methods:      map[string]MethodRateLimit{"test_noArgsRets": {Rate: 0.001}},
+	})
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	dial := func(apiKey string) *Client {
+		client, err := DialHTTP(httpsrv.URL)
+		require.NoError(t, err)
+		client.SetHeader("X-Api-Key", apiKey)
+		return client
+	}
+	alice, bob := dial("alice"), dial("bob")
+	defer alice.Close()
+	defer bob.Close()
+
+	var res echoResult
+	require.NoError(t, alice.Call(&res, "test_echo", "x", 1))
+	// The method limit is reached before the client limit
+	require.NoError(t, alice.Call(nil, "test_noArgsRets"))
+	requireRateLimited(t, alice.Call(nil, "test_noArgsRets"), methodRateLimitedErrorCode)
+	// Free methods are not charged
+	var s string
+	for i := 0; i < 10; i++ {
+		require.NoError(t, alice.Call(&s, "test_rets"))
+	}
+	requireRateLimited(t, alice.Call(&res, "test_echo", "x", 1), rateLimitedErrorCode)
+
+	// Each client has its own limits, also within batches
+	batch := []BatchElem{
+		{Method: "test_echo", Args: []interface{}{"x", 1}, Result: &res},
+		{Method: "test_echo", Args: []interface{}{"x", 1}, Result: &res},
+	}
+	require.NoError(t, bob.BatchCall(batch))
+	if batch[0].Error != nil {
+		batch[0], batch[1] = batch[1], batch[0]
+	}
+	require.NoError(t, batch[0].Error)
+	requireRateLimited(t, batch[1].Error, rateLimitedErrorCode)
+}
+
+func TestRateLimitWebsocket(t *testing.T) {
+	server := newRateLimitedTestServer(t, RateLimitConfig{Rate: 0.001, Burst: 2})
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server.WebsocketHandler([]string{"*"}, nil, false))
+	defer httpsrv.Close()
+	wsURL := "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+
+	client, err := DialWebsocket(context.Background(), wsURL, "")
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, client.Call(nil, "test_noArgsRets"))
+	require.NoError(t, client.Call(nil, "test_noArgsRets"))
+	requireRateLimited(t, client.Call(nil, "test_noArgsRets"), rateLimitedErrorCode)
+
+	// Connections from the same address share their limits
+	client2, err := DialWebsocket(context.Background(), wsURL, "")
+	require.NoError(t, err)
+	defer client2.Close()
+	requireRateLimited(t, client2.Call(nil, "test_noArgsRets"), rateLimitedErrorCode)
+}
+
+func TestRateLimitClientKey(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{Rate: 1, APIKeyHeader: "X-Api-Key", JwtSubject: true})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("POST", "http://url.com", nil)
+	r.RemoteAddr = "10.0.0.1:4242"
+	require.Equal(t, "ip:10.0.0.1", limiter.ClientKey(r))
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "tenant"}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	r.Header.Set("Authorization", "Bearer "+token)
+	require.Equal(t, "jwt:tenant", limiter.ClientKey(r))
+
+	r.Header.Set("X-Api-Key", "secret-key")
+	key := limiter.ClientKey(r)
+	require.True(t, strings.HasPrefix(key, "key:"))
+	require.NotContains(t, key, "secret-key")
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	_, err := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 2, Costs: map[string]int{"debug_traceBlockByNumber": 10}})
+	require.Error(t, err)
+	_, err = NewRateLimiter(RateLimitConfig{Methods: map[string]MethodRateLimit{"eth_call": {}}})
+	require.Error(t, err)
+
+	limiter, err := NewRateLimiter(RateLimitConfig{Rate: 1, Costs: map[string]int{"debug_traceBlockByNumber": 10}})
+	require.NoError(t, err)
+	require.Equal(t, 10, limiter.cfg.Burst)
+}
+
+func TestRateLimitMetrics(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{Rate: 0.001, Burst: 1, MaxClients: 2})
+	require.NoError(t, err)
+	exposed := func() string {
+		var buf bytes.Buffer
+		metrics.WritePrometheus(&buf, false)
+		return buf.String()
+	}
+
+	require.NoError(t, limiter.charge("ip:metrics-1", "eth_call"))
+	requireRateLimited(t, limiter.charge("ip:metrics-1", "eth_call"), rateLimitedErrorCode)
+	require.NoError(t, limiter.charge("ip:metrics-2", "eth_call"))
+	require.Contains(t, exposed(), `rpc_client_requests{client="ip:metrics-1"} 1`)
+	require.Contains(t, exposed(), `rpc_client_rate_limited{client="ip:metrics-1",method="eth_call"} 1`)
+
+	// The series of the clients which are forgotten are removed with them
+	require.NoError(t, limiter.charge("ip:metrics-3", "eth_call"))
+	require.NotContains(t, exposed(), `client="ip:metrics-1"`)
+	require.Contains(t, exposed(), `rpc_client_cost{client="ip:metrics-2"} 1`)
+	require.Contains(t, exposed(), `rpc_client_cost{client="ip:metrics-3"} 1`)
+}
+
+func TestRateLimitMetricsUntrustedSubject(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{Rate: 0.001, Burst: 1, JwtSubject: true, Methods: map[string]MethodRateLimit{"eth_call": {Rate: 0.001, Burst: 1}}})
+	require.NoError(t, err)
+
+	// The subject of an unverified token ends up in the labels of the metrics of the client
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: `a"},x="\`}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	r := httptest.NewRequest("POST", "http://url.com", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	key := limiter.ClientKey(r)
+	require.Equal(t, `jwt:a"},x="\`, key)
+
+	require.NoError(t, limiter.charge(key, "eth_call"))
+	requireRateLimited(t, limiter.charge(key, "eth_call"), methodRateLimitedErrorCode)
+	var buf bytes.Buffer
+	metrics.WritePrometheus(&buf, false)
+	require.Contains(t, buf.String(), `rpc_client_requests{client="jwt:a\"},x=\"\\"} 1`)
+	require.Contains(t, buf.String(), `rpc_client_rate_limited{client="jwt:a\"},x=\"\\",method="eth_call"} 1`)
+}
@@ -46,6 +46,7 @@ const (
 type Server struct {
 	services        serviceRegistry
 	methodAllowList AllowList
+	rateLimiter     *RateLimiter
 	idgen           func() ID
 	run             int32
 	codecs          mapset.Set
@@ -71,6 +72,11 @@ func (s *Server) SetAllowList(allowList AllowList) {
 	s.methodAllowList = allowList
 }
 
+// SetRateLimiter sets the limiter charging the calls of the clients of this server
+func (s *Server) SetRateLimiter(limiter *RateLimiter) {
+	s.rateLimiter = limiter
+}
+
 // SetBatchLimit sets limit of number of requests in a batch
 func (s *Server) SetBatchLimit(limit int) {
 	s.batchLimit = limit
@@ -90,6 +96,11 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, remoteClientKey(codec.remoteAddr()))
+}
+
+// serveCodec serves the codec of a connection of the client identified by rateLimitKey
+func (s *Server) serveCodec(codec ServerCodec, rateLimitKey string) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -101,7 +112,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.rateLimiter, rateLimitKey)
 	<-codec.closed()
 	c.Close()
 }
@@ -117,6 +128,10 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stre
 
 	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.traceRequests)
 	h.allowSubscribe = false
+	if s.rateLimiter != nil {
+		h.rateLimiter = s.rateLimiter
+		h.rateLimitKey, _ = ctx.Value(rateLimitKeyCtxKey{}).(string)
+	}
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -64,7 +64,11 @@ func (s *Server) WebsocketHandler(allowedOrigins []string, jwtSecret []byte, com
 			return
 		}
 		codec := newWebsocketCodec(conn)
-		s.ServeCodec(codec, 0)
+		rateLimitKey := remoteClientKey(r.RemoteAddr)
+		if s.rateLimiter != nil {
+			rateLimitKey = s.rateLimiter.ClientKey(r)
+		}
+		s.serveCodec(codec, rateLimitKey)
 	})
 }
 
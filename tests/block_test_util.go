@@ -41,6 +41,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/stages"
 )
@@ -105,6 +106,12 @@ type btHeaderMarshaling struct {
 }
 
 func (t *BlockTest) Run(tst *testing.T, _ bool) error {
+	return t.RunHistoryV3(tst, ethconfig.EnableHistoryV3InTest)
+}
+
+// RunHistoryV3 runs the test executing the blocks with the parallel executor of HistoryV3 when historyV3 is set,
+// and serially otherwise
+func (t *BlockTest) RunHistoryV3(tst *testing.T, historyV3 bool) error {
 	config, ok := Forks[t.json.Network]
 	if !ok {
 		return UnsupportedForkError{t.json.Network}
@@ -118,7 +125,7 @@ func (t *BlockTest) Run(tst *testing.T, _ bool) error {
 	if config.TerminalTotalDifficulty != nil {
 		engine = serenity.New(engine) // the Merge
 	}
-	m := stages.MockWithGenesisEngine(tst, t.genesis(config), engine, false)
+	m := stages.MockWithHistoryV3(tst, t.genesis(config), engine, historyV3)
 
 	// import pre accounts & construct test genesis block & state root
 	if m.Genesis.Hash() != t.json.Genesis.Hash {
@@ -0,0 +1,130 @@
+//go:build integration
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/log/v3"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/firehose"
+)
+
+// The fixtures of the tests/testdata submodule are only checked for the invariants of the Firehose
+// output, no golden file being committed for them: the golden files would follow the revision of
+// the submodule. The golden files cover the fixtures of tests/execution-spec-tests.
+
+func TestFirehoseState(t *testing.T) {
+	defer log.Root().SetHandler(log.Root().GetHandler())
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlError, log.StderrHandler))
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+
+	st := new(testMatcher)
+
+	// EOF is not implemented yet
+	st.skipLoad(`^EIPTests/stEOF/`)
+
+	// Very time consuming
+	st.skipLoad(`^stTimeConsuming/`)
+	st.skipLoad(`.*vmPerformance/`)
+	st.skipLoad(`^stQuadraticComplexityTest/`)
+
+	st.walk(t, stateTestDir, func(t *testing.T, name string, test *StateTest) {
+		db := memdb.NewTestDB(t)
+		for _, subtest := range test.Subtests() {
+			subtest := subtest
+			key := fmt.Sprintf("%s/%d", subtest.Fork, subtest.Index)
+			t.Run(key, func(t *testing.T) {
+				tx, err := db.BeginRw(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer tx.Rollback()
+
+				printer := firehose.NewToBufferPrinter()
+				_, err = test.RunWithFirehose(tx, subtest, vm.Config{}, firehose.NewContext(printer))
+				if err != nil && len(test.json.Post[subtest.Fork][subtest.Index].ExpectException) == 0 {
+					if err := st.checkFailure(t, err); err != nil {
+						t.Error(err)
+					}
+					return
+				}
+
+				post, err := firehosePostState(tx)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				output := printer.Buffer().Bytes()
+				if err := CheckFirehoseInvariants(output, test.json.Pre, post); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	})
+}
+
+func TestFirehoseBlockchain(t *testing.T) {
+	defer log.Root().SetHandler(log.Root().GetHandler())
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlError, log.StderrHandler))
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+
+	bt := new(testMatcher)
+	// Same skips as TestBlockchain
+	bt.skipLoad(`^GeneralStateTests/`)
+	bt.skipLoad(`^TransitionTests/bcArrowGlacierToMerge/powToPosBlockRejection\.json`)
+	bt.skipLoad(`^TransitionTests/bcFrontierToHomestead/blockChainFrontierWithLargerTDvsHomesteadBlockchain\.json`)
+	if ethconfig.EnableHistoryV3InTest {
+		bt.skipLoad(`^InvalidBlocks/bcInvalidHeaderTest/log1_wrongBloom\.json`)
+		bt.skipLoad(`^InvalidBlocks/bcInvalidHeaderTest/wrongReceiptTrie\.json`)
+		bt.skipLoad(`^InvalidBlocks/bcInvalidHeaderTest/wrongGasUsed\.json`)
+	}
+
+	bt.walk(t, blockTestDir, func(t *testing.T, name string, test *BlockTest) {
+		printer := firehose.NewToBufferPrinter()
+		withFirehose(printer, test.genesis(Forks[test.json.Network]), func() {
+			if err := bt.checkFailure(t, test.Run(t, false)); err != nil {
+				t.Error(err)
+			}
+		})
+
+		if err := CheckFirehoseInvariants(printer.Buffer().Bytes(), nil, test.json.Post); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// firehosePostState returns the accounts of the plain state, only their balance being set.
+func firehosePostState(tx kv.Tx) (core.GenesisAlloc, error) {
+	alloc := core.GenesisAlloc{}
+	err := tx.ForEach(kv.PlainState, nil, func(k, v []byte) error {
+		if len(k) != length.Addr {
+			return nil
+		}
+
+		var account accounts.Account
+		if err := account.DecodeForStorage(v); err != nil {
+			return err
+		}
+		alloc[libcommon.BytesToAddress(k)] = core.GenesisAccount{Balance: account.Balance.ToBig()}
+		return nil
+	})
+
+	return alloc, err
+}
@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+)
+
+var updateFirehoseGolden = flag.Bool("update-firehose-golden", false, "write the Firehose output of the fixtures to their golden files instead of comparing it")
+
+var firehoseGoldenDir = filepath.Join(".", "firehose-golden")
+
+func TestFirehoseExecutionSpec(t *testing.T) {
+	defer log.Root().SetHandler(log.Root().GetHandler())
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlError, log.StderrHandler))
+
+	bt := new(testMatcher)
+
+	dir := filepath.Join(".", "execution-spec-tests")
+
+	bt.walk(t, dir, func(t *testing.T, name string, test *BlockTest) {
+		printer := firehose.NewToBufferPrinter()
+		withFirehose(printer, test.genesis(Forks[test.json.Network]), func() {
+			if err := bt.checkFailure(t, test.Run(t, false)); err != nil {
+				t.Error(err)
+			}
+		})
+
+		output := printer.Buffer().Bytes()
+		if err := CheckFirehoseInvariants(output, nil, test.json.Post); err != nil {
+			t.Error(err)
+		}
+		if err := CompareFirehoseGolden(firehoseGoldenPath("execution-spec-tests", name), output, *updateFirehoseGolden); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Tests that the parallel executor of HistoryV3, which records the transactions of a block in buffers
+// merged in transaction order, prints the same output as the serial execution of the blocks
+func TestFirehoseParallelExecution(t *testing.T) {
+	defer log.Root().SetHandler(log.Root().GetHandler())
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlError, log.StderrHandler))
+
+	bt := new(testMatcher)
+
+	dir := filepath.Join(".", "execution-spec-tests")
+
+	bt.walk(t, dir, func(t *testing.T, name string, test *BlockTest) {
+		requireSameFirehoseExecution(t, test.genesis(Forks[test.json.Network]), func(historyV3 bool) error {
+			return bt.checkFailure(t, test.RunHistoryV3(t, historyV3))
+		})
+	})
+
+	// The fixtures are all post-Byzantium, the receipts of pre-Byzantium transactions are covered on a
+	// generated chain with a transfer, a contract creation, a call emitting a log and a failed call
+	t.Run("pre-byzantium", func(t *testing.T) {
+		key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender := crypto.PubkeyToAddress(key.PublicKey)
+		logger, failing := libcommon.Address{0x10}, libcommon.Address{0x20}
+		genesis := &core.Genesis{
+			Config:   Forks["Homestead"],
+			GasLimit: 10_000_000,
+			Alloc: core.GenesisAlloc{
+				sender:  {Balance: big.NewInt(1_000_000_000_000_000_000)},
+				logger:  {Code: []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.LOG0)}, Balance: new(big.Int)},
+				failing: {Code: []byte{byte(vm.INVALID)}, Balance: new(big.Int)},
+			},
+		}
+		signer := types.LatestSignerForChainID(nil)
+		m := stages.MockWithHistoryV3(t, genesis, ethash.NewFaker(), false)
+		chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 2, func(i int, block *core.BlockGen) {
+			nonce := block.TxNonce(sender)
+			for _, tx := range []types.Transaction{
+				types.NewTransaction(nonce, libcommon.Address{0x30}, uint256.NewInt(1_000), 21_000, uint256.NewInt(1), nil),
+				types.NewContractCreation(nonce+1, uint256.NewInt(0), 100_000, uint256.NewInt(1), []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.RETURN)}),
+				types.NewTransaction(nonce+2, logger, uint256.NewInt(0), 50_000, uint256.NewInt(1), nil),
+				types.NewTransaction(nonce+3, failing, uint256.NewInt(0), 50_000, uint256.NewInt(1), nil),
+			} {
+				signed, err := types.SignTx(tx, *signer, key)
+				require.NoError(t, err)
+				block.AddTx(signed)
+			}
+		}, false /* intermediateHashes */)
+		require.NoError(t, err)
+
+		requireSameFirehoseExecution(t, genesis, func(historyV3 bool) error {
+			return stages.MockWithHistoryV3(t, genesis, ethash.NewFaker(), historyV3).InsertChain(chain)
+		})
+	})
+}
+
+// requireSameFirehoseExecution requires that run, executing blocks on top of genesis, prints the same
+// Firehose output with the serial executor and with the parallel executor of HistoryV3.
+func requireSameFirehoseExecution(t *testing.T, genesis *core.Genesis, run func(historyV3 bool) error) {
+	t.Helper()
+	outputs := map[bool][]byte{}
+	for _, historyV3 := range []bool{false, true} {
+		printer := firehose.NewToBufferPrinter()
+		withFirehose(printer, genesis, func() {
+			if err := run(historyV3); err != nil {
+				t.Error(err)
+			}
+		})
+		outputs[historyV3] = printer.Buffer().Bytes()
+	}
+
+	require.NotEmpty(t, outputs[false])
+	require.Equal(t, string(outputs[false]), string(outputs[true]))
+}
+
+// withFirehose runs f with the global Firehose instrumentation enabled and printing to printer,
+// genesis being the genesis recorded when the chain is initialized.
+func withFirehose(printer firehose.Printer, genesis *core.Genesis, f func()) {
+	firehose.Enabled = true
+	firehose.GenesisConfig = genesis
+	firehose.SetSyncPrinter(printer)
+	defer func() {
+		firehose.Enabled = false
+		firehose.GenesisConfig = nil
+	}()
+
+	f()
+}
+
+// firehoseGoldenPath returns the golden file of the test with the given name, "<file>/<key>" as
+// named by testMatcher.walk.
+func firehoseGoldenPath(suite, name string) string {
+	return filepath.Join(firehoseGoldenDir, suite, strings.Replace(name, ".json", "", 1)+".golden")
+}
+
+func TestCheckFirehoseInvariants(t *testing.T) {
+	var tests map[string]*BlockTest
+	if err := readJSONFile(filepath.Join(".", "execution-spec-tests", "vm", "chain_id", "chain_id.json"), &tests); err != nil {
+		t.Fatal(err)
+	}
+	post := tests["000_shanghai"].json.Post
+
+	golden, err := os.ReadFile(firehoseGoldenPath("execution-spec-tests", "vm/chain_id/chain_id.json/000_shanghai"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Golden files are the records without their prefix, hex fields that are abbreviated are
+	// not the ones checked
+	output := regexp.MustCompile("(?m)^").ReplaceAllString(strings.TrimSuffix(string(golden), "\n"), firehoseRecordPrefix)
+
+	for _, tt := range []struct {
+		name     string
+		old, new string
+		err      string
+	}{
+		{"valid", "", "", ""},
+		{"unbalanced call", "\n" + firehoseRecordPrefix + "EVM_END_CALL 1 99956895 . 7", "", "transaction ended with 1 calls open"},
+		{"unclosed system call", "FINALIZE_BLOCK 1", "FINALIZE_BLOCK 1\n" + firehoseRecordPrefix + "BEGIN_SYSTEM_CALL block_reward . 12", "block ended with 1 frames open"},
+		{"ordinal not increasing", "gas_buy 2", "gas_buy 9", "ordinal 3 is not greater than previous ordinal 9"},
+		{"balance not continuous", "3635c9adc5de996c36 gas_refund", "3635c9adc5de996c37 gas_refund", "post-state balance is"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckFirehoseInvariants([]byte(strings.Replace(output, tt.old, tt.new, 1)), nil, post)
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/firehose"
+)
+
+// Firehose output of the fixtures is compared against golden files, and checked for the
+// invariants any instrumented execution must respect whatever the fixture:
+//
+//   - every EVM_RUN_CALL is closed by an EVM_END_CALL of the same call, in stack order, and
+//     transactions and system calls end with no call left open, neither being nested;
+//   - the ordinals of a block strictly increase, in the order records are printed;
+//   - following the canonical chain (blocks applied with NEW_BLOCK and removed with
+//     UNDO_BLOCK), the balance changes of each account are continuous, the old value of a
+//     change being the new value of the previous one, and end at the post-state balance.
+//     Balance changes of failed calls and transactions are ignored as their state is reverted.
+
+const firehoseRecordPrefix = "DMLOG "
+
+// firehoseGoldenMaxHex is the length above which hex fields are abbreviated in golden files,
+// the hex of large code or input (up to 49152 bytes for EIP-3860 fixtures) would make them
+// mostly noise.
+const firehoseGoldenMaxHex = 128
+
+// FirehoseGolden returns the Firehose output in the form it's committed as a golden file, hex
+// fields longer than firehoseGoldenMaxHex being replaced by their length and digest.
+func FirehoseGolden(output []byte) []byte {
+	var golden bytes.Buffer
+	for _, line := range firehoseLines(output) {
+		fields := strings.Split(line, " ")
+		for i, field := range fields {
+			if len(field) > firehoseGoldenMaxHex && isHex(field) {
+				digest := sha256.Sum256([]byte(field))
+				fields[i] = fmt.Sprintf("<%d:%s>", len(field), hex.EncodeToString(digest[:8]))
+			}
+		}
+		golden.WriteString(strings.Join(fields, " "))
+		golden.WriteByte('\n')
+	}
+
+	return golden.Bytes()
+}
+
+// CompareFirehoseGolden compares the Firehose output with the golden file at path, or writes
+// it when update is set.
+func CompareFirehoseGolden(path string, output []byte, update bool) error {
+	golden := FirehoseGolden(output)
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(path, golden, 0644)
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read golden file, run with -update-firehose-golden to create it: %w", err)
+	}
+
+	if !bytes.Equal(expected, golden) {
+		expectedLines, actualLines := strings.Split(string(expected), "\n"), strings.Split(string(golden), "\n")
+		for i := 0; i < len(expectedLines) && i < len(actualLines); i++ {
+			if expectedLines[i] != actualLines[i] {
+				return fmt.Errorf("firehose output differs from golden file %s at line %d:\n  want: %s\n  got:  %s", path, i+1, expectedLines[i], actualLines[i])
+			}
+		}
+		return fmt.Errorf("firehose output differs from golden file %s: got %d lines, want %d", path, len(actualLines), len(expectedLines))
+	}
+
+	return nil
+}
+
+// CheckFirehoseInvariants checks the invariants of the Firehose output of a chain going from the
+// pre state, whose balances are not recorded, to the post state, the balance of accounts not in
+// post being expected to be zero. Balances are not checked when post is nil.
+func CheckFirehoseInvariants(output []byte, pre, post core.GenesisAlloc) error {
+	checker := &firehoseChecker{blocks: map[libcommon.Hash][]firehoseBalanceChange{}}
+	for i, line := range firehoseLines(output) {
+		if err := checker.record(line); err != nil {
+			return fmt.Errorf("line %d: %w: %s", i+1, err, abbreviate(line))
+		}
+	}
+
+	if post == nil {
+		return nil
+	}
+
+	balances := map[libcommon.Address]*big.Int{}
+	for address, account := range pre {
+		if account.Balance != nil {
+			balances[address] = account.Balance
+		}
+	}
+	for _, hash := range checker.canonical {
+		for _, change := range checker.blocks[hash] {
+			previous := balances[change.address]
+			if previous == nil {
+				previous = new(big.Int)
+			}
+			if previous.Cmp(change.oldValue) != 0 {
+				return fmt.Errorf("balance change of %x at ordinal %d in block %x starts from %s, previous balance is %s", change.address, change.ordinal, hash, change.oldValue, previous)
+			}
+			balances[change.address] = change.newValue
+		}
+	}
+
+	for address, balance := range balances {
+		expected := new(big.Int)
+		if account, found := post[address]; found && account.Balance != nil {
+			expected = account.Balance
+		}
+		if balance.Cmp(expected) != 0 {
+			return fmt.Errorf("balance changes of %x end at %s, post-state balance is %s", address, balance, expected)
+		}
+	}
+	for address, account := range post {
+		if _, found := balances[address]; !found && account.Balance != nil && account.Balance.Sign() != 0 {
+			return fmt.Errorf("post-state balance of %x is %s, no balance change recorded", address, account.Balance)
+		}
+	}
+
+	return nil
+}
+
+type firehoseBalanceChange struct {
+	address  libcommon.Address
+	oldValue *big.Int
+	newValue *big.Int
+	ordinal  uint64
+}
+
+// firehoseFrame accumulates the balance changes of a call, or of a transaction or block for
+// the root frames, until it's known whether its state is kept.
+type firehoseFrame struct {
+	index   string
+	failed  bool
+	changes []firehoseBalanceChange
+}
+
+type firehoseChecker struct {
+	inBlock     bool
+	frames      []*firehoseFrame
+	lastOrdinal uint64
+
+	// blocks are the balance changes kept by the blocks ended, by hash
+	blocks    map[libcommon.Hash][]firehoseBalanceChange
+	canonical []libcommon.Hash
+}
+
+func (c *firehoseChecker) record(line string) error {
+	kind, rest, _ := strings.Cut(line, " ")
+	schema, found := firehose.Schema[kind]
+	if !found {
+		return fmt.Errorf("unknown record kind %q", kind)
+	}
+	fields, err := schema.Split(rest)
+	if err != nil {
+		return err
+	}
+	field := func(name string) string {
+		return fields[schema.Index(name)]
+	}
+
+	for i, name := range schema.Fields {
+		if name != "ordinal" {
+			continue
+		}
+		ordinal, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return err
+		}
+		if ordinal <= c.lastOrdinal {
+			return fmt.Errorf("ordinal %d is not greater than previous ordinal %d", ordinal, c.lastOrdinal)
+		}
+		c.lastOrdinal = ordinal
+	}
+
+	switch kind {
+	case "BEGIN_BLOCK":
+		// A block whose execution failed is dropped without being cancelled
+		c.inBlock, c.lastOrdinal = true, 0
+		c.frames = []*firehoseFrame{{index: "block"}}
+
+	case "CANCEL_BLOCK":
+		c.inBlock, c.frames = false, nil
+
+	case "END_BLOCK":
+		if len(c.frames) != 1 {
+			return fmt.Errorf("block ended with %d frames open", len(c.frames)-1)
+		}
+		changes := c.frames[0].changes
+		c.inBlock, c.frames = false, nil
+
+		var endData struct {
+			Header struct {
+				Hash libcommon.Hash `json:"hash"`
+			} `json:"header"`
+		}
+		if err := json.Unmarshal([]byte(field("end_data")), &endData); err != nil {
+			return fmt.Errorf("decode end data: %w", err)
+		}
+		c.blocks[endData.Header.Hash] = changes
+
+	case "NEW_BLOCK":
+		hash := libcommon.HexToHash(field("hash"))
+		if _, found := c.blocks[hash]; !found {
+			return fmt.Errorf("new block %x was never ended", hash)
+		}
+		c.canonical = append(c.canonical, hash)
+
+	case "UNDO_BLOCK":
+		hash := libcommon.HexToHash(field("hash"))
+		if len(c.canonical) == 0 || c.canonical[len(c.canonical)-1] != hash {
+			return fmt.Errorf("undone block %x is not the canonical head", hash)
+		}
+		c.canonical = c.canonical[:len(c.canonical)-1]
+
+	case "BEGIN_APPLY_TRX":
+		if len(c.frames) != 1 {
+			return fmt.Errorf("transaction started with %d frames open", len(c.frames)-1)
+		}
+		c.push("trx")
+
+	case "FAILED_APPLY_TRX", "END_APPLY_TRX":
+		if len(c.frames) != 2 || c.top().index != "trx" {
+			return fmt.Errorf("transaction ended with %d calls open", len(c.frames)-2)
+		}
+		c.top().failed = kind == "FAILED_APPLY_TRX"
+		c.pop()
+
+	case "BEGIN_SYSTEM_CALL":
+		if len(c.frames) != 1 {
+			return fmt.Errorf("system call started with %d frames open", len(c.frames)-1)
+		}
+		c.push("system")
+
+	case "END_SYSTEM_CALL":
+		if len(c.frames) != 2 || c.top().index != "system" {
+			return fmt.Errorf("system call ended with %d calls open", len(c.frames)-2)
+		}
+		c.pop()
+
+	case "EVM_RUN_CALL":
+		c.push(field("call_index"))
+
+	case "EVM_CALL_FAILED":
+		if c.top().index != field("call_index") {
+			return fmt.Errorf("call %s failed while call %s is the active one", field("call_index"), c.top().index)
+		}
+		c.top().failed = true
+
+	case "EVM_END_CALL":
+		if len(c.frames) < 2 || c.top().index != field("call_index") {
+			return fmt.Errorf("call %s ended while call %s is the active one", field("call_index"), c.top().index)
+		}
+		c.pop()
+
+	case "BALANCE_CHANGE":
+		if !c.inBlock {
+			return fmt.Errorf("balance change outside of a block")
+		}
+		ordinal, _ := strconv.ParseUint(field("ordinal"), 10, 64)
+		c.top().changes = append(c.top().changes, firehoseBalanceChange{
+			address:  libcommon.HexToAddress(field("address")),
+			oldValue: firehoseBigInt(field("old_value")),
+			newValue: firehoseBigInt(field("new_value")),
+			ordinal:  ordinal,
+		})
+	}
+
+	return nil
+}
+
+func (c *firehoseChecker) push(index string) {
+	c.frames = append(c.frames, &firehoseFrame{index: index})
+}
+
+func (c *firehoseChecker) top() *firehoseFrame {
+	if len(c.frames) == 0 {
+		return &firehoseFrame{}
+	}
+
+	return c.frames[len(c.frames)-1]
+}
+
+// pop closes the active frame, its changes are kept by the parent frame unless it failed.
+func (c *firehoseChecker) pop() {
+	frame := c.top()
+	c.frames = c.frames[:len(c.frames)-1]
+	if !frame.failed {
+		parent := c.top()
+		parent.changes = append(parent.changes, frame.changes...)
+	}
+}
+
+func firehoseLines(output []byte) (lines []string) {
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, firehoseRecordPrefix) {
+			lines = append(lines, strings.TrimPrefix(line, firehoseRecordPrefix))
+		}
+	}
+
+	return lines
+}
+
+func firehoseBigInt(in string) *big.Int {
+	if in == "." {
+		return new(big.Int)
+	}
+
+	return new(big.Int).SetBytes(common.FromHex(in))
+}
+
+func isHex(in string) bool {
+	_, err := hex.DecodeString(in)
+	return err == nil
+}
+
+func abbreviate(line string) string {
+	if len(line) > 200 {
+		return line[:200] + "..."
+	}
+
+	return line
+}
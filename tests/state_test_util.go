@@ -169,7 +169,13 @@ func (t *StateTest) Subtests() []StateSubtest {
 
 // Run executes a specific subtest and verifies the post-state and logs
 func (t *StateTest) Run(tx kv.RwTx, subtest StateSubtest, vmconfig vm.Config) (*state.IntraBlockState, error) {
-	state, root, err := t.RunNoVerify(tx, subtest, vmconfig)
+	return t.RunWithFirehose(tx, subtest, vmconfig, firehose.NoOpContext)
+}
+
+// RunWithFirehose is Run recording the execution with the given Firehose context, the subtest
+// being recorded as a block made of its transaction on top of the test's genesis block.
+func (t *StateTest) RunWithFirehose(tx kv.RwTx, subtest StateSubtest, vmconfig vm.Config, firehoseContext *firehose.Context) (*state.IntraBlockState, error) {
+	state, root, err := t.runNoVerify(tx, subtest, vmconfig, firehoseContext)
 	if err != nil {
 		return state, err
 	}
@@ -187,6 +193,10 @@ func (t *StateTest) Run(tx kv.RwTx, subtest StateSubtest, vmconfig vm.Config) (*
 
 // RunNoVerify runs a specific subtest and returns the statedb and post-state root
 func (t *StateTest) RunNoVerify(tx kv.RwTx, subtest StateSubtest, vmconfig vm.Config) (*state.IntraBlockState, libcommon.Hash, error) {
+	return t.runNoVerify(tx, subtest, vmconfig, firehose.NoOpContext)
+}
+
+func (t *StateTest) runNoVerify(tx kv.RwTx, subtest StateSubtest, vmconfig vm.Config, firehoseContext *firehose.Context) (*state.IntraBlockState, libcommon.Hash, error) {
 	config, eips, err := GetChainConfig(subtest.Fork)
 	if err != nil {
 		return nil, libcommon.Hash{}, UnsupportedForkError{subtest.Fork}
@@ -221,8 +231,9 @@ func (t *StateTest) RunNoVerify(tx kv.RwTx, subtest StateSubtest, vmconfig vm.Co
 	if err != nil {
 		return nil, libcommon.Hash{}, err
 	}
+	var txn types.Transaction
 	if len(post.Tx) != 0 {
-		txn, err := types.UnmarshalTransactionFromBinary(post.Tx)
+		txn, err = types.UnmarshalTransactionFromBinary(post.Tx)
 		if err != nil {
 			return nil, libcommon.Hash{}, err
 		}
@@ -245,19 +256,44 @@ func (t *StateTest) RunNoVerify(tx kv.RwTx, subtest StateSubtest, vmconfig vm.Co
 		rnd := libcommon.BigToHash(t.json.Env.Random)
 		context.PrevRanDao = &rnd
 	}
-	evm := vm.NewEVM(context, txContext, statedb, config, vmconfig, firehose.NoOpContext)
+	evm := vm.NewEVM(context, txContext, statedb, config, vmconfig, firehoseContext)
+
+	if firehoseContext.Enabled() {
+		firehoseContext.StartBlock(block)
+		if txn != nil {
+			firehoseContext.StartTransaction(txn, baseFee)
+		} else {
+			firehoseStartMessage(firehoseContext, msg, t.json.Tx)
+		}
+		firehoseContext.RecordTrxFrom(msg.From())
+	}
 
 	// Execute the message.
 	snapshot := statedb.Snapshot()
 	gaspool := new(core.GasPool)
 	gaspool.AddGas(block.GasLimit())
-	if _, err = core.ApplyMessage(evm, msg, gaspool, true /* refunds */, false /* gasBailout */); err != nil {
+	result, err := core.ApplyMessage(evm, msg, gaspool, true /* refunds */, false /* gasBailout */)
+	if err != nil {
 		statedb.RevertToSnapshot(snapshot)
 	}
 
-	if err = statedb.FinalizeTx(evm.ChainRules(), w); err != nil {
+	if err := statedb.FinalizeTx(evm.ChainRules(), w); err != nil {
 		return nil, libcommon.Hash{}, err
 	}
+
+	if firehoseContext.Enabled() {
+		if err != nil {
+			firehoseContext.RecordFailedTransaction(err)
+		} else {
+			receipt := &types.Receipt{GasUsed: result.UsedGas, CumulativeGasUsed: result.UsedGas, Logs: statedb.Logs()}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			firehoseContext.EndTransaction(receipt)
+		}
+		firehoseContext.FinalizeBlock(block)
+		firehoseContext.EndBlock(block, nil, block.Difficulty())
+		firehoseContext.RecordNewBlock(block.Header())
+	}
+
 	if err = statedb.CommitBlock(evm.ChainRules(), w); err != nil {
 		return nil, libcommon.Hash{}, err
 	}
@@ -309,6 +345,40 @@ func (t *StateTest) RunNoVerify(tx kv.RwTx, subtest StateSubtest, vmconfig vm.Co
 	return statedb, root, nil
 }
 
+// firehoseStartMessage starts the Firehose transaction of a message built from the fields of a
+// test, which has neither signature nor hash.
+func firehoseStartMessage(firehoseContext *firehose.Context, msg core.Message, tx stTransactionMarshaling) {
+	var maxFeePerGas, maxPriorityFeePerGas *big.Int
+	txType := uint8(types.LegacyTxType)
+	if tx.AccessLists != nil {
+		txType = types.AccessListTxType
+	}
+	if tx.MaxFeePerGas != nil {
+		maxFeePerGas, maxPriorityFeePerGas = (*big.Int)(tx.MaxFeePerGas), (*big.Int)(tx.MaxFeePerGas)
+		if tx.MaxPriorityFeePerGas != nil {
+			maxPriorityFeePerGas = (*big.Int)(tx.MaxPriorityFeePerGas)
+		}
+		txType = types.DynamicFeeTxType
+	}
+
+	firehoseContext.StartTransactionRaw(
+		libcommon.Hash{},
+		msg.To(),
+		msg.Value(),
+		nil, nil, nil,
+		msg.Gas(),
+		msg.GasPrice(),
+		msg.Nonce(),
+		msg.Data(),
+		firehose.AccessList(msg.AccessList()),
+		maxFeePerGas,
+		maxPriorityFeePerGas,
+		txType,
+		nil,
+		nil,
+	)
+}
+
 func MakePreState(rules *chain.Rules, tx kv.RwTx, accounts core.GenesisAlloc, blockNr uint64) (*state.IntraBlockState, error) {
 	r := state.NewPlainStateReader(tx)
 	statedb := state.New(r)
@@ -17,6 +17,7 @@ import (
 	"github.com/ledgerwatch/erigon-lib/common/datadir"
 	"github.com/ledgerwatch/erigon-lib/common/dir"
 	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/downloader/downloadergrpc"
 	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
 	"github.com/ledgerwatch/erigon-lib/etl"
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -33,6 +34,7 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/debug"
 	"github.com/ledgerwatch/erigon/turbo/logging"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/urfave/cli/v2"
 )
@@ -84,6 +86,20 @@ var snapshotCommand = cli.Command{
 			Before: func(ctx *cli.Context) error { return debug.Setup(ctx, nil) },
 			Flags:  joinFlags([]cli.Flag{&utils.DataDirFlag}, debug.Flags, logging.Flags),
 		},
+		{
+			Name:   "verify",
+			Action: doVerifyCommand,
+			Usage:  "Check segments and indices for corruption, and optionally repair them",
+			Before: func(ctx *cli.Context) error { return debug.Setup(ctx, nil) },
+			Flags: joinFlags([]cli.Flag{
+				&utils.DataDirFlag,
+				&SnapshotVerifySampleFlag,
+				&SnapshotVerifyHashesFlag,
+				&SnapshotRepairIndicesFlag,
+				&SnapshotRepairDownloadFlag,
+				&utils.DownloaderAddrFlag,
+			}, debug.Flags, logging.Flags),
+		},
 		{
 			Name:   "ram",
 			Action: doRam,
@@ -124,6 +140,24 @@ var (
 		Name:  "rebuild",
 		Usage: "Force rebuild",
 	}
+	SnapshotVerifySampleFlag = cli.Uint64Flag{
+		Name:  "sample",
+		Usage: "Decode every N-th header, body and transaction, and check it against the indices. 1 - means all of them",
+		Value: 1_000,
+	}
+	SnapshotVerifyHashesFlag = cli.BoolFlag{
+		Name:  "hashes",
+		Usage: "Check segments against the preverified torrent hashes, which reads them entirely",
+		Value: true,
+	}
+	SnapshotRepairIndicesFlag = cli.BoolFlag{
+		Name:  "repair.indices",
+		Usage: "Rebuild the broken indices",
+	}
+	SnapshotRepairDownloadFlag = cli.BoolFlag{
+		Name:  "repair.download",
+		Usage: "Remove the broken segments, and download them again with the downloader at --downloader.api.addr",
+	}
 )
 
 func preloadFileAsync(name string) {
@@ -234,6 +268,67 @@ func doIndicesCommand(cliCtx *cli.Context) error {
 	return nil
 }
 
+func doVerifyCommand(cliCtx *cli.Context) error {
+	ctx := cliCtx.Context
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	repairIndices := cliCtx.Bool(SnapshotRepairIndicesFlag.Name)
+	repairDownload := cliCtx.Bool(SnapshotRepairDownloadFlag.Name)
+	downloaderAddr := cliCtx.String(utils.DownloaderAddrFlag.Name)
+	if repairDownload && downloaderAddr == "" {
+		return fmt.Errorf("--%s requires --%s", SnapshotRepairDownloadFlag.Name, utils.DownloaderAddrFlag.Name)
+	}
+
+	chainDB := mdbx.NewMDBX(log.New()).Path(dirs.Chaindata).Readonly().MustOpen()
+	chainConfig := fromdb.ChainConfig(chainDB)
+	chainDB.Close()
+	chainID, _ := uint256.FromBig(chainConfig.ChainID)
+	preverified := snapcfg.KnownCfg(chainConfig.ChainName, nil, nil).Preverified
+
+	opts := snapshotsync.VerifyOptions{
+		SampleEvery: cliCtx.Uint64(SnapshotVerifySampleFlag.Name),
+		Preverified: preverified,
+		CheckHashes: cliCtx.Bool(SnapshotVerifyHashesFlag.Name),
+	}
+	corruptions, err := snapshotsync.VerifySegments(ctx, dirs.Snap, opts)
+	if err != nil {
+		return err
+	}
+	for _, c := range corruptions {
+		log.Error("[snapshots] Verify", "problem", c)
+	}
+	if len(corruptions) == 0 {
+		log.Info("[snapshots] Verify done, no problems found")
+		return nil
+	}
+
+	if repairIndices {
+		if err := snapshotsync.RebuildBrokenIndices(ctx, corruptions, *chainID, dirs.Tmp); err != nil {
+			return err
+		}
+	}
+	if repairDownload {
+		requests, err := snapshotsync.RemoveBrokenSegments(corruptions, preverified)
+		if err != nil {
+			return err
+		}
+		if len(requests) > 0 {
+			downloaderClient, err := downloadergrpc.NewClient(ctx, downloaderAddr)
+			if err != nil {
+				return err
+			}
+			if err := snapshotsync.RequestSnapshotsDownload(ctx, requests, downloaderClient); err != nil {
+				return err
+			}
+			log.Info("[snapshots] Requested download of the removed segments, run `snapshots index` once they are downloaded", "amount", len(requests))
+		}
+	}
+	if !repairIndices && !repairDownload {
+		return fmt.Errorf("found %d problems, use --%s and --%s to repair them", len(corruptions), SnapshotRepairIndicesFlag.Name, SnapshotRepairDownloadFlag.Name)
+	}
+	return nil
+}
+
 func doUncompress(cliCtx *cli.Context) error {
 	ctx := cliCtx.Context
 
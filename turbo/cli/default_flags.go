@@ -34,6 +34,8 @@ var DefaultFlags = []cli.Flag{
 	&PruneReceiptBeforeFlag,
 	&PruneTxIndexBeforeFlag,
 	&PruneCallTracesBeforeFlag,
+	&PruneReceiptKeepAddressesFlag,
+	&PruneReceiptKeepTopicsFlag,
 	&BatchSizeFlag,
 	&BodyCacheLimitFlag,
 	&DatabaseVerbosityFlag,
@@ -46,6 +48,7 @@ var DefaultFlags = []cli.Flag{
 	&TLSCACertFlag,
 	&StateStreamDisableFlag,
 	&SyncLoopThrottleFlag,
+	&SyncReceiptsDownloadFlag,
 	&BadBlockFlag,
 
 	&utils.HTTPEnabledFlag,
@@ -68,6 +71,8 @@ var DefaultFlags = []cli.Flag{
 	&utils.RpcStreamingDisableFlag,
 	&utils.DBReadConcurrencyFlag,
 	&utils.RpcAccessListFlag,
+	&utils.RpcRateLimitFlag,
+	&utils.RpcMaxGetProofRewindBlockCount,
 	&utils.RpcTraceCompatFlag,
 	&utils.RpcGasCapFlag,
 	&utils.RpcBatchLimit,
@@ -140,6 +145,7 @@ var DefaultFlags = []cli.Flag{
 	&utils.DisableIPV6,
 	&utils.NoDownloaderFlag,
 	&utils.DownloaderVerifyFlag,
+	&utils.SnapVerifyFlag,
 	&HealthCheckFlag,
 	&utils.HeimdallURLFlag,
 	&utils.WithoutHeimdallFlag,
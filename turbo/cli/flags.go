@@ -107,6 +107,15 @@ var (
 		Usage: `Prune data before this block`,
 	}
 
+	PruneReceiptKeepAddressesFlag = cli.StringFlag{
+		Name:  "prune.r.keep.addresses",
+		Usage: `Comma separated list of contracts whose logs are never pruned, together with the receipts of their blocks (requires pruning of receipts)`,
+	}
+	PruneReceiptKeepTopicsFlag = cli.StringFlag{
+		Name:  "prune.r.keep.topics",
+		Usage: `Comma separated list of event topics whose logs are never pruned, together with the receipts of their blocks (requires pruning of receipts)`,
+	}
+
 	ExperimentsFlag = cli.StringFlag{
 		Name: "experiments",
 		Usage: `Enable some experimental stages:
@@ -146,6 +155,11 @@ var (
 		Value: "",
 	}
 
+	SyncReceiptsDownloadFlag = cli.BoolFlag{
+		Name:  "sync.receipts.download",
+		Usage: "Download receipts from peers instead of executing blocks. Gives logs and receipts without keeping the state, falls back to execution if peers don't serve the receipts",
+	}
+
 	BadBlockFlag = cli.StringFlag{
 		Name:  "bad.block",
 		Usage: "Marks block with given hex string as bad and forces initial reorg before normal staged sync",
@@ -208,6 +222,8 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		ctx.Uint64(PruneReceiptBeforeFlag.Name),
 		ctx.Uint64(PruneTxIndexBeforeFlag.Name),
 		ctx.Uint64(PruneCallTracesBeforeFlag.Name),
+		strings.Split(ctx.String(PruneReceiptKeepAddressesFlag.Name), ","),
+		strings.Split(ctx.String(PruneReceiptKeepTopicsFlag.Name), ","),
 		strings.Split(ctx.String(ExperimentsFlag.Name), ","),
 	)
 	if err != nil {
@@ -239,6 +255,8 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 		}
 	}
 
+	cfg.Sync.DownloadReceipts = ctx.Bool(SyncReceiptsDownloadFlag.Name)
+
 	if ctx.String(SyncLoopThrottleFlag.Name) != "" {
 		syncLoopThrottle, err := time.ParseDuration(ctx.String(SyncLoopThrottleFlag.Name))
 		if err != nil {
@@ -305,7 +323,15 @@ func ApplyFlagsForEthConfigCobra(f *pflag.FlagSet, cfg *ethconfig.Config) {
 			beforeC = *v
 		}
 
-		mode, err := prune.FromCli(cfg.Genesis.Config.ChainID.Uint64(), *v, exactH, exactR, exactT, exactC, beforeH, beforeR, beforeT, beforeC, experiments)
+		var keepAddresses, keepTopics []string
+		if v := f.StringSlice(PruneReceiptKeepAddressesFlag.Name, nil, PruneReceiptKeepAddressesFlag.Usage); v != nil {
+			keepAddresses = *v
+		}
+		if v := f.StringSlice(PruneReceiptKeepTopicsFlag.Name, nil, PruneReceiptKeepTopicsFlag.Usage); v != nil {
+			keepTopics = *v
+		}
+
+		mode, err := prune.FromCli(cfg.Genesis.Config.ChainID.Uint64(), *v, exactH, exactR, exactT, exactC, beforeH, beforeR, beforeT, beforeC, keepAddresses, keepTopics, experiments)
 		if err != nil {
 			utils.Fatalf(fmt.Sprintf("error while parsing mode: %v", err))
 		}
@@ -384,12 +410,15 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 		RpcStreamingDisable:  ctx.Bool(utils.RpcStreamingDisableFlag.Name),
 		DBReadConcurrency:    ctx.Int(utils.DBReadConcurrencyFlag.Name),
 		RpcAllowListFilePath: ctx.String(utils.RpcAccessListFlag.Name),
+		RpcRateLimitFilePath: ctx.String(utils.RpcRateLimitFlag.Name),
 		Gascap:               ctx.Uint64(utils.RpcGasCapFlag.Name),
 		MaxTraces:            ctx.Uint64(utils.TraceMaxtracesFlag.Name),
 		TraceCompatibility:   ctx.Bool(utils.RpcTraceCompatFlag.Name),
 		BatchLimit:           ctx.Int(utils.RpcBatchLimit.Name),
 		ReturnDataLimit:      ctx.Int(utils.RpcReturnDataLimit.Name),
 
+		MaxGetProofRewindBlockCount: ctx.Int(utils.RpcMaxGetProofRewindBlockCount.Name),
+
 		TxPoolApiAddr: ctx.String(utils.TxpoolApiAddrFlag.Name),
 
 		StateCache: kvcache.DefaultCoherentConfig,
@@ -29,6 +29,7 @@ import (
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/diagnostics"
 	"github.com/ledgerwatch/erigon/firehose"
+	"github.com/ledgerwatch/erigon/firehose/stream"
 	"github.com/ledgerwatch/erigon/metrics/exp"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/logging"
@@ -87,6 +88,35 @@ var (
 		Name:  "firehose-block-progress",
 		Usage: "Activate/deactivate Firehose block progress output instrumentation, disabled by default",
 	}
+	firehoseMempoolFlag = &cli.BoolFlag{
+		Name:  "firehose-mempool",
+		Usage: "Activate/deactivate Firehose mempool output instrumentation of transactions added, replaced, dropped and included, disabled by default",
+	}
+	firehoseMempoolOutputFlag = &cli.StringFlag{
+		Name:  "firehose-mempool-output",
+		Usage: "File the Firehose mempool records are appended to, in the --firehose-output-format encoding, required when --firehose-mempool is set",
+		Value: "",
+	}
+	firehoseOutputFormatFlag = &cli.StringFlag{
+		Name:  "firehose-output-format",
+		Usage: "Encoding of Firehose sync output instrumentation, either 'text' for space-separated FIRE lines or 'protobuf' for length-prefixed Block messages",
+		Value: string(firehose.TextOutputFormat),
+	}
+	firehoseServerAddrFlag = &cli.StringFlag{
+		Name:  "firehose-server-addr",
+		Usage: "Serve Firehose blocks to stream clients on this address as they are executed, with undo steps for the blocks removed by unwinds, either 'unix:///path/to/socket' or 'tcp://host:port', disabled when empty",
+		Value: "",
+	}
+	firehoseServerBufferDirFlag = &cli.StringFlag{
+		Name:  "firehose-server-buffer-dir",
+		Usage: "Directory of the on-disk ring buffer keeping recent blocks for Firehose stream clients, required when --firehose-server-addr is set",
+		Value: "",
+	}
+	firehoseServerBufferSizeFlag = &cli.IntFlag{
+		Name:  "firehose-server-buffer-size",
+		Usage: "Number of recent blocks kept in the Firehose stream server ring buffer",
+		Value: 1000,
+	}
 	firehoseGenesisFileFlag = &cli.StringFlag{
 		Name:  "firehose-genesis-file",
 		Usage: "On private chains where the genesis config is not known to Geth, you **must** provide the 'genesis.json' file path for proper instrumentation of genesis block",
@@ -102,7 +132,8 @@ var Flags = []cli.Flag{
 
 // FirehoseFlags holds all StreamingFast Firehose related command-line flags.
 var FirehoseFlags = []cli.Flag{
-	firehoseEnabledFlag, firehoseSyncInstrumentationFlag, firehoseBlockProgressFlag,
+	firehoseEnabledFlag, firehoseSyncInstrumentationFlag, firehoseBlockProgressFlag, firehoseMempoolFlag,
+	firehoseMempoolOutputFlag, firehoseOutputFormatFlag, firehoseServerAddrFlag, firehoseServerBufferDirFlag, firehoseServerBufferSizeFlag,
 	firehoseGenesisFileFlag,
 }
 
@@ -196,6 +227,7 @@ func Setup(ctx *cli.Context, genesis *core.Genesis) error {
 		exp.Setup(address)
 		diagnostics.SetupLogsAccess(ctx)
 		diagnostics.SetupDbAccess(ctx)
+		diagnostics.SetupSyncStagesAccess()
 	}
 
 	// pprof server
@@ -220,6 +252,27 @@ func Setup(ctx *cli.Context, genesis *core.Genesis) error {
 	if ctx.IsSet(firehoseBlockProgressFlag.Name) {
 		firehose.BlockProgressEnabled = ctx.Bool(firehoseBlockProgressFlag.Name)
 	}
+	if ctx.IsSet(firehoseMempoolFlag.Name) {
+		firehose.MempoolEnabled = ctx.Bool(firehoseMempoolFlag.Name)
+	}
+	outputFormat := firehose.OutputFormat(ctx.String(firehoseOutputFormatFlag.Name))
+	printer, err := firehose.NewPrinter(outputFormat, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if serverAddr := ctx.String(firehoseServerAddrFlag.Name); serverAddr != "" {
+		server, err := startFirehoseServer(serverAddr, ctx.String(firehoseServerBufferDirFlag.Name), ctx.Int(firehoseServerBufferSizeFlag.Name))
+		if err != nil {
+			return err
+		}
+		printer = firehose.NewTeePrinter(printer, firehose.NewProtobufHandlerPrinter(server.Publish))
+	}
+	firehose.SetSyncPrinter(printer)
+	if firehose.Enabled && firehose.MempoolEnabled {
+		if err := setupFirehoseMempoolOutput(ctx.String(firehoseMempoolOutputFlag.Name), outputFormat); err != nil {
+			return err
+		}
+	}
 
 	var genesisProvenance string
 
@@ -251,6 +304,9 @@ func Setup(ctx *cli.Context, genesis *core.Genesis) error {
 		"enabled", firehose.Enabled,
 		"sync_instrumentation_enabled", firehose.SyncInstrumentationEnabled,
 		"block_progress_enabled", firehose.BlockProgressEnabled,
+		"mempool_enabled", firehose.MempoolEnabled,
+		"mempool_output", ctx.String(firehoseMempoolOutputFlag.Name),
+		"output_format", outputFormat,
 		"genesis_provenance", genesisProvenance,
 		"firehose_version", params.FirehoseVersion(),
 		"erigon_version", params.VersionWithMeta,
@@ -284,6 +340,58 @@ func StartPProf(address string, withMetrics bool) {
 func Exit() {
 	_ = Handler.StopCPUProfile()
 	_ = Handler.StopGoTrace()
+
+	if firehoseServer != nil {
+		firehoseServer.Close()
+	}
+}
+
+var firehoseServer *stream.Server
+
+func startFirehoseServer(addr string, bufferDir string, bufferSize int) (*stream.Server, error) {
+	if bufferDir == "" {
+		return nil, fmt.Errorf("flag --%s is required when --%s is set", firehoseServerBufferDirFlag.Name, firehoseServerAddrFlag.Name)
+	}
+
+	buffer, err := stream.OpenBuffer(bufferDir, bufferSize)
+	if err != nil {
+		return nil, fmt.Errorf("firehose stream buffer: %w", err)
+	}
+
+	listener, err := stream.Listen(addr)
+	if err != nil {
+		return nil, fmt.Errorf("firehose stream server: %w", err)
+	}
+
+	firehoseServer = stream.NewServer(buffer)
+	go func() {
+		if err := firehoseServer.Serve(listener); err != nil {
+			log.Error("Firehose stream server stopped", "err", err)
+		}
+	}()
+
+	log.Info("Firehose stream server started", "addr", addr, "buffer_dir", bufferDir, "buffer_size", bufferSize)
+	return firehoseServer, nil
+}
+
+// setupFirehoseMempoolOutput makes the mempool records go to their own file, apart from the
+// records of the blocks.
+func setupFirehoseMempoolOutput(path string, format firehose.OutputFormat) error {
+	if path == "" {
+		return fmt.Errorf("flag --%s is required when --%s is set", firehoseMempoolOutputFlag.Name, firehoseMempoolFlag.Name)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("firehose mempool output: %w", err)
+	}
+	printer, err := firehose.NewPrinter(format, file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	firehose.SetMempoolPrinter(printer)
+	return nil
 }
 
 // RaiseFdLimit raises out the number of allowed file handles per process
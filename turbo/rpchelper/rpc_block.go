@@ -33,6 +33,24 @@ func GetLatestBlockNumber(tx kv.Tx) (uint64, error) {
 	return blockNum, nil
 }
 
+// GetLatestBlockNumberWithReceipts is GetLatestBlockNumber for the methods serving receipts and logs only,
+// which also serve the blocks whose receipts were downloaded from peers instead of being executed. The
+// state of these blocks is not known, the methods reading state stick to GetLatestBlockNumber.
+func GetLatestBlockNumberWithReceipts(tx kv.Tx) (uint64, error) {
+	blockNum, err := GetLatestBlockNumber(tx)
+	if err != nil {
+		return 0, err
+	}
+	receiptsNum, err := stages.GetStageProgress(tx, stages.Receipts)
+	if err != nil {
+		return 0, fmt.Errorf("getting latest block number with receipts: %w", err)
+	}
+	if receiptsNum > blockNum {
+		return receiptsNum, nil
+	}
+	return blockNum, nil
+}
+
 func GetFinalizedBlockNumber(tx kv.Tx) (uint64, error) {
 	forkchoiceFinalizedHash := rawdb.ReadForkchoiceFinalized(tx)
 	if forkchoiceFinalizedHash != (libcommon.Hash{}) {
@@ -0,0 +1,566 @@
+package snapshotsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/background"
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	dir2 "github.com/ledgerwatch/erigon-lib/common/dir"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/downloader/downloadercfg"
+	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	"github.com/ledgerwatch/erigon-lib/recsplit"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
+)
+
+// CorruptionKind tells how a problem found by VerifySegments can be repaired
+type CorruptionKind int
+
+const (
+	BrokenSegment  CorruptionKind = iota // Segment is truncated, has wrong content or doesn't match its preverified hash: it has to be downloaded again
+	BrokenIndex                          // Index is missing, stale or doesn't match its segment: it can be rebuilt from the segment
+	MissingSegment                       // No segment of the type covers the range
+)
+
+func (k CorruptionKind) String() string {
+	switch k {
+	case BrokenSegment:
+		return "broken segment"
+	case BrokenIndex:
+		return "broken index"
+	case MissingSegment:
+		return "missing segment"
+	default:
+		return fmt.Sprintf("unknown corruption: %d", int(k))
+	}
+}
+
+// Corruption is a problem found in the snapshots
+type Corruption struct {
+	Kind    CorruptionKind
+	Segment snaptype.FileInfo // Segment which has the problem, or whose index has it. Path is empty for missing segments
+	File    string            // Name of the broken file
+	Word    int               // Position of the broken word in the segment, -1 when the problem isn't about a word
+	Offset  uint64            // Offset of the broken word in the segment
+	Reason  string
+}
+
+func (c Corruption) String() string {
+	if c.Word < 0 {
+		return fmt.Sprintf("%s: %s: %s", c.Kind, c.File, c.Reason)
+	}
+	return fmt.Sprintf("%s: %s: word %d at offset %d: %s", c.Kind, c.File, c.Word, c.Offset, c.Reason)
+}
+
+type VerifyOptions struct {
+	SampleEvery uint64              // Decode every N-th header, body and transaction and check it against the index, 0 and 1 check all of them
+	Preverified snapcfg.Preverified // Torrent hashes to check the .torrent files against
+	CheckHashes bool                // Also compute the torrent hashes of the preverified segments, which reads them entirely
+}
+
+// VerifySegments checks the block snapshots in the directory: the ranges covered by the segments, their preverified hashes,
+// the content of the segments, and that their indices match them. Returns the problems found, the error is only about
+// failing to run the checks.
+func VerifySegments(ctx context.Context, dir string, opts VerifyOptions) ([]Corruption, error) {
+	files, err := snaptype.Segments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SampleEvery == 0 {
+		opts.SampleEvery = 1
+	}
+	corruptions := verifyRanges(files)
+
+	hashes := make(map[string]string, len(opts.Preverified))
+	for _, p := range opts.Preverified {
+		hashes[p.Name] = p.Hash
+	}
+	txsInRange := map[Range][2]uint64{} // First transaction ID and amount of transactions of the range, read from the bodies
+	for _, t := range snaptype.AllSnapshotTypes {
+		for _, f := range files {
+			if f.T != t {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+			_, fName := filepath.Split(f.Path)
+			if hash, ok := hashes[fName]; ok {
+				c, err := verifyTorrentHash(f, hash, opts.CheckHashes)
+				if err != nil {
+					return nil, err
+				}
+				corruptions = append(corruptions, c...)
+			}
+			v := &segmentVerifier{f: f, sampleEvery: opts.SampleEvery}
+			switch t {
+			case snaptype.Headers:
+				v.verifyHeaders()
+			case snaptype.Bodies:
+				if firstTxID, txAmount, ok := v.verifyBodies(); ok {
+					txsInRange[Range{f.From, f.To}] = [2]uint64{firstTxID, txAmount}
+				}
+			case snaptype.Transactions:
+				if txs, ok := txsInRange[Range{f.From, f.To}]; ok {
+					v.verifyTransactions(txs[0], txs[1])
+				} else {
+					v.verifyTransactions(0, 0)
+				}
+			}
+			corruptions = append(corruptions, v.corruptions...)
+		}
+	}
+	return corruptions, nil
+}
+
+// verifyRanges reports the gaps between the segments of each type, and the ranges which don't have segments of all types.
+// Segments within larger ones, e.g. left from merges, are ignored.
+func verifyRanges(files []snaptype.FileInfo) (corruptions []Corruption) {
+	var max uint64
+	for _, f := range files {
+		if f.To > max {
+			max = f.To
+		}
+	}
+	for _, t := range snaptype.AllSnapshotTypes {
+		var prevTo uint64
+		for i, f := range files {
+			if f.T != t || f.To <= prevTo {
+				continue
+			}
+			// Files are sorted by range start, so the next ones of the type starting at the same block have larger ranges
+			if j := nextOfType(files, i+1, t); j >= 0 && files[j].From == f.From {
+				continue
+			}
+			if f.From > prevTo {
+				corruptions = append(corruptions, missingSegment(prevTo, f.From, t))
+			}
+			prevTo = f.To
+		}
+		if prevTo < max {
+			corruptions = append(corruptions, missingSegment(prevTo, max, t))
+		}
+	}
+	return corruptions
+}
+
+func nextOfType(files []snaptype.FileInfo, from int, t snaptype.Type) int {
+	for j := from; j < len(files); j++ {
+		if files[j].T == t {
+			return j
+		}
+	}
+	return -1
+}
+
+func missingSegment(from, to uint64, t snaptype.Type) Corruption {
+	return Corruption{
+		Kind:    MissingSegment,
+		Segment: snaptype.FileInfo{From: from, To: to, T: t, Ext: ".seg"},
+		File:    snaptype.SegmentFileName(from, to, t),
+		Word:    -1,
+		Reason:  fmt.Sprintf("no %s for blocks %d-%d", t, from, to),
+	}
+}
+
+// verifyTorrentHash compares the .torrent file of the segment, and optionally the segment itself, with the preverified hash
+func verifyTorrentHash(f snaptype.FileInfo, hash string, checkData bool) ([]Corruption, error) {
+	var corruptions []Corruption
+	_, fName := filepath.Split(f.Path)
+	if dir2.FileExist(f.Path + ".torrent") {
+		mi, err := metainfo.LoadFromFile(f.Path + ".torrent")
+		if err != nil {
+			corruptions = append(corruptions, Corruption{Kind: BrokenSegment, Segment: f, File: fName + ".torrent", Word: -1, Reason: err.Error()})
+		} else if got := mi.HashInfoBytes().HexString(); got != hash {
+			corruptions = append(corruptions, Corruption{Kind: BrokenSegment, Segment: f, File: fName + ".torrent", Word: -1,
+				Reason: fmt.Sprintf("torrent hash %s, preverified %s", got, hash)})
+		}
+	}
+	if !checkData {
+		return corruptions, nil
+	}
+	info := &metainfo.Info{PieceLength: downloadercfg.DefaultPieceSize, Name: fName}
+	if err := info.BuildFromFilePath(f.Path); err != nil {
+		return nil, fmt.Errorf("hashing %s: %w", fName, err)
+	}
+	info.Name = fName
+	infoBytes, err := bencode.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	if got := (metainfo.MetaInfo{InfoBytes: infoBytes}).HashInfoBytes().HexString(); got != hash {
+		corruptions = append(corruptions, Corruption{Kind: BrokenSegment, Segment: f, File: fName, Word: -1,
+			Reason: fmt.Sprintf("torrent hash %s, preverified %s", got, hash)})
+	}
+	return corruptions, nil
+}
+
+// segmentVerifier checks a segment and its indices, stopping at the first problem of each file
+type segmentVerifier struct {
+	f           snaptype.FileInfo
+	sampleEvery uint64
+	corruptions []Corruption
+
+	word   int
+	offset uint64
+}
+
+func (v *segmentVerifier) fileName() string {
+	_, fName := filepath.Split(v.f.Path)
+	return fName
+}
+
+func (v *segmentVerifier) segmentBroken(format string, args ...interface{}) {
+	v.corruptions = append(v.corruptions, Corruption{Kind: BrokenSegment, Segment: v.f, File: v.fileName(), Word: -1, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (v *segmentVerifier) wordBroken(format string, args ...interface{}) {
+	v.corruptions = append(v.corruptions, Corruption{Kind: BrokenSegment, Segment: v.f, File: v.fileName(), Word: v.word, Offset: v.offset, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (v *segmentVerifier) indexBroken(idxName string, word int, format string, args ...interface{}) {
+	v.corruptions = append(v.corruptions, Corruption{Kind: BrokenIndex, Segment: v.f, File: idxName, Word: word, Offset: v.offset, Reason: fmt.Sprintf(format, args...)})
+}
+
+// open opens the segment, which panics on some truncated files
+func (v *segmentVerifier) open() (d *compress.Decompressor) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			v.segmentBroken("can't open: %v", rec)
+			d = nil
+		}
+	}()
+	d, err := compress.NewDecompressor(v.f.Path)
+	if err != nil {
+		v.segmentBroken("can't open: %v", err)
+		return nil
+	}
+	if d == nil {
+		v.segmentBroken("can't open")
+	}
+	return d
+}
+
+// openIndex opens the index of the segment, and checks that it was built for it.
+// Returns nil when the index is to be rebuilt.
+func (v *segmentVerifier) openIndex(d *compress.Decompressor, t string, baseDataID uint64, keyCount uint64) (idx *recsplit.Index) {
+	idxName := snaptype.IdxFileName(v.f.From, v.f.To, t)
+	defer func() {
+		if rec := recover(); rec != nil {
+			v.indexBroken(idxName, -1, "can't open: %v", rec)
+			idx = nil
+		}
+	}()
+	dir, _ := filepath.Split(v.f.Path)
+	idxPath := filepath.Join(dir, idxName)
+	if !dir2.FileExist(idxPath) {
+		v.indexBroken(idxName, -1, "missing")
+		return nil
+	}
+	idx, err := recsplit.OpenIndex(idxPath)
+	if err != nil {
+		v.indexBroken(idxName, -1, "can't open: %v", err)
+		return nil
+	}
+	reason := ""
+	switch {
+	case idx.ModTime().Before(d.ModTime()):
+		reason = fmt.Sprintf("built at %s, before the segment was written at %s", idx.ModTime(), d.ModTime())
+	case idx.BaseDataID() != baseDataID:
+		reason = fmt.Sprintf("starts at %d, expected %d", idx.BaseDataID(), baseDataID)
+	case idx.KeyCount() != keyCount:
+		reason = fmt.Sprintf("has %d keys, the segment has %d words", idx.KeyCount(), keyCount)
+	}
+	if reason != "" {
+		v.indexBroken(idxName, -1, reason)
+		idx.Close()
+		return nil
+	}
+	return idx
+}
+
+// walk calls the function on the sampled words of the segment, and on the last one.
+// Returns false when the segment can't be read to its end.
+func (v *segmentVerifier) walk(d *compress.Decompressor, f func(word []byte) bool) (ok bool) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			v.wordBroken("can't decompress: %v", rec)
+			log.Debug("[snapshots] verify", "file", v.fileName(), "err", rec, "stack", dbg.Stack())
+			ok = false
+		}
+	}()
+	defer d.EnableReadAhead().DisableReadAhead()
+
+	count := d.Count()
+	g := d.MakeGetter()
+	word := make([]byte, 0, 4096)
+	v.word, v.offset = 0, 0
+	for ; g.HasNext(); v.word++ {
+		var nextPos uint64
+		if uint64(v.word)%v.sampleEvery == 0 || v.word == count-1 {
+			word, nextPos = g.Next(word[:0])
+			if !f(word) {
+				return false
+			}
+		} else {
+			nextPos = g.Skip()
+		}
+		v.offset = nextPos
+	}
+	if v.word != count {
+		v.wordBroken("segment ends after %d words, expected %d", v.word, count)
+		return false
+	}
+	return true
+}
+
+// checkOrdinal checks that the index points at the word being verified
+func (v *segmentVerifier) checkOrdinal(idx *recsplit.Index, key []byte) bool {
+	if idx == nil {
+		return true
+	}
+	if offset := idx.OrdinalLookup(uint64(v.word)); offset != v.offset {
+		v.indexBroken(idx.FileName(), v.word, "points at offset %d", offset)
+		return false
+	}
+	if key == nil {
+		return true
+	}
+	if i := recsplit.NewIndexReader(idx).Lookup(key); i != uint64(v.word) {
+		v.indexBroken(idx.FileName(), v.word, "key %x points at word %d", key, i)
+		return false
+	}
+	return true
+}
+
+func (v *segmentVerifier) verifyHeaders() {
+	d := v.open()
+	if d == nil {
+		return
+	}
+	defer d.Close()
+	if d.Count() != int(v.f.To-v.f.From) {
+		v.segmentBroken("has %d headers, expected %d", d.Count(), v.f.To-v.f.From)
+		return
+	}
+	idx := v.openIndex(d, snaptype.Headers.String(), v.f.From, uint64(d.Count()))
+	if idx != nil {
+		defer idx.Close()
+	}
+	v.walk(d, func(word []byte) bool {
+		if len(word) < 2 {
+			v.wordBroken("header of %d bytes", len(word))
+			return false
+		}
+		var header types.Header
+		if err := rlp.DecodeBytes(word[1:], &header); err != nil {
+			v.wordBroken("can't decode header: %v", err)
+			return false
+		}
+		if expected := v.f.From + uint64(v.word); header.Number.Uint64() != expected {
+			v.wordBroken("header of block %d, expected %d", header.Number.Uint64(), expected)
+			return false
+		}
+		hash := crypto.Keccak256Hash(word[1:])
+		if hash[0] != word[0] {
+			v.wordBroken("header starts with %x, its hash with %x", word[0], hash[0])
+			return false
+		}
+		if !v.checkOrdinal(idx, hash[:]) {
+			idx = nil // Reported, no need to check the index further
+		}
+		return true
+	})
+}
+
+// verifyBodies returns the ID of the first transaction of the segment, and the amount of transactions of the blocks
+func (v *segmentVerifier) verifyBodies() (firstTxID, txAmount uint64, ok bool) {
+	d := v.open()
+	if d == nil {
+		return 0, 0, false
+	}
+	defer d.Close()
+	if d.Count() != int(v.f.To-v.f.From) {
+		v.segmentBroken("has %d bodies, expected %d", d.Count(), v.f.To-v.f.From)
+		return 0, 0, false
+	}
+	idx := v.openIndex(d, snaptype.Bodies.String(), v.f.From, uint64(d.Count()))
+	if idx != nil {
+		defer idx.Close()
+	}
+	var prev *types.BodyForStorage
+	num := make([]byte, 10)
+	ok = v.walk(d, func(word []byte) bool {
+		body := &types.BodyForStorage{}
+		if err := rlp.DecodeBytes(word, body); err != nil {
+			v.wordBroken("can't decode body: %v", err)
+			return false
+		}
+		if prev == nil {
+			firstTxID = body.BaseTxId
+		} else if body.BaseTxId < prev.BaseTxId+uint64(prev.TxAmount) {
+			v.wordBroken("first transaction %d, previous sampled body ends at %d", body.BaseTxId, prev.BaseTxId+uint64(prev.TxAmount))
+			return false
+		}
+		prev = body
+		n := binary.PutUvarint(num, uint64(v.word))
+		if !v.checkOrdinal(idx, num[:n]) {
+			idx = nil
+		}
+		return true
+	})
+	if !ok || prev == nil {
+		return 0, 0, false
+	}
+	return firstTxID, prev.BaseTxId + uint64(prev.TxAmount) - firstTxID, true
+}
+
+// verifyTransactions checks the transactions against the amount expected from the bodies, unless it is 0
+func (v *segmentVerifier) verifyTransactions(firstTxID, txAmount uint64) {
+	d := v.open()
+	if d == nil {
+		return
+	}
+	defer d.Close()
+	if txAmount != 0 && uint64(d.Count()) != txAmount {
+		v.segmentBroken("has %d transactions, the bodies have %d", d.Count(), txAmount)
+		return
+	}
+	var idx *recsplit.Index
+	if txAmount != 0 {
+		idx = v.openIndex(d, snaptype.Transactions.String(), firstTxID, uint64(d.Count()))
+		if idx != nil {
+			defer idx.Close()
+		}
+		if idx2Block := v.openIndex(d, snaptype.Transactions2Block.String(), v.f.From, uint64(d.Count())); idx2Block != nil {
+			idx2Block.Close()
+		}
+	}
+	v.walk(d, func(word []byte) bool {
+		if len(word) == 0 { // System transaction
+			if !v.checkOrdinal(idx, nil) {
+				idx = nil
+			}
+			return true
+		}
+		if len(word) <= 1+20 {
+			v.wordBroken("transaction of %d bytes", len(word))
+			return false
+		}
+		txnRlp := word[1+20:]
+		txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(txnRlp), uint64(len(txnRlp))))
+		if err != nil {
+			v.wordBroken("can't decode transaction: %v", err)
+			return false
+		}
+		hash := txn.Hash()
+		if hash[0] != word[0] {
+			v.wordBroken("transaction starts with %x, its hash with %x", word[0], hash[0])
+			return false
+		}
+		if !v.checkOrdinal(idx, hash[:]) {
+			idx = nil
+		}
+		return true
+	})
+}
+
+// CheckSegments is the check of the snapshots done on startup: it samples the segments and their indices,
+// without reading them entirely to compute their hashes
+func CheckSegments(ctx context.Context, dir string) error {
+	corruptions, err := VerifySegments(ctx, dir, VerifyOptions{SampleEvery: 1_000})
+	if err != nil {
+		return err
+	}
+	for _, c := range corruptions {
+		log.Error("[snapshots] Verify", "problem", c)
+	}
+	if len(corruptions) > 0 {
+		return fmt.Errorf("found %d problems in snapshots, run `erigon snapshots verify` to repair them", len(corruptions))
+	}
+	return nil
+}
+
+// RebuildBrokenIndices removes the broken indices, and builds them again from their segments
+func RebuildBrokenIndices(ctx context.Context, corruptions []Corruption, chainID uint256.Int, tmpDir string) error {
+	rebuilt := map[string]struct{}{}
+	for _, c := range corruptions {
+		if c.Kind != BrokenIndex {
+			continue
+		}
+		if _, ok := rebuilt[c.Segment.Path]; ok {
+			continue
+		}
+		rebuilt[c.Segment.Path] = struct{}{}
+		dir, fName := filepath.Split(c.Segment.Path)
+		for _, idxName := range indexFileNames(c.Segment) {
+			if err := os.Remove(filepath.Join(dir, idxName)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		log.Info("[snapshots] Rebuilding index", "segment", fName)
+		if err := buildIdx(ctx, c.Segment, chainID, tmpDir, &background.Progress{}, log.LvlInfo); err != nil {
+			return fmt.Errorf("rebuilding index of %s: %w", fName, err)
+		}
+	}
+	return nil
+}
+
+// RemoveBrokenSegments removes the broken segments which have preverified hashes, together with their indices and
+// .torrent files, and returns the requests to download them again. Segments which aren't preverified are kept,
+// as they can't be downloaded.
+func RemoveBrokenSegments(corruptions []Corruption, preverified snapcfg.Preverified) ([]DownloadRequest, error) {
+	hashes := make(map[string]string, len(preverified))
+	for _, p := range preverified {
+		hashes[p.Name] = p.Hash
+	}
+	var requests []DownloadRequest
+	removed := map[string]struct{}{}
+	for _, c := range corruptions {
+		if c.Kind != BrokenSegment {
+			continue
+		}
+		if _, ok := removed[c.Segment.Path]; ok {
+			continue
+		}
+		removed[c.Segment.Path] = struct{}{}
+		dir, fName := filepath.Split(c.Segment.Path)
+		hash, ok := hashes[fName]
+		if !ok {
+			log.Warn("[snapshots] Broken segment is not preverified, can't download it again", "segment", fName)
+			continue
+		}
+		toRemove := append(indexFileNames(c.Segment), fName+".torrent", fName)
+		for _, name := range toRemove {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+				return requests, err
+			}
+		}
+		log.Info("[snapshots] Removed broken segment", "segment", fName)
+		requests = append(requests, NewDownloadRequest(nil, fName, hash))
+	}
+	return requests, nil
+}
+
+func indexFileNames(f snaptype.FileInfo) []string {
+	names := []string{snaptype.IdxFileName(f.From, f.To, f.T.String())}
+	if f.T == snaptype.Transactions {
+		names = append(names, snaptype.IdxFileName(f.From, f.To, snaptype.Transactions2Block.String()))
+	}
+	return names
+}
@@ -0,0 +1,184 @@
+package snapshotsync
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/background"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/downloader/snaptype"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snapcfg"
+)
+
+// createVerifyTestSnapshots writes the segments of blocks [from, to) with a transaction and two system transactions each,
+// and builds their indices. Headers can be changed before they are written.
+func createVerifyTestSnapshots(t *testing.T, dir string, from, to uint64, changeHeader func(h *types.Header)) {
+	ctx, tmpDir := context.Background(), t.TempDir()
+	key, err := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+
+	segment := func(segType snaptype.Type) *compress.Compressor {
+		c, err := compress.NewCompressor(ctx, "test", filepath.Join(dir, snaptype.SegmentFileName(from, to, segType)), tmpDir, 100, 1, log.LvlDebug)
+		require.NoError(t, err)
+		return c
+	}
+	headers, bodies, txs := segment(snaptype.Headers), segment(snaptype.Bodies), segment(snaptype.Transactions)
+	defer headers.Close()
+	defer bodies.Close()
+	defer txs.Close()
+
+	baseTxID := from * 3
+	for blockNum := from; blockNum < to; blockNum++ {
+		h := &types.Header{Number: new(big.Int).SetUint64(blockNum), Difficulty: big.NewInt(1), GasLimit: 8_000_000}
+		if changeHeader != nil {
+			changeHeader(h)
+		}
+		headerRlp, err := rlp.EncodeToBytes(h)
+		require.NoError(t, err)
+		require.NoError(t, headers.AddWord(append([]byte{crypto.Keccak256(headerRlp)[0]}, headerRlp...)))
+
+		bodyRlp, err := rlp.EncodeToBytes(&types.BodyForStorage{BaseTxId: baseTxID, TxAmount: 3})
+		require.NoError(t, err)
+		require.NoError(t, bodies.AddWord(bodyRlp))
+		baseTxID += 3
+
+		txn, err := types.SignTx(types.NewTransaction(blockNum, libcommon.Address{1}, uint256.NewInt(1), 21_000, uint256.NewInt(1), nil), *signer, key)
+		require.NoError(t, err)
+		txnRlp, err := rlp.EncodeToBytes(txn)
+		require.NoError(t, err)
+		hash := txn.Hash()
+		require.NoError(t, txs.AddWord(nil))
+		require.NoError(t, txs.AddWord(append(append([]byte{hash[0]}, sender[:]...), txnRlp...)))
+		require.NoError(t, txs.AddWord(nil))
+	}
+	require.NoError(t, headers.Compress())
+	require.NoError(t, bodies.Compress())
+	require.NoError(t, txs.Compress())
+
+	p := &background.Progress{}
+	require.NoError(t, HeadersIdx(ctx, filepath.Join(dir, snaptype.SegmentFileName(from, to, snaptype.Headers)), from, tmpDir, p, log.LvlDebug))
+	require.NoError(t, BodiesIdx(ctx, filepath.Join(dir, snaptype.SegmentFileName(from, to, snaptype.Bodies)), from, tmpDir, p, log.LvlDebug))
+	require.NoError(t, TransactionsIdx(ctx, *uint256.NewInt(1), from, to, dir, tmpDir, p, log.LvlDebug))
+}
+
+func TestVerifySegments(t *testing.T) {
+	ctx := context.Background()
+	verify := func(t *testing.T, dir string, opts VerifyOptions) []Corruption {
+		corruptions, err := VerifySegments(ctx, dir, opts)
+		require.NoError(t, err)
+		return corruptions
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		dir := t.TempDir()
+		createVerifyTestSnapshots(t, dir, 0, 1_000, nil)
+		createVerifyTestSnapshots(t, dir, 1_000, 2_000, nil)
+		require.Empty(t, verify(t, dir, VerifyOptions{}))
+		require.Empty(t, verify(t, dir, VerifyOptions{SampleEvery: 100}))
+	})
+
+	t.Run("stale index", func(t *testing.T) {
+		dir := t.TempDir()
+		createVerifyTestSnapshots(t, dir, 0, 1_000, nil)
+		idxName := snaptype.IdxFileName(0, 1_000, snaptype.Bodies.String())
+		past := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, idxName), past, past))
+
+		corruptions := verify(t, dir, VerifyOptions{})
+		require.Equal(t, 1, len(corruptions))
+		require.Equal(t, BrokenIndex, corruptions[0].Kind)
+		require.Equal(t, idxName, corruptions[0].File)
+
+		require.NoError(t, RebuildBrokenIndices(ctx, corruptions, *uint256.NewInt(1), t.TempDir()))
+		require.Empty(t, verify(t, dir, VerifyOptions{}))
+	})
+
+	t.Run("wrong header", func(t *testing.T) {
+		dir := t.TempDir()
+		createVerifyTestSnapshots(t, dir, 0, 1_000, func(h *types.Header) {
+			if h.Number.Uint64() == 500 {
+				h.Number.SetUint64(5_000)
+			}
+		})
+		corruptions := verify(t, dir, VerifyOptions{SampleEvery: 100})
+		require.Equal(t, 1, len(corruptions))
+		require.Equal(t, BrokenSegment, corruptions[0].Kind)
+		require.Equal(t, snaptype.SegmentFileName(0, 1_000, snaptype.Headers), corruptions[0].File)
+		require.Equal(t, 500, corruptions[0].Word)
+		require.NotZero(t, corruptions[0].Offset)
+
+		// Not sampled
+		require.Empty(t, verify(t, dir, VerifyOptions{SampleEvery: 300}))
+	})
+
+	t.Run("truncated segment", func(t *testing.T) {
+		dir := t.TempDir()
+		createVerifyTestSnapshots(t, dir, 0, 1_000, nil)
+		segPath := filepath.Join(dir, snaptype.SegmentFileName(0, 1_000, snaptype.Transactions))
+		stat, err := os.Stat(segPath)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(segPath, stat.Size()/2))
+
+		// Indices are also older than the segment now
+		var broken []Corruption
+		for _, c := range verify(t, dir, VerifyOptions{}) {
+			if c.Kind == BrokenSegment {
+				broken = append(broken, c)
+			}
+		}
+		require.Equal(t, 1, len(broken))
+		require.Equal(t, snaptype.SegmentFileName(0, 1_000, snaptype.Transactions), broken[0].File)
+	})
+
+	t.Run("missing range", func(t *testing.T) {
+		dir := t.TempDir()
+		createVerifyTestSnapshots(t, dir, 0, 1_000, nil)
+		createVerifyTestSnapshots(t, dir, 2_000, 3_000, nil)
+		require.NoError(t, os.Remove(filepath.Join(dir, snaptype.SegmentFileName(2_000, 3_000, snaptype.Bodies))))
+
+		var missing []string
+		for _, c := range verify(t, dir, VerifyOptions{}) {
+			require.Equal(t, MissingSegment, c.Kind)
+			missing = append(missing, c.File)
+		}
+		require.ElementsMatch(t, []string{
+			snaptype.SegmentFileName(1_000, 2_000, snaptype.Headers),
+			snaptype.SegmentFileName(1_000, 3_000, snaptype.Bodies),
+			snaptype.SegmentFileName(1_000, 2_000, snaptype.Transactions),
+		}, missing)
+	})
+
+	t.Run("preverified hash", func(t *testing.T) {
+		dir := t.TempDir()
+		createVerifyTestSnapshots(t, dir, 0, 1_000, nil)
+		segName := snaptype.SegmentFileName(0, 1_000, snaptype.Headers)
+		preverified := snapcfg.Preverified{{Name: segName, Hash: "0000000000000000000000000000000000000000"}}
+
+		require.Empty(t, verify(t, dir, VerifyOptions{Preverified: preverified}))
+		corruptions := verify(t, dir, VerifyOptions{Preverified: preverified, CheckHashes: true})
+		require.Equal(t, 1, len(corruptions))
+		require.Equal(t, BrokenSegment, corruptions[0].Kind)
+		require.Equal(t, segName, corruptions[0].File)
+
+		requests, err := RemoveBrokenSegments(corruptions, preverified)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(requests))
+		require.Equal(t, segName, requests[0].path)
+		require.NoFileExists(t, filepath.Join(dir, segName))
+		require.NoFileExists(t, filepath.Join(dir, snaptype.IdxFileName(0, 1_000, snaptype.Headers.String())))
+	})
+}
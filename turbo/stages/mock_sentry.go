@@ -102,6 +102,10 @@ type MockSentry struct {
 	TransactionsV3 bool
 	agg            *libstate.AggregatorV3
 	BlockSnapshots *snapshotsync.RoSnapshots
+
+	downloadReceipts bool
+	// Receipts of the inserted blocks, served to the receipt requests when downloading receipts
+	receipts map[libcommon.Hash]types.Receipts
 }
 
 func (ms *MockSentry) Close() {
@@ -150,8 +154,47 @@ func (ms *MockSentry) HandShake(ctx context.Context, in *emptypb.Empty) (*proto_
 }
 func (ms *MockSentry) SendMessageByMinBlock(_ context.Context, r *proto_sentry.SendMessageByMinBlockRequest) (*proto_sentry.SentPeers, error) {
 	ms.sentMessages = append(ms.sentMessages, r.Data)
+	if r.Data.Id == proto_sentry.MessageId_GET_RECEIPTS_66 && ms.downloadReceipts {
+		if err := ms.answerReceipts(r.Data.Data); err != nil {
+			return nil, err
+		}
+		return &proto_sentry.SentPeers{Peers: []*ptypes.H512{ms.PeerId}}, nil
+	}
 	return nil, nil
 }
+
+// answerReceipts answers a receipt request as a peer does, with the receipts of the requested blocks it knows
+func (ms *MockSentry) answerReceipts(data []byte) error {
+	var req eth.GetReceiptsPacket66
+	if err := rlp.DecodeBytes(data, &req); err != nil {
+		return err
+	}
+	receipts := make(eth.ReceiptsPacket, 0, len(req.GetReceiptsPacket))
+	for _, hash := range req.GetReceiptsPacket {
+		if blockReceipts, ok := ms.receipts[hash]; ok {
+			receipts = append(receipts, blockReceipts)
+		}
+	}
+	b, err := rlp.EncodeToBytes(&eth.ReceiptsPacket66{
+		RequestId:      req.RequestId,
+		ReceiptsPacket: receipts,
+	})
+	if err != nil {
+		return err
+	}
+	ms.ReceiveWg.Add(1)
+	for _, err = range ms.Send(&proto_sentry.InboundMessage{Id: proto_sentry.MessageId_RECEIPTS_66, Data: b, PeerId: ms.PeerId}) {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SentMessages returns the number of messages sent to the peers
+func (ms *MockSentry) SentMessages() int {
+	return len(ms.sentMessages)
+}
 func (ms *MockSentry) SendMessageById(_ context.Context, r *proto_sentry.SendMessageByIdRequest) (*proto_sentry.SentPeers, error) {
 	ms.sentMessages = append(ms.sentMessages, r.Data)
 	return nil, nil
@@ -208,14 +251,30 @@ func MockWithGenesis(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, w
 
 func MockWithGenesisEngine(t *testing.T, gspec *core.Genesis, engine consensus.Engine, withPosDownloader bool) *MockSentry {
 	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
-	return MockWithEverything(t, gspec, key, prune.DefaultMode, engine, false, withPosDownloader)
+	return MockWithEverything(t, gspec, key, prune.DefaultMode, engine, false, false, withPosDownloader)
 }
 
 func MockWithGenesisPruneMode(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, prune prune.Mode, withPosDownloader bool) *MockSentry {
-	return MockWithEverything(t, gspec, key, prune, ethash.NewFaker(), false, withPosDownloader)
+	return MockWithEverything(t, gspec, key, prune, ethash.NewFaker(), false, false, withPosDownloader)
+}
+
+// MockWithReceiptsDownload creates a mock which downloads receipts instead of executing blocks
+func MockWithReceiptsDownload(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey) *MockSentry {
+	return MockWithEverything(t, gspec, key, prune.DefaultMode, ethash.NewFaker(), false, true, false)
+}
+
+// MockWithHistoryV3 creates a mock executing blocks with the parallel executor of HistoryV3 when historyV3 is set,
+// and serially otherwise, whatever the build tags
+func MockWithHistoryV3(t *testing.T, gspec *core.Genesis, engine consensus.Engine, historyV3 bool) *MockSentry {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	return mockWithEverything(t, gspec, key, prune.DefaultMode, engine, false, false, false, historyV3)
 }
 
-func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, prune prune.Mode, engine consensus.Engine, withTxPool bool, withPosDownloader bool) *MockSentry {
+func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, prune prune.Mode, engine consensus.Engine, withTxPool bool, withReceiptsDownload bool, withPosDownloader bool) *MockSentry {
+	return mockWithEverything(t, gspec, key, prune, engine, withTxPool, withReceiptsDownload, withPosDownloader, ethconfig.EnableHistoryV3InTest)
+}
+
+func mockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey, prune prune.Mode, engine consensus.Engine, withTxPool bool, withReceiptsDownload bool, withPosDownloader bool, historyV3 bool) *MockSentry {
 	var tmpdir string
 	if t != nil {
 		tmpdir = t.TempDir()
@@ -226,12 +285,13 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 	var err error
 
 	cfg := ethconfig.Defaults
-	cfg.HistoryV3 = ethconfig.EnableHistoryV3InTest
+	cfg.HistoryV3 = historyV3
 	cfg.StateStream = true
 	cfg.BatchSize = 1 * datasize.MB
 	cfg.Sync.BodyDownloadTimeoutSeconds = 10
 	cfg.DeprecatedTxPool.Disable = !withTxPool
 	cfg.DeprecatedTxPool.StartOnInit = true
+	cfg.Sync.DownloadReceipts = withReceiptsDownload
 
 	var db kv.RwDB
 	if t != nil {
@@ -286,6 +346,9 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 		BlockSnapshots: allSnapshots,
 		HistoryV3:      cfg.HistoryV3,
 		TransactionsV3: cfg.TransactionsV3,
+
+		downloadReceipts: cfg.Sync.DownloadReceipts && !cfg.HistoryV3,
+		receipts:         map[libcommon.Hash]types.Receipts{},
 	}
 	if t != nil {
 		t.Cleanup(mock.Close)
@@ -441,6 +504,7 @@ func MockWithEverything(t *testing.T, gspec *core.Genesis, key *ecdsa.PrivateKey
 				cfg.TransactionsV3,
 			),
 			stagedsync.StageSendersCfg(mock.DB, mock.ChainConfig, false, dirs.Tmp, prune, blockRetire, mock.sentriesClient.Hd),
+			stagedsync.StageReceiptsCfg(mock.DB, mock.sentriesClient.Rd, mock.sentriesClient.SendReceiptRequest, cfg.Sync.BodyDownloadTimeoutSeconds, prune, blockReader, cfg.Sync.DownloadReceipts, cfg.HistoryV3),
 			stagedsync.StageExecuteBlocksCfg(
 				mock.DB,
 				prune,
@@ -540,7 +604,7 @@ func MockWithTxPool(t *testing.T) *MockSentry {
 		},
 	}
 
-	return MockWithEverything(t, gspec, key, prune.DefaultMode, ethash.NewFaker(), true, false)
+	return MockWithEverything(t, gspec, key, prune.DefaultMode, ethash.NewFaker(), true, false, false)
 }
 
 func MockWithZeroTTD(t *testing.T, withPosDownloader bool) *MockSentry {
@@ -644,6 +708,12 @@ func (ms *MockSentry) insertPoWBlocks(chain *core.ChainPack) error {
 			return err
 		}
 	}
+	if ms.downloadReceipts {
+		// Receipts are served when the stage requests them
+		for i, blockReceipts := range chain.Receipts[0:n] {
+			ms.receipts[chain.Blocks[i].Hash()] = blockReceipts
+		}
+	}
 	ms.ReceiveWg.Wait() // Wait for all messages to be processed before we proceed
 
 	initialCycle := false
@@ -712,7 +782,11 @@ func (ms *MockSentry) InsertChain(chain *core.ChainPack) error {
 		if err != nil {
 			return err
 		}
-		if execAt == 0 {
+		receiptsAt, err := stages.GetStageProgress(tx, stages.Receipts)
+		if err != nil {
+			return err
+		}
+		if execAt == 0 && receiptsAt == 0 {
 			return fmt.Errorf("sentryMock.InsertChain end up with Execution stage progress = 0")
 		}
 		return nil
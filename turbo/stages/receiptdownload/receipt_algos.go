@@ -0,0 +1,185 @@
+package receiptdownload
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"golang.org/x/exp/slices"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/turbo/services"
+)
+
+// Reset prepares the download of the receipts of blocks from `from` to `to` inclusive, forgetting about previous requests
+func (rd *ReceiptDownload) Reset(from, to uint64) {
+	rd.requestedMap = make(map[libcommon.Hash][]uint64)
+	rd.headers = make(map[uint64]*types.Header)
+	rd.delivered = make(map[uint64]types.Receipts)
+	rd.requests = make(map[uint64]*ReceiptRequest)
+	rd.requestsByID = make(map[uint64]*ReceiptRequest)
+	rd.attempts = make(map[uint64]int)
+	rd.requestedLow = from
+	rd.maxProgress = to
+}
+
+// RequestMoreReceipts creates the next request for receipts, for the blocks which are neither delivered nor waiting for an answer.
+// It returns ErrNotServed once the receipts of a block were requested the maximum number of times.
+func (rd *ReceiptDownload) RequestMoreReceipts(tx kv.Tx, blockReader services.HeaderReader, currentTime uint64) (*ReceiptRequest, error) {
+	blockNums := make([]uint64, 0, MaxReceiptsInRequest)
+	hashes := make([]libcommon.Hash, 0, MaxReceiptsInRequest)
+
+	for blockNum := rd.requestedLow; len(blockNums) < MaxReceiptsInRequest && blockNum <= rd.maxProgress && blockNum < rd.requestedLow+MaxReceiptsInFlight; blockNum++ {
+		if _, ok := rd.delivered[blockNum]; ok {
+			// Already delivered, no need to request
+			continue
+		}
+		if req, ok := rd.requests[blockNum]; ok {
+			if currentTime < req.waitUntil {
+				continue
+			}
+			rd.requestFailed(blockNum)
+		}
+		if rd.attempts[blockNum] >= rd.maxAttempts {
+			return nil, fmt.Errorf("%w: block %d requested %d times", ErrNotServed, blockNum, rd.attempts[blockNum])
+		}
+
+		header, ok := rd.headers[blockNum]
+		if !ok {
+			var err error
+			header, err = blockReader.HeaderByNumber(context.Background(), tx, blockNum)
+			if err != nil {
+				return nil, fmt.Errorf("header not found: %w, blockNum=%d", err, blockNum)
+			}
+			if header == nil {
+				return nil, fmt.Errorf("header not found: blockNum=%d", blockNum)
+			}
+			if header.ReceiptHash == types.EmptyRootHash {
+				// Block without transactions
+				rd.delivered[blockNum] = types.Receipts{}
+				continue
+			}
+			rd.headers[blockNum] = header
+		}
+		// Blocks can have the same receipts, e.g. a single transfer with the same gas used
+		if !slices.Contains(rd.requestedMap[header.ReceiptHash], blockNum) {
+			rd.requestedMap[header.ReceiptHash] = append(rd.requestedMap[header.ReceiptHash], blockNum)
+		}
+		blockNums = append(blockNums, blockNum)
+		hashes = append(hashes, header.Hash())
+	}
+	if len(blockNums) == 0 {
+		return nil, nil
+	}
+	return &ReceiptRequest{RequestID: rand.Uint64(), BlockNums: blockNums, Hashes: hashes}, nil // nolint: gosec
+}
+
+// RequestSent records that the request was sent to the peer, and is to be sent again after timeWithTimeout.
+// Requests which no peer could take are recorded with an empty peer, and are retried the same way.
+func (rd *ReceiptDownload) RequestSent(req *ReceiptRequest, timeWithTimeout uint64, peer [64]byte) {
+	for _, num := range req.BlockNums {
+		rd.requests[num] = req
+	}
+	rd.requestsByID[req.RequestID] = req
+	req.waitUntil = timeWithTimeout
+	req.peerID = peer
+}
+
+// requestFailed forgets about the request for the receipts of the block, and counts it as a failed attempt
+func (rd *ReceiptDownload) requestFailed(blockNum uint64) {
+	if req, ok := rd.requests[blockNum]; ok {
+		delete(rd.requestsByID, req.RequestID)
+		delete(rd.requests, blockNum)
+	}
+	rd.attempts[blockNum]++
+}
+
+// DeliverReceipts takes the receipts received from a peer and passes them to the stage.
+// Receipts are dropped when the stage doesn't collect them, e.g. when they aren't downloaded.
+func (rd *ReceiptDownload) DeliverReceipts(requestID uint64, receipts [][]*types.Receipt, lenOfP2PMsg uint64, peerID [64]byte) {
+	select {
+	case rd.deliveryCh <- Delivery{requestID: requestID, receipts: receipts, lenOfP2PMessage: lenOfP2PMsg, peerID: peerID}:
+	default:
+		return
+	}
+
+	select {
+	case rd.DeliveryNotify <- struct{}{}:
+	default:
+	}
+}
+
+// GetDeliveries matches the delivered receipts with the requested blocks by their receipt roots, which validates them.
+// Returns how many blocks got their receipts.
+func (rd *ReceiptDownload) GetDeliveries() uint64 {
+	var delivered uint64
+	for {
+		var delivery Delivery
+		select { // read as much as we can, but don't wait
+		case delivery = <-rd.deliveryCh:
+		default:
+			return delivered
+		}
+
+		var matched, unmatched int
+		for _, receipts := range delivery.receipts {
+			root := types.DeriveSha(types.Receipts(receipts))
+			blockNums, ok := rd.requestedMap[root]
+			if !ok {
+				unmatched++
+				continue
+			}
+			delete(rd.requestedMap, root) // Delivered, cleaning up
+			for _, blockNum := range blockNums {
+				rd.delivered[blockNum] = receipts
+				delete(rd.requests, blockNum)
+				delivered++
+			}
+			matched++
+		}
+		// A peer which answers without the receipts of some blocks most likely doesn't have them,
+		// so there is no point waiting for the timeout to ask again
+		if req, ok := rd.requestsByID[delivery.requestID]; ok && req.peerID == delivery.peerID {
+			for _, blockNum := range req.BlockNums {
+				if rd.requests[blockNum] == req {
+					rd.requestFailed(blockNum)
+				}
+			}
+			delete(rd.requestsByID, req.RequestID)
+		}
+		if total := matched + unmatched; total > 0 {
+			// Approximate numbers
+			rd.deliveredCount += float64(delivery.lenOfP2PMessage) * float64(matched) / float64(total)
+			rd.wastedCount += float64(delivery.lenOfP2PMessage) * float64(unmatched) / float64(total)
+		}
+	}
+}
+
+// NextReceipts returns the receipts of the lowest block which was not processed yet, if they were delivered,
+// and moves on to the next block
+func (rd *ReceiptDownload) NextReceipts() (blockNum uint64, receipts types.Receipts, ok bool) {
+	blockNum = rd.requestedLow
+	if receipts, ok = rd.delivered[blockNum]; !ok {
+		return 0, nil, false
+	}
+	delete(rd.delivered, blockNum)
+	delete(rd.headers, blockNum)
+	delete(rd.attempts, blockNum)
+	rd.requestedLow++
+	return blockNum, receipts, true
+}
+
+func (rd *ReceiptDownload) DeliveryCounts() (float64, float64) {
+	return rd.deliveredCount, rd.wastedCount
+}
+
+// FallBack gives up on downloading receipts, for the lifetime of the process: they are produced by the execution instead
+func (rd *ReceiptDownload) FallBack() {
+	rd.fellBack = true
+}
+
+func (rd *ReceiptDownload) FellBack() bool {
+	return rd.fellBack
+}
@@ -0,0 +1,71 @@
+package receiptdownload
+
+import (
+	"errors"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+const (
+	MaxReceiptsInRequest = 256
+	// MaxReceiptsInFlight limits how far above the lowest missing block receipts are requested, and so how many
+	// delivered receipts can be waiting in memory to be written
+	MaxReceiptsInFlight = 16 * MaxReceiptsInRequest
+	// DefaultMaxAttempts is how many times the receipts of a block are requested before giving up on the peers
+	DefaultMaxAttempts = 5
+)
+
+// ErrNotServed is returned when the receipts of a block were requested the maximum number of times without being delivered
+var ErrNotServed = errors.New("receipts not served by peers")
+
+type Delivery struct {
+	peerID          [64]byte
+	requestID       uint64
+	receipts        [][]*types.Receipt
+	lenOfP2PMessage uint64
+}
+
+// ReceiptDownload represents the state of receipts downloading process
+type ReceiptDownload struct {
+	requestedMap   map[libcommon.Hash][]uint64 // Receipt root => numbers of the blocks waiting for receipts with this root
+	DeliveryNotify chan struct{}
+	deliveryCh     chan Delivery
+	headers        map[uint64]*types.Header
+	delivered      map[uint64]types.Receipts
+	requests       map[uint64]*ReceiptRequest
+	requestsByID   map[uint64]*ReceiptRequest
+	attempts       map[uint64]int // How many times the receipts of a block were requested without being delivered
+	maxAttempts    int
+	requestedLow   uint64 // Lower bound of block number for outstanding requests
+	maxProgress    uint64 // Highest block number to download receipts for
+	deliveredCount float64
+	wastedCount    float64
+	fellBack       bool
+}
+
+// ReceiptRequest is a sketch of the request for the receipts of blocks, sent to a single peer
+type ReceiptRequest struct {
+	RequestID uint64
+	BlockNums []uint64
+	Hashes    []libcommon.Hash
+	peerID    [64]byte
+	waitUntil uint64
+}
+
+// NewReceiptDownload create a new receipt download state object
+func NewReceiptDownload(maxAttempts int) *ReceiptDownload {
+	rd := &ReceiptDownload{
+		maxAttempts: maxAttempts,
+		// DeliveryNotify has capacity 1, and it is also used so that senders never block
+		// This makes this channel a mailbox with no more than one letter in it, meaning
+		// that there is something to collect
+		DeliveryNotify: make(chan struct{}, 1),
+		// delivery channel needs to have enough capacity not to create contention
+		// between delivery and collections
+		deliveryCh: make(chan Delivery, 2*MaxReceiptsInRequest),
+	}
+	rd.Reset(0, 0)
+	return rd
+}
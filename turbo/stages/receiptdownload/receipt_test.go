@@ -0,0 +1,121 @@
+package receiptdownload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+type testHeaderReader map[uint64]*types.Header
+
+func (r testHeaderReader) Header(_ context.Context, _ kv.Getter, _ libcommon.Hash, blockHeight uint64) (*types.Header, error) {
+	return r[blockHeight], nil
+}
+func (r testHeaderReader) HeaderByNumber(_ context.Context, _ kv.Getter, blockHeight uint64) (*types.Header, error) {
+	return r[blockHeight], nil
+}
+func (r testHeaderReader) HeaderByHash(context.Context, kv.Getter, libcommon.Hash) (*types.Header, error) {
+	return nil, nil
+}
+
+func TestDeliverReceipts(t *testing.T) {
+	transfer := []*types.Receipt{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: types.Logs{}}}
+	logger := []*types.Receipt{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 50000, Logs: types.Logs{{Address: libcommon.Address{1}, Topics: []libcommon.Hash{{1}}}}}}
+	for _, r := range append(transfer, logger...) {
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+	}
+	headers := testHeaderReader{
+		1: {Number: libcommon.Big1, ReceiptHash: types.DeriveSha(types.Receipts(transfer))},
+		2: {Number: libcommon.Big2, ReceiptHash: types.DeriveSha(types.Receipts(transfer))},
+		3: {Number: libcommon.Big3, ReceiptHash: types.EmptyRootHash},
+		4: {Number: libcommon.Big256, ReceiptHash: types.DeriveSha(types.Receipts(logger))},
+	}
+	peer := [64]byte{1}
+
+	rd := NewReceiptDownload(2)
+	rd.Reset(1, 4)
+	req, err := rd.RequestMoreReceipts(nil, headers, 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 4}, req.BlockNums)
+	require.Equal(t, []libcommon.Hash{headers[1].Hash(), headers[2].Hash(), headers[4].Hash()}, req.Hashes)
+	rd.RequestSent(req, 10, peer)
+
+	// Nothing more to request until the request times out
+	next, err := rd.RequestMoreReceipts(nil, headers, 5)
+	require.NoError(t, err)
+	require.Nil(t, next)
+
+	// Blocks with the same receipts get them from a single delivery, receipts of other blocks are wasted
+	rd.DeliverReceipts(req.RequestID, [][]*types.Receipt{transfer, {}}, 100, peer)
+	require.Equal(t, uint64(2), rd.GetDeliveries())
+	delivered, wasted := rd.DeliveryCounts()
+	require.Equal(t, float64(50), delivered)
+	require.Equal(t, float64(50), wasted)
+
+	for _, want := range []uint64{1, 2, 3} {
+		blockNum, receipts, ok := rd.NextReceipts()
+		require.True(t, ok)
+		require.Equal(t, want, blockNum)
+		if want == 3 {
+			require.Empty(t, receipts)
+		} else {
+			require.Equal(t, types.Receipts(transfer), receipts)
+		}
+	}
+	_, _, ok := rd.NextReceipts()
+	require.False(t, ok)
+
+	// The peer answered without the receipts of block 4, so they are requested again without waiting for the timeout
+	req, err = rd.RequestMoreReceipts(nil, headers, 5)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4}, req.BlockNums)
+	rd.RequestSent(req, 10, [64]byte{})
+
+	// Second attempt times out
+	_, err = rd.RequestMoreReceipts(nil, headers, 10)
+	require.True(t, errors.Is(err, ErrNotServed))
+}
+
+func TestDeliverReceiptsOtherRequest(t *testing.T) {
+	receipts := []*types.Receipt{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: types.Logs{}}}
+	receipts[0].Bloom = types.CreateBloom(types.Receipts(receipts))
+	headers := testHeaderReader{
+		1: {Number: libcommon.Big1, ReceiptHash: types.DeriveSha(types.Receipts(receipts))},
+	}
+	peer := [64]byte{1}
+
+	rd := NewReceiptDownload(2)
+	rd.Reset(1, 1)
+	req, err := rd.RequestMoreReceipts(nil, headers, 0)
+	require.NoError(t, err)
+	rd.RequestSent(req, 10, peer)
+
+	// Empty answers to other requests, or from other peers, don't fail the request
+	rd.DeliverReceipts(req.RequestID+1, nil, 10, peer)
+	rd.GetDeliveries()
+	rd.DeliverReceipts(req.RequestID, nil, 10, [64]byte{2})
+	rd.GetDeliveries()
+	next, err := rd.RequestMoreReceipts(nil, headers, 5)
+	require.NoError(t, err)
+	require.Nil(t, next)
+
+	// The empty answer of the peer to the request fails it, and the receipts are requested again
+	rd.DeliverReceipts(req.RequestID, nil, 10, peer)
+	rd.GetDeliveries()
+	next, err = rd.RequestMoreReceipts(nil, headers, 5)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, next.BlockNums)
+	require.NotEqual(t, req.RequestID, next.RequestID)
+	rd.RequestSent(next, 10, peer)
+
+	rd.DeliverReceipts(next.RequestID, nil, 10, peer)
+	rd.GetDeliveries()
+	_, err = rd.RequestMoreReceipts(nil, headers, 5)
+	require.True(t, errors.Is(err, ErrNotServed))
+}
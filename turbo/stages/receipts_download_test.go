@@ -0,0 +1,214 @@
+package stages_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/bitmapdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/protocols/eth"
+	stages2 "github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+	"github.com/ledgerwatch/erigon/turbo/stages/receiptdownload"
+)
+
+// receiptsDownloadChain generates a chain with blocks which have transfers (with the same receipts), blocks
+// which have logs, and empty blocks
+func receiptsDownloadChain(t *testing.T) (*stages.MockSentry, *core.ChainPack) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.LatestSignerForChainID(nil)
+		// Constructor emitting a log with topic 1, and deploying no code
+		logger = hexutil.MustDecode("0x600160006000a100")
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+		}
+	)
+	m := stages.MockWithReceiptsDownload(t, gspec, key)
+	if m.HistoryV3 {
+		t.Skip("receipts are not stored with HistoryV3")
+	}
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 12, func(i int, b *core.BlockGen) {
+		nonce := b.TxNonce(address)
+		switch i % 3 {
+		case 0:
+			tx, err := types.SignTx(types.NewTransaction(nonce, libcommon.Address{1}, uint256.NewInt(1000), params.TxGas, nil, nil), *signer, key)
+			require.NoError(t, err)
+			b.AddTx(tx)
+		case 1:
+			tx, err := types.SignTx(types.NewContractCreation(nonce, new(uint256.Int), 1_000_000, new(uint256.Int), logger), *signer, key)
+			require.NoError(t, err)
+			b.AddTx(tx)
+		}
+	}, false /* intermediateHashes */)
+	require.NoError(t, err)
+	return m, chain
+}
+
+func checkReceiptsDownloadChain(t *testing.T, tx kv.Tx, chain *core.ChainPack) {
+	top := chain.TopBlock.NumberU64()
+	for _, stage := range []stages2.SyncStage{stages2.LogIndex, stages2.TxLookup} {
+		progress, err := stages2.GetStageProgress(tx, stage)
+		require.NoError(t, err)
+		require.Equal(t, top, progress, stage)
+	}
+
+	for _, block := range chain.Blocks {
+		receipts := rawdb.ReadRawReceipts(tx, block.NumberU64())
+		require.Equal(t, block.Transactions().Len(), len(receipts), block.NumberU64())
+		for _, r := range receipts {
+			r.Bloom = types.CreateBloom(types.Receipts{r})
+		}
+		require.Equal(t, block.ReceiptHash(), types.DeriveSha(receipts), block.NumberU64())
+		for _, txn := range block.Transactions() {
+			blockNum, err := rawdb.ReadTxLookupEntry(tx, txn.Hash())
+			require.NoError(t, err)
+			require.NotNil(t, blockNum)
+			require.Equal(t, block.NumberU64(), *blockNum)
+		}
+	}
+
+	topic := libcommon.Hash{31: 1}
+	m, err := bitmapdb.Get(tx, kv.LogTopicIndex, topic[:], 0, 10_000_000)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{2, 5, 8, 11}, m.ToArray())
+}
+
+// receiptRequests returns the receipt requests sent by the mock
+func receiptRequests(t *testing.T, m *stages.MockSentry) []eth.GetReceiptsPacket66 {
+	var requests []eth.GetReceiptsPacket66
+	for i := 0; i < m.SentMessages(); i++ {
+		msg := m.SentMessage(i)
+		if msg.Id != sentry.MessageId_GET_RECEIPTS_66 {
+			continue
+		}
+		var req eth.GetReceiptsPacket66
+		require.NoError(t, rlp.DecodeBytes(msg.Data, &req))
+		requests = append(requests, req)
+	}
+	return requests
+}
+
+// blocksWithReceipts returns the hashes of the blocks of the chain which have transactions
+func blocksWithReceipts(chain *core.ChainPack) []libcommon.Hash {
+	var hashes []libcommon.Hash
+	for _, block := range chain.Blocks {
+		if block.Transactions().Len() > 0 {
+			hashes = append(hashes, block.Hash())
+		}
+	}
+	return hashes
+}
+
+func TestReceiptsDownload(t *testing.T) {
+	m, chain := receiptsDownloadChain(t)
+	require.NoError(t, m.InsertChain(chain))
+
+	tx, err := m.DB.BeginRo(m.Ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	executionProgress, err := stages2.GetStageProgress(tx, stages2.Execution)
+	require.NoError(t, err)
+	require.Zero(t, executionProgress)
+	receiptsProgress, err := stages2.GetStageProgress(tx, stages2.Receipts)
+	require.NoError(t, err)
+	require.Equal(t, chain.TopBlock.NumberU64(), receiptsProgress)
+	checkReceiptsDownloadChain(t, tx, chain)
+
+	// The RPC daemon serves the receipts and logs of the blocks with downloaded receipts, but reads the
+	// latest state at the last executed block
+	latest, err := rpchelper.GetLatestBlockNumberWithReceipts(tx)
+	require.NoError(t, err)
+	require.Equal(t, chain.TopBlock.NumberU64(), latest)
+	latest, err = rpchelper.GetLatestBlockNumber(tx)
+	require.NoError(t, err)
+	require.Zero(t, latest)
+	blockNum, _, plainState, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), tx, nil)
+	require.NoError(t, err)
+	require.Zero(t, blockNum)
+	require.True(t, plainState)
+
+	// The receipts came in answer to a single request, for the blocks with transactions
+	requests := receiptRequests(t, m)
+	require.Len(t, requests, 1)
+	require.Equal(t, blocksWithReceipts(chain), []libcommon.Hash(requests[0].GetReceiptsPacket))
+}
+
+func TestReceiptsDownloadFallback(t *testing.T) {
+	m, chain := receiptsDownloadChain(t)
+
+	// Send the blocks, but not the receipts
+	b, err := rlp.EncodeToBytes(&eth.NewBlockPacket{
+		Block: chain.TopBlock,
+		TD:    big.NewInt(1), // This is ignored anyway
+	})
+	require.NoError(t, err)
+	m.ReceiveWg.Add(1)
+	for _, err = range m.Send(&sentry.InboundMessage{Id: sentry.MessageId_NEW_BLOCK_66, Data: b, PeerId: m.PeerId}) {
+		require.NoError(t, err)
+	}
+	b, err = rlp.EncodeToBytes(&eth.BlockHeadersPacket66{
+		RequestId:          1,
+		BlockHeadersPacket: chain.Headers,
+	})
+	require.NoError(t, err)
+	m.ReceiveWg.Add(1)
+	for _, err = range m.Send(&sentry.InboundMessage{Id: sentry.MessageId_BLOCK_HEADERS_66, Data: b, PeerId: m.PeerId}) {
+		require.NoError(t, err)
+	}
+	bodies := make(eth.BlockBodiesPacket, chain.Length())
+	for i, block := range chain.Blocks {
+		bodies[i] = block.Body()
+	}
+	b, err = rlp.EncodeToBytes(&eth.BlockBodiesPacket66{
+		RequestId:         1,
+		BlockBodiesPacket: bodies,
+	})
+	require.NoError(t, err)
+	m.ReceiveWg.Add(1)
+	for _, err = range m.Send(&sentry.InboundMessage{Id: sentry.MessageId_BLOCK_BODIES_66, Data: b, PeerId: m.PeerId}) {
+		require.NoError(t, err)
+	}
+	m.ReceiveWg.Wait() // Wait for all messages to be processed before we proceed
+
+	// The peer answers the receipt requests without the receipts, it doesn't have them
+	if _, err = stages.StageLoopStep(m.Ctx, m.ChainConfig, m.DB, m.Sync, m.Notifications, false, m.UpdateHead); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := m.DB.BeginRo(m.Ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	executionProgress, err := stages2.GetStageProgress(tx, stages2.Execution)
+	require.NoError(t, err)
+	require.Equal(t, chain.TopBlock.NumberU64(), executionProgress)
+	checkReceiptsDownloadChain(t, tx, chain)
+
+	// Each empty answer failed its request, and the receipts were requested again until the peers were given up on
+	requests := receiptRequests(t, m)
+	require.Len(t, requests, receiptdownload.DefaultMaxAttempts)
+	ids := map[uint64]struct{}{}
+	for _, req := range requests {
+		require.Equal(t, blocksWithReceipts(chain), []libcommon.Hash(req.GetReceiptsPacket))
+		ids[req.RequestId] = struct{}{}
+	}
+	require.Len(t, ids, receiptdownload.DefaultMaxAttempts)
+}
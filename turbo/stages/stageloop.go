@@ -408,6 +408,7 @@ func NewDefaultStages(ctx context.Context,
 			cfg.TransactionsV3,
 		),
 		stagedsync.StageSendersCfg(db, controlServer.ChainConfig, false, dirs.Tmp, cfg.Prune, blockRetire, controlServer.Hd),
+		stagedsync.StageReceiptsCfg(db, controlServer.Rd, controlServer.SendReceiptRequest, cfg.Sync.BodyDownloadTimeoutSeconds, cfg.Prune, blockReader, cfg.Sync.DownloadReceipts, cfg.HistoryV3),
 		stagedsync.StageExecuteBlocksCfg(
 			db,
 			cfg.Prune,
@@ -153,9 +153,20 @@ func TraceTx(
 		if config != nil && config.TracerConfig != nil {
 			cfg = *config.TracerConfig
 		}
-		if tracer, err = tracers.New(*config.Tracer, &tracers.Context{
-			TxHash: txCtx.TxHash,
-		}, cfg); err != nil {
+		tracerCtx := &tracers.Context{
+			BlockNumber: blockCtx.BlockNumber,
+			TxHash:      txCtx.TxHash,
+			AccessList:  message.AccessList(),
+		}
+		// The location of the transaction is the one set by IntraBlockState.Prepare
+		if located, ok := ibs.(interface {
+			BlockHash() libcommon.Hash
+			TxIndex() int
+		}); ok {
+			tracerCtx.BlockHash = located.BlockHash()
+			tracerCtx.TxIndex = located.TxIndex()
+		}
+		if tracer, err = tracers.New(*config.Tracer, tracerCtx, cfg); err != nil {
 			stream.WriteNil()
 			return err
 		}
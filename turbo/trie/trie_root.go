@@ -125,6 +125,11 @@ type RootHashAggregator struct {
 	a              accounts.Account
 	leafData       GenStructStepLeafData
 	accData        GenStructStepAccountData
+	// retainDec, when set, makes the nodes on the retained paths be built rather than only hashed,
+	// rootNode being the root of the resulting trie
+	retainDec    RetainDecider
+	retainPrefix []byte
+	rootNode     node
 }
 
 type StreamReceiver interface {
@@ -316,6 +321,24 @@ func (l *FlatDBTrieLoader) CalcTrieRoot(tx kv.Tx, prefix []byte, quit <-chan str
 	return l.receiver.Root(), nil
 }
 
+// CalcTrie works like CalcTrieRoot, but also builds the trie: the nodes on the paths retained by the
+// RetainDecider are loaded, and the rest of the trie is folded into hash nodes.
+func (l *FlatDBTrieLoader) CalcTrie(tx kv.Tx, quit <-chan struct{}) (*Trie, error) {
+	l.defaultReceiver.retainDec = l.rd
+	l.defaultReceiver.rootNode = nil
+	defer func() { l.defaultReceiver.retainDec = nil }()
+	l.receiver = l.defaultReceiver
+	root, err := l.CalcTrieRoot(tx, []byte{}, quit)
+	if err != nil {
+		return nil, err
+	}
+	t := New(root)
+	if l.defaultReceiver.rootNode != nil {
+		t.root = l.defaultReceiver.rootNode
+	}
+	return t, nil
+}
+
 func (l *FlatDBTrieLoader) logProgress(accountKey, ihK []byte) {
 	var k string
 	if accountKey != nil {
@@ -330,6 +353,21 @@ func (r *RootHashAggregator) RetainNothing(_ []byte) bool {
 	return false
 }
 
+func (r *RootHashAggregator) retainAccount(prefix []byte) bool {
+	return r.retainDec != nil && r.retainDec.Retain(prefix)
+}
+
+// retainStorage decides on the storage prefixes of the current account, which are retained
+// in the nibbles of the account hash and incarnation followed by the storage key
+func (r *RootHashAggregator) retainStorage(prefix []byte) bool {
+	if r.retainDec == nil {
+		return false
+	}
+	hexutil.DecompressNibbles(r.currAccK, &r.retainPrefix)
+	r.retainPrefix = append(r.retainPrefix, prefix...)
+	return r.retainDec.Retain(r.retainPrefix)
+}
+
 func (r *RootHashAggregator) Reset(hc HashCollector2, shc StorageHashCollector2, trace bool) {
 	r.hc = hc
 	r.shc = shc
@@ -496,6 +534,9 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		}
 		if r.hb.hasRoot() {
 			r.root = r.hb.rootHash()
+			if r.retainDec != nil {
+				r.rootNode = r.hb.root()
+			}
 		} else {
 			r.root = EmptyRoot
 		}
@@ -566,7 +607,7 @@ func (r *RootHashAggregator) genStructStorage() error {
 		r.leafData.Value = rlphacks.RlpSerializableBytes(r.valueStorage)
 		data = &r.leafData
 	}
-	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.RetainNothing, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.retainStorage, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.shc == nil {
 			return nil
 		}
@@ -634,7 +675,7 @@ func (r *RootHashAggregator) genStructAccount() error {
 	r.currStorage.Reset()
 	r.succStorage.Reset()
 	var err error
-	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.RetainNothing, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.retainAccount, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.hc == nil {
 			return nil
 		}
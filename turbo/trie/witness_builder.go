@@ -175,6 +175,11 @@ func (b *WitnessBuilder) processAccountStorage(n *accountNode, hex []byte, limit
 		return b.addEmptyRoot()
 	}
 
+	// The storage of the account wasn't loaded, none of it is needed
+	if hn, ok := n.storage.(hashNode); ok {
+		return b.addHashOp(hn)
+	}
+
 	// Here we substitute rs parameter for storageRs, because it needs to become the default
 	return b.makeBlockWitness(n.storage, hex, limiter, true)
 }